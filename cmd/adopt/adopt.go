@@ -0,0 +1,106 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package adopt
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/output"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+)
+
+const defaultPort = "8080"
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+
+	Name string
+	Port string
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest) *cobra.Command {
+	o := &CliOptions{
+		Config:   config,
+		Manifest: manifest,
+	}
+	adoptCmd := &cobra.Command{
+		Use:   "adopt <container> [--name <name>][--port <port>]",
+		Short: "Register running Docker container as an event target",
+		Long: `Register running Docker container as an event target.
+Adopted containers are not started, stopped or removed by tmctl,
+their published port is used to deliver events.`,
+		Example: "tmctl adopt my-service --name my-target --port 8080",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.adopt(args[0])
+		},
+	}
+	adoptCmd.Flags().StringVar(&o.Name, "name", "", "Target name, defaults to the container name")
+	adoptCmd.Flags().StringVar(&o.Port, "port", defaultPort, "Container port that accepts events")
+	return adoptCmd
+}
+
+func (o *CliOptions) adopt(container string) error {
+	ctx := context.Background()
+	client, err := docker.NewClient()
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	info, err := client.ContainerInspect(ctx, container)
+	if err != nil {
+		return fmt.Errorf("container %q: %w", container, err)
+	}
+	if !info.State.Running {
+		return fmt.Errorf("container %q is not running", container)
+	}
+	containerName := strings.TrimPrefix(info.Name, "/")
+	if len(info.HostConfig.PortBindings[nat.Port(o.Port+"/tcp")]) == 0 {
+		return fmt.Errorf("port %s of container %q is not published", o.Port, containerName)
+	}
+	name := o.Name
+	if name == "" {
+		name = containerName
+	}
+	if err := triggermesh.ValidateComponentName(name, o.Config.Context); err != nil {
+		if o.Name == "" {
+			return fmt.Errorf("%w, use --name to set the target name", err)
+		}
+		return err
+	}
+
+	s := service.NewAdopted(name, containerName, info.Config.Image, o.Port, o.Config.Context)
+	log.Println("Updating manifest")
+	if _, err := o.Manifest.Add(s); err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	output.PrintStatus("consumer", s, nil, nil)
+	return nil
+}
@@ -0,0 +1,45 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest) *cobra.Command {
+	o := &CliOptions{
+		Config:   config,
+		Manifest: manifest,
+	}
+	brokerCmd := &cobra.Command{
+		Use:   "broker",
+		Short: "Manage the broker of the current context",
+	}
+	brokerCmd.AddCommand(o.newDescribeCmd())
+	brokerCmd.AddCommand(o.newHistoryCmd())
+	brokerCmd.AddCommand(o.newRollbackCmd())
+	brokerCmd.AddCommand(o.newValidateCmd())
+	return brokerCmd
+}
@@ -0,0 +1,357 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net"
+	"net/url"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/docker/go-connections/nat"
+	"github.com/spf13/cobra"
+	kyaml "sigs.k8s.io/yaml"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/ingest"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+const (
+	reachabilityTimeout = 300 * time.Millisecond
+	lookupTimeout       = 5 * time.Second
+)
+
+type description struct {
+	Name           string               `json:"name"`
+	Image          string               `json:"image"`
+	Online         bool                 `json:"online"`
+	IngestURL      string               `json:"ingestURL,omitempty"`
+	Limits         *config.IngestLimits `json:"limits,omitempty"`
+	Backend        string               `json:"backend"`
+	DeadLetterURLs []string             `json:"deadLetterURLs,omitempty"`
+	Routes         []route              `json:"routes"`
+	Config         json.RawMessage      `json:"config,omitempty"`
+}
+
+type route struct {
+	Trigger string                  `json:"trigger"`
+	Filters []eventingbroker.Filter `json:"filters,omitempty"`
+	// DataFilter is the payload expression evaluated
+	// by the destination function, not by the broker.
+	DataFilter  string                          `json:"dataFilter,omitempty"`
+	Destination string                          `json:"destination"`
+	URL         string                          `json:"url"`
+	ContentMode string                          `json:"contentMode"`
+	Delivery    *eventingbroker.DeliveryOptions `json:"deliveryOptions,omitempty"`
+	Reachable   bool                            `json:"reachable"`
+}
+
+func (o *CliOptions) newDescribeCmd() *cobra.Command {
+	var output string
+	describeCmd := &cobra.Command{
+		Use:   "describe [-o json]",
+		Short: "Show the broker status and its routing table",
+		Example: `tmctl broker describe
+
+tmctl broker describe -o json > broker.json`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			if output != "" && output != "json" {
+				return fmt.Errorf("unsupported output format %q", output)
+			}
+			return o.describe(output)
+		},
+	}
+	describeCmd.Flags().StringVarP(&output, "output", "o", "", "Output format: json")
+	cobra.CheckErr(describeCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json"}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	return describeCmd
+}
+
+func (o *CliOptions) describe(output string) error {
+	d, err := o.description()
+	if err != nil {
+		return err
+	}
+	if output == "json" {
+		data, err := json.MarshalIndent(d, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding description: %w", err)
+		}
+		fmt.Println(string(data))
+		return nil
+	}
+
+	w := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
+	status := "offline"
+	if d.Online {
+		status = "online"
+	}
+	fmt.Fprintf(w, "Broker:\t%s\n", d.Name)
+	fmt.Fprintf(w, "Image:\t%s\n", d.Image)
+	fmt.Fprintf(w, "Status:\t%s\n", status)
+	if d.IngestURL != "" {
+		fmt.Fprintf(w, "Ingest URL:\t%s\n", d.IngestURL)
+	}
+	if d.Limits != nil {
+		fmt.Fprintf(w, "Max Payload Size:\t%s\n", valueOrNone(d.Limits.MaxPayloadSize))
+		fmt.Fprintf(w, "Ingest Rate Limit:\t%s\n", valueOrNone(d.Limits.RateLimit))
+	}
+	fmt.Fprintf(w, "Backend:\t%s\n", d.Backend)
+	dlq := "none"
+	if len(d.DeadLetterURLs) != 0 {
+		dlq = strings.Join(d.DeadLetterURLs, ", ")
+	}
+	fmt.Fprintf(w, "DLQ:\t%s\n", dlq)
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if len(d.Routes) == 0 {
+		fmt.Println("\nNo triggers")
+		return nil
+	}
+	fmt.Println()
+	w = tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
+	fmt.Fprintln(w, "Trigger\tFilters\tDestination\tURL\tContent Mode\tDelivery\tReachable")
+	for _, r := range d.Routes {
+		filters := filtersString(r.Filters)
+		if r.DataFilter != "" {
+			filters = fmt.Sprintf("%s, %s (by %s)", filters, r.DataFilter, r.Destination)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\t%s\t%t\n", r.Trigger, filters, r.Destination, r.URL, r.ContentMode, deliveryString(r.Delivery), r.Reachable)
+	}
+	return w.Flush()
+}
+
+func (o *CliOptions) description() (description, error) {
+	broker := o.Config.Context
+	b, err := tmbroker.New(broker, o.Config.Triggermesh.Broker)
+	if err != nil {
+		return description{}, fmt.Errorf("broker object: %w", err)
+	}
+	d := description{
+		Name:    broker,
+		Image:   b.(*tmbroker.Broker).Image(),
+		Backend: o.backend(),
+		Routes:  []route{},
+	}
+	if container, err := b.(triggermesh.Runnable).Info(context.Background()); err == nil && container.Online {
+		d.Online = true
+		d.IngestURL = o.Config.URLs().URL(urls.Host, container.HostPort())
+		if port, limited := ingest.Port(context.Background(), broker); limited {
+			d.IngestURL = o.Config.URLs().URL(urls.Host, port)
+		}
+	}
+	if limits, set := o.Config.Triggermesh.Broker.Limits[broker]; set && !limits.Empty() {
+		d.Limits = &limits
+	}
+
+	raw, err := os.ReadFile(tmbroker.ConfigPath(o.Config.ConfigHome, broker))
+	if err != nil {
+		return description{}, fmt.Errorf("broker config: %w", err)
+	}
+	if d.Config, err = kyaml.YAMLToJSON(raw); err != nil {
+		return description{}, fmt.Errorf("broker config: %w", err)
+	}
+	configuration, err := tmbroker.ReadConfig(o.Config.ConfigHome, broker)
+	if err != nil {
+		return description{}, fmt.Errorf("broker config: %w", err)
+	}
+
+	dlq := make(map[string]struct{})
+	for name, trigger := range configuration.Triggers {
+		r := route{
+			Trigger:     name,
+			Filters:     trigger.Filters,
+			DataFilter:  o.dataFilter(name),
+			Destination: o.destination(name, trigger.Target),
+			URL:         trigger.Target.TargetURL(),
+			ContentMode: contentMode(trigger.Target),
+			Delivery:    trigger.Target.DeliveryOptions,
+			Reachable:   reachable(hostDestination(trigger.Target)),
+		}
+		if r.Delivery != nil && r.Delivery.DeadLetterURL != nil && *r.Delivery.DeadLetterURL != "" {
+			dlq[*r.Delivery.DeadLetterURL] = struct{}{}
+		}
+		d.Routes = append(d.Routes, r)
+	}
+	sort.Slice(d.Routes, func(i, j int) bool {
+		return d.Routes[i].Trigger < d.Routes[j].Trigger
+	})
+	for u := range dlq {
+		d.DeadLetterURLs = append(d.DeadLetterURLs, u)
+	}
+	sort.Strings(d.DeadLetterURLs)
+	return d, nil
+}
+
+func (o *CliOptions) backend() string {
+	switch {
+	case o.Config.Triggermesh.Broker.Redis != nil:
+		return fmt.Sprintf("redis (%s)", o.Config.Triggermesh.Broker.Redis.Address)
+	case o.Config.Triggermesh.Broker.Memory != nil:
+		return "memory"
+	}
+	return "unknown"
+}
+
+// dataFilter returns the payload expression of the trigger
+// delivering the events to the data filter function.
+func (o *CliOptions) dataFilter(trigger string) string {
+	for _, object := range o.Manifest.Objects {
+		if object.Kind == tmbroker.TriggerKind && object.Metadata.Name == trigger {
+			return object.Metadata.Annotations[triggermesh.DataFilterAnnotation]
+		}
+	}
+	return ""
+}
+
+// destination returns the name of the component receiving the trigger events.
+// Broker config may have only the URL, then the manifest trigger is used.
+func (o *CliOptions) destination(trigger string, target tmbroker.LocalTarget) string {
+	if target.Component != "" {
+		return target.Component
+	}
+	for _, object := range o.Manifest.Objects {
+		if object.Kind != tmbroker.TriggerKind || object.Metadata.Name != trigger {
+			continue
+		}
+		if t, ok := object.Spec["target"].(map[string]interface{}); ok {
+			if ref, ok := t["ref"].(map[string]interface{}); ok {
+				if name, ok := ref["name"].(string); ok {
+					return name
+				}
+			}
+		}
+	}
+	return "-"
+}
+
+// hostDestination returns the destination address as seen from the host.
+// Components addressed by their names in the context network
+// are reached through the published port of their containers.
+// contentMode returns the content mode of the deliveries to the target.
+func contentMode(target tmbroker.LocalTarget) string {
+	if target.ContentMode == "" {
+		return tmbroker.ContentModeBinary
+	}
+	return target.ContentMode
+}
+
+func hostDestination(target tmbroker.LocalTarget) string {
+	address := target.TargetURL()
+	u, err := url.Parse(address)
+	if err != nil || target.Component == "" || u.Hostname() != target.Component {
+		return address
+	}
+	client, err := docker.NewClient()
+	if err != nil {
+		return address
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), lookupTimeout)
+	defer cancel()
+	container, err := (&docker.Container{Name: target.Component}).LookupHostConfig(ctx, client)
+	if err != nil {
+		return address
+	}
+	port := container.PublishedPort(nat.Port(triggermesh.ContainerPort + "/tcp"))
+	if port == "" {
+		return address
+	}
+	u.Host = urls.New("").Address(urls.Host, port)
+	return u.String()
+}
+
+// reachable checks if the destination accepts TCP connections.
+// Addresses of the docker host are checked on the host.
+func reachable(destination string) bool {
+	u, err := url.Parse(destination)
+	if err != nil || u.Host == "" {
+		return false
+	}
+	port := u.Port()
+	if port == "" {
+		port = "80"
+		if u.Scheme == "https" {
+			port = "443"
+		}
+	}
+	address := urls.New("").HostAddress(net.JoinHostPort(u.Hostname(), port))
+	conn, err := net.DialTimeout("tcp", address, reachabilityTimeout)
+	if err != nil {
+		return false
+	}
+	conn.Close()
+	return true
+}
+
+func valueOrNone(value string) string {
+	if value == "" {
+		return "none"
+	}
+	return value
+}
+
+func filtersString(filters []eventingbroker.Filter) string {
+	if len(filters) == 0 {
+		return "*"
+	}
+	data, err := json.Marshal(filters)
+	if err != nil {
+		return "?"
+	}
+	return string(data)
+}
+
+func deliveryString(delivery *eventingbroker.DeliveryOptions) string {
+	if delivery == nil {
+		return "-"
+	}
+	var options []string
+	if delivery.Retry != nil {
+		options = append(options, fmt.Sprintf("retry=%d", *delivery.Retry))
+	}
+	if delivery.BackoffPolicy != nil {
+		options = append(options, fmt.Sprintf("backoff=%s", *delivery.BackoffPolicy))
+	}
+	if delivery.BackoffDelay != nil {
+		options = append(options, fmt.Sprintf("delay=%s", *delivery.BackoffDelay))
+	}
+	if delivery.DeadLetterURL != nil {
+		options = append(options, fmt.Sprintf("dlq=%s", *delivery.DeadLetterURL))
+	}
+	if len(options) == 0 {
+		return "-"
+	}
+	return strings.Join(options, ",")
+}
@@ -0,0 +1,186 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/log"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+func (o *CliOptions) newHistoryCmd() *cobra.Command {
+	var last int
+	historyCmd := &cobra.Command{
+		Use:   "history [--last <n>]",
+		Short: "Show the timeline of the broker trigger changes",
+		Long: `Show the timeline of the broker trigger changes.
+Every change of the broker configuration is recorded as the revision with
+the command that made it. The timeline lists the triggers added, removed
+and changed by each revision with the diff of the trigger spec.`,
+		Example: `tmctl broker history
+
+tmctl broker history --last 5`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.history(last)
+		},
+	}
+	historyCmd.Flags().IntVar(&last, "last", 0, "Show only the specified number of the latest revisions")
+	return historyCmd
+}
+
+func (o *CliOptions) newRollbackCmd() *cobra.Command {
+	var revision int
+	var force bool
+	rollbackCmd := &cobra.Command{
+		Use:   "rollback --to <revision> [--force]",
+		Short: "Restore the broker configuration revision",
+		Long: `Restore the broker configuration revision.
+The running broker reloads the restored configuration and the trigger objects
+of the manifest are updated to match it. Triggers that deliver events to the
+components that no longer exist are not restored unless --force is set.`,
+		Example: "tmctl broker rollback --to 3",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.rollback(revision, force)
+		},
+	}
+	rollbackCmd.Flags().IntVar(&revision, "to", 0, "Revision to restore, see \"tmctl broker history\"")
+	rollbackCmd.Flags().BoolVar(&force, "force", false, "Restore the triggers with the missing destination components")
+	cobra.CheckErr(rollbackCmd.MarkFlagRequired("to"))
+	return rollbackCmd
+}
+
+func (o *CliOptions) history(last int) error {
+	revisions, err := tmbroker.History(o.Config.ConfigHome, o.Config.Context)
+	if err != nil {
+		return err
+	}
+	if len(revisions) == 0 {
+		fmt.Printf("Broker %q configuration has no recorded changes\n", o.Config.Context)
+		return nil
+	}
+	from := 0
+	if last > 0 && len(revisions) > last {
+		from = len(revisions) - last
+	}
+	for i := from; i < len(revisions); i++ {
+		revision := revisions[i]
+		command := revision.Command
+		switch {
+		case i == 0:
+			command = "initial configuration"
+		case command == "":
+			command = "unknown command"
+		}
+		if i != from {
+			fmt.Println()
+		}
+		fmt.Printf("Revision %d\t%s\t%s\n", revision.Number, revision.Time.Format("2006-01-02 15:04:05"), command)
+		if i == 0 {
+			continue
+		}
+		changes, err := tmbroker.Changes(revisions[i-1].Config, revision.Config)
+		if err != nil {
+			return fmt.Errorf("revision %d: %w", revision.Number, err)
+		}
+		if len(changes) == 0 {
+			fmt.Println("  no trigger changes")
+		}
+		for _, change := range changes {
+			fmt.Printf("  %s trigger %q\n", change.Type, change.Trigger)
+			for _, line := range strings.Split(strings.TrimRight(change.Diff, "\n"), "\n") {
+				if line != "" {
+					fmt.Printf("    %s\n", line)
+				}
+			}
+		}
+	}
+	return nil
+}
+
+func (o *CliOptions) rollback(number int, force bool) error {
+	revision, err := tmbroker.ReadRevision(o.Config.ConfigHome, o.Config.Context, number)
+	if err != nil {
+		return err
+	}
+	current, err := tmbroker.ReadConfig(o.Config.ConfigHome, o.Config.Context)
+	if err != nil {
+		return fmt.Errorf("broker config: %w", err)
+	}
+	changes, err := tmbroker.Changes(current, revision.Config)
+	if err != nil {
+		return err
+	}
+	if len(changes) == 0 {
+		fmt.Printf("Broker %q configuration already matches revision %d\n", o.Config.Context, number)
+		return nil
+	}
+
+	destinations := make(map[string]struct{})
+	for _, name := range tmbroker.Destinations(o.Manifest.Objects) {
+		destinations[name] = struct{}{}
+	}
+	var missing []string
+	for _, change := range changes {
+		if change.Type == tmbroker.TriggerRemoved {
+			continue
+		}
+		component := revision.Config.Triggers[change.Trigger].Target.Component
+		if _, exists := destinations[component]; component != "" && !exists {
+			missing = append(missing, fmt.Sprintf("%s (%s)", change.Trigger, component))
+		}
+	}
+	sort.Strings(missing)
+	if len(missing) != 0 && !force {
+		return fmt.Errorf("revision %d restores the triggers with missing destinations: %s, use --force to restore them anyway",
+			number, strings.Join(missing, ", "))
+	}
+
+	if _, err := tmbroker.Rollback(o.Config.ConfigHome, o.Config.Context, number); err != nil {
+		return fmt.Errorf("rollback: %w", err)
+	}
+	for _, change := range changes {
+		if change.Type == tmbroker.TriggerRemoved {
+			if err := o.Manifest.Remove(change.Trigger, tmbroker.TriggerKind); err != nil {
+				return fmt.Errorf("removing trigger %q from manifest: %w", change.Trigger, err)
+			}
+			log.Printf("Trigger %q removed", change.Trigger)
+			continue
+		}
+		trigger, err := tmbroker.ManifestTrigger(change.Trigger, o.Config.Context, o.Config.ConfigHome,
+			revision.Config.Triggers[change.Trigger], o.Manifest.Objects)
+		if err != nil {
+			log.Printf("WARNING! Trigger %q is restored in the broker config only: %v", change.Trigger, err)
+			continue
+		}
+		if _, err := o.Manifest.Apply(trigger); err != nil {
+			return fmt.Errorf("updating trigger %q in manifest: %w", change.Trigger, err)
+		}
+		log.Printf("Trigger %q %s", change.Trigger, change.Type)
+	}
+	log.Printf("Broker %q configuration is restored to revision %d", o.Config.Context, number)
+	return nil
+}
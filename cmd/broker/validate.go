@@ -0,0 +1,122 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/prompt"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+func (o *CliOptions) newValidateCmd() *cobra.Command {
+	var fix, pruneUnknown bool
+	validateCmd := &cobra.Command{
+		Use:   "validate [--fix][--prune-unknown]",
+		Short: "Validate the broker configuration",
+		Long: `Validate the broker configuration.
+Reports the triggers with the unknown destinations, invalid destination URLs
+and malformed filters. The same validation runs before the broker is started.`,
+		Example: `tmctl broker validate
+
+tmctl broker validate --prune-unknown`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.validate(fix, pruneUnknown)
+		},
+	}
+	validateCmd.Flags().BoolVar(&fix, "fix", false, "Interactively remove the triggers with problems")
+	validateCmd.Flags().BoolVar(&pruneUnknown, "prune-unknown", false, "Remove the triggers with unknown destinations without asking")
+	return validateCmd
+}
+
+func (o *CliOptions) validate(fix, pruneUnknown bool) error {
+	path := tmbroker.ConfigPath(o.Config.ConfigHome, o.Config.Context)
+	problems, err := tmbroker.ValidateConfig(path, tmbroker.Destinations(o.Manifest.Objects))
+	if err != nil {
+		return fmt.Errorf("broker config: %w", err)
+	}
+	if len(problems) == 0 {
+		fmt.Printf("Broker %q configuration is valid\n", o.Config.Context)
+		return nil
+	}
+	for _, p := range problems {
+		fmt.Println(p)
+	}
+	if !fix && !pruneUnknown {
+		return fmt.Errorf("broker config has %d problem(s)", len(problems))
+	}
+
+	var remove []string
+	removed := make(map[string]struct{})
+	for _, p := range problems {
+		if p.Trigger == "" {
+			// the file cannot be parsed, nothing to fix per trigger
+			return fmt.Errorf("broker config cannot be fixed automatically, edit %s", path)
+		}
+		if _, done := removed[p.Trigger]; done {
+			continue
+		}
+		switch {
+		case pruneUnknown && p.UnknownDestination:
+		case fix:
+			ok, err := prompt.Confirm(fmt.Sprintf("Remove trigger %q (%s)?", p.Trigger, p.Message))
+			if err != nil {
+				return err
+			}
+			if !ok {
+				continue
+			}
+		default:
+			continue
+		}
+		removed[p.Trigger] = struct{}{}
+		remove = append(remove, p.Trigger)
+	}
+	if len(remove) == 0 {
+		return fmt.Errorf("broker config has %d problem(s)", len(problems))
+	}
+	if err := tmbroker.RemoveTriggers(path, remove); err != nil {
+		return fmt.Errorf("removing triggers: %w", err)
+	}
+	for _, trigger := range remove {
+		if err := o.Manifest.Remove(trigger, tmbroker.TriggerKind); err != nil {
+			return fmt.Errorf("removing trigger %q from manifest: %w", trigger, err)
+		}
+		log.Printf("Removed trigger %q", trigger)
+	}
+	if remaining := len(problems) - countProblems(problems, removed); remaining != 0 {
+		return fmt.Errorf("broker config has %d problem(s) left", remaining)
+	}
+	return nil
+}
+
+func countProblems(problems []tmbroker.Problem, triggers map[string]struct{}) int {
+	count := 0
+	for _, p := range problems {
+		if _, exists := triggers[p.Trigger]; exists {
+			count++
+		}
+	}
+	return count
+}
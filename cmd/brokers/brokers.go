@@ -24,6 +24,7 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/triggermesh/tmctl/pkg/completion"
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 )
@@ -58,7 +59,7 @@ func NewCmd(config *config.Config) *cobra.Command {
 		if err != nil {
 			return []string{}, cobra.ShellCompDirectiveNoFileComp
 		}
-		return list, cobra.ShellCompDirectiveNoFileComp
+		return completion.DescribeBrokers(list, completion.ContainerStatus()), cobra.ShellCompDirectiveNoFileComp
 	}))
 	return brokersCmd
 }
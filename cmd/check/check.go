@@ -0,0 +1,158 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package check
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/selftest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/urls"
+	"github.com/triggermesh/tmctl/pkg/wiretap"
+)
+
+const (
+	successColorCode = "\033[92m"
+	defaultColorCode = "\033[39m"
+	failColorCode    = "\033[31m"
+
+	checkEventType   = "io.triggermesh.tmctl.check"
+	checkEventSource = "tmctl-check"
+)
+
+type CliOptions struct {
+	Config *config.Config
+
+	Timeout time.Duration
+}
+
+func NewCmd(config *config.Config) *cobra.Command {
+	o := &CliOptions{
+		Config: config,
+	}
+	checkCmd := &cobra.Command{
+		Use:   "check [broker][--timeout <duration>]",
+		Short: "Verify local installation by delivering the test event through the broker",
+		Long: `Verify local installation by delivering the test event through the broker.
+Temporary trigger and event receiver are removed once the check is finished,
+the broker manifest is not changed. Non-zero exit code means that the check failed.`,
+		Example: "tmctl check --timeout 30s",
+		Args:    cobra.RangeArgs(0, 1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			return []string{}, cobra.ShellCompDirectiveNoFileComp
+		},
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				o.Config.Context = args[0]
+			}
+			if o.Config.Context == "" {
+				return fmt.Errorf("broker is not selected")
+			}
+			if err := docker.CheckDaemon(); err != nil {
+				return err
+			}
+			return o.check()
+		},
+	}
+	checkCmd.Flags().DurationVar(&o.Timeout, "timeout", 30*time.Second, "Event delivery timeout")
+	return checkCmd
+}
+
+func (o *CliOptions) check() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	// broker reachable
+	broker, err := tmbroker.New(o.Config.Context, o.Config.Triggermesh.Broker)
+	if err != nil {
+		return fail("broker reachable", err, nil)
+	}
+	container, err := broker.(triggermesh.Runnable).Info(ctx)
+	if err != nil || !container.Online {
+		return fail("broker reachable", fmt.Errorf("broker %q is not running", o.Config.Context), nil)
+	}
+	brokerURL := o.Config.URLs().URL(urls.Host, container.HostPort())
+	pass("broker reachable", brokerURL)
+
+	// trigger registered
+	w, err := wiretap.New(o.Config.Context, o.Config.ConfigHome)
+	if err != nil {
+		return fail("trigger registered", err, nil)
+	}
+	w.Name = fmt.Sprintf("%s-check", o.Config.Context)
+	eventType := fmt.Sprintf("%s.%d", checkEventType, time.Now().UnixNano())
+	w.Filters = []eventingbroker.Filter{*tmbroker.FilterAttribute("type", eventType)}
+	events, err := w.Listen(ctx)
+	if err != nil {
+		return fail("trigger registered", err, nil)
+	}
+	// trigger is removed on any exit path
+	defer func() {
+		if err := w.Cleanup(context.Background()); err != nil {
+			log.Printf("Cleanup: %v", err)
+		}
+	}()
+	if err := w.CreateTrigger(); err != nil {
+		return fail("trigger registered", err, nil)
+	}
+	triggers, err := tmbroker.GetTargetTriggers(w.Name, o.Config.Context, o.Config.ConfigHome)
+	if err != nil || len(triggers) == 0 {
+		return fail("trigger registered", fmt.Errorf("trigger %q is not found in broker config", w.Name), nil)
+	}
+	pass("trigger registered", w.Name)
+
+	// delivery observed
+	start := time.Now()
+	probe := selftest.Probe{
+		EventType: eventType,
+		Source:    checkEventSource,
+		Timeout:   o.Timeout,
+		Interval:  2 * time.Second,
+	}
+	id, err := probe.Deliver(ctx, brokerURL, events)
+	if err != nil {
+		return fail("delivery observed", err, selftest.Logs(ctx, broker.(triggermesh.Runnable), start))
+	}
+	pass("delivery observed", fmt.Sprintf("event %s in %s", id, time.Since(start).Round(time.Millisecond)))
+	return nil
+}
+
+func pass(phase, details string) {
+	fmt.Printf("%sPASS%s\t%s (%s)\n", successColorCode, defaultColorCode, phase, details)
+}
+
+func fail(phase string, err error, logs []string) error {
+	fmt.Printf("%sFAIL%s\t%s: %v\n", failColorCode, defaultColorCode, phase, err)
+	if len(logs) != 0 {
+		fmt.Println("Broker logs:")
+		for _, line := range logs {
+			fmt.Printf("  %s\n", line)
+		}
+	}
+	return fmt.Errorf("check failed")
+}
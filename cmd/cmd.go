@@ -17,30 +17,61 @@ limitations under the License.
 package cmd
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 	"github.com/spf13/cobra/doc"
 
+	"github.com/triggermesh/tmctl/cmd/adopt"
+	"github.com/triggermesh/tmctl/cmd/broker"
 	"github.com/triggermesh/tmctl/cmd/brokers"
+	"github.com/triggermesh/tmctl/cmd/check"
 	"github.com/triggermesh/tmctl/cmd/config"
+	crdcmd "github.com/triggermesh/tmctl/cmd/crd"
 	"github.com/triggermesh/tmctl/cmd/create"
 	"github.com/triggermesh/tmctl/cmd/delete"
+	"github.com/triggermesh/tmctl/cmd/deliveries"
 	"github.com/triggermesh/tmctl/cmd/describe"
+	"github.com/triggermesh/tmctl/cmd/diff"
 	"github.com/triggermesh/tmctl/cmd/dump"
+	"github.com/triggermesh/tmctl/cmd/explainroute"
+	"github.com/triggermesh/tmctl/cmd/export"
+	"github.com/triggermesh/tmctl/cmd/expose"
+	"github.com/triggermesh/tmctl/cmd/filter"
+	"github.com/triggermesh/tmctl/cmd/gc"
+	"github.com/triggermesh/tmctl/cmd/get"
 	import_ "github.com/triggermesh/tmctl/cmd/import"
+	"github.com/triggermesh/tmctl/cmd/link"
+	"github.com/triggermesh/tmctl/cmd/lint"
 	"github.com/triggermesh/tmctl/cmd/logs"
+	"github.com/triggermesh/tmctl/cmd/migrate"
+	"github.com/triggermesh/tmctl/cmd/pin"
+	"github.com/triggermesh/tmctl/cmd/record"
+	"github.com/triggermesh/tmctl/cmd/scale"
+	"github.com/triggermesh/tmctl/cmd/schema"
+	"github.com/triggermesh/tmctl/cmd/secret"
 	"github.com/triggermesh/tmctl/cmd/sendevent"
+	"github.com/triggermesh/tmctl/cmd/set"
 	"github.com/triggermesh/tmctl/cmd/start"
+	"github.com/triggermesh/tmctl/cmd/stats"
 	"github.com/triggermesh/tmctl/cmd/stop"
+	"github.com/triggermesh/tmctl/cmd/template"
+	"github.com/triggermesh/tmctl/cmd/validate"
 	"github.com/triggermesh/tmctl/cmd/version"
 	"github.com/triggermesh/tmctl/cmd/watch"
 
 	cliconfig "github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/crashlog"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
 	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 )
 
@@ -56,32 +87,122 @@ Find more information at: https://docs.triggermesh.io`,
 
 	c, err := cliconfig.New()
 	cobra.CheckErr(err)
-	crds, err := crd.Fetch(c.ConfigHome, c.Triggermesh.ComponentsVersion)
-	cobra.CheckErr(err)
+	// the CRDs are loaded into the shared map right before the commands
+	// that need them run, the shell completion reads the CRD index only
+	crds := make(map[string]crd.CRD)
+	loadCRD := func() error {
+		if len(crds) != 0 {
+			return nil
+		}
+		fetched, err := crd.Fetch(c.ConfigHome, c.Triggermesh.ComponentsVersion)
+		if err != nil {
+			return err
+		}
+		for kind, definition := range fetched {
+			crds[kind] = definition
+		}
+		return nil
+	}
+	withCRD := func(cmd *cobra.Command) *cobra.Command {
+		lazyCRD(cmd, loadCRD)
+		return cmd
+	}
 
+	command := manifest.RedactCommand(os.Args)
 	manifest := manifest.New(filepath.Join(
 		c.ConfigHome,
 		c.Context,
 		triggermesh.ManifestFile))
+	manifest.Version = ver
+	manifest.Command = command
 	_ = manifest.Read()
+	tmbroker.SetHistoryCommand(command)
 
+	// without arguments, show the components overview after the help text
+	rootCmd.RunE = func(cmd *cobra.Command, args []string) error {
+		if err := cmd.Help(); err != nil {
+			return err
+		}
+		if len(manifest.Objects) == 0 {
+			return nil
+		}
+		if err := loadCRD(); err != nil {
+			return err
+		}
+		fmt.Printf("\nBroker %q components:\n", c.Context)
+		return (&get.CliOptions{Config: c, Manifest: manifest, CRD: crds}).Get("")
+	}
+
+	rootCmd.AddCommand(adopt.NewCmd(c, manifest))
+	rootCmd.AddCommand(broker.NewCmd(c, manifest))
 	rootCmd.AddCommand(brokers.NewCmd(c))
-	rootCmd.AddCommand(create.NewCmd(c, manifest, crds))
-	rootCmd.AddCommand(config.NewCmd())
-	rootCmd.AddCommand(delete.NewCmd(c, manifest, crds))
-	rootCmd.AddCommand(describe.NewCmd(c, manifest, crds))
-	rootCmd.AddCommand(dump.NewCmd(c, manifest, crds))
-	rootCmd.AddCommand(import_.NewCmd(c, crds))
-	rootCmd.AddCommand(logs.NewCmd(c, manifest, crds))
-	rootCmd.AddCommand(sendevent.NewCmd(c, manifest, crds))
-	rootCmd.AddCommand(start.NewCmd(c, manifest, crds))
+	rootCmd.AddCommand(check.NewCmd(c))
+	rootCmd.AddCommand(withCRD(create.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(crdcmd.NewCmd(c))
+	rootCmd.AddCommand(config.NewCmd(c))
+	rootCmd.AddCommand(withCRD(delete.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(deliveries.NewCmd(c, manifest))
+	rootCmd.AddCommand(withCRD(describe.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(withCRD(diff.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(withCRD(dump.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(withCRD(explainroute.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(export.NewCmd(c, manifest))
+	rootCmd.AddCommand(withCRD(expose.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(filter.NewCmd(c, manifest))
+	rootCmd.AddCommand(gc.NewCmd(c))
+	rootCmd.AddCommand(withCRD(get.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(withCRD(import_.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(link.NewCmd(c))
+	rootCmd.AddCommand(withCRD(lint.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(withCRD(logs.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(migrate.NewCmd(c, manifest))
+	rootCmd.AddCommand(withCRD(pin.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(record.NewCmd(c))
+	rootCmd.AddCommand(withCRD(scale.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(schema.NewCmd(c, manifest))
+	rootCmd.AddCommand(secret.NewCmd(c, manifest))
+	rootCmd.AddCommand(withCRD(sendevent.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(withCRD(set.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(withCRD(start.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(withCRD(stats.NewCmd(c, manifest, crds)))
 	rootCmd.AddCommand(stop.NewCmd(c, manifest))
-	rootCmd.AddCommand(watch.NewCmd(c))
+	rootCmd.AddCommand(withCRD(template.NewCmd(c, manifest, crds)))
+	rootCmd.AddCommand(withCRD(validate.NewCmd(crds)))
+	rootCmd.AddCommand(withCRD(watch.NewCmd(c, manifest, crds)))
 	rootCmd.AddCommand(version.NewCmd(ver, commit, c))
 
 	rootCmd.PersistentFlags().StringVar(&c.Triggermesh.ComponentsVersion, "version", c.Triggermesh.ComponentsVersion, "TriggerMesh components version.")
 	cobra.CheckErr(rootCmd.RegisterFlagCompletionFunc("version", cobra.NoFileCompletions))
 
+	rootCmd.PersistentFlags().BoolVar(&c.StrictKinds, "strict-kinds", false,
+		"Fail instead of skipping the manifest objects of the kinds unknown to the CRD set.")
+	rootCmd.PersistentFlags().StringVar(&c.OnNameConflict, "on-name-conflict", docker.NameConflictFail,
+		fmt.Sprintf("Action when the component container name is taken by the container not created by tmctl or created in another context (%s).",
+			strings.Join(docker.NameConflictPolicies, "|")))
+	cobra.CheckErr(rootCmd.RegisterFlagCompletionFunc("on-name-conflict", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return docker.NameConflictPolicies, cobra.ShellCompDirectiveNoFileComp
+	}))
+
+	var eventsLog string
+	rootCmd.PersistentFlags().StringVar(&eventsLog, "events-log", os.Getenv(lifecycle.EnvEventsLog),
+		"Append JSON lifecycle events to the file path or the file descriptor number.")
+	// initializers run after the flags are parsed, regardless of the subcommand hooks
+	cobra.OnInitialize(func() {
+		cobra.CheckErr(lifecycle.Open(eventsLog))
+		// the context is resolved on each lookup since some commands switch it
+		cobra.CheckErr(docker.ManageNames(c.ConfigHome, func() string { return c.Context }, c.OnNameConflict))
+	})
+	// output of the exited containers is kept for "tmctl logs --previous",
+	// the context is resolved on exit since some commands switch it
+	docker.OnExit(func(container string, exited time.Time, output []byte) {
+		dir := filepath.Join(c.ConfigHome, c.Context, crashlog.Dir)
+		if _, err := crashlog.Save(dir, container, exited, output); err != nil {
+			log.Printf("WARNING! Saving %q logs: %v", container, err)
+		}
+	})
+
+	recoverPanics(rootCmd)
+
 	if os.Getenv("TMCTL_GENERATE_DOCS") == "true" {
 		rootCmd.DisableAutoGenTag = true
 		if err := doc.GenMarkdownTree(rootCmd, "./docs"); err != nil {
@@ -89,5 +210,56 @@ Find more information at: https://docs.triggermesh.io`,
 		}
 		os.Exit(0)
 	}
+
+	// explicit flags are parsed later and take precedence over the defaults
+	config.ApplyDefaults(rootCmd, c)
 	return rootCmd
 }
+
+// lazyCRD wraps the commands of the subtree to load the CRDs before they run.
+// Commands fetching the CRDs of the version in their arguments are skipped.
+func lazyCRD(cmd *cobra.Command, load func() error) {
+	if runE := cmd.RunE; runE != nil && cmd.Annotations[crd.FetchedByCommand] == "" {
+		cmd.RunE = func(c *cobra.Command, args []string) error {
+			if err := load(); err != nil {
+				return err
+			}
+			return runE(c, args)
+		}
+	}
+	if run := cmd.Run; run != nil && cmd.Annotations[crd.FetchedByCommand] == "" {
+		cmd.Run = func(c *cobra.Command, args []string) {
+			cobra.CheckErr(load())
+			run(c, args)
+		}
+	}
+	for _, subCmd := range cmd.Commands() {
+		lazyCRD(subCmd, load)
+	}
+}
+
+// recoverPanics wraps the execution of the command and its subcommands
+// to convert panics into errors, so that the failure goes through
+// the regular error handling instead of crashing the process.
+func recoverPanics(cmd *cobra.Command) {
+	if run := cmd.Run; run != nil && cmd.RunE == nil {
+		cmd.RunE = func(c *cobra.Command, args []string) error {
+			run(c, args)
+			return nil
+		}
+		cmd.Run = nil
+	}
+	if runE := cmd.RunE; runE != nil {
+		cmd.RunE = func(c *cobra.Command, args []string) (err error) {
+			defer func() {
+				if r := recover(); r != nil {
+					err = fmt.Errorf("%s: unexpected failure: %v", c.CommandPath(), r)
+				}
+			}()
+			return runE(c, args)
+		}
+	}
+	for _, subCmd := range cmd.Commands() {
+		recoverPanics(subCmd)
+	}
+}
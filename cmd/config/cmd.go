@@ -24,9 +24,9 @@ import (
 	cliconfig "github.com/triggermesh/tmctl/pkg/config"
 )
 
-func NewCmd() *cobra.Command {
+func NewCmd(c *cliconfig.Config) *cobra.Command {
 	configCmd := &cobra.Command{
-		Use:   "config [set|get]",
+		Use:   "config [set|get|set-default|unset-default|view]",
 		Short: "Read and write config values",
 		Run: func(cmd *cobra.Command, args []string) {
 			cmd.HelpFunc()(cmd, args)
@@ -34,6 +34,9 @@ func NewCmd() *cobra.Command {
 	}
 	configCmd.AddCommand(getCmd())
 	configCmd.AddCommand(setCmd())
+	configCmd.AddCommand(setDefaultCmd())
+	configCmd.AddCommand(unsetDefaultCmd())
+	configCmd.AddCommand(viewCmd(c))
 	return configCmd
 }
 
@@ -57,12 +60,65 @@ func getCmd() *cobra.Command {
 	}
 }
 
+const (
+	brokerImageKey = "broker-image"
+	internalTLSKey = "internal-tls"
+	caBundleKey    = "ca-bundle"
+	outputTypeKey  = "output-type-template"
+	triggerNameKey = "trigger-name-template"
+	strictSpecKey  = "strict-spec"
+)
+
 func setCmd() *cobra.Command {
 	return &cobra.Command{
 		Use:   "set <key> <value>",
 		Short: "Write config value",
-		Args:  cobra.ExactArgs(2),
+		Example: `tmctl config set triggermesh.version v1.25.0
+
+tmctl config set broker-image ghcr.io/corp/broker:dev
+
+tmctl config set max-payload-size 1MB
+
+tmctl config set ingest-rate-limit 500/s
+
+tmctl config set internal-tls true
+
+tmctl config set strict-spec true
+
+tmctl config set advertise-address events.example.com
+
+tmctl config set ca-bundle /path/to/ca.pem
+
+tmctl config set output-type-template 'corp.events.{{.Name}}.v1'
+
+tmctl config set trigger-name-template '{{.Target}}-{{.FilterHash}}'`,
+		Args: cobra.ExactArgs(2),
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if args[0] == brokerImageKey {
+				return cliconfig.SetBrokerImage(args[1])
+			}
+			if args[0] == cliconfig.MaxPayloadSizeKey || args[0] == cliconfig.IngestRateLimitKey {
+				if err := cliconfig.SetBrokerLimit(args[0], args[1]); err != nil {
+					return err
+				}
+				fmt.Println("Run \"tmctl start --restart\" to apply the broker limits")
+				return nil
+			}
+			if args[0] == internalTLSKey {
+				return cliconfig.SetInternalTLS(args[1])
+			}
+			if args[0] == strictSpecKey {
+				return cliconfig.SetStrictSpec(args[1])
+			}
+			if args[0] == caBundleKey {
+				return cliconfig.SetCABundle(args[1])
+			}
+			if args[0] == outputTypeKey {
+				return cliconfig.SetOutputTypeTemplate(args[1])
+			}
+			if args[0] == triggerNameKey {
+				return cliconfig.SetTriggerNameTemplate(args[1])
+			}
 			return cliconfig.Set(args[0], args[1])
 		},
 	}
@@ -0,0 +1,204 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"encoding/csv"
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
+
+	cliconfig "github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
+)
+
+// globalPath is the path of the root command persistent flags.
+const globalPath = "global"
+
+// ApplyDefaults replaces the default values of the command flags with
+// the ones stored in the config. The flags are not marked as changed
+// so that the values given on the command line take precedence.
+func ApplyDefaults(root *cobra.Command, c *cliconfig.Config) {
+	for _, d := range c.FlagDefaults() {
+		flag, err := lookupFlag(root, d.Key)
+		if err == nil {
+			err = setDefault(flag, d.Value)
+		}
+		if err != nil {
+			log.Printf("WARNING! Skipping default of %q: %v", d.Key, err)
+		}
+	}
+}
+
+// lookupFlag returns the flag of the "<command path>.<flag>" key.
+func lookupFlag(root *cobra.Command, key string) (*pflag.Flag, error) {
+	path := strings.Split(key, ".")
+	if len(path) < 2 {
+		return nil, fmt.Errorf("flag path %q must be <command>.<flag>, e.g. %s.version or create.source.timeout", key, globalPath)
+	}
+	name := path[len(path)-1]
+	if name == "help" {
+		return nil, fmt.Errorf("help flag has no default")
+	}
+	if len(path) == 2 && path[0] == globalPath {
+		if flag := root.PersistentFlags().Lookup(name); flag != nil {
+			return flag, nil
+		}
+		return nil, fmt.Errorf("unknown global flag %q", name)
+	}
+	cmd := root
+	for _, command := range path[:len(path)-1] {
+		var sub *cobra.Command
+		for _, c := range cmd.Commands() {
+			if c.Name() == command {
+				sub = c
+				break
+			}
+		}
+		if sub == nil {
+			return nil, fmt.Errorf("unknown command %q", strings.TrimSpace(cmd.CommandPath()+" "+command))
+		}
+		cmd = sub
+	}
+	if flag := cmd.LocalFlags().Lookup(name); flag != nil {
+		return flag, nil
+	}
+	// the inherited flags are shared with the other subcommands of the parent
+	for parent := cmd.Parent(); parent != nil; parent = parent.Parent() {
+		if parent.PersistentFlags().Lookup(name) == nil {
+			continue
+		}
+		if parent == root {
+			return nil, fmt.Errorf("flag %q is global, use %s.%s", name, globalPath, name)
+		}
+		return nil, fmt.Errorf("flag %q is inherited from %q, use %s", name, parent.CommandPath(),
+			strings.Join(append(strings.Fields(parent.CommandPath())[1:], name), "."))
+	}
+	return nil, fmt.Errorf("unknown flag %q of %q", name, cmd.CommandPath())
+}
+
+// setDefault sets the flag value and the default shown in the help.
+func setDefault(flag *pflag.Flag, value string) error {
+	// Set of the slice flags appends to the values that are already set,
+	// the default must be replaced by the values given on the command line
+	if slice, ok := flag.Value.(pflag.SliceValue); ok {
+		var values []string
+		if value != "" {
+			var err error
+			if values, err = csv.NewReader(strings.NewReader(value)).Read(); err != nil {
+				return fmt.Errorf("invalid value %q of --%s: %w", value, flag.Name, err)
+			}
+		}
+		if err := slice.Replace(values); err != nil {
+			return fmt.Errorf("invalid value %q of --%s: %w", value, flag.Name, err)
+		}
+	} else if err := flag.Value.Set(value); err != nil {
+		return fmt.Errorf("invalid value %q of --%s: %w", value, flag.Name, err)
+	}
+	flag.DefValue = flag.Value.String()
+	return nil
+}
+
+func setDefaultCmd() *cobra.Command {
+	var allContexts bool
+	setDefaultCmd := &cobra.Command{
+		Use:   "set-default <command>.<flag> <value>",
+		Short: "Set the default value of the command flag",
+		Long: `Set the default value of the command flag in the current context.
+
+The flag path is the command names followed by the flag name,
+the persistent flags of tmctl have the "global" path.
+The flags given on the command line take precedence over the defaults.`,
+		Example: `tmctl config set-default create.transformation.target logger
+
+tmctl config set-default create.timeout 10s
+
+tmctl config set-default global.version v1.25.0 --all-contexts`,
+		Args: cobra.ExactArgs(2),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			flag, err := lookupFlag(cmd.Root(), args[0])
+			if err != nil {
+				return err
+			}
+			if err := setDefault(flag, args[1]); err != nil {
+				return err
+			}
+			return cliconfig.SetFlagDefault(scope(allContexts), args[0], args[1])
+		},
+	}
+	setDefaultCmd.Flags().BoolVar(&allContexts, "all-contexts", false, "Set the default for all contexts")
+	return setDefaultCmd
+}
+
+func unsetDefaultCmd() *cobra.Command {
+	var allContexts bool
+	unsetDefaultCmd := &cobra.Command{
+		Use:     "unset-default <command>.<flag>",
+		Short:   "Restore the built-in default value of the command flag",
+		Example: "tmctl config unset-default create.transformation.target",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return cliconfig.UnsetFlagDefault(scope(allContexts), args[0])
+		},
+	}
+	unsetDefaultCmd.Flags().BoolVar(&allContexts, "all-contexts", false, "Remove the default shared by all contexts")
+	return unsetDefaultCmd
+}
+
+func viewCmd(c *cliconfig.Config) *cobra.Command {
+	return &cobra.Command{
+		Use:   "view",
+		Short: "Show the config and the effective flag defaults",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			config, err := cliconfig.Get("")
+			if err != nil {
+				return err
+			}
+			fmt.Print(config)
+			defaults := c.FlagDefaults()
+			if len(defaults) == 0 {
+				return nil
+			}
+			fmt.Println()
+			w := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
+			fmt.Fprintln(w, "Flag\tDefault\tOrigin")
+			for _, d := range defaults {
+				origin := fmt.Sprintf("context %q", d.Scope)
+				if d.Scope == cliconfig.AllContexts {
+					origin = "all contexts"
+				}
+				if _, err := lookupFlag(cmd.Root(), d.Key); err != nil {
+					origin += ", ignored: " + err.Error()
+				}
+				fmt.Fprintf(w, "%s\t%s\t%s\n", d.Key, d.Value, origin)
+			}
+			return w.Flush()
+		},
+	}
+}
+
+func scope(allContexts bool) string {
+	if allContexts {
+		return cliconfig.AllContexts
+	}
+	return ""
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
+	tmcrd "github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+type CliOptions struct {
+	Config *config.Config
+}
+
+func NewCmd(config *config.Config) *cobra.Command {
+	o := &CliOptions{
+		Config: config,
+	}
+	crdCmd := &cobra.Command{
+		Use:   "crd",
+		Short: "Manage the cached TriggerMesh CRDs",
+	}
+	crdCmd.AddCommand(o.newRefreshCmd())
+	return crdCmd
+}
+
+func (o *CliOptions) newRefreshCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "refresh",
+		Short: "Download the CRDs of the configured TriggerMesh version",
+		Long: `Download the CRDs of the configured TriggerMesh version.
+Replaces the cached CRDs, including the definitions embedded into the binary
+that are used when the CRDs cannot be downloaded.`,
+		Example: `tmctl crd refresh
+
+tmctl crd refresh --version v1.25.0`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			version := o.Config.Triggermesh.ComponentsVersion
+			crds, err := tmcrd.Refresh(o.Config.ConfigHome, version)
+			if err != nil {
+				return fmt.Errorf("refreshing %s CRDs: %w", version, err)
+			}
+			log.Printf("Cached %d %s CRDs", len(crds), version)
+			return nil
+		},
+	}
+}
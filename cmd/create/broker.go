@@ -24,30 +24,48 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/output"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/ingest"
+	"github.com/triggermesh/tmctl/pkg/urls"
 )
 
 func (o *CliOptions) newBrokerCmd() *cobra.Command {
-	var version string
+	var version, image string
+	var limits config.IngestLimits
 	brokerCmd := &cobra.Command{
-		Use:               "broker <name>",
-		Short:             "Create TriggerMesh Broker. More information at https://docs.triggermesh.io/brokers/",
-		Example:           "tmctl create broker foo",
+		Use:   "broker <name>",
+		Short: "Create TriggerMesh Broker. More information at https://docs.triggermesh.io/brokers/",
+		Example: `tmctl create broker foo --broker-image ghcr.io/corp/broker:dev
+
+tmctl create broker foo --max-payload-size 1MB --ingest-rate-limit 500/s`,
 		Args:              cobra.MinimumNArgs(1),
 		ValidArgsFunction: cobra.NoFileCompletions,
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return o.broker(args[0], version)
+			if err := limits.Validate(); err != nil {
+				return err
+			}
+			return o.broker(args[0], version, image, limits)
 		},
 	}
 	brokerCmd.Flags().StringVar(&version, "version", o.Config.Triggermesh.Broker.Version, "TriggerMesh broker version.")
+	brokerCmd.Flags().StringVar(&image, "broker-image", "", "Custom broker image, overrides the version")
+	brokerCmd.Flags().StringVar(&limits.MaxPayloadSize, config.MaxPayloadSizeKey, "", "Largest event accepted by the broker, e.g. \"1MB\"")
+	brokerCmd.Flags().StringVar(&limits.RateLimit, config.IngestRateLimitKey, "", "Events per second or minute accepted by the broker, e.g. \"500/s\"")
+	cobra.CheckErr(brokerCmd.RegisterFlagCompletionFunc(config.MaxPayloadSizeKey, cobra.NoFileCompletions))
+	cobra.CheckErr(brokerCmd.RegisterFlagCompletionFunc(config.IngestRateLimitKey, cobra.NoFileCompletions))
 	return brokerCmd
 }
 
-func (o *CliOptions) broker(name, version string) error {
+func (o *CliOptions) broker(name, version, image string, limits config.IngestLimits) error {
 	ctx := context.Background()
+	if err := triggermesh.ValidateName(name); err != nil {
+		return err
+	}
 	o.Manifest.Path = filepath.Join(o.Config.ConfigHome, name, triggermesh.ManifestFile)
 	if _, err := os.Stat(o.Manifest.Path); !os.IsNotExist(err) {
 		return fmt.Errorf("broker %q already exists", name)
@@ -57,6 +75,13 @@ func (o *CliOptions) broker(name, version string) error {
 		return fmt.Errorf("creating broker config: %w", err)
 	}
 
+	if image != "" {
+		o.Config.Triggermesh.Broker.SetImage(name, image)
+		for _, warning := range tmbroker.CompatibilityWarnings(image) {
+			log.Printf("WARNING! %s", warning)
+		}
+	}
+	o.Config.Triggermesh.Broker.SetLimits(name, limits)
 	brokerConfig := o.Config.Triggermesh.Broker
 	brokerConfig.Version = version
 
@@ -70,10 +95,11 @@ func (o *CliOptions) broker(name, version string) error {
 	}
 
 	log.Println("Updating manifest")
-	restart, err := o.Manifest.Add(broker)
+	applied, err := o.Manifest.Apply(broker)
 	if err != nil {
 		return fmt.Errorf("unable to update manifest: %w", err)
 	}
+	restart := applied != manifest.Unchanged
 
 	o.Config.Context = name
 	if err := o.Config.Save(); err != nil {
@@ -81,9 +107,18 @@ func (o *CliOptions) broker(name, version string) error {
 	}
 
 	log.Println("Starting container")
-	if _, err := broker.(triggermesh.Runnable).Start(ctx, nil, restart); err != nil {
+	container, err := broker.(triggermesh.Runnable).Start(ctx, nil, restart)
+	if err != nil {
 		return err
 	}
+	if !limits.Empty() {
+		log.Println("Starting ingest proxy")
+		proxy, err := ingest.Start(ctx, o.Config.ConfigHome, name, container, limits)
+		if err != nil {
+			return err
+		}
+		log.Printf("Broker ingest is limited at %s", o.Config.URLs().URL(urls.Host, proxy.HostPort()))
+	}
 
 	output.PrintStatus("broker", broker, []string{}, []string{})
 	return nil
@@ -17,25 +17,119 @@ limitations under the License.
 package create
 
 import (
+	"context"
 	"fmt"
+	"strconv"
 	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
 
+	"github.com/triggermesh/tmctl/pkg/bridge"
 	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/credentials"
 	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
+	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
-	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/logging"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/signature"
 )
 
 type CliOptions struct {
 	Config   *config.Config
 	Manifest *manifest.Manifest
 	CRD      map[string]crd.CRD
+
+	// logLevel is the requested adapter logging level,
+	// empty value keeps the level of the existing component.
+	logLevel string
+
+	// adapterVersion pins the adapter image version of the component,
+	// empty value keeps the pinned version or follows the context version.
+	adapterVersion string
+
+	// awsCredentials are resolved from the AWS SDK chain on request.
+	awsCredentials     *credentials.AWS
+	refreshCredentials bool
+
+	// timeout limits the Docker lookups of the components.
+	timeout time.Duration
+
+	// eventTypesFrom selects the event types of the sources filter:
+	// declared by the component or observed at the broker.
+	eventTypesFrom string
+
+	// cutover orders the trigger changes when the transformation
+	// is inserted before a live target.
+	cutover string
+
+	// decrypt is the decryption mode of the SOPS encrypted --from files.
+	decrypt string
+
+	// outputType is the event type produced by the transformations,
+	// it takes precedence over the output type template of the context.
+	outputType string
+
+	// targetPath is appended to the address of the target component
+	// in the triggers created for it.
+	targetPath string
+
+	// dryRun prints the changes of the command without applying them.
+	dryRun bool
+
+	// replyTo is the consumer of the target reply events
+	// of the replyEventType type.
+	replyTo        string
+	replyEventType string
+
+	// verifyDestination probes the target of the created triggers,
+	// strictDestination fails the creation if the probe fails.
+	verifyDestination bool
+	strictDestination bool
+
+	// triggerName names the single trigger created by the command
+	// instead of the trigger name template of the context.
+	triggerName string
+
+	// contentMode is the CloudEvents content mode of the created triggers.
+	contentMode string
+
+	// signature is the request signature verification of the webhook
+	// source, signatureSecret is the key the requests are signed with.
+	signature       *signature.Verification
+	signatureSecret string
+
+	// watchKind records the kinds of the sources filter on the transformation
+	// so that "tmctl start" subscribes it to the sources created later.
+	watchKind bool
+	// noEditor reads the typed transformation spec in the terminal
+	// instead of opening it in $EDITOR.
+	noEditor bool
+
+	// strictSpec fails the creation on the spec fields unknown to the
+	// component schema instead of warning, overrides the strict-spec config.
+	strictSpec *bool
+
+	// sourceTargets are the consumers of the created source events.
+	sourceTargets []string
+
+	// keepPartial keeps the changes of the failed command in place
+	// instead of rolling them back.
+	keepPartial bool
+	// transaction records the changes of the running command.
+	transaction *bridge.Transaction
 }
 
+const defaultTimeout = bridge.DefaultTimeout
+
+const (
+	eventTypesDeclared = bridge.EventTypesDeclared
+	eventTypesObserved = bridge.EventTypesObserved
+)
+
 func NewCmd(config *config.Config, manifest *manifest.Manifest, crds map[string]crd.CRD) *cobra.Command {
 	o := &CliOptions{
 		CRD:      crds,
@@ -47,21 +141,123 @@ func NewCmd(config *config.Config, manifest *manifest.Manifest, crds map[string]
 		Short: "Create TriggerMesh component",
 		// CompletionOptions: cobra.CompletionOptions{DisableDescriptions: true},
 		Args: cobra.MinimumNArgs(1),
-		PersistentPreRun: func(cmd *cobra.Command, _ []string) {
-			cobra.CheckErr(docker.CheckDaemon())
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := o.lookup("checking docker daemon", docker.CheckDaemonContext); err != nil {
+				return err
+			}
 			if cmd.Name() != "broker" {
-				cobra.CheckErr(o.Manifest.Read())
+				if err := o.Manifest.Read(); err != nil {
+					return fmt.Errorf("reading manifest: %w", err)
+				}
 			}
+			return nil
 		},
 	}
+	createCmd.PersistentFlags().DurationVar(&o.timeout, "timeout", defaultTimeout, "Time to wait for the Docker daemon replies on the components lookup")
 	createCmd.AddCommand(o.newBrokerCmd())
 	createCmd.AddCommand(o.newSourceCmd())
 	createCmd.AddCommand(o.newTargetCmd())
 	createCmd.AddCommand(o.newTransformationCmd())
+	createCmd.AddCommand(o.newSynchronizerCmd())
 	createCmd.AddCommand(o.newTriggerCmd())
+	createCmd.AddCommand(o.newFromTemplateCmd())
 	return createCmd
 }
 
+// fetchCRD loads the CRDs of the components version
+// that the command arguments may have changed.
+func (o *CliOptions) fetchCRD() error {
+	crds, err := crd.Fetch(o.Config.ConfigHome, o.Config.Triggermesh.ComponentsVersion)
+	if err != nil {
+		return err
+	}
+	o.CRD = crds
+	return nil
+}
+
+// builder returns the bridge builder configured with the command options.
+func (o *CliOptions) builder() *bridge.Builder {
+	return &bridge.Builder{
+		Config:         o.Config,
+		Manifest:       o.Manifest,
+		CRD:            o.CRD,
+		Timeout:        o.timeout,
+		LogLevel:       o.logLevel,
+		AdapterVersion: o.adapterVersion,
+		EventTypesFrom: o.eventTypesFrom,
+		DryRun:         o.dryRun,
+
+		VerifyDestination: o.verifyDestination,
+		Strict:            o.strictDestination,
+		TriggerName:       o.triggerName,
+		ContentMode:       o.contentMode,
+		Transaction:       o.transaction,
+		Progress: func(message string) {
+			log.Println(message)
+		},
+	}
+}
+
+// transact runs the create command recording its changes. The changes
+// applied before the failure are rolled back or, with --keep-partial, kept
+// and listed along with the commands to undo them.
+func (o *CliOptions) transact(create func() error) error {
+	if o.dryRun {
+		return create()
+	}
+	o.transaction = &bridge.Transaction{}
+	defer func() {
+		o.transaction = nil
+	}()
+	return o.transaction.Finish(create(), o.keepPartial)
+}
+
+// keepPartialParam reads the --keep-partial flag of the commands
+// parsing their flags as the component parameters.
+func (o *CliOptions) keepPartialParam(params map[string]string) error {
+	value, exists := params["keep-partial"]
+	if !exists {
+		return nil
+	}
+	delete(params, "keep-partial")
+	o.keepPartial = true
+	if value != "" {
+		var err error
+		if o.keepPartial, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("keep-partial: %w", err)
+		}
+	}
+	return nil
+}
+
+// lookup runs the Docker request of the command phase with the configured
+// timeout. The phase is named in the error to show where the command stopped.
+func (o *CliOptions) lookup(phase string, request func(context.Context) error) error {
+	return o.builder().Lookup(phase, request)
+}
+
+// brokerURL returns the URL the components send the events to:
+// the broker ingest proxy, if any, or the broker container.
+func (o *CliOptions) brokerURL() (string, error) {
+	return o.builder().BrokerURL()
+}
+
+// timeoutParam extracts the lookup timeout for the commands
+// that parse their arguments themselves.
+func (o *CliOptions) timeoutParam(params map[string]string) error {
+	value, exists := params["timeout"]
+	if !exists {
+		return nil
+	}
+	delete(params, "timeout")
+	timeout, err := time.ParseDuration(value)
+	if err != nil {
+		return fmt.Errorf("timeout: %w", err)
+	}
+	o.timeout = timeout
+	return nil
+}
+
 func argsToMap(args []string) map[string]string {
 	result := make(map[string]string)
 	for k := 0; k < len(args); k++ {
@@ -89,20 +285,101 @@ func isFlag(s string) bool {
 }
 
 func (o *CliOptions) translateEventSource(eventSourcesFilter []string) ([]string, error) {
-	var result []string
-	for _, source := range eventSourcesFilter {
-		s, err := components.GetObject(source, o.Config, o.Manifest, o.CRD)
-		if err != nil {
-			return nil, fmt.Errorf("%q event producer object: %w", source, err)
-		}
-		if _, ok := s.(triggermesh.Producer); !ok {
-			return nil, fmt.Errorf("%q is not an event producer", source)
-		}
-		et, err := s.(triggermesh.Producer).GetEventTypes()
-		if err != nil {
-			return nil, fmt.Errorf("%q event source: %w", source, err)
-		}
-		result = append(result, et...)
+	return o.builder().SourcesEventTypes(eventSourcesFilter)
+}
+
+// validateEventTypesFrom checks the origin of the sources event types.
+func (o *CliOptions) validateEventTypesFrom() error {
+	switch o.eventTypesFrom {
+	case eventTypesDeclared, eventTypesObserved:
+		return nil
+	}
+	return fmt.Errorf("unknown event types origin %q, must be %q or %q", o.eventTypesFrom, eventTypesDeclared, eventTypesObserved)
+}
+
+// validateCutover checks the trigger cutover strategy.
+func (o *CliOptions) validateCutover() error {
+	switch o.cutover {
+	case bridge.CutoverAddNewFirst, bridge.CutoverDropOldFirst:
+		return nil
 	}
-	return result, nil
+	return fmt.Errorf("unknown cutover %q, must be %q or %q", o.cutover, bridge.CutoverAddNewFirst, bridge.CutoverDropOldFirst)
+}
+
+// outputEventType returns the event type produced by the transformation
+// and its origin: the --event-type flag or the output type template.
+func (o *CliOptions) outputEventType(name, kind string) (string, string, error) {
+	return o.builder().OutputEventType(name, kind, o.outputType)
+}
+
+// validateName checks the user-defined component name,
+// empty name is generated by the component.
+func (o *CliOptions) validateName(name string) error {
+	if name == "" {
+		return nil
+	}
+	return triggermesh.ValidateComponentName(name, o.Config.Context)
+}
+
+// eventsLogParam opens the lifecycle events log for the commands
+// that parse their arguments themselves.
+func eventsLogParam(params map[string]string) error {
+	destination, exists := params["events-log"]
+	if !exists {
+		return nil
+	}
+	delete(params, "events-log")
+	return lifecycle.Open(destination)
+}
+
+// logLevelParam extracts the logging level from the component parameters.
+func (o *CliOptions) logLevelParam(params map[string]string) error {
+	level, exists := params["log-level"]
+	if !exists {
+		return nil
+	}
+	delete(params, "log-level")
+	if err := logging.Validate(level); err != nil {
+		return err
+	}
+	o.logLevel = level
+	return nil
+}
+
+// triggerNameParam extracts the name of the trigger created by the command.
+func (o *CliOptions) triggerNameParam(params map[string]string) error {
+	name, exists := params["trigger-name"]
+	if !exists {
+		return nil
+	}
+	delete(params, "trigger-name")
+	o.triggerName = name
+	return o.validateName(name)
+}
+
+// adapterVersionParam extracts the pinned adapter version from the component parameters.
+func (o *CliOptions) adapterVersionParam(params map[string]string) error {
+	version, exists := params["adapter-version"]
+	if !exists {
+		return nil
+	}
+	delete(params, "adapter-version")
+	if version == "" {
+		return fmt.Errorf("adapter version must not be empty")
+	}
+	o.adapterVersion = version
+	return nil
+}
+
+// pinVersion records the requested adapter version in the component annotation.
+func (o *CliOptions) pinVersion(c triggermesh.Component) error {
+	return o.builder().PinVersion(c)
+}
+
+// loggingEnv records the requested logging level in the component annotation
+// and adds the logger configuration to the container environment.
+// The returned flag is true if the level has changed and the container
+// must be restarted to apply it.
+func (o *CliOptions) loggingEnv(c triggermesh.Component, env map[string]string) (map[string]string, bool, error) {
+	return o.builder().LoggingEnv(c, env)
 }
@@ -31,7 +31,7 @@ import (
 
 func (o *CliOptions) sourcesCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	if len(args) == 0 {
-		sources, err := crd.ListSources(o.CRD)
+		sources, err := o.listKinds("sources.triggermesh.io", "source")
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
@@ -53,17 +53,25 @@ func (o *CliOptions) sourcesCompletion(cmd *cobra.Command, args []string, toComp
 		}
 	}
 
+	if args[0] == fileSinkKind {
+		return []string{"--path", "--format", "--max-file-size"}, cobra.ShellCompDirectiveNoFileComp
+	}
+
 	prefix := ""
 	toComplete = strings.TrimLeft(toComplete, "-")
 	var properties map[string]crd.Property
 
-	crd, exists := o.CRD[args[0]+"source"]
-	if !exists {
+	kind := args[0] + "source"
+	index := completion.Index(o.Config)
+	if index == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if _, exists := index.Kinds[kind]; !exists {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
 	if !strings.Contains(toComplete, ".") {
-		_, properties = completion.SpecFromCRD(crd)
+		_, properties = index.Spec(kind)
 		if property, exists := properties[toComplete]; exists {
 			if property.Typ == "object" {
 				return []string{"--" + toComplete + "."}, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
@@ -72,7 +80,7 @@ func (o *CliOptions) sourcesCompletion(cmd *cobra.Command, args []string, toComp
 		}
 	} else {
 		path := strings.Split(toComplete, ".")
-		exists, nestedProperties := completion.SpecFromCRD(crd, path...)
+		exists, nestedProperties := index.Spec(kind, path...)
 		if len(nestedProperties) != 0 {
 			prefix = toComplete
 			if !strings.HasSuffix(prefix, ".") && prefix != "--" {
@@ -82,7 +90,7 @@ func (o *CliOptions) sourcesCompletion(cmd *cobra.Command, args []string, toComp
 		} else if exists {
 			return []string{"--" + toComplete}, cobra.ShellCompDirectiveNoFileComp
 		} else {
-			_, properties = completion.SpecFromCRD(crd, path[:len(path)-1]...)
+			_, properties = index.Spec(kind, path[:len(path)-1]...)
 			prefix = strings.Join(path[:len(path)-1], ".") + "."
 		}
 	}
@@ -96,24 +104,46 @@ func (o *CliOptions) sourcesCompletion(cmd *cobra.Command, args []string, toComp
 		name = prefix + name
 		spec = append(spec, fmt.Sprintf("--%s\t(%s) %s", name, attr, property.Description))
 	}
-	return append(spec, "--name\tOptional component name."), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	spec = append(spec, credentialsFlags(args[0])...)
+	return append(spec,
+		"--name\tOptional component name.",
+		"--log-level\tAdapter logging level.",
+		"--keep-partial\tKeep the changes of the failed command instead of rolling them back.",
+	), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+}
+
+// credentialsFlags returns the cloud credentials helper flags of the kind.
+func credentialsFlags(kind string) []string {
+	switch {
+	case strings.HasPrefix(kind, "aws"):
+		return []string{
+			"--credentials-profile\tAWS profile to resolve the credentials from.",
+			"--use-aws-credentials\tResolve the credentials through the AWS SDK default chain.",
+			"--refresh-credentials\tResolve the credentials again on every start.",
+		}
+	case strings.HasPrefix(kind, "googlecloud"):
+		return []string{"--gcp-service-account\tService account key file."}
+	case strings.HasPrefix(kind, "azure"):
+		return []string{"--azure-env\tRead the service principal from AZURE_* environment variables."}
+	}
+	return nil
 }
 
 func (o *CliOptions) targetsCompletion(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
 	if len(args) == 0 {
-		targets, err := crd.ListTargets(o.CRD)
+		targets, err := o.listKinds("targets.triggermesh.io", "target")
 		if err != nil {
 			return nil, cobra.ShellCompDirectiveNoFileComp
 		}
-		return append(targets, "--from-image"), cobra.ShellCompDirectiveNoFileComp
+		return append(targets, "function", fileSinkKind, "--from-image"), cobra.ShellCompDirectiveNoFileComp
 	}
 
 	if lastParam(args) == "--source" && strings.HasSuffix(args[len(args)-1], ",") {
-		return completion.ListSources(o.Manifest), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+		return completion.Components(o.Manifest, completion.ListSources(o.Manifest)), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
 	}
 
 	if lastParam(args) == "--eventTypes" && strings.HasSuffix(args[len(args)-1], ",") {
-		return completion.ListEventTypes(o.Manifest, o.Config, o.CRD),
+		return completion.ListEventTypesWithOrigin(o.Manifest, o.Config, completion.Index(o.Config)),
 			cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
 	}
 
@@ -125,10 +155,12 @@ func (o *CliOptions) targetsCompletion(cmd *cobra.Command, args []string, toComp
 	}
 	switch args[len(args)-1] {
 	case "--source":
-		return completion.ListSources(o.Manifest), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+		return completion.Components(o.Manifest, completion.ListSources(o.Manifest)), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
 	case "--eventTypes":
-		return completion.ListEventTypes(o.Manifest, o.Config, o.CRD),
+		return completion.ListEventTypesWithOrigin(o.Manifest, o.Config, completion.Index(o.Config)),
 			cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	case "--reply-to":
+		return completion.Components(o.Manifest, completion.ListTargets(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
 	}
 	if strings.HasPrefix(args[len(args)-1], "--") {
 		return []string{}, cobra.ShellCompDirectiveNoFileComp
@@ -138,13 +170,20 @@ func (o *CliOptions) targetsCompletion(cmd *cobra.Command, args []string, toComp
 	toComplete = strings.TrimLeft(toComplete, "-")
 	var properties map[string]crd.Property
 
-	crd, exists := o.CRD[args[0]+"target"]
-	if !exists {
+	kind := args[0] + "target"
+	if args[0] == "function" {
+		kind = "function"
+	}
+	index := completion.Index(o.Config)
+	if index == nil {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	if _, exists := index.Kinds[kind]; !exists {
 		return nil, cobra.ShellCompDirectiveNoFileComp
 	}
 
 	if !strings.Contains(toComplete, ".") {
-		_, properties = completion.SpecFromCRD(crd)
+		_, properties = index.Spec(kind)
 		if property, exists := properties[toComplete]; exists {
 			if property.Typ == "object" {
 				return []string{"--" + toComplete + "."}, cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
@@ -153,7 +192,7 @@ func (o *CliOptions) targetsCompletion(cmd *cobra.Command, args []string, toComp
 		}
 	} else {
 		path := strings.Split(toComplete, ".")
-		exists, nestedProperties := completion.SpecFromCRD(crd, path...)
+		exists, nestedProperties := index.Spec(kind, path...)
 		if len(nestedProperties) != 0 {
 			prefix = toComplete
 			if !strings.HasSuffix(prefix, ".") && prefix != "--" {
@@ -163,7 +202,7 @@ func (o *CliOptions) targetsCompletion(cmd *cobra.Command, args []string, toComp
 		} else if exists {
 			return []string{"--" + toComplete}, cobra.ShellCompDirectiveNoFileComp
 		} else {
-			_, properties = completion.SpecFromCRD(crd, path[:len(path)-1]...)
+			_, properties = index.Spec(kind, path[:len(path)-1]...)
 			prefix = strings.Join(path[:len(path)-1], ".") + "."
 		}
 	}
@@ -177,13 +216,24 @@ func (o *CliOptions) targetsCompletion(cmd *cobra.Command, args []string, toComp
 		name = prefix + name
 		spec = append(spec, fmt.Sprintf("--%s\t(%s) %s", name, attr, property.Description))
 	}
+	spec = append(spec, credentialsFlags(args[0])...)
 	return append(spec,
 		"--source\tEvent source name.",
 		"--eventTypes\tEvent types filter.",
 		"--name\tOptional component name.",
+		"--log-level\tAdapter logging level.",
 	), cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
 }
 
+// listKinds returns the kinds of the API group from the CRD index.
+func (o *CliOptions) listKinds(group, suffix string) ([]string, error) {
+	index := completion.Index(o.Config)
+	if index == nil {
+		return nil, fmt.Errorf("CRD index of %s is not available", o.Config.Triggermesh.ComponentsVersion)
+	}
+	return index.ListGroup(group, suffix), nil
+}
+
 func lastParam(args []string) string {
 	for i := len(args) - 1; i >= 0; i-- {
 		if strings.HasPrefix(args[i], "--") {
@@ -0,0 +1,131 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"encoding/base64"
+	"fmt"
+
+	"github.com/triggermesh/tmctl/pkg/credentials"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+// credentialsParams resolves the cloud credentials requested with the
+// --credentials-profile, --use-aws-credentials, --gcp-service-account or
+// --azure-env parameters and sets them in the component parameters
+// according to the spec of the component CRD.
+func (o *CliOptions) credentialsParams(crdKind string, params map[string]string) error {
+	profile, withProfile := params["credentials-profile"]
+	_, useAWS := params["use-aws-credentials"]
+	_, refresh := params["refresh-credentials"]
+	serviceAccount, useGCP := params["gcp-service-account"]
+	_, useAzure := params["azure-env"]
+	for _, key := range []string{"credentials-profile", "use-aws-credentials", "refresh-credentials", "gcp-service-account", "azure-env"} {
+		delete(params, key)
+	}
+	useAWS = useAWS || withProfile
+
+	requested := 0
+	for _, use := range []bool{useAWS, useGCP, useAzure} {
+		if use {
+			requested++
+		}
+	}
+	switch {
+	case requested > 1:
+		return fmt.Errorf("only one of AWS, GCP or Azure credentials can be requested")
+	case refresh && !useAWS:
+		return fmt.Errorf("--refresh-credentials requires --credentials-profile or --use-aws-credentials")
+	case requested == 0:
+		return nil
+	}
+
+	var provider string
+	var values map[string]string
+	var err error
+	switch {
+	case useAWS:
+		provider = credentials.ProviderAWS
+		if o.awsCredentials, err = credentials.ResolveAWS(profile); err != nil {
+			return err
+		}
+		o.refreshCredentials = refresh
+		values = o.awsCredentials.Values()
+	case useGCP:
+		provider = credentials.ProviderGCP
+		if values, err = credentials.GCPServiceAccount(serviceAccount); err != nil {
+			return err
+		}
+	case useAzure:
+		provider = credentials.ProviderAzure
+		if values, err = credentials.AzureEnv(); err != nil {
+			return err
+		}
+	}
+
+	crd, exists := o.CRD[crdKind]
+	if !exists {
+		return fmt.Errorf("CRD for kind %q not found", crdKind)
+	}
+	resolved, err := credentials.Params(provider, crd, values)
+	if err != nil {
+		return err
+	}
+	for k, v := range resolved {
+		params[k] = v
+	}
+	return nil
+}
+
+// credentialsSecrets stores the resolved credentials that have no spec
+// fields, e.g. the session token, in the component secret.
+func (o *CliOptions) credentialsSecrets(c triggermesh.Component, secrets []triggermesh.Component, env map[string]string) {
+	if o.awsCredentials == nil {
+		return
+	}
+	data := make(map[string]interface{})
+	for k, v := range o.awsCredentials.SecretData() {
+		data[k] = base64.StdEncoding.EncodeToString([]byte(v))
+		env[k] = v
+	}
+	for _, secret := range secrets {
+		if secret.GetName() == c.GetName()+"-secret" {
+			secret.SetSpec(data)
+		}
+	}
+}
+
+// annotateCredentials records the origin of the resolved credentials.
+func (o *CliOptions) annotateCredentials(c triggermesh.Component) error {
+	if o.awsCredentials == nil {
+		return nil
+	}
+	refresh := ""
+	if o.refreshCredentials {
+		refresh = "true"
+	}
+	for key, value := range map[string]string{
+		triggermesh.CredentialsProfileAnnotation: o.awsCredentials.Profile,
+		triggermesh.CredentialsExpiryAnnotation:  o.awsCredentials.Expiry(),
+		triggermesh.CredentialsRefreshAnnotation: refresh,
+	} {
+		if err := o.Manifest.Annotate(c.GetName(), c.GetKind(), key, value); err != nil {
+			return fmt.Errorf("unable to update manifest: %w", err)
+		}
+	}
+	return nil
+}
@@ -0,0 +1,108 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"context"
+	"fmt"
+	"path/filepath"
+
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/output"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/filesink"
+)
+
+// fileSinkKind is the target kind of the file sink pseudo-target.
+const fileSinkKind = "file"
+
+func (o *CliOptions) fileSink(name string, params map[string]string, eventSourcesFilter, eventTypesFilter []string) error {
+	ctx := context.Background()
+
+	path, exists := params["path"]
+	if !exists {
+		return fmt.Errorf("--path is required")
+	}
+	// the directory is mounted into the sink container, relative paths
+	// would be resolved against the directory of the later commands
+	path, err := filepath.Abs(path)
+	if err != nil {
+		return fmt.Errorf("events directory: %w", err)
+	}
+	spec := map[string]interface{}{
+		"path":   path,
+		"format": filesink.FormatJSONL,
+	}
+	if format, exists := params["format"]; exists {
+		spec["format"] = format
+	}
+	if size, exists := params["max-file-size"]; exists {
+		spec["maxFileSize"] = size
+	}
+	for key := range params {
+		switch key {
+		case "path", "format", "max-file-size", "disable-file-args":
+		default:
+			return fmt.Errorf("unknown file target parameter %q", key)
+		}
+	}
+	if err := filesink.Validate(spec); err != nil {
+		return err
+	}
+
+	et, err := o.translateEventSource(eventSourcesFilter)
+	if err != nil {
+		return err
+	}
+	eventTypesFilter = append(eventTypesFilter, et...)
+
+	if err := o.builder().CheckTriggerName(len(eventTypesFilter)); err != nil {
+		return err
+	}
+	f := filesink.New(name, o.Config.Context, spec)
+
+	log.Println("Updating manifest")
+	applied, err := o.Manifest.Apply(f)
+	if err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	restart := applied != manifest.Unchanged
+
+	log.Println("Starting container")
+	if _, err := f.(triggermesh.Runnable).Start(ctx, nil, restart); err != nil {
+		return err
+	}
+
+	// update our triggers in case of sink container restart
+	if restart {
+		if err := o.updateTriggers(f); err != nil {
+			return err
+		}
+	}
+
+	for _, et := range eventTypesFilter {
+		if _, err := o.createTrigger("", f, tmbroker.FilterAttribute("type", et)); err != nil {
+			return fmt.Errorf("creating trigger: %w", err)
+		}
+	}
+
+	output.PrintStatus("consumer", f, eventSourcesFilter, eventTypesFilter)
+	fmt.Printf("Events are written to %s\n", f.(*filesink.FileSink).CurrentFile())
+	return nil
+}
@@ -0,0 +1,127 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/output"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/function"
+)
+
+const defaultFunctionEntrypoint = "handler"
+
+func (o *CliOptions) function(name string, params map[string]string, eventSourcesFilter, eventTypesFilter []string) error {
+	ctx := context.Background()
+
+	runtime, exists := params["runtime"]
+	if !exists {
+		return fmt.Errorf("function runtime is required")
+	}
+	if !supportedRuntime(runtime) {
+		var runtimes []string
+		for r := range adapter.FunctionRuntimes {
+			runtimes = append(runtimes, r)
+		}
+		sort.Strings(runtimes)
+		return fmt.Errorf("unsupported function runtime %q, expected one of: %s", runtime, strings.Join(runtimes, ", "))
+	}
+	codeFile, exists := params["code"]
+	if !exists {
+		return fmt.Errorf("function code file is required")
+	}
+	code, err := os.ReadFile(codeFile)
+	if err != nil {
+		return fmt.Errorf("reading function code: %w", err)
+	}
+	params["code"] = string(code)
+	if _, exists := params["entrypoint"]; !exists {
+		params["entrypoint"] = defaultFunctionEntrypoint
+	}
+	delete(params, "disable-file-args")
+
+	et, err := o.translateEventSource(eventSourcesFilter)
+	if err != nil {
+		return err
+	}
+	eventTypesFilter = append(eventTypesFilter, et...)
+
+	if err := o.builder().CheckTriggerName(len(eventTypesFilter)); err != nil {
+		return err
+	}
+
+	crd, exists := o.CRD["function"]
+	if !exists {
+		return fmt.Errorf("CRD for kind %q not found", function.Kind)
+	}
+	f := function.New(name, o.Config.Context, o.Config.Triggermesh.ComponentsVersion, crd, params)
+	f.(*function.Function).Version = o.builder().ComponentVersion(f)
+
+	log.Println("Updating manifest")
+	applied, err := o.Manifest.Apply(f)
+	if err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	restart := applied != manifest.Unchanged
+	if err := o.pinVersion(f); err != nil {
+		return err
+	}
+	env, levelChanged, err := o.loggingEnv(f, nil)
+	if err != nil {
+		return err
+	}
+	restart = restart || levelChanged
+
+	log.Println("Starting container")
+	if _, err := f.(triggermesh.Runnable).Start(ctx, env, restart); err != nil {
+		return err
+	}
+
+	// update our triggers in case of function container restart
+	if restart {
+		if err := o.updateTriggers(f); err != nil {
+			return err
+		}
+	}
+
+	for _, et := range eventTypesFilter {
+		if _, err := o.createTrigger("", f, tmbroker.FilterAttribute("type", et)); err != nil {
+			return fmt.Errorf("creating trigger: %w", err)
+		}
+	}
+
+	output.PrintStatus("consumer", f, eventSourcesFilter, eventTypesFilter)
+	return nil
+}
+
+func supportedRuntime(runtime string) bool {
+	for r := range adapter.FunctionRuntimes {
+		if strings.Contains(strings.ToLower(runtime), r) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,99 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"fmt"
+	"os"
+	"strings"
+)
+
+var kafkaSASLMechanisms = []string{"PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512", "GSSAPI"}
+
+// kafkaParams maps the Kafka connection flags onto the nested
+// KafkaSource and KafkaTarget spec parameters.
+func kafkaParams(params map[string]string, isSource bool) error {
+	rename := map[string]string{
+		"bootstrap-servers": "bootstrapServers",
+		"sasl-user":         "auth.username",
+		"sasl-password":     "auth.password",
+	}
+	if isSource {
+		rename["group-id"] = "groupID"
+	} else if _, exists := params["group-id"]; exists {
+		return fmt.Errorf("--group-id is supported by the kafka source only")
+	}
+	for flag, key := range rename {
+		if value, exists := params[flag]; exists {
+			params[key] = value
+			delete(params, flag)
+		}
+	}
+
+	_, user := params["auth.username"]
+	_, password := params["auth.password"]
+	mechanism, sasl := params["sasl-mechanism"]
+	delete(params, "sasl-mechanism")
+	switch {
+	case sasl:
+		mechanism = strings.ToUpper(mechanism)
+		valid := false
+		for _, m := range kafkaSASLMechanisms {
+			if m == mechanism {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unsupported SASL mechanism %q, expected one of: %s", mechanism, strings.Join(kafkaSASLMechanisms, ", "))
+		}
+		if mechanism != "GSSAPI" && (!user || !password) {
+			return fmt.Errorf("SASL mechanism %q requires --sasl-user and --sasl-password", mechanism)
+		}
+		params["auth.securityMechanism"] = mechanism
+		params["auth.saslEnable"] = "true"
+	case user || password:
+		return fmt.Errorf("--sasl-user and --sasl-password require --sasl-mechanism")
+	}
+
+	tls, tlsEnabled := params["tls"]
+	delete(params, "tls")
+	if caFile, exists := params["tls-ca-file"]; exists {
+		delete(params, "tls-ca-file")
+		if tlsEnabled && tls == "false" {
+			return fmt.Errorf("--tls-ca-file cannot be used with TLS disabled")
+		}
+		ca, err := os.ReadFile(caFile)
+		if err != nil {
+			return fmt.Errorf("reading CA file: %w", err)
+		}
+		// CA is a secret field, its contents are stored in the component's
+		// secret so the manifest does not depend on the local file.
+		params["auth.tls.ca"] = string(ca)
+		tlsEnabled, tls = true, "true"
+	}
+	if tlsEnabled {
+		params["auth.tlsEnable"] = "true"
+		if tls == "false" {
+			params["auth.tlsEnable"] = "false"
+		}
+	}
+	if _, exists := params["auth.saslEnable"]; !exists && tlsEnabled {
+		params["auth.saslEnable"] = "false"
+	}
+	return nil
+}
@@ -0,0 +1,120 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestKafkaParams(t *testing.T) {
+	caFile := filepath.Join(t.TempDir(), "ca.pem")
+	require.NoError(t, os.WriteFile(caFile, []byte("-----BEGIN CERTIFICATE-----"), 0o600))
+
+	testCases := map[string]struct {
+		params   map[string]string
+		isSource bool
+		expected map[string]string
+		err      string
+	}{
+		"source connection": {
+			params:   map[string]string{"bootstrap-servers": "b-1.msk:9092", "topic": "orders", "group-id": "tmctl"},
+			isSource: true,
+			expected: map[string]string{"bootstrapServers": "b-1.msk:9092", "topic": "orders", "groupID": "tmctl"},
+		},
+		"target group id": {
+			params: map[string]string{"group-id": "tmctl"},
+			err:    "--group-id is supported by the kafka source only",
+		},
+		"sasl": {
+			params: map[string]string{"sasl-mechanism": "scram-sha-512", "sasl-user": "user", "sasl-password": "secret"},
+			expected: map[string]string{
+				"auth.username":          "user",
+				"auth.password":          "secret",
+				"auth.securityMechanism": "SCRAM-SHA-512",
+				"auth.saslEnable":        "true",
+			},
+		},
+		"gssapi without password": {
+			params: map[string]string{"sasl-mechanism": "GSSAPI"},
+			expected: map[string]string{
+				"auth.securityMechanism": "GSSAPI",
+				"auth.saslEnable":        "true",
+			},
+		},
+		"unsupported mechanism": {
+			params: map[string]string{"sasl-mechanism": "oauthbearer", "sasl-user": "user", "sasl-password": "secret"},
+			err:    `unsupported SASL mechanism "OAUTHBEARER", expected one of: PLAIN, SCRAM-SHA-256, SCRAM-SHA-512, GSSAPI`,
+		},
+		"mechanism without credentials": {
+			params: map[string]string{"sasl-mechanism": "PLAIN", "sasl-user": "user"},
+			err:    `SASL mechanism "PLAIN" requires --sasl-user and --sasl-password`,
+		},
+		"password without mechanism": {
+			params: map[string]string{"sasl-password": "secret"},
+			err:    "--sasl-user and --sasl-password require --sasl-mechanism",
+		},
+		"tls": {
+			params: map[string]string{"tls": ""},
+			expected: map[string]string{
+				"auth.tlsEnable":  "true",
+				"auth.saslEnable": "false",
+			},
+		},
+		"tls disabled": {
+			params: map[string]string{"tls": "false"},
+			expected: map[string]string{
+				"auth.tlsEnable":  "false",
+				"auth.saslEnable": "false",
+			},
+		},
+		"ca file": {
+			params: map[string]string{"tls-ca-file": caFile, "sasl-mechanism": "PLAIN", "sasl-user": "user", "sasl-password": "secret"},
+			expected: map[string]string{
+				"auth.username":          "user",
+				"auth.password":          "secret",
+				"auth.securityMechanism": "PLAIN",
+				"auth.saslEnable":        "true",
+				"auth.tls.ca":            "-----BEGIN CERTIFICATE-----",
+				"auth.tlsEnable":         "true",
+			},
+		},
+		"ca file with tls disabled": {
+			params: map[string]string{"tls-ca-file": caFile, "tls": "false"},
+			err:    "--tls-ca-file cannot be used with TLS disabled",
+		},
+		"missing ca file": {
+			params: map[string]string{"tls-ca-file": filepath.Join(filepath.Dir(caFile), "missing.pem")},
+			err:    "reading CA file: open " + filepath.Join(filepath.Dir(caFile), "missing.pem") + ": no such file or directory",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := kafkaParams(tc.params, tc.isSource)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, tc.params)
+		})
+	}
+}
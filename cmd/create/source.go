@@ -24,14 +24,20 @@ import (
 
 	"github.com/spf13/cobra"
 
+	"github.com/triggermesh/tmctl/pkg/bridge"
+	"github.com/triggermesh/tmctl/pkg/docker"
 	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/output"
+	"github.com/triggermesh/tmctl/pkg/secrets"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
-	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/source"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/pkg"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/signature"
+	"github.com/triggermesh/tmctl/pkg/urls"
 )
 
 func (o *CliOptions) newSourceCmd() *cobra.Command {
@@ -42,13 +48,39 @@ func (o *CliOptions) newSourceCmd() *cobra.Command {
 	--endpoint https://www.example.com \
 	--eventType sample-event \
 	--interval 30s  \
-	--method GET`,
+	--method GET
+
+tmctl create source kafka \
+	--bootstrap-servers broker-1:9096,broker-2:9096 \
+	--topic orders \
+	--group-id tmctl \
+	--sasl-mechanism SCRAM-SHA-512 \
+	--sasl-user user \
+	--sasl-password password \
+	--tls-ca-file ./ca.pem
+
+tmctl create source awss3 \
+	--arn arn:aws:s3:::bucket \
+	--auth.credentials.accessKeyID <access key> \
+	--auth.credentials.secretAccessKey <secret key> \
+	--target logger,processor
+
+tmctl create source webhook \
+	--eventType github.push \
+	--signature-header X-Hub-Signature-256 \
+	--signature-secret ./webhook-secret \
+	--signature-algo hmac-sha256`,
+		// the CRDs of the version in the arguments are fetched by the command
+		Annotations:        map[string]string{crd.FetchedByCommand: "true"},
 		DisableFlagParsing: true,
 		SilenceErrors:      true,
 		ValidArgsFunction:  o.sourcesCompletion,
 		// CompletionOptions:  cobra.CompletionOptions{DisableDescriptions: false},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 || args[0] == "--help" {
+				if err := o.fetchCRD(); err != nil {
+					return err
+				}
 				sources, err := crd.ListSources(o.CRD)
 				if err != nil {
 					return fmt.Errorf("list sources: %w", err)
@@ -59,21 +91,50 @@ func (o *CliOptions) newSourceCmd() *cobra.Command {
 				return nil
 			}
 			params := argsToMap(args)
+			if args[0] == "kafka" {
+				if err := kafkaParams(params, true); err != nil {
+					return err
+				}
+			}
 			var name string
 			if n, exists := params["name"]; exists {
 				name = n
 				delete(params, "name")
 			}
+			if err := o.validateName(name); err != nil {
+				return err
+			}
 			if v, exists := params["version"]; exists {
 				o.Config.Triggermesh.ComponentsVersion = v
 				delete(params, "version")
 			}
-			crd, err := crd.Fetch(o.Config.ConfigHome, o.Config.Triggermesh.ComponentsVersion)
-			if err != nil {
+			if err := o.logLevelParam(params); err != nil {
+				return err
+			}
+			if err := o.adapterVersionParam(params); err != nil {
+				return err
+			}
+			if err := eventsLogParam(params); err != nil {
+				return err
+			}
+			if err := o.timeoutParam(params); err != nil {
+				return err
+			}
+			if err := o.strictSpecParam(params); err != nil {
+				return err
+			}
+			if err := o.targetsParam(params); err != nil {
+				return err
+			}
+			if err := o.keepPartialParam(params); err != nil {
+				return err
+			}
+			if err := o.fetchCRD(); err != nil {
+				return err
+			}
+			if err := o.credentialsParams(args[0]+"source", params); err != nil {
 				return err
 			}
-			// defer crd.Close()
-			o.CRD = crd
 
 			if _, readDisabled := params["disable-file-args"]; !readDisabled {
 				for key, value := range params {
@@ -86,46 +147,62 @@ func (o *CliOptions) newSourceCmd() *cobra.Command {
 			} else {
 				delete(params, "disable-file-args")
 			}
+			if err := o.signatureParams(args[0], params); err != nil {
+				return err
+			}
 			if image, exists := params["from-image"]; exists {
+				if o.signature != nil {
+					return fmt.Errorf("signature verification is supported by the webhook source only")
+				}
 				delete(params, "from-image")
-				return o.sourceFromImage(name, image, params)
+				return o.transact(func() error {
+					return o.sourceFromImage(name, image, params)
+				})
 			}
-			return o.source(name, args[0], params)
+			return o.transact(func() error {
+				return o.source(name, args[0], pkg.ParseArgs(params))
+			})
 		},
 	}
 }
 
-func (o *CliOptions) source(name, kind string, params map[string]string) error {
+func (o *CliOptions) source(name, kind string, spec map[string]interface{}) error {
 	ctx := context.Background()
-	broker, err := tmbroker.New(o.Config.Context, o.Config.Triggermesh.Broker)
-	if err != nil {
-		return fmt.Errorf("broker object: %v", err)
-	}
-	port, err := broker.(triggermesh.Consumer).GetPort(ctx)
+	sink, err := o.brokerURL()
 	if err != nil {
-		return fmt.Errorf("broker offline: %v", err)
+		return err
 	}
-	params["sink.uri"] = "http://host.docker.internal:" + port
+	spec["sink"] = map[string]interface{}{"uri": sink}
 
 	crd, exists := o.CRD[kind+"source"]
 	if !exists {
 		return fmt.Errorf("CRD for kind %q not found", kind)
 	}
-	s := source.New(name, kind, o.Config.Context, o.Config.Triggermesh.ComponentsVersion, crd, params, nil)
+	s := source.New(name, kind, o.Config.Context, o.Config.Triggermesh.ComponentsVersion, crd, spec, nil)
+	s.(*source.Source).Version = o.builder().ComponentVersion(s)
+	if err := o.checkSpec(crd, s); err != nil {
+		return err
+	}
 
 	secrets, secretsEnv, err := components.ProcessSecrets(s.(triggermesh.Parent), o.Manifest)
 	if err != nil {
 		return fmt.Errorf("processing secrets: %v", err)
 	}
+	o.credentialsSecrets(s, secrets, secretsEnv)
 	secretsChanged := false
 
+	_, existed, err := o.Manifest.Object(s.GetName(), s.GetKind())
+	if err != nil {
+		return fmt.Errorf("manifest object: %w", err)
+	}
+
 	log.Println("Updating manifest")
 	for _, secret := range secrets {
-		dirty, err := o.Manifest.Add(secret)
+		applied, err := o.builder().Apply(secret)
 		if err != nil {
 			return fmt.Errorf("unable to write secret: %w", err)
 		}
-		if dirty {
+		if applied != manifest.Unchanged {
 			secretsChanged = true
 		}
 	}
@@ -135,42 +212,186 @@ func (o *CliOptions) source(name, kind string, params map[string]string) error {
 		return fmt.Errorf("source initialization: %w", err)
 	}
 	s.(triggermesh.Reconcilable).UpdateStatus(status)
+	if !existed {
+		o.transaction.Record(bridge.Step{
+			Description: fmt.Sprintf("initialized external resources of %q", s.GetName()),
+			Undo: func() error {
+				return s.(triggermesh.Reconcilable).Finalize(context.Background(), secretsEnv)
+			},
+			Command: fmt.Sprintf("tmctl delete source %s", s.GetName()),
+		})
+	}
 
-	restart, err := o.Manifest.Add(s)
+	applied, err := o.builder().Apply(s)
 	if err != nil {
 		return fmt.Errorf("unable to update manifest: %w", err)
 	}
+	restart := applied != manifest.Unchanged
+	if err := o.pinVersion(s); err != nil {
+		return err
+	}
+	if err := o.annotateCredentials(s); err != nil {
+		return err
+	}
+	env, levelChanged, err := o.loggingEnv(s, secretsEnv)
+	if err != nil {
+		return err
+	}
 	log.Println("Starting container")
-	if _, err := s.(triggermesh.Runnable).Start(ctx, secretsEnv, (restart || secretsChanged)); err != nil {
+	container, err := o.builder().StartContainer(ctx, s, env, (restart || secretsChanged || levelChanged))
+	if err != nil {
 		return err
 	}
+	if o.signature != nil {
+		if err := o.startVerifier(ctx, s, container); err != nil {
+			return err
+		}
+		if !existed {
+			o.transaction.Record(bridge.Step{
+				Description: fmt.Sprintf("started verifier %q", signature.ProxyName(s.GetName())),
+				Undo: func() error {
+					return signature.Remove(context.Background(), o.Config.ConfigHome, o.Config.Context, s.GetName())
+				},
+				Command: fmt.Sprintf("tmctl delete source %s", s.GetName()),
+			})
+		}
+	}
 	output.PrintStatus("producer", s, []string{}, []string{})
+	return o.fanOut(s)
+}
+
+// targetsParam extracts the consumers of the source events,
+// the --target flag accepts the comma-separated list.
+func (o *CliOptions) targetsParam(params map[string]string) error {
+	value, exists := params["target"]
+	if !exists {
+		return nil
+	}
+	delete(params, "target")
+	targets := strings.FieldsFunc(value, func(r rune) bool {
+		return r == ',' || r == ' '
+	})
+	if len(targets) == 0 {
+		return fmt.Errorf("--target requires the names of the consumers")
+	}
+	unique := make(map[string]struct{}, len(targets))
+	for _, target := range targets {
+		if _, exists := unique[target]; !exists {
+			unique[target] = struct{}{}
+			o.sourceTargets = append(o.sourceTargets, target)
+		}
+	}
 	return nil
 }
 
-func (o *CliOptions) sourceFromImage(name, image string, params map[string]string) error {
-	ctx := context.Background()
-	broker, err := tmbroker.New(o.Config.Context, o.Config.Triggermesh.Broker)
+// fanOut creates the triggers delivering the source events to
+// the --target consumers and reports the triggers of every target.
+// The targets wired before the failed one keep their triggers
+// if the command runs with --keep-partial.
+func (o *CliOptions) fanOut(source triggermesh.Component) error {
+	if len(o.sourceTargets) == 0 {
+		return nil
+	}
+	log.Println("Creating triggers")
+	fanOut, err := o.builder().AddFanOut(source, o.sourceTargets)
 	if err != nil {
-		return fmt.Errorf("broker object: %v", err)
+		return err
+	}
+	var failed []string
+	fmt.Println("Fan-out:")
+	for _, target := range fanOut {
+		if target.Err != nil {
+			failed = append(failed, target.Target)
+			fmt.Printf("\t%s: \u001b[31mfailed\033[39m(%v)\n", target.Target, target.Err)
+			if len(target.Triggers) != 0 {
+				fmt.Printf("\t%s: partially wired by %s\n", target.Target, strings.Join(target.Triggers, ", "))
+			}
+			continue
+		}
+		fmt.Printf("\t%s: %s\n", target.Target, strings.Join(target.Triggers, ", "))
+	}
+	if len(failed) != 0 {
+		return fmt.Errorf("wiring %d of %d targets failed: %s", len(failed), len(fanOut), strings.Join(failed, ", "))
+	}
+	return nil
+}
+
+// signatureParams reads the request signature verification flags
+// of the webhook source.
+func (o *CliOptions) signatureParams(kind string, params map[string]string) error {
+	header, headerSet := params["signature-header"]
+	secret, secretSet := params["signature-secret"]
+	algorithm, algorithmSet := params["signature-algo"]
+	delete(params, "signature-header")
+	delete(params, "signature-secret")
+	delete(params, "signature-algo")
+	if !headerSet && !secretSet && !algorithmSet {
+		return nil
+	}
+	if kind != "webhook" {
+		return fmt.Errorf("signature verification is supported by the webhook source only")
+	}
+	if !headerSet || !secretSet || secret == "" {
+		return fmt.Errorf("signature verification requires both --signature-header and --signature-secret")
 	}
-	port, err := broker.(triggermesh.Consumer).GetPort(ctx)
+	if !algorithmSet {
+		algorithm = signature.DefaultAlgorithm
+	}
+	v := signature.Verification{Header: header, Algorithm: algorithm}
+	if err := v.Validate(); err != nil {
+		return err
+	}
+	o.signature = &v
+	// secret read from the file usually ends with the newline
+	o.signatureSecret = strings.TrimSuffix(secret, "\n")
+	return nil
+}
+
+// startVerifier stores the signature secret, records the verification
+// in the source annotations and starts the verifier in front of the source.
+func (o *CliOptions) startVerifier(ctx context.Context, s triggermesh.Component, container *docker.Container) error {
+	store := secrets.Context(o.Config.ConfigHome, o.Config.Context)
+	if err := store.Write(s.GetName(), signature.SecretField, []byte(o.signatureSecret), 0); err != nil {
+		return fmt.Errorf("storing signature secret: %w", err)
+	}
+	for key, value := range o.signature.Annotations() {
+		if err := o.Manifest.Annotate(s.GetName(), s.GetKind(), key, value); err != nil {
+			return fmt.Errorf("unable to update manifest: %w", err)
+		}
+	}
+	log.Println("Starting signature verifier")
+	verifier, err := signature.Start(ctx, o.Config.ConfigHome, o.Config.Context, container, *o.signature, []byte(o.signatureSecret))
+	if err != nil {
+		return err
+	}
+	log.Printf("Signed webhook requests are accepted at %s", o.Config.URLs().URL(urls.External, verifier.HostPort()))
+	return nil
+}
+
+func (o *CliOptions) sourceFromImage(name, image string, params map[string]string) error {
+	ctx := context.Background()
+	sink, err := o.brokerURL()
 	if err != nil {
-		return fmt.Errorf("broker offline: %v", err)
+		return err
 	}
-	params["K_SINK"] = "http://host.docker.internal:" + port
+	params["K_SINK"] = sink
 
 	s := service.New(name, image, o.Config.Context, service.Producer, params)
 
 	log.Println("Updating manifest")
-	restart, err := o.Manifest.Add(s)
+	applied, err := o.builder().Apply(s)
 	if err != nil {
 		return fmt.Errorf("unable to update manifest: %w", err)
 	}
+	restart := applied != manifest.Unchanged
+	env, levelChanged, err := o.loggingEnv(s, nil)
+	if err != nil {
+		return err
+	}
 	log.Println("Starting container")
-	if _, err := s.(triggermesh.Runnable).Start(ctx, nil, restart); err != nil {
+	if _, err := o.builder().StartContainer(ctx, s, env, restart || levelChanged); err != nil {
 		return err
 	}
 	output.PrintStatus("producer", s, []string{}, []string{})
-	return nil
+	return o.fanOut(s)
 }
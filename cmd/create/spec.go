@@ -0,0 +1,88 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"fmt"
+	"log"
+	"strconv"
+
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// strictSpecParam extracts the --strict flag for the commands
+// that parse their arguments themselves.
+func (o *CliOptions) strictSpecParam(params map[string]string) error {
+	value, exists := params["strict"]
+	if !exists {
+		return nil
+	}
+	delete(params, "strict")
+	strict := true
+	if value != "" {
+		var err error
+		if strict, err = strconv.ParseBool(value); err != nil {
+			return fmt.Errorf("strict: %w", err)
+		}
+	}
+	o.strictSpec = &strict
+	return nil
+}
+
+func (o *CliOptions) strict() bool {
+	if o.strictSpec != nil {
+		return *o.strictSpec
+	}
+	return o.Config.StrictSpec
+}
+
+// checkSpec reports the fields of the component spec that are not declared
+// in the schema of the component CRD. Unless in the strict mode, the fields
+// are removed from the spec, like the API server prunes them.
+func (o *CliOptions) checkSpec(c crd.CRD, component triggermesh.Component) error {
+	schema, _, err := c.ServedSchema()
+	if err != nil {
+		return err
+	}
+	pruned, fields, err := schema.Prune(component.GetSpec())
+	if err != nil {
+		return fmt.Errorf("%s spec: %w", component.GetKind(), err)
+	}
+	if err := o.unknownFields(fields); err != nil {
+		return err
+	}
+	if len(fields) != 0 {
+		component.SetSpec(pruned)
+	}
+	return nil
+}
+
+// unknownFields warns about the unknown fields or,
+// in the strict mode, fails listing them.
+func (o *CliOptions) unknownFields(fields []crd.UnknownField) error {
+	if len(fields) == 0 {
+		return nil
+	}
+	if o.strict() {
+		return crd.UnknownFieldsError(fields)
+	}
+	for _, field := range fields {
+		log.Printf("WARNING! %s", field)
+	}
+	return nil
+}
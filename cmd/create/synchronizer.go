@@ -0,0 +1,199 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"context"
+	"fmt"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/target"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/logging"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+const synchronizerKind = "synchronizer"
+
+func (o *CliOptions) newSynchronizerCmd() *cobra.Command {
+	var name, targetName, correlationKey, responseTimeout string
+	var requestTypes, replyTypes []string
+	synchronizerCmd := &cobra.Command{
+		Use:   "synchronizer --target <name> [--name <name>][--correlation-key <attribute>][--response-timeout <duration>][--eventTypes <type>...][--reply-type <type>...]",
+		Short: "Create TriggerMesh synchronizer for request-reply flows. More information at https://docs.triggermesh.io",
+		Example: `tmctl create synchronizer \
+	--correlation-key correlationid \
+	--response-timeout 10s \
+	--target sockeye`,
+		ValidArgs: []string{"--name", "--target", "--correlation-key", "--response-timeout", "--eventTypes", "--reply-type", "--log-level"},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) > 0 {
+				return fmt.Errorf("unexpected argument(s): %v", args)
+			}
+			if err := o.validateName(name); err != nil {
+				return err
+			}
+			if o.logLevel != "" {
+				if err := logging.Validate(o.logLevel); err != nil {
+					return err
+				}
+			}
+			if _, err := time.ParseDuration(responseTimeout); err != nil {
+				return fmt.Errorf("invalid response timeout %q: %w", responseTimeout, err)
+			}
+			return o.synchronizer(name, targetName, correlationKey, responseTimeout, requestTypes, replyTypes)
+		},
+	}
+	synchronizerCmd.Flags().StringVar(&name, "name", "", "Synchronizer name")
+	synchronizerCmd.Flags().StringVar(&targetName, "target", "", "Target that replies to the requests")
+	synchronizerCmd.Flags().StringVar(&correlationKey, "correlation-key", "correlationid", "CloudEvent attribute correlating the requests with the replies")
+	synchronizerCmd.Flags().StringVar(&responseTimeout, "response-timeout", "10s", "Time to wait for the reply before the request fails")
+	synchronizerCmd.Flags().StringSliceVar(&requestTypes, "eventTypes", []string{}, "Request event types routed to the target. Default is the types consumed by the target")
+	synchronizerCmd.Flags().StringSliceVar(&replyTypes, "reply-type", []string{}, "Reply event types routed back to the synchronizer. Default is the types produced by the target")
+	synchronizerCmd.Flags().StringVar(&o.logLevel, "log-level", "", "Adapter logging level: debug, info, warn or error")
+	cobra.CheckErr(synchronizerCmd.MarkFlagRequired("target"))
+
+	cobra.CheckErr(synchronizerCmd.RegisterFlagCompletionFunc("name", cobra.NoFileCompletions))
+	cobra.CheckErr(synchronizerCmd.RegisterFlagCompletionFunc("correlation-key", cobra.NoFileCompletions))
+	cobra.CheckErr(synchronizerCmd.RegisterFlagCompletionFunc("response-timeout", cobra.NoFileCompletions))
+	cobra.CheckErr(synchronizerCmd.RegisterFlagCompletionFunc("log-level", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return logging.Levels, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(synchronizerCmd.RegisterFlagCompletionFunc("eventTypes", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.ListEventTypesWithOrigin(o.Manifest, o.Config, completion.Index(o.Config)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(synchronizerCmd.RegisterFlagCompletionFunc("reply-type", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.ListEventTypesWithOrigin(o.Manifest, o.Config, completion.Index(o.Config)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(synchronizerCmd.RegisterFlagCompletionFunc("target", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.Components(o.Manifest, completion.ListTargets(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	return synchronizerCmd
+}
+
+func (o *CliOptions) synchronizer(name, targetName, correlationKey, responseTimeout string, requestTypes, replyTypes []string) error {
+	ctx := context.Background()
+
+	targetComponent, err := o.lookupTarget(targetName)
+	if err != nil {
+		return err
+	}
+	if len(requestTypes) == 0 {
+		consumed, _ := targetComponent.(triggermesh.Consumer).ConsumedEventTypes()
+		for _, et := range consumed {
+			if et != "*" {
+				requestTypes = append(requestTypes, et)
+			}
+		}
+	}
+	if len(requestTypes) == 0 {
+		return fmt.Errorf("cannot detect request event types of %q, use --eventTypes", targetName)
+	}
+	if len(replyTypes) == 0 {
+		replyTypes = targetReplyTypes(targetComponent)
+	}
+	if len(replyTypes) == 0 {
+		return fmt.Errorf("cannot detect reply event types of %q, use --reply-type", targetName)
+	}
+
+	sink, err := o.brokerURL()
+	if err != nil {
+		return err
+	}
+
+	crd, exists := o.CRD[synchronizerKind]
+	if !exists {
+		return fmt.Errorf("CRD for kind %q not found", synchronizerKind)
+	}
+	spec := map[string]interface{}{
+		"correlationKey": map[string]interface{}{
+			"attribute": correlationKey,
+		},
+		"response": map[string]interface{}{
+			"timeout": responseTimeout,
+		},
+		"sink": map[string]interface{}{
+			"uri": sink,
+		},
+	}
+	s := transformation.New(name, synchronizerKind, o.Config.Context,
+		o.Config.Triggermesh.ComponentsVersion, crd, spec)
+
+	log.Println("Updating manifest")
+	applied, err := o.Manifest.Apply(s)
+	if err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	restart := applied != manifest.Unchanged
+	env, levelChanged, err := o.loggingEnv(s, nil)
+	if err != nil {
+		return err
+	}
+	restart = restart || levelChanged
+
+	log.Println("Starting container")
+	if _, err := s.(triggermesh.Runnable).Start(ctx, env, restart); err != nil {
+		return err
+	}
+
+	// update our triggers in case of synchronizer container restart
+	if restart {
+		if err := o.updateTriggers(s); err != nil {
+			return err
+		}
+	}
+
+	// requests forwarded by the synchronizer to the broker go to the target,
+	// target replies come back to the synchronizer to be matched by the correlation key
+	for _, et := range requestTypes {
+		if _, err := o.createTrigger("", targetComponent, tmbroker.FilterAttribute("type", et)); err != nil {
+			return fmt.Errorf("creating trigger: %w", err)
+		}
+	}
+	for _, et := range replyTypes {
+		if _, err := o.createTrigger("", s, tmbroker.FilterAttribute("type", et)); err != nil {
+			return fmt.Errorf("creating trigger: %w", err)
+		}
+	}
+
+	url, err := o.Config.URLs().ComponentURL(ctx, s.(triggermesh.Consumer), urls.External)
+	if err != nil {
+		return fmt.Errorf("synchronizer port: %w", err)
+	}
+	fmt.Printf("Synchronizer %q is available at %s\n", s.GetName(), url)
+	fmt.Printf("Next steps:\n\ttmctl send-event --target %s --eventType %s <data>\n", s.GetName(), requestTypes[0])
+	return nil
+}
+
+// targetReplyTypes returns the types of the events the target replies with.
+func targetReplyTypes(c triggermesh.Component) []string {
+	var types []string
+	switch t := c.(type) {
+	case *target.Target:
+		types, _ = t.ProducedEventTypes()
+	case triggermesh.Producer:
+		types, _ = t.GetEventTypes()
+	}
+	return types
+}
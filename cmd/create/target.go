@@ -26,7 +26,9 @@ import (
 
 	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
 
+	"github.com/triggermesh/tmctl/pkg/bridge"
 	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/output"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
@@ -34,45 +36,114 @@ import (
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/target"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/pkg"
 )
 
 func (o *CliOptions) newTargetCmd() *cobra.Command {
 	return &cobra.Command{
-		Use:   "target [kind]/[--from-image <image>][--name <name>][--source <name>...][--eventTypes <type>...]",
+		Use:   "target [kind]/[--from-image <image>][--name <name>][--source <name>...][--eventTypes <type>...][--trigger-name <name>][--reply-to <name>][--reply-event-type <type>]",
 		Short: "Create TriggerMesh target. More information at https://docs.triggermesh.io",
 		Example: `tmctl create target http \
 	--endpoint https://image-charts.com \
 	--method GET \
-	--response.eventType qr-data.response`,
+	--response.eventType qr-data.response
+
+tmctl create target http \
+	--url https://example.com/api \
+	--method POST \
+	--header 'Content-Type: application/json' \
+	--basic-auth user:password
+
+tmctl create target http \
+	--endpoint https://example.com/api \
+	--method GET \
+	--response.eventType api.response \
+	--reply-to sockeye
+
+tmctl create target function \
+	--runtime python \
+	--entrypoint handler \
+	--code ./handler.py
+
+tmctl create target http \
+	--endpoint https://example.com \
+	--strict
+
+tmctl create target file \
+	--name audit-log \
+	--path ./events/ \
+	--max-file-size 50MB \
+	--eventTypes com.example.order.created`,
+		// the CRDs of the version in the arguments are fetched by the command
+		Annotations:        map[string]string{crd.FetchedByCommand: "true"},
 		DisableFlagParsing: true,
 		SilenceErrors:      true,
 		ValidArgsFunction:  o.targetsCompletion,
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) == 0 || args[0] == "--help" {
+				if err := o.fetchCRD(); err != nil {
+					return err
+				}
 				targets, err := crd.ListTargets(o.CRD)
 				if err != nil {
 					return fmt.Errorf("list sources: %w", err)
 				}
 				// help can never return an error
 				_ = cmd.Help()
-				fmt.Printf("\nAvailable target kinds:\n---\n%s\n", strings.Join(targets, "\n"))
+				fmt.Printf("\nAvailable target kinds:\n---\n%s\nfunction\n%s\n", strings.Join(targets, "\n"), fileSinkKind)
 				return nil
 			}
+			var headers []string
+			if args[0] == "http" {
+				headers, args = extractRepeatedFlag(args, "header")
+			}
 			params := argsToMap(args[0:])
+			if args[0] == "http" {
+				if err := httpTargetParams(params, headers); err != nil {
+					return err
+				}
+			}
+			if args[0] == "kafka" {
+				if err := kafkaParams(params, false); err != nil {
+					return err
+				}
+			}
 			var name string
 			if n, exists := params["name"]; exists {
 				name = n
 				delete(params, "name")
 			}
+			if err := o.validateName(name); err != nil {
+				return err
+			}
 			if v, exists := params["version"]; exists {
 				o.Config.Triggermesh.ComponentsVersion = v
 				delete(params, "version")
 			}
-			crd, err := crd.Fetch(o.Config.ConfigHome, o.Config.Triggermesh.ComponentsVersion)
-			if err != nil {
+			if err := o.logLevelParam(params); err != nil {
+				return err
+			}
+			if err := o.triggerNameParam(params); err != nil {
+				return err
+			}
+			if err := o.adapterVersionParam(params); err != nil {
+				return err
+			}
+			if err := eventsLogParam(params); err != nil {
+				return err
+			}
+			if err := o.timeoutParam(params); err != nil {
+				return err
+			}
+			if err := o.strictSpecParam(params); err != nil {
+				return err
+			}
+			if err := o.fetchCRD(); err != nil {
+				return err
+			}
+			if err := o.credentialsParams(args[0]+"target", params); err != nil {
 				return err
 			}
-			o.CRD = crd
 
 			var eventSourcesFilter, eventTypesFilter []string
 			if sf, exists := params["source"]; exists {
@@ -89,6 +160,19 @@ func (o *CliOptions) newTargetCmd() *cobra.Command {
 				}
 				delete(params, "eventTypes")
 			}
+			if err := o.replyParams(params); err != nil {
+				return err
+			}
+			_, fromImage := params["from-image"]
+			if o.replyTo != "" && (fromImage || args[0] == "function" || args[0] == fileSinkKind) {
+				return fmt.Errorf("--reply-to is supported by the target kinds that declare the response events")
+			}
+			if !fromImage && args[0] == "function" {
+				return o.function(name, params, eventSourcesFilter, eventTypesFilter)
+			}
+			if !fromImage && args[0] == fileSinkKind {
+				return o.fileSink(name, params, eventSourcesFilter, eventTypesFilter)
+			}
 			if _, readDisabled := params["disable-file-args"]; !readDisabled {
 				for key, value := range params {
 					data, err := os.ReadFile(value)
@@ -104,12 +188,12 @@ func (o *CliOptions) newTargetCmd() *cobra.Command {
 				delete(params, "from-image")
 				return o.targetFromImage(name, image, params, eventSourcesFilter, eventTypesFilter)
 			}
-			return o.target(name, args[0], params, eventSourcesFilter, eventTypesFilter)
+			return o.target(name, args[0], pkg.ParseArgs(params), eventSourcesFilter, eventTypesFilter)
 		},
 	}
 }
 
-func (o *CliOptions) target(name, kind string, args map[string]string, eventSourcesFilter, eventTypesFilter []string) error {
+func (o *CliOptions) target(name, kind string, spec map[string]interface{}, eventSourcesFilter, eventTypesFilter []string) error {
 	ctx := context.Background()
 
 	et, err := o.translateEventSource(eventSourcesFilter)
@@ -122,12 +206,33 @@ func (o *CliOptions) target(name, kind string, args map[string]string, eventSour
 	if !exists {
 		return fmt.Errorf("CRD for kind %q not found", kind)
 	}
-	t := target.New(name, kind, o.Config.Context, o.Config.Triggermesh.ComponentsVersion, crd, args)
+	t := target.New(name, kind, o.Config.Context, o.Config.Triggermesh.ComponentsVersion, crd, spec)
+	t.(*target.Target).Version = o.builder().ComponentVersion(t)
+	if err := o.checkSpec(crd, t); err != nil {
+		return err
+	}
+	triggers := len(eventTypesFilter)
+	if o.replyTo != "" {
+		triggers++
+	}
+	if err := o.builder().CheckTriggerName(triggers); err != nil {
+		return err
+	}
+	if o.replyTo != "" {
+		// fail before the target is created
+		if _, err := bridge.ReplyEventType(t, o.replyEventType); err != nil {
+			return fmt.Errorf("--reply-to: %w", err)
+		}
+		if _, err := o.lookupTarget(o.replyTo); err != nil {
+			return fmt.Errorf("--reply-to: %w", err)
+		}
+	}
 
 	secrets, secretsEnv, err := components.ProcessSecrets(t.(triggermesh.Parent), o.Manifest)
 	if err != nil {
 		return fmt.Errorf("processing secrets: %v", err)
 	}
+	o.credentialsSecrets(t, secrets, secretsEnv)
 	secretsChanged := false
 
 	log.Println("Updating manifest")
@@ -140,13 +245,25 @@ func (o *CliOptions) target(name, kind string, args map[string]string, eventSour
 			secretsChanged = true
 		}
 	}
-	restart, err := o.Manifest.Add(t)
+	applied, err := o.Manifest.Apply(t)
 	if err != nil {
 		return fmt.Errorf("unable to update manifest: %w", err)
 	}
+	restart := applied != manifest.Unchanged
+	if err := o.pinVersion(t); err != nil {
+		return err
+	}
+	if err := o.annotateCredentials(t); err != nil {
+		return err
+	}
+	env, levelChanged, err := o.loggingEnv(t, secretsEnv)
+	if err != nil {
+		return err
+	}
+	restart = restart || levelChanged
 
 	log.Println("Starting container")
-	if _, err := t.(triggermesh.Runnable).Start(ctx, secretsEnv, (restart || secretsChanged)); err != nil {
+	if _, err := t.(triggermesh.Runnable).Start(ctx, env, (restart || secretsChanged)); err != nil {
 		return err
 	}
 
@@ -163,36 +280,40 @@ func (o *CliOptions) target(name, kind string, args map[string]string, eventSour
 		}
 	}
 
+	if o.replyTo != "" {
+		if _, err := o.builder().AddReply(t, o.replyTo, o.replyEventType); err != nil {
+			return fmt.Errorf("creating reply trigger: %w", err)
+		}
+	}
+
 	output.PrintStatus("consumer", t, eventSourcesFilter, eventTypesFilter)
 	return nil
 }
 
-func (o *CliOptions) createTrigger(name string, target triggermesh.Component, filter *eventingbroker.Filter) (triggermesh.Component, error) {
-	trigger, err := tmbroker.NewTrigger(name, o.Config.Context, o.Config.ConfigHome, target, filter)
-	if err != nil {
-		return nil, err
-	}
-	if err := trigger.(*tmbroker.Trigger).WriteLocalConfig(); err != nil {
-		return nil, err
+// replyParams extracts the reply wiring parameters of the target.
+func (o *CliOptions) replyParams(params map[string]string) error {
+	o.replyTo = params["reply-to"]
+	o.replyEventType = params["reply-event-type"]
+	delete(params, "reply-to")
+	delete(params, "reply-event-type")
+	if o.replyEventType != "" && o.replyTo == "" {
+		return fmt.Errorf("--reply-event-type requires --reply-to")
 	}
-	if _, err := o.Manifest.Add(trigger); err != nil {
-		return nil, err
-	}
-	return trigger, nil
+	return nil
+}
+
+func (o *CliOptions) createTrigger(name string, target triggermesh.Component, filter *eventingbroker.Filter) (triggermesh.Component, error) {
+	return o.createPathTrigger(name, target, "", filter)
+}
+
+// createPathTrigger creates the trigger delivering the events
+// to the path of the target address.
+func (o *CliOptions) createPathTrigger(name string, target triggermesh.Component, path string, filter *eventingbroker.Filter) (triggermesh.Component, error) {
+	return o.builder().AddTrigger(name, target, path, filter)
 }
 
 func (o *CliOptions) updateTriggers(target triggermesh.Component) error {
-	triggers, err := tmbroker.GetTargetTriggers(target.GetName(), o.Config.Context, o.Config.ConfigHome)
-	if err != nil {
-		return fmt.Errorf("target triggers: %w", err)
-	}
-	for _, trigger := range triggers {
-		trigger.(*tmbroker.Trigger).SetTarget(target)
-		if err := trigger.(*tmbroker.Trigger).WriteLocalConfig(); err != nil {
-			return fmt.Errorf("broker config update: %w", err)
-		}
-	}
-	return nil
+	return o.builder().UpdateTriggers(target)
 }
 
 func (o *CliOptions) targetFromImage(name, image string, params map[string]string, eventSourcesFilter, eventTypesFilter []string) error {
@@ -204,15 +325,24 @@ func (o *CliOptions) targetFromImage(name, image string, params map[string]strin
 	}
 	eventTypesFilter = append(eventTypesFilter, et...)
 
+	if err := o.builder().CheckTriggerName(len(eventTypesFilter)); err != nil {
+		return err
+	}
 	s := service.New(name, image, o.Config.Context, service.Consumer, params)
 
 	log.Println("Updating manifest")
-	restart, err := o.Manifest.Add(s)
+	applied, err := o.Manifest.Apply(s)
 	if err != nil {
 		return fmt.Errorf("unable to update manifest: %w", err)
 	}
+	restart := applied != manifest.Unchanged
+	env, levelChanged, err := o.loggingEnv(s, nil)
+	if err != nil {
+		return err
+	}
+	restart = restart || levelChanged
 	log.Println("Starting container")
-	if _, err := s.(triggermesh.Runnable).Start(ctx, nil, restart); err != nil {
+	if _, err := s.(triggermesh.Runnable).Start(ctx, env, restart); err != nil {
 		return err
 	}
 	// update our triggers in case of target container restart
@@ -0,0 +1,103 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"fmt"
+	"net/url"
+	"strings"
+)
+
+var httpMethods = []string{"GET", "POST", "PUT", "PATCH", "DELETE", "HEAD", "OPTIONS"}
+
+// httpTargetParams maps the HTTP target shortcut flags
+// onto the HTTPTarget spec parameters.
+func httpTargetParams(params map[string]string, headers []string) error {
+	if endpoint, exists := params["url"]; exists {
+		u, err := url.ParseRequestURI(endpoint)
+		if err != nil {
+			return fmt.Errorf("invalid URL %q: %w", endpoint, err)
+		}
+		if u.Scheme != "http" && u.Scheme != "https" {
+			return fmt.Errorf("invalid URL %q: scheme must be http or https", endpoint)
+		}
+		params["endpoint"] = endpoint
+		delete(params, "url")
+	}
+	if method, exists := params["method"]; exists {
+		method = strings.ToUpper(method)
+		valid := false
+		for _, m := range httpMethods {
+			if m == method {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unsupported HTTP method %q, expected one of: %s", method, strings.Join(httpMethods, ", "))
+		}
+		params["method"] = method
+	}
+	for _, header := range headers {
+		kv := strings.SplitN(header, ":", 2)
+		if len(kv) != 2 || strings.TrimSpace(kv[0]) == "" {
+			return fmt.Errorf("header %q is expected to be in \"Key: Value\" format", header)
+		}
+		name := strings.TrimSpace(kv[0])
+		// the dots of the parameter keys are the nested spec fields
+		if strings.Contains(name, ".") {
+			return fmt.Errorf("header name %q must not contain \".\"", name)
+		}
+		params["headers."+name] = strings.TrimSpace(kv[1])
+	}
+	if auth, exists := params["basic-auth"]; exists {
+		credentials := strings.SplitN(auth, ":", 2)
+		if len(credentials) != 2 || credentials[0] == "" {
+			return fmt.Errorf("basic auth is expected to be in \"user:password\" format")
+		}
+		// password is a secret field, it will be extracted into the secret object.
+		params["basicAuthUsername"] = credentials[0]
+		params["basicAuthPassword"] = credentials[1]
+		delete(params, "basic-auth")
+	}
+	if skip, exists := params["skip-tls-verify"]; exists {
+		params["skipVerify"] = "true"
+		if skip == "false" {
+			params["skipVerify"] = "false"
+		}
+		delete(params, "skip-tls-verify")
+	}
+	return nil
+}
+
+// extractRepeatedFlag removes all occurrences of the flag from the arguments
+// and returns its values.
+func extractRepeatedFlag(args []string, flag string) ([]string, []string) {
+	var values, rest []string
+	for i := 0; i < len(args); i++ {
+		switch {
+		case args[i] == "--"+flag && i+1 < len(args):
+			values = append(values, args[i+1])
+			i++
+		case strings.HasPrefix(args[i], "--"+flag+"="):
+			values = append(values, strings.TrimPrefix(args[i], "--"+flag+"="))
+		default:
+			rest = append(rest, args[i])
+		}
+	}
+	return values, rest
+}
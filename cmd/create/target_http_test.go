@@ -0,0 +1,125 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestHTTPTargetParams(t *testing.T) {
+	testCases := map[string]struct {
+		params   map[string]string
+		headers  []string
+		expected map[string]string
+		err      string
+	}{
+		"url and method": {
+			params:   map[string]string{"url": "https://example.com/orders", "method": "put"},
+			expected: map[string]string{"endpoint": "https://example.com/orders", "method": "PUT"},
+		},
+		"relative url": {
+			params: map[string]string{"url": "example.com/orders"},
+			err:    `invalid URL "example.com/orders": parse "example.com/orders": invalid URI for request`,
+		},
+		"url scheme": {
+			params: map[string]string{"url": "ftp://example.com"},
+			err:    `invalid URL "ftp://example.com": scheme must be http or https`,
+		},
+		"unsupported method": {
+			params: map[string]string{"method": "trace"},
+			err:    `unsupported HTTP method "TRACE", expected one of: GET, POST, PUT, PATCH, DELETE, HEAD, OPTIONS`,
+		},
+		"repeated headers": {
+			params:  map[string]string{},
+			headers: []string{"Authorization: Bearer abc:def", " X-Request-Source :tmctl"},
+			expected: map[string]string{
+				"headers.Authorization":    "Bearer abc:def",
+				"headers.X-Request-Source": "tmctl",
+			},
+		},
+		"header without value": {
+			params:  map[string]string{},
+			headers: []string{"Authorization"},
+			err:     `header "Authorization" is expected to be in "Key: Value" format`,
+		},
+		"dotted header name": {
+			params:  map[string]string{},
+			headers: []string{"X-Api.Key: abc"},
+			err:     `header name "X-Api.Key" must not contain "."`,
+		},
+		"basic auth": {
+			params:   map[string]string{"basic-auth": "user:pass:word"},
+			expected: map[string]string{"basicAuthUsername": "user", "basicAuthPassword": "pass:word"},
+		},
+		"basic auth without user": {
+			params: map[string]string{"basic-auth": ":password"},
+			err:    `basic auth is expected to be in "user:password" format`,
+		},
+		"skip tls verify": {
+			params:   map[string]string{"skip-tls-verify": ""},
+			expected: map[string]string{"skipVerify": "true"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := httpTargetParams(tc.params, tc.headers)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, tc.params)
+		})
+	}
+}
+
+func TestExtractRepeatedFlag(t *testing.T) {
+	testCases := map[string]struct {
+		args   []string
+		values []string
+		rest   []string
+	}{
+		"separate values": {
+			args:   []string{"--header", "A: 1", "--url", "http://foo", "--header", "B: 2"},
+			values: []string{"A: 1", "B: 2"},
+			rest:   []string{"--url", "http://foo"},
+		},
+		"inline values": {
+			args:   []string{"--header=A: 1", "--method=POST"},
+			values: []string{"A: 1"},
+			rest:   []string{"--method=POST"},
+		},
+		"missing value": {
+			args: []string{"--url", "http://foo", "--header"},
+			rest: []string{"--url", "http://foo", "--header"},
+		},
+		"other flag prefix": {
+			args: []string{"--headers", "A: 1"},
+			rest: []string{"--headers", "A: 1"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			values, rest := extractRepeatedFlag(tc.args, "header")
+			assert.Equal(t, tc.values, values)
+			assert.Equal(t, tc.rest, rest)
+		})
+	}
+}
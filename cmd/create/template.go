@@ -0,0 +1,110 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/prompt"
+	"github.com/triggermesh/tmctl/pkg/templates"
+)
+
+func (o *CliOptions) newFromTemplateCmd() *cobra.Command {
+	var name string
+	var values, eventSourcesFilter, eventTypesFilter []string
+	fromTemplateCmd := &cobra.Command{
+		Use:   "from-template <template> [--name <name>][--param <name>=<value>...][--source <name>...][--eventTypes <type>...]",
+		Short: "Create TriggerMesh component from the saved template",
+		Example: `tmctl create from-template corp-http \
+	--name billing \
+	--param url=https://example.com/billing \
+	--param token='Bearer abc'`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completion.ListTemplates(o.Config.ConfigHome), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.validateName(name); err != nil {
+				return err
+			}
+			return o.fromTemplate(args[0], name, values, eventSourcesFilter, eventTypesFilter)
+		},
+	}
+	fromTemplateCmd.Flags().StringVar(&name, "name", "", "Component name")
+	fromTemplateCmd.Flags().StringArrayVar(&values, "param", []string{}, "Template param value in \"<name>=<value>\" format, secret params are prompted if not set")
+	fromTemplateCmd.Flags().StringSliceVar(&eventSourcesFilter, "source", []string{}, "Sources component names as a trigger filter of the created target")
+	fromTemplateCmd.Flags().StringSliceVar(&eventTypesFilter, "eventTypes", []string{}, "Event types filter of the created target")
+	cobra.CheckErr(fromTemplateCmd.RegisterFlagCompletionFunc("name", cobra.NoFileCompletions))
+	cobra.CheckErr(fromTemplateCmd.RegisterFlagCompletionFunc("param", cobra.NoFileCompletions))
+	cobra.CheckErr(fromTemplateCmd.RegisterFlagCompletionFunc("source", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.Components(o.Manifest, completion.ListSources(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(fromTemplateCmd.RegisterFlagCompletionFunc("eventTypes", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.ListEventTypesWithOrigin(o.Manifest, o.Config, completion.Index(o.Config)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	return fromTemplateCmd
+}
+
+// fromTemplate creates the source or the target
+// with the spec of the template and the param values.
+func (o *CliOptions) fromTemplate(templateName, name string, values, eventSourcesFilter, eventTypesFilter []string) error {
+	t, err := templates.New(o.Config.ConfigHome).Get(templateName)
+	if err != nil {
+		return err
+	}
+	params := make(map[string]string, len(values))
+	for _, value := range values {
+		kv := strings.SplitN(value, "=", 2)
+		if len(kv) != 2 || kv[0] == "" {
+			return fmt.Errorf("param %q is expected to be in \"<name>=<value>\" format", value)
+		}
+		params[kv[0]] = kv[1]
+	}
+	for _, p := range t.Params {
+		if _, set := params[p.Name]; set || !p.Secret {
+			continue
+		}
+		if params[p.Name], err = prompt.Password(p.Name); err != nil {
+			return fmt.Errorf("secret param %q: %w", p.Name, err)
+		}
+	}
+	spec, err := t.Instantiate(params)
+	if err != nil {
+		return err
+	}
+	kind := strings.ToLower(t.Kind)
+	if _, exists := o.CRD[kind]; !exists {
+		return fmt.Errorf("template %q: CRD for kind %q not found", t.Name, t.Kind)
+	}
+	switch {
+	case strings.HasSuffix(kind, "target"):
+		return o.target(name, strings.TrimSuffix(kind, "target"), spec, eventSourcesFilter, eventTypesFilter)
+	case strings.HasSuffix(kind, "source"):
+		if len(eventSourcesFilter) != 0 || len(eventTypesFilter) != 0 {
+			return fmt.Errorf("--source and --eventTypes are supported by the target templates")
+		}
+		return o.source(name, strings.TrimSuffix(kind, "source"), spec)
+	}
+	return fmt.Errorf("template %q: %s is neither source nor target", t.Name, t.Kind)
+}
@@ -17,7 +17,6 @@ limitations under the License.
 package create
 
 import (
-	"bufio"
 	"bytes"
 	"context"
 	"fmt"
@@ -27,17 +26,19 @@ import (
 
 	"github.com/jroimartin/gocui"
 	"github.com/spf13/cobra"
-	"gopkg.in/yaml.v3"
+	"golang.org/x/term"
 
+	"github.com/triggermesh/tmctl/pkg/bridge"
+	"github.com/triggermesh/tmctl/pkg/changeset"
 	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
 	transformationgui "github.com/triggermesh/tmctl/pkg/gui/transformation"
-	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/output"
+	"github.com/triggermesh/tmctl/pkg/prompt"
+	"github.com/triggermesh/tmctl/pkg/sops"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
-	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
-	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
-	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/logging"
 )
 
 const (
@@ -67,11 +68,11 @@ https://github.com/triggermesh/triggermesh/tree/main/config/samples/bumblebee`
 )
 
 func (o *CliOptions) newTransformationCmd() *cobra.Command {
-	var name, target, file string
+	var name, target, file, engine, expression string
 	var eventSourcesFilter, eventTypesFilter []string
 	var wizard bool
 	transformationCmd := &cobra.Command{
-		Use:   "transformation [--target <name>][--source <name>...][--eventTypes <type>...][--from <path>][--wizard]",
+		Use:   "transformation [--target <name>][--source <name|kind:kind>...][--watch-kind][--eventTypes <type>...][--from <path>][--engine <bumblebee|jq>][--expression <query>][--dry-run][--wizard][--no-editor]",
 		Short: "Create TriggerMesh transformation. More information at https://docs.triggermesh.io/transformation/jsontransformation/",
 		Example: `tmctl create transformation <<EOF
   data:
@@ -79,9 +80,49 @@ func (o *CliOptions) newTransformationCmd() *cobra.Command {
     paths:
     - key: new-field
       value: hello from Transformation!
-EOF`,
-		ValidArgs: []string{"--name", "--target", "--source", "--eventTypes", "--from", "--wizard"},
+EOF
+
+tmctl create transformation --source kind:awssqssource --watch-kind --target sockeye -f spec.yaml
+
+tmctl create transformation --engine jq --target sockeye \
+  --expression '.data | {id: .order_id, total: (.items | map(.price) | add)}'`,
+		ValidArgs: []string{"--name", "--target", "--source", "--watch-kind", "--eventTypes", "--from", "--engine", "--expression", "--event-type", "--target-path", "--wizard", "--log-level", "--adapter-version", "--dry-run", "--trigger-name", "--cutover", "--decrypt", "--no-editor"},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.validateName(name); err != nil {
+				return err
+			}
+			if err := o.validateName(o.triggerName); err != nil {
+				return err
+			}
+			if o.logLevel != "" {
+				if err := logging.Validate(o.logLevel); err != nil {
+					return err
+				}
+			}
+			if engine != transformation.EngineBumblebee && engine != transformation.EngineJQ {
+				return fmt.Errorf("unsupported transformation engine %q", engine)
+			}
+			if err := o.validateEventTypesFrom(); err != nil {
+				return err
+			}
+			if err := sops.ValidateMode(o.decrypt); err != nil {
+				return err
+			}
+			if err := o.validateCutover(); err != nil {
+				return err
+			}
+			if o.targetPath != "" && target == "" {
+				return fmt.Errorf("--target-path requires --target")
+			}
+			if o.watchKind && wizard {
+				return fmt.Errorf("--watch-kind is not supported by the wizard")
+			}
+			if expression != "" {
+				if engine != transformation.EngineJQ {
+					return fmt.Errorf("--expression is supported by %q engine only", transformation.EngineJQ)
+				}
+				return o.transformation(name, target, engine, strings.NewReader(expression), eventSourcesFilter, eventTypesFilter)
+			}
 			if wizard {
 				name, sourceEventType, target, spec, err := transformationgui.Create(o.CRD, o.Manifest, o.Config)
 				if err == gocui.ErrQuit {
@@ -90,72 +131,93 @@ EOF`,
 				if err != nil {
 					return fmt.Errorf("transformation wizard error: %w", err)
 				}
-				return o.transformation(name, target, spec, []string{}, []string{sourceEventType})
+				if err := o.validateName(name); err != nil {
+					return err
+				}
+				return o.transformation(name, target, transformation.EngineBumblebee, spec, []string{}, []string{sourceEventType})
 			}
 			if file != "" {
-				data, err := os.ReadFile(file)
+				data, decrypted, err := sops.ReadFile(file, o.decrypt)
 				if err != nil {
 					return fmt.Errorf("file %q read: %w", file, err)
 				}
-				return o.transformation(name, target, bytes.NewBuffer(data), eventSourcesFilter, eventTypesFilter)
+				if decrypted && o.decrypt == sops.DecryptAuto {
+					fmt.Fprintf(os.Stderr, "Decrypting %s encrypted with SOPS\n", file)
+				}
+				if err := o.transformation(name, target, engine, bytes.NewBuffer(data), eventSourcesFilter, eventTypesFilter); err != nil || o.dryRun {
+					return err
+				}
+				return config.AddRecentSpec(o.Config.Context, file)
 			}
-			return o.transformation(name, target, nil, eventSourcesFilter, eventTypesFilter)
+			return o.transformation(name, target, engine, nil, eventSourcesFilter, eventTypesFilter)
 		},
 	}
 
-	crd, err := crd.Fetch(o.Config.ConfigHome, o.Config.Triggermesh.ComponentsVersion)
-	cobra.CheckErr(err)
-	o.CRD = crd
-
 	transformationCmd.Flags().StringVar(&name, "name", "", "Transformation name")
 	transformationCmd.Flags().StringVarP(&file, "from", "f", "", "Transformation specification file")
+	transformationCmd.Flags().StringVar(&o.decrypt, "decrypt", sops.DecryptAuto, "Decrypt the --from file encrypted with SOPS: auto, always or never")
+	transformationCmd.Flags().Lookup("decrypt").NoOptDefVal = sops.DecryptAlways
+	transformationCmd.Flags().StringVar(&engine, "engine", transformation.EngineBumblebee, "Transformation engine, \"bumblebee\" or \"jq\"")
+	transformationCmd.Flags().StringVar(&expression, "expression", "", "JQ transformation expression")
 	transformationCmd.Flags().StringVar(&target, "target", "", "Target name")
-	transformationCmd.Flags().StringSliceVar(&eventSourcesFilter, "source", []string{}, "Sources component names")
+	transformationCmd.Flags().StringVar(&o.targetPath, "target-path", "", "Path of the target address that the transformed events are delivered to")
+	transformationCmd.Flags().StringVar(&o.outputType, "event-type", "", "Type of the produced events, overrides the output-type-template config")
+	transformationCmd.Flags().StringVar(&o.logLevel, "log-level", "", "Adapter logging level: debug, info, warn or error")
+	transformationCmd.Flags().StringVar(&o.adapterVersion, "adapter-version", "", "Adapter image version pinned for the transformation, defaults to the context version")
+	transformationCmd.Flags().StringSliceVar(&eventSourcesFilter, "source", []string{}, "Sources component names or \"kind:<kind>\" selectors of all sources of the kind")
+	transformationCmd.Flags().BoolVar(&o.watchKind, "watch-kind", false, "Subscribe to the sources of the --source kinds created later, on \"tmctl start\"")
 	transformationCmd.Flags().StringSliceVar(&eventTypesFilter, "eventTypes", []string{}, "Event types filter")
+	transformationCmd.Flags().StringVar(&o.eventTypesFrom, "event-types-from", eventTypesDeclared, "Event types of the sources filter, \"declared\" by the sources or \"observed\" by \"tmctl stats observe\"")
 
+	transformationCmd.Flags().StringVar(&o.triggerName, "trigger-name", "", "Name of the trigger created by the command, if it creates exactly one")
+	transformationCmd.Flags().StringVar(&o.cutover, "cutover", bridge.CutoverAddNewFirst, "Order of the trigger changes when the transformation is inserted before a live target, \"add-new-first\" or \"drop-old-first\"")
+	transformationCmd.Flags().BoolVar(&o.dryRun, "dry-run", false, "Print the changes without applying them")
+	transformationCmd.Flags().BoolVar(&o.keepPartial, "keep-partial", false, "Keep the changes of the failed command instead of rolling them back, print the commands to undo them")
 	transformationCmd.Flags().BoolVar(&wizard, "wizard", false, "Experimental transformation wizard")
+	transformationCmd.Flags().BoolVar(&o.noEditor, "no-editor", false, "Type the transformation spec in the terminal instead of opening $EDITOR")
 
 	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("name", cobra.NoFileCompletions))
+	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("expression", cobra.NoFileCompletions))
+	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("trigger-name", cobra.NoFileCompletions))
+	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("event-type", cobra.NoFileCompletions))
+	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("target-path", cobra.NoFileCompletions))
+	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("adapter-version", cobra.NoFileCompletions))
+	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("from", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completion.ListSpecFiles(toComplete, o.Config.RecentSpecs[o.Config.Context], "yaml", "yml", "json"),
+			cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("log-level", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return logging.Levels, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("decrypt", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return sops.Modes, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("cutover", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{bridge.CutoverAddNewFirst, bridge.CutoverDropOldFirst}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("engine", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{transformation.EngineBumblebee, transformation.EngineJQ}, cobra.ShellCompDirectiveNoFileComp
+	}))
 	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("source", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-		return completion.ListSources(o.Manifest), cobra.ShellCompDirectiveNoFileComp
+		sources := completion.Components(o.Manifest, completion.ListSources(o.Manifest))
+		for _, kind := range completion.ListSourceKinds(o.Manifest) {
+			sources = append(sources, bridge.SourceKindSelector+kind)
+		}
+		return sources, cobra.ShellCompDirectiveNoFileComp
 	}))
 	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("eventTypes", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-		return completion.ListEventTypes(o.Manifest, o.Config, o.CRD), cobra.ShellCompDirectiveNoFileComp
+		return completion.ListEventTypesWithOrigin(o.Manifest, o.Config, completion.Index(o.Config)), cobra.ShellCompDirectiveNoFileComp
 	}))
 	cobra.CheckErr(transformationCmd.RegisterFlagCompletionFunc("target", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-		return completion.ListTargets(o.Manifest), cobra.ShellCompDirectiveNoFileComp
+		return completion.Components(o.Manifest, completion.ListTargets(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
 	}))
 	return transformationCmd
 }
 
-func (o *CliOptions) transformation(name, target string, specReader io.Reader, eventSourcesFilter, eventTypesFilter []string) error {
-	ctx := context.Background()
-	targetLabel := ""
-
-	var targetComponent triggermesh.Component
-	if target != "" {
-		targetLabel = target
-		t, err := o.lookupTarget(ctx, target)
-		if err != nil {
-			return err
-		}
-		targetComponent = t
-	}
-
-	var expectedEventTypes []string
-	if consumer, ok := targetComponent.(triggermesh.Consumer); ok {
-		expectedEventTypes, _ = consumer.ConsumedEventTypes()
-	}
-
-	et, err := o.translateEventSource(eventSourcesFilter)
-	if err != nil {
-		return err
-	}
-	eventTypesFilter = append(eventTypesFilter, et...)
-
+func (o *CliOptions) transformation(name, target, engine string, specReader io.Reader, eventSourcesFilter, eventTypesFilter []string) error {
 	var data []byte
 	if specReader == nil {
-		input, err := fromStdIn()
+		input, err := o.fromStdIn(engine)
 		if err != nil {
 			return fmt.Errorf("stdin read: %w", err)
 		}
@@ -167,169 +229,76 @@ func (o *CliOptions) transformation(name, target string, specReader io.Reader, e
 		}
 		data = specFile
 	}
-	if len(data) == 0 {
-		return fmt.Errorf("empty spec")
-	}
-	var spec map[string]interface{}
-	if err := yaml.Unmarshal(data, &spec); err != nil {
-		return fmt.Errorf("decode spec: %w", err)
-	}
-
-	crd, exists := o.CRD["transformation"]
-	if !exists {
-		return fmt.Errorf("CRD for kind \"transformation\" not found")
-	}
-
-	t := transformation.New(name, "transformation", o.Config.Context,
-		o.Config.Triggermesh.ComponentsVersion, crd, spec)
-
-	transformationEventType := fmt.Sprintf("%s.output", t.GetName())
-	if len(expectedEventTypes) > 0 {
-		transformationEventType = expectedEventTypes[0]
-	}
-	producedEventTypes, _ := t.(triggermesh.Producer).GetEventTypes()
-	if len(producedEventTypes) == 0 {
-		if err := t.(triggermesh.Producer).SetEventAttributes(map[string]string{
-			"type": transformationEventType,
-		}); err != nil {
-			return fmt.Errorf("setting event type: %w", err)
-		}
-	} else {
-		transformationEventType = producedEventTypes[0]
-		targetLabel = producedEventTypes[0]
-	}
-
-	eventTypesMatch := false
-	if len(expectedEventTypes) == 0 {
-		eventTypesMatch = true
-	}
-	for _, eet := range expectedEventTypes {
-		if eet == transformationEventType {
-			eventTypesMatch = true
-			break
-		}
-	}
-
-	if targetComponent != nil && !eventTypesMatch {
-		log.Printf(`WARNING! The transformation produces events of %q type, while target %q expectes %s. The target adapter may not work in this configuration.`,
-			transformationEventType, targetComponent.GetName(), strings.Join(expectedEventTypes, ","))
-	}
-
-	t.(*transformation.Transformation).SetLabel(transformation.TransformationContextLabel, transformationContexts(targetLabel, eventTypesFilter))
-
-	log.Println("Updating manifest")
-	restart, err := o.Manifest.Add(t)
+	var result bridge.Result
+	var changes *changeset.Set
+	err := o.transact(func() (err error) {
+		changes, err = o.builder().Changes(func(b *bridge.Builder) (err error) {
+			result, err = b.AddTransformation(context.Background(), bridge.TransformationSpec{
+				Name:       name,
+				Engine:     engine,
+				Spec:       data,
+				Target:     target,
+				TargetPath: o.targetPath,
+				EventType:  o.outputType,
+				Sources:    eventSourcesFilter,
+				EventTypes: eventTypesFilter,
+				WatchKinds: o.watchKind,
+				Cutover:    o.cutover,
+			})
+			return err
+		})
+		return err
+	})
 	if err != nil {
-		return fmt.Errorf("unable to update manifest: %w", err)
-	}
-
-	log.Println("Starting container")
-	if _, err := t.(triggermesh.Runnable).Start(ctx, nil, restart); err != nil {
 		return err
 	}
-
-	// update our triggers in case of target container restart
-	if restart {
-		if err := o.updateTriggers(t); err != nil {
-			return err
-		}
-	}
-
-	var targetTriggers []triggermesh.Component
-	// creating new trigger from transformation to target
-	if targetComponent != nil {
-		if targetTriggers, err = tmbroker.GetTargetTriggers(targetComponent.GetName(), o.Config.Context, o.Config.ConfigHome); err != nil {
-			return fmt.Errorf("target triggers: %w", err)
-		}
-		if _, err := o.createTrigger("", targetComponent, tmbroker.FilterAttribute("type", transformationEventType)); err != nil {
-			return fmt.Errorf("create trigger: %w", err)
-		}
-	}
-
-	// updating existing triggers from sources to target
-	for _, et := range eventTypesFilter {
-		filter := tmbroker.FilterAttribute("type", et)
-		if _, err := o.createTrigger("", t, filter); err != nil {
-			return err
-		}
-		for _, component := range targetTriggers {
-			trigger := component.(*tmbroker.Trigger)
-			if trigger.Filters[0].Exact == nil ||
-				trigger.Filters[0].Exact["type"] != et {
-				continue
-			}
-			if err := trigger.RemoveFromLocalConfig(); err != nil {
-				return err
-			}
-			if err := o.Manifest.Remove(trigger.GetName(), trigger.GetKind()); err != nil {
-				return err
-			}
-		}
-	}
-
-	if len(eventTypesFilter) == 0 {
-		for _, trigger := range targetTriggers {
-			if len(trigger.(*tmbroker.Trigger).Filters) == 1 &&
-				trigger.(*tmbroker.Trigger).Filters[0].Exact["type"] == transformationEventType {
-				continue
-			}
-			trigger.(*tmbroker.Trigger).SetTarget(t)
-			if err := trigger.(*tmbroker.Trigger).WriteLocalConfig(); err != nil {
-				return err
-			}
-			if _, err := o.Manifest.Add(trigger); err != nil {
-				return err
-			}
-		}
+	if o.dryRun {
+		fmt.Println("Planned changes, nothing has been applied:")
+		changes.Print(os.Stdout)
+		return nil
 	}
-	output.PrintStatus("consumer", t, eventSourcesFilter, eventTypesFilter)
+	output.PrintStatus("consumer", result.Component, eventSourcesFilter, result.EventTypes)
+	fmt.Println()
+	changes.Print(os.Stdout)
+	changes.Emit()
 	return nil
 }
 
-func fromStdIn() (string, error) {
-	fmt.Printf("%s%s%s\n\n", helpColorCode, helpText, defaultColorCode)
-	fmt.Printf("Insert Bumblebee transformation below\nPress Enter key twice to finish:\n")
-	input, err := readInput()
+func (o *CliOptions) fromStdIn(engine string) (string, error) {
+	// piped input is read as is, without the prompt polluting the output
+	interactive := term.IsTerminal(int(os.Stdin.Fd()))
+	switch {
+	case !interactive:
+	case !o.noEditor:
+		return o.fromEditor(engine)
+	case engine == transformation.EngineJQ:
+		fmt.Printf("Insert JQ expression below\nPress Enter key twice to finish:\n")
+	default:
+		fmt.Printf("%s%s%s\n\n", helpColorCode, helpText, defaultColorCode)
+		fmt.Printf("Insert Bumblebee transformation below\nPress Enter key twice to finish:\n")
+	}
+	input, err := prompt.Multiline(os.Stdin, interactive)
 	if err != nil {
 		return "", fmt.Errorf("input read: %w", err)
 	}
-	input = strings.TrimRight(input, "\n")
-	input = strings.TrimLeft(input, "\n")
 	return input, nil
 }
 
-func readInput() (string, error) {
-	var lines string
-	scn := bufio.NewScanner(os.Stdin)
-	for scn.Scan() {
-		line := scn.Text()
-		if len(line) == 0 {
-			break
-		}
-		lines = fmt.Sprintf("%s\n%s", lines, line)
-	}
-	return lines, scn.Err()
-}
-
-func (o *CliOptions) lookupTarget(ctx context.Context, target string) (triggermesh.Component, error) {
-	targetObject, err := components.GetObject(target, o.Config, o.Manifest, o.CRD)
-	if err != nil {
-		return nil, fmt.Errorf("transformation target: %w", err)
+// fromEditor opens the transformation spec in $EDITOR, the spec that
+// the transformation would be rejected with is opened again with the error on top.
+func (o *CliOptions) fromEditor(engine string) (string, error) {
+	validate := func(text string) error {
+		return o.builder().ValidateTransformation(engine, []byte(text))
 	}
-	if _, ok := targetObject.(triggermesh.Consumer); !ok {
-		return nil, fmt.Errorf("%q is not an event consumer", target)
+	if engine == transformation.EngineJQ {
+		return prompt.Edit("transformation-*.jq", "Insert JQ expression below.\nLines starting with '#' are ignored, empty input cancels the creation.", validate)
 	}
-	return targetObject, nil
+	template := helpText + "\n\nInsert Bumblebee transformation below.\nLines starting with '#' are ignored, empty input cancels the creation."
+	return prompt.Edit("transformation-*.yaml", template, validate)
 }
 
-func transformationContexts(target string, sourceEventTypes []string) string {
-	contexts := []string{}
-	for _, et := range sourceEventTypes {
-		c := et
-		if target != "" {
-			c = fmt.Sprintf("%s-%s", et, target)
-		}
-		contexts = append(contexts, c)
-	}
-	return strings.Join(contexts, ",")
+// lookupTarget returns the consumer component of the manifest
+// making sure that its container is running.
+func (o *CliOptions) lookupTarget(target string) (triggermesh.Component, error) {
+	return o.builder().LookupTarget(target)
 }
@@ -22,10 +22,13 @@ import (
 	"strings"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/util/validation"
+	"knative.dev/pkg/apis"
 
 	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
 
 	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/datafilter"
 	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
@@ -33,69 +36,284 @@ import (
 )
 
 func (o *CliOptions) newTriggerCmd() *cobra.Command {
-	var name, target, rawFilter string
+	var name, target, targetBroker, rawFilter, dataFilter, transform, allEventTypesOf, sequentialGroup string
 	var eventSourcesFilter, eventTypesFilter []string
+	var noVerify bool
+	var priority int
 	triggerCmd := &cobra.Command{
-		Use:       "trigger --target <name> [--source <name>...][--eventTypes <type>...]",
-		Short:     "Create TriggerMesh trigger. More information at https://docs.triggermesh.io/brokers/triggers/",
-		Example:   "tmctl create trigger --target sockeye --source foo-httppollersource",
-		ValidArgs: []string{"--target", "--name", "--source", "--eventTypes"},
+		Use:   "trigger --target <name> [--source <name>...][--eventTypes <type>...][--transform <file>][--data-filter <expression>]",
+		Short: "Create TriggerMesh trigger. More information at https://docs.triggermesh.io/brokers/triggers/",
+		Example: `tmctl create trigger --target sockeye --source foo-httppollersource
+
+tmctl create trigger --target sockeye --eventTypes order.created --transform rename.yaml
+
+tmctl create trigger --target transformation/foo-transformation --eventTypes order.created
+
+tmctl create trigger --target https://example.com/hooks/orders --eventTypes order.created
+
+tmctl create trigger --target-broker other-broker --eventTypes order.created
+
+tmctl create trigger --target sockeye --all-event-types-of awss3source
+
+tmctl create trigger --target post-only-service --eventTypes order.created --no-verify
+
+tmctl create trigger --target legacy-service --eventTypes order.created --content-mode structured
+
+tmctl create trigger --target sockeye --eventTypes order.created --data-filter 'data.region == "eu-west-1"'`,
+		ValidArgs: []string{"--target", "--target-broker", "--name", "--source", "--eventTypes", "--filter", "--data-filter", "--transform", "--event-type", "--target-path", "--no-verify", "--strict", "--content-mode", "--keep-partial", "--priority", "--sequential-group"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) > 0 {
 				return fmt.Errorf("unexpected argument(s): %v", args)
 			}
-			return o.trigger(name, rawFilter, eventSourcesFilter, eventTypesFilter, target)
+			if cmd.Flags().Changed("priority") || sequentialGroup != "" {
+				if err := validateOrdering(priority, sequentialGroup, o.Config.Triggermesh.Broker.Version); err != nil {
+					return err
+				}
+			}
+			if err := o.validateName(name); err != nil {
+				return err
+			}
+			if err := o.validateEventTypesFrom(); err != nil {
+				return err
+			}
+			if o.contentMode != "" {
+				if err := tmbroker.ValidateContentMode(o.contentMode); err != nil {
+					return err
+				}
+			}
+			o.verifyDestination = !noVerify
+			if cmd.Flags().Changed("strict") {
+				o.strictSpec = &o.strictDestination
+			}
+			if o.outputType != "" && transform == "" && dataFilter == "" {
+				return fmt.Errorf("--event-type requires --transform or --data-filter")
+			}
+			var expression *datafilter.Expression
+			if dataFilter != "" {
+				var err error
+				if expression, err = datafilter.Parse(dataFilter); err != nil {
+					return err
+				}
+			}
+			if (target == "") == (targetBroker == "") {
+				return fmt.Errorf("either --target or --target-broker must be set")
+			}
+			if allEventTypesOf != "" {
+				if targetBroker != "" || name != "" || len(eventSourcesFilter) != 0 || len(eventTypesFilter) != 0 {
+					return fmt.Errorf("--all-event-types-of cannot be combined with --target-broker, --name, --source or --eventTypes")
+				}
+				return o.transact(func() error {
+					return o.templateTriggers(target, allEventTypesOf)
+				})
+			}
+			return o.transact(func() error {
+				if targetBroker != "" {
+					return o.link(name, rawFilter, eventSourcesFilter, eventTypesFilter, targetBroker)
+				}
+				return o.trigger(name, rawFilter, expression, eventSourcesFilter, eventTypesFilter, target, transform)
+			})
 		},
 	}
 	triggerCmd.Flags().StringVar(&name, "name", "", "Trigger name")
-	triggerCmd.Flags().StringVar(&target, "target", "", "Target name")
+	triggerCmd.Flags().StringVar(&target, "target", "", "Target name, kind-qualified \"<kind>/<name>\" reference or external http(s) URI")
+	triggerCmd.Flags().StringVar(&targetBroker, "target-broker", "", "Forward the events to the broker of another context")
 	triggerCmd.Flags().StringVar(&rawFilter, "filter", "", "Raw filter JSON")
+	triggerCmd.Flags().StringVar(&dataFilter, "data-filter", "", "Expression on the event payload fields, e.g. 'data.region == \"eu-west-1\"', evaluated by the filter function before delivery")
 	triggerCmd.Flags().StringSliceVar(&eventSourcesFilter, "source", []string{}, "Event sources filter")
 	triggerCmd.Flags().StringSliceVar(&eventTypesFilter, "eventTypes", []string{}, "Event types filter")
-	cobra.CheckErr(triggerCmd.MarkFlagRequired("target"))
+	triggerCmd.Flags().StringVar(&o.eventTypesFrom, "event-types-from", eventTypesDeclared, "Event types of the sources filter, \"declared\" by the sources or \"observed\" by \"tmctl stats observe\"")
+	triggerCmd.Flags().StringVar(&allEventTypesOf, "all-event-types-of", "", "Create a trigger per event type produced by the source component or kind")
+	triggerCmd.Flags().StringVar(&transform, "transform", "", "Bumblebee transformation spec file applied to the events before delivery")
+	triggerCmd.Flags().StringVar(&o.targetPath, "target-path", "", "Path of the target address that the events are delivered to")
+	triggerCmd.Flags().StringVar(&o.outputType, "event-type", "", "Type of the events produced by the --transform spec or the --data-filter function, overrides the output-type-template config")
+	triggerCmd.Flags().BoolVar(&noVerify, "no-verify", false, "Do not probe the target component, e.g. if it rejects the requests other than POST")
+	triggerCmd.Flags().BoolVar(&o.strictDestination, "strict", false, "Fail if the target component does not respond, its port belongs to another container or the filter has unknown fields")
+	triggerCmd.Flags().StringVar(&o.contentMode, "content-mode", "", "CloudEvents content mode of the deliveries, \"binary\" or \"structured\", keeps the mode of the existing trigger if empty")
+	triggerCmd.Flags().BoolVar(&o.keepPartial, "keep-partial", false, "Keep the changes of the failed command instead of rolling them back, print the commands to undo them")
+	triggerCmd.Flags().IntVar(&priority, "priority", 0, "Dispatch priority among the triggers matching the same event, requires the broker supporting ordered dispatch")
+	triggerCmd.Flags().StringVar(&sequentialGroup, "sequential-group", "", "Dispatch the event to the triggers of the group one after another, requires the broker supporting ordered dispatch")
+	triggerCmd.MarkFlagsMutuallyExclusive("target", "target-broker")
+	triggerCmd.MarkFlagsMutuallyExclusive("target-path", "target-broker")
+	triggerCmd.MarkFlagsMutuallyExclusive("target-broker", "transform")
+	triggerCmd.MarkFlagsMutuallyExclusive("target-broker", "data-filter")
+	triggerCmd.MarkFlagsMutuallyExclusive("transform", "data-filter")
+	triggerCmd.MarkFlagsMutuallyExclusive("all-event-types-of", "data-filter")
+	triggerCmd.MarkFlagsMutuallyExclusive("all-event-types-of", "filter")
+	triggerCmd.MarkFlagsMutuallyExclusive("all-event-types-of", "transform")
+	triggerCmd.MarkFlagsMutuallyExclusive("no-verify", "strict")
 
 	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("name", cobra.NoFileCompletions))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("data-filter", cobra.NoFileCompletions))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("event-type", cobra.NoFileCompletions))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("target-path", cobra.NoFileCompletions))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("priority", cobra.NoFileCompletions))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("sequential-group", cobra.NoFileCompletions))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("event-types-from", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{eventTypesDeclared, eventTypesObserved}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("content-mode", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return tmbroker.ContentModes, cobra.ShellCompDirectiveNoFileComp
+	}))
 	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("source", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-		return completion.ListSources(o.Manifest), cobra.ShellCompDirectiveNoFileComp
+		return completion.Components(o.Manifest, completion.ListSources(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
 	}))
 	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("eventTypes", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-		return completion.ListEventTypes(o.Manifest, o.Config, o.CRD), cobra.ShellCompDirectiveNoFileComp
+		return completion.ListEventTypesWithOrigin(o.Manifest, o.Config, completion.Index(o.Config)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("all-event-types-of", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		sources := completion.ListSources(o.Manifest)
+		if kinds, err := o.listKinds("sources.triggermesh.io", "source"); err == nil {
+			sources = append(sources, kinds...)
+		}
+		return sources, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("target-broker", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.DescribeBrokers(completion.ListBrokers(o.Config.ConfigHome, o.Config.Context), completion.ContainerStatus()), cobra.ShellCompDirectiveNoFileComp
 	}))
 	cobra.CheckErr(triggerCmd.RegisterFlagCompletionFunc("target", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-		return completion.ListTargets(o.Manifest), cobra.ShellCompDirectiveNoFileComp
+		return completion.Components(o.Manifest, completion.ListTargets(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
 	}))
 	return triggerCmd
 }
 
-func (o *CliOptions) trigger(name string, rawFilter string, eventSourcesFilter, eventTypesFilter []string, target string) error {
-	var filters []*eventingbroker.Filter
+func (o *CliOptions) trigger(name string, rawFilter string, dataFilter *datafilter.Expression, eventSourcesFilter, eventTypesFilter []string, target, transform string) error {
+	filters, err := o.triggerFilters(rawFilter, eventSourcesFilter, eventTypesFilter)
+	if err != nil {
+		return err
+	}
+
+	if isURI(target) {
+		switch {
+		case transform != "":
+			return fmt.Errorf("--transform requires the target component")
+		case dataFilter != nil:
+			return fmt.Errorf("--data-filter requires the target component")
+		case o.targetPath != "":
+			return fmt.Errorf("--target-path is not supported for the URI target, include the path in the URI")
+		}
+		uri, err := apis.ParseURL(target)
+		if err != nil {
+			return fmt.Errorf("target URI: %w", err)
+		}
+		return o.createTriggers(name, o.uriTrigger(uri), filters)
+	}
+
+	component, err := o.targetComponent(target)
+	if err != nil {
+		return err
+	}
+
+	if transform != "" {
+		return o.transformedTrigger(name, transform, component, filters)
+	}
+	if dataFilter != nil {
+		return o.dataFilteredTrigger(name, dataFilter, component, filters)
+	}
+	return o.createTriggers(name, o.componentTrigger(component), filters)
+}
+
+// isURI returns true if the trigger target is the external address
+// rather than the component of the context.
+func isURI(target string) bool {
+	return strings.HasPrefix(target, "http://") || strings.HasPrefix(target, "https://")
+}
+
+// targetComponent returns the event consumer referenced by its name
+// or by the kind-qualified "<kind>/<name>" reference, e.g. "transformation/foo"
+// or "awss3target/bar". Target kinds may be given without the "target" suffix.
+func (o *CliOptions) targetComponent(reference string) (triggermesh.Component, error) {
+	name := reference
+	if kind, n, qualified := strings.Cut(reference, "/"); qualified {
+		name = n
+		found := false
+		for _, object := range o.Manifest.Objects {
+			if object.Metadata.Name == name &&
+				(strings.EqualFold(object.Kind, kind) || strings.EqualFold(object.Kind, kind+"target")) {
+				found = true
+				break
+			}
+		}
+		if !found {
+			return nil, fmt.Errorf("%s %q not found", kind, name)
+		}
+	}
+	component, err := components.GetObject(name, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return nil, fmt.Errorf("%q not found: %w", name, err)
+	}
+	if _, ok := component.(triggermesh.Consumer); !ok {
+		return nil, fmt.Errorf("%q is not an event target", name)
+	}
+	return component, nil
+}
+
+// componentTrigger returns the function creating the trigger
+// that delivers the events to the target component.
+func (o *CliOptions) componentTrigger(target triggermesh.Component) func(string, *eventingbroker.Filter) error {
+	return func(name string, filter *eventingbroker.Filter) error {
+		_, err := o.createPathTrigger(name, target, o.targetPath, filter)
+		return err
+	}
+}
+
+// uriTrigger returns the function creating the trigger
+// that delivers the events to the external URI.
+func (o *CliOptions) uriTrigger(uri *apis.URL) func(string, *eventingbroker.Filter) error {
+	return func(name string, filter *eventingbroker.Filter) error {
+		_, err := o.builder().AddURITrigger(name, uri, filter)
+		return err
+	}
+}
+
+// link creates the triggers forwarding the events to the broker of another context.
+func (o *CliOptions) link(name string, rawFilter string, eventSourcesFilter, eventTypesFilter []string, targetBroker string) error {
+	if targetBroker == o.Config.Context {
+		return fmt.Errorf("broker %q cannot forward events to itself", targetBroker)
+	}
+	filters, err := o.triggerFilters(rawFilter, eventSourcesFilter, eventTypesFilter)
+	if err != nil {
+		return err
+	}
+	if len(filters) == 0 {
+		return fmt.Errorf("forwarding all events to another broker is not allowed, set the event types or the filter")
+	}
+	component, err := tmbroker.LinkTarget(o.Config.ConfigHome, targetBroker, o.Config.Triggermesh.Broker)
+	if err != nil {
+		return err
+	}
+	return o.createTriggers(name, o.componentTrigger(component), filters)
+}
+
+func (o *CliOptions) triggerFilters(rawFilter string, eventSourcesFilter, eventTypesFilter []string) ([]*eventingbroker.Filter, error) {
 	if rawFilter != "" {
 		var filter eventingbroker.Filter
 		if err := json.Unmarshal([]byte(rawFilter), &filter); err != nil {
-			return fmt.Errorf("cannot decode filter JSON %q: %w", rawFilter, err)
+			return nil, fmt.Errorf("cannot decode filter JSON %q: %w", rawFilter, err)
 		}
-		filters = []*eventingbroker.Filter{&filter}
-	} else {
-		et, err := o.translateEventSource(eventSourcesFilter)
+		unknown, err := tmbroker.FilterUnknownFields(rawFilter)
 		if err != nil {
-			return err
+			return nil, fmt.Errorf("cannot decode filter JSON %q: %w", rawFilter, err)
 		}
-		for _, eventTypes := range append(eventTypesFilter, et...) {
-			filters = append(filters, tmbroker.FilterAttribute("type", eventTypes))
+		if err := o.unknownFields(unknown); err != nil {
+			return nil, err
 		}
+		return []*eventingbroker.Filter{&filter}, nil
 	}
-
-	component, err := components.GetObject(target, o.Config, o.Manifest, o.CRD)
+	et, err := o.translateEventSource(eventSourcesFilter)
 	if err != nil {
-		return fmt.Errorf("%q not found: %w", target, err)
+		return nil, err
 	}
-	if _, ok := component.(triggermesh.Consumer); !ok {
-		return fmt.Errorf("%q is not an event target", target)
+	var filters []*eventingbroker.Filter
+	for _, eventTypes := range append(eventTypesFilter, et...) {
+		filters = append(filters, tmbroker.FilterAttribute("type", eventTypes))
 	}
+	return filters, nil
+}
 
+func (o *CliOptions) createTriggers(name string, create func(string, *eventingbroker.Filter) error, filters []*eventingbroker.Filter) error {
 	log.Println("Creating trigger")
 	if len(filters) == 0 {
-		if _, err = o.createTrigger(name, component, nil); err != nil {
+		if err := create(name, nil); err != nil {
 			return err
 		}
 	}
@@ -106,23 +324,103 @@ func (o *CliOptions) trigger(name string, rawFilter string, eventSourcesFilter,
 		if name != "" {
 			newTrigger = fmt.Sprintf("%s-%d", name, i+1)
 		}
-		if _, err = o.createTrigger(newTrigger, component, filter); err != nil {
+		if err := create(newTrigger, filter); err != nil {
 			return err
 		}
 		delete(oldTriggers, newTrigger)
 	}
 
+	removed := make([]*tmbroker.Trigger, 0, len(oldTriggers))
 	for _, oldTrigger := range oldTriggers {
-		if err := oldTrigger.RemoveFromLocalConfig(); err != nil {
-			return err
+		removed = append(removed, oldTrigger)
+	}
+	return o.builder().RemoveTriggers(removed)
+}
+
+// templateTriggers creates a trigger per event type produced by the source
+// component or kind. Triggers have deterministic names so that the repeated
+// run creates only the triggers of the event types added since the last run.
+func (o *CliOptions) templateTriggers(target, source string) error {
+	eventTypes, err := o.producedEventTypes(source)
+	if err != nil {
+		return err
+	}
+	if len(eventTypes) == 0 {
+		return fmt.Errorf("%q does not declare the event types it produces", source)
+	}
+	if isURI(target) {
+		return fmt.Errorf("--all-event-types-of requires the target component")
+	}
+	component, err := o.targetComponent(target)
+	if err != nil {
+		return err
+	}
+	target = component.GetName()
+	configuration, err := tmbroker.ReadConfig(o.Config.ConfigHome, o.Config.Context)
+	if err != nil {
+		return fmt.Errorf("broker config: %w", err)
+	}
+	created := 0
+	for _, eventType := range eventTypes {
+		name := tmbroker.TemplateTriggerName(target, eventType)
+		filter := tmbroker.FilterAttribute("type", eventType)
+		if _, exists := configuration.Triggers[name]; exists {
+			continue
 		}
-		if err := o.Manifest.Remove(oldTrigger.Name, oldTrigger.GetKind()); err != nil {
-			return err
+		// the same route may exist under another name
+		if _, exists := tmbroker.FindTrigger(target, o.targetPath, o.Config.Context, o.Config.ConfigHome, []eventingbroker.Filter{*filter}); exists {
+			continue
+		}
+		if _, err := o.createPathTrigger(name, component, o.targetPath, filter); err != nil {
+			return fmt.Errorf("creating trigger for %q: %w", eventType, err)
 		}
+		log.Printf("Trigger %q delivers %q events to %q", name, eventType, target)
+		created++
+	}
+	if created == 0 {
+		log.Printf("Triggers for all %d event types of %q exist", len(eventTypes), source)
 	}
 	return nil
 }
 
+// producedEventTypes returns the event types of the manifest source
+// or, if there is no such component, of the source kind CRD.
+func (o *CliOptions) producedEventTypes(source string) ([]string, error) {
+	component, err := components.GetObject(source, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", source, err)
+	}
+	if component != nil {
+		producer, ok := component.(triggermesh.Producer)
+		if !ok {
+			return nil, fmt.Errorf("%q is not an event producer", source)
+		}
+		return producer.GetEventTypes()
+	}
+	kind := strings.ToLower(source)
+	for _, k := range []string{kind, kind + "source"} {
+		if c, exists := o.CRD[k]; exists {
+			return c.ProducedEventTypes(), nil
+		}
+	}
+	return nil, fmt.Errorf("%q is neither the component nor the source kind", source)
+}
+
+// validateOrdering checks the trigger dispatch order parameters.
+// No broker version has the dispatch order settings: triggers matching
+// the same event receive it concurrently, so the parameters are rejected
+// rather than silently dropped from the broker config.
+func validateOrdering(priority int, sequentialGroup, brokerVersion string) error {
+	if priority < 0 {
+		return fmt.Errorf("trigger priority must not be negative")
+	}
+	if sequentialGroup != "" && len(validation.IsDNS1123Label(sequentialGroup)) != 0 {
+		return fmt.Errorf("invalid sequential group name %q", sequentialGroup)
+	}
+	return fmt.Errorf("broker %s does not support ordered dispatch required by --priority and --sequential-group, "+
+		"triggers matching the same event are dispatched concurrently", brokerVersion)
+}
+
 func (o *CliOptions) listTriggers(prefix string) map[string]*tmbroker.Trigger {
 	result := make(map[string]*tmbroker.Trigger, 0)
 	for _, v := range o.Manifest.Objects {
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+
+	"gopkg.in/yaml.v3"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/datafilter"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/function"
+)
+
+// dataFilteredTrigger creates the filter function between the event filters and
+// the target. The broker matches the event attributes only, the function replies
+// with the events whose payload matches the expression and drops the rest.
+// The function and both triggers around it are annotated with the trigger name
+// so that they can be described and deleted as a single unit.
+func (o *CliOptions) dataFilteredTrigger(name string, expression *datafilter.Expression, target triggermesh.Component, filters []*eventingbroker.Filter) error {
+	ctx := context.Background()
+	if len(filters) == 0 {
+		// function output would be delivered back to the function
+		return fmt.Errorf("data filter requires event types, sources or filter")
+	}
+	code, err := expression.FunctionCode()
+	if err != nil {
+		return err
+	}
+
+	if name == "" {
+		filterStruct, _ := yaml.Marshal(filters)
+		hash := md5.Sum([]byte(fmt.Sprintf("%s-%s-%s", target.GetName(), string(filterStruct), expression)))
+		name = fmt.Sprintf("%s-trigger-%s", o.Config.Context, hex.EncodeToString(hash[:4]))
+	}
+
+	crd, exists := o.CRD["function"]
+	if !exists {
+		return fmt.Errorf("CRD for kind %q not found", function.Kind)
+	}
+	eventType, eventTypeOrigin, err := o.outputEventType(name, function.Kind)
+	if err != nil {
+		return err
+	}
+	for _, filter := range filters {
+		if filter.Exact["type"] == eventType {
+			return fmt.Errorf("data filter output type %q matches the trigger filter", eventType)
+		}
+	}
+	f := function.New(name+"-datafilter", o.Config.Context, o.Config.Triggermesh.ComponentsVersion, crd, map[string]interface{}{
+		"runtime":    datafilter.Runtime,
+		"entrypoint": datafilter.Entrypoint,
+		"code":       code,
+		"ceOverrides": map[string]interface{}{
+			"extensions": map[string]interface{}{
+				"type": eventType,
+			},
+		},
+	})
+	f.(*function.Function).Version = o.builder().ComponentVersion(f)
+
+	log.Println("Updating manifest")
+	applied, err := o.builder().Apply(f)
+	if err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	restart := applied != manifest.Unchanged
+	for key, value := range map[string]string{
+		triggermesh.OwnerAnnotation:           name,
+		triggermesh.EventTypeOriginAnnotation: eventTypeOrigin,
+		triggermesh.DataFilterAnnotation:      expression.String(),
+	} {
+		if err := o.Manifest.Annotate(f.GetName(), f.GetKind(), key, value); err != nil {
+			return fmt.Errorf("unable to update manifest: %w", err)
+		}
+	}
+
+	log.Println("Starting container")
+	if _, err := o.builder().StartContainer(ctx, f, nil, restart); err != nil {
+		return err
+	}
+	if restart {
+		if err := o.updateTriggers(f); err != nil {
+			return err
+		}
+	}
+
+	log.Println("Creating triggers")
+	outputTrigger, err := o.createPathTrigger(name+"-output", target, o.targetPath, tmbroker.FilterAttribute("type", eventType))
+	if err != nil {
+		return fmt.Errorf("creating trigger: %w", err)
+	}
+	if err := o.Manifest.Annotate(outputTrigger.GetName(), outputTrigger.GetKind(), triggermesh.OwnerAnnotation, name); err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	for i, filter := range filters {
+		input, err := o.createTrigger(fmt.Sprintf("%s-%d", name, i+1), f, filter)
+		if err != nil {
+			return fmt.Errorf("creating trigger: %w", err)
+		}
+		// the expression is kept on the input triggers that have
+		// the attribute filters to evaluate the events against both
+		for key, value := range map[string]string{
+			triggermesh.OwnerAnnotation:      name,
+			triggermesh.DataFilterAnnotation: expression.String(),
+		} {
+			if err := o.Manifest.Annotate(input.GetName(), input.GetKind(), key, value); err != nil {
+				return fmt.Errorf("unable to update manifest: %w", err)
+			}
+		}
+	}
+	return nil
+}
@@ -0,0 +1,53 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateOrdering(t *testing.T) {
+	testCases := map[string]struct {
+		priority        int
+		sequentialGroup string
+		err             string
+	}{
+		"priority": {
+			priority: 1,
+			err:      "broker v1.3.0 does not support ordered dispatch required by --priority and --sequential-group, triggers matching the same event are dispatched concurrently",
+		},
+		"sequential group": {
+			sequentialGroup: "cache",
+			err:             "broker v1.3.0 does not support ordered dispatch required by --priority and --sequential-group, triggers matching the same event are dispatched concurrently",
+		},
+		"negative priority": {
+			priority: -1,
+			err:      "trigger priority must not be negative",
+		},
+		"invalid group name": {
+			sequentialGroup: "Cache_Writers",
+			err:             `invalid sequential group name "Cache_Writers"`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.EqualError(t, validateOrdering(tc.priority, tc.sequentialGroup, "v1.3.0"), tc.err)
+		})
+	}
+}
@@ -0,0 +1,132 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package create
+
+import (
+	"context"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"os"
+
+	"gopkg.in/yaml.v3"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
+)
+
+// transformedTrigger creates the anonymous transformation between the event filters
+// and the target. The transformation and both triggers around it are annotated
+// with the trigger name so that they can be described and deleted as a single unit.
+func (o *CliOptions) transformedTrigger(name, specFile string, target triggermesh.Component, filters []*eventingbroker.Filter) error {
+	ctx := context.Background()
+	if len(filters) == 0 {
+		// transformation output would be delivered back to the transformation
+		return fmt.Errorf("transformed trigger requires event types, sources or filter")
+	}
+	data, err := os.ReadFile(specFile)
+	if err != nil {
+		return fmt.Errorf("file %q read: %w", specFile, err)
+	}
+	var spec map[string]interface{}
+	if err := yaml.Unmarshal(data, &spec); err != nil {
+		return fmt.Errorf("decode spec: %w", err)
+	}
+	for _, finding := range transformation.Lint(spec) {
+		log.Printf("WARNING! Transformation spec %s", finding)
+	}
+
+	if name == "" {
+		filterStruct, _ := yaml.Marshal(filters)
+		hash := md5.Sum([]byte(fmt.Sprintf("%s-%s-%s", target.GetName(), string(filterStruct), string(data))))
+		name = fmt.Sprintf("%s-trigger-%s", o.Config.Context, hex.EncodeToString(hash[:4]))
+	}
+
+	crd, exists := o.CRD["transformation"]
+	if !exists {
+		return fmt.Errorf("CRD for kind %q not found", "transformation")
+	}
+	t := transformation.New(name+"-transformation", "transformation", o.Config.Context,
+		o.Config.Triggermesh.ComponentsVersion, crd, spec)
+
+	eventType, eventTypeOrigin, err := o.outputEventType(name, t.GetKind())
+	if err != nil {
+		return err
+	}
+	if produced, _ := t.(triggermesh.Producer).GetEventTypes(); len(produced) != 0 {
+		eventType = produced[0]
+		eventTypeOrigin = triggermesh.EventTypeFromCRD
+	} else if err := t.(triggermesh.Producer).SetEventAttributes(map[string]string{
+		"type": eventType,
+	}); err != nil {
+		return fmt.Errorf("setting event type: %w", err)
+	}
+	for _, filter := range filters {
+		if filter.Exact["type"] == eventType {
+			return fmt.Errorf("transformation output type %q matches the trigger filter", eventType)
+		}
+	}
+
+	log.Println("Updating manifest")
+	applied, err := o.builder().Apply(t)
+	if err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	restart := applied != manifest.Unchanged
+	if err := o.Manifest.Annotate(t.GetName(), t.GetKind(), triggermesh.OwnerAnnotation, name); err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	if err := o.Manifest.Annotate(t.GetName(), t.GetKind(), triggermesh.EventTypeOriginAnnotation, eventTypeOrigin); err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+
+	log.Println("Starting container")
+	if _, err := o.builder().StartContainer(ctx, t, nil, restart); err != nil {
+		return err
+	}
+	if restart {
+		if err := o.updateTriggers(t); err != nil {
+			return err
+		}
+	}
+
+	log.Println("Creating triggers")
+	owned := []triggermesh.Component{}
+	outputTrigger, err := o.createPathTrigger(name+"-output", target, o.targetPath, tmbroker.FilterAttribute("type", eventType))
+	if err != nil {
+		return fmt.Errorf("creating trigger: %w", err)
+	}
+	owned = append(owned, outputTrigger)
+	for i, filter := range filters {
+		input, err := o.createTrigger(fmt.Sprintf("%s-%d", name, i+1), t, filter)
+		if err != nil {
+			return fmt.Errorf("creating trigger: %w", err)
+		}
+		owned = append(owned, input)
+	}
+	for _, trigger := range owned {
+		if err := o.Manifest.Annotate(trigger.GetName(), trigger.GetKind(), triggermesh.OwnerAnnotation, name); err != nil {
+			return fmt.Errorf("unable to update manifest: %w", err)
+		}
+	}
+	return nil
+}
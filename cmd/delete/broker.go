@@ -24,8 +24,10 @@ import (
 	"github.com/spf13/cobra"
 
 	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 )
 
 func (o *CliOptions) deleteBrokerCmd() *cobra.Command {
@@ -35,7 +37,7 @@ func (o *CliOptions) deleteBrokerCmd() *cobra.Command {
 		Example: "tmctl delete broker foo",
 		Args:    cobra.ExactArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return completion.ListObjectsByKind("RedisBroker", o.Manifest), cobra.ShellCompDirectiveNoFileComp
+			return completion.Components(o.Manifest, completion.ListObjectsByKind("RedisBroker", o.Manifest)), cobra.ShellCompDirectiveNoFileComp
 		}, RunE: func(cmd *cobra.Command, args []string) error {
 			return o.deleteBroker(args[0])
 		},
@@ -46,7 +48,11 @@ func (o *CliOptions) deleteBroker(broker string) error {
 	oo := *o
 	oo.Config.Context = broker
 	oo.Manifest = manifest.New(filepath.Join(oo.Config.ConfigHome, broker, triggermesh.ManifestFile))
-	cobra.CheckErr(oo.Manifest.Read())
+	if err := oo.Manifest.Read(); err != nil {
+		return fmt.Errorf("reading manifest: %w", err)
+	}
+
+	warnLinks(o.Config.ConfigHome, broker)
 
 	if err := oo.deleteBrokerComponents([]string{}, true); err != nil {
 		return fmt.Errorf("deleting component: %w", err)
@@ -54,8 +60,25 @@ func (o *CliOptions) deleteBroker(broker string) error {
 	if err := os.RemoveAll(filepath.Join(oo.Config.ConfigHome, broker)); err != nil {
 		return fmt.Errorf("delete broker %q: %v", broker, err)
 	}
+	o.Config.Triggermesh.Broker.SetImage(broker, "")
 	if broker == o.Config.Context {
 		return o.switchContext()
 	}
-	return nil
+	return o.Config.Save()
+}
+
+// warnLinks reports the links between the deleted broker and other contexts.
+func warnLinks(configBase, broker string) {
+	links, err := tmbroker.Links(configBase, broker)
+	if err != nil {
+		return
+	}
+	for _, link := range links {
+		if link.To == broker {
+			log.Printf("WARNING! Trigger %q of broker %q forwards events to %q and is left dangling, delete it with \"tmctl brokers --set %s && tmctl delete trigger %s\"",
+				link.Trigger, link.From, broker, link.From, link.Trigger)
+			continue
+		}
+		log.Printf("WARNING! Events are no longer forwarded from %q to %q, trigger %q is deleted", broker, link.To, link.Trigger)
+	}
 }
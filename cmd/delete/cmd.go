@@ -18,6 +18,7 @@ package delete
 
 import (
 	"context"
+	"errors"
 	"fmt"
 	"strings"
 
@@ -30,16 +31,26 @@ import (
 	"github.com/triggermesh/tmctl/pkg/kubernetes"
 	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/output"
+	"github.com/triggermesh/tmctl/pkg/secrets"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/filesink"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/ingest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/replicas"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/signature"
 )
 
 type CliOptions struct {
 	Config   *config.Config
 	Manifest *manifest.Manifest
 	CRD      map[string]crd.CRD
+
+	Yes     bool
+	NoColor bool
 }
 
 func NewCmd(config *config.Config, manifest *manifest.Manifest, crds map[string]crd.CRD) *cobra.Command {
@@ -49,17 +60,42 @@ func NewCmd(config *config.Config, manifest *manifest.Manifest, crds map[string]
 		Manifest: manifest,
 	}
 	deleteCmd := &cobra.Command{
-		Use:   "delete <kind> <name>",
+		Use:   "delete [<kind> <name>]",
 		Short: "Delete TriggerMesh component",
+		Long: `Delete TriggerMesh component.
+
+Without arguments, the command lists the components of the current broker
+to pick the ones to delete and shows the triggers and containers that are
+removed along with them. The list requires an interactive terminal.`,
+		Example: `tmctl delete
+
+tmctl delete --yes --no-color
+
+tmctl delete source foo-awss3source`,
 		// CompletionOptions: cobra.CompletionOptions{DisableDescriptions: true},
-		Args: cobra.MinimumNArgs(1),
-		PersistentPreRun: func(cmd *cobra.Command, _ []string) {
-			cobra.CheckErr(docker.CheckDaemon())
+		PersistentPreRunE: func(cmd *cobra.Command, _ []string) error {
+			if err := docker.CheckDaemon(); err != nil {
+				return err
+			}
 			if cmd.Name() != "broker" {
-				cobra.CheckErr(o.Manifest.Read())
+				if err := o.Manifest.Read(); err != nil {
+					return fmt.Errorf("reading manifest: %w", err)
+				}
+			}
+			return nil
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				return fmt.Errorf("unknown component kind %q", args[0])
+			}
+			if !output.Interactive() {
+				return fmt.Errorf("component kind and name are required when the terminal is not interactive")
 			}
+			return o.pick()
 		},
 	}
+	deleteCmd.Flags().BoolVarP(&o.Yes, "yes", "y", false, "Do not ask for the confirmation of the picked components deletion")
+	deleteCmd.Flags().BoolVar(&o.NoColor, "no-color", false, "Disable colors in the components list")
 	deleteCmd.AddCommand(o.deleteBrokerCmd())
 	deleteCmd.AddCommand(o.deleteSourceCmd())
 	deleteCmd.AddCommand(o.deleteTargetCmd())
@@ -106,16 +142,33 @@ func (o *CliOptions) deleteBrokerComponents(names []string, deleteBroker bool) e
 func (o *CliOptions) deleteEverything(ctx context.Context, object kubernetes.Object, client *client.Client) {
 	log.Printf("Deleting %q %s", object.Metadata.Name, strings.ToLower(object.Kind))
 	if object.Kind == tmbroker.BrokerKind {
+		_ = ingest.Remove(ctx, o.Config.ConfigHome, object.Metadata.Name)
 		object.Metadata.Name = object.Metadata.Name + "-broker"
 	}
 	if err := o.removeExternalServices(ctx, object); err != nil && !strings.HasPrefix(err.Error(), "Unsubscribed from topic") {
 		log.Printf("WARNING: external services are not deleted: %v", err)
 	}
 	// not all components are runnable, but removeContainer should try to stop it anyway
-	_ = o.removeContainer(ctx, object.Metadata.Name, client)
+	if count := replicas.Count(object); count > 1 {
+		_ = replicas.Remove(ctx, object.Metadata.Name, count)
+	}
+	if _, verified := signature.FromObject(object); verified {
+		_ = signature.Remove(ctx, o.Config.ConfigHome, o.Config.Context, object.Metadata.Name)
+	}
+	if _, adopted := object.Metadata.Annotations[service.AdoptedContainerAnnotation]; !adopted {
+		_ = o.removeContainer(ctx, object.Metadata.Name, client)
+	}
+	if object.APIVersion == filesink.APIVersion {
+		if path, _ := object.Spec["path"].(string); path != "" {
+			log.Printf("Event files of %q are kept in %s", object.Metadata.Name, path)
+		}
+	}
 	o.removeObject(object.Metadata.Name)
 	o.cleanupTriggers(object.Metadata.Name)
 	o.cleanupSecrets(object.Metadata.Name)
+	if err := secrets.Context(o.Config.ConfigHome, o.Config.Context).Remove(object.Metadata.Name); err != nil {
+		log.Printf("Deleting secret files of %q: %v", object.Metadata.Name, err)
+	}
 }
 
 func (o *CliOptions) removeObject(component string) {
@@ -171,6 +224,11 @@ func (o *CliOptions) cleanupSecrets(component string) {
 
 func (o *CliOptions) removeExternalServices(ctx context.Context, object kubernetes.Object) error {
 	component, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+	var unsupported *components.UnsupportedKindError
+	if errors.As(err, &unsupported) {
+		// unknown kinds are not reconciled by tmctl
+		return nil
+	}
 	if err != nil {
 		return err
 	}
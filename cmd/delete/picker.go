@@ -0,0 +1,177 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package delete
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/output"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/replicas"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/signature"
+)
+
+// pick lets the user choose the components to delete from the list,
+// prints the cascade plan and deletes the components after confirmation.
+func (o *CliOptions) pick() error {
+	ctx := context.Background()
+	var items []output.Item
+	for _, object := range o.Manifest.Objects {
+		if !deletable(object) {
+			continue
+		}
+		items = append(items, output.Item{
+			Name:   object.Metadata.Name,
+			Group:  object.Kind,
+			Status: o.status(ctx, object),
+		})
+	}
+	if len(items) == 0 {
+		log.Printf("Broker %q has no components to delete", o.Config.Context)
+		return nil
+	}
+
+	list := output.NewList(o.NoColor)
+	selected, err := list.Select(fmt.Sprintf("Components of broker %q:", o.Config.Context), items)
+	if err != nil {
+		return fmt.Errorf("selecting components: %w", err)
+	}
+	if len(selected) == 0 {
+		log.Println("Nothing selected")
+		return nil
+	}
+
+	names := make([]string, 0, len(selected))
+	for _, item := range selected {
+		names = append(names, item.Name)
+	}
+	fmt.Fprintln(list.Out, "\nDeletion plan:")
+	for _, line := range o.plan(names) {
+		fmt.Fprintln(list.Out, line)
+	}
+	if !o.Yes {
+		confirmed, err := list.Confirm(fmt.Sprintf("Delete %d component(s)?", len(names)))
+		if err != nil {
+			return fmt.Errorf("confirmation: %w", err)
+		}
+		if !confirmed {
+			log.Println("Aborted")
+			return nil
+		}
+	}
+	return o.deleteBrokerComponents(names, false)
+}
+
+// deletable reports whether the object can be picked for deletion.
+// Brokers are deleted with their own command, secrets and owned
+// objects are removed together with their components.
+func deletable(object kubernetes.Object) bool {
+	if object.Kind == "Secret" || object.Kind == tmbroker.BrokerKind {
+		return false
+	}
+	_, owned := object.Metadata.Annotations[triggermesh.OwnerAnnotation]
+	return !owned || object.Kind == tmbroker.TriggerKind
+}
+
+func (o *CliOptions) status(ctx context.Context, object kubernetes.Object) string {
+	c, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+	var unsupported *components.UnsupportedKindError
+	if errors.As(err, &unsupported) {
+		return unsupported.Status()
+	}
+	if err != nil || c == nil {
+		return ""
+	}
+	runnable, ok := c.(triggermesh.Runnable)
+	if !ok {
+		return ""
+	}
+	if container, err := runnable.Info(ctx); err == nil && container.Online {
+		return "online"
+	}
+	return "offline"
+}
+
+// plan describes the objects and containers that are removed
+// along with the selected components.
+func (o *CliOptions) plan(names []string) []string {
+	selected := make(map[string]struct{}, len(names))
+	for _, name := range names {
+		selected[name] = struct{}{}
+	}
+	var lines []string
+	for _, object := range o.Manifest.Objects {
+		if _, ok := selected[object.Metadata.Name]; !ok {
+			continue
+		}
+		lines = append(lines, fmt.Sprintf("  %s %q", object.Kind, object.Metadata.Name))
+		if containers := o.containers(object); len(containers) != 0 {
+			lines = append(lines, fmt.Sprintf("    containers: %s", strings.Join(containers, ", ")))
+		}
+		triggers, err := tmbroker.GetTargetTriggers(object.Metadata.Name, o.Config.Context, o.Config.ConfigHome)
+		if err == nil {
+			var cascade []string
+			for _, trigger := range triggers {
+				if _, ok := selected[trigger.GetName()]; !ok {
+					cascade = append(cascade, trigger.GetName())
+				}
+			}
+			if len(cascade) != 0 {
+				lines = append(lines, fmt.Sprintf("    triggers:   %s", strings.Join(cascade, ", ")))
+			}
+		}
+		for _, secret := range o.Manifest.Objects {
+			if secret.Kind == "Secret" && secret.Metadata.Name == object.Metadata.Name+"-secret" {
+				lines = append(lines, fmt.Sprintf("    secret:     %s", secret.Metadata.Name))
+			}
+		}
+	}
+	return lines
+}
+
+// containers returns the names of the containers that deleteEverything stops.
+func (o *CliOptions) containers(object kubernetes.Object) []string {
+	c, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+	if err != nil || c == nil {
+		return nil
+	}
+	if _, ok := c.(triggermesh.Runnable); !ok {
+		return nil
+	}
+	var result []string
+	if _, adopted := object.Metadata.Annotations[service.AdoptedContainerAnnotation]; !adopted {
+		result = append(result, object.Metadata.Name)
+	}
+	if count := replicas.Count(object); count > 1 {
+		for i := 1; i < count; i++ {
+			result = append(result, replicas.Name(object.Metadata.Name, i))
+		}
+		result = append(result, replicas.ProxyName(object.Metadata.Name))
+	}
+	if _, verified := signature.FromObject(object); verified {
+		result = append(result, signature.ProxyName(object.Metadata.Name))
+	}
+	return result
+}
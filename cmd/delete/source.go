@@ -32,8 +32,9 @@ func (o *CliOptions) deleteSourceCmd() *cobra.Command {
 		Example: "tmctl delete source foo",
 		Args:    cobra.MinimumNArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return append(completion.ListObjectsByAPI("sources.triggermesh.io/v1alpha1", o.Manifest),
-					completion.ListObjectsByAPI("serving.knative.dev/v1", o.Manifest)...),
+			return completion.Components(o.Manifest,
+					completion.ListObjectsByAPI("sources.triggermesh.io/v1alpha1", o.Manifest),
+					completion.ListObjectsByAPI("serving.knative.dev/v1", o.Manifest)),
 				cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
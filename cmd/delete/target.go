@@ -23,6 +23,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/triggermesh/tmctl/pkg/completion"
 	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/filesink"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/function"
 )
 
 func (o *CliOptions) deleteTargetCmd() *cobra.Command {
@@ -32,8 +34,11 @@ func (o *CliOptions) deleteTargetCmd() *cobra.Command {
 		Example: "tmctl delete target foo",
 		Args:    cobra.MinimumNArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return append(completion.ListObjectsByAPI("targets.triggermesh.io/v1alpha1", o.Manifest),
-					completion.ListObjectsByAPI("serving.knative.dev/v1", o.Manifest)...),
+			return completion.Components(o.Manifest,
+					completion.ListObjectsByAPI("targets.triggermesh.io/v1alpha1", o.Manifest),
+					completion.ListObjectsByAPI(function.APIVersion, o.Manifest),
+					completion.ListObjectsByAPI(filesink.APIVersion, o.Manifest),
+					completion.ListObjectsByAPI("serving.knative.dev/v1", o.Manifest)),
 				cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
@@ -50,6 +55,8 @@ func (o *CliOptions) deleteTarget(names []string) error {
 	}
 	for _, object := range o.Manifest.Objects {
 		if object.APIVersion != "targets.triggermesh.io/v1alpha1" &&
+			object.APIVersion != function.APIVersion &&
+			object.APIVersion != filesink.APIVersion &&
 			object.APIVersion != "serving.knative.dev/v1" {
 			continue
 		}
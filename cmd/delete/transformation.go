@@ -21,8 +21,11 @@ import (
 	"fmt"
 
 	"github.com/spf13/cobra"
+
 	"github.com/triggermesh/tmctl/pkg/completion"
 	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
 )
 
 func (o *CliOptions) deleteTransformationCmd() *cobra.Command {
@@ -32,7 +35,8 @@ func (o *CliOptions) deleteTransformationCmd() *cobra.Command {
 		Example: "tmctl delete transformation foo",
 		Args:    cobra.MinimumNArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return completion.ListObjectsByKind("Transformation", o.Manifest), cobra.ShellCompDirectiveNoFileComp
+			return completion.Components(o.Manifest, append(completion.ListObjectsByKind("Transformation", o.Manifest),
+				completion.ListObjectsByKind("JQTransformation", o.Manifest)...)), cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return o.deleteTransformation(args)
@@ -46,16 +50,22 @@ func (o *CliOptions) deleteTransformation(names []string) error {
 	if err != nil {
 		return fmt.Errorf("docker client: %w", err)
 	}
+	// output stages of the JQ transformations
+	// are deleted together with their owners
+	var objects []kubernetes.Object
 	for _, object := range o.Manifest.Objects {
-		if object.Kind != "Transformation" {
+		if object.Kind != "Transformation" && object.Kind != "JQTransformation" {
 			continue
 		}
 		for _, name := range names {
-			if name == object.Metadata.Name {
-				o.deleteEverything(ctx, object, client)
+			if name == object.Metadata.Name || name == object.Metadata.Annotations[triggermesh.OwnerAnnotation] {
+				objects = append(objects, object)
 				break
 			}
 		}
 	}
+	for _, object := range objects {
+		o.deleteEverything(ctx, object, client)
+	}
 	return nil
 }
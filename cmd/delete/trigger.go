@@ -23,6 +23,8 @@ import (
 	"github.com/spf13/cobra"
 	"github.com/triggermesh/tmctl/pkg/completion"
 	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
 )
 
 func (o *CliOptions) deleteTriggerCmd() *cobra.Command {
@@ -32,7 +34,7 @@ func (o *CliOptions) deleteTriggerCmd() *cobra.Command {
 		Example: "tmctl delete trigger foo",
 		Args:    cobra.MinimumNArgs(1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return completion.ListObjectsByKind("Trigger", o.Manifest), cobra.ShellCompDirectiveNoFileComp
+			return completion.Components(o.Manifest, completion.ListObjectsByKind("Trigger", o.Manifest)), cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			return o.deleteTrigger(args)
@@ -46,16 +48,39 @@ func (o *CliOptions) deleteTrigger(names []string) error {
 	if err != nil {
 		return fmt.Errorf("docker client: %w", err)
 	}
+	// triggers created with the inline transformation
+	// are deleted together with the rest of the set
+	owners := make(map[string]struct{}, len(names))
 	for _, object := range o.Manifest.Objects {
+		if object.Kind != "Trigger" {
+			continue
+		}
+		for _, name := range names {
+			if name == object.Metadata.Name || name == object.Metadata.Annotations[triggermesh.OwnerAnnotation] {
+				if owner, set := object.Metadata.Annotations[triggermesh.OwnerAnnotation]; set {
+					owners[owner] = struct{}{}
+				}
+			}
+		}
+	}
+	var objects []kubernetes.Object
+	for _, object := range o.Manifest.Objects {
+		if _, owned := owners[object.Metadata.Annotations[triggermesh.OwnerAnnotation]]; owned {
+			objects = append(objects, object)
+			continue
+		}
 		if object.Kind != "Trigger" {
 			continue
 		}
 		for _, name := range names {
 			if name == object.Metadata.Name {
-				o.deleteEverything(ctx, object, client)
+				objects = append(objects, object)
 				break
 			}
 		}
 	}
+	for _, object := range objects {
+		o.deleteEverything(ctx, object, client)
+	}
 	return nil
 }
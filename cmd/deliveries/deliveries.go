@@ -0,0 +1,160 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliveries
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/deliveries"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+
+	trigger string
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest) *cobra.Command {
+	o := &CliOptions{
+		Config:   config,
+		Manifest: manifest,
+	}
+	var pending bool
+	var cancel, retryNow string
+	deliveriesCmd := &cobra.Command{
+		Use:   "deliveries [broker][--pending][--cancel <event-id>][--retry-now <event-id>][--trigger <name>]",
+		Short: "Inspect the events awaiting the delivery to the trigger targets",
+		Long: `Inspect the events awaiting the delivery to the trigger targets.
+Pending deliveries are read from the broker backend and require the redis broker.
+The number of attempts and the next attempt time are estimated from the trigger
+delivery options.`,
+		Example: `tmctl deliveries --pending
+tmctl deliveries --cancel 8f7f2a3e-5b8b-4d1c-9d3e-1b2a6f0c7e55
+tmctl deliveries --retry-now 8f7f2a3e-5b8b-4d1c-9d3e-1b2a6f0c7e55 --trigger orders-trigger`,
+		Args: cobra.RangeArgs(0, 1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return []string{}, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completion.DescribeBrokers(completion.ListBrokers(o.Config.ConfigHome, o.Config.Context), completion.ContainerStatus()), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				o.Config.Context = args[0]
+			}
+			switch {
+			case pending:
+				return o.pending()
+			case cancel != "":
+				return o.cancel(cancel)
+			case retryNow != "":
+				return o.retryNow(retryNow)
+			}
+			return fmt.Errorf("one of --pending, --cancel or --retry-now is required")
+		},
+	}
+	deliveriesCmd.Flags().BoolVar(&pending, "pending", false, "List the events awaiting the delivery")
+	deliveriesCmd.Flags().StringVar(&cancel, "cancel", "", "Drop the pending event so that the broker stops delivering it")
+	deliveriesCmd.Flags().StringVar(&retryNow, "retry-now", "", "Deliver the pending event to the trigger target immediately")
+	deliveriesCmd.Flags().StringVar(&o.trigger, "trigger", "", "Limit the deliveries to the trigger")
+	deliveriesCmd.MarkFlagsMutuallyExclusive("pending", "cancel", "retry-now")
+	cobra.CheckErr(deliveriesCmd.RegisterFlagCompletionFunc("trigger", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.Components(o.Manifest, completion.ListObjectsByKind(tmbroker.TriggerKind, o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	return deliveriesCmd
+}
+
+func (o *CliOptions) open() (*deliveries.Queue, error) {
+	q, err := deliveries.Open(o.Config.ConfigHome, o.Config.Context, o.Config.Triggermesh.Broker)
+	if errors.Is(err, deliveries.ErrUnsupported) {
+		return nil, fmt.Errorf("broker %q: %w, see \"tmctl config set triggermesh.broker.redis.address\"", o.Config.Context, err)
+	}
+	return q, err
+}
+
+func (o *CliOptions) pending() error {
+	q, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+	pending, err := q.Pending(o.trigger)
+	if err != nil {
+		return fmt.Errorf("pending deliveries: %w", err)
+	}
+	if len(pending) == 0 {
+		fmt.Println("No events awaiting the delivery")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
+	fmt.Fprintln(w, "Trigger\tEvent ID\tType\tTarget\tAttempts\tNext Attempt")
+	for _, p := range pending {
+		next := "-"
+		if !p.NextAttempt.IsZero() {
+			next = p.NextAttempt.Format(time.RFC3339)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t~%d\t%s\n", p.Trigger, p.Event.ID(), p.Event.Type(), p.Target, p.Attempts, next)
+	}
+	return w.Flush()
+}
+
+func (o *CliOptions) cancel(eventID string) error {
+	q, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+	p, err := q.Find(o.trigger, eventID)
+	if err != nil {
+		return fmt.Errorf("event %q: %w", eventID, err)
+	}
+	if err := q.Cancel(p); err != nil {
+		return fmt.Errorf("cancel event %q: %w", eventID, err)
+	}
+	log.Printf("Event %q delivery to %q cancelled", eventID, p.Target)
+	return nil
+}
+
+func (o *CliOptions) retryNow(eventID string) error {
+	q, err := o.open()
+	if err != nil {
+		return err
+	}
+	defer q.Close()
+	p, err := q.Find(o.trigger, eventID)
+	if err != nil {
+		return fmt.Errorf("event %q: %w", eventID, err)
+	}
+	if err := q.Deliver(context.Background(), p); err != nil {
+		return fmt.Errorf("retry event %q: %w", eventID, err)
+	}
+	log.Printf("Event %q delivered to %q", eventID, p.Target)
+	return nil
+}
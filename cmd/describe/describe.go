@@ -18,28 +18,51 @@ package describe
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"os"
 	"path/filepath"
+	"strconv"
 	"strings"
 	"text/tabwriter"
+	"time"
 
 	kyaml "sigs.k8s.io/yaml"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
 
+	"github.com/triggermesh/tmctl/pkg/completion"
 	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/crashlog"
+	"github.com/triggermesh/tmctl/pkg/deliveries"
+	"github.com/triggermesh/tmctl/pkg/health"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/observed"
+	"github.com/triggermesh/tmctl/pkg/throughput"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/filesink"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/ingest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/logging"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/replicas"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/signature"
+	"github.com/triggermesh/tmctl/pkg/tunnel"
+	"github.com/triggermesh/tmctl/pkg/urls"
 )
 
+// previousLogsLines is the number of the saved output lines shown inline.
+const previousLogsLines = 3
+
 const (
 	successColorCode = "\033[92m"
 	defaultColorCode = "\033[39m"
@@ -50,6 +73,15 @@ type CliOptions struct {
 	Config   *config.Config
 	Manifest *manifest.Manifest
 	CRD      map[string]crd.CRD
+
+	// fast skips the image digest resolution.
+	fast bool
+	// noProbe skips the consumers health endpoint requests.
+	noProbe bool
+	// metrics adds the observed event rates of the components.
+	metrics    bool
+	digests    digestCache
+	containers map[string]containerInfo
 }
 
 func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
@@ -58,11 +90,19 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 		Config:   config,
 		Manifest: m,
 	}
-	return &cobra.Command{
-		Use:     "describe [broker]",
-		Short:   "List broker components and their statuses",
-		Example: "tmctl describe",
-		Args:    cobra.RangeArgs(0, 1),
+	var example, eventFile string
+	var watch, untilReady bool
+	describeCmd := &cobra.Command{
+		Use:   "describe [broker][--watch [--until-ready]][--metrics][--example <transformation>][--event <file>]",
+		Short: "List broker components and their statuses",
+		Example: `tmctl describe
+
+tmctl describe --watch --until-ready
+
+tmctl describe --metrics
+
+tmctl describe --example foo-transformation --event order.json`,
+		Args: cobra.RangeArgs(0, 1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 			return []string{}, cobra.ShellCompDirectiveNoFileComp
 		},
@@ -74,33 +114,118 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 					o.Config.Context,
 					triggermesh.ManifestFile))
 			}
-			cobra.CheckErr(o.Manifest.Read())
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			if example != "" {
+				return o.Example(example, eventFile)
+			}
+			if watch {
+				return o.Watch(untilReady)
+			}
+			if untilReady {
+				return fmt.Errorf("--until-ready requires --watch")
+			}
 			return o.Describe()
 		},
 	}
+	describeCmd.Flags().BoolVar(&watch, "watch", false, "Refresh the output until interrupted")
+	describeCmd.Flags().BoolVar(&untilReady, "until-ready", false, "Stop watching once all components are online")
+	describeCmd.Flags().BoolVar(&o.fast, "fast", false, "Skip the image digests resolution")
+	describeCmd.Flags().BoolVar(&o.metrics, "metrics", false, "Show the events per minute received and sent by the components over the last 1 and 5 minutes")
+	describeCmd.Flags().BoolVar(&o.noProbe, "no-probe", false, "Skip the health endpoint probing of the targets and transformations")
+	describeCmd.Flags().StringVar(&example, "example", "", "Show the sample event transformed by the transformation")
+	describeCmd.Flags().StringVar(&eventFile, "event", "", "Sample event, recording or payload file used with --example")
+	cobra.CheckErr(describeCmd.RegisterFlagCompletionFunc("example", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.Components(o.Manifest, completion.ListObjectsByKind("Transformation", o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	return describeCmd
 }
 
 func (o *CliOptions) Describe() error {
-	broker := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
-	triggers := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
-	producers := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
-	consumers := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
-	transformations := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
+	return o.describe(os.Stdout)
+}
+
+func (o *CliOptions) describe(out io.Writer) error {
+	if o.Config.StrictKinds {
+		if err := components.CheckKinds(o.Manifest, o.CRD); err != nil {
+			return err
+		}
+	}
+	broker := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	triggers := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	producers := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	consumers := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	transformations := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	creds := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	provenance := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	images := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	links := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	network := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	rates := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	signatures := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	limits := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	previous := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	unsupported := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	fileSinks := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
 	fmt.Fprintln(broker, "Broker\tStatus")
-	fmt.Fprintln(triggers, "Trigger\tTarget\tFilter")
-	fmt.Fprintln(transformations, "Transformation\tEventTypes\tStatus")
-	fmt.Fprintln(producers, "Source\tKind\tEventTypes\tStatus")
-	fmt.Fprintln(consumers, "Target\tKind\tExpected Events\tStatus")
+	fmt.Fprintln(triggers, "Trigger\tTarget\tFilter\tContent Mode")
+	fmt.Fprintln(transformations, "Transformation\tEngine\tEventTypes\tLog Level\tStatus\tHealth")
+	fmt.Fprintln(producers, "Source\tKind\tEventTypes\tObserved\tLog Level\tStatus")
+	fmt.Fprintln(consumers, "Target\tKind\tExpected Events\tLog Level\tStatus\tHealth")
+	fmt.Fprintln(creds, "Credentials\tProfile\tExpires")
+	fmt.Fprintln(provenance, "Provenance\tKind\tVersion\tCreated\tModified\tCommand")
+	fmt.Fprintln(images, "Image\tDigest\tComponents")
+	fmt.Fprintln(links, "Link\tFrom\tTo\tFilter")
+	fmt.Fprintln(network, "Network\tInternal\tHost")
+	fmt.Fprintln(rates, "Throughput\tKind\tIn/min (1m/5m)\tOut/min (1m/5m)")
+	fmt.Fprintln(signatures, "Signature\tHeader\tAlgorithm\tEndpoint\tRejected")
+	fmt.Fprintln(limits, "Ingest Limits\tMax Payload Size\tRate Limit\tEndpoint")
+	fmt.Fprintln(previous, "Previous Logs\tExited\tLast Lines")
+	fmt.Fprintln(unsupported, "Unsupported\tKind\tStatus")
+	fmt.Fprintln(fileSinks, "File Sink\tCurrent File\tSize\tMax Size")
 	brokersPrint := false
 	triggersPrint := false
 	transformationsPrint := false
 	producersPrint := false
 	consumersPrint := false
+	credsPrint := false
+	provenancePrint := false
+	unsupportedPrint := false
+
+	observedTypes, err := observed.Load(o.Config.ConfigHome, o.Config.Context)
+	if err != nil {
+		log.Printf("WARNING! Observed event types: %v", err)
+	}
+
+	// inline transformations are rendered as the part of their triggers
+	inlineTargets := o.inlineTransformationTargets()
+
+	runnables := o.runnables()
+	o.containers = o.inspect(runnables)
 
 	for _, object := range o.Manifest.Objects {
+		if created, set := object.Metadata.Annotations[triggermesh.ProvenanceCreatedAnnotation]; set {
+			provenancePrint = true
+			fmt.Fprintf(provenance, "%s\t%s\t%s\t%s\t%s\t%s\n", object.Metadata.Name, object.Kind,
+				annotationOrDash(object, triggermesh.ProvenanceVersionAnnotation), created,
+				annotationOrDash(object, triggermesh.ProvenanceModifiedAnnotation),
+				annotationOrDash(object, triggermesh.ProvenanceCommandAnnotation))
+		}
+		owner, owned := object.Metadata.Annotations[triggermesh.OwnerAnnotation]
+		if owned && object.Kind != tmbroker.TriggerKind {
+			continue
+		}
 		c, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+		var unsupportedKind *components.UnsupportedKindError
+		if errors.As(err, &unsupportedKind) {
+			unsupportedPrint = true
+			fmt.Fprintf(unsupported, "%s\t%s\t%s\n", object.Metadata.Name, object.Kind, unsupportedKind.Status())
+			continue
+		}
 		if err != nil {
-			return fmt.Errorf("creating component interface: %w", err)
+			log.Printf("WARNING! Skipping %s %q: %v", object.Kind, object.Metadata.Name, err)
+			continue
 		}
 		if c == nil {
 			continue
@@ -109,18 +234,54 @@ func (o *CliOptions) Describe() error {
 			switch c.GetKind() {
 			case tmbroker.BrokerKind:
 				brokersPrint = true
-				fmt.Fprintf(broker, "%s\t%s\n", c.GetName(), status(c))
+				name := c.GetName()
+				if image, set := o.Config.Triggermesh.Broker.Images[name]; set {
+					name = fmt.Sprintf("%s (%s)", name, image)
+				}
+				fmt.Fprintf(broker, "%s\t%s\n", name, o.status(c))
 			case tmbroker.TriggerKind:
 				filterString := "*"
 				if len(c.(*tmbroker.Trigger).Filters) != 0 {
 					filterString = triggerFilterToString(c.(*tmbroker.Trigger).Filters)
 				}
+				target := c.(*tmbroker.Trigger).TargetName()
+				if url := c.(*tmbroker.Trigger).LocalURL; url != nil && c.(*tmbroker.Trigger).TargetPath() != "" {
+					target = fmt.Sprintf("%s (%s)", target, url)
+				}
+				if owned {
+					finalTarget, ok := inlineTargets[owner]
+					switch {
+					case !ok:
+						continue
+					case target == owner+"-transformation":
+						target = finalTarget + " (transformed)"
+					case target == owner+"-datafilter":
+						// broker filters match the attributes only,
+						// the payload is matched by the owned function
+						target = fmt.Sprintf("%s (data filtered by function %s)", finalTarget, target)
+						filterString = appendDataFilter(filterString, object.Metadata.Annotations[triggermesh.DataFilterAnnotation])
+					default:
+						// transformation or data filter output trigger
+						continue
+					}
+				}
+				contentMode := c.(*tmbroker.Trigger).ContentMode
+				if contentMode == "" {
+					contentMode = tmbroker.ContentModeBinary
+				}
 				triggersPrint = true
-				fmt.Fprintf(triggers, "%s\t%s\t%s\n", c.GetName(), c.(*tmbroker.Trigger).Target.Ref.Name, filterString)
+				fmt.Fprintf(triggers, "%s\t%s\t%s\t%s\n", c.GetName(), target, filterString, contentMode)
 			}
 			continue
 		}
 
+		if profile, set := object.Metadata.Annotations[triggermesh.CredentialsProfileAnnotation]; set {
+			credsPrint = true
+			fmt.Fprintf(creds, "%s\t%s\t%s\n", object.Metadata.Name, profile,
+				credentialsExpiry(object.Metadata.Annotations[triggermesh.CredentialsExpiryAnnotation]))
+		}
+
+		level := logging.Effective(object)
 		producer, pOk := c.(triggermesh.Producer)
 		consumer, cOk := c.(triggermesh.Consumer)
 		switch {
@@ -133,25 +294,36 @@ func (o *CliOptions) Describe() error {
 						et = []string{"*"}
 					}
 					producersPrint = true
-					fmt.Fprintf(producers, "%s\tservice (%s)\t%s\t%s\n", c.GetName(), service.Image, strings.Join(et, ", "), status(c))
+					fmt.Fprintf(producers, "%s\tservice (%s)\t%s\t%s\t%s\t%s\n", c.GetName(), service.Image, strings.Join(et, ", "),
+						observedTypesOf(observedTypes, c.GetName()), level, o.sourceStatus(c))
 				}
 				if service.IsTarget() {
 					et, _ := c.(triggermesh.Consumer).ConsumedEventTypes()
 					if len(et) == 0 {
 						et = []string{"*"}
 					}
+					kind := fmt.Sprintf("service (%s)", service.Image)
+					if service.IsAdopted() {
+						kind = fmt.Sprintf("adopted (%s)", service.AdoptedContainer())
+					}
 					consumersPrint = true
-					fmt.Fprintf(consumers, "%s\tservice (%s)\t%s\t%s\n", c.GetName(), service.Image, strings.Join(et, ", "), status(c))
+					fmt.Fprintf(consumers, "%s\t%s\t%s\t%s\t%s\t%s\n", c.GetName(), kind, strings.Join(et, ", "), level, o.status(c), o.health(c))
 				}
 			}
 			// transformation
-			if _, ok := c.(*transformation.Transformation); ok {
+			if t, ok := c.(*transformation.Transformation); ok {
 				et, _ := producer.GetEventTypes()
+				if t.Engine() == transformation.EngineJQ {
+					// the type of the JQ output is set by its output stage
+					if stage, err := components.GetObject(transformation.OutputStage(t.GetName()), o.Config, o.Manifest, o.CRD); err == nil && stage != nil {
+						et, _ = stage.(triggermesh.Producer).GetEventTypes()
+					}
+				}
 				if len(et) == 0 {
 					et = []string{"*"}
 				}
 				transformationsPrint = true
-				fmt.Fprintf(transformations, "%s\t%s\t%s\n", c.GetName(), strings.Join(et, ", "), status(c))
+				fmt.Fprintf(transformations, "%s\t%s\t%s\t%s\t%s\t%s\n", c.GetName(), t.Engine(), eventTypesWithOrigin(et, object), level, o.status(c), o.health(c))
 			}
 		case pOk:
 			// source
@@ -160,7 +332,8 @@ func (o *CliOptions) Describe() error {
 				et = []string{"*"}
 			}
 			producersPrint = true
-			fmt.Fprintf(producers, "%s\t%s\t%s\t%s\n", c.GetName(), c.GetKind(), strings.Join(et, ", "), status(c))
+			fmt.Fprintf(producers, "%s\t%s\t%s\t%s\t%s\t%s\n", c.GetName(), c.GetKind(), strings.Join(et, ", "),
+				observedTypesOf(observedTypes, c.GetName()), level, o.sourceStatus(c))
 		case cOk:
 			// target
 			et, _ := consumer.ConsumedEventTypes()
@@ -168,12 +341,27 @@ func (o *CliOptions) Describe() error {
 				et = []string{"*"}
 			}
 			consumersPrint = true
-			fmt.Fprintf(consumers, "%s\t%s\t%s\t%s\n", c.GetName(), c.GetKind(), strings.Join(et, ", "), status(c))
+			fmt.Fprintf(consumers, "%s\t%s\t%s\t%s\t%s\t%s\n", c.GetName(), c.GetKind(), strings.Join(et, ", "), level, o.status(c), o.health(c))
+			// targets replying to the events also produce them
+			if replyTo, set := object.Metadata.Annotations[triggermesh.ReplyToAnnotation]; set {
+				producersPrint = true
+				fmt.Fprintf(producers, "%s\t%s (replies to %s)\t%s\t%s\t%s\t%s\n", c.GetName(), c.GetKind(), replyTo,
+					object.Metadata.Annotations[triggermesh.ReplyEventTypeAnnotation],
+					observedTypesOf(observedTypes, c.GetName()), level, o.status(c))
+			}
+		}
+		if count := replicas.Count(object); cOk && count > 1 {
+			for i, replica := range replicas.Status(context.Background(), c.GetName(), count) {
+				fmt.Fprintf(consumers, "%s\treplica %d/%d\t\t%s\t%s\n", replica.Name, i+2, count, level, replicaStatus(replica))
+			}
 		}
 	}
 	if brokersPrint {
 		fmt.Fprintln(broker)
 	}
+	if o.printLimits(limits) {
+		fmt.Fprintln(limits)
+	}
 	if triggersPrint {
 		fmt.Fprintln(triggers)
 	}
@@ -186,21 +374,383 @@ func (o *CliOptions) Describe() error {
 	if consumersPrint {
 		fmt.Fprintln(consumers)
 	}
+	if o.printFileSinks(fileSinks) {
+		fmt.Fprintln(fileSinks)
+	}
+	if unsupportedPrint {
+		fmt.Fprintln(unsupported)
+	}
+	if o.printPreviousLogs(previous, runnables) {
+		fmt.Fprintln(previous, "Run \"tmctl logs <component> --previous\" to see the saved output")
+		fmt.Fprintln(previous)
+	}
+	if o.printSignatures(signatures) {
+		fmt.Fprintln(signatures)
+	}
+	if o.metrics && o.printRates(rates) {
+		fmt.Fprintln(rates)
+	}
+	if credsPrint {
+		fmt.Fprintln(creds)
+	}
+	if o.printLinks(links) {
+		fmt.Fprintln(links)
+	}
+	if o.printNetwork(network, runnables) {
+		fmt.Fprintln(network)
+	}
+	if provenancePrint {
+		fmt.Fprintln(provenance)
+	}
+	if o.printImages(images, runnables) {
+		fmt.Fprintln(images)
+	}
 	return nil
 }
 
-func status(component triggermesh.Component) string {
+// printLinks lists the triggers forwarding the events between
+// the broker of the context and the brokers of other contexts.
+func (o *CliOptions) printLinks(w io.Writer) bool {
+	brokerLinks, err := tmbroker.Links(o.Config.ConfigHome, o.Config.Context)
+	if err != nil {
+		log.Printf("WARNING! Broker links: %v", err)
+		return false
+	}
+	for _, link := range brokerLinks {
+		filter := "*"
+		if len(link.Filters) != 0 {
+			filter = triggerFilterToString(link.Filters)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", link.Trigger, link.From, link.To, filter)
+	}
+	return len(brokerLinks) != 0
+}
+
+// printFileSinks lists the files the file sinks are appending the events to.
+func (o *CliOptions) printFileSinks(w io.Writer) bool {
+	printed := false
+	for _, object := range o.Manifest.Objects {
+		if object.APIVersion != filesink.APIVersion {
+			continue
+		}
+		c, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+		if err != nil || c == nil {
+			continue
+		}
+		sink := c.(*filesink.FileSink)
+		size := "-"
+		if n, err := sink.CurrentSize(); err == nil {
+			size = filesink.FormatSize(n)
+		}
+		maxSize, _ := sink.GetSpec()["maxFileSize"].(string)
+		if maxSize == "" {
+			maxSize = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", sink.GetName(), sink.CurrentFile(), size, maxSize)
+		printed = true
+	}
+	return printed
+}
+
+// printPreviousLogs shows the last lines of the saved output
+// of the offline components exited containers.
+func (o *CliOptions) printPreviousLogs(w io.Writer, runnables []triggermesh.Component) bool {
+	dir := filepath.Join(o.Config.ConfigHome, o.Config.Context, crashlog.Dir)
+	printed := false
+	for _, c := range runnables {
+		info := o.containers[componentKey(c)]
+		if info.container == nil || info.container.Online {
+			continue
+		}
+		capture, err := crashlog.Get(dir, info.container.Name, 0)
+		if err != nil {
+			continue
+		}
+		lines, err := capture.Tail(previousLogsLines)
+		if err != nil || len(lines) == 0 {
+			continue
+		}
+		exited := capture.Exited.Local().Format(time.RFC3339)
+		for i, line := range lines {
+			line = strings.ReplaceAll(line, "\t", " ")
+			if i == 0 {
+				fmt.Fprintf(w, "%s\t%s\t%s\n", c.GetName(), exited, line)
+				continue
+			}
+			fmt.Fprintf(w, "\t\t%s\n", line)
+		}
+		printed = true
+	}
+	return printed
+}
+
+// printSignatures lists the webhook sources verifying the request signatures
+// with the address of the verifier and the number of the rejected requests.
+func (o *CliOptions) printSignatures(w io.Writer) bool {
+	printed := false
+	for _, object := range o.Manifest.Objects {
+		v, verified := signature.FromObject(object)
+		if !verified {
+			continue
+		}
+		endpoint, rejected := fmt.Sprintf("%soffline%s", offlineColorCode, defaultColorCode), "-"
+		port, count, err := signature.Status(context.Background(), object.Metadata.Name)
+		if err != nil {
+			log.Printf("WARNING! %q verifier: %v", object.Metadata.Name, err)
+		}
+		if port != "" {
+			endpoint = o.Config.URLs().URL(urls.External, port)
+			rejected = strconv.Itoa(count)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\t%s\n", object.Metadata.Name, v.Header, v.Algorithm, endpoint, rejected)
+		printed = true
+	}
+	return printed
+}
+
+// printLimits lists the ingest limits of the context broker
+// enforced by its ingest proxy, false if the ingest is not limited.
+func (o *CliOptions) printLimits(w io.Writer) bool {
+	limits := o.Config.Triggermesh.Broker.Limits[o.Config.Context]
+	if limits.Empty() {
+		return false
+	}
+	endpoint := fmt.Sprintf("%soffline%s", offlineColorCode, defaultColorCode)
+	if port, limited := ingest.Port(context.Background(), o.Config.Context); limited {
+		endpoint = o.Config.URLs().URL(urls.Host, port)
+	}
+	none := func(value string) string {
+		if value == "" {
+			return "-"
+		}
+		return value
+	}
+	fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", o.Config.Context, none(limits.MaxPayloadSize), none(limits.RateLimit), endpoint)
+	return true
+}
+
+// printRates lists the events per minute received and sent by the components.
+// The components that the triggers route the events to but that have not
+// received any are marked, the rates are not available without the broker
+// backend exposing the events.
+func (o *CliOptions) printRates(w io.Writer) bool {
+	rates, err := throughput.Load(o.Config, o.Manifest, o.CRD)
+	if err != nil && !errors.Is(err, deliveries.ErrUnsupported) {
+		log.Printf("WARNING! Event rates: %v", err)
+	}
+	printed := false
+	for _, object := range o.Manifest.Objects {
+		if _, owned := object.Metadata.Annotations[triggermesh.OwnerAnnotation]; owned {
+			continue
+		}
+		in, out := throughput.NotAvailable, throughput.NotAvailable
+		if rates != nil {
+			rate := rates.Of(object.Metadata.Name)
+			in, out = rate.In(), rate.Out()
+			if rates.Idle(object.Metadata.Name) {
+				in = fmt.Sprintf("%s%s (idle)%s", offlineColorCode, in, defaultColorCode)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", object.Metadata.Name, object.Kind, in, out)
+		printed = true
+	}
+	return printed
+}
+
+// printNetwork lists the names the components are resolved by
+// in the context network and the ports published on the host.
+func (o *CliOptions) printNetwork(w io.Writer, runnables []triggermesh.Component) bool {
+	printed := false
+	for _, c := range runnables {
+		networked, ok := c.(triggermesh.Networked)
+		if !ok {
+			continue
+		}
+		aliases := networked.NetworkAliases()
+		if len(aliases) == 0 {
+			continue
+		}
+		host := "-"
+		if info := o.containers[componentKey(c)]; info.container != nil && info.container.Online {
+			if port := info.container.HostPort(); port != "" {
+				host = o.Config.URLs().Address(urls.Host, port)
+			}
+		}
+		fmt.Fprintf(w, "%s\t%s:%s, %s\t%s\n", c.GetName(), aliases[0], triggermesh.ContainerPort,
+			strings.Join(aliases[1:], ", "), host)
+		printed = true
+	}
+	return printed
+}
+
+// runnables returns the manifest components running in containers.
+func (o *CliOptions) runnables() []triggermesh.Component {
+	var result []triggermesh.Component
+	for _, object := range o.Manifest.Objects {
+		if _, owned := object.Metadata.Annotations[triggermesh.OwnerAnnotation]; owned {
+			continue
+		}
+		c, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+		if err != nil || c == nil {
+			continue
+		}
+		if _, ok := c.(triggermesh.Runnable); ok {
+			result = append(result, c)
+		}
+	}
+	return result
+}
+
+// printImages lists the digests of the components images in the manifest order.
+func (o *CliOptions) printImages(w io.Writer, runnables []triggermesh.Component) bool {
+	var order []string
+	users := make(map[string][]string)
+	digests := make(map[string]string)
+	for _, c := range runnables {
+		info := o.containers[componentKey(c)]
+		if info.digest == "" {
+			continue
+		}
+		image := info.container.RuntimeImage()
+		if _, exists := users[image]; !exists {
+			order = append(order, image)
+			digests[image] = info.digest
+		}
+		user := c.GetName()
+		if o.pinned(c) {
+			user += " (pinned)"
+		}
+		users[image] = append(users[image], user)
+	}
+	for _, image := range order {
+		fmt.Fprintf(w, "%s\t%s\t%s\n", image, digests[image], strings.Join(users[image], ", "))
+	}
+	return len(order) != 0
+}
+
+// pinned returns true if the component adapter version
+// is pinned rather than following the context version.
+func (o *CliOptions) pinned(c triggermesh.Component) bool {
+	for _, object := range o.Manifest.Objects {
+		if object.Metadata.Name == c.GetName() && strings.EqualFold(object.Kind, c.GetKind()) {
+			return object.Metadata.Annotations[triggermesh.AdapterVersionAnnotation] != ""
+		}
+	}
+	return false
+}
+
+func annotationOrDash(object kubernetes.Object, key string) string {
+	if value := object.Metadata.Annotations[key]; value != "" {
+		return value
+	}
+	return "-"
+}
+
+// inlineTransformationTargets returns the targets of the triggers'
+// inline transformations and data filters, indexed by the owner trigger name.
+func (o *CliOptions) inlineTransformationTargets() map[string]string {
+	result := make(map[string]string)
+	for _, object := range o.Manifest.Objects {
+		owner, owned := object.Metadata.Annotations[triggermesh.OwnerAnnotation]
+		if !owned || object.Kind != tmbroker.TriggerKind || object.Metadata.Name != owner+"-output" {
+			continue
+		}
+		if target, _, _ := unstructured.NestedString(object.Spec, "target", "ref", "name"); target != "" {
+			result[owner] = target
+		}
+	}
+	return result
+}
+
+// appendDataFilter adds the payload expression to the attribute filters.
+func appendDataFilter(filterString, expression string) string {
+	if filterString == "*" {
+		return expression
+	}
+	return filterString + ", " + expression
+}
+
+// eventTypesWithOrigin appends the origin of the produced event type
+// recorded by the create command: a flag, the output type template or the CRD.
+func eventTypesWithOrigin(eventTypes []string, object kubernetes.Object) string {
+	if origin, set := object.Metadata.Annotations[triggermesh.EventTypeOriginAnnotation]; set {
+		return fmt.Sprintf("%s (%s)", strings.Join(eventTypes, ", "), origin)
+	}
+	return strings.Join(eventTypes, ", ")
+}
+
+// observedTypesOf returns the event types recorded by "tmctl stats observe".
+func observedTypesOf(types *observed.Types, component string) string {
+	if types == nil {
+		return "-"
+	}
+	if et := types.Of(component); len(et) != 0 {
+		return strings.Join(et, ", ")
+	}
+	return "-"
+}
+
+func (o *CliOptions) status(component triggermesh.Component) string {
 	offlineStatus := fmt.Sprintf("%soffline%s", offlineColorCode, defaultColorCode)
 	if container, ok := component.(triggermesh.Runnable); ok {
-		c, err := container.Info(context.Background())
+		info, inspected := o.containers[componentKey(component)]
+		if !inspected {
+			info.container, info.err = container.Info(context.Background())
+		}
+		c, err := info.container, info.err
 		if err != nil || !c.Online {
 			return offlineStatus
 		}
-		return fmt.Sprintf("%sonline(http://localhost:%s)%s", successColorCode, c.HostPort(), defaultColorCode)
+		return fmt.Sprintf("%sonline(%s)%s", successColorCode, o.Config.URLs().URL(urls.Host, c.HostPort()), defaultColorCode)
 	}
 	return offlineStatus
 }
 
+// sourceStatus adds the public URL of the source exposed with "tmctl expose".
+// health returns the result of the component endpoint probe.
+func (o *CliOptions) health(component triggermesh.Component) string {
+	info, inspected := o.containers[componentKey(component)]
+	if !inspected || info.health.Status == "" {
+		return string(health.Unknown)
+	}
+	switch info.health.Status {
+	case health.Healthy:
+		return fmt.Sprintf("%s%s%s", successColorCode, info.health, defaultColorCode)
+	case health.Unhealthy:
+		return fmt.Sprintf("%s%s%s", offlineColorCode, info.health, defaultColorCode)
+	}
+	return info.health.String()
+}
+
+func (o *CliOptions) sourceStatus(component triggermesh.Component) string {
+	if url := tunnel.URL(o.Config.ConfigHome, o.Config.Context, component.GetName()); url != "" {
+		return fmt.Sprintf("%s, exposed(%s)", o.status(component), url)
+	}
+	return o.status(component)
+}
+
+// credentialsExpiry highlights the expired credentials.
+func credentialsExpiry(expiry string) string {
+	if expiry == "" {
+		return "never"
+	}
+	expires, err := time.Parse(time.RFC3339, expiry)
+	if err != nil {
+		return expiry
+	}
+	if time.Now().After(expires) {
+		return fmt.Sprintf("%sexpired(%s)%s", offlineColorCode, expiry, defaultColorCode)
+	}
+	return expiry
+}
+
+func replicaStatus(replica replicas.Replica) string {
+	if !replica.Online {
+		return fmt.Sprintf("%soffline%s", offlineColorCode, defaultColorCode)
+	}
+	return fmt.Sprintf("%sonline(%s)%s", successColorCode, urls.New("").URL(urls.Host, replica.Port), defaultColorCode)
+}
+
 func triggerFilterToString(filters []eventingbroker.Filter) string {
 	var result []string
 	for _, filter := range filters {
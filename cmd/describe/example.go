@@ -0,0 +1,392 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package describe
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"reflect"
+	"sort"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/schema"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+const (
+	exampleEventType   = "io.triggermesh.tmctl.example"
+	exampleEventSource = "tmctl-describe"
+
+	// how long to wait for the temporary transformation to reply
+	exampleTimeout = 30 * time.Second
+)
+
+// Example runs the sample event through the transformation and prints
+// the input and output events side by side with the list of changes.
+// Transformation container started by tmctl forwards events to the broker,
+// so the sample goes through the temporary container without a sink.
+func (o *CliOptions) Example(name, eventFile string) error {
+	ctx := context.Background()
+	c, err := components.GetObject(name, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return fmt.Errorf("%q not found: %w", name, err)
+	}
+	t, ok := c.(*transformation.Transformation)
+	if !ok {
+		return fmt.Errorf("%q is not a transformation", name)
+	}
+
+	input, err := o.exampleEvent(t, eventFile)
+	if err != nil {
+		return err
+	}
+
+	spec := make(map[string]interface{}, len(t.GetSpec()))
+	for k, v := range t.GetSpec() {
+		if k == "sink" {
+			continue
+		}
+		spec[k] = v
+	}
+	temp := transformation.New(name+"-example", t.Kind, o.Config.Context, o.Config.Triggermesh.ComponentsVersion, t.CRD, spec)
+	log.Println("Starting temporary transformation")
+	container, err := temp.(triggermesh.Runnable).Start(ctx, nil, true)
+	if err != nil {
+		return fmt.Errorf("starting transformation: %w", err)
+	}
+	defer func() {
+		if err := temp.(triggermesh.Runnable).Stop(ctx); err != nil {
+			log.Printf("Removing temporary transformation: %v", err)
+		}
+	}()
+
+	output, err := transform(ctx, o.Config.URLs().URL(urls.Host, container.HostPort()), *input)
+	if err != nil {
+		return err
+	}
+	printSideBySide(input, output)
+	fmt.Println()
+	changes := attributeChanges(input, output)
+	changes = append(changes, payloadChanges(input.Data(), output.Data())...)
+	if len(changes) == 0 {
+		fmt.Println("No changes")
+		return nil
+	}
+	fmt.Println("Changes:")
+	for _, change := range changes {
+		fmt.Printf("  %s\n", change)
+	}
+	return nil
+}
+
+// exampleEvent returns the transformation input event. File may contain
+// a single CloudEvent, the events recorded with "tmctl record" or the plain payload.
+// If the file is not set, the payload is generated from the registered schema.
+func (o *CliOptions) exampleEvent(t *transformation.Transformation, eventFile string) (*cloudevents.Event, error) {
+	inputTypes := o.transformationInputTypes(t.GetName())
+	if eventFile == "" {
+		registry := schema.New(o.Config.ConfigHome, o.Config.Context)
+		for _, eventType := range inputTypes {
+			data, err := registry.Sample(eventType)
+			if err == schema.ErrNotFound {
+				continue
+			}
+			if err != nil {
+				return nil, fmt.Errorf("%q sample: %w", eventType, err)
+			}
+			return newExampleEvent(eventType, data)
+		}
+		return nil, fmt.Errorf("no registered schemas for %q input event types, use --event to set the sample event", t.GetName())
+	}
+
+	data, err := os.ReadFile(eventFile)
+	if err != nil {
+		return nil, fmt.Errorf("reading event file: %w", err)
+	}
+	if event, ok := parseEvent(data); ok {
+		return event, nil
+	}
+	if event := lastRecorded(data, inputTypes); event != nil {
+		return event, nil
+	}
+	eventType := exampleEventType
+	if len(inputTypes) != 0 {
+		eventType = inputTypes[0]
+	}
+	return newExampleEvent(eventType, data)
+}
+
+// transformationInputTypes returns the event types routed to the transformation.
+func (o *CliOptions) transformationInputTypes(name string) []string {
+	triggers, err := tmbroker.GetTargetTriggers(name, o.Config.Context, o.Config.ConfigHome)
+	if err != nil {
+		return nil
+	}
+	var result []string
+	for _, trigger := range triggers {
+		for _, filter := range trigger.(*tmbroker.Trigger).Filters {
+			if eventType, set := filter.Exact["type"]; set {
+				result = append(result, eventType)
+			}
+		}
+	}
+	return result
+}
+
+func newExampleEvent(eventType string, data []byte) (*cloudevents.Event, error) {
+	event := cloudevents.NewEvent()
+	event.SetID(fmt.Sprintf("%d", time.Now().UnixNano()))
+	event.SetType(eventType)
+	event.SetSource(exampleEventSource)
+	contentType := cloudevents.TextPlain
+	if json.Valid(data) {
+		contentType = cloudevents.ApplicationJSON
+	}
+	if err := event.SetData(contentType, data); err != nil {
+		return nil, fmt.Errorf("event data: %w", err)
+	}
+	return &event, nil
+}
+
+func parseEvent(data []byte) (*cloudevents.Event, bool) {
+	var probe struct {
+		SpecVersion string `json:"specversion"`
+	}
+	if err := json.Unmarshal(data, &probe); err != nil || probe.SpecVersion == "" {
+		return nil, false
+	}
+	event := cloudevents.NewEvent()
+	if err := json.Unmarshal(data, &event); err != nil {
+		return nil, false
+	}
+	return &event, true
+}
+
+// lastRecorded returns the last event of the matching type from the recording.
+func lastRecorded(data []byte, eventTypes []string) *cloudevents.Event {
+	var result *cloudevents.Event
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(make([]byte, 0, 64*1024), 10*1024*1024)
+	for scanner.Scan() {
+		event, ok := parseEvent(scanner.Bytes())
+		if !ok {
+			return nil
+		}
+		if len(eventTypes) == 0 || contains(eventTypes, event.Type()) {
+			result = event
+		}
+	}
+	return result
+}
+
+func transform(ctx context.Context, url string, event cloudevents.Event) (*cloudevents.Event, error) {
+	c, err := cloudevents.NewClientHTTP()
+	if err != nil {
+		return nil, fmt.Errorf("cloudevents client, %w", err)
+	}
+	ctx = cloudevents.ContextWithTarget(ctx, url)
+	deadline := time.Now().Add(exampleTimeout)
+	for {
+		reply, result := c.Request(ctx, event)
+		if cloudevents.IsACK(result) {
+			if reply == nil {
+				return nil, fmt.Errorf("transformation did not reply with the event")
+			}
+			return reply, nil
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("sending event: %w", result)
+		}
+		// adapter may not be listening yet
+		time.Sleep(time.Second)
+	}
+}
+
+func printSideBySide(input, output *cloudevents.Event) {
+	left := strings.Split(strings.TrimRight(prettyEvent(input), "\n"), "\n")
+	right := strings.Split(strings.TrimRight(prettyEvent(output), "\n"), "\n")
+	width := len("Input")
+	for _, line := range left {
+		if len(line) > width {
+			width = len(line)
+		}
+	}
+	fmt.Printf("%-*s  |  %s\n", width, "Input", "Output")
+	fmt.Printf("%s--+--%s\n", strings.Repeat("-", width), strings.Repeat("-", width))
+	for i := 0; i < len(left) || i < len(right); i++ {
+		var l, r string
+		if i < len(left) {
+			l = left[i]
+		}
+		if i < len(right) {
+			r = right[i]
+		}
+		fmt.Printf("%-*s  |  %s\n", width, l, r)
+	}
+}
+
+func prettyEvent(event *cloudevents.Event) string {
+	var out bytes.Buffer
+	fmt.Fprintf(&out, "type: %s\nsource: %s\n", event.Type(), event.Source())
+	if event.Subject() != "" {
+		fmt.Fprintf(&out, "subject: %s\n", event.Subject())
+	}
+	for _, name := range sortedKeys(event.Extensions()) {
+		fmt.Fprintf(&out, "%s: %v\n", name, event.Extensions()[name])
+	}
+	out.WriteString("data:\n")
+	var indented bytes.Buffer
+	if err := json.Indent(&indented, event.Data(), "", "  "); err != nil {
+		out.Write(event.Data())
+	} else {
+		out.Write(indented.Bytes())
+	}
+	return out.String()
+}
+
+// attributeChanges lists the context attributes modified by the transformation.
+func attributeChanges(input, output *cloudevents.Event) []string {
+	before := map[string]interface{}{
+		"type":            input.Type(),
+		"source":          input.Source(),
+		"subject":         input.Subject(),
+		"dataschema":      input.DataSchema(),
+		"datacontenttype": input.DataContentType(),
+	}
+	after := map[string]interface{}{
+		"type":            output.Type(),
+		"source":          output.Source(),
+		"subject":         output.Subject(),
+		"dataschema":      output.DataSchema(),
+		"datacontenttype": output.DataContentType(),
+	}
+	for k, v := range input.Extensions() {
+		before[k] = v
+	}
+	for k, v := range output.Extensions() {
+		after[k] = v
+	}
+	for k, v := range before {
+		if v == "" {
+			delete(before, k)
+		}
+	}
+	for k, v := range after {
+		if v == "" {
+			delete(after, k)
+		}
+	}
+	return diff("attribute ", before, after)
+}
+
+// payloadChanges lists the payload fields modified by the transformation.
+func payloadChanges(input, output []byte) []string {
+	var before, after interface{}
+	if json.Unmarshal(input, &before) != nil || json.Unmarshal(output, &after) != nil {
+		if bytes.Equal(input, output) {
+			return nil
+		}
+		return []string{"~ data (not a JSON)"}
+	}
+	beforeFields, afterFields := make(map[string]interface{}), make(map[string]interface{})
+	flatten("data", before, beforeFields)
+	flatten("data", after, afterFields)
+	return diff("", beforeFields, afterFields)
+}
+
+func flatten(path string, value interface{}, result map[string]interface{}) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		if len(v) == 0 {
+			result[path] = v
+		}
+		for key, nested := range v {
+			flatten(path+"."+key, nested, result)
+		}
+	case []interface{}:
+		if len(v) == 0 {
+			result[path] = v
+		}
+		for i, nested := range v {
+			flatten(fmt.Sprintf("%s[%d]", path, i), nested, result)
+		}
+	default:
+		result[path] = v
+	}
+}
+
+func diff(prefix string, before, after map[string]interface{}) []string {
+	keys := make(map[string]interface{}, len(before)+len(after))
+	for k := range before {
+		keys[k] = nil
+	}
+	for k := range after {
+		keys[k] = nil
+	}
+	var result []string
+	for _, key := range sortedKeys(keys) {
+		prev, existed := before[key]
+		next, exists := after[key]
+		switch {
+		case !existed:
+			result = append(result, fmt.Sprintf("+ %s%s: %s", prefix, key, jsonString(next)))
+		case !exists:
+			result = append(result, fmt.Sprintf("- %s%s: %s", prefix, key, jsonString(prev)))
+		case !reflect.DeepEqual(prev, next):
+			result = append(result, fmt.Sprintf("~ %s%s: %s -> %s", prefix, key, jsonString(prev), jsonString(next)))
+		}
+	}
+	return result
+}
+
+func jsonString(value interface{}) string {
+	data, err := json.Marshal(value)
+	if err != nil {
+		return fmt.Sprintf("%v", value)
+	}
+	return string(data)
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
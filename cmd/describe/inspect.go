@@ -0,0 +1,149 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package describe
+
+import (
+	"context"
+	"sync"
+
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/health"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+// inspectWorkers limits the number of the concurrent Docker requests.
+const inspectWorkers = 8
+
+type containerInfo struct {
+	container *docker.Container
+	err       error
+	digest    string
+	health    health.Result
+}
+
+// digestCache resolves the digest of each image once per command run.
+type digestCache struct {
+	mu      sync.Mutex
+	entries map[string]*digestEntry
+}
+
+type digestEntry struct {
+	once   sync.Once
+	digest string
+}
+
+func (d *digestCache) get(ctx context.Context, image string) string {
+	d.mu.Lock()
+	if d.entries == nil {
+		d.entries = make(map[string]*digestEntry)
+	}
+	entry, exists := d.entries[image]
+	if !exists {
+		entry = &digestEntry{}
+		d.entries[image] = entry
+	}
+	d.mu.Unlock()
+
+	entry.once.Do(func() {
+		client, err := docker.ClientFrom(ctx)
+		if err != nil {
+			return
+		}
+		entry.digest, _ = docker.ImageDigest(ctx, client, image)
+	})
+	return entry.digest
+}
+
+// inspect looks up the containers of the components in parallel
+// sharing one Docker client. Results are indexed by the component
+// so that the rendering order does not depend on the completion order.
+func (o *CliOptions) inspect(runnables []triggermesh.Component) map[string]containerInfo {
+	ctx := context.Background()
+	if client, err := docker.NewClient(); err == nil {
+		ctx = docker.WithClient(ctx, client)
+		defer client.Close()
+	}
+
+	result := make(map[string]containerInfo, len(runnables))
+	var mu sync.Mutex
+	var wg sync.WaitGroup
+	jobs := make(chan triggermesh.Component)
+	for i := 0; i < inspectWorkers && i < len(runnables); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for c := range jobs {
+				var info containerInfo
+				info.container, info.err = c.(triggermesh.Runnable).Info(ctx)
+				if info.err == nil && !info.container.Online {
+					if client, err := docker.ClientFrom(ctx); err == nil {
+						info.container.CaptureExit(ctx, client)
+					}
+				}
+				if info.err == nil && !o.fast {
+					info.digest = o.digests.get(ctx, info.container.RuntimeImage())
+				}
+				info.health = o.probe(ctx, c, info)
+				mu.Lock()
+				result[componentKey(c)] = info
+				mu.Unlock()
+			}
+		}()
+	}
+	for _, c := range runnables {
+		jobs <- c
+	}
+	close(jobs)
+	wg.Wait()
+	return result
+}
+
+// probe checks if the consumer adapter is serving the requests.
+// Kinds declaring the readiness probe in their CRD are checked with it.
+func (o *CliOptions) probe(ctx context.Context, c triggermesh.Component, info containerInfo) health.Result {
+	if o.noProbe {
+		return health.Result{Status: health.Unknown}
+	}
+	if components.HasReadiness(c, o.CRD) {
+		if info.err != nil || !info.container.Online {
+			return health.Result{Status: health.Unknown}
+		}
+		return o.readiness(ctx, c, info.container)
+	}
+	if _, consumer := c.(triggermesh.Consumer); !consumer {
+		return health.Result{Status: health.Unknown}
+	}
+	if info.err != nil || !info.container.Online || info.container.HostPort() == "" {
+		return health.Result{Status: health.Unknown}
+	}
+	return health.Probe(ctx, o.Config.URLs().URL(urls.Host, info.container.HostPort()))
+}
+
+// readiness runs the readiness probe of the component kind once.
+func (o *CliOptions) readiness(ctx context.Context, c triggermesh.Component, container *docker.Container) health.Result {
+	r, err := components.Readiness(c, o.CRD)
+	if err != nil {
+		return health.Result{Status: health.Unknown, Detail: err.Error()}
+	}
+	return r.Probe(ctx, components.ReadinessTarget(c.(triggermesh.Runnable), container))
+}
+
+func componentKey(c triggermesh.Component) string {
+	return c.GetKind() + "/" + c.GetName()
+}
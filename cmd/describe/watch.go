@@ -0,0 +1,183 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package describe
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"time"
+
+	"github.com/triggermesh/tmctl/pkg/health"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+)
+
+const (
+	watchPeriod = 2 * time.Second
+
+	clearScreen    = "\033[H\033[2J"
+	highlightStart = "\033[7m"
+	highlightEnd   = "\033[27m"
+)
+
+// Watch refreshes the components status until interrupted or, if untilReady
+// is set, until all components are online. Lines changed since the previous
+// refresh are highlighted on terminal, otherwise only the changes are printed.
+func (o *CliOptions) Watch(untilReady bool) error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+	go func() {
+		select {
+		case <-c:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	tty := isTerminal(os.Stdout)
+	ticker := time.NewTicker(watchPeriod)
+	defer ticker.Stop()
+	var previous []string
+	for {
+		if err := o.Manifest.Read(); err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err := o.describe(&out); err != nil {
+			return err
+		}
+		current := strings.Split(strings.TrimRight(out.String(), "\n"), "\n")
+		if tty {
+			fmt.Print(clearScreen)
+			fmt.Println(highlightChanges(previous, current))
+		} else {
+			printDelta(previous, current)
+		}
+		previous = current
+
+		if untilReady && o.ready(ctx) {
+			fmt.Println("All components are online")
+			return nil
+		}
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+// ready returns true if all runnable components are online and ready.
+func (o *CliOptions) ready(ctx context.Context) bool {
+	for _, object := range o.Manifest.Objects {
+		c, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+		if err != nil || c == nil {
+			continue
+		}
+		runnable, ok := c.(triggermesh.Runnable)
+		if !ok {
+			continue
+		}
+		// reuse the lookups of the last describe output
+		info, inspected := o.containers[componentKey(c)]
+		if !inspected {
+			info.container, info.err = runnable.Info(ctx)
+		}
+		if info.err != nil || !info.container.Online {
+			return false
+		}
+		// kinds declaring the readiness probe must also pass it,
+		// e.g. the sources that never listen on the port
+		if components.HasReadiness(c, o.CRD) {
+			result := info.health
+			if result.Status != health.Healthy && result.Status != health.Unhealthy {
+				result = o.readiness(ctx, c, info.container)
+			}
+			if result.Status != health.Healthy {
+				return false
+			}
+		}
+	}
+	return true
+}
+
+// highlightChanges returns the current output with the lines
+// that were not present in the previous output highlighted.
+func highlightChanges(previous, current []string) string {
+	if previous == nil {
+		return strings.Join(current, "\n")
+	}
+	seen := lineSet(previous)
+	result := make([]string, len(current))
+	for i, line := range current {
+		if strings.TrimSpace(line) != "" && !seen[line] {
+			line = highlightStart + line + highlightEnd
+		}
+		result[i] = line
+	}
+	return strings.Join(result, "\n")
+}
+
+// printDelta prints the full output on the first refresh
+// and the added and removed lines afterwards.
+func printDelta(previous, current []string) {
+	if previous == nil {
+		fmt.Println(strings.Join(current, "\n"))
+		return
+	}
+	prevSet, currSet := lineSet(previous), lineSet(current)
+	var delta []string
+	for _, line := range previous {
+		if strings.TrimSpace(line) != "" && !currSet[line] {
+			delta = append(delta, "- "+line)
+		}
+	}
+	for _, line := range current {
+		if strings.TrimSpace(line) != "" && !prevSet[line] {
+			delta = append(delta, "+ "+line)
+		}
+	}
+	if len(delta) == 0 {
+		return
+	}
+	fmt.Printf("--- %s\n%s\n", time.Now().Format("15:04:05"), strings.Join(delta, "\n"))
+}
+
+func lineSet(lines []string) map[string]bool {
+	set := make(map[string]bool, len(lines))
+	for _, line := range lines {
+		set[line] = true
+	}
+	return set
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
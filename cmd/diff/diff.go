@@ -0,0 +1,192 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package diff
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD:      crd,
+		Config:   config,
+		Manifest: manifest,
+	}
+	var file, revision string
+	var exitCode bool
+	diffCmd := &cobra.Command{
+		Use:   "diff --file <manifest> [--revision <rev>][--exit-code]",
+		Short: "Compare the manifest with the current context and its containers",
+		Example: `tmctl diff --file manifest.yaml
+
+tmctl diff --file manifest.yaml --revision origin/main --exit-code`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("manifest file is required")
+			}
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			other, err := readManifest(file, revision)
+			if err != nil {
+				return err
+			}
+			differ, err := o.diff(os.Stdout, other)
+			if err != nil {
+				return err
+			}
+			if differ && exitCode {
+				os.Exit(1)
+			}
+			return nil
+		},
+	}
+	diffCmd.Flags().StringVarP(&file, "file", "f", "", "Manifest file compared with the current context")
+	diffCmd.Flags().StringVar(&revision, "revision", "", "Read the manifest file at the git revision")
+	diffCmd.Flags().BoolVar(&exitCode, "exit-code", false, "Exit with 1 if the manifests differ, like \"git diff --exit-code\"")
+	cobra.CheckErr(diffCmd.RegisterFlagCompletionFunc("file", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completion.ListSpecFiles(toComplete, nil, "yaml", "yml"),
+			cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(diffCmd.RegisterFlagCompletionFunc("revision", cobra.NoFileCompletions))
+	return diffCmd
+}
+
+// diff prints the manifest changes and the containers that differ from the
+// other manifest. The returned flag is true if the manifests differ.
+func (o *CliOptions) diff(out io.Writer, other *manifest.Manifest) (bool, error) {
+	changes, err := manifest.Diff(o.Manifest.Objects, other.Objects)
+	if err != nil {
+		return false, err
+	}
+	drift := o.containersDrift(other)
+	if len(changes) == 0 && len(drift) == 0 {
+		fmt.Fprintln(out, "No differences")
+		return false, nil
+	}
+	if len(changes) != 0 {
+		fmt.Fprintln(out, "Manifest:")
+		for _, c := range changes {
+			switch c.Type {
+			case manifest.Added:
+				fmt.Fprintf(out, "+ %s %s\n", c.Kind, c.Name)
+			case manifest.Removed:
+				fmt.Fprintf(out, "- %s %s\n", c.Kind, c.Name)
+			case manifest.Changed:
+				fmt.Fprintf(out, "~ %s %s\n", c.Kind, c.Name)
+				for _, line := range strings.Split(strings.TrimSuffix(c.Diff, "\n"), "\n") {
+					fmt.Fprintf(out, "    %s\n", line)
+				}
+			}
+		}
+	}
+	if len(drift) != 0 {
+		if len(changes) != 0 {
+			fmt.Fprintln(out)
+		}
+		fmt.Fprintln(out, "Containers:")
+		for _, d := range drift {
+			fmt.Fprintf(out, "! %s\n", d)
+		}
+	}
+	return len(changes) != 0, nil
+}
+
+// containersDrift compares the containers of the context with the components
+// of the other manifest. Containers are not checked if Docker is not available.
+func (o *CliOptions) containersDrift(other *manifest.Manifest) []string {
+	if err := docker.CheckDaemon(); err != nil {
+		return nil
+	}
+	ctx := context.Background()
+	var result []string
+	for _, object := range other.Objects {
+		c, err := components.GetObject(object.Metadata.Name, o.Config, other, o.CRD)
+		if err != nil || c == nil {
+			continue
+		}
+		runnable, ok := c.(triggermesh.Runnable)
+		if !ok {
+			continue
+		}
+		container, err := runnable.Info(ctx)
+		if err != nil || !container.Online {
+			result = append(result, fmt.Sprintf("%s: not running", c.GetName()))
+			continue
+		}
+		if image := container.RuntimeImage(); image != container.Image {
+			result = append(result, fmt.Sprintf("%s: running %s, expected %s", c.GetName(), image, container.Image))
+		}
+	}
+	return result
+}
+
+// readManifest reads the manifest file from the disk
+// or from the git repository at the revision.
+func readManifest(file, revision string) (*manifest.Manifest, error) {
+	if revision == "" {
+		if _, err := os.Stat(file); err != nil {
+			return nil, err
+		}
+		m := manifest.New(file)
+		return m, m.Read()
+	}
+	if filepath.IsAbs(file) {
+		return nil, fmt.Errorf("manifest path must be relative to the current directory to read it at the revision")
+	}
+	data, err := exec.Command("git", "show", revision+":./"+filepath.ToSlash(file)).Output()
+	if err != nil {
+		if exitErr, ok := err.(*exec.ExitError); ok {
+			return nil, fmt.Errorf("git show: %s", strings.TrimSpace(string(exitErr.Stderr)))
+		}
+		return nil, fmt.Errorf("git show: %w", err)
+	}
+	tmp, err := os.CreateTemp("", "manifest-*.yaml")
+	if err != nil {
+		return nil, err
+	}
+	defer os.Remove(tmp.Name())
+	defer tmp.Close()
+	if _, err := tmp.Write(data); err != nil {
+		return nil, err
+	}
+	m := manifest.New(tmp.Name())
+	return m, m.Read()
+}
@@ -19,6 +19,7 @@ package dump
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"os"
 	"path/filepath"
@@ -28,13 +29,17 @@ import (
 
 	"github.com/digitalocean/godo"
 	"github.com/spf13/cobra"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
 
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/kustomize"
+	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/filesink"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/secret"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 )
@@ -45,6 +50,8 @@ const (
 	platformKnative           = "knative"
 	platformDockerCompose     = "docker-compose"
 	platformDigitalOcean      = "digitalocean"
+
+	layoutKustomize = "kustomize"
 )
 
 type doOptions struct {
@@ -60,7 +67,15 @@ type CliOptions struct {
 	Format   string
 	Platform string
 
-	NoSecrets bool
+	Layout    string
+	OutputDir string
+	Overlays  []string
+
+	NoSecrets         bool
+	IncludeProvenance bool
+
+	ValidateAgainst string
+	Force           bool
 }
 
 func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
@@ -71,9 +86,13 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 	}
 	do := &doOptions{}
 	dumpCmd := &cobra.Command{
-		Use:       "dump [broker] -p <kubernetes|knative|docker-compose|digitalocean> [-o json]",
-		Short:     "Generate TriggerMesh manifests",
-		Example:   "tmctl dump",
+		Use:   "dump [broker] -p <kubernetes|knative|docker-compose|digitalocean> [-o json]",
+		Short: "Generate TriggerMesh manifests",
+		Example: `tmctl dump
+
+tmctl dump --validate-against ~/.kube/config
+
+tmctl dump --format kustomize --output-dir deploy/ --overlays dev,staging,prod`,
 		ValidArgs: []string{"--platform", "--output"},
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 0 {
@@ -83,14 +102,30 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 					o.Config.Context,
 					triggermesh.ManifestFile))
 			}
-			cobra.CheckErr(o.Manifest.Read())
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			if err := o.validLayout(); err != nil {
+				return err
+			}
+			if o.ValidateAgainst != "" {
+				if err := o.validate(); err != nil {
+					return err
+				}
+			}
 			return o.dump(do)
 		},
 	}
 
 	dumpCmd.Flags().StringVarP(&o.Platform, "platform", "p", "kubernetes", "Target platform. One of kubernetes, knative, docker-compose, digitalocean")
 	dumpCmd.Flags().BoolVar(&o.NoSecrets, "no-secrets", false, "Remove secret values from the manifest")
+	dumpCmd.Flags().BoolVar(&o.IncludeProvenance, "include-provenance", false, "Keep the annotations with the tmctl version and the commands that created the objects")
 	dumpCmd.Flags().StringVarP(&o.Format, "output", "o", "yaml", "Output format")
+	dumpCmd.Flags().StringVar(&o.Layout, "format", "", "Write the manifest in the specified layout instead of printing it. Only \"kustomize\" is supported")
+	dumpCmd.Flags().StringVar(&o.OutputDir, "output-dir", "", "Directory to write the kustomize base and overlays to")
+	dumpCmd.Flags().StringSliceVar(&o.Overlays, "overlays", []string{"dev"}, "Comma-separated list of the kustomize overlays. The first overlay is populated with the current values")
+	dumpCmd.Flags().StringVar(&o.ValidateAgainst, "validate-against", "", "Validate the components against the CRDs of the kubeconfig cluster or the CRD directory")
+	dumpCmd.Flags().BoolVar(&o.Force, "force", false, "Dump the manifest even if the validation fails")
 
 	dumpCmd.Flags().StringVarP(&do.Region, "do-region", "r", "fra", "DigitalOcean region")
 	dumpCmd.Flags().StringVarP(&do.InstanceSize, "do-instance", "i", "professional-xs", "DigitalOcean instance size")
@@ -107,19 +142,45 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 	cobra.CheckErr(dumpCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 		return []string{"json", "yaml"}, cobra.ShellCompDirectiveNoFileComp
 	}))
+	cobra.CheckErr(dumpCmd.RegisterFlagCompletionFunc("format", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{layoutKustomize}, cobra.ShellCompDirectiveNoFileComp
+	}))
 
 	return dumpCmd
 }
 
 func (o *CliOptions) dump(do *doOptions) error {
+	if o.Config.StrictKinds {
+		if err := components.CheckKinds(o.Manifest, o.CRD); err != nil {
+			return err
+		}
+	}
 	var externalReconcilable []string
 	var output interface{}
 	for _, object := range o.Manifest.Objects {
 		additionalEnv := make(map[string]string)
 		component, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+		var unsupported *components.UnsupportedKindError
+		if errors.As(err, &unsupported) {
+			// the cluster may have the CRD, the object is passed through as is
+			if o.Platform != platformKubernetes && o.Platform != platformKnative {
+				log.Printf("WARNING! Skipping %s %q: %s", object.Kind, object.Metadata.Name, unsupported.Status())
+				continue
+			}
+			if output == nil {
+				output = []interface{}{}
+			}
+			output = append(output.([]interface{}), object)
+			continue
+		}
 		if err != nil {
 			continue
 		}
+		if component != nil && component.GetAPIVersion() == filesink.APIVersion {
+			// file sinks write to the local host only
+			log.Printf("WARNING! Skipping %s %q: local only component", object.Kind, object.Metadata.Name)
+			continue
+		}
 		if o.NoSecrets && component.GetAPIVersion() == "v1" && component.GetKind() == "Secret" {
 			redactedData := make(map[string]string, len(component.GetSpec()))
 			for key := range component.GetSpec() {
@@ -128,6 +189,9 @@ func (o *CliOptions) dump(do *doOptions) error {
 			component = secret.New(component.GetName(), o.Config.Context, redactedData)
 			object, _ = component.AsK8sObject()
 		}
+		if !o.IncludeProvenance {
+			object = manifest.StripProvenance(object)
+		}
 		if reconcilable, ok := component.(triggermesh.Reconcilable); ok {
 			if container, ok := component.(triggermesh.Runnable); ok {
 				if _, err := container.Info(context.Background()); err == nil {
@@ -147,6 +211,12 @@ func (o *CliOptions) dump(do *doOptions) error {
 			}
 		}
 
+		if object.Kind == tmbroker.TriggerKind && (o.Platform == platformKubernetes || o.Platform == platformKnative) {
+			if object, err = o.clusterTarget(object); err != nil {
+				return fmt.Errorf("trigger %q: %w", object.Metadata.Name, err)
+			}
+		}
+
 		switch o.Platform {
 		case platformDigitalOcean:
 			exportable, ok := component.(triggermesh.Exportable)
@@ -242,11 +312,17 @@ func (o *CliOptions) dump(do *doOptions) error {
 			return fmt.Errorf("platform %q is not supported", o.Platform)
 		}
 	}
-	res, err := o.format(output)
-	if err != nil {
-		return fmt.Errorf("output format error: %w", err)
+	if o.Layout == layoutKustomize {
+		if err := o.kustomize(output); err != nil {
+			return fmt.Errorf("kustomize: %w", err)
+		}
+	} else {
+		res, err := o.format(output)
+		if err != nil {
+			return fmt.Errorf("output format error: %w", err)
+		}
+		fmt.Println(string(res))
 	}
-	fmt.Println(string(res))
 
 	if len(externalReconcilable) != 0 {
 		fmt.Fprintf(os.Stderr, "\nWARNING: manifest contains running components that use external shared resources to produce events.\n"+
@@ -256,6 +332,43 @@ func (o *CliOptions) dump(do *doOptions) error {
 	return nil
 }
 
+func (o *CliOptions) validLayout() error {
+	switch o.Layout {
+	case "":
+		return nil
+	case layoutKustomize:
+		if o.Platform != platformKubernetes && o.Platform != platformKnative {
+			return fmt.Errorf("%q format is not supported for %q platform", o.Layout, o.Platform)
+		}
+		if o.OutputDir == "" {
+			return fmt.Errorf("--output-dir is required for %q format", o.Layout)
+		}
+		return nil
+	}
+	return fmt.Errorf("format %q is not supported", o.Layout)
+}
+
+// kustomize writes the platform objects as the kustomize base
+// with the overlays.
+func (o *CliOptions) kustomize(output interface{}) error {
+	items, _ := output.([]interface{})
+	objects := make([]kubernetes.Object, 0, len(items))
+	for _, item := range items {
+		if object, ok := item.(kubernetes.Object); ok {
+			objects = append(objects, object)
+		}
+	}
+	if err := kustomize.Write(o.OutputDir, objects, o.Overlays); err != nil {
+		return err
+	}
+	fmt.Fprintf(os.Stderr, "Base and %d overlay(s) written to %s\n", len(o.Overlays), o.OutputDir)
+	if len(o.Overlays) != 0 {
+		fmt.Fprintf(os.Stderr, "Build the environment with \"kubectl kustomize %s\"\n",
+			filepath.Join(o.OutputDir, kustomize.OverlaysDir, o.Overlays[0]))
+	}
+	return nil
+}
+
 func (o *CliOptions) getStaticBrokerConfig() ([]byte, error) {
 	var staticBrokerConfig tmbroker.Configuration
 	for _, object := range o.Manifest.Objects {
@@ -275,11 +388,14 @@ func (o *CliOptions) getStaticBrokerConfig() ([]byte, error) {
 			Filters: trigger.Filters,
 			Target: tmbroker.LocalTarget{
 				URL: func() string {
+					if trigger.IsExternal() {
+						return trigger.Target.URI.String()
+					}
 					switch o.Platform {
 					case platformDigitalOcean:
-						return fmt.Sprintf("${%s.PRIVATE_URL}", trigger.Target.Ref.Name)
+						return fmt.Sprintf("${%s.PRIVATE_URL}%s", trigger.Target.Ref.Name, trigger.TargetPath())
 					case platformDockerCompose, platformKubernetesGeneric:
-						return fmt.Sprintf("http://%s:8080", trigger.Target.Ref.Name)
+						return fmt.Sprintf("http://%s:8080%s", trigger.Target.Ref.Name, trigger.TargetPath())
 					}
 					return ""
 				}(),
@@ -289,6 +405,37 @@ func (o *CliOptions) getStaticBrokerConfig() ([]byte, error) {
 	return json.Marshal(staticBrokerConfig)
 }
 
+// clusterTarget replaces the trigger destination with the reference
+// to the target object in the cluster.
+func (o *CliOptions) clusterTarget(object kubernetes.Object) (kubernetes.Object, error) {
+	data, err := json.Marshal(object.Spec["target"])
+	if err != nil {
+		return object, err
+	}
+	var destination duckv1.Destination
+	if err := json.Unmarshal(data, &destination); err != nil {
+		return object, fmt.Errorf("decoding destination: %w", err)
+	}
+	if destination, err = tmbroker.ClusterDestination(destination, o.Manifest.Objects); err != nil {
+		return object, err
+	}
+	if data, err = json.Marshal(destination); err != nil {
+		return object, err
+	}
+	var target map[string]interface{}
+	if err := json.Unmarshal(data, &target); err != nil {
+		return object, err
+	}
+	// the spec map is shared with the manifest object
+	spec := make(map[string]interface{}, len(object.Spec))
+	for key, value := range object.Spec {
+		spec[key] = value
+	}
+	spec["target"] = target
+	object.Spec = spec
+	return object, nil
+}
+
 func (o *CliOptions) knativeEventingTransformation(object kubernetes.Object) kubernetes.Object {
 	switch object.APIVersion {
 	case tmbroker.APIVersion:
@@ -0,0 +1,68 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package dump
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// validate checks the manifest components against the CRDs installed in the
+// target cluster, rather than the local CRDs the components were created with.
+func (o *CliOptions) validate() error {
+	crds, err := crd.Load(o.ValidateAgainst)
+	if err != nil {
+		return fmt.Errorf("loading CRDs from %q: %w", o.ValidateAgainst, err)
+	}
+	failed := false
+	for _, object := range o.Manifest.Objects {
+		if !strings.Contains(object.APIVersion, ".triggermesh.io/") {
+			continue
+		}
+		var problems []string
+		c, exists := crds[strings.ToLower(object.Kind)]
+		if !exists {
+			problems = []string{fmt.Sprintf("kind %s is not installed", object.Kind)}
+		} else {
+			schema, _, err := c.ServedSchema()
+			if err != nil {
+				problems = []string{err.Error()}
+			} else {
+				problems = schema.Problems(object.Spec)
+			}
+		}
+		if len(problems) == 0 {
+			continue
+		}
+		failed = true
+		fmt.Fprintf(os.Stderr, "%s %q:\n", object.Kind, object.Metadata.Name)
+		for _, problem := range problems {
+			fmt.Fprintf(os.Stderr, "  %s\n", problem)
+		}
+	}
+	if !failed {
+		return nil
+	}
+	if o.Force {
+		fmt.Fprintln(os.Stderr, "WARNING! Manifest does not match the CRDs, dumping anyway")
+		return nil
+	}
+	return fmt.Errorf("manifest does not match the CRDs of %q, use --force to dump anyway", o.ValidateAgainst)
+}
@@ -0,0 +1,233 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package explainroute
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/route"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// statusTimeout limits the lookup of the consumer container.
+const statusTimeout = 5 * time.Second
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD:      crd,
+		Config:   config,
+		Manifest: manifest,
+	}
+	var eventType, source string
+	explainCmd := &cobra.Command{
+		Use:   "explain-route --type <type> [--source <source>]",
+		Short: "Explain which components the event of the type would reach",
+		Long: `Explain which components the event of the type would reach.
+Every trigger of the broker is evaluated against the event attributes,
+the events that the matched transformations send back to the broker are
+followed with their declared output types. No events are sent.`,
+		Example: `tmctl explain-route --type com.amazon.s3.objectcreated
+tmctl explain-route --type order.created --source foo-webhooksource`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.explain(os.Stdout, eventType, source)
+		},
+	}
+	explainCmd.Flags().StringVar(&eventType, "type", "", "Type of the event")
+	explainCmd.Flags().StringVar(&source, "source", "", "Source attribute of the event or the name of the source component")
+	cobra.CheckErr(explainCmd.MarkFlagRequired("type"))
+	cobra.CheckErr(explainCmd.RegisterFlagCompletionFunc("type", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.ListEventTypesWithKind(o.Manifest, o.Config, completion.Index(o.Config)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(explainCmd.RegisterFlagCompletionFunc("source", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.Components(o.Manifest, completion.ListSources(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	return explainCmd
+}
+
+func (o *CliOptions) explain(out io.Writer, eventType, source string) error {
+	configuration, err := tmbroker.ReadConfig(o.Config.ConfigHome, o.Config.Context)
+	if err != nil {
+		return fmt.Errorf("broker config: %w", err)
+	}
+	event := route.Event{"type": eventType}
+	if source != "" {
+		event["source"] = o.sourceAttribute(source)
+	}
+	fmt.Fprintf(out, "Event %s\n\n", attributes(event))
+
+	hops := route.Trace(configuration.Triggers, event, o.consumer)
+	if len(hops) == 0 {
+		fmt.Fprintln(out, "Broker has no triggers, the event is dropped")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 10, 5, 3, ' ', 0)
+	fmt.Fprintln(w, "Trigger\tDestination\tDecision")
+	printHops(w, hops, "")
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	terminals := make(map[string]bool)
+	collectTerminals(hops, terminals)
+	if len(terminals) == 0 {
+		fmt.Fprintln(out, "\nNo trigger matches the event, it is dropped by the broker")
+		return nil
+	}
+	var ends []string
+	for end, down := range terminals {
+		if down {
+			end += " (down)"
+		}
+		ends = append(ends, end)
+	}
+	sort.Strings(ends)
+	fmt.Fprintf(out, "\nPath terminates at: %s\n", strings.Join(ends, ", "))
+	return nil
+}
+
+func printHops(w io.Writer, hops []route.Hop, indent string) {
+	for _, hop := range hops {
+		fmt.Fprintf(w, "%s%s\t%s\t%s\n", indent, hop.Trigger, hop.Destination, decision(hop))
+		printHops(w, hop.Next, indent+"  ")
+	}
+}
+
+func decision(hop route.Hop) string {
+	var result string
+	switch hop.Decision {
+	case route.Matched:
+		result = "matched"
+	case route.Unknown:
+		result = fmt.Sprintf("may match, %s", hop.Reason)
+	default:
+		return fmt.Sprintf("not matched, %s", hop.Reason)
+	}
+	switch {
+	case hop.Down():
+		result = "would match but target is down"
+		if hop.Decision == route.Unknown {
+			result = fmt.Sprintf("may match but target is down, %s", hop.Reason)
+		}
+	case hop.Loop:
+		result += fmt.Sprintf(", replies with %q that loops back to this trigger", hop.Reply["type"])
+	case hop.Reply != nil:
+		result += fmt.Sprintf(", type changes to %q", hop.Reply["type"])
+	case hop.Consumer != nil && hop.Consumer.Replies:
+		result += ", reply type is not declared, the path is not followed"
+	}
+	return result
+}
+
+// collectTerminals returns the destinations where the path of the event ends
+// and whether they are down.
+func collectTerminals(hops []route.Hop, terminals map[string]bool) {
+	for _, hop := range hops {
+		if hop.Decision == route.NotMatched {
+			continue
+		}
+		if hop.Reply != nil && !hop.Loop && !hop.Down() {
+			collectTerminals(hop.Next, terminals)
+			continue
+		}
+		terminals[hop.Destination] = terminals[hop.Destination] || hop.Down()
+	}
+}
+
+// consumer describes the trigger destination component of the manifest.
+func (o *CliOptions) consumer(name string) *route.Consumer {
+	c, err := components.GetObject(name, o.Config, o.Manifest, o.CRD)
+	if err != nil || c == nil {
+		// missing component cannot receive the events
+		return &route.Consumer{}
+	}
+	result := &route.Consumer{Running: true}
+	if runnable, ok := c.(triggermesh.Runnable); ok {
+		ctx, cancel := context.WithTimeout(context.Background(), statusTimeout)
+		defer cancel()
+		container, err := runnable.Info(ctx)
+		result.Running = err == nil && container.Online
+	}
+	switch component := c.(type) {
+	case *transformation.Transformation:
+		// JQ transformations deliver the events to the target directly
+		result.Replies = component.Engine() != transformation.EngineJQ
+	case *service.Service:
+		result.Replies = component.IsSource()
+	}
+	if producer, ok := c.(triggermesh.Producer); ok && result.Replies {
+		if et, err := producer.GetEventTypes(); err == nil && len(et) != 0 {
+			result.Type = et[0]
+		}
+		result.Source, _ = producer.GetEventSource()
+	}
+	return result
+}
+
+// sourceAttribute returns the source attribute of the events
+// produced by the source component or the value itself.
+func (o *CliOptions) sourceAttribute(source string) string {
+	c, err := components.GetObject(source, o.Config, o.Manifest, o.CRD)
+	if err != nil || c == nil {
+		return source
+	}
+	if producer, ok := c.(triggermesh.Producer); ok {
+		if attribute, err := producer.GetEventSource(); err == nil && attribute != "" {
+			return attribute
+		}
+	}
+	return source
+}
+
+func attributes(event route.Event) string {
+	keys := make([]string, 0, len(event))
+	for key := range event {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	var result []string
+	for _, key := range keys {
+		result = append(result, fmt.Sprintf("%s=%s", key, event[key]))
+	}
+	return strings.Join(result, " ")
+}
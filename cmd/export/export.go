@@ -0,0 +1,130 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package export
+
+import (
+	"fmt"
+	"os"
+	"time"
+
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/triggermesh/tmctl/pkg/bundle"
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/prompt"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest) *cobra.Command {
+	o := &CliOptions{
+		Config:   config,
+		Manifest: manifest,
+	}
+	var output string
+	exportCmd := &cobra.Command{
+		Use:   "export <component> --output <bundle.tar.gz>",
+		Short: "Export the component with its triggers and secrets as a bundle",
+		Long: `Export the component with its triggers and secrets as a bundle.
+Secrets are encrypted with the passphrase prompted during the export.
+The bundle can be imported into another broker with "tmctl import <bundle.tar.gz>".`,
+		Example: "tmctl export foo-salesforcesource --output salesforce.tar.gz",
+		Args:    cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completion.Components(o.Manifest, completion.ListSources(o.Manifest), completion.ListTargets(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			if output == "" {
+				output = args[0] + ".tar.gz"
+			}
+			return o.export(args[0], output)
+		},
+	}
+	exportCmd.Flags().StringVarP(&output, "output", "o", "", "Bundle file. Default is <component>.tar.gz")
+	return exportCmd
+}
+
+func (o *CliOptions) export(name, output string) error {
+	b, err := o.collect(name)
+	if err != nil {
+		return err
+	}
+	var passphrase string
+	if len(b.Secrets) != 0 {
+		if passphrase, err = prompt.NewPassword("Secrets passphrase"); err != nil {
+			return err
+		}
+	}
+	f, err := os.Create(output)
+	if err != nil {
+		return fmt.Errorf("bundle file: %w", err)
+	}
+	defer f.Close()
+	if err := bundle.Write(f, b, passphrase); err != nil {
+		os.Remove(output)
+		return fmt.Errorf("writing bundle: %w", err)
+	}
+	log.Printf("Exported %q with %d trigger(s) and %d secret(s) to %s",
+		name, len(b.Objects)-1, len(b.Secrets), output)
+	return nil
+}
+
+// collect returns the bundle of the component, triggers delivering
+// events to the component and the component's secrets.
+func (o *CliOptions) collect(name string) (*bundle.Bundle, error) {
+	b := &bundle.Bundle{
+		Component: name,
+		Created:   time.Now().UTC(),
+	}
+	var triggers []kubernetes.Object
+	for _, object := range o.Manifest.Objects {
+		switch {
+		case object.Metadata.Name == name:
+			if object.APIVersion == tmbroker.APIVersion {
+				return nil, fmt.Errorf("%q is not a component", name)
+			}
+			b.Kind = object.Kind
+			b.Objects = []kubernetes.Object{object}
+		case object.Kind == "Secret" && object.Metadata.Name == name+"-secret":
+			b.Secrets = append(b.Secrets, object)
+		case object.Kind == tmbroker.TriggerKind:
+			if target, _, _ := unstructured.NestedString(object.Spec, "target", "ref", "name"); target == name {
+				triggers = append(triggers, object)
+			}
+		}
+	}
+	if b.Kind == "" {
+		return nil, fmt.Errorf("%q not found", name)
+	}
+	b.Objects = append(b.Objects, triggers...)
+	return b, nil
+}
@@ -0,0 +1,157 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package expose
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/tunnel"
+)
+
+const (
+	providerLocaltunnel = "localtunnel"
+	providerCustomSSH   = "custom-ssh"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+
+	Provider string
+	Server   string
+	Command  string
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD:      crd,
+		Config:   config,
+		Manifest: manifest,
+	}
+	exposeCmd := &cobra.Command{
+		Use:   "expose <source> [--provider localtunnel|custom-ssh][--server <url>][--command <template>]",
+		Short: "Expose source to the internet to receive external webhooks",
+		Long: `Expose source to the internet to receive external webhooks.
+The tunnel is kept open until the command is interrupted and reconnected
+if it drops. The public URL is temporary and is not saved in the manifest.`,
+		Example: `tmctl expose foo-webhooksource
+
+tmctl expose foo-webhooksource --provider custom-ssh \
+	--command 'ssh -R 80:localhost:{{.Port}} serveo.net'`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return []string{}, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completion.Components(o.Manifest, completion.ListSources(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.expose(args[0])
+		},
+	}
+	exposeCmd.Flags().StringVar(&o.Provider, "provider", providerLocaltunnel, "Tunnel provider, \"localtunnel\" or \"custom-ssh\"")
+	exposeCmd.Flags().StringVar(&o.Server, "server", tunnel.DefaultLocaltunnelServer, "Localtunnel server address")
+	exposeCmd.Flags().StringVar(&o.Command, "command", "", "Tunnel command template, {{.Port}} is replaced with the source port")
+	cobra.CheckErr(exposeCmd.RegisterFlagCompletionFunc("provider", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{providerLocaltunnel, providerCustomSSH}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(exposeCmd.RegisterFlagCompletionFunc("server", cobra.NoFileCompletions))
+	cobra.CheckErr(exposeCmd.RegisterFlagCompletionFunc("command", cobra.NoFileCompletions))
+	return exposeCmd
+}
+
+func (o *CliOptions) expose(source string) error {
+	var provider tunnel.Provider
+	switch o.Provider {
+	case providerLocaltunnel:
+		provider = &tunnel.Localtunnel{Server: o.Server}
+	case providerCustomSSH:
+		if o.Command == "" {
+			return fmt.Errorf("%s provider requires --command", providerCustomSSH)
+		}
+		provider = &tunnel.Command{Template: o.Command}
+	default:
+		return fmt.Errorf("unsupported tunnel provider %q", o.Provider)
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	component, err := components.GetObject(source, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return fmt.Errorf("%q: %w", source, err)
+	}
+	if _, ok := component.(triggermesh.Producer); !ok {
+		return fmt.Errorf("%q is not an event source", source)
+	}
+	runnable, ok := component.(triggermesh.Runnable)
+	if !ok {
+		return fmt.Errorf("%q is not runnable", source)
+	}
+	container, err := runnable.Info(ctx)
+	if err != nil || !container.Online {
+		return fmt.Errorf("%q is not running", source)
+	}
+	port := container.HostPort()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer close(c)
+	go func() {
+		<-c
+		cancel()
+	}()
+
+	defer func() {
+		if err := tunnel.Clear(o.Config.ConfigHome, o.Config.Context, source); err != nil {
+			log.Printf("Cleanup: %v", err)
+		}
+	}()
+
+	log.Printf("Opening %s tunnel to %q (localhost:%s)", o.Provider, source, port)
+	tunnel.Keep(ctx, provider, port, func(url string) {
+		if err := tunnel.SetURL(o.Config.ConfigHome, o.Config.Context, source, url); err != nil {
+			log.Printf("Saving tunnel URL: %v", err)
+		}
+		log.Printf("%q is available at %s", source, url)
+	}, func(err error, retry time.Duration) {
+		if err := tunnel.Clear(o.Config.ConfigHome, o.Config.Context, source); err != nil {
+			log.Printf("Cleanup: %v", err)
+		}
+		log.Printf("Tunnel dropped: %v. Reconnecting in %s", err, retry)
+	})
+	log.Println("Closing tunnel")
+	return nil
+}
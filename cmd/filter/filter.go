@@ -0,0 +1,239 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filter
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/spf13/cobra"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/datafilter"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/route"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/wiretap"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+}
+
+// rule is the trigger evaluated against the events: the broker
+// filters match the attributes, the data filter matches the payload.
+type rule struct {
+	trigger string
+	filters []eventingbroker.Filter
+	data    *datafilter.Expression
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest) *cobra.Command {
+	o := &CliOptions{
+		Config:   config,
+		Manifest: manifest,
+	}
+	filterCmd := &cobra.Command{
+		Use:   "filter",
+		Short: "Work with the trigger filters",
+	}
+	filterCmd.AddCommand(o.newTestCmd())
+	return filterCmd
+}
+
+func (o *CliOptions) newTestCmd() *cobra.Command {
+	var events, trigger, rawFilter, dataFilter string
+	testCmd := &cobra.Command{
+		Use:   "test --events <file> (--trigger <name> | [--filter <json>][--data-filter <expression>])",
+		Short: "Evaluate the trigger filters against the recorded events",
+		Long: `Evaluate the trigger filters against the recorded events.
+Events are read from the file written by "tmctl record". The broker filters
+are evaluated against the event attributes, the data filters against the
+JSON payload. No events are sent.`,
+		Example: `tmctl filter test --events events.jsonl --trigger foo-trigger
+
+tmctl filter test --events events.jsonl --filter '{"exact":{"type":"order.created"}}' --data-filter 'data.region == "eu-west-1"'`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if trigger == "" && rawFilter == "" && dataFilter == "" {
+				return fmt.Errorf("either --trigger, --filter or --data-filter must be set")
+			}
+			rules, err := o.rules(trigger, rawFilter, dataFilter)
+			if err != nil {
+				return err
+			}
+			f, err := os.Open(events)
+			if err != nil {
+				return fmt.Errorf("events file: %w", err)
+			}
+			defer f.Close()
+			recorded, err := wiretap.ReadRecorded(f)
+			if err != nil {
+				return fmt.Errorf("events file %q: %w", events, err)
+			}
+			return test(os.Stdout, rules, recorded)
+		},
+	}
+	testCmd.Flags().StringVar(&events, "events", "", "File with the events recorded by \"tmctl record\"")
+	testCmd.Flags().StringVar(&trigger, "trigger", "", "Trigger to evaluate, the name of the trigger created with --transform or --data-filter selects all its input triggers")
+	testCmd.Flags().StringVar(&rawFilter, "filter", "", "Raw filter JSON")
+	testCmd.Flags().StringVar(&dataFilter, "data-filter", "", "Expression on the event payload fields, e.g. 'data.region == \"eu-west-1\"'")
+	testCmd.MarkFlagsMutuallyExclusive("trigger", "filter")
+	testCmd.MarkFlagsMutuallyExclusive("trigger", "data-filter")
+	cobra.CheckErr(testCmd.MarkFlagRequired("events"))
+	cobra.CheckErr(testCmd.RegisterFlagCompletionFunc("events", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{"jsonl", "json"}, cobra.ShellCompDirectiveFilterFileExt
+	}))
+	cobra.CheckErr(testCmd.RegisterFlagCompletionFunc("filter", cobra.NoFileCompletions))
+	cobra.CheckErr(testCmd.RegisterFlagCompletionFunc("data-filter", cobra.NoFileCompletions))
+	cobra.CheckErr(testCmd.RegisterFlagCompletionFunc("trigger", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.Components(o.Manifest, completion.ListObjectsByKind(tmbroker.TriggerKind, o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	return testCmd
+}
+
+// rules returns the triggers of the broker config with the data filters
+// recorded in the manifest or, without the trigger, the filters of the flags.
+func (o *CliOptions) rules(trigger, rawFilter, dataFilter string) ([]rule, error) {
+	if trigger == "" {
+		r := rule{trigger: "-"}
+		if rawFilter != "" {
+			var filter eventingbroker.Filter
+			if err := json.Unmarshal([]byte(rawFilter), &filter); err != nil {
+				return nil, fmt.Errorf("cannot decode filter JSON %q: %w", rawFilter, err)
+			}
+			r.filters = []eventingbroker.Filter{filter}
+		}
+		if dataFilter != "" {
+			expression, err := datafilter.Parse(dataFilter)
+			if err != nil {
+				return nil, err
+			}
+			r.data = expression
+		}
+		return []rule{r}, nil
+	}
+
+	if err := o.Manifest.Read(); err != nil {
+		return nil, fmt.Errorf("reading manifest: %w", err)
+	}
+	configuration, err := tmbroker.ReadConfig(o.Config.ConfigHome, o.Config.Context)
+	if err != nil {
+		return nil, fmt.Errorf("broker config: %w", err)
+	}
+	var rules []rule
+	for _, object := range o.Manifest.Objects {
+		if object.Kind != tmbroker.TriggerKind {
+			continue
+		}
+		owner := object.Metadata.Annotations[triggermesh.OwnerAnnotation]
+		// output triggers of the owner deliver the results, not the recorded events
+		if object.Metadata.Name != trigger && (owner != trigger || object.Metadata.Name == owner+"-output") {
+			continue
+		}
+		spec, exists := configuration.Triggers[object.Metadata.Name]
+		if !exists {
+			return nil, fmt.Errorf("trigger %q is not in the broker config", object.Metadata.Name)
+		}
+		r := rule{
+			trigger: object.Metadata.Name,
+			filters: spec.Filters,
+		}
+		if expression, set := object.Metadata.Annotations[triggermesh.DataFilterAnnotation]; set {
+			if r.data, err = datafilter.Parse(expression); err != nil {
+				return nil, fmt.Errorf("trigger %q: %w", object.Metadata.Name, err)
+			}
+		}
+		rules = append(rules, r)
+	}
+	if len(rules) == 0 {
+		return nil, fmt.Errorf("trigger %q not found", trigger)
+	}
+	sort.Slice(rules, func(i, j int) bool {
+		return rules[i].trigger < rules[j].trigger
+	})
+	return rules, nil
+}
+
+// test prints the result of every event, the event matches
+// if any of the rules matches both its attributes and payload.
+func test(out io.Writer, rules []rule, events []cloudevents.Event) error {
+	if len(events) == 0 {
+		fmt.Fprintln(out, "No recorded events")
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 10, 5, 3, ' ', 0)
+	fmt.Fprintln(w, "Event\tType\tResult")
+	matched := 0
+	for _, event := range events {
+		result, ok := evaluate(rules, event)
+		if ok {
+			matched++
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", event.ID(), event.Type(), result)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	fmt.Fprintf(out, "\n%d of %d recorded events match\n", matched, len(events))
+	return nil
+}
+
+// evaluate returns the trigger that matches the event
+// or the reasons why none of the triggers match.
+func evaluate(rules []rule, event cloudevents.Event) (string, bool) {
+	var reasons []string
+	for _, r := range rules {
+		reason := r.evaluate(event)
+		switch {
+		case reason == "" && len(rules) == 1:
+			return "matched", true
+		case reason == "":
+			return "matched by " + r.trigger, true
+		case len(rules) > 1:
+			reason = fmt.Sprintf("%s: %s", r.trigger, reason)
+		}
+		reasons = append(reasons, reason)
+	}
+	return "not matched, " + strings.Join(reasons, "; "), false
+}
+
+// evaluate returns the reason why the event does not match the rule,
+// empty reason means that the event matches.
+func (r rule) evaluate(event cloudevents.Event) string {
+	if decision, reason := route.Match(r.filters, route.Observed(event)); decision != route.Matched {
+		return reason
+	}
+	if r.data == nil {
+		return ""
+	}
+	if matched, reason := r.data.Match(event.Data()); !matched {
+		return reason
+	}
+	return ""
+}
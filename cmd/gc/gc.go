@@ -0,0 +1,148 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package gc
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/go-units"
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/garbage"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter"
+)
+
+type CliOptions struct {
+	Config *config.Config
+
+	DryRun      bool
+	AllContexts bool
+}
+
+func NewCmd(config *config.Config) *cobra.Command {
+	o := &CliOptions{
+		Config: config,
+	}
+	gcCmd := &cobra.Command{
+		Use:   "gc [--dry-run][--all-contexts]",
+		Short: "Remove exited tmctl containers and unused TriggerMesh images",
+		Long: `Remove exited tmctl containers and unused TriggerMesh images.
+Exited containers created by tmctl in the current context are removed,
+"--all-contexts" removes the ones of all contexts. TriggerMesh images are removed
+if no container uses them and their tags match neither the configured version
+nor the versions pinned in the manifests of any context. Containers without
+tmctl labels and images tagged outside of the TriggerMesh registry are never removed.`,
+		Example: `tmctl gc --dry-run
+
+tmctl gc --all-contexts`,
+		Args: cobra.NoArgs,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			return []string{"--dry-run", "--all-contexts"}, cobra.ShellCompDirectiveNoFileComp
+		},
+		SilenceUsage: true,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := docker.CheckDaemon(); err != nil {
+				return err
+			}
+			return o.gc()
+		},
+	}
+	gcCmd.Flags().BoolVar(&o.DryRun, "dry-run", false, "List the containers and images to remove without removing them")
+	gcCmd.Flags().BoolVar(&o.AllContexts, "all-contexts", false, "Remove the exited containers of all contexts")
+	return gcCmd
+}
+
+func (o *CliOptions) gc() error {
+	ctx := context.Background()
+	client, err := docker.NewClient()
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	containers, err := docker.LabeledContainers(ctx, client, func(context string) bool {
+		return o.AllContexts || context == o.Config.Context
+	})
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+	used, err := docker.UsedImages(ctx, client)
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+	repository := adapter.Registry + "/"
+	images, err := docker.Images(ctx, client, repository)
+	if err != nil {
+		return fmt.Errorf("listing images: %w", err)
+	}
+	refs, err := garbage.ContextReferences(o.Config)
+	if err != nil {
+		return fmt.Errorf("images are kept: %w", err)
+	}
+	plan := garbage.NewPlan(containers, used, images, repository, refs)
+	if plan.Empty() {
+		fmt.Println("Nothing to remove")
+		return nil
+	}
+	if o.DryRun {
+		for _, container := range plan.Containers {
+			fmt.Printf("Container %s\t%s\t%s\n", container.Name, contextName(container.Context), units.HumanSize(float64(container.Size)))
+		}
+		for _, image := range plan.Images {
+			for _, tag := range image.Tags {
+				fmt.Printf("Image %s\t%s\n", tag, units.HumanSize(float64(image.Size)))
+			}
+		}
+		fmt.Printf("Up to %s can be reclaimed\n", units.HumanSize(float64(plan.Size())))
+		return nil
+	}
+
+	var reclaimed int64
+	for _, container := range plan.Containers {
+		log.Printf("Removing container %s", container.Name)
+		if err := docker.RemoveContainer(ctx, container.ID, client); err != nil {
+			log.Printf("Removing container %q: %v", container.Name, err)
+			continue
+		}
+		reclaimed += container.Size
+	}
+	for _, image := range plan.Images {
+		removed := true
+		for _, tag := range image.Tags {
+			log.Printf("Removing image %s", tag)
+			if err := docker.RemoveImage(ctx, tag, client); err != nil {
+				log.Printf("Removing image %q: %v", tag, err)
+				removed = false
+			}
+		}
+		if removed {
+			reclaimed += image.Size
+		}
+	}
+	fmt.Printf("Reclaimed %s\n", units.HumanSize(float64(reclaimed)))
+	return nil
+}
+
+// contextName returns the printed name of the container context.
+func contextName(context string) string {
+	if context == "" {
+		return "unknown context"
+	}
+	return context
+}
@@ -0,0 +1,382 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package get
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+	kyaml "sigs.k8s.io/yaml"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/deliveries"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/throughput"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+const (
+	roleProducer = "producer"
+	roleConsumer = "consumer"
+	roleBoth     = "both"
+)
+
+var resources = []string{"brokers", "sources", "targets", "transformations", "triggers"}
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+
+	Output  string
+	Metrics bool
+}
+
+// Row is the component summary printed by the get command.
+type Row struct {
+	Name        string   `json:"name"`
+	Kind        string   `json:"kind"`
+	Role        string   `json:"role,omitempty"`
+	Status      string   `json:"status,omitempty"`
+	Port        string   `json:"port,omitempty"`
+	EventTypes  []string `json:"eventTypes,omitempty"`
+	Image       string   `json:"image,omitempty"`
+	ContainerID string   `json:"containerID,omitempty"`
+	// Rates are set with --metrics if the broker exposes the events.
+	Rates *throughput.Rate `json:"rates,omitempty"`
+	// Idle marks the component that the triggers route
+	// the events to but that has not received any.
+	Idle bool `json:"idle,omitempty"`
+
+	resource string
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		Config:   config,
+		Manifest: manifest,
+		CRD:      crd,
+	}
+	getCmd := &cobra.Command{
+		Use:   "get [brokers|sources|targets|transformations|triggers][-o wide|name|json|yaml]",
+		Short: "Print the table of the broker components",
+		Example: `tmctl get
+
+tmctl get targets -o wide
+
+tmctl get sources -o name
+
+tmctl get targets --metrics`,
+		Args:      cobra.MaximumNArgs(1),
+		ValidArgs: resources,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var resource string
+			if len(args) == 1 {
+				resource = args[0]
+			}
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.Get(resource)
+		},
+	}
+	getCmd.Flags().StringVarP(&o.Output, "output", "o", "", "Output format: wide, name, json or yaml")
+	getCmd.Flags().BoolVar(&o.Metrics, "metrics", false, "Show the events per minute received and sent by the components over the last 1 and 5 minutes")
+	cobra.CheckErr(getCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{"wide", "name", "json", "yaml"}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	return getCmd
+}
+
+// Get prints the components of the resource type, all components if the type is empty.
+func (o *CliOptions) Get(resource string) error {
+	if resource != "" {
+		valid := false
+		for _, r := range resources {
+			if r == resource {
+				valid = true
+				break
+			}
+		}
+		if !valid {
+			return fmt.Errorf("unknown resource type %q, expected one of: %s", resource, strings.Join(resources, ", "))
+		}
+	}
+	rows, err := o.rows(resource)
+	if err != nil {
+		return err
+	}
+	if o.Metrics {
+		o.rates(rows)
+	}
+	return o.print(os.Stdout, rows)
+}
+
+func (o *CliOptions) rows(resource string) ([]Row, error) {
+	if o.Config.StrictKinds {
+		if err := components.CheckKinds(o.Manifest, o.CRD); err != nil {
+			return nil, err
+		}
+	}
+	ctx := context.Background()
+	var rows []Row
+	for _, object := range o.Manifest.Objects {
+		// owned components are the implementation details of their owners
+		if _, owned := object.Metadata.Annotations[triggermesh.OwnerAnnotation]; owned && object.Kind != tmbroker.TriggerKind {
+			continue
+		}
+		c, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+		var unsupported *components.UnsupportedKindError
+		if errors.As(err, &unsupported) {
+			// listed to let the user know the object is there but not managed
+			if resource == "" || unsupportedResource(unsupported.Group) == resource {
+				rows = append(rows, Row{Name: object.Metadata.Name, Kind: object.Kind, Status: unsupported.Status()})
+			}
+			continue
+		}
+		if err != nil {
+			log.Printf("WARNING! Skipping %s %q: %v", object.Kind, object.Metadata.Name, err)
+			continue
+		}
+		if c == nil {
+			continue
+		}
+		row := Row{
+			Name: c.GetName(),
+			Kind: c.GetKind(),
+		}
+		switch t := c.(type) {
+		case *tmbroker.Trigger:
+			row.resource = "triggers"
+			row.EventTypes = filterEventTypes(t.Filters)
+		case *tmbroker.Broker:
+			row.resource = "brokers"
+			row.Role = roleBoth
+		case *transformation.Transformation:
+			row.resource = "transformations"
+			row.Role = roleBoth
+			row.EventTypes, _ = t.GetEventTypes()
+		case *service.Service:
+			row.resource = "targets"
+			switch {
+			case t.IsSource() && t.IsTarget():
+				row.Role = roleBoth
+			case t.IsSource():
+				row.resource = "sources"
+				row.Role = roleProducer
+				row.EventTypes, _ = t.GetEventTypes()
+			default:
+				row.Role = roleConsumer
+				row.EventTypes, _ = t.ConsumedEventTypes()
+			}
+		case triggermesh.Producer:
+			row.resource = "sources"
+			row.Role = roleProducer
+			row.EventTypes, _ = t.GetEventTypes()
+		case triggermesh.Consumer:
+			row.resource = "targets"
+			row.Role = roleConsumer
+			row.EventTypes, _ = t.ConsumedEventTypes()
+		}
+		if resource != "" && row.resource != resource {
+			continue
+		}
+		if runnable, ok := c.(triggermesh.Runnable); ok {
+			row.Status = "offline"
+			if container, err := runnable.Info(ctx); err == nil {
+				if container.Online {
+					row.Status = "online"
+				}
+				row.Port = container.HostPort()
+				row.Image = container.RuntimeImage()
+				row.ContainerID = container.ID
+				if len(row.ContainerID) > 12 {
+					row.ContainerID = row.ContainerID[:12]
+				}
+			}
+		}
+		rows = append(rows, row)
+	}
+	sort.SliceStable(rows, func(i, j int) bool {
+		if rows[i].Kind != rows[j].Kind {
+			return rows[i].Kind < rows[j].Kind
+		}
+		return rows[i].Name < rows[j].Name
+	})
+	return rows, nil
+}
+
+// unsupportedResource returns the resource type of the unsupported
+// object API group, empty for the groups with no resource type.
+func unsupportedResource(group string) string {
+	switch group {
+	case "sources.triggermesh.io":
+		return "sources"
+	case "targets.triggermesh.io":
+		return "targets"
+	case "flow.triggermesh.io":
+		return "transformations"
+	}
+	return ""
+}
+
+// rates sets the observed event rates of the rows. Missing rates
+// are printed as not available, the command does not fail.
+func (o *CliOptions) rates(rows []Row) {
+	rates, err := throughput.Load(o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		if !errors.Is(err, deliveries.ErrUnsupported) {
+			log.Printf("WARNING! Event rates: %v", err)
+		}
+		return
+	}
+	for i := range rows {
+		rate := rates.Of(rows[i].Name)
+		rows[i].Rates = &rate
+		rows[i].Idle = rates.Idle(rows[i].Name)
+	}
+}
+
+func (o *CliOptions) print(out io.Writer, rows []Row) error {
+	switch o.Output {
+	case "json":
+		if rows == nil {
+			rows = []Row{}
+		}
+		data, err := json.MarshalIndent(rows, "", "  ")
+		if err != nil {
+			return fmt.Errorf("marshal rows: %w", err)
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	case "yaml":
+		if rows == nil {
+			rows = []Row{}
+		}
+		data, err := kyaml.Marshal(rows)
+		if err != nil {
+			return fmt.Errorf("marshal rows: %w", err)
+		}
+		fmt.Fprint(out, string(data))
+		return nil
+	case "name":
+		for _, row := range rows {
+			fmt.Fprintf(out, "%s/%s\n", strings.ToLower(row.Kind), row.Name)
+		}
+		return nil
+	case "", "wide":
+	default:
+		return fmt.Errorf("unsupported output format %q", o.Output)
+	}
+
+	w := tabwriter.NewWriter(out, 10, 5, 3, ' ', 0)
+	wide := o.Output == "wide"
+	header := "NAME\tKIND\tROLE\tSTATUS\tPORT\tEVENT TYPES"
+	if wide {
+		header += "\tIMAGE\tCONTAINER ID"
+	}
+	if o.Metrics {
+		header += "\tIN/MIN (1M/5M)\tOUT/MIN (1M/5M)"
+	}
+	fmt.Fprintln(w, header)
+	idle := false
+	for _, row := range rows {
+		line := strings.Join([]string{
+			row.Name,
+			row.Kind,
+			orNone(row.Role),
+			orNone(row.Status),
+			orNone(row.Port),
+			orNone(strings.Join(row.EventTypes, ",")),
+		}, "\t")
+		if wide {
+			line = fmt.Sprintf("%s\t%s\t%s", line, orNone(row.Image), orNone(row.ContainerID))
+		}
+		if o.Metrics {
+			in, out := rateColumns(row)
+			line = fmt.Sprintf("%s\t%s\t%s", line, in, out)
+			idle = idle || row.Idle
+		}
+		fmt.Fprintln(w, line)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if idle {
+		fmt.Fprintln(out, "\n* no events received in the last 5 minutes although the triggers route to the component")
+	}
+	return nil
+}
+
+// rateColumns returns the incoming and outgoing rates of the row,
+// the direction that does not apply to the component role is a dash.
+func rateColumns(row Row) (string, string) {
+	if row.Rates == nil {
+		return throughput.NotAvailable, throughput.NotAvailable
+	}
+	in, out := row.Rates.In(), row.Rates.Out()
+	if row.Idle {
+		in += "*"
+	}
+	switch row.Role {
+	case roleProducer:
+		in = "-"
+	case roleConsumer:
+		out = "-"
+	}
+	return in, out
+}
+
+// filterEventTypes returns the event types matched by the trigger filters.
+func filterEventTypes(filters []eventingbroker.Filter) []string {
+	var result []string
+	for _, f := range filters {
+		if v, ok := f.Exact["type"]; ok {
+			result = append(result, v)
+		}
+		if v, ok := f.Prefix["type"]; ok {
+			result = append(result, v+"*")
+		}
+		if v, ok := f.Suffix["type"]; ok {
+			result = append(result, "*"+v)
+		}
+	}
+	return result
+}
+
+func orNone(value string) string {
+	if value == "" {
+		return "<none>"
+	}
+	return value
+}
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package import_
+
+import (
+	"fmt"
+	"os"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/triggermesh/tmctl/cmd/start"
+	"github.com/triggermesh/tmctl/pkg/bundle"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/prompt"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// importBundle re-creates the component exported with "tmctl export"
+// in the current context and starts it.
+func importBundle(path string, config *config.Config, m *manifest.Manifest, crds map[string]crd.CRD) error {
+	if config.Context == "" {
+		return fmt.Errorf("broker is not selected")
+	}
+	if err := m.Read(); err != nil {
+		return err
+	}
+	f, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("bundle file: %w", err)
+	}
+	defer f.Close()
+	b, err := bundle.Read(f, func() (string, error) {
+		return prompt.Password("Secrets passphrase")
+	})
+	if err != nil {
+		return fmt.Errorf("reading bundle: %w", err)
+	}
+
+	objects := append(b.Secrets, b.Objects...)
+	var conflicts []string
+	for _, object := range objects {
+		for _, existing := range m.Objects {
+			if existing.Metadata.Name == object.Metadata.Name {
+				conflicts = append(conflicts, fmt.Sprintf("%s %q", existing.Kind, existing.Metadata.Name))
+			}
+		}
+	}
+	if len(conflicts) != 0 {
+		return fmt.Errorf("%q already has: %s", config.Context, strings.Join(conflicts, ", "))
+	}
+
+	for _, object := range objects {
+		if object.Metadata.Labels == nil {
+			object.Metadata.Labels = make(map[string]string)
+		}
+		object.Metadata.Labels[triggermesh.ContextLabel] = config.Context
+		if object.Kind == tmbroker.TriggerKind {
+			if err := unstructured.SetNestedField(object.Spec, config.Context, "broker", "name"); err != nil {
+				return fmt.Errorf("trigger %q: %w", object.Metadata.Name, err)
+			}
+		}
+		m.Objects = append(m.Objects, object)
+	}
+	if err := m.Write(); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+
+	for _, object := range b.Objects {
+		if object.Kind != tmbroker.TriggerKind {
+			continue
+		}
+		trigger, err := components.GetObject(object.Metadata.Name, config, m, crds)
+		if err != nil {
+			return fmt.Errorf("trigger %q: %w", object.Metadata.Name, err)
+		}
+		if err := trigger.(*tmbroker.Trigger).WriteLocalConfig(); err != nil {
+			return fmt.Errorf("updating broker config: %w", err)
+		}
+	}
+	log.Printf("Imported %q into %q", b.Component, config.Context)
+
+	return (&start.CliOptions{
+		Config:   config,
+		Manifest: m,
+		CRD:      crds,
+	}).Start()
+}
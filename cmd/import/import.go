@@ -17,24 +17,67 @@ limitations under the License.
 package import_
 
 import (
+	"fmt"
+
 	"github.com/spf13/cobra"
+	"github.com/spf13/pflag"
 
+	"github.com/triggermesh/tmctl/pkg/completion"
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/load"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/sops"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 )
 
-func NewCmd(config *config.Config, crd map[string]crd.CRD) *cobra.Command {
+func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
 	var from string
+	var convert bool
+	var decrypt string
 	importCmd := &cobra.Command{
-		Use:     "import -f <path/to/manifest.yaml>/<manifest URL>",
-		Short:   "Import TriggerMesh manifest",
-		Example: "tmctl import -f manifest.yaml",
+		Use:   "import -f <path/to/manifest.yaml>/<manifest URL> | <bundle.tar.gz>",
+		Short: "Import TriggerMesh manifest or component bundle",
+		Example: `tmctl import -f manifest.yaml
+
+tmctl import salesforce.tar.gz
+
+tmctl import --file knative.yaml --convert
+
+tmctl import -f manifest.enc.yaml --decrypt`,
+		Args: cobra.RangeArgs(0, 1),
 		RunE: func(cmd *cobra.Command, args []string) error {
-			return load.Import(from, config, crd)
+			if err := sops.ValidateMode(decrypt); err != nil {
+				return err
+			}
+			if len(args) == 1 {
+				return importBundle(args[0], config, m, crd)
+			}
+			if from == "" {
+				return fmt.Errorf("manifest path or bundle file is required")
+			}
+			if convert {
+				return load.ImportKnative(from, decrypt, config, crd)
+			}
+			return load.Import(from, decrypt, config, crd)
 		},
 	}
 	importCmd.Flags().StringVarP(&from, "from", "f", "", "Import manifest from")
-	cobra.CheckErr(importCmd.MarkFlagRequired("from"))
+	importCmd.Flags().BoolVar(&convert, "convert", false, "Convert Knative Eventing manifest into the local integration")
+	importCmd.Flags().StringVar(&decrypt, "decrypt", sops.DecryptAuto, "Decrypt the manifest encrypted with SOPS: auto, always or never")
+	importCmd.Flags().Lookup("decrypt").NoOptDefVal = sops.DecryptAlways
+	// --file is an alias of --from
+	importCmd.Flags().SetNormalizeFunc(func(f *pflag.FlagSet, name string) pflag.NormalizedName {
+		if name == "file" {
+			name = "from"
+		}
+		return pflag.NormalizedName(name)
+	})
+	cobra.CheckErr(importCmd.RegisterFlagCompletionFunc("from", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completion.ListSpecFiles(toComplete, nil, "yaml", "yml", "json"),
+			cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(importCmd.RegisterFlagCompletionFunc("decrypt", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return sops.Modes, cobra.ShellCompDirectiveNoFileComp
+	}))
 	return importCmd
 }
@@ -0,0 +1,93 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package link
+
+import (
+	"fmt"
+	"path/filepath"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+type CliOptions struct {
+	Config *config.Config
+}
+
+func NewCmd(config *config.Config) *cobra.Command {
+	o := &CliOptions{
+		Config: config,
+	}
+	var eventTypes []string
+	linkCmd := &cobra.Command{
+		Use:     "link <from-broker> <to-broker> --eventTypes <type>...",
+		Short:   "Forward the events from the broker of one context to another",
+		Example: `tmctl link orders billing --eventTypes order.created,order.cancelled`,
+		Args:    cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) >= 2 {
+				return []string{}, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completion.DescribeBrokers(completion.ListBrokers(o.Config.ConfigHome, ""), completion.ContainerStatus()), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := docker.CheckDaemon(); err != nil {
+				return err
+			}
+			return o.link(args[0], args[1], eventTypes)
+		},
+	}
+	linkCmd.Flags().StringSliceVar(&eventTypes, "eventTypes", []string{}, "Event types forwarded to the other broker")
+	cobra.CheckErr(linkCmd.MarkFlagRequired("eventTypes"))
+	cobra.CheckErr(linkCmd.RegisterFlagCompletionFunc("eventTypes", cobra.NoFileCompletions))
+	return linkCmd
+}
+
+func (o *CliOptions) link(from, to string, eventTypes []string) error {
+	if from == to {
+		return fmt.Errorf("broker %q cannot forward events to itself", from)
+	}
+	m := manifest.New(filepath.Join(o.Config.ConfigHome, from, triggermesh.ManifestFile))
+	if err := m.Read(); err != nil {
+		return fmt.Errorf("reading %q manifest: %w", from, err)
+	}
+	target, err := tmbroker.LinkTarget(o.Config.ConfigHome, to, o.Config.Triggermesh.Broker)
+	if err != nil {
+		return err
+	}
+	for _, eventType := range eventTypes {
+		trigger, err := tmbroker.NewTrigger("", from, o.Config.ConfigHome, target, tmbroker.FilterAttribute("type", eventType))
+		if err != nil {
+			return fmt.Errorf("creating trigger: %w", err)
+		}
+		if err := trigger.(*tmbroker.Trigger).WriteLocalConfig(); err != nil {
+			return err
+		}
+		if _, err := m.Add(trigger); err != nil {
+			return err
+		}
+		log.Printf("Events of %q type are forwarded from %q to %q", eventType, from, to)
+	}
+	return nil
+}
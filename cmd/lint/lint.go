@@ -0,0 +1,106 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lint
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+	"gopkg.in/yaml.v3"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD:      crd,
+		Config:   config,
+		Manifest: manifest,
+	}
+	return &cobra.Command{
+		Use:     "lint <component|file>",
+		Short:   "Check transformation specification for common mistakes",
+		Example: "tmctl lint foo-transformation",
+		Args:    cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completion.Components(o.Manifest, completion.ListObjectsByKind("Transformation", o.Manifest)), cobra.ShellCompDirectiveDefault
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.lint(args[0])
+		},
+	}
+}
+
+func (o *CliOptions) lint(name string) error {
+	spec, err := o.readSpec(name)
+	if err != nil {
+		return err
+	}
+	findings := transformation.Lint(spec)
+	if len(findings) == 0 {
+		fmt.Println("No issues found")
+		return nil
+	}
+	var errors int
+	for _, finding := range findings {
+		if finding.Severity == transformation.SeverityError {
+			errors++
+		}
+		fmt.Println(finding)
+	}
+	if errors != 0 {
+		return fmt.Errorf("%d error(s) found", errors)
+	}
+	return nil
+}
+
+func (o *CliOptions) readSpec(name string) (map[string]interface{}, error) {
+	if data, err := os.ReadFile(name); err == nil {
+		var spec map[string]interface{}
+		if err := yaml.Unmarshal(data, &spec); err != nil {
+			return nil, fmt.Errorf("decode spec: %w", err)
+		}
+		return spec, nil
+	}
+	if err := o.Manifest.Read(); err != nil {
+		return nil, fmt.Errorf("unable to read manifest: %w", err)
+	}
+	component, err := components.GetObject(name, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return nil, fmt.Errorf("%q: %w", name, err)
+	}
+	t, ok := component.(*transformation.Transformation)
+	if !ok {
+		return nil, fmt.Errorf("%q is not a transformation", name)
+	}
+	return t.GetSpec(), nil
+}
@@ -19,11 +19,13 @@ package logs
 import (
 	"bufio"
 	"context"
+	"errors"
 	"fmt"
 	"io"
 	"log"
 	"os"
 	"os/signal"
+	"path/filepath"
 	"syscall"
 	"time"
 
@@ -31,10 +33,13 @@ import (
 
 	"github.com/triggermesh/tmctl/pkg/completion"
 	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/crashlog"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/logging"
 )
 
 const defaultColorCode = "\033[0m"
@@ -68,24 +73,51 @@ func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]c
 		Config:   config,
 		Manifest: manifest,
 	}
-	var follow bool
+	var follow, previous bool
+	var level string
+	var index int
 	logsCmd := &cobra.Command{
-		Use:     "logs [name]",
-		Short:   "Display components logs",
-		Example: "tmctl logs",
+		Use:   "logs [name][--previous [--index <n>]]",
+		Short: "Display components logs",
+		Example: `tmctl logs
+
+tmctl logs foo-awss3source --previous`,
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return completion.ListAll(o.Manifest), cobra.ShellCompDirectiveNoFileComp
+			return completion.Components(o.Manifest, completion.ListAll(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cobra.CheckErr(o.Manifest.Read())
-			return o.logs(args, follow)
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			if level != "" {
+				if err := logging.Validate(level); err != nil {
+					return err
+				}
+			}
+			if previous {
+				if len(args) != 1 {
+					return fmt.Errorf("--previous requires the component name")
+				}
+				return o.previous(args[0], index)
+			}
+			if cmd.Flags().Changed("index") {
+				return fmt.Errorf("--index requires --previous")
+			}
+			return o.logs(args, follow, level)
 		},
 	}
 	logsCmd.Flags().BoolVarP(&follow, "follow", "f", false, "Follow logs output")
+	logsCmd.Flags().BoolVar(&previous, "previous", false, "Show the logs of the exited container saved before it was recreated")
+	logsCmd.Flags().IntVar(&index, "index", 0, "Saved logs to show with --previous, 0 is the most recent")
+	logsCmd.MarkFlagsMutuallyExclusive("previous", "follow")
+	logsCmd.Flags().StringVar(&level, "level", "", "Show log entries of this severity or higher: debug, info, warn or error")
+	cobra.CheckErr(logsCmd.RegisterFlagCompletionFunc("level", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return logging.Levels, cobra.ShellCompDirectiveNoFileComp
+	}))
 	return logsCmd
 }
 
-func (o *CliOptions) logs(filter []string, follow bool) error {
+func (o *CliOptions) logs(filter []string, follow bool, level string) error {
 	cancel := make(chan os.Signal, 1)
 	signal.Notify(cancel, os.Interrupt, syscall.SIGTERM)
 	defer close(cancel)
@@ -96,7 +128,8 @@ func (o *CliOptions) logs(filter []string, follow bool) error {
 	for _, object := range o.Manifest.Objects {
 		component, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
 		if err != nil {
-			return fmt.Errorf("creating component interface: %w", err)
+			log.Printf("WARNING! Skipping %s %q: %v", object.Kind, object.Metadata.Name, err)
+			continue
 		}
 		if component == nil {
 			continue
@@ -117,6 +150,9 @@ func (o *CliOptions) logs(filter []string, follow bool) error {
 		if !ok {
 			continue
 		}
+		if effective := logging.Effective(object); level != "" && !logging.Enabled(effective, level) {
+			log.Printf("%s logs at %s level, use \"tmctl set log-level %s %s\" to see more", component.GetName(), effective, component.GetName(), level)
+		}
 		since := time.Now()
 		if !follow {
 			since = since.Add(-defaultLogPeriod * time.Hour)
@@ -138,10 +174,10 @@ func (o *CliOptions) logs(filter []string, follow bool) error {
 		colorIndex++
 		if follow {
 			log.Printf("%sListening %s%s", colorCode, component.GetName(), defaultColorCode)
-			go readLogs(logs, cancel, colorCode)
+			go readLogs(logs, cancel, colorCode, level)
 		} else {
 			fmt.Printf("---------------\n%s\n---------------\n", component.GetName())
-			readLogs(logs, cancel, defaultColorCode)
+			readLogs(logs, cancel, defaultColorCode, level)
 		}
 	}
 	if follow {
@@ -150,7 +186,36 @@ func (o *CliOptions) logs(filter []string, follow bool) error {
 	return nil
 }
 
-func readLogs(logs io.ReadCloser, calncel chan os.Signal, colorCode string) {
+// previous prints the saved output of the exited component container.
+func (o *CliOptions) previous(component string, index int) error {
+	container := component
+	for _, object := range o.Manifest.Objects {
+		if object.Metadata.Name == component && object.Kind == tmbroker.BrokerKind {
+			container += "-broker"
+		}
+	}
+	dir := filepath.Join(o.Config.ConfigHome, o.Config.Context, crashlog.Dir)
+	capture, err := crashlog.Get(dir, container, index)
+	if errors.Is(err, crashlog.ErrNotFound) {
+		captures, _ := crashlog.List(dir, container)
+		if len(captures) == 0 {
+			return fmt.Errorf("%q has no previous logs", component)
+		}
+		return fmt.Errorf("%q has %d previous logs, index must be between 0 and %d", component, len(captures), len(captures)-1)
+	}
+	if err != nil {
+		return fmt.Errorf("previous logs: %w", err)
+	}
+	data, err := os.ReadFile(capture.Path)
+	if err != nil {
+		return fmt.Errorf("previous logs: %w", err)
+	}
+	fmt.Printf("---------------\n%s (exited %s)\n---------------\n", component, capture.Exited.Local().Format(time.RFC3339))
+	fmt.Print(string(data))
+	return nil
+}
+
+func readLogs(logs io.ReadCloser, calncel chan os.Signal, colorCode, level string) {
 	defer logs.Close()
 	scanner := bufio.NewScanner(logs)
 	for scanner.Scan() {
@@ -162,6 +227,11 @@ func readLogs(logs io.ReadCloser, calncel chan os.Signal, colorCode string) {
 			if len(log) > 8 {
 				log = log[8:]
 			}
+			if level != "" {
+				if l, ok := logging.LineLevel(log); ok && !logging.Enabled(level, l) {
+					continue
+				}
+			}
 			fmt.Printf("%s%s%s\n", colorCode, string(log), defaultColorCode)
 		}
 	}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package migrate
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest) *cobra.Command {
+	o := &CliOptions{
+		Config:   config,
+		Manifest: manifest,
+	}
+	var dryRun bool
+	migrateCmd := &cobra.Command{
+		Use:   "migrate [--dry-run]",
+		Short: "Rewrite the manifest created by the older tmctl version",
+		Long: `Rewrite the manifest created by the older tmctl version.
+
+Manifests are migrated in memory every time they are read, the migrated
+objects are written back by the first command that changes the manifest.
+The original file is kept next to the manifest with the .v<version>.bak suffix.`,
+		Example: "tmctl migrate --dry-run",
+		Args:    cobra.NoArgs,
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			return []string{}, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.migrate(dryRun)
+		},
+	}
+	migrateCmd.Flags().BoolVar(&dryRun, "dry-run", false, "Print the changes without writing the manifest")
+	return migrateCmd
+}
+
+func (o *CliOptions) migrate(dryRun bool) error {
+	changes, version, err := manifest.Plan(o.Manifest.Path)
+	if err != nil {
+		return fmt.Errorf("broker %q manifest: %w", o.Config.Context, err)
+	}
+	if version == manifest.CurrentVersion {
+		fmt.Printf("Manifest is up to date (version %d)\n", version)
+		return nil
+	}
+	for _, change := range changes {
+		fmt.Println(change)
+	}
+	if dryRun {
+		fmt.Printf("Manifest version %d would be migrated to %d, %d object(s) changed\n", version, manifest.CurrentVersion, len(changes))
+		return nil
+	}
+	if err := o.Manifest.Write(); err != nil {
+		return fmt.Errorf("writing manifest: %w", err)
+	}
+	fmt.Printf("Manifest migrated from version %d to %d, %d object(s) changed\n", version, manifest.CurrentVersion, len(changes))
+	if o.Manifest.Backup != "" {
+		fmt.Printf("Original manifest is saved in %s\n", o.Manifest.Backup)
+	}
+	return nil
+}
@@ -0,0 +1,119 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package pin
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/cmd/start"
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD:      crd,
+		Config:   config,
+		Manifest: manifest,
+	}
+	var remove bool
+	pinCmd := &cobra.Command{
+		Use:   "pin <component> [version]",
+		Short: "Pin the component adapter image version and restart its container",
+		Long: `Pin the component adapter image version and restart its container.
+Pinned components keep running the pinned version when the context version changes,
+"--remove" unpins the component so that it follows the context version again.`,
+		Example: `tmctl pin foo-httppollersource v1.24.3
+tmctl pin foo-httppollersource --remove`,
+		Args: cobra.RangeArgs(1, 2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) == 0 {
+				return completion.Components(o.Manifest, completion.ListSources(o.Manifest), completion.ListTargets(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			var version string
+			switch {
+			case remove && len(args) == 2:
+				return fmt.Errorf("--remove does not accept the version")
+			case !remove && len(args) == 1:
+				return fmt.Errorf("version is required, use --remove to unpin the component")
+			case !remove:
+				version = args[1]
+			}
+			if err := docker.CheckDaemon(); err != nil {
+				return err
+			}
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.pin(args[0], version)
+		},
+	}
+	pinCmd.Flags().BoolVar(&remove, "remove", false, "Remove the pinned version, the component follows the context version")
+	return pinCmd
+}
+
+// pin records the adapter version in the component annotation
+// and restarts the component with the new image.
+// Empty version removes the pin.
+func (o *CliOptions) pin(name, version string) error {
+	c, err := components.GetObject(name, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return fmt.Errorf("%q: %w", name, err)
+	}
+	if c == nil {
+		return fmt.Errorf("component %q not found", name)
+	}
+	current, ok := c.(triggermesh.Versioned)
+	if !ok {
+		return fmt.Errorf("%q does not run TriggerMesh adapter image", name)
+	}
+	if err := o.Manifest.Annotate(name, c.GetKind(), triggermesh.AdapterVersionAnnotation, version); err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	if version == "" {
+		version = o.Config.Triggermesh.ComponentsVersion
+		log.Printf("Unpinned %s, context version is %s", name, version)
+	}
+	if version == current.GetVersion() {
+		return nil
+	}
+	log.Printf("Restarting %s with %s adapter version", name, version)
+	s := &start.CliOptions{
+		Config:     o.Config,
+		Manifest:   o.Manifest,
+		CRD:        o.CRD,
+		Components: []string{name},
+	}
+	return s.Start()
+}
@@ -0,0 +1,126 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package record
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/wiretap"
+)
+
+const megabyte = 1024 * 1024
+
+type CliOptions struct {
+	Config *config.Config
+
+	Output  string
+	MaxSize int64
+}
+
+func NewCmd(config *config.Config) *cobra.Command {
+	o := &CliOptions{
+		Config: config,
+	}
+	recordCmd := &cobra.Command{
+		Use:   "record [broker] --output <file> [--max-size <MB>]",
+		Short: "Record all events received by the broker",
+		Long: `Record all events received by the broker.
+Events are written as CloudEvents JSON lines with the "recordedat" extension
+holding the receive time. The catch-all trigger is removed when recording stops.`,
+		Example: "tmctl record --output events.jsonl --max-size 100",
+		Args:    cobra.RangeArgs(0, 1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			return []string{}, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if len(args) != 0 {
+				o.Config.Context = args[0]
+			}
+			if o.MaxSize < 0 {
+				return fmt.Errorf("--max-size must not be negative")
+			}
+			return o.record()
+		},
+	}
+	recordCmd.Flags().StringVarP(&o.Output, "output", "o", "", "File to write the events to")
+	recordCmd.Flags().Int64Var(&o.MaxSize, "max-size", 0, "Rotate the output file when it reaches the size in megabytes, 0 disables rotation")
+	cobra.CheckErr(recordCmd.MarkFlagRequired("output"))
+	cobra.CheckErr(recordCmd.RegisterFlagCompletionFunc("max-size", cobra.NoFileCompletions))
+	return recordCmd
+}
+
+func (o *CliOptions) record() error {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer close(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	recorder, err := wiretap.NewRecorder(o.Output, o.MaxSize*megabyte)
+	if err != nil {
+		return err
+	}
+	defer recorder.Close()
+
+	w, err := wiretap.New(o.Config.Context, o.Config.ConfigHome)
+	if err != nil {
+		return fmt.Errorf("wiretap: %w", err)
+	}
+	w.Name = "recorder"
+
+	events, err := w.Listen(ctx)
+	if err != nil {
+		return fmt.Errorf("listener: %w", err)
+	}
+	// trigger is removed on any exit path, including interruption and errors.
+	defer func() {
+		if err := w.Cleanup(context.Background()); err != nil {
+			log.Printf("Cleanup: %v", err)
+		}
+	}()
+	if err := w.CreateTrigger(); err != nil {
+		return fmt.Errorf("create trigger: %w", err)
+	}
+
+	log.Printf("Recording %s events to %s", o.Config.Context, o.Output)
+	recorded := 0
+	for {
+		select {
+		case <-c:
+			log.Printf("Recorded %d events. Cleaning up", recorded)
+			return nil
+		case event, ok := <-events:
+			if !ok {
+				return fmt.Errorf("listener stopped")
+			}
+			if err := recorder.Record(event); err != nil {
+				return fmt.Errorf("record event: %w", err)
+			}
+			recorded++
+			fmt.Printf("%s\t%s\t%s\n", event.Time().Format("15:04:05"), event.Type(), event.ID())
+		}
+	}
+}
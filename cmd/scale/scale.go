@@ -0,0 +1,134 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package scale
+
+import (
+	"context"
+	"fmt"
+	"strconv"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/replicas"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD:      crd,
+		Config:   config,
+		Manifest: manifest,
+	}
+	var count int
+	scaleCmd := &cobra.Command{
+		Use:   "scale <target> --replicas <count>",
+		Short: "Run multiple replicas of the target behind the load balancer",
+		Long: `Run multiple replicas of the target container.
+Events are delivered to the replicas in round-robin order.`,
+		Example: "tmctl scale foo-target --replicas 3",
+		Args:    cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return completion.Components(o.Manifest, completion.ListTargets(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			if count < 1 {
+				return fmt.Errorf("number of replicas must be positive")
+			}
+			return o.scale(args[0], count)
+		},
+	}
+	scaleCmd.Flags().IntVar(&count, "replicas", 1, "Number of target replicas")
+	return scaleCmd
+}
+
+func (o *CliOptions) scale(name string, count int) error {
+	ctx := context.Background()
+	c, err := components.GetObject(name, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return fmt.Errorf("%q: %w", name, err)
+	}
+	if c == nil {
+		return fmt.Errorf("component %q not found", name)
+	}
+	runnable, ok := c.(triggermesh.Runnable)
+	if !ok {
+		return fmt.Errorf("%q is not runnable", name)
+	}
+	if _, ok := c.(triggermesh.Consumer); !ok {
+		return fmt.Errorf("%q is not an event consumer", name)
+	}
+	if s, ok := c.(*service.Service); ok && s.IsAdopted() {
+		return fmt.Errorf("adopted container %q cannot be scaled", name)
+	}
+
+	previous := 1
+	for _, object := range o.Manifest.Objects {
+		if object.Metadata.Name == name && object.Kind == c.GetKind() {
+			previous = replicas.Count(object)
+		}
+	}
+
+	secrets := make(map[string]string)
+	if parent, ok := c.(triggermesh.Parent); ok {
+		if _, secrets, err = components.ProcessSecrets(parent, o.Manifest); err != nil {
+			return fmt.Errorf("processing secrets: %w", err)
+		}
+	}
+	// existing container is returned as is, we need its parameters
+	// to start replicas.
+	primary, err := runnable.Start(ctx, secrets, false)
+	if err != nil {
+		return fmt.Errorf("starting %q: %w", name, err)
+	}
+
+	log.Printf("Scaling %q to %d replica(s)", name, count)
+	port, err := replicas.Scale(ctx, o.Config.ConfigHome, o.Config.Context, primary, previous, count, false)
+	if err != nil {
+		return err
+	}
+	if err := replicas.RouteTriggers(name, port, o.Config.Context, o.Config.ConfigHome); err != nil {
+		return err
+	}
+
+	value := strconv.Itoa(count)
+	if count == 1 {
+		value = ""
+	}
+	if err := o.Manifest.Annotate(name, c.GetKind(), triggermesh.ReplicasAnnotation, value); err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	return nil
+}
@@ -0,0 +1,149 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/schema"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest) *cobra.Command {
+	o := &CliOptions{
+		Config:   config,
+		Manifest: manifest,
+	}
+	schemaCmd := &cobra.Command{
+		Use:   "schema [set|get|list|delete]",
+		Short: "Manage event payload schemas",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.HelpFunc()(cmd, args)
+		},
+	}
+	schemaCmd.AddCommand(o.setCmd())
+	schemaCmd.AddCommand(o.getCmd())
+	schemaCmd.AddCommand(o.listCmd())
+	schemaCmd.AddCommand(o.deleteCmd())
+	return schemaCmd
+}
+
+func (o *CliOptions) registry() *schema.Registry {
+	return schema.New(o.Config.ConfigHome, o.Config.Context)
+}
+
+func (o *CliOptions) eventTypesCompletion(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completion.ListFilteredEventTypes(o.Config.Context, o.Config.ConfigHome, o.Manifest), cobra.ShellCompDirectiveNoFileComp
+}
+
+func (o *CliOptions) setCmd() *cobra.Command {
+	var file string
+	setCmd := &cobra.Command{
+		Use:               "set <event-type> --from <schema.json>",
+		Short:             "Register JSON schema of the event type payload",
+		Example:           "tmctl schema set orders.transformed --from schema.json",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: o.eventTypesCompletion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if file == "" {
+				return fmt.Errorf("schema file is required")
+			}
+			data, err := os.ReadFile(file)
+			if err != nil {
+				return fmt.Errorf("reading schema: %w", err)
+			}
+			if err := o.registry().Set(args[0], data); err != nil {
+				return err
+			}
+			return config.AddRecentSpec(o.Config.Context, file)
+		},
+	}
+	setCmd.Flags().StringVar(&file, "from", "", "JSON schema file")
+	cobra.CheckErr(setCmd.RegisterFlagCompletionFunc("from", func(cmd *cobra.Command, args []string, toComplete string) ([]string, cobra.ShellCompDirective) {
+		return completion.ListSpecFiles(toComplete, o.Config.RecentSpecs[o.Config.Context], "json"),
+			cobra.ShellCompDirectiveNoSpace | cobra.ShellCompDirectiveNoFileComp
+	}))
+	return setCmd
+}
+
+func (o *CliOptions) getCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "get <event-type>",
+		Short:             "Print JSON schema of the event type payload",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: o.registeredCompletion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			data, err := o.registry().Get(args[0])
+			if err != nil {
+				return fmt.Errorf("%q: %w", args[0], err)
+			}
+			fmt.Println(string(data))
+			return nil
+		},
+	}
+}
+
+func (o *CliOptions) listCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "list",
+		Short: "List event types with registered schemas",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			list, err := o.registry().List()
+			if err != nil {
+				return err
+			}
+			for _, eventType := range list {
+				fmt.Println(eventType)
+			}
+			return nil
+		},
+	}
+}
+
+func (o *CliOptions) deleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "delete <event-type>",
+		Short:             "Remove the event type schema",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: o.registeredCompletion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.registry().Delete(args[0])
+		},
+	}
+}
+
+func (o *CliOptions) registeredCompletion(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	list, _ := o.registry().List()
+	return list, cobra.ShellCompDirectiveNoFileComp
+}
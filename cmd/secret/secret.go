@@ -0,0 +1,133 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secret
+
+import (
+	"fmt"
+	"os"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/secrets"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest) *cobra.Command {
+	o := &CliOptions{
+		Config:   config,
+		Manifest: manifest,
+	}
+	secretCmd := &cobra.Command{
+		Use:   "secret",
+		Short: "Manage the secret files written for the containers",
+	}
+	secretCmd.AddCommand(o.newListCmd())
+	secretCmd.AddCommand(o.newPruneCmd())
+	return secretCmd
+}
+
+func (o *CliOptions) newListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Short:   "List the secret files of the current context",
+		Example: "tmctl secret list",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.list()
+		},
+	}
+}
+
+func (o *CliOptions) newPruneCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "prune",
+		Short:   "Remove the secret files of the deleted components",
+		Example: "tmctl secret prune",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.prune()
+		},
+	}
+}
+
+func (o *CliOptions) list() error {
+	store := secrets.Context(o.Config.ConfigHome, o.Config.Context)
+	entries, err := store.List()
+	if err != nil {
+		return fmt.Errorf("listing secrets: %w", err)
+	}
+	if len(entries) == 0 {
+		fmt.Println("No secret files")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
+	fmt.Fprintln(w, "Component\tField\tFile\tStatus")
+	for _, entry := range entries {
+		status := "in use"
+		if !o.exists(entry.Component) {
+			status = "orphaned"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%s\n", entry.Component, entry.Field, entry.Name, status)
+	}
+	return w.Flush()
+}
+
+func (o *CliOptions) prune() error {
+	store := secrets.Context(o.Config.ConfigHome, o.Config.Context)
+	orphans, err := store.Orphans(o.exists)
+	if err != nil {
+		return fmt.Errorf("listing secrets: %w", err)
+	}
+	removed := make(map[string]bool)
+	for _, orphan := range orphans {
+		if removed[orphan.Component] {
+			continue
+		}
+		log.Printf("Removing secret files of %q", orphan.Component)
+		if err := store.Remove(orphan.Component); err != nil {
+			return fmt.Errorf("removing %q secrets: %w", orphan.Component, err)
+		}
+		removed[orphan.Component] = true
+	}
+	if len(removed) == 0 {
+		log.Println("No orphaned secret files")
+	}
+	return nil
+}
+
+func (o *CliOptions) exists(component string) bool {
+	for _, object := range o.Manifest.Objects {
+		if object.Metadata.Name == component {
+			return true
+		}
+	}
+	return false
+}
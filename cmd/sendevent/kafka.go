@@ -0,0 +1,144 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sendevent
+
+import (
+	"context"
+	"fmt"
+	"os"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	ceclient "github.com/cloudevents/sdk-go/v2/client"
+	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/triggermesh/tmctl/pkg/kafka"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+)
+
+const (
+	protocolHTTP  = "http"
+	protocolKafka = "kafka"
+
+	kafkaSourceKind = "KafkaSource"
+)
+
+type kafkaOptions struct {
+	kafka.Producer
+
+	Via    string
+	CAFile string
+}
+
+func (o *CliOptions) kafkaFlags(cmd *cobra.Command) {
+	cmd.Flags().StringSliceVar(&o.Kafka.BootstrapServers, "bootstrap-servers", []string{}, "Kafka bootstrap servers")
+	cmd.Flags().StringVar(&o.Kafka.Topic, "topic", "", "Kafka topic")
+	cmd.Flags().StringVar(&o.Kafka.SASLMechanism, "sasl-mechanism", "", "Kafka SASL mechanism")
+	cmd.Flags().StringVar(&o.Kafka.SASLUser, "sasl-user", "", "Kafka SASL username")
+	cmd.Flags().StringVar(&o.Kafka.SASLPassword, "sasl-password", "", "Kafka SASL password")
+	cmd.Flags().BoolVar(&o.Kafka.TLS, "tls", false, "Enable TLS connection to Kafka")
+	cmd.Flags().StringVar(&o.Kafka.CAFile, "tls-ca-file", "", "Kafka certificate authority file")
+	cmd.Flags().StringVar(&o.Kafka.Via, "via", "", "Kafka source to take the connection parameters from")
+	cobra.CheckErr(cmd.RegisterFlagCompletionFunc("via", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		var sources []string
+		for _, object := range o.Manifest.Objects {
+			if object.Kind == kafkaSourceKind {
+				sources = append(sources, object.Metadata.Name)
+			}
+		}
+		return sources, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(cmd.RegisterFlagCompletionFunc("sasl-mechanism", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{"PLAIN", "SCRAM-SHA-256", "SCRAM-SHA-512"}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	for _, flag := range []string{"bootstrap-servers", "topic", "sasl-user", "sasl-password"} {
+		cobra.CheckErr(cmd.RegisterFlagCompletionFunc(flag, cobra.NoFileCompletions))
+	}
+}
+
+// kafkaDefaults fills the connection parameters that are not set
+// by the flags from the Kafka source spec.
+func (o *CliOptions) kafkaDefaults(source string) error {
+	component, err := components.GetObject(source, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return fmt.Errorf("%q: %w", source, err)
+	}
+	if component.GetKind() != kafkaSourceKind {
+		return fmt.Errorf("%q is not a Kafka source", source)
+	}
+	spec := component.GetSpec()
+	if len(o.Kafka.BootstrapServers) == 0 {
+		servers, _, _ := unstructured.NestedSlice(spec, "bootstrapServers")
+		for _, server := range servers {
+			if s, ok := server.(string); ok {
+				o.Kafka.BootstrapServers = append(o.Kafka.BootstrapServers, s)
+			}
+		}
+	}
+	if o.Kafka.Topic == "" {
+		o.Kafka.Topic, _, _ = unstructured.NestedString(spec, "topic")
+	}
+	if o.Kafka.SASLMechanism == "" {
+		if enabled, _, _ := unstructured.NestedBool(spec, "auth", "saslEnable"); enabled {
+			o.Kafka.SASLMechanism, _, _ = unstructured.NestedString(spec, "auth", "securityMechanism")
+		}
+	}
+	if o.Kafka.SASLUser == "" {
+		o.Kafka.SASLUser, _, _ = unstructured.NestedString(spec, "auth", "username")
+	}
+	if !o.Kafka.TLS {
+		o.Kafka.TLS, _, _ = unstructured.NestedBool(spec, "auth", "tlsEnable")
+	}
+
+	parent, ok := component.(triggermesh.Parent)
+	if !ok {
+		return nil
+	}
+	_, secrets, err := components.ProcessSecrets(parent, o.Manifest)
+	if err != nil {
+		return fmt.Errorf("%q secrets: %w", source, err)
+	}
+	if o.Kafka.SASLPassword == "" {
+		o.Kafka.SASLPassword = secrets["password"]
+	}
+	if o.Kafka.CAFile == "" {
+		o.Kafka.CA = secrets["ca"]
+	}
+	return nil
+}
+
+func (o *CliOptions) sendKafka(ctx context.Context, event cloudevents.Event) error {
+	if o.Kafka.CAFile != "" {
+		ca, err := os.ReadFile(o.Kafka.CAFile)
+		if err != nil {
+			return fmt.Errorf("CA file %q: %w", o.Kafka.CAFile, err)
+		}
+		o.Kafka.CA = string(ca)
+	}
+	event = ceclient.DefaultIDToUUIDIfNotSet(ctx, event)
+	event = ceclient.DefaultTimeToNowIfNotSet(ctx, event)
+
+	fmt.Printf("Destination: kafka(%s/%s)\n", o.Kafka.BootstrapServers, o.Kafka.Topic)
+	fmt.Printf("Request:\n------\n%s------", event.String())
+	response := "\033[92mOK\033[39m"
+	if err := o.Kafka.Produce(ctx, o.Config.Context+"-kafka-producer", event); err != nil {
+		response = fmt.Sprintf("\u001b[31mError\033[39m(%s)", err.Error())
+	}
+	fmt.Printf("\nResponse: %s\n", response)
+	return nil
+}
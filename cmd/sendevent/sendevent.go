@@ -19,19 +19,29 @@ package sendevent
 import (
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
+	"net/http"
 	"os"
 	"strings"
+	"time"
 
 	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/protocol"
+	cehttp "github.com/cloudevents/sdk-go/v2/protocol/http"
 	"github.com/spf13/cobra"
 
 	"github.com/triggermesh/tmctl/pkg/completion"
 	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/eventtemplate"
 	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/schema"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/ingest"
+	"github.com/triggermesh/tmctl/pkg/urls"
 )
 
 const (
@@ -43,6 +53,13 @@ type CliOptions struct {
 	Config   *config.Config
 	Manifest *manifest.Manifest
 	CRD      map[string]crd.CRD
+
+	Validate    bool
+	Protocol    string
+	ContentMode string
+	Count       int
+	Seed        int64
+	Kafka       kafkaOptions
 }
 
 func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
@@ -53,52 +70,127 @@ func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]c
 	}
 	var eventType, target, file string
 	sendCmd := &cobra.Command{
-		Use:     "send-event [--eventType <type>][--target <name>][--file <filename>] <data>",
-		Short:   "Send CloudEvent to the target",
-		Example: "tmctl send-event '{\"hello\":\"world\"}'",
+		Use:   "send-event [--eventType <type>][--target <name>][--file <filename>][--protocol http|kafka] <data>",
+		Short: "Send CloudEvent to the target",
+		Example: `tmctl send-event '{"hello":"world"}'
+
+tmctl send-event --protocol kafka \
+	--bootstrap-servers localhost:9092 \
+	--topic orders '{"hello":"world"}'
+
+tmctl send-event --target legacy-service --content-mode structured '{"hello":"world"}'
+
+tmctl send-event --via foo-kafkasource '{"hello":"world"}'
+
+tmctl send-event --count 10 --eventType 'order.{{randChoice "created" "paid"}}' \
+	'{"id":"{{uuid}}","customer":"{{name}}","email":"{{email}}","amount":{{randInt 1 100}},"time":"{{now}}"}'`,
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return []string{"--target", "--eventType", "--file"}, cobra.ShellCompDirectiveNoFileComp
+			return []string{"--target", "--eventType", "--file", "--validate", "--protocol", "--via", "--count", "--seed", "--content-mode"}, cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
-			cobra.CheckErr(o.Manifest.Read())
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			if o.Kafka.Via != "" {
+				o.Protocol = protocolKafka
+				if err := o.kafkaDefaults(o.Kafka.Via); err != nil {
+					return err
+				}
+			}
+			if o.Protocol != protocolHTTP && o.Protocol != protocolKafka {
+				return fmt.Errorf("unsupported protocol %q", o.Protocol)
+			}
+			if err := tmbroker.ValidateContentMode(o.ContentMode); err != nil {
+				return err
+			}
+			if o.Protocol == protocolKafka && cmd.Flags().Changed("content-mode") {
+				return fmt.Errorf("--content-mode requires the http protocol")
+			}
 			if target == "" {
 				target = o.Config.Context
 			}
 
+			if o.Count < 1 {
+				return fmt.Errorf("events count must be positive")
+			}
+			if !cmd.Flags().Changed("seed") {
+				o.Seed = time.Now().UnixNano()
+			}
+
+			payloads := []string{strings.Join(args, " ")}
 			if file != "" {
 				events, err := readEventsFromFile(file)
 				if err != nil {
 					return fmt.Errorf("reading events from file: %w", err)
 				}
-
-				for _, event := range events {
-					err := o.send(eventType, target, event)
-					if err != nil {
-						fmt.Printf("Failed to send event: %v\n", err)
-					}
+				payloads = events
+			}
+			events, err := o.render(eventType, payloads)
+			if err != nil {
+				return err
+			}
+			if file == "" && len(events) == 1 {
+				return o.send(events[0].eventType, target, events[0].data)
+			}
+			for _, event := range events {
+				err := o.send(event.eventType, target, event.data)
+				if err != nil {
+					fmt.Printf("Failed to send event: %v\n", err)
 				}
-
-				return nil
 			}
-
-			return o.send(eventType, target, strings.Join(args, " "))
+			return nil
 		},
 	}
 	sendCmd.Flags().StringVar(&target, "target", "", "Component to send the event to. Default is the broker")
 	sendCmd.Flags().StringVar(&eventType, "eventType", defaultEventType, "CloudEvent Type attribute")
 	sendCmd.Flags().StringVarP(&file, "file", "f", "", "File containing a list of events")
+	sendCmd.Flags().IntVar(&o.Count, "count", 1, "Number of events to send, templates are evaluated for every event")
+	sendCmd.Flags().Int64Var(&o.Seed, "seed", 0, "Random generators seed for reproducible templates")
+	sendCmd.Flags().BoolVar(&o.Validate, "validate", false, "Validate event payload against the registered schema")
+	sendCmd.Flags().StringVar(&o.Protocol, "protocol", protocolHTTP, "Event delivery protocol, \"http\" or \"kafka\"")
+	sendCmd.Flags().StringVar(&o.ContentMode, "content-mode", tmbroker.ContentModeBinary, "CloudEvents content mode of the request, \"binary\" or \"structured\"")
+	o.kafkaFlags(sendCmd)
 
 	cobra.CheckErr(sendCmd.RegisterFlagCompletionFunc("eventType", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 		return completion.ListFilteredEventTypes(o.Config.Context, o.Config.ConfigHome, o.Manifest), cobra.ShellCompDirectiveNoFileComp
 	}))
 	cobra.CheckErr(sendCmd.RegisterFlagCompletionFunc("target", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-		return completion.ListTargets(o.Manifest), cobra.ShellCompDirectiveNoFileComp
+		return completion.Components(o.Manifest, completion.ListTargets(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(sendCmd.RegisterFlagCompletionFunc("protocol", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{protocolHTTP, protocolKafka}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	cobra.CheckErr(sendCmd.RegisterFlagCompletionFunc("content-mode", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return tmbroker.ContentModes, cobra.ShellCompDirectiveNoFileComp
 	}))
 	return sendCmd
 }
 
 func (o *CliOptions) send(eventType, target, data string) error {
 	ctx := context.Background()
+	if o.Validate {
+		err := schema.New(o.Config.ConfigHome, o.Config.Context).Validate(eventType, []byte(data))
+		switch {
+		case errors.Is(err, schema.ErrNotFound):
+			fmt.Printf("No schema registered for %q, skipping validation\n", eventType)
+		case err != nil:
+			return fmt.Errorf("%q schema violation: %w", eventType, err)
+		}
+	}
+	event := cloudevents.NewEvent()
+	event.SetSource(defaultEventSource)
+	event.SetType(eventType)
+	contentType := cloudevents.TextPlain
+	if json.Valid([]byte(data)) {
+		contentType = cloudevents.ApplicationJSON
+	}
+	if err := event.SetData(contentType, []byte(data)); err != nil {
+		return fmt.Errorf("event data: %w", err)
+	}
+	if o.Protocol == protocolKafka {
+		return o.sendKafka(ctx, event)
+	}
+
 	component, err := components.GetObject(target, o.Config, o.Manifest, o.CRD)
 	if err != nil {
 		return fmt.Errorf("destination target: %w", err)
@@ -107,7 +199,14 @@ func (o *CliOptions) send(eventType, target, data string) error {
 	if !ok {
 		return fmt.Errorf("%q is not an event consumer", target)
 	}
-	port, err := consumer.GetPort(ctx)
+	// the broker receives the events through its ingest proxy, if any
+	_, broker := component.(*tmbroker.Broker)
+	var endpoint string
+	if broker {
+		endpoint, err = o.Config.URLs().BrokerIngestURL(ctx, ingest.Entrypoint{Broker: target, Component: consumer}, urls.Host)
+	} else {
+		endpoint, err = o.Config.URLs().ComponentURL(ctx, consumer, urls.Host)
+	}
 	if err != nil {
 		return fmt.Errorf("target port: %w", err)
 	}
@@ -116,29 +215,85 @@ func (o *CliOptions) send(eventType, target, data string) error {
 	if err != nil {
 		return fmt.Errorf("cloudevents client, %w", err)
 	}
-	event := cloudevents.NewEvent()
-	event.SetSource(defaultEventSource)
-	event.SetType(eventType)
-	contentType := cloudevents.TextPlain
-	if json.Valid([]byte(data)) {
-		contentType = cloudevents.ApplicationJSON
-	}
-	if err := event.SetData(contentType, []byte(data)); err != nil {
-		return fmt.Errorf("event data: %w", err)
+
+	ctx = cloudevents.ContextWithTarget(ctx, endpoint)
+	if o.ContentMode == tmbroker.ContentModeStructured {
+		ctx = cloudevents.WithEncodingStructured(ctx)
 	}
 
-	brokerEndpoint := fmt.Sprintf("http://localhost:%s", port)
-	fmt.Printf("Destination: %s(%s)\n", target, brokerEndpoint)
+	fmt.Printf("Destination: %s(%s)\n", target, endpoint)
 	fmt.Printf("Request:\n------\n%s------", event.String())
-	result := c.Send(cloudevents.ContextWithTarget(ctx, brokerEndpoint), event)
+	// request-reply components, such as synchronizer, respond with the correlated event
+	reply, result := c.Request(ctx, event)
 	response := "\033[92mOK\033[39m"
 	if !cloudevents.IsACK(result) {
 		response = fmt.Sprintf("\u001b[31mError\033[39m(%s)", result.Error())
+		if limit := o.limitExceeded(target, result); broker && limit != "" {
+			response = fmt.Sprintf("\u001b[31mRejected\033[39m(%s)", limit)
+		}
 	}
 	fmt.Printf("\nResponse: %s\n", response)
+	if reply != nil {
+		fmt.Printf("Reply:\n------\n%s------\n", reply.String())
+	}
 	return nil
 }
 
+// limitExceeded explains the rejection of the event that exceeds
+// the ingest limits of the broker, if the response tells so.
+func (o *CliOptions) limitExceeded(broker string, result protocol.Result) string {
+	var response *cehttp.Result
+	if !cloudevents.ResultAs(result, &response) {
+		return ""
+	}
+	limits := o.Config.Triggermesh.Broker.Limits[broker]
+	switch {
+	case response.StatusCode == http.StatusRequestEntityTooLarge && limits.MaxPayloadSize != "":
+		return fmt.Sprintf("%d, event payload exceeds the broker limit of %s", response.StatusCode, limits.MaxPayloadSize)
+	case response.StatusCode == http.StatusTooManyRequests && limits.RateLimit != "":
+		return fmt.Sprintf("%d, event rate exceeds the broker limit of %s", response.StatusCode, limits.RateLimit)
+	}
+	return ""
+}
+
+type templatedEvent struct {
+	eventType string
+	data      string
+}
+
+// render evaluates the event type and payload templates for every event
+// before any of them is sent, so that template errors abort the command.
+func (o *CliOptions) render(eventType string, payloads []string) ([]templatedEvent, error) {
+	generator := eventtemplate.New(o.Seed)
+	typeTemplate, err := generator.Parse("eventType", eventType)
+	if err != nil {
+		return nil, err
+	}
+	var dataTemplates []*eventtemplate.Template
+	for _, payload := range payloads {
+		t, err := generator.Parse("payload", payload)
+		if err != nil {
+			return nil, err
+		}
+		dataTemplates = append(dataTemplates, t)
+	}
+	var result []templatedEvent
+	for i := 0; i < o.Count; i++ {
+		for _, dataTemplate := range dataTemplates {
+			eventType, err := typeTemplate.Execute()
+			if err != nil {
+				return nil, err
+			}
+			data, err := dataTemplate.Execute()
+			if err != nil {
+				return nil, err
+			}
+			result = append(result, templatedEvent{eventType: eventType, data: data})
+		}
+	}
+	return result, nil
+}
+
 func readEventsFromFile(file string) ([]string, error) {
 	var rawEvents []json.RawMessage
 
@@ -0,0 +1,116 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package set
+
+import (
+	"fmt"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/cmd/start"
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/logging"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD:      crd,
+		Config:   config,
+		Manifest: manifest,
+	}
+	setCmd := &cobra.Command{
+		Use:   "set [log-level]",
+		Short: "Update component runtime settings",
+		Run: func(cmd *cobra.Command, args []string) {
+			cmd.HelpFunc()(cmd, args)
+		},
+	}
+	setCmd.AddCommand(o.logLevelCmd())
+	return setCmd
+}
+
+func (o *CliOptions) logLevelCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "log-level <component> <level>",
+		Short:   "Set the component adapter logging level and restart its container",
+		Example: "tmctl set log-level foo-httppollersource debug",
+		Args:    cobra.ExactArgs(2),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			switch len(args) {
+			case 0:
+				return completion.Components(o.Manifest, completion.ListSources(o.Manifest), completion.ListTargets(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+			case 1:
+				return logging.Levels, cobra.ShellCompDirectiveNoFileComp
+			}
+			return nil, cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := docker.CheckDaemon(); err != nil {
+				return err
+			}
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.logLevel(args[0], args[1])
+		},
+	}
+}
+
+func (o *CliOptions) logLevel(name, level string) error {
+	if err := logging.Validate(level); err != nil {
+		return err
+	}
+	c, err := components.GetObject(name, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return fmt.Errorf("%q: %w", name, err)
+	}
+	if c == nil {
+		return fmt.Errorf("component %q not found", name)
+	}
+	if _, ok := c.(triggermesh.Runnable); !ok || c.GetAPIVersion() == tmbroker.APIVersion {
+		return fmt.Errorf("%q is not an adapter", name)
+	}
+	if s, ok := c.(*service.Service); ok && s.IsAdopted() {
+		return fmt.Errorf("adopted container %q is not managed by tmctl", name)
+	}
+	if err := o.Manifest.Annotate(name, c.GetKind(), triggermesh.LogLevelAnnotation, level); err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	log.Printf("Restarting %s with %s log level", name, level)
+	s := &start.CliOptions{
+		Config:     o.Config,
+		Manifest:   o.Manifest,
+		CRD:        o.CRD,
+		Components: []string{name},
+	}
+	return s.Start()
+}
@@ -18,19 +18,40 @@ package start
 
 import (
 	"context"
+	"encoding/base64"
+	"errors"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
+	"time"
 
 	"github.com/spf13/cobra"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
+	"github.com/triggermesh/tmctl/pkg/bridge"
+	"github.com/triggermesh/tmctl/pkg/certs"
 	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/credentials"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/health"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
 	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/secrets"
+	"github.com/triggermesh/tmctl/pkg/selftest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/secret"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/ingest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/logging"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/replicas"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/signature"
+	"github.com/triggermesh/tmctl/pkg/urls"
 )
 
 type CliOptions struct {
@@ -39,6 +60,16 @@ type CliOptions struct {
 	CRD      map[string]crd.CRD
 
 	Restart bool
+	// LogLevel overrides the adapters logging level of all components.
+	LogLevel string
+	// Components limits the start to the listed components,
+	// which are restarted regardless of the Restart option.
+	Components []string
+	// Wait for each component to pass its readiness probe
+	// before starting the next one.
+	Wait bool
+	// SkipSelfTest disables the test event delivery through the started broker.
+	SkipSelfTest bool
 }
 
 func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
@@ -53,9 +84,14 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 		Example: "tmctl start",
 		Args:    cobra.RangeArgs(0, 1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
-			return []string{"--restart", "--version"}, cobra.ShellCompDirectiveNoFileComp
+			return []string{"--restart", "--version", "--log-level", "--wait", "--skip-self-test"}, cobra.ShellCompDirectiveNoFileComp
 		},
 		RunE: func(cmd *cobra.Command, args []string) error {
+			if o.LogLevel != "" {
+				if err := logging.Validate(o.LogLevel); err != nil {
+					return err
+				}
+			}
 			if len(args) != 0 {
 				o.Config.Context = args[0]
 				o.Manifest = manifest.New(filepath.Join(
@@ -63,17 +99,41 @@ func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD)
 					o.Config.Context,
 					triggermesh.ManifestFile))
 			}
-			cobra.CheckErr(o.Manifest.Read())
-			return o.start()
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.Start()
 		},
 	}
 	startCmd.Flags().BoolVar(&o.Restart, "restart", false, "Restart components")
+	startCmd.Flags().StringVar(&o.LogLevel, "log-level", "", "Adapters logging level: debug, info, warn or error")
+	startCmd.Flags().BoolVar(&o.Wait, "wait", false, "Wait for each component to pass its readiness probe before starting the next one")
+	startCmd.Flags().BoolVar(&o.SkipSelfTest, "skip-self-test", false, fmt.Sprintf("Do not verify the broker by delivering the test event of %q type through it", selftest.EventType))
+	cobra.CheckErr(startCmd.RegisterFlagCompletionFunc("log-level", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return logging.Levels, cobra.ShellCompDirectiveNoFileComp
+	}))
 	return startCmd
 }
 
-func (o *CliOptions) start() error {
+// Start runs the broker and the components of the context.
+func (o *CliOptions) Start() error {
+	if o.Config.StrictKinds {
+		if err := components.CheckKinds(o.Manifest, o.CRD); err != nil {
+			return err
+		}
+	}
 	ctx := context.Background()
+	var broker triggermesh.Component
 	var brokerPort string
+	var certsDir string
+	store := secrets.Context(o.Config.ConfigHome, o.Config.Context)
+	o.warnOrphanSecrets(store)
+	if o.Config.Triggermesh.Broker.InternalTLS {
+		certsDir = filepath.Join(o.Config.ConfigHome, o.Config.Context, certs.Dir)
+		if err := certs.Provision(certsDir, store); err != nil {
+			return fmt.Errorf("provisioning certificates: %w", err)
+		}
+	}
 	// start eventing first
 	for _, object := range o.Manifest.Objects {
 		if object.Kind == tmbroker.BrokerKind {
@@ -81,12 +141,56 @@ func (o *CliOptions) start() error {
 			if err != nil {
 				return fmt.Errorf("creating broker object: %w", err)
 			}
+			if image, set := o.Config.Triggermesh.Broker.Images[object.Metadata.Name]; set {
+				log.Printf("Using custom broker image %s", image)
+				for _, warning := range tmbroker.CompatibilityWarnings(image) {
+					log.Printf("WARNING! %s", warning)
+				}
+			}
+			var env map[string]string
+			if certsDir != "" {
+				// broker does not serve TLS but trusts the local CA
+				// when delivering events to the HTTPS targets
+				log.Println("WARNING! Broker does not support TLS, components send events to the broker over HTTP")
+				if env, err = certs.Env(certsDir, store, ""); err != nil {
+					return fmt.Errorf("certificates environment: %w", err)
+				}
+			}
+			if err := validateBrokerConfig(o.Config.ConfigHome, object.Metadata.Name, o.Manifest); err != nil {
+				return err
+			}
 			log.Println("Starting broker")
-			container, err := b.(triggermesh.Runnable).Start(ctx, nil, o.Restart)
+			container, err := b.(triggermesh.Runnable).Start(ctx, env, o.Restart)
 			if err != nil {
 				return fmt.Errorf("starting broker container: %w", err)
 			}
+			if err := o.waitReady(ctx, b, container); err != nil {
+				return err
+			}
+			broker = b
 			brokerPort = container.HostPort()
+			limits := o.Config.Triggermesh.Broker.Limits[object.Metadata.Name]
+			if limits.Empty() {
+				if _, limited := ingest.Port(ctx, object.Metadata.Name); limited {
+					if err := ingest.Remove(ctx, o.Config.ConfigHome, object.Metadata.Name); err != nil {
+						return fmt.Errorf("removing ingest proxy: %w", err)
+					}
+				}
+				continue
+			}
+			log.Println("Starting ingest proxy")
+			proxy, err := ingest.Start(ctx, o.Config.ConfigHome, object.Metadata.Name, container, limits)
+			if err != nil {
+				return err
+			}
+			brokerPort = proxy.HostPort()
+		}
+	}
+
+	// components started selectively do not need the broker to be verified again
+	if broker != nil && !o.SkipSelfTest && len(o.Components) == 0 {
+		if err := o.selfTest(ctx, broker, brokerPort); err != nil {
+			return err
 		}
 	}
 
@@ -94,7 +198,20 @@ func (o *CliOptions) start() error {
 		if object.APIVersion == tmbroker.APIVersion {
 			continue
 		}
-		c, _ := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+		if !o.selected(object.Metadata.Name) {
+			continue
+		}
+		c, err := components.GetObject(object.Metadata.Name, o.Config, o.Manifest, o.CRD)
+		var unsupported *components.UnsupportedKindError
+		if errors.As(err, &unsupported) {
+			log.Printf("WARNING! Skipping %s %q: %s", object.Kind, object.Metadata.Name, unsupported.Status())
+			continue
+		}
+		if err != nil {
+			// broken object must not prevent the healthy ones from starting
+			log.Printf("WARNING! Skipping %s %q: %v", object.Kind, object.Metadata.Name, err)
+			continue
+		}
 		if c == nil {
 			continue
 		}
@@ -102,7 +219,14 @@ func (o *CliOptions) start() error {
 			continue
 		}
 		if _, ok := c.(triggermesh.Producer); ok {
-			sink := "http://host.docker.internal:" + brokerPort
+			sink := o.Config.URLs().URL(urls.ContainerNetwork, brokerPort)
+			if t, ok := c.(*transformation.Transformation); ok && t.Engine() == transformation.EngineJQ {
+				s, err := o.sinkRef(ctx, t.GetSpec())
+				if err != nil {
+					return fmt.Errorf("%q sink: %w", c.GetName(), err)
+				}
+				sink = s
+			}
 			spec := c.GetSpec()
 			if spec == nil {
 				spec = make(map[string]interface{})
@@ -113,6 +237,10 @@ func (o *CliOptions) start() error {
 				spec["sink"] = map[string]interface{}{"uri": sink}
 			}
 		}
+		credentialsChanged, err := o.refreshCredentials(object)
+		if err != nil {
+			return err
+		}
 		secrets := make(map[string]string, 0)
 		if parent, ok := c.(triggermesh.Parent); ok {
 			_, secretsEnv, err := components.ProcessSecrets(parent, o.Manifest)
@@ -121,6 +249,23 @@ func (o *CliOptions) start() error {
 			}
 			secrets = secretsEnv
 		}
+		tls := false
+		if certsDir != "" {
+			if tls = o.servesTLS(c); tls {
+				if err := certs.Provision(certsDir, store, c.GetName()); err != nil {
+					return fmt.Errorf("%q certificate: %w", c.GetName(), err)
+				}
+				certsEnv, err := certs.Env(certsDir, store, c.GetName())
+				if err != nil {
+					return fmt.Errorf("%q certificate: %w", c.GetName(), err)
+				}
+				for k, v := range certsEnv {
+					secrets[k] = v
+				}
+			} else if _, ok := c.(triggermesh.Consumer); ok {
+				log.Printf("WARNING! %s does not support TLS, falling back to HTTP", c.GetName())
+			}
+		}
 		if reconcilable, ok := c.(triggermesh.Reconcilable); ok {
 			status, err := reconcilable.Initialize(ctx, secrets)
 			if err != nil {
@@ -128,10 +273,33 @@ func (o *CliOptions) start() error {
 			}
 			reconcilable.UpdateStatus(status)
 		}
+		restart := o.Restart || len(o.Components) != 0 || credentialsChanged
+		level := logging.Level(object)
+		if o.LogLevel != "" && o.LogLevel != level {
+			if err := o.Manifest.Annotate(object.Metadata.Name, object.Kind, triggermesh.LogLevelAnnotation, o.LogLevel); err != nil {
+				return fmt.Errorf("unable to update manifest: %w", err)
+			}
+			level = o.LogLevel
+			restart = true
+		}
+		secrets = logging.Env(level, secrets)
 		log.Printf("Starting %s\n", object.Metadata.Name)
-		if _, err := c.(triggermesh.Runnable).Start(ctx, secrets, o.Restart); err != nil {
+		container, err := c.(triggermesh.Runnable).Start(ctx, secrets, restart)
+		if err != nil {
 			return fmt.Errorf("starting component %q: %w", c.GetName(), err)
 		}
+		if err := o.waitReady(ctx, c, container); err != nil {
+			return err
+		}
+		if v, verified := signature.FromObject(object); verified {
+			secret, err := store.Read(c.GetName(), signature.SecretField)
+			if err != nil {
+				return fmt.Errorf("%q signature secret: %w", c.GetName(), err)
+			}
+			if _, err := signature.Start(ctx, o.Config.ConfigHome, o.Config.Context, container, v, secret); err != nil {
+				return fmt.Errorf("%q: %w", c.GetName(), err)
+			}
+		}
 		if _, ok := c.(triggermesh.Consumer); ok {
 			triggers, err := tmbroker.GetTargetTriggers(c.GetName(), o.Config.Context, o.Config.ConfigHome)
 			if err != nil {
@@ -139,11 +307,203 @@ func (o *CliOptions) start() error {
 			}
 			for _, t := range triggers {
 				t.(*tmbroker.Trigger).SetTarget(c)
-				if err := t.(*tmbroker.Trigger).WriteLocalConfig(); err != nil {
+				if tls {
+					t.(*tmbroker.Trigger).SetTLS()
+				}
+				if err := t.(*tmbroker.Trigger).UpdateLocalTarget(); err != nil {
 					return fmt.Errorf("updating broker config: %w", err)
 				}
 			}
+			// the trigger addresses may keep the port of the previous container,
+			// e.g. if the target lookup failed while the triggers were updated
+			if replicas.Count(object) <= 1 {
+				if _, err := o.builder().SyncTargetPort(c, container); err != nil {
+					return fmt.Errorf("%q triggers: %w", c.GetName(), err)
+				}
+			}
+			if count := replicas.Count(object); count > 1 {
+				log.Printf("Starting %d replicas of %s\n", count-1, object.Metadata.Name)
+				port, err := replicas.Scale(ctx, o.Config.ConfigHome, o.Config.Context, container, count, count, restart)
+				if err != nil {
+					return fmt.Errorf("%q replicas: %w", c.GetName(), err)
+				}
+				if err := replicas.RouteTriggers(c.GetName(), port, o.Config.Context, o.Config.ConfigHome); err != nil {
+					return fmt.Errorf("%q replicas: %w", c.GetName(), err)
+				}
+			}
 		}
 	}
+	// subscribe the components watching the source kinds
+	// to the sources created after them
+	if err := o.builder().SyncWatchedKinds(); err != nil {
+		return fmt.Errorf("watched source kinds: %w", err)
+	}
 	return nil
 }
+
+// builder returns the bridge builder of the started context.
+func (o *CliOptions) builder() *bridge.Builder {
+	return &bridge.Builder{
+		Config:   o.Config,
+		Manifest: o.Manifest,
+		CRD:      o.CRD,
+		Progress: func(message string) {
+			log.Println(message)
+		},
+	}
+}
+
+// waitReady blocks until the component passes the readiness probe
+// declared in its CRD, if the start was requested to wait.
+func (o *CliOptions) waitReady(ctx context.Context, c triggermesh.Component, container *docker.Container) error {
+	if !o.Wait {
+		return nil
+	}
+	r, err := components.Readiness(c, o.CRD)
+	if err != nil {
+		return err
+	}
+	timeout, err := time.ParseDuration(o.Config.Docker.StartTimeout)
+	if err != nil {
+		return fmt.Errorf("config timeout value: %w", err)
+	}
+	log.Printf("Waiting for %s to be ready (%s)", c.GetName(), r)
+	if result := r.Wait(ctx, components.ReadinessTarget(c.(triggermesh.Runnable), container), timeout); result.Status == health.Unhealthy {
+		return fmt.Errorf("%q is not ready: %s", c.GetName(), result.Detail)
+	}
+	return nil
+}
+
+// selfTest delivers the test event through the broker to make sure
+// that it accepts and routes the events rather than just runs.
+func (o *CliOptions) selfTest(ctx context.Context, broker triggermesh.Component, port string) error {
+	timeout, err := time.ParseDuration(o.Config.Docker.StartTimeout)
+	if err != nil {
+		return fmt.Errorf("config timeout value: %w", err)
+	}
+	log.Println("Verifying broker")
+	brokerURL := o.Config.URLs().URL(urls.Host, port)
+	if _, err := selftest.New(timeout).Verify(ctx, o.Config.ConfigHome, broker.GetName(), brokerURL); err != nil {
+		// the broker reports the configuration problems on startup
+		logs := selftest.Logs(ctx, broker.(triggermesh.Runnable), time.Time{})
+		return fmt.Errorf("broker self-test failed: %w\nBroker logs:\n  %s\nUse \"--skip-self-test\" to start the broker without the test event",
+			err, strings.Join(logs, "\n  "))
+	}
+	log.Println("Broker verified")
+	return nil
+}
+
+// selected returns true if the component is in the list of the started ones.
+func (o *CliOptions) selected(name string) bool {
+	if len(o.Components) == 0 {
+		return true
+	}
+	for _, c := range o.Components {
+		if c == name {
+			return true
+		}
+	}
+	return false
+}
+
+// servesTLS checks the component CRD annotation of the TLS support.
+func (o *CliOptions) servesTLS(c triggermesh.Component) bool {
+	crd, exists := o.CRD[strings.ToLower(c.GetKind())]
+	return exists && crd.ServesTLS()
+}
+
+// sinkRef resolves the component's sink reference into the local address.
+func (o *CliOptions) sinkRef(ctx context.Context, spec map[string]interface{}) (string, error) {
+	name, _, err := unstructured.NestedString(spec, "sink", "ref", "name")
+	if err != nil || name == "" {
+		return "", fmt.Errorf("sink reference is not set")
+	}
+	c, err := components.GetObject(name, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return "", fmt.Errorf("%q: %w", name, err)
+	}
+	consumer, ok := c.(triggermesh.Consumer)
+	if !ok {
+		return "", fmt.Errorf("%q is not an event consumer", name)
+	}
+	url, err := o.Config.URLs().ComponentURL(ctx, consumer, urls.ContainerNetwork)
+	if err != nil {
+		return "", fmt.Errorf("%q port: %w", name, err)
+	}
+	return url, nil
+}
+
+// validateBrokerConfig fails fast if the broker configuration
+// has the problems that would make the broker ignore the triggers or fail.
+func validateBrokerConfig(configHome, broker string, m *manifest.Manifest) error {
+	problems, err := tmbroker.ValidateConfig(tmbroker.ConfigPath(configHome, broker), tmbroker.Destinations(m.Objects))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	if err != nil {
+		return fmt.Errorf("broker config: %w", err)
+	}
+	if len(problems) == 0 {
+		return nil
+	}
+	report := make([]string, 0, len(problems))
+	for _, p := range problems {
+		report = append(report, p.String())
+	}
+	return fmt.Errorf("broker %q config is invalid:\n%s\nRun \"tmctl broker validate --fix\" to repair it",
+		broker, strings.Join(report, "\n"))
+}
+
+// refreshCredentials resolves the short-lived cloud credentials of the
+// component again before its container is started, e.g. to pick up
+// the renewed SSO session. True is returned if the component secret has changed.
+// warnOrphanSecrets reports the secret files left behind by the deleted components.
+func (o *CliOptions) warnOrphanSecrets(store *secrets.Store) {
+	orphans, err := store.Orphans(func(component string) bool {
+		for _, object := range o.Manifest.Objects {
+			if object.Metadata.Name == component {
+				return true
+			}
+		}
+		return false
+	})
+	if err != nil {
+		log.Printf("WARNING! Checking secrets: %v", err)
+		return
+	}
+	for _, orphan := range orphans {
+		log.Printf("WARNING! Secret %s of the deleted component %q is left on disk, run \"tmctl secret prune\" to remove it", orphan.Field, orphan.Component)
+	}
+}
+
+func (o *CliOptions) refreshCredentials(object kubernetes.Object) (bool, error) {
+	if object.Metadata.Annotations[triggermesh.CredentialsRefreshAnnotation] != "true" {
+		return false, nil
+	}
+	creds, err := credentials.ResolveAWS(object.Metadata.Annotations[triggermesh.CredentialsProfileAnnotation])
+	if err != nil {
+		return false, fmt.Errorf("%q credentials: %w", object.Metadata.Name, err)
+	}
+	secretName := object.Metadata.Name + "-secret"
+	data := make(map[string]string)
+	for _, existing := range o.Manifest.Objects {
+		if existing.Kind == "Secret" && existing.Metadata.Name == secretName {
+			for k, v := range existing.Data {
+				data[k] = v
+			}
+		}
+	}
+	delete(data, credentials.AWSSessionTokenEnv)
+	for k, v := range creds.SecretData() {
+		data[k] = base64.StdEncoding.EncodeToString([]byte(v))
+	}
+	changed, err := o.Manifest.Add(secret.New(secretName, o.Config.Context, data))
+	if err != nil {
+		return false, fmt.Errorf("unable to update manifest: %w", err)
+	}
+	if err := o.Manifest.Annotate(object.Metadata.Name, object.Kind,
+		triggermesh.CredentialsExpiryAnnotation, creds.Expiry()); err != nil {
+		return false, fmt.Errorf("unable to update manifest: %w", err)
+	}
+	return changed, nil
+}
@@ -0,0 +1,220 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"context"
+	"fmt"
+	"os"
+	"os/signal"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/observed"
+	"github.com/triggermesh/tmctl/pkg/throughput"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/wiretap"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+
+	// since, top and output configure the event counts per type
+	since  time.Duration
+	top    int
+	output string
+}
+
+func NewCmd(config *config.Config, m *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		Config:   config,
+		Manifest: m,
+		CRD:      crd,
+	}
+	var byType, watch bool
+	statsCmd := &cobra.Command{
+		Use:   "stats [--by-type [--since <duration>][--top <n>][-o json][--watch]]",
+		Short: "Show the event types observed at the broker ingest",
+		Long: `Show the event types observed at the broker ingest.
+Event types are recorded by "tmctl stats observe" and can be used
+to create the triggers with "--event-types-from observed".
+
+With "--by-type" the events accepted by the broker are counted per their
+type and source, the busiest first. Types that match no triggers are
+flagged as dropped. Brokers that do not keep the accepted events fall back
+to the counts of the types recorded by "tmctl stats observe".`,
+		Example: `tmctl stats observe
+
+tmctl stats
+
+tmctl stats --by-type --since 10m --top 5 --watch`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if !byType {
+				for _, flag := range []string{"since", "top", "output", "watch"} {
+					if cmd.Flags().Changed(flag) {
+						return fmt.Errorf("--%s requires --by-type", flag)
+					}
+				}
+				return o.list()
+			}
+			if o.output != "" && o.output != "json" {
+				return fmt.Errorf("unsupported output format %q", o.output)
+			}
+			if watch && o.output != "" {
+				return fmt.Errorf("--watch does not support %q output", o.output)
+			}
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			if watch {
+				return o.watchTypes()
+			}
+			return o.listTypes(os.Stdout)
+		},
+	}
+	statsCmd.Flags().BoolVar(&byType, "by-type", false, "Count the events accepted by the broker per type and source")
+	statsCmd.Flags().DurationVar(&o.since, "since", time.Hour, "Count the events accepted since the duration ago")
+	statsCmd.Flags().IntVar(&o.top, "top", 0, "Show the given number of the busiest event types only")
+	statsCmd.Flags().StringVarP(&o.output, "output", "o", "", "Output format: json")
+	statsCmd.Flags().BoolVar(&watch, "watch", false, "Refresh the counts until interrupted")
+	cobra.CheckErr(statsCmd.RegisterFlagCompletionFunc("output", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{"json"}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	statsCmd.AddCommand(o.newObserveCmd())
+	statsCmd.AddCommand(o.newResetCmd())
+	return statsCmd
+}
+
+func (o *CliOptions) newObserveCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "observe",
+		Short: "Record the event types passing through the broker until interrupted",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.observe()
+		},
+	}
+}
+
+func (o *CliOptions) newResetCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:   "reset",
+		Short: "Clear the observed event types",
+		Args:  cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := observed.Reset(o.Config.ConfigHome, o.Config.Context); err != nil {
+				return fmt.Errorf("observed types: %w", err)
+			}
+			log.Println("Observed event types cleared")
+			return nil
+		},
+	}
+}
+
+func (o *CliOptions) list() error {
+	types, err := observed.Load(o.Config.ConfigHome, o.Config.Context)
+	if err != nil {
+		return err
+	}
+	entries := types.Entries()
+	if len(entries) == 0 {
+		fmt.Println("No event types observed, run \"tmctl stats observe\" while the sources are producing events")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
+	fmt.Fprintln(w, "Component\tEventType\tCount\tFirst Seen\tLast Seen")
+	for _, e := range entries {
+		component := e.Component
+		if component == "" {
+			component = "-"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%d\t%s\t%s\n", component, e.Type, e.Count,
+			e.FirstSeen.Format(time.RFC3339), e.LastSeen.Format(time.RFC3339))
+	}
+	return w.Flush()
+}
+
+// observe creates the trigger for all events delivered to the local listener
+// and records the pairs of the producing component and the event type.
+func (o *CliOptions) observe() error {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer close(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	types, err := observed.Load(o.Config.ConfigHome, o.Config.Context)
+	if err != nil {
+		return err
+	}
+	producers := throughput.Producers(o.Config, o.Manifest, o.CRD)
+
+	w, err := wiretap.New(o.Config.Context, o.Config.ConfigHome)
+	if err != nil {
+		return fmt.Errorf("wiretap: %w", err)
+	}
+	w.Name = "observer"
+	events, err := w.Listen(ctx)
+	if err != nil {
+		return fmt.Errorf("listener: %w", err)
+	}
+	defer func() {
+		if err := w.Cleanup(context.Background()); err != nil {
+			log.Printf("Cleanup: %v", err)
+		}
+	}()
+	if err := w.CreateTrigger(); err != nil {
+		return fmt.Errorf("create trigger: %w", err)
+	}
+	log.Println("Observing event types...")
+	for {
+		select {
+		case <-c:
+			log.Println("Cleaning up")
+			return types.Save()
+		case event, ok := <-events:
+			if !ok {
+				return fmt.Errorf("listener stopped")
+			}
+			component := producers[event.Source()]
+			if !types.Add(component, event.Type(), event.Time()) {
+				continue
+			}
+			if component == "" {
+				log.Printf("New event type %q from the unknown source %q", event.Type(), event.Source())
+			} else {
+				log.Printf("New event type %q from %s", event.Type(), component)
+			}
+			if err := types.Save(); err != nil {
+				return fmt.Errorf("saving observed types: %w", err)
+			}
+		}
+	}
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package stats
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"os/signal"
+	"strings"
+	"syscall"
+	"text/tabwriter"
+	"time"
+
+	"github.com/triggermesh/tmctl/pkg/throughput"
+)
+
+const (
+	watchPeriod = 2 * time.Second
+
+	clearScreen      = "\033[H\033[2J"
+	droppedColorCode = "\033[31m"
+	defaultColorCode = "\033[39m"
+)
+
+// listTypes prints the events accepted by the broker per type and source.
+func (o *CliOptions) listTypes(out io.Writer) error {
+	counts, origin, err := throughput.LoadByType(o.Config, o.Manifest, o.CRD, time.Now().Add(-o.since))
+	if err != nil {
+		return err
+	}
+	counts = throughput.Top(counts, o.top)
+	if o.output == "json" {
+		data, err := json.MarshalIndent(counts, "", "  ")
+		if err != nil {
+			return fmt.Errorf("encoding counts: %w", err)
+		}
+		fmt.Fprintln(out, string(data))
+		return nil
+	}
+	if origin == throughput.FromObserved {
+		fmt.Fprintln(out, "Broker does not keep the accepted events, showing the totals of the types recorded by \"tmctl stats observe\"")
+		fmt.Fprintln(out)
+	}
+	if len(counts) == 0 {
+		fmt.Fprintf(out, "No events accepted by the broker since %s\n", o.since)
+		return nil
+	}
+	w := tabwriter.NewWriter(out, 10, 5, 5, ' ', 0)
+	fmt.Fprintln(w, "EventType\tSource\tComponent\tCount\tLast Seen\tTriggers")
+	dropped := false
+	for _, c := range counts {
+		triggers := strings.Join(c.Triggers, ",")
+		if c.Dropped {
+			triggers = fmt.Sprintf("%snone (dropped)%s", droppedColorCode, defaultColorCode)
+			dropped = true
+		} else if triggers == "" {
+			// the filters could not be evaluated without the event attributes
+			triggers = "?"
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\t%d\t%s\t%s\n", c.Type, orDash(c.Source), orDash(c.Component), c.Count,
+			c.LastSeen.Format(time.RFC3339), triggers)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+	if dropped {
+		fmt.Fprintln(out, "\nDropped events match no triggers, use \"tmctl create trigger\" to route them")
+	}
+	return nil
+}
+
+// watchTypes refreshes the counts per type until interrupted.
+func (o *CliOptions) watchTypes() error {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer signal.Stop(c)
+	go func() {
+		select {
+		case <-c:
+			cancel()
+		case <-ctx.Done():
+		}
+	}()
+
+	tty := isTerminal(os.Stdout)
+	ticker := time.NewTicker(watchPeriod)
+	defer ticker.Stop()
+	for {
+		if err := o.Manifest.Read(); err != nil {
+			return err
+		}
+		var out bytes.Buffer
+		if err := o.listTypes(&out); err != nil {
+			return err
+		}
+		if tty {
+			fmt.Print(clearScreen)
+		} else {
+			fmt.Printf("--- %s\n", time.Now().Format("15:04:05"))
+		}
+		fmt.Print(out.String())
+		select {
+		case <-ctx.Done():
+			return nil
+		case <-ticker.C:
+		}
+	}
+}
+
+func orDash(value string) string {
+	if value == "" {
+		return "-"
+	}
+	return value
+}
+
+func isTerminal(f *os.File) bool {
+	info, err := f.Stat()
+	if err != nil {
+		return false
+	}
+	return info.Mode()&os.ModeCharDevice != 0
+}
@@ -19,16 +19,24 @@ package stop
 import (
 	"context"
 	"fmt"
+	"os"
 	"path/filepath"
+	"strings"
 
+	"github.com/docker/docker/client"
 	"github.com/spf13/cobra"
 
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/docker"
 	"github.com/triggermesh/tmctl/pkg/log"
 	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/prompt"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/ingest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/replicas"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/signature"
 )
 
 type CliOptions struct {
@@ -41,11 +49,14 @@ func NewCmd(config *config.Config, m *manifest.Manifest) *cobra.Command {
 		Config:   config,
 		Manifest: m,
 	}
-	return &cobra.Command{
-		Use:     "stop [broker]",
-		Short:   "Stops TriggerMesh components, removes docker containers",
-		Example: "tmctl stop",
-		Args:    cobra.RangeArgs(0, 1),
+	var cleanup, volumes bool
+	stopCmd := &cobra.Command{
+		Use:   "stop [broker][--cleanup [--volumes]]",
+		Short: "Stops TriggerMesh components, removes docker containers",
+		Example: `tmctl stop
+
+tmctl stop --cleanup --volumes`,
+		Args: cobra.RangeArgs(0, 1),
 		RunE: func(cmd *cobra.Command, args []string) error {
 			if len(args) != 0 {
 				o.Config.Context = args[0]
@@ -54,10 +65,21 @@ func NewCmd(config *config.Config, m *manifest.Manifest) *cobra.Command {
 					o.Config.Context,
 					triggermesh.ManifestFile))
 			}
-			cobra.CheckErr(o.Manifest.Read())
+			if volumes && !cleanup {
+				return fmt.Errorf("--volumes requires --cleanup")
+			}
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			if cleanup {
+				return o.cleanup(volumes)
+			}
 			return o.stop()
 		},
 	}
+	stopCmd.Flags().BoolVar(&cleanup, "cleanup", false, "Also remove the leftover replicas and the files generated for the containers")
+	stopCmd.Flags().BoolVar(&volumes, "volumes", false, "Remove the named volumes of the containers after the confirmation")
+	return stopCmd
 }
 
 func (o *CliOptions) stop() error {
@@ -66,23 +88,159 @@ func (o *CliOptions) stop() error {
 	if err != nil {
 		return fmt.Errorf("docker client: %w", err)
 	}
+	o.stopComponents(ctx, client)
+	return nil
+}
 
+func (o *CliOptions) stopComponents(ctx context.Context, client *client.Client) {
 	for _, object := range o.Manifest.Objects {
 		if object.Kind == tmbroker.TriggerKind || object.Kind == "Secret" {
 			continue
 		}
+		if _, adopted := object.Metadata.Annotations[service.AdoptedContainerAnnotation]; adopted {
+			continue
+		}
 		if object.Kind == tmbroker.BrokerKind {
 			wiretapContainerName := object.Metadata.Name + "-wiretap"
 			if err := docker.ForceStop(ctx, wiretapContainerName, client); err != nil {
 				log.Printf("Stopping %q: %v", wiretapContainerName, err)
 			}
+			if err := docker.ForceStop(ctx, ingest.ProxyName(object.Metadata.Name), client); err != nil {
+				log.Printf("Stopping %q ingest proxy: %v", object.Metadata.Name, err)
+			}
 
 			object.Metadata.Name += "-broker"
 		}
+		if count := replicas.Count(object); count > 1 {
+			if err := replicas.Remove(ctx, object.Metadata.Name, count); err != nil {
+				log.Printf("Stopping %q replicas: %v", object.Metadata.Name, err)
+			}
+		}
+		if _, verified := signature.FromObject(object); verified {
+			if err := docker.ForceStop(ctx, signature.ProxyName(object.Metadata.Name), client); err != nil {
+				log.Printf("Stopping %q verifier: %v", object.Metadata.Name, err)
+			}
+		}
 		log.Printf("Stopping %s\n", object.Metadata.Name)
 		if err := docker.ForceStop(ctx, object.Metadata.Name, client); err != nil {
 			log.Printf("Stopping %q: %v", object.Metadata.Name, err)
 		}
 	}
+}
+
+// containerNames returns the names of the containers created by tmctl for
+// the manifest components, the replicas suffixes are matched by the prefix
+// since the replicas count could change since they were started.
+func (o *CliOptions) containerNames(ctx context.Context, client *client.Client) ([]string, error) {
+	names := map[string]struct{}{
+		o.Config.Context + "-wiretap":      {},
+		ingest.ProxyName(o.Config.Context): {},
+	}
+	var prefixes []string
+	for _, object := range o.Manifest.Objects {
+		if object.Kind == tmbroker.TriggerKind || object.Kind == "Secret" {
+			continue
+		}
+		if _, adopted := object.Metadata.Annotations[service.AdoptedContainerAnnotation]; adopted {
+			continue
+		}
+		name := object.Metadata.Name
+		if object.Kind == tmbroker.BrokerKind {
+			name += "-broker"
+		}
+		names[name] = struct{}{}
+		names[replicas.ProxyName(name)] = struct{}{}
+		names[signature.ProxyName(name)] = struct{}{}
+		prefixes = append(prefixes, replicas.NamePrefix(name))
+	}
+	containers, err := docker.LabeledContainers(ctx, client, func(context string) bool {
+		return context == o.Config.Context || context == ""
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, container := range containers {
+		// the component label is the name the container was requested
+		// with, the container itself may be renamed on the name conflict
+		if _, exists := names[container.Component]; exists {
+			result = append(result, container.Name)
+			continue
+		}
+		for _, prefix := range prefixes {
+			if strings.HasPrefix(container.Component, prefix) {
+				result = append(result, container.Name)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// cleanup stops the components and removes everything "tmctl start"
+// recreates from the manifest and the broker config: leftover containers,
+// the context network, load balancer configs, function code copies and, optionally, volumes.
+func (o *CliOptions) cleanup(withVolumes bool) error {
+	ctx := context.Background()
+	client, err := docker.NewClient()
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	containers, err := o.containerNames(ctx, client)
+	if err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+	var volumes []string
+	if withVolumes {
+		for _, container := range containers {
+			named, err := docker.NamedVolumes(ctx, container, client)
+			if err != nil {
+				log.Printf("Volumes of %q: %v", container, err)
+				continue
+			}
+			volumes = append(volumes, named...)
+		}
+	}
+
+	o.stopComponents(ctx, client)
+	// leftovers of the previous scales and the wiretap
+	if containers, err = o.containerNames(ctx, client); err != nil {
+		return fmt.Errorf("listing containers: %w", err)
+	}
+	for _, container := range containers {
+		if err := docker.ForceStop(ctx, container, client); err != nil {
+			log.Printf("Removing %q: %v", container, err)
+		}
+	}
+	network := docker.NetworkName(o.Config.Context)
+	if err := docker.RemoveNetwork(ctx, network, client); err != nil {
+		log.Printf("Removing network %q: %v", network, err)
+	}
+
+	contextDir := filepath.Join(o.Config.ConfigHome, o.Config.Context)
+	lbConfigs, _ := filepath.Glob(filepath.Join(contextDir, "*-lb.conf"))
+	lbConfigs = append(lbConfigs, filepath.Join(contextDir, ingest.ProxyName(o.Config.Context)+".conf"))
+	for _, file := range append(lbConfigs, filepath.Join(contextDir, "functions")) {
+		if err := os.RemoveAll(file); err != nil {
+			log.Printf("Removing %q: %v", file, err)
+		}
+	}
+
+	if len(volumes) == 0 {
+		return nil
+	}
+	confirmed, err := prompt.Confirm(fmt.Sprintf("Remove volumes %s? The data will be lost", strings.Join(volumes, ", ")))
+	if err != nil {
+		return fmt.Errorf("volumes are kept: %w", err)
+	}
+	if !confirmed {
+		return nil
+	}
+	for _, volume := range volumes {
+		log.Printf("Removing volume %s", volume)
+		if err := docker.RemoveVolume(ctx, volume, client); err != nil {
+			log.Printf("Removing volume %q: %v", volume, err)
+		}
+	}
 	return nil
 }
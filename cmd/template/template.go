@@ -0,0 +1,257 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package template
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/templates"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+type CliOptions struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+}
+
+func NewCmd(config *config.Config, manifest *manifest.Manifest, crds map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		Config:   config,
+		Manifest: manifest,
+		CRD:      crds,
+	}
+	templateCmd := &cobra.Command{
+		Use:   "template",
+		Short: "Manage the component templates shared by the broker contexts",
+	}
+	templateCmd.AddCommand(o.newSaveCmd())
+	templateCmd.AddCommand(o.newListCmd())
+	templateCmd.AddCommand(o.newExportCmd())
+	templateCmd.AddCommand(o.newImportCmd())
+	templateCmd.AddCommand(o.newDeleteCmd())
+	return templateCmd
+}
+
+func (o *CliOptions) newSaveCmd() *cobra.Command {
+	var name string
+	var params []string
+	var force bool
+	saveCmd := &cobra.Command{
+		Use:   "save <component> --name <template> [--params <name>[=<path>],...]",
+		Short: "Save the component spec as the template with the parameterized fields",
+		Example: `tmctl template save foo-httptarget --name corp-http --params url=endpoint,token=headers.Authorization
+
+tmctl template save foo-httptarget --name corp-http --params endpoint`,
+		Args: cobra.ExactArgs(1),
+		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+			if len(args) != 0 {
+				return nil, cobra.ShellCompDirectiveNoFileComp
+			}
+			return append(completion.ListSources(o.Manifest), completion.ListTargets(o.Manifest)...), cobra.ShellCompDirectiveNoFileComp
+		},
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := o.Manifest.Read(); err != nil {
+				return fmt.Errorf("reading manifest: %w", err)
+			}
+			return o.save(args[0], name, params, force)
+		},
+	}
+	saveCmd.Flags().StringVar(&name, "name", "", "Template name")
+	saveCmd.Flags().StringSliceVar(&params, "params", []string{}, "Templated spec fields, \"<name>\" matches the field of the same name, \"<name>=<path>\" selects the field by its dotted path")
+	saveCmd.Flags().BoolVar(&force, "force", false, "Replace the existing template")
+	cobra.CheckErr(saveCmd.MarkFlagRequired("name"))
+	cobra.CheckErr(saveCmd.RegisterFlagCompletionFunc("name", cobra.NoFileCompletions))
+	cobra.CheckErr(saveCmd.RegisterFlagCompletionFunc("params", cobra.NoFileCompletions))
+	return saveCmd
+}
+
+func (o *CliOptions) newListCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:     "list",
+		Short:   "List the saved templates",
+		Example: "tmctl template list",
+		Args:    cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.list()
+		},
+	}
+}
+
+func (o *CliOptions) newExportCmd() *cobra.Command {
+	var file string
+	exportCmd := &cobra.Command{
+		Use:   "export <template> [--file <path>]",
+		Short: "Write the template to the file or stdout",
+		Example: `tmctl template export corp-http > corp-http.yaml
+
+tmctl template export corp-http --file corp-http.yaml`,
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: o.templatesCompletion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.export(args[0], file)
+		},
+	}
+	exportCmd.Flags().StringVarP(&file, "file", "f", "", "Output file, stdout if not set")
+	return exportCmd
+}
+
+func (o *CliOptions) newImportCmd() *cobra.Command {
+	var name string
+	var force bool
+	importCmd := &cobra.Command{
+		Use:     "import <file> [--name <template>]",
+		Short:   "Save the exported template",
+		Example: "tmctl template import corp-http.yaml",
+		Args:    cobra.ExactArgs(1),
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.importTemplate(args[0], name, force)
+		},
+	}
+	importCmd.Flags().StringVar(&name, "name", "", "Save the template under another name")
+	importCmd.Flags().BoolVar(&force, "force", false, "Replace the existing template")
+	cobra.CheckErr(importCmd.RegisterFlagCompletionFunc("name", cobra.NoFileCompletions))
+	return importCmd
+}
+
+func (o *CliOptions) newDeleteCmd() *cobra.Command {
+	return &cobra.Command{
+		Use:               "delete <template>",
+		Short:             "Delete the saved template",
+		Example:           "tmctl template delete corp-http",
+		Args:              cobra.ExactArgs(1),
+		ValidArgsFunction: o.templatesCompletion,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			if err := templates.New(o.Config.ConfigHome).Delete(args[0]); err != nil {
+				return err
+			}
+			log.Printf("Template %q deleted", args[0])
+			return nil
+		},
+	}
+}
+
+func (o *CliOptions) save(component, name string, params []string, force bool) error {
+	var object *kubernetes.Object
+	for i := range o.Manifest.Objects {
+		if o.Manifest.Objects[i].Metadata.Name == component {
+			object = &o.Manifest.Objects[i]
+			break
+		}
+	}
+	if object == nil {
+		return fmt.Errorf("component %q not found", component)
+	}
+	kind := strings.ToLower(object.Kind)
+	c, exists := o.CRD[kind]
+	if !exists || !strings.HasSuffix(kind, "source") && !strings.HasSuffix(kind, "target") {
+		return fmt.Errorf("%q is neither source nor target, only their specs can be templated", component)
+	}
+	schema, _, err := c.ServedSchema()
+	if err != nil {
+		return err
+	}
+	t, added, err := templates.FromObject(name, *object, params, schema.SecretFields())
+	if err != nil {
+		return err
+	}
+	for _, p := range added {
+		log.Printf("Secret field %q is templated as %q param", p.Path, p.Name)
+	}
+	if err := templates.New(o.Config.ConfigHome).Save(t, force); err != nil {
+		return err
+	}
+	log.Printf("Template %q saved", t.Name)
+	return nil
+}
+
+func (o *CliOptions) list() error {
+	list, err := templates.New(o.Config.ConfigHome).List()
+	if err != nil {
+		return fmt.Errorf("listing templates: %w", err)
+	}
+	if len(list) == 0 {
+		fmt.Println("No templates")
+		return nil
+	}
+	w := tabwriter.NewWriter(os.Stdout, 10, 5, 5, ' ', 0)
+	fmt.Fprintln(w, "Name\tKind\tParams")
+	for _, t := range list {
+		var params []string
+		for _, p := range t.Params {
+			if p.Secret {
+				params = append(params, p.Name+" (secret)")
+				continue
+			}
+			params = append(params, p.Name)
+		}
+		fmt.Fprintf(w, "%s\t%s\t%s\n", t.Name, t.Kind, strings.Join(params, ", "))
+	}
+	return w.Flush()
+}
+
+func (o *CliOptions) export(name, file string) error {
+	t, err := templates.New(o.Config.ConfigHome).Get(name)
+	if err != nil {
+		return err
+	}
+	data, err := t.Marshal()
+	if err != nil {
+		return fmt.Errorf("encoding template: %w", err)
+	}
+	if file == "" {
+		_, err := os.Stdout.Write(data)
+		return err
+	}
+	return os.WriteFile(file, data, 0600)
+}
+
+func (o *CliOptions) importTemplate(file, name string, force bool) error {
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return fmt.Errorf("reading template: %w", err)
+	}
+	t, err := templates.Parse(data)
+	if err != nil {
+		return err
+	}
+	if name != "" {
+		t.Name = name
+	}
+	if err := templates.New(o.Config.ConfigHome).Save(t, force); err != nil {
+		return err
+	}
+	log.Printf("Template %q imported", t.Name)
+	return nil
+}
+
+func (o *CliOptions) templatesCompletion(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+	if len(args) != 0 {
+		return nil, cobra.ShellCompDirectiveNoFileComp
+	}
+	return completion.ListTemplates(o.Config.ConfigHome), cobra.ShellCompDirectiveNoFileComp
+}
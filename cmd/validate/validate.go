@@ -0,0 +1,120 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/spf13/cobra"
+
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/validate"
+)
+
+type CliOptions struct {
+	CRD map[string]crd.CRD
+
+	file   string
+	crdDir string
+	failOn string
+}
+
+func NewCmd(crds map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD: crds,
+	}
+	validateCmd := &cobra.Command{
+		Use:   "validate -f <manifest> [--crd-dir <dir>][--fail-on error|warning]",
+		Short: "Validate the manifest file without Docker and the broker",
+		Example: `tmctl validate -f manifest.yaml
+
+tmctl validate -f manifest.yaml --crd-dir ./crds --fail-on warning`,
+		Args: cobra.NoArgs,
+		RunE: func(cmd *cobra.Command, args []string) error {
+			return o.validate()
+		},
+	}
+	validateCmd.Flags().StringVarP(&o.file, "file", "f", "", "Manifest file")
+	validateCmd.Flags().StringVar(&o.crdDir, "crd-dir", "", "Directory with the CRD files, defaults to the CRDs of the configured TriggerMesh version")
+	validateCmd.Flags().StringVar(&o.failOn, "fail-on", string(validate.SeverityError), "Lowest severity of the findings that fails the validation, \"error\" or \"warning\"")
+	cobra.CheckErr(validateCmd.MarkFlagRequired("file"))
+	cobra.CheckErr(validateCmd.MarkFlagDirname("crd-dir"))
+	cobra.CheckErr(validateCmd.RegisterFlagCompletionFunc("fail-on", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return []string{string(validate.SeverityError), string(validate.SeverityWarning)}, cobra.ShellCompDirectiveNoFileComp
+	}))
+	return validateCmd
+}
+
+func (o *CliOptions) validate() error {
+	threshold, err := validate.ParseSeverity(o.failOn)
+	if err != nil {
+		return fmt.Errorf("--fail-on: %w", err)
+	}
+	data, err := os.ReadFile(o.file)
+	if err != nil {
+		return fmt.Errorf("reading manifest: %w", err)
+	}
+	objects, err := manifest.Decode(data)
+	if err != nil {
+		return fmt.Errorf("decoding manifest: %w", err)
+	}
+	crds, err := o.crds()
+	if err != nil {
+		return err
+	}
+
+	findings := validate.Objects(objects, crds)
+	if len(findings) == 0 {
+		fmt.Printf("%s: %d object(s), no issues found\n", o.file, len(objects))
+		return nil
+	}
+	order, groups := validate.ByObject(findings)
+	for _, object := range order {
+		fmt.Printf("%s:\n", object)
+		for _, finding := range groups[object] {
+			fmt.Printf("  %s\n", finding)
+		}
+	}
+	errors := validate.Count(findings, validate.SeverityError)
+	fmt.Printf("\n%d error(s), %d warning(s)\n", errors, len(findings)-errors)
+	if failed := validate.Count(findings, threshold); failed != 0 {
+		return fmt.Errorf("%s: %d finding(s) of %s severity or higher", o.file, failed, threshold)
+	}
+	return nil
+}
+
+// crds returns the CRDs of the directory or the CRDs
+// of the configured TriggerMesh version.
+func (o *CliOptions) crds() (map[string]crd.CRD, error) {
+	if o.crdDir == "" {
+		return o.CRD, nil
+	}
+	info, err := os.Stat(o.crdDir)
+	if err != nil {
+		return nil, fmt.Errorf("--crd-dir: %w", err)
+	}
+	if !info.IsDir() {
+		return nil, fmt.Errorf("--crd-dir: %s is not a directory", o.crdDir)
+	}
+	crds, err := crd.Load(o.crdDir)
+	if err != nil {
+		return nil, fmt.Errorf("loading CRDs from %q: %w", o.crdDir, err)
+	}
+	return crds, nil
+}
@@ -25,29 +25,61 @@ import (
 
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 )
 
 func NewCmd(ver, commit string, c *config.Config) *cobra.Command {
+	var components bool
 	versionCmd := &cobra.Command{
-		Use:   "version",
+		Use:   "version [--components]",
 		Short: "CLI version information",
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 			return []string{}, cobra.ShellCompDirectiveNoFileComp
 		},
 		Run: func(_ *cobra.Command, _ []string) {
+			if components {
+				printComponents(c)
+				return
+			}
 			fmt.Println("CLI:")
 			fmt.Println(" Version: ", ver)
 			fmt.Println(" Commit: ", commit)
 			fmt.Printf(" OS/Arch: %s/%s\n", runtime.GOOS, runtime.GOARCH)
 			fmt.Println("\nTriggerMesh:")
-			fmt.Println(" Components version: ", c.Triggermesh.ComponentsVersion)
+			printComponents(c)
 			fmt.Println("\nDocker:")
 			fmt.Println(" ", dockerVersion())
 		},
 	}
+	versionCmd.Flags().BoolVar(&components, "components", false, "Print only the TriggerMesh components information and the source of the CRDs")
 	return versionCmd
 }
 
+func printComponents(c *config.Config) {
+	fmt.Println(" Components version: ", c.Triggermesh.ComponentsVersion)
+	fmt.Println(" CRDs: ", crdSource(c))
+	fmt.Println(" Embedded CRDs version: ", crd.EmbeddedVersion())
+	fmt.Println(" Broker version: ", c.Triggermesh.Broker.Version)
+	if image, set := c.Triggermesh.Broker.Images[c.Context]; set {
+		fmt.Printf(" Broker image (%s):  %s\n", c.Context, image)
+	}
+}
+
+// crdSource describes where the CRDs of the components version came from.
+func crdSource(c *config.Config) string {
+	switch source := crd.LoadedFrom(); source {
+	case crd.SourceCache:
+		if origin, ok := crd.Origin(c.ConfigHome, c.Triggermesh.ComponentsVersion); ok {
+			return fmt.Sprintf("%s (from %s)", source, origin)
+		}
+		return string(source)
+	case "":
+		return "not loaded"
+	default:
+		return string(source)
+	}
+}
+
 func dockerVersion() string {
 	client, err := docker.NewClient()
 	if err != nil {
@@ -23,21 +23,45 @@ import (
 	"fmt"
 	"io"
 	"net"
+	"net/url"
 	"os"
 	"os/signal"
 	"strings"
 	"syscall"
 	"time"
 
+	cloudevents "github.com/cloudevents/sdk-go/v2"
 	"github.com/spf13/cobra"
+	"golang.org/x/term"
 
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/completion"
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/output"
+	"github.com/triggermesh/tmctl/pkg/route"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/urls"
 	"github.com/triggermesh/tmctl/pkg/wiretap"
 )
 
 type CliOptions struct {
-	Config *config.Config
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+
+	render func(cloudevents.Event) string
+	// jsonOutput prints the events in JSON, one per line.
+	jsonOutput bool
+	// routes annotates the events with the triggers predicted to match them,
+	// onlyUnrouted shows only the events that match no trigger.
+	routes       bool
+	onlyUnrouted bool
 }
 
 type brokerLog struct {
@@ -47,13 +71,22 @@ type brokerLog struct {
 	Name   string `json:"name"`
 }
 
-func NewCmd(config *config.Config) *cobra.Command {
-	o := &CliOptions{Config: config}
+func NewCmd(config *config.Config, manifest *manifest.Manifest, crd map[string]crd.CRD) *cobra.Command {
+	o := &CliOptions{
+		CRD:      crd,
+		Config:   config,
+		Manifest: manifest,
+	}
+	var fromComponent, record, outputFormat, maxPayload string
+	var pretty bool
 	watchCmd := &cobra.Command{
-		Use:     "watch [broker]",
-		Short:   "Watch events flowing through the broker",
-		Example: "tmctl watch",
-		Args:    cobra.RangeArgs(0, 1),
+		Use:   "watch [broker][--from-component <name>][--record <file>][--pretty][--output json][--routes][--only-unrouted]",
+		Short: "Watch events flowing through the broker",
+		Example: `tmctl watch
+tmctl watch --pretty --max-payload 1k
+tmctl watch --routes
+tmctl watch --only-unrouted`,
+		Args: cobra.RangeArgs(0, 1),
 		ValidArgsFunction: func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
 			return []string{}, cobra.ShellCompDirectiveNoFileComp
 		},
@@ -61,12 +94,75 @@ func NewCmd(config *config.Config) *cobra.Command {
 			if len(args) != 0 {
 				o.Config.Context = args[0]
 			}
+			if err := o.setRender(pretty, outputFormat, maxPayload); err != nil {
+				return err
+			}
+			if o.onlyUnrouted {
+				o.routes = true
+			}
+			if fromComponent != "" {
+				if err := o.Manifest.Read(); err != nil {
+					return fmt.Errorf("reading manifest: %w", err)
+				}
+				return o.tap(fromComponent, record)
+			}
+			if record != "" {
+				return fmt.Errorf("--record requires --from-component")
+			}
+			if o.render != nil || o.routes {
+				return o.listen()
+			}
 			return o.watch()
 		},
 	}
+	watchCmd.Flags().StringVar(&fromComponent, "from-component", "", "Watch only the events produced by the component")
+	watchCmd.Flags().StringVar(&record, "record", "", "Save the watched events to the file")
+	watchCmd.Flags().BoolVar(&pretty, "pretty", false, "Print the event attributes header and the formatted payload")
+	watchCmd.Flags().StringVar(&maxPayload, "max-payload", "4k", "Truncate the payload printed in pretty mode at the given size")
+	watchCmd.Flags().StringVarP(&outputFormat, "output", "o", "", "Print the events in the given format. Supported value: json")
+	watchCmd.Flags().BoolVar(&o.routes, "routes", false, "Annotate the events with the triggers predicted to match them")
+	watchCmd.Flags().BoolVar(&o.onlyUnrouted, "only-unrouted", false, "Show only the events predicted to match no trigger")
+	cobra.CheckErr(watchCmd.RegisterFlagCompletionFunc("from-component", func(cmd *cobra.Command, args []string, _ string) ([]string, cobra.ShellCompDirective) {
+		return completion.Components(o.Manifest, completion.ListSources(o.Manifest)), cobra.ShellCompDirectiveNoFileComp
+	}))
 	return watchCmd
 }
 
+// setRender selects the way the events received by the listener are printed.
+func (o *CliOptions) setRender(pretty bool, outputFormat, maxPayload string) error {
+	switch outputFormat {
+	case "":
+	case "json":
+		if pretty {
+			return fmt.Errorf("--pretty and --output json are mutually exclusive")
+		}
+		o.jsonOutput = true
+		o.render = func(event cloudevents.Event) string {
+			data, err := json.Marshal(event)
+			if err != nil {
+				return fmt.Sprintf("marshal event %q: %v", event.ID(), err)
+			}
+			return string(data)
+		}
+		return nil
+	default:
+		return fmt.Errorf("unsupported output format %q", outputFormat)
+	}
+	if !pretty {
+		return nil
+	}
+	size, err := output.ParseSize(maxPayload)
+	if err != nil {
+		return fmt.Errorf("--max-payload: %w", err)
+	}
+	format := output.EventFormat{
+		MaxPayload: size,
+		Color:      term.IsTerminal(int(os.Stdout.Fd())),
+	}
+	o.render = format.Format
+	return nil
+}
+
 func (o *CliOptions) watch() error {
 	c := make(chan os.Signal, 1)
 	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
@@ -96,7 +192,7 @@ func (o *CliOptions) watch() error {
 		return fmt.Errorf("broker logs: %w", err)
 	}
 	log.Println("Watching...")
-	go listenBroker(brokerLogs, c)
+	go listenBroker(brokerLogs, c, nil)
 	go listenEvents(eventDisplayLogs, c)
 	go checkConnectivity(w.Destination, c)
 
@@ -105,47 +201,253 @@ func (o *CliOptions) watch() error {
 	return nil
 }
 
+// tap creates a temporary trigger for the component's event types
+// and displays the events received by the local listener.
+func (o *CliOptions) tap(name, record string) error {
+	component, err := components.GetObject(name, o.Config, o.Manifest, o.CRD)
+	if err != nil {
+		return fmt.Errorf("%q: %w", name, err)
+	}
+	producer, ok := component.(triggermesh.Producer)
+	if !ok {
+		return fmt.Errorf("%q is not an event producer", name)
+	}
+	eventTypes, err := producer.GetEventTypes()
+	if err != nil || len(eventTypes) == 0 {
+		return fmt.Errorf("%q does not expose event types", name)
+	}
+
+	// nil interface disables the recording
+	var recordFile io.Writer
+	if record != "" {
+		f, err := os.OpenFile(record, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+		if err != nil {
+			return fmt.Errorf("record file: %w", err)
+		}
+		defer f.Close()
+		recordFile = f
+	}
+
+	w, err := wiretap.New(o.Config.Context, o.Config.ConfigHome)
+	if err != nil {
+		return fmt.Errorf("wiretap: %w", err)
+	}
+	w.Name = fmt.Sprintf("wiretap-%s", name)
+	w.Filters = eventTypesFilter(eventTypes)
+	return o.receive(w, name+" events", recordFile)
+}
+
+// listen displays all events flowing through the broker
+// using the local listener instead of the event display container.
+func (o *CliOptions) listen() error {
+	w, err := wiretap.New(o.Config.Context, o.Config.ConfigHome)
+	if err != nil {
+		return fmt.Errorf("wiretap: %w", err)
+	}
+	return o.receive(w, "events", nil)
+}
+
+// receive creates the wiretap trigger pointing to the local listener
+// and prints the received events until interrupted.
+func (o *CliOptions) receive(w *wiretap.Wiretap, description string, recordFile io.Writer) error {
+	c := make(chan os.Signal, 1)
+	signal.Notify(c, os.Interrupt, syscall.SIGTERM)
+	defer close(c)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	render := o.render
+	if render == nil {
+		render = func(event cloudevents.Event) string { return event.String() }
+	}
+
+	events, err := w.Listen(ctx)
+	if err != nil {
+		return fmt.Errorf("listener: %w", err)
+	}
+	// trigger is removed on any exit path, including interruption and errors.
+	defer func() {
+		if err := w.Cleanup(context.Background()); err != nil {
+			log.Printf("Cleanup: %v", err)
+		}
+	}()
+	if err := w.CreateTrigger(); err != nil {
+		return fmt.Errorf("create trigger: %w", err)
+	}
+	brokerLogs, err := w.BrokerLogs(ctx, o.Config.Triggermesh.Broker)
+	if err != nil {
+		return fmt.Errorf("broker logs: %w", err)
+	}
+	log.Printf("Watching %s...", description)
+	brokerDone := make(chan os.Signal)
+	defer close(brokerDone)
+	brokerFailed := make(chan error, 1)
+	go listenBroker(brokerLogs, brokerDone, brokerFailed)
+
+	for {
+		select {
+		case <-c:
+			log.Println("Cleaning up")
+			return nil
+		case err := <-brokerFailed:
+			log.Println("Cleaning up")
+			return fmt.Errorf("broker: %w", err)
+		case event, ok := <-events:
+			if !ok {
+				return fmt.Errorf("listener stopped")
+			}
+			if !o.routes {
+				fmt.Println(render(event))
+			} else if hops := o.predict(w.Name, event); !o.onlyUnrouted || len(hops) == 0 {
+				fmt.Println(o.annotate(render(event), hops))
+			} else {
+				continue
+			}
+			if recordFile != nil {
+				if err := recordEvent(recordFile, event); err != nil {
+					return fmt.Errorf("record event: %w", err)
+				}
+			}
+		}
+	}
+}
+
+// predict evaluates the current triggers of the broker config, except the
+// wiretap one, against the received event. The broker does not report the
+// triggers it dispatched the event to, so the routes are predicted.
+func (o *CliOptions) predict(wiretap string, event cloudevents.Event) []route.Hop {
+	configuration, err := tmbroker.ReadConfig(o.Config.ConfigHome, o.Config.Context)
+	if err != nil {
+		log.Printf("Broker config: %v", err)
+		return nil
+	}
+	delete(configuration.Triggers, wiretap)
+	return route.Predict(configuration.Triggers, route.Observed(event))
+}
+
+// annotate appends the predicted routes to the printed event.
+func (o *CliOptions) annotate(printed string, hops []route.Hop) string {
+	if o.jsonOutput {
+		routes := make([]predictedRoute, 0, len(hops))
+		for _, hop := range hops {
+			routes = append(routes, predictedRoute{Trigger: hop.Trigger, Destination: hop.Destination})
+		}
+		data, err := json.Marshal(annotatedEvent{Event: json.RawMessage(printed), PredictedRoutes: routes})
+		if err != nil {
+			return printed
+		}
+		return string(data)
+	}
+	printed = strings.TrimRight(printed, "\n")
+	if len(hops) == 0 {
+		return printed + "\nPredicted routes: none, the event matches no trigger\n"
+	}
+	var b strings.Builder
+	b.WriteString(printed)
+	b.WriteString("\nPredicted routes:\n")
+	for _, hop := range hops {
+		fmt.Fprintf(&b, "  %s -> %s\n", hop.Trigger, hop.Destination)
+	}
+	return b.String()
+}
+
+type annotatedEvent struct {
+	Event           json.RawMessage  `json:"event"`
+	PredictedRoutes []predictedRoute `json:"predictedRoutes"`
+}
+
+type predictedRoute struct {
+	Trigger     string `json:"trigger"`
+	Destination string `json:"destination"`
+}
+
+func eventTypesFilter(eventTypes []string) []eventingbroker.Filter {
+	if len(eventTypes) == 1 {
+		return []eventingbroker.Filter{*tmbroker.FilterAttribute("type", eventTypes[0])}
+	}
+	var filters []eventingbroker.Filter
+	for _, et := range eventTypes {
+		filters = append(filters, *tmbroker.FilterAttribute("type", et))
+	}
+	return []eventingbroker.Filter{{Any: filters}}
+}
+
+func recordEvent(out io.Writer, event cloudevents.Event) error {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+	_, err = fmt.Fprintf(out, "%s\n", data)
+	return err
+}
+
 func listenEvents(output io.ReadCloser, done chan os.Signal) {
-	readLogs(output, done, func(data []byte) {
+	_ = readLogs(output, done, func(data []byte) error {
 		fmt.Println(string(data))
+		return nil
 	})
 }
 
-func listenBroker(output io.ReadCloser, done chan os.Signal) {
-	readLogs(output, done, func(data []byte) {
+// listenBroker prints the broker errors and configuration changes.
+// If the failed channel is set, the first broker error or the end
+// of the broker logs is sent to it and the listening stops.
+func listenBroker(output io.ReadCloser, done chan os.Signal, failed chan<- error) {
+	err := readLogs(output, done, func(data []byte) error {
 		var logItem brokerLog
 		if err := json.Unmarshal(data, &logItem); err != nil {
-			return
+			return nil
 		}
 		if logItem.Level == "error" {
 			fmt.Printf("❗ error: %s", logItem)
-			return
+			if failed != nil {
+				return fmt.Errorf("%s", logItem.Msg)
+			}
+			return nil
 		}
 		if logItem.Logger == "subs" {
 			fmt.Printf("🔧 configuration: %s: %s\n", logItem.Msg, logItem.Name)
 		}
+		return nil
 	})
+	if err == io.EOF {
+		err = fmt.Errorf("logs stream ended")
+	}
+	if err != nil && failed != nil {
+		failed <- err
+	}
 }
 
-func readLogs(output io.ReadCloser, done chan os.Signal, handler func([]byte)) {
+// readLogs passes the log lines to the handler until done is signaled,
+// the handler fails or the stream ends, in which case io.EOF is returned.
+func readLogs(output io.ReadCloser, done chan os.Signal, handler func([]byte) error) error {
 	scanner := bufio.NewScanner(output)
 	for scanner.Scan() {
 		select {
 		case <-done:
 			output.Close()
-			return
+			return nil
 		default:
 			log := scanner.Bytes()
 			if len(log) > 8 {
 				log = log[8:]
 			}
-			handler(log)
+			if err := handler(log); err != nil {
+				return err
+			}
 		}
 	}
+	if err := scanner.Err(); err != nil {
+		return err
+	}
+	return io.EOF
 }
 
 func checkConnectivity(destination string, done chan os.Signal) {
-	port := strings.TrimPrefix(destination, "http://host.docker.internal")
+	address := destination
+	if u, err := url.Parse(urls.New("").HostURL(destination)); err == nil {
+		address = u.Host
+	}
 	ticker := time.NewTicker(3 * time.Second)
 	defer ticker.Stop()
 	for {
@@ -153,7 +455,7 @@ func checkConnectivity(destination string, done chan os.Signal) {
 		case <-done:
 			return
 		case <-ticker.C:
-			if _, err := net.Dial("tcp", "localhost"+port); err != nil {
+			if _, err := net.Dial("tcp", address); err != nil {
 				log.Printf("Wiretap container is unreachable: %v", err)
 				done <- syscall.SIGTERM
 				return
@@ -0,0 +1,94 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package watch
+
+import (
+	"io"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// brokerLogLines returns the log stream in the multiplexed
+// Docker format with the 8 bytes header before every line.
+func brokerLogLines(lines ...string) io.ReadCloser {
+	var b strings.Builder
+	for _, line := range lines {
+		b.WriteString("\x01\x00\x00\x00\x00\x00\x00\x00")
+		b.WriteString(line)
+		b.WriteString("\n")
+	}
+	return io.NopCloser(strings.NewReader(b.String()))
+}
+
+func TestListenBroker(t *testing.T) {
+	testCases := map[string]struct {
+		logs io.ReadCloser
+		err  string
+	}{
+		"broker error": {
+			logs: brokerLogLines(
+				`{"level":"info","logger":"subs","msg":"Subscription added","name":"wiretap"}`,
+				`{"level":"error","logger":"broker","msg":"could not connect to redis"}`,
+				`{"level":"info","logger":"subs","msg":"Subscription removed","name":"wiretap"}`,
+			),
+			err: "could not connect to redis",
+		},
+		"logs stream ended": {
+			logs: brokerLogLines(
+				`{"level":"info","logger":"subs","msg":"Subscription added","name":"wiretap"}`,
+				`not JSON`,
+			),
+			err: "logs stream ended",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			failed := make(chan error, 1)
+			listenBroker(tc.logs, make(chan os.Signal), failed)
+			select {
+			case err := <-failed:
+				assert.EqualError(t, err, tc.err)
+			case <-time.After(time.Second):
+				t.Fatal("broker failure is not signaled")
+			}
+		})
+	}
+}
+
+func TestListenBrokerDone(t *testing.T) {
+	reader, writer := io.Pipe()
+	done := make(chan os.Signal)
+	failed := make(chan error, 1)
+	stopped := make(chan struct{})
+	go func() {
+		listenBroker(reader, done, failed)
+		close(stopped)
+	}()
+	close(done)
+	_, err := writer.Write([]byte("\x01\x00\x00\x00\x00\x00\x00\x00{\"level\":\"info\"}\n"))
+	assert.NoError(t, err)
+	select {
+	case <-stopped:
+	case <-time.After(time.Second):
+		t.Fatal("listener is not stopped")
+	}
+	assert.Empty(t, failed)
+}
@@ -0,0 +1,31 @@
+#!/bin/sh
+
+# Copyright 2022 TriggerMesh Inc.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Bundles the CRDs of the default TriggerMesh components version into
+# the archive embedded in the binary. The CRDs are downloaded from the
+# release page, the version can be overridden with the first argument.
+
+set -e
+
+cd "$(dirname "$0")/.."
+
+VERSION=${1:-$(sed -n 's/^[[:space:]]*defaultTmVersion[[:space:]]*= "\(.*\)"/\1/p' pkg/config/config.go)}
+OUT=pkg/triggermesh/crd/bundle
+
+curl -sSfL "https://github.com/triggermesh/triggermesh/releases/download/${VERSION}/triggermesh-crds.yaml" |
+  gzip -n -9 > "$OUT/triggermesh-crds.yaml.gz"
+
+echo "$VERSION" > "$OUT/VERSION"
@@ -18,6 +18,7 @@ package main
 
 import (
 	"github.com/triggermesh/tmctl/cmd"
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
 	"github.com/triggermesh/tmctl/pkg/log"
 )
 
@@ -27,7 +28,12 @@ var (
 )
 
 func main() {
-	if err := cmd.NewRootCommand(Version, Commit).Execute(); err != nil {
+	err := cmd.NewRootCommand(Version, Commit).Execute()
+	if err != nil {
+		lifecycle.Emit(lifecycle.Error, "", "", map[string]string{"error": err.Error()})
+	}
+	_ = lifecycle.Close()
+	if err != nil {
 		log.Fatal(err)
 	}
 }
@@ -0,0 +1,305 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bridge creates the components of the broker context and wires them
+// with the triggers. Builder operations take explicit parameters and never
+// interact with the terminal, so that the bridges can be built from Go code
+// as well as from the CLI commands.
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/triggermesh/tmctl/pkg/changeset"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/observed"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/ingest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/logging"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+// DefaultTimeout limits the Docker lookups if the builder has no timeout.
+const DefaultTimeout = 10 * time.Second
+
+// Origins of the event types of the sources filter.
+const (
+	EventTypesDeclared = "declared"
+	EventTypesObserved = "observed"
+)
+
+// Builder creates the components in the manifest of the broker context
+// and starts their containers.
+type Builder struct {
+	Config   *config.Config
+	Manifest *manifest.Manifest
+	CRD      map[string]crd.CRD
+
+	// Timeout limits the Docker lookups of the components.
+	Timeout time.Duration
+	// LogLevel is the requested adapter logging level,
+	// empty value keeps the level of the existing component.
+	LogLevel string
+	// AdapterVersion pins the adapter image version of the component,
+	// empty value keeps the pinned version or follows the context version.
+	AdapterVersion string
+	// EventTypesFrom selects the event types of the sources filter:
+	// declared by the component or observed at the broker.
+	EventTypesFrom string
+	// Progress receives the build steps and warnings, may be nil.
+	Progress func(message string)
+	// WaitReady confirms that the component is ready to receive events,
+	// the readiness probe of the component kind is used if nil.
+	WaitReady func(ctx context.Context, c triggermesh.Component) error
+	// DryRun makes Changes plan the build without applying it.
+	DryRun bool
+	// VerifyDestination probes the target of the trigger before writing it
+	// and checks that the target port is published by the target container.
+	VerifyDestination bool
+	// Strict fails the trigger creation if the destination verification
+	// fails, otherwise the failure is reported as the warning.
+	Strict bool
+	// TriggerName names the trigger created without the explicit name
+	// instead of the trigger name template of the context.
+	// Builds creating more than one such trigger are rejected.
+	TriggerName string
+	// ContentMode is the CloudEvents content mode of the created triggers,
+	// empty value keeps the mode of the existing trigger.
+	ContentMode string
+	// Transaction records the changes applied by the build so that the
+	// failed build can be rolled back, may be nil.
+	Transaction *Transaction
+
+	// changes are the planned changes of the dry run.
+	changes *changeset.Set
+}
+
+// Result describes the component created by the builder.
+type Result struct {
+	Component triggermesh.Component
+	// EventTypes are the event types the component is subscribed to.
+	EventTypes []string
+	// Warnings are the problems that did not prevent the component creation.
+	Warnings []string
+}
+
+func (b *Builder) progress(format string, args ...interface{}) {
+	if b.Progress != nil {
+		b.Progress(fmt.Sprintf(format, args...))
+	}
+}
+
+func (b *Builder) warn(result *Result, format string, args ...interface{}) {
+	warning := fmt.Sprintf(format, args...)
+	result.Warnings = append(result.Warnings, warning)
+	b.progress("WARNING! %s", warning)
+}
+
+// Lookup runs the Docker request of the build phase with the configured
+// timeout. The phase is named in the error to show where the build stopped.
+func (b *Builder) Lookup(phase string, request func(context.Context) error) error {
+	timeout := b.Timeout
+	if timeout <= 0 {
+		timeout = DefaultTimeout
+	}
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+	err := request(ctx)
+	switch {
+	case err == nil:
+		return nil
+	case errors.Is(err, docker.ErrNotResponding):
+		return fmt.Errorf("%s: docker not responding after %s", phase, timeout)
+	}
+	return fmt.Errorf("%s: %w", phase, err)
+}
+
+// LookupTarget returns the consumer component of the manifest
+// making sure that its container is running.
+func (b *Builder) LookupTarget(target string) (triggermesh.Component, error) {
+	targetObject, err := components.GetObject(target, b.Config, b.Manifest, b.CRD)
+	if err != nil {
+		return nil, fmt.Errorf("transformation target: %w", err)
+	}
+	if targetObject == nil {
+		return nil, fmt.Errorf("target %q: component not found", target)
+	}
+	if _, ok := targetObject.(triggermesh.Consumer); !ok {
+		return nil, fmt.Errorf("%q is not an event consumer", target)
+	}
+	runnable, ok := targetObject.(triggermesh.Runnable)
+	if !ok {
+		return targetObject, nil
+	}
+	return targetObject, b.Lookup(fmt.Sprintf("looking up target %q", target), func(ctx context.Context) error {
+		container, err := runnable.Info(ctx)
+		if err != nil {
+			return err
+		}
+		if !container.Online {
+			return fmt.Errorf("container not running, use \"tmctl start\" to start it")
+		}
+		return nil
+	})
+}
+
+// BrokerURL returns the URL the components send the events to:
+// the broker ingest proxy, if any, or the broker container.
+func (b *Builder) BrokerURL() (string, error) {
+	broker, err := tmbroker.New(b.Config.Context, b.Config.Triggermesh.Broker)
+	if err != nil {
+		return "", fmt.Errorf("broker object: %v", err)
+	}
+	// the events are sent through the proxy enforcing the ingest limits
+	entrypoint := ingest.Entrypoint{Broker: b.Config.Context, Component: broker.(triggermesh.Consumer)}
+	var url string
+	if err := b.Lookup("resolving broker port", func(ctx context.Context) (err error) {
+		url, err = b.Config.URLs().BrokerIngestURL(ctx, entrypoint, urls.ContainerNetwork)
+		return err
+	}); err != nil {
+		return "", fmt.Errorf("broker offline: %w", err)
+	}
+	return url, nil
+}
+
+// SourcesEventTypes returns the event types produced by the source components
+// selected by their names or kinds, without duplicates.
+func (b *Builder) SourcesEventTypes(sources []string) ([]string, error) {
+	sources, err := b.ExpandSources(sources)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, source := range sources {
+		s, err := components.GetObject(source, b.Config, b.Manifest, b.CRD)
+		if err != nil {
+			return nil, fmt.Errorf("%q event producer object: %w", source, err)
+		}
+		if _, ok := s.(triggermesh.Producer); !ok {
+			return nil, fmt.Errorf("%q is not an event producer", source)
+		}
+		if b.EventTypesFrom == EventTypesObserved {
+			et, err := b.observedEventTypes(source)
+			if err != nil {
+				return nil, err
+			}
+			result = appendUnique(result, et...)
+			continue
+		}
+		et, err := s.(triggermesh.Producer).GetEventTypes()
+		if err != nil {
+			return nil, fmt.Errorf("%q event source: %w", source, err)
+		}
+		result = appendUnique(result, et...)
+	}
+	return result, nil
+}
+
+func appendUnique(list []string, items ...string) []string {
+	for _, item := range items {
+		exists := false
+		for _, i := range list {
+			if i == item {
+				exists = true
+				break
+			}
+		}
+		if !exists {
+			list = append(list, item)
+		}
+	}
+	return list
+}
+
+func (b *Builder) observedEventTypes(source string) ([]string, error) {
+	types, err := observed.Load(b.Config.ConfigHome, b.Config.Context)
+	if err != nil {
+		return nil, fmt.Errorf("observed types: %w", err)
+	}
+	et := types.Of(source)
+	if len(et) == 0 {
+		return nil, fmt.Errorf("no event types observed from %q, run \"tmctl stats observe\" while the source is producing events", source)
+	}
+	return et, nil
+}
+
+// OutputEventType returns the event type produced by the transformation
+// and its origin: the explicitly requested type or the output type template.
+func (b *Builder) OutputEventType(name, kind, eventType string) (string, string, error) {
+	if eventType != "" {
+		return eventType, triggermesh.EventTypeFromFlag, nil
+	}
+	eventType, err := b.Config.OutputType(name, kind)
+	if err != nil {
+		return "", "", err
+	}
+	return eventType, triggermesh.EventTypeFromTemplate, nil
+}
+
+// LoggingEnv records the requested logging level in the component annotation
+// and adds the logger configuration to the container environment.
+// The returned flag is true if the level has changed and the container
+// must be restarted to apply it.
+func (b *Builder) LoggingEnv(c triggermesh.Component, env map[string]string) (map[string]string, bool, error) {
+	var current string
+	for _, object := range b.Manifest.Objects {
+		if object.Metadata.Name == c.GetName() && strings.EqualFold(object.Kind, c.GetKind()) {
+			current = logging.Level(object)
+			break
+		}
+	}
+	if b.LogLevel == "" || b.LogLevel == current {
+		return logging.Env(current, env), false, nil
+	}
+	if err := b.Manifest.Annotate(c.GetName(), c.GetKind(), triggermesh.LogLevelAnnotation, b.LogLevel); err != nil {
+		return nil, false, fmt.Errorf("unable to update manifest: %w", err)
+	}
+	return logging.Env(b.LogLevel, env), true, nil
+}
+
+// ComponentVersion returns the adapter version of the component being created:
+// the requested version, the version pinned in the manifest or the context version.
+func (b *Builder) ComponentVersion(c triggermesh.Component) string {
+	if b.AdapterVersion != "" {
+		return b.AdapterVersion
+	}
+	for _, object := range b.Manifest.Objects {
+		if object.Metadata.Name == c.GetName() && strings.EqualFold(object.Kind, c.GetKind()) {
+			return components.AdapterVersion(object, b.Config.Triggermesh.ComponentsVersion)
+		}
+	}
+	return b.Config.Triggermesh.ComponentsVersion
+}
+
+// PinVersion records the requested adapter version in the component annotation.
+func (b *Builder) PinVersion(c triggermesh.Component) error {
+	if b.AdapterVersion == "" {
+		return nil
+	}
+	if err := b.Manifest.Annotate(c.GetName(), c.GetKind(), triggermesh.AdapterVersionAnnotation, b.AdapterVersion); err != nil {
+		return fmt.Errorf("unable to update manifest: %w", err)
+	}
+	return nil
+}
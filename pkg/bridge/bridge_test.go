@@ -0,0 +1,1216 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/changeset"
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/target"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/test"
+)
+
+const version = "v1.21.1"
+
+type portBinding struct {
+	HostIP   string `json:"HostIp"`
+	HostPort string `json:"HostPort"`
+}
+
+type fakeContainer struct {
+	ID           string
+	Name         string
+	Image        string
+	PortBindings map[string][]portBinding
+	NetworkMode  string
+	Aliases      []string
+	Labels       map[string]string
+}
+
+// fakeDocker is the Docker daemon keeping the containers in memory.
+type fakeDocker struct {
+	sync.Mutex
+	containers map[string]*fakeContainer
+	networks   []string
+	nextPort   int
+	// failCreate are the names of the containers failing to be created.
+	failCreate map[string]bool
+}
+
+var containerPath = regexp.MustCompile(`^/containers/([^/]+)(/[a-z]+)?$`)
+
+func newFakeDocker(t *testing.T, running ...fakeContainer) *fakeDocker {
+	d := &fakeDocker{
+		containers: make(map[string]*fakeContainer),
+		nextPort:   40000,
+	}
+	for i := range running {
+		// unlabeled containers are not created by tmctl
+		if running[i].Labels == nil {
+			running[i].Labels = map[string]string{docker.ComponentLabel: running[i].Name}
+		}
+		d.containers[running[i].ID] = &running[i]
+	}
+	daemon := httptest.NewServer(http.HandlerFunc(d.serve))
+	t.Cleanup(daemon.Close)
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+	return d
+}
+
+func (d *fakeDocker) serve(w http.ResponseWriter, r *http.Request) {
+	d.Lock()
+	defer d.Unlock()
+	path := regexp.MustCompile(`^/v[0-9.]+`).ReplaceAllString(r.URL.Path, "")
+	w.Header().Set("Content-Type", "application/json")
+	switch {
+	case path == "/_ping":
+		w.Header().Set("API-Version", "1.41")
+		fmt.Fprint(w, "OK")
+	case path == "/images/create":
+		fmt.Fprint(w, "{}")
+	case path == "/containers/json":
+		var list []map[string]interface{}
+		for _, c := range d.containers {
+			var ports []map[string]interface{}
+			for _, bindings := range c.PortBindings {
+				for _, binding := range bindings {
+					port, _ := strconv.Atoi(binding.HostPort)
+					ports = append(ports, map[string]interface{}{"PrivatePort": 8080, "PublicPort": port, "Type": "tcp"})
+				}
+			}
+			list = append(list, map[string]interface{}{"Id": c.ID, "Names": []string{"/" + c.Name}, "Ports": ports, "Labels": c.Labels,
+				"HostConfig": map[string]interface{}{"NetworkMode": c.NetworkMode}})
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	case path == "/networks":
+		var list []map[string]interface{}
+		for _, name := range d.networks {
+			list = append(list, map[string]interface{}{"Id": name, "Name": name})
+		}
+		_ = json.NewEncoder(w).Encode(list)
+	case path == "/networks/create":
+		var request struct{ Name string }
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		d.networks = append(d.networks, request.Name)
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id":%q}`, request.Name)
+	case path == "/containers/create":
+		var request struct {
+			Image      string
+			Labels     map[string]string
+			HostConfig struct {
+				PortBindings map[string][]portBinding
+				NetworkMode  string
+			}
+			NetworkingConfig struct {
+				EndpointsConfig map[string]struct{ Aliases []string }
+			}
+		}
+		_ = json.NewDecoder(r.Body).Decode(&request)
+		for port, bindings := range request.HostConfig.PortBindings {
+			for i := range bindings {
+				if bindings[i].HostPort == "" {
+					d.nextPort++
+					bindings[i].HostPort = fmt.Sprint(d.nextPort)
+				}
+			}
+			request.HostConfig.PortBindings[port] = bindings
+		}
+		name := r.URL.Query().Get("name")
+		if d.failCreate[name] {
+			w.WriteHeader(http.StatusInternalServerError)
+			fmt.Fprint(w, `{"message":"injected failure"}`)
+			return
+		}
+		d.containers[name] = &fakeContainer{ID: name, Name: name, Image: request.Image, PortBindings: request.HostConfig.PortBindings,
+			NetworkMode: request.HostConfig.NetworkMode, Aliases: request.NetworkingConfig.EndpointsConfig[request.HostConfig.NetworkMode].Aliases,
+			Labels: request.Labels}
+		w.WriteHeader(http.StatusCreated)
+		fmt.Fprintf(w, `{"Id":%q}`, name)
+	default:
+		match := containerPath.FindStringSubmatch(path)
+		if match == nil {
+			w.WriteHeader(http.StatusNotFound)
+			return
+		}
+		c, exists := d.containers[match[1]]
+		if !exists {
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"no such container"}`)
+			return
+		}
+		switch {
+		case r.Method == http.MethodDelete:
+			delete(d.containers, c.ID)
+			w.WriteHeader(http.StatusNoContent)
+		case match[2] == "/json":
+			_ = json.NewEncoder(w).Encode(map[string]interface{}{
+				"Id":         c.ID,
+				"Name":       "/" + c.Name,
+				"State":      map[string]interface{}{"Running": true, "Status": "running"},
+				"Config":     map[string]interface{}{"Image": c.Image},
+				"HostConfig": map[string]interface{}{"PortBindings": c.PortBindings, "NetworkMode": c.NetworkMode},
+			})
+		case match[2] == "/start":
+			w.WriteHeader(http.StatusNoContent)
+		case match[2] == "/logs":
+			w.Header().Set("Content-Type", "application/vnd.docker.raw-stream")
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}
+}
+
+func (d *fakeDocker) running(name string) bool {
+	d.Lock()
+	defer d.Unlock()
+	_, exists := d.containers[name]
+	return exists
+}
+
+// newBuilder returns the builder of the "foo" broker context
+// copied from the fixtures to the temporary directory.
+func newBuilder(t *testing.T) (*Builder, *[]string) {
+	configBase := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configBase, "foo"), os.ModePerm))
+	for _, file := range []string{triggermesh.ManifestFile, triggermesh.BrokerConfigFile} {
+		source := test.Manifest()
+		if file == triggermesh.BrokerConfigFile {
+			source = filepath.Join(test.ConfigBase(), "broker.conf")
+		}
+		data, err := os.ReadFile(source)
+		require.NoError(t, err)
+		require.NoError(t, os.WriteFile(filepath.Join(configBase, "foo", file), data, 0600))
+	}
+	m := manifest.New(filepath.Join(configBase, "foo", triggermesh.ManifestFile))
+	require.NoError(t, m.Read())
+
+	var messages []string
+	return &Builder{
+		Config: &config.Config{
+			Context:     "foo",
+			ConfigHome:  configBase,
+			Triggermesh: config.TmConfig{ComponentsVersion: version},
+		},
+		Manifest: m,
+		CRD:      test.CRD(),
+		Progress: func(message string) {
+			messages = append(messages, message)
+		},
+	}, &messages
+}
+
+func TestAddTransformation(t *testing.T) {
+	docker := newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+		NetworkMode:  "tmctl-foo",
+	}, fakeContainer{
+		ID:          "foo-broker",
+		Name:        "foo-broker",
+		NetworkMode: "tmctl-foo",
+	})
+	b, messages := newBuilder(t)
+
+	result, err := b.AddTransformation(context.Background(), TransformationSpec{
+		Name:       "bar-transformation",
+		Spec:       []byte("data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n"),
+		Target:     "sockeye",
+		EventTypes: []string{"com.amazon.s3.objectcreated"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "bar-transformation", result.Component.GetName())
+	assert.Equal(t, []string{"com.amazon.s3.objectcreated"}, result.EventTypes)
+	assert.Empty(t, result.Warnings)
+	assert.Contains(t, *messages, "Starting container")
+	assert.True(t, docker.running("bar-transformation"))
+	assert.Equal(t, "tmctl-foo", docker.containers["bar-transformation"].NetworkMode)
+	assert.Equal(t, []string{"bar-transformation", "bar-transformation.foo.local"}, docker.containers["bar-transformation"].Aliases)
+
+	routes := make(map[string]string)
+	configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+	require.NoError(t, err)
+	for _, trigger := range configuration.Triggers {
+		routes[trigger.Filters[0].Exact["type"]+" "+trigger.Target.Component] = trigger.Target.URL
+	}
+	// the broker reaches the components by their names in the context network
+	assert.Equal(t, "http://bar-transformation:8080", routes["com.amazon.s3.objectcreated bar-transformation"])
+	assert.Equal(t, "http://sockeye:8080", routes["bar-transformation.output sockeye"])
+
+	m := manifest.New(b.Manifest.Path)
+	require.NoError(t, m.Read())
+	var annotated bool
+	var refKinds []string
+	for _, object := range m.Objects {
+		if object.Metadata.Name == "bar-transformation" {
+			annotated = object.Metadata.Annotations[triggermesh.EventTypeOriginAnnotation] == triggermesh.EventTypeFromTemplate
+		}
+		if object.Kind == tmbroker.TriggerKind {
+			if name, _, _ := unstructured.NestedString(object.Spec, "target", "ref", "name"); name == "bar-transformation" {
+				kind, _, _ := unstructured.NestedString(object.Spec, "target", "ref", "kind")
+				refKinds = append(refKinds, kind)
+			}
+		}
+	}
+	assert.True(t, annotated)
+	// the reference uses the kind of the manifest object
+	assert.Equal(t, []string{"Transformation"}, refKinds)
+}
+
+func TestAddTransformationEngines(t *testing.T) {
+	engines := map[string]TransformationSpec{
+		transformation.EngineBumblebee: {
+			Spec: []byte("data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n"),
+		},
+		transformation.EngineJQ: {
+			Engine: transformation.EngineJQ,
+			Spec:   []byte(".foo = \"bar\""),
+		},
+	}
+	brokerConfigs := make(map[string]map[string]tmbroker.LocalTriggerSpec)
+	for engine, spec := range engines {
+		docker := newFakeDocker(t, fakeContainer{
+			ID:           "sockeye",
+			Name:         "sockeye",
+			Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+			PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+			NetworkMode:  "tmctl-foo",
+		}, fakeContainer{
+			ID:           "foo-broker",
+			Name:         "foo-broker",
+			PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59853"}}},
+			NetworkMode:  "tmctl-foo",
+		})
+		b, _ := newBuilder(t)
+		spec.Name = "bar-transformation"
+		spec.Target = "sockeye"
+		spec.EventTypes = []string{"com.amazon.s3.objectcreated"}
+		_, err := b.AddTransformation(context.Background(), spec)
+		require.NoError(t, err, engine)
+
+		configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+		require.NoError(t, err)
+		brokerConfigs[engine] = configuration.Triggers
+
+		if engine == transformation.EngineJQ {
+			stage := transformation.OutputStage("bar-transformation")
+			assert.True(t, docker.running(stage))
+			stageObject := objectNamed(t, b.Manifest, stage)
+			assert.Equal(t, "bar-transformation", stageObject.Metadata.Annotations[triggermesh.OwnerAnnotation])
+			jq := objectNamed(t, b.Manifest, "bar-transformation")
+			sink, _, _ := unstructured.NestedString(jq.Spec, "sink", "ref", "name")
+			assert.Equal(t, stage, sink)
+		}
+	}
+	// the output of both engines goes through the broker
+	// with the same subscriptions and the same output type
+	assert.Equal(t, brokerConfigs[transformation.EngineBumblebee], brokerConfigs[transformation.EngineJQ])
+	var routes []string
+	for _, trigger := range brokerConfigs[transformation.EngineJQ] {
+		routes = append(routes, trigger.Filters[0].Exact["type"]+" "+trigger.Target.Component)
+	}
+	assert.Contains(t, routes, "com.amazon.s3.objectcreated bar-transformation")
+	assert.Contains(t, routes, "bar-transformation.output sockeye")
+}
+
+func TestAddTransformationPlan(t *testing.T) {
+	docker := newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+		NetworkMode:  "tmctl-foo",
+	}, fakeContainer{
+		ID:          "foo-broker",
+		Name:        "foo-broker",
+		NetworkMode: "tmctl-foo",
+	})
+	b, _ := newBuilder(t)
+	manifestBefore, err := os.ReadFile(b.Manifest.Path)
+	require.NoError(t, err)
+	build := func(b *Builder) error {
+		_, err := b.AddTransformation(context.Background(), TransformationSpec{
+			Name:       "bar-transformation",
+			Spec:       []byte("data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n"),
+			Target:     "sockeye",
+			EventTypes: []string{"com.amazon.s3.objectcreated"},
+		})
+		return err
+	}
+
+	b.DryRun = true
+	plan, err := b.Changes(build)
+	require.NoError(t, err)
+	assert.False(t, docker.running("bar-transformation"))
+	manifestAfter, err := os.ReadFile(b.Manifest.Path)
+	require.NoError(t, err)
+	assert.Equal(t, string(manifestBefore), string(manifestAfter))
+
+	b.DryRun = false
+	applied, err := b.Changes(build)
+	require.NoError(t, err)
+	assert.True(t, docker.running("bar-transformation"))
+	assert.Contains(t, applied.Changes(), changeset.Change{Object: "bar-transformation", Kind: "Transformation", Action: changeset.Created})
+	assert.Contains(t, applied.Changes(), changeset.Change{Object: "bar-transformation", Kind: changeset.ContainerKind, Action: changeset.Started})
+	// the plan matches the changes made by the build
+	assert.Equal(t, plan.Changes(), applied.Changes())
+
+	// nothing changes when the build is repeated
+	again, err := b.Changes(build)
+	require.NoError(t, err)
+	assert.True(t, again.Empty())
+}
+
+func TestSyncTargetPort(t *testing.T) {
+	// Docker published the different port after the container restart
+	newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "61000"}}},
+	})
+	b, messages := newBuilder(t)
+
+	sockeye, err := b.LookupTarget("sockeye")
+	require.NoError(t, err)
+	container, err := sockeye.(triggermesh.Runnable).Info(context.Background())
+	require.NoError(t, err)
+
+	fixes, err := b.SyncTargetPort(sockeye, container)
+	require.NoError(t, err)
+	assert.Equal(t, []tmbroker.PortFix{{
+		Trigger: "foo-trigger-9dad7875",
+		From:    "http://host.docker.internal:59852",
+		To:      "http://host.docker.internal:61000",
+	}}, fixes)
+	assert.Contains(t, *messages, `Trigger "foo-trigger-9dad7875" pointed to http://host.docker.internal:61000 instead of the stale http://host.docker.internal:59852`)
+
+	configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "http://host.docker.internal:61000", configuration.Triggers["foo-trigger-9dad7875"].Target.URL)
+	assert.Equal(t, "sockeye", configuration.Triggers["foo-trigger-9dad7875"].Target.Component)
+	// triggers of the other components are not changed
+	assert.Equal(t, "http://host.docker.internal:59944", configuration.Triggers["foo-trigger-6ada801c"].Target.URL)
+
+	m := manifest.New(b.Manifest.Path)
+	require.NoError(t, m.Read())
+	for _, object := range m.Objects {
+		if object.Metadata.Name == "sockeye" {
+			assert.Equal(t, "61000", object.Metadata.Annotations[triggermesh.HostPortAnnotation])
+		}
+	}
+
+	// the consistent config is not changed
+	fixes, err = b.SyncTargetPort(sockeye, container)
+	require.NoError(t, err)
+	assert.Empty(t, fixes)
+}
+
+func TestReplyEventType(t *testing.T) {
+	crds := test.CRD()
+	defaultType := target.New("foo-httptarget", "http", "foo", version, crds["httptarget"], map[string]string{
+		"endpoint": "https://example.com",
+		"method":   "GET",
+	})
+	eventType, err := ReplyEventType(defaultType, "")
+	require.NoError(t, err)
+	assert.Equal(t, "io.triggermesh.targets.response", eventType)
+	_, err = ReplyEventType(defaultType, "api.response")
+	assert.Error(t, err)
+
+	declared := target.New("foo-httptarget", "http", "foo", version, crds["httptarget"], map[string]string{
+		"endpoint":           "https://example.com",
+		"method":             "GET",
+		"response.eventType": "api.response",
+	})
+	eventType, err = ReplyEventType(declared, "")
+	require.NoError(t, err)
+	assert.Equal(t, "api.response", eventType)
+
+	var anyTypeCRD crd.CRD
+	anyTypeCRD.Metadata.Annotations.ProducedEventTypes = `[{"type": "*"}]`
+	anyType := target.New("foo-echotarget", "echo", "foo", version, anyTypeCRD, map[string]string{})
+	_, err = ReplyEventType(anyType, "")
+	assert.EqualError(t, err, "reply event type is required, echotarget replies with *")
+	eventType, err = ReplyEventType(anyType, "api.response")
+	require.NoError(t, err)
+	assert.Equal(t, "api.response", eventType)
+
+	silent := target.New("foo-silenttarget", "silent", "foo", version, crd.CRD{}, map[string]string{})
+	_, err = ReplyEventType(silent, "api.response")
+	assert.EqualError(t, err, "silenttarget declares no response event types")
+}
+
+func TestAddReply(t *testing.T) {
+	newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+	})
+	b, _ := newBuilder(t)
+	httpTarget := target.New("foo-httptarget", "http", "foo", version, test.CRD()["httptarget"], map[string]string{
+		"endpoint":           "https://example.com",
+		"method":             "GET",
+		"response.eventType": "api.response",
+	})
+	_, err := b.Manifest.Apply(httpTarget)
+	require.NoError(t, err)
+
+	_, err = b.AddReply(httpTarget, "foo-httptarget", "")
+	assert.Error(t, err)
+
+	trigger, err := b.AddReply(httpTarget, "sockeye", "")
+	require.NoError(t, err)
+	assert.Equal(t, "sockeye", trigger.(*tmbroker.Trigger).TargetName())
+	assert.Equal(t, "api.response", trigger.(*tmbroker.Trigger).Filters[0].Exact["type"])
+
+	for _, object := range b.Manifest.Objects {
+		if object.Metadata.Name == "foo-httptarget" {
+			assert.Equal(t, "sockeye", object.Metadata.Annotations[triggermesh.ReplyToAnnotation])
+			assert.Equal(t, "api.response", object.Metadata.Annotations[triggermesh.ReplyEventTypeAnnotation])
+		}
+	}
+}
+
+func TestAddTriggerVerifyDestination(t *testing.T) {
+	destination := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	port := strings.TrimPrefix(destination.URL, "http://127.0.0.1:")
+	sockeye := fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: port}}},
+	}
+	filter := tmbroker.FilterAttribute("type", "verify.test")
+
+	newFakeDocker(t, sockeye)
+	b, messages := newBuilder(t)
+	b.VerifyDestination = true
+	target, err := b.LookupTarget("sockeye")
+	require.NoError(t, err)
+	_, err = b.AddTrigger("verified", target, "", filter)
+	require.NoError(t, err)
+	assert.Empty(t, *messages)
+
+	// the port is published by the container of another component
+	sockeye.Labels = map[string]string{docker.ComponentLabel: "bar"}
+	newFakeDocker(t, sockeye)
+	b, messages = newBuilder(t)
+	b.VerifyDestination = true
+	_, err = b.AddTrigger("verified", target, "", filter)
+	require.NoError(t, err)
+	assert.Equal(t, []string{fmt.Sprintf(`WARNING! Trigger "verified" destination: port %s belongs to "bar" rather than "sockeye"`, port)}, *messages)
+
+	// strict verification does not write the trigger of the unresponsive target
+	destination.Close()
+	sockeye.Labels = nil
+	newFakeDocker(t, sockeye)
+	b, _ = newBuilder(t)
+	b.VerifyDestination = true
+	b.Strict = true
+	_, err = b.AddTrigger("verified", target, "", filter)
+	assert.ErrorContains(t, err, `"sockeye" does not respond at port `+port)
+	configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+	require.NoError(t, err)
+	assert.NotContains(t, configuration.Triggers, "verified")
+}
+
+func TestAddTriggerName(t *testing.T) {
+	newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+	})
+	b, _ := newBuilder(t)
+	sockeye, err := b.LookupTarget("sockeye")
+	require.NoError(t, err)
+
+	b.Config.TriggerNameTemplates = map[string]string{"foo": "{{.Target}}-{{.FilterHash}}"}
+	filter := tmbroker.FilterAttribute("type", "order.created")
+	trigger, err := b.AddTrigger("", sockeye, "", filter)
+	require.NoError(t, err)
+	assert.Equal(t, "sockeye-"+tmbroker.FilterHash("sockeye", "", filter), trigger.GetName())
+	// the same route is reused
+	trigger, err = b.AddTrigger("", sockeye, "", filter)
+	require.NoError(t, err)
+	assert.Equal(t, "sockeye-"+tmbroker.FilterHash("sockeye", "", filter), trigger.GetName())
+
+	b.Config.TriggerNameTemplates["foo"] = "{{.EventType}}"
+	_, err = b.AddTrigger("", sockeye, "", tmbroker.FilterAttribute("type", "order.updated"))
+	assert.ErrorContains(t, err, `invalid name "order.updated"`)
+
+	b.TriggerName = "orders"
+	trigger, err = b.AddTrigger("", sockeye, "", tmbroker.FilterAttribute("type", "order.updated"))
+	require.NoError(t, err)
+	assert.Equal(t, "orders", trigger.GetName())
+	_, err = b.AddTrigger("", sockeye, "", tmbroker.FilterAttribute("type", "order.deleted"))
+	assert.EqualError(t, err, `trigger "orders" already exists with another target, path or filter`)
+
+	assert.NoError(t, b.CheckTriggerName(1))
+	assert.Error(t, b.CheckTriggerName(2))
+	b.TriggerName = ""
+	assert.NoError(t, b.CheckTriggerName(2))
+}
+
+func TestAddTriggerReuse(t *testing.T) {
+	newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+	})
+	b, messages := newBuilder(t)
+	sockeye, err := b.LookupTarget("sockeye")
+	require.NoError(t, err)
+
+	for i := 0; i < 3; i++ {
+		for _, filter := range []*eventingbroker.Filter{
+			tmbroker.FilterAttribute("type", "order.created"),
+			{Prefix: map[string]string{"type": "order."}},
+			nil,
+		} {
+			_, err := b.AddTrigger("", sockeye, "", filter)
+			require.NoError(t, err)
+		}
+	}
+	// the route of the fixture trigger is reused under its name
+	trigger, err := b.AddTrigger("", sockeye, "", tmbroker.FilterAttribute("type", "foo-transformation.output"))
+	require.NoError(t, err)
+	assert.Equal(t, "foo-trigger-9dad7875", trigger.GetName())
+	configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+	require.NoError(t, err)
+	assert.Len(t, configuration.Triggers, 5)
+
+	// the requested name is kept and the duplicate route is reported
+	*messages = nil
+	trigger, err = b.AddTrigger("my-trigger", sockeye, "", tmbroker.FilterAttribute("type", "foo-transformation.output"))
+	require.NoError(t, err)
+	assert.Equal(t, "my-trigger", trigger.GetName())
+	assert.Equal(t, []string{`WARNING! Trigger "foo-trigger-9dad7875" has the same filters, "sockeye" receives the events of both triggers`}, *messages)
+	configuration, err = tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+	require.NoError(t, err)
+	assert.Contains(t, configuration.Triggers, "my-trigger")
+	assert.Contains(t, configuration.Triggers, "foo-trigger-9dad7875")
+}
+
+func TestAddTriggerPaths(t *testing.T) {
+	newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+	})
+	b, _ := newBuilder(t)
+	sockeye, err := b.LookupTarget("sockeye")
+	require.NoError(t, err)
+
+	filter := tmbroker.FilterAttribute("type", "order.created")
+	a, err := b.AddTrigger("", sockeye, "/a", filter)
+	require.NoError(t, err)
+	bTrigger, err := b.AddTrigger("", sockeye, "/b", filter)
+	require.NoError(t, err)
+	assert.NotEqual(t, a.GetName(), bTrigger.GetName())
+
+	// the route to the same path is reused
+	again, err := b.AddTrigger("", sockeye, "/a/", filter)
+	require.NoError(t, err)
+	assert.Equal(t, a.GetName(), again.GetName())
+
+	configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "/a", configuration.Triggers[a.GetName()].Target.Path())
+	assert.Equal(t, "/b", configuration.Triggers[bTrigger.GetName()].Target.Path())
+
+	// the implicit name of the other path is the conflict
+	b.TriggerName = a.GetName()
+	_, err = b.AddTrigger("", sockeye, "/c", filter)
+	assert.EqualError(t, err, fmt.Sprintf("trigger %q already exists with another target, path or filter", a.GetName()))
+}
+
+func TestAddTriggerContentMode(t *testing.T) {
+	docker := newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+		NetworkMode:  "tmctl-foo",
+	}, fakeContainer{
+		ID:          "foo-broker",
+		Name:        "foo-broker",
+		NetworkMode: "tmctl-foo",
+	})
+	b, _ := newBuilder(t)
+	sockeye, err := b.LookupTarget("sockeye")
+	require.NoError(t, err)
+
+	b.ContentMode = tmbroker.ContentModeStructured
+	_, err = b.AddTrigger("orders", sockeye, "", tmbroker.FilterAttribute("type", "order.created"))
+	require.NoError(t, err)
+	configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+	require.NoError(t, err)
+	target := configuration.Triggers["orders"].Target
+	assert.Equal(t, "http://foo-structured-proxy:8080/?target=http%3A%2F%2Fsockeye%3A8080", target.URL)
+	assert.Equal(t, tmbroker.ContentModeStructured, target.ContentMode)
+	assert.Equal(t, "http://sockeye:8080", target.TargetURL())
+	assert.True(t, docker.running("foo-structured-proxy"))
+	assert.Equal(t, "tmctl-foo", docker.containers["foo-structured-proxy"].NetworkMode)
+	var annotation string
+	for _, object := range b.Manifest.Objects {
+		if object.Metadata.Name == "orders" {
+			annotation = object.Metadata.Annotations[tmbroker.ContentModeAnnotation]
+		}
+	}
+	assert.Equal(t, tmbroker.ContentModeStructured, annotation)
+
+	// the existing trigger keeps its mode
+	b.ContentMode = ""
+	_, err = b.AddTrigger("orders", sockeye, "", tmbroker.FilterAttribute("type", "order.created"))
+	require.NoError(t, err)
+	configuration, err = tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, tmbroker.ContentModeStructured, configuration.Triggers["orders"].Target.ContentMode)
+
+	b.ContentMode = tmbroker.ContentModeBinary
+	_, err = b.AddTrigger("orders", sockeye, "", tmbroker.FilterAttribute("type", "order.created"))
+	require.NoError(t, err)
+	configuration, err = tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, tmbroker.LocalTarget{URL: "http://sockeye:8080", Component: "sockeye"}, configuration.Triggers["orders"].Target)
+}
+
+func TestAddTransformationLegacyNetwork(t *testing.T) {
+	// containers started before the context network was introduced
+	docker := newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+	}, fakeContainer{
+		ID:   "foo-broker",
+		Name: "foo-broker",
+	})
+	b, _ := newBuilder(t)
+
+	_, err := b.AddTransformation(context.Background(), TransformationSpec{
+		Name:       "bar-transformation",
+		Spec:       []byte("data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n"),
+		Target:     "sockeye",
+		EventTypes: []string{"com.amazon.s3.objectcreated"},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"tmctl-foo"}, docker.networks)
+
+	configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+	require.NoError(t, err)
+	for _, trigger := range configuration.Triggers {
+		if trigger.Target.Component == "sockeye" {
+			// the broker is not in the network until the context is started again
+			assert.Equal(t, "http://host.docker.internal:59852", trigger.Target.URL)
+		}
+	}
+}
+
+func TestAddTransformationPinnedVersion(t *testing.T) {
+	docker := newFakeDocker(t)
+	b, _ := newBuilder(t)
+	b.AdapterVersion = "v1.20.0"
+
+	spec := TransformationSpec{
+		Name: "bar-transformation",
+		Spec: []byte("data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n"),
+	}
+	_, err := b.AddTransformation(context.Background(), spec)
+	require.NoError(t, err)
+	assert.True(t, strings.HasSuffix(docker.containers["bar-transformation"].Image, ":v1.20.0"))
+
+	// pinned version is kept when the component is updated without the request
+	b.AdapterVersion = ""
+	result, err := b.AddTransformation(context.Background(), spec)
+	require.NoError(t, err)
+	assert.Equal(t, "v1.20.0", result.Component.(triggermesh.Versioned).GetVersion())
+
+	m := manifest.New(b.Manifest.Path)
+	require.NoError(t, m.Read())
+	var pinned string
+	for _, object := range m.Objects {
+		if object.Metadata.Name == "bar-transformation" {
+			pinned = object.Metadata.Annotations[triggermesh.AdapterVersionAnnotation]
+		}
+	}
+	assert.Equal(t, "v1.20.0", pinned)
+}
+
+func TestAddTransformationErrors(t *testing.T) {
+	newFakeDocker(t)
+	testCases := map[string]struct {
+		spec TransformationSpec
+		err  string
+	}{
+		"empty spec": {
+			spec: TransformationSpec{},
+			err:  "empty spec",
+		},
+		"target not running": {
+			spec: TransformationSpec{Target: "sockeye", Spec: []byte("data: []")},
+			err:  `looking up target "sockeye"`,
+		},
+		"unknown source": {
+			spec: TransformationSpec{Sources: []string{"missing"}, Spec: []byte("data: []")},
+			err:  `"missing"`,
+		},
+		"invalid JQ expression": {
+			spec: TransformationSpec{Engine: transformation.EngineJQ, Spec: []byte(".foo |")},
+			err:  "JQ expression",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			b, _ := newBuilder(t)
+			_, err := b.AddTransformation(context.Background(), tc.spec)
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestValidateTransformation(t *testing.T) {
+	testCases := map[string]struct {
+		engine string
+		spec   string
+		err    string
+	}{
+		"bumblebee": {
+			engine: transformation.EngineBumblebee,
+			spec:   "data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n",
+		},
+		"malformed spec": {
+			engine: transformation.EngineBumblebee,
+			spec:   "data: [",
+			err:    "decode spec",
+		},
+		"schema violation": {
+			engine: transformation.EngineBumblebee,
+			spec:   "data: foo",
+			err:    "transformation spec: CR validation",
+		},
+		"jq": {
+			engine: transformation.EngineJQ,
+			spec:   ".data | {id: .id}",
+		},
+		"jq syntax error": {
+			engine: transformation.EngineJQ,
+			spec:   ".data |",
+			err:    "JQ expression",
+		},
+		"jq unknown function": {
+			engine: transformation.EngineJQ,
+			spec:   "nosuchfunction(1)",
+			err:    "JQ expression: function not defined: nosuchfunction/1",
+		},
+		"empty": {
+			engine: transformation.EngineJQ,
+			spec:   "  \n",
+			err:    "empty spec",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			b, _ := newBuilder(t)
+			err := b.ValidateTransformation(tc.engine, []byte(tc.spec))
+			if tc.err == "" {
+				assert.NoError(t, err)
+				return
+			}
+			require.Error(t, err)
+			assert.Contains(t, err.Error(), tc.err)
+		})
+	}
+}
+
+func TestExpandSources(t *testing.T) {
+	b, _ := newBuilder(t)
+	source := *objectNamed(t, b.Manifest, "foo-awss3source")
+	source.Metadata.Name = "bar-awss3source"
+	source.Spec = map[string]interface{}{
+		"arn":        "arn:aws:s3:::bar",
+		"eventTypes": []interface{}{"s3:ObjectCreated:*"},
+	}
+	b.Manifest.Objects = append(b.Manifest.Objects, source)
+
+	sources, err := b.ExpandSources([]string{"foo-awss3source", "kind:AWSS3Source", "sockeye", "kind:awss3source"})
+	require.NoError(t, err)
+	// the named source is not repeated by the kind selector
+	assert.Equal(t, []string{"foo-awss3source", "bar-awss3source", "sockeye"}, sources)
+
+	declared, err := b.SourcesEventTypes([]string{"foo-awss3source", "bar-awss3source"})
+	require.NoError(t, err)
+	expanded, err := b.SourcesEventTypes([]string{"kind:awss3source", "foo-awss3source"})
+	require.NoError(t, err)
+	assert.Equal(t, declared, expanded)
+	// the event types shared by the sources are not repeated
+	unique := make(map[string]struct{})
+	for _, et := range expanded {
+		unique[et] = struct{}{}
+	}
+	assert.Len(t, expanded, len(unique))
+
+	_, err = b.ExpandSources([]string{"kind:awssqssource"})
+	assert.EqualError(t, err, `no "awssqssource" sources in the manifest`)
+	sources, err = b.ExpandSources([]string{"kind:awssqssource"}, "AWSSQSSource")
+	require.NoError(t, err)
+	assert.Empty(t, sources)
+	_, err = b.ExpandSources([]string{"kind:"})
+	assert.Error(t, err)
+}
+
+func TestAddTransformationWatchKinds(t *testing.T) {
+	newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+		NetworkMode:  "tmctl-foo",
+	}, fakeContainer{
+		ID:          "foo-broker",
+		Name:        "foo-broker",
+		NetworkMode: "tmctl-foo",
+	})
+	b, _ := newBuilder(t)
+	source := *objectNamed(t, b.Manifest, "foo-awss3source")
+	require.NoError(t, b.Manifest.Remove(source.Metadata.Name, source.Kind))
+	routes := func() []string {
+		configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+		require.NoError(t, err)
+		var routes []string
+		for _, trigger := range configuration.Triggers {
+			routes = append(routes, trigger.Filters[0].Exact["type"]+" "+trigger.Target.Component)
+		}
+		return routes
+	}
+	before := routes()
+
+	_, err := b.AddTransformation(context.Background(), TransformationSpec{
+		Name:       "bar-transformation",
+		Spec:       []byte("data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n"),
+		Target:     "sockeye",
+		Sources:    []string{"kind:AWSS3Source"},
+		WatchKinds: true,
+	})
+	require.NoError(t, err)
+	assert.Equal(t, "awss3source", objectNamed(t, b.Manifest, "bar-transformation").Metadata.Annotations[triggermesh.WatchSourceKindsAnnotation])
+	// the existing routes of the target are kept
+	after := routes()
+	for _, route := range before {
+		assert.Contains(t, after, route)
+	}
+
+	// the source created later is subscribed on sync
+	b.Manifest.Objects = append(b.Manifest.Objects, source)
+	require.NoError(t, b.Manifest.Write())
+	eventTypes, err := b.SourcesEventTypes([]string{source.Metadata.Name})
+	require.NoError(t, err)
+	require.NotEmpty(t, eventTypes)
+	require.NoError(t, b.SyncWatchedKinds())
+	synced := routes()
+	for _, eventType := range eventTypes {
+		assert.Contains(t, synced, eventType+" bar-transformation")
+	}
+	triggers := len(synced)
+	require.NoError(t, b.SyncWatchedKinds())
+	assert.Len(t, routes(), triggers)
+
+	_, err = b.AddTransformation(context.Background(), TransformationSpec{
+		Name:       "baz-transformation",
+		Spec:       []byte("data: []"),
+		Sources:    []string{"foo-awss3source"},
+		WatchKinds: true,
+	})
+	assert.Error(t, err)
+}
+
+func objectNamed(t *testing.T, m *manifest.Manifest, name string) *kubernetes.Object {
+	for i, object := range m.Objects {
+		if object.Metadata.Name == name {
+			return &m.Objects[i]
+		}
+	}
+	require.Failf(t, "object not found", "%q", name)
+	return nil
+}
+
+func TestAddFanOut(t *testing.T) {
+	newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+	})
+	b, _ := newBuilder(t)
+	source, err := components.GetObject("foo-awss3source", b.Config, b.Manifest, b.CRD)
+	require.NoError(t, err)
+	eventTypes, err := source.(triggermesh.Producer).GetEventTypes()
+	require.NoError(t, err)
+	require.NotEmpty(t, eventTypes)
+
+	fanOut, err := b.AddFanOut(source, []string{"sockeye", "missing"})
+	require.NoError(t, err)
+	require.Len(t, fanOut, 2)
+	assert.Equal(t, "sockeye", fanOut[0].Target)
+	assert.NoError(t, fanOut[0].Err)
+	assert.Len(t, fanOut[0].Triggers, len(eventTypes))
+	// the failed target does not roll back the triggers of the others
+	assert.Equal(t, "missing", fanOut[1].Target)
+	assert.Error(t, fanOut[1].Err)
+	assert.Empty(t, fanOut[1].Triggers)
+	for _, trigger := range fanOut[0].Triggers {
+		assert.Equal(t, tmbroker.TriggerKind, objectNamed(t, b.Manifest, trigger).Kind)
+	}
+
+	// the existing triggers are reused
+	again, err := b.AddFanOut(source, []string{"sockeye"})
+	require.NoError(t, err)
+	assert.Equal(t, fanOut[0].Triggers, again[0].Triggers)
+}
+
+func TestAddTransformationCutover(t *testing.T) {
+	for cutover, expected := range map[string][]string{
+		"": {
+			"trigger.added bar-transformation.output", "trigger.added com.amazon.s3.objectremoved",
+			"ready", "trigger.removed direct",
+		},
+		CutoverDropOldFirst: {
+			"trigger.removed direct",
+			"trigger.added bar-transformation.output", "trigger.added com.amazon.s3.objectremoved",
+		},
+	} {
+		t.Run("cutover "+cutover, func(t *testing.T) {
+			newFakeDocker(t, fakeContainer{
+				ID:           "sockeye",
+				Name:         "sockeye",
+				Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+				PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+				NetworkMode:  "tmctl-foo",
+			}, fakeContainer{
+				ID:          "foo-broker",
+				Name:        "foo-broker",
+				NetworkMode: "tmctl-foo",
+			})
+			b, _ := newBuilder(t)
+			// the live route from the source to the target
+			require.NoError(t, tmbroker.AddTrigger(b.Config.ConfigHome, "foo", "direct", tmbroker.LocalTriggerSpec{
+				Filters: []eventingbroker.Filter{*tmbroker.FilterAttribute("type", "com.amazon.s3.objectremoved")},
+				Target:  tmbroker.LocalTarget{URL: "http://sockeye:8080", Component: "sockeye"},
+			}))
+
+			var steps []string
+			filters := func(name string) string {
+				configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+				require.NoError(t, err)
+				if trigger, exists := configuration.Triggers[name]; exists {
+					return trigger.Filters[0].Exact["type"]
+				}
+				return name
+			}
+			stop := lifecycle.Capture(func(event lifecycle.Event) {
+				switch event.Type {
+				case lifecycle.TriggerAdded:
+					steps = append(steps, string(event.Type)+" "+filters(event.Component))
+				case lifecycle.TriggerRemoved:
+					steps = append(steps, string(event.Type)+" "+event.Component)
+				}
+			})
+			defer stop()
+			b.WaitReady = func(ctx context.Context, c triggermesh.Component) error {
+				assert.Equal(t, "bar-transformation", c.GetName())
+				steps = append(steps, "ready")
+				return nil
+			}
+
+			_, err := b.AddTransformation(context.Background(), TransformationSpec{
+				Name:       "bar-transformation",
+				Spec:       []byte("data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n"),
+				Target:     "sockeye",
+				EventTypes: []string{"com.amazon.s3.objectremoved"},
+				Cutover:    cutover,
+			})
+			require.NoError(t, err)
+			assert.Equal(t, expected, steps)
+
+			configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+			require.NoError(t, err)
+			assert.NotContains(t, configuration.Triggers, "direct")
+		})
+	}
+}
+
+func TestAddTransformationSupersededTriggers(t *testing.T) {
+	typeFilter := func(eventType string) eventingbroker.Filter {
+		return *tmbroker.FilterAttribute("type", eventType)
+	}
+	sourceFilter := *tmbroker.FilterAttribute("source", "arn:aws:s3:::bucket")
+	testCases := map[string]struct {
+		triggers   map[string][]eventingbroker.Filter
+		eventTypes []string
+		kept       []string
+	}{
+		"one trigger": {
+			triggers:   map[string][]eventingbroker.Filter{"direct": {typeFilter("com.amazon.s3.objectcreated")}},
+			eventTypes: []string{"com.amazon.s3.objectcreated"},
+		},
+		"multiple triggers": {
+			triggers: map[string][]eventingbroker.Filter{
+				"created": {typeFilter("com.amazon.s3.objectcreated")},
+				"removed": {typeFilter("com.amazon.s3.objectremoved")},
+				"other":   {typeFilter("com.amazon.sqs.message")},
+			},
+			eventTypes: []string{"com.amazon.s3.objectcreated", "com.amazon.s3.objectremoved"},
+			kept:       []string{"other"},
+		},
+		"multiple filters": {
+			triggers: map[string][]eventingbroker.Filter{
+				"bucket":   {sourceFilter, typeFilter("com.amazon.s3.objectcreated")},
+				"prefixed": {*tmbroker.FilterAttribute("type", "com.amazon.s3.*")},
+			},
+			eventTypes: []string{"com.amazon.s3.objectcreated"},
+			kept:       []string{"prefixed"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			newFakeDocker(t, fakeContainer{
+				ID:           "sockeye",
+				Name:         "sockeye",
+				Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+				PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+				NetworkMode:  "tmctl-foo",
+			}, fakeContainer{
+				ID:          "foo-broker",
+				Name:        "foo-broker",
+				NetworkMode: "tmctl-foo",
+			})
+			b, _ := newBuilder(t)
+			for name, filters := range tc.triggers {
+				require.NoError(t, tmbroker.AddTrigger(b.Config.ConfigHome, "foo", name, tmbroker.LocalTriggerSpec{
+					Filters: filters,
+					Target:  tmbroker.LocalTarget{URL: "http://sockeye:8080", Component: "sockeye"},
+				}))
+			}
+			b.WaitReady = func(context.Context, triggermesh.Component) error {
+				return nil
+			}
+
+			_, err := b.AddTransformation(context.Background(), TransformationSpec{
+				Name:       "bar-transformation",
+				Spec:       []byte("data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n"),
+				Target:     "sockeye",
+				EventTypes: tc.eventTypes,
+			})
+			require.NoError(t, err)
+
+			configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+			require.NoError(t, err)
+			var kept []string
+			for name := range tc.triggers {
+				if _, exists := configuration.Triggers[name]; exists {
+					kept = append(kept, name)
+				}
+			}
+			assert.ElementsMatch(t, tc.kept, kept)
+			// the only path of the event type to the target goes through the transformation
+			for _, eventType := range tc.eventTypes {
+				var targets []string
+				for _, trigger := range configuration.Triggers {
+					if trigger.Target.Component != "sockeye" && trigger.Target.Component != "bar-transformation" {
+						continue
+					}
+					for _, filter := range trigger.Filters {
+						if tmbroker.EqualFilter(filter, typeFilter(eventType)) {
+							targets = append(targets, trigger.Target.Component)
+						}
+					}
+				}
+				assert.Equal(t, []string{"bar-transformation"}, targets, eventType)
+			}
+		})
+	}
+}
+
+func TestAddTransformationCutoverNotReady(t *testing.T) {
+	newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+		NetworkMode:  "tmctl-foo",
+	}, fakeContainer{
+		ID:          "foo-broker",
+		Name:        "foo-broker",
+		NetworkMode: "tmctl-foo",
+	})
+	b, _ := newBuilder(t)
+	require.NoError(t, tmbroker.AddTrigger(b.Config.ConfigHome, "foo", "direct", tmbroker.LocalTriggerSpec{
+		Filters: []eventingbroker.Filter{*tmbroker.FilterAttribute("type", "com.amazon.s3.objectremoved")},
+		Target:  tmbroker.LocalTarget{URL: "http://sockeye:8080", Component: "sockeye"},
+	}))
+	b.WaitReady = func(context.Context, triggermesh.Component) error {
+		return fmt.Errorf("not ready")
+	}
+
+	_, err := b.AddTransformation(context.Background(), TransformationSpec{
+		Name:       "bar-transformation",
+		Spec:       []byte("data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n"),
+		Target:     "sockeye",
+		EventTypes: []string{"com.amazon.s3.objectremoved"},
+	})
+	assert.ErrorContains(t, err, `direct triggers "direct" are kept`)
+
+	// events keep flowing through the old route
+	configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, "foo")
+	require.NoError(t, err)
+	assert.Contains(t, configuration.Triggers, "direct")
+}
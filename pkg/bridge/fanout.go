@@ -0,0 +1,95 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"fmt"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+// FanOut is the wiring of the source events to one of the targets.
+type FanOut struct {
+	Target string
+	// Triggers are the names of the triggers delivering
+	// the source events to the target.
+	Triggers []string
+	// Err is the reason the target is not wired, if any.
+	Err error
+}
+
+// AddFanOut creates the triggers delivering the events of the source
+// to every target, one trigger per produced event type. Failure to wire
+// one target does not roll back the triggers of the others, it is
+// reported in the result of the target instead.
+func (b *Builder) AddFanOut(source triggermesh.Component, targets []string) ([]FanOut, error) {
+	filters, err := sourceFilters(source)
+	if err != nil {
+		return nil, err
+	}
+	result := make([]FanOut, 0, len(targets))
+	for _, name := range targets {
+		fanOut := FanOut{Target: name}
+		fanOut.Triggers, fanOut.Err = b.wire(name, filters)
+		result = append(result, fanOut)
+	}
+	return result, nil
+}
+
+func (b *Builder) wire(target string, filters []*eventingbroker.Filter) ([]string, error) {
+	component, err := b.LookupTarget(target)
+	if err != nil {
+		return nil, err
+	}
+	var triggers []string
+	for _, filter := range filters {
+		trigger, err := b.AddTrigger("", component, "", filter)
+		if err != nil {
+			return triggers, err
+		}
+		triggers = append(triggers, trigger.GetName())
+	}
+	return triggers, nil
+}
+
+// sourceFilters returns the filters of the events produced by the source:
+// one filter per declared event type or, if the types are unknown,
+// the filter of the source attribute.
+func sourceFilters(source triggermesh.Component) ([]*eventingbroker.Filter, error) {
+	producer, ok := source.(triggermesh.Producer)
+	if !ok {
+		return nil, fmt.Errorf("%q is not an event producer", source.GetName())
+	}
+	eventTypes, err := producer.GetEventTypes()
+	if err != nil {
+		return nil, fmt.Errorf("%q event types: %w", source.GetName(), err)
+	}
+	var filters []*eventingbroker.Filter
+	for _, et := range appendUnique(nil, eventTypes...) {
+		filters = append(filters, tmbroker.FilterAttribute("type", et))
+	}
+	if len(filters) != 0 {
+		return filters, nil
+	}
+	if eventSource, err := producer.GetEventSource(); err == nil && eventSource != "" {
+		return []*eventingbroker.Filter{tmbroker.FilterAttribute("source", eventSource)}, nil
+	}
+	return nil, fmt.Errorf("%q declares neither its event types nor source", source.GetName())
+}
@@ -0,0 +1,169 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+
+	"github.com/triggermesh/tmctl/pkg/changeset"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+// Changes runs the build and returns the changes it made. The dry-run builder
+// runs the build against the scratch copy of the context instead, without
+// starting the containers, and returns the changes it would make.
+func (b *Builder) Changes(build func(*Builder) error) (*changeset.Set, error) {
+	if !b.DryRun {
+		changes, stop := changeset.Collect(false)
+		defer stop()
+		return changes, build(b)
+	}
+	scratch, err := os.MkdirTemp("", "tmctl-plan-")
+	if err != nil {
+		return nil, fmt.Errorf("dry run: %w", err)
+	}
+	defer os.RemoveAll(scratch)
+	sandbox, err := b.sandbox(scratch)
+	if err != nil {
+		return nil, fmt.Errorf("dry run: %w", err)
+	}
+	changes, stop := changeset.Collect(true)
+	defer stop()
+	sandbox.changes = changes
+	return changes, build(sandbox)
+}
+
+// sandbox returns the copy of the builder working with the copy
+// of the context files in the scratch directory.
+func (b *Builder) sandbox(scratch string) (*Builder, error) {
+	source := filepath.Join(b.Config.ConfigHome, b.Config.Context)
+	if err := copyDir(source, filepath.Join(scratch, b.Config.Context)); err != nil {
+		return nil, err
+	}
+	config := *b.Config
+	config.ConfigHome = scratch
+	config.ConfigFile = ""
+
+	m := manifest.New(filepath.Join(scratch, b.Config.Context, filepath.Base(b.Manifest.Path)))
+	m.Version = b.Manifest.Version
+	m.Command = b.Manifest.Command
+	if err := m.Read(); err != nil {
+		return nil, err
+	}
+	sandbox := *b
+	sandbox.Config = &config
+	sandbox.Manifest = m
+	return &sandbox, nil
+}
+
+func copyDir(source, destination string) error {
+	return filepath.WalkDir(source, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		relative, err := filepath.Rel(source, path)
+		if err != nil {
+			return err
+		}
+		target := filepath.Join(destination, relative)
+		if entry.IsDir() {
+			return os.MkdirAll(target, os.ModePerm)
+		}
+		if !entry.Type().IsRegular() {
+			return nil
+		}
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return os.WriteFile(target, data, 0o600)
+	})
+}
+
+// start starts the component container. The dry-run builder records
+// whether the container would be started or restarted instead.
+func (b *Builder) start(ctx context.Context, c triggermesh.Component, env map[string]string, restart bool) error {
+	runnable := c.(triggermesh.Runnable)
+	if !b.DryRun {
+		_, err := b.StartContainer(ctx, c, env, restart)
+		return err
+	}
+	change := changeset.Change{Object: c.GetName(), Kind: changeset.ContainerKind}
+	var container *docker.Container
+	err := b.Lookup("looking up container", func(ctx context.Context) (err error) {
+		container, err = runnable.Info(ctx)
+		return err
+	})
+	switch {
+	case errors.Is(err, docker.ErrNotFound):
+		change.Action = changeset.Started
+	case err != nil:
+		return err
+	case restart || !container.Online:
+		change.Action = changeset.Restarted
+	case networked(c) && !container.Joined(docker.NetworkName(b.Config.Context)):
+		// containers created before the context network was introduced
+		change.Action = changeset.Restarted
+	default:
+		return nil
+	}
+	b.changes.Record(change)
+	return nil
+}
+
+func networked(c triggermesh.Component) bool {
+	n, ok := c.(triggermesh.Networked)
+	return ok && len(n.NetworkAliases()) != 0
+}
+
+// destination returns the component the triggers deliver the events to.
+// The dry run does not start the containers, the triggers of the planned
+// containers are pointed to the placeholder address.
+func (b *Builder) destination(c triggermesh.Component) triggermesh.Component {
+	if !b.DryRun {
+		return c
+	}
+	for _, change := range b.changes.Changes() {
+		if change.Object == c.GetName() && change.Kind == changeset.ContainerKind {
+			return plannedConsumer{c}
+		}
+	}
+	return c
+}
+
+// plannedConsumer is the component whose container is not started by the dry run.
+type plannedConsumer struct {
+	triggermesh.Component
+}
+
+func (p plannedConsumer) ConsumedEventTypes() ([]string, error) {
+	if consumer, ok := p.Component.(triggermesh.Consumer); ok {
+		return consumer.ConsumedEventTypes()
+	}
+	return nil, nil
+}
+
+func (p plannedConsumer) GetPort(context.Context) (string, error) {
+	return "0", nil
+}
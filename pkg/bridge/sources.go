@@ -0,0 +1,152 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+// SourceKindSelector prefixes the kind of the sources in the sources filter,
+// e.g. "kind:awssqssource" selects every AWS SQS source of the manifest.
+const SourceKindSelector = "kind:"
+
+// SourceKind returns the kind selected by the sources filter item, if any.
+func SourceKind(source string) (string, bool) {
+	if !strings.HasPrefix(source, SourceKindSelector) {
+		return "", false
+	}
+	return strings.TrimPrefix(source, SourceKindSelector), true
+}
+
+// WatchedKinds returns the source kinds recorded on the manifest object
+// to be re-expanded by "tmctl start".
+func WatchedKinds(annotations map[string]string) []string {
+	var kinds []string
+	for _, kind := range strings.Split(annotations[triggermesh.WatchSourceKindsAnnotation], ",") {
+		if kind = strings.TrimSpace(kind); kind != "" {
+			kinds = append(kinds, kind)
+		}
+	}
+	return kinds
+}
+
+// ExpandSources replaces the kind selectors of the sources filter with the
+// names of the manifest event producers of that kind. Duplicate names are
+// removed keeping the order of the first occurrence. The selector that
+// matches no producers is an error unless its kind is watched.
+func (b *Builder) ExpandSources(sources []string, watched ...string) ([]string, error) {
+	var result []string
+	seen := make(map[string]struct{})
+	add := func(name string) {
+		if _, exists := seen[name]; exists {
+			return
+		}
+		seen[name] = struct{}{}
+		result = append(result, name)
+	}
+	for _, source := range sources {
+		kind, selector := SourceKind(source)
+		if !selector {
+			add(source)
+			continue
+		}
+		if kind == "" {
+			return nil, fmt.Errorf("empty source kind in %q", source)
+		}
+		names, err := b.producersOfKind(kind)
+		if err != nil {
+			return nil, err
+		}
+		if len(names) == 0 && !containsFold(watched, kind) {
+			return nil, fmt.Errorf("no %q sources in the manifest", kind)
+		}
+		for _, name := range names {
+			add(name)
+		}
+	}
+	return result, nil
+}
+
+// producersOfKind returns the names of the manifest event producers
+// of the kind, matched case-insensitively.
+func (b *Builder) producersOfKind(kind string) ([]string, error) {
+	var names []string
+	for _, object := range b.Manifest.Objects {
+		if object.APIVersion == tmbroker.APIVersion || !strings.EqualFold(object.Kind, kind) {
+			continue
+		}
+		c, err := components.GetObject(object.Metadata.Name, b.Config, b.Manifest, b.CRD)
+		if err != nil {
+			return nil, fmt.Errorf("%q event producer object: %w", object.Metadata.Name, err)
+		}
+		if _, ok := c.(triggermesh.Producer); ok {
+			names = append(names, object.Metadata.Name)
+		}
+	}
+	return names, nil
+}
+
+// SyncWatchedKinds subscribes the components that watch the source kinds
+// to the event types of the current sources of these kinds. The existing
+// triggers are reused, so the repeated sync does not change anything.
+func (b *Builder) SyncWatchedKinds() error {
+	for _, object := range b.Manifest.Objects {
+		kinds := WatchedKinds(object.Metadata.Annotations)
+		if len(kinds) == 0 {
+			continue
+		}
+		c, err := components.GetObject(object.Metadata.Name, b.Config, b.Manifest, b.CRD)
+		if err != nil {
+			return fmt.Errorf("%q: %w", object.Metadata.Name, err)
+		}
+		if _, ok := c.(triggermesh.Consumer); !ok {
+			continue
+		}
+		var selectors []string
+		for _, kind := range kinds {
+			selectors = append(selectors, SourceKindSelector+kind)
+		}
+		sources, err := b.ExpandSources(selectors, kinds...)
+		if err != nil {
+			return err
+		}
+		eventTypes, err := b.SourcesEventTypes(sources)
+		if err != nil {
+			return err
+		}
+		for _, et := range eventTypes {
+			if _, err := b.AddTrigger("", b.destination(c), "", tmbroker.FilterAttribute("type", et)); err != nil {
+				return fmt.Errorf("%q trigger: %w", c.GetName(), err)
+			}
+		}
+	}
+	return nil
+}
+
+func containsFold(list []string, s string) bool {
+	for _, item := range list {
+		if strings.EqualFold(item, s) {
+			return true
+		}
+	}
+	return false
+}
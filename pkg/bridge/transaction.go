@@ -0,0 +1,377 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"reflect"
+	"strings"
+	"sync"
+
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+// Step is the change applied by the build along with its inverse.
+type Step struct {
+	// Description is the applied change, e.g. `created trigger "foo"`.
+	Description string
+	// Undo reverts the change, nil if it cannot be reverted automatically.
+	Undo func() error
+	// Command is the CLI command reverting the change, if any.
+	Command string
+}
+
+// Transaction records the steps applied by the builds sharing it, so that
+// the failed build does not leave the context partially changed.
+type Transaction struct {
+	mu    sync.Mutex
+	steps []Step
+}
+
+// Record appends the applied step to the transaction. Nil transaction
+// records nothing.
+func (t *Transaction) Record(step Step) {
+	if t == nil {
+		return
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	t.steps = append(t.steps, step)
+}
+
+// Steps returns the applied steps in the order of their application.
+func (t *Transaction) Steps() []Step {
+	if t == nil {
+		return nil
+	}
+	t.mu.Lock()
+	defer t.mu.Unlock()
+	return append([]Step{}, t.steps...)
+}
+
+// Rollback reverts the applied steps in the reverse order. Steps that
+// could not be reverted are returned along with the undo errors.
+func (t *Transaction) Rollback() ([]Step, []error) {
+	steps := t.Steps()
+	var kept []Step
+	var errs []error
+	for i := len(steps) - 1; i >= 0; i-- {
+		step := steps[i]
+		if step.Undo == nil {
+			kept = append([]Step{step}, kept...)
+			continue
+		}
+		if err := step.Undo(); err != nil {
+			kept = append([]Step{step}, kept...)
+			errs = append(errs, fmt.Errorf("%s: %w", step.Description, err))
+		}
+	}
+	return kept, errs
+}
+
+// Finish ends the transaction of the build that returned the error. The steps
+// applied before the failure are rolled back unless keepPartial is set, either
+// way they are listed in the returned PartialError. The errors of the builds
+// that have not changed anything are returned as is.
+func (t *Transaction) Finish(err error, keepPartial bool) error {
+	applied := t.Steps()
+	if err == nil || len(applied) == 0 {
+		return err
+	}
+	partial := &PartialError{Err: err, Applied: applied}
+	if keepPartial {
+		partial.Kept = applied
+		return partial
+	}
+	partial.RolledBack = true
+	partial.Kept, partial.RollbackErrors = t.Rollback()
+	return partial
+}
+
+// PartialError is the error of the build failed after some of its steps
+// had been applied.
+type PartialError struct {
+	Err error
+	// Applied are the steps applied before the failure.
+	Applied []Step
+	// RolledBack is true if the rollback of the applied steps was attempted.
+	RolledBack bool
+	// Kept are the applied steps left in place: all of them without the
+	// rollback, otherwise those that failed or could not be reverted.
+	Kept []Step
+	// RollbackErrors are the failures of the rollback.
+	RollbackErrors []error
+}
+
+func (e *PartialError) Unwrap() error {
+	return e.Err
+}
+
+func (e *PartialError) Error() string {
+	var b strings.Builder
+	b.WriteString(e.Err.Error())
+	if e.RolledBack {
+		fmt.Fprintf(&b, "\nRolled back %d of %d applied steps", len(e.Applied)-len(e.Kept), len(e.Applied))
+		for _, err := range e.RollbackErrors {
+			fmt.Fprintf(&b, "\n\trollback failed: %v", err)
+		}
+	}
+	if len(e.Kept) == 0 {
+		return b.String()
+	}
+	b.WriteString("\nApplied steps left in place:")
+	for _, step := range e.Kept {
+		fmt.Fprintf(&b, "\n\t%s", step.Description)
+	}
+	if commands := e.UndoCommands(); len(commands) != 0 {
+		b.WriteString("\nUndo them with:")
+		for _, command := range commands {
+			fmt.Fprintf(&b, "\n\t%s", command)
+		}
+	}
+	return b.String()
+}
+
+// UndoCommands returns the commands reverting the kept steps,
+// the latest step first.
+func (e *PartialError) UndoCommands() []string {
+	var commands []string
+	for i := len(e.Kept) - 1; i >= 0; i-- {
+		if command := e.Kept[i].Command; command != "" && !contains(commands, command) {
+			commands = append(commands, command)
+		}
+	}
+	return commands
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
+
+// record adds the step to the transaction of the build, if any.
+func (b *Builder) record(step Step) {
+	if !b.DryRun {
+		b.Transaction.Record(step)
+	}
+}
+
+// Apply writes the component to the manifest. The change is recorded in the
+// transaction of the build, so that the previous object can be restored.
+func (b *Builder) Apply(c triggermesh.Component) (manifest.Result, error) {
+	previous, existed, err := b.Manifest.Object(c.GetName(), c.GetKind())
+	if err != nil {
+		return manifest.Unchanged, fmt.Errorf("manifest object: %w", err)
+	}
+	result, err := b.Manifest.Apply(c)
+	if result == manifest.Unchanged && err == nil {
+		return result, nil
+	}
+	step := Step{Description: fmt.Sprintf("%s %s %q in the manifest", result, c.GetKind(), c.GetName())}
+	if existed {
+		step.Undo = func() error {
+			return b.Manifest.Restore(previous)
+		}
+	} else {
+		step.Undo = func() error {
+			object, err := c.AsK8sObject()
+			if err != nil {
+				return err
+			}
+			return b.Manifest.Remove(object.Metadata.Name, object.Kind)
+		}
+		step.Command = deleteCommand(c)
+	}
+	b.record(step)
+	return result, err
+}
+
+// deleteCommand returns the command deleting the component.
+func deleteCommand(c triggermesh.Component) string {
+	var kind string
+	switch group, _, _ := strings.Cut(c.GetAPIVersion(), "/"); {
+	case group == "sources.triggermesh.io":
+		kind = "source"
+	case group == "targets.triggermesh.io":
+		kind = "target"
+	case group == "flow.triggermesh.io":
+		kind = "transformation"
+	case c.GetKind() == tmbroker.TriggerKind:
+		kind = "trigger"
+	default:
+		return ""
+	}
+	return fmt.Sprintf("tmctl delete %s %s", kind, c.GetName())
+}
+
+// StartContainer starts the component container. The container that did
+// not exist before is recorded in the transaction of the build to be removed
+// on rollback. Restarted containers keep running with the new spec.
+func (b *Builder) StartContainer(ctx context.Context, c triggermesh.Component, env map[string]string, restart bool) (*docker.Container, error) {
+	runnable := c.(triggermesh.Runnable)
+	if b.Transaction == nil || b.DryRun {
+		return runnable.Start(ctx, env, restart)
+	}
+	err := b.Lookup("looking up container", func(ctx context.Context) error {
+		_, err := runnable.Info(ctx)
+		return err
+	})
+	existed := !errors.Is(err, docker.ErrNotFound)
+	container, err := runnable.Start(ctx, env, restart)
+	switch {
+	case !existed:
+		b.record(Step{
+			Description: fmt.Sprintf("started container %q", c.GetName()),
+			Undo: func() error {
+				// the container may have never been created
+				err := b.Lookup("looking up container", func(ctx context.Context) error {
+					_, err := runnable.Info(ctx)
+					return err
+				})
+				if errors.Is(err, docker.ErrNotFound) {
+					return nil
+				}
+				return runnable.Stop(context.Background())
+			},
+			Command: fmt.Sprintf("docker rm -f %s", docker.ContainerName(b.Config.Context, c.GetName())),
+		})
+	case restart && err == nil:
+		b.record(Step{
+			Description: fmt.Sprintf("restarted container %q with the new spec", c.GetName()),
+			Command:     "tmctl start --restart",
+		})
+	}
+	return container, err
+}
+
+// triggerSnapshot is the state of the trigger before the change.
+type triggerSnapshot struct {
+	name string
+	// spec is the broker config entry of the trigger, if any.
+	spec    *tmbroker.LocalTriggerSpec
+	object  *kubernetes.Object
+	removed bool
+}
+
+// snapshotTrigger returns the current state of the trigger, nil if the
+// build has no transaction.
+func (b *Builder) snapshotTrigger(name string) (*triggerSnapshot, error) {
+	if b.Transaction == nil || b.DryRun {
+		return nil, nil
+	}
+	snapshot, err := b.triggerState(name)
+	if err != nil {
+		return nil, fmt.Errorf("trigger %q state: %w", name, err)
+	}
+	return snapshot, nil
+}
+
+func (b *Builder) triggerState(name string) (*triggerSnapshot, error) {
+	snapshot := &triggerSnapshot{name: name}
+	configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, b.Config.Context)
+	if err != nil {
+		return nil, err
+	}
+	if spec, exists := configuration.Triggers[name]; exists {
+		snapshot.spec = &spec
+	}
+	object, exists, err := b.Manifest.Object(name, tmbroker.TriggerKind)
+	if err != nil {
+		return nil, err
+	}
+	if exists {
+		snapshot.object = &object
+	}
+	return snapshot, nil
+}
+
+// recordTrigger records the change of the trigger since the snapshot
+// in the transaction of the build. Unchanged triggers are not recorded.
+func (b *Builder) recordTrigger(previous *triggerSnapshot) {
+	if previous == nil {
+		return
+	}
+	current, err := b.triggerState(previous.name)
+	if err == nil && reflect.DeepEqual(previous.spec, current.spec) && equalObjects(previous.object, current.object) {
+		return
+	}
+	configBase, broker := b.Config.ConfigHome, b.Config.Context
+	step := Step{
+		Description: fmt.Sprintf("updated trigger %q", previous.name),
+		Undo: func() error {
+			if previous.spec != nil {
+				if err := tmbroker.AddTrigger(configBase, broker, previous.name, *previous.spec); err != nil {
+					return err
+				}
+			} else if err := tmbroker.RemoveTrigger(configBase, broker, previous.name); err != nil {
+				return err
+			}
+			if previous.object != nil {
+				return b.Manifest.Restore(*previous.object)
+			}
+			return b.Manifest.Remove(previous.name, tmbroker.TriggerKind)
+		},
+	}
+	switch {
+	case previous.spec == nil:
+		step.Description = fmt.Sprintf("created trigger %q", previous.name)
+		step.Command = fmt.Sprintf("tmctl delete trigger %s", previous.name)
+	case err == nil && current.spec == nil:
+		step.Description = fmt.Sprintf("removed trigger %q", previous.name)
+		step.Command = createTriggerCommand(previous.name, *previous.spec)
+	}
+	b.record(step)
+}
+
+func equalObjects(a, b *kubernetes.Object) bool {
+	if a == nil || b == nil {
+		return a == b
+	}
+	equal, err := manifest.Equal(*a, *b)
+	return err == nil && equal
+}
+
+// createTriggerCommand returns the command creating the trigger
+// with the spec, empty string if there is no such command.
+func createTriggerCommand(name string, spec tmbroker.LocalTriggerSpec) string {
+	if spec.Target.Component == "" || len(spec.Filters) > 1 {
+		return ""
+	}
+	command := fmt.Sprintf("tmctl create trigger --name %s --target %s", name, spec.Target.Component)
+	if path := spec.Target.Path(); path != "" {
+		command += fmt.Sprintf(" --target-path '%s'", path)
+	}
+	if len(spec.Filters) == 1 {
+		filter, err := json.Marshal(spec.Filters[0])
+		if err != nil {
+			return ""
+		}
+		command += fmt.Sprintf(" --filter '%s'", filter)
+	}
+	return command
+}
@@ -0,0 +1,333 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
+)
+
+func TestTransactionRollback(t *testing.T) {
+	var undone []string
+	undo := func(name string, err error) func() error {
+		return func() error {
+			undone = append(undone, name)
+			return err
+		}
+	}
+	tx := &Transaction{}
+	tx.Record(Step{Description: "first", Undo: undo("first", nil), Command: "undo first"})
+	tx.Record(Step{Description: "second", Command: "undo second"})
+	tx.Record(Step{Description: "third", Undo: undo("third", fmt.Errorf("busy")), Command: "undo third"})
+	tx.Record(Step{Description: "fourth", Undo: undo("fourth", nil)})
+
+	failure := fmt.Errorf("build failed")
+	err := tx.Finish(failure, false)
+	var partial *PartialError
+	require.ErrorAs(t, err, &partial)
+	assert.ErrorIs(t, err, failure)
+	assert.Equal(t, []string{"fourth", "third", "first"}, undone)
+	assert.True(t, partial.RolledBack)
+	assert.Len(t, partial.Applied, 4)
+	assert.Equal(t, []string{"second", "third"}, descriptions(partial.Kept))
+	assert.Equal(t, []string{"undo third", "undo second"}, partial.UndoCommands())
+	assert.Equal(t, "build failed\n"+
+		"Rolled back 2 of 4 applied steps\n"+
+		"\trollback failed: third: busy\n"+
+		"Applied steps left in place:\n"+
+		"\tsecond\n"+
+		"\tthird\n"+
+		"Undo them with:\n"+
+		"\tundo third\n"+
+		"\tundo second", err.Error())
+}
+
+func TestTransactionKeepPartial(t *testing.T) {
+	tx := &Transaction{}
+	tx.Record(Step{Description: "first", Undo: func() error {
+		return fmt.Errorf("must not be called")
+	}, Command: "undo first"})
+
+	err := tx.Finish(fmt.Errorf("build failed"), true)
+	var partial *PartialError
+	require.ErrorAs(t, err, &partial)
+	assert.False(t, partial.RolledBack)
+	assert.Equal(t, []string{"first"}, descriptions(partial.Kept))
+	assert.Equal(t, "build failed\nApplied steps left in place:\n\tfirst\nUndo them with:\n\tundo first", err.Error())
+}
+
+func TestTransactionNothingApplied(t *testing.T) {
+	failure := fmt.Errorf("build failed")
+	assert.Equal(t, failure, (&Transaction{}).Finish(failure, false))
+	assert.NoError(t, (&Transaction{}).Finish(nil, false))
+
+	var tx *Transaction
+	tx.Record(Step{Description: "ignored"})
+	assert.Empty(t, tx.Steps())
+}
+
+// contextState is the state of the context compared before and after the build.
+type contextState struct {
+	Objects    []string
+	Triggers   map[string]tmbroker.LocalTriggerSpec
+	Containers []string
+}
+
+func stateOf(t *testing.T, b *Builder, d *fakeDocker) contextState {
+	m := manifest.New(b.Manifest.Path)
+	require.NoError(t, m.Read())
+	var state contextState
+	for _, object := range m.Objects {
+		state.Objects = append(state.Objects, object.Kind+"/"+object.Metadata.Name)
+	}
+	sort.Strings(state.Objects)
+	configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, b.Config.Context)
+	require.NoError(t, err)
+	state.Triggers = configuration.Triggers
+	d.Lock()
+	defer d.Unlock()
+	for name := range d.containers {
+		state.Containers = append(state.Containers, name)
+	}
+	sort.Strings(state.Containers)
+	return state
+}
+
+func TestAddTransformationRollback(t *testing.T) {
+	testCases := map[string]struct {
+		cutover     string
+		contentMode string
+		failCreate  string
+		notReady    bool
+		expectedErr string
+	}{
+		"container fails to start": {
+			failCreate:  "bar-transformation",
+			expectedErr: "injected failure",
+		},
+		"trigger fails": {
+			contentMode: tmbroker.ContentModeStructured,
+			failCreate:  tmbroker.ProxyName("foo"),
+			expectedErr: "injected failure",
+		},
+		"trigger fails after old triggers removed": {
+			cutover:     CutoverDropOldFirst,
+			contentMode: tmbroker.ContentModeStructured,
+			failCreate:  tmbroker.ProxyName("foo"),
+			expectedErr: "injected failure",
+		},
+		"transformation is not ready": {
+			notReady:    true,
+			expectedErr: "not ready",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			d := newFakeDocker(t, fakeContainer{
+				ID:           "sockeye",
+				Name:         "sockeye",
+				Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+				PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+				NetworkMode:  "tmctl-foo",
+			}, fakeContainer{
+				ID:          "foo-broker",
+				Name:        "foo-broker",
+				NetworkMode: "tmctl-foo",
+			})
+			d.failCreate = map[string]bool{tc.failCreate: true}
+			b, _ := newBuilder(t)
+			require.NoError(t, tmbroker.AddTrigger(b.Config.ConfigHome, "foo", "direct", tmbroker.LocalTriggerSpec{
+				Filters: []eventingbroker.Filter{*tmbroker.FilterAttribute("type", "com.amazon.s3.objectremoved")},
+				Target:  tmbroker.LocalTarget{URL: "http://sockeye:8080", Component: "sockeye"},
+			}))
+			b.ContentMode = tc.contentMode
+			b.WaitReady = func(context.Context, triggermesh.Component) error {
+				if tc.notReady {
+					return fmt.Errorf("not ready")
+				}
+				return nil
+			}
+			initial := stateOf(t, b, d)
+
+			b.Transaction = &Transaction{}
+			_, err := b.AddTransformation(context.Background(), TransformationSpec{
+				Name:       "bar-transformation",
+				Spec:       []byte("data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n"),
+				Target:     "sockeye",
+				EventTypes: []string{"com.amazon.s3.objectremoved"},
+				Cutover:    tc.cutover,
+			})
+			require.ErrorContains(t, err, tc.expectedErr)
+			err = b.Transaction.Finish(err, false)
+
+			var partial *PartialError
+			require.ErrorAs(t, err, &partial)
+			assert.Empty(t, partial.RollbackErrors)
+			assert.Empty(t, partial.Kept)
+			assert.Equal(t, initial, stateOf(t, b, d))
+		})
+	}
+}
+
+func TestAddTransformationKeepPartial(t *testing.T) {
+	d := newFakeDocker(t, fakeContainer{
+		ID:           "sockeye",
+		Name:         "sockeye",
+		Image:        "docker.io/n3wscott/sockeye:v0.7.0",
+		PortBindings: map[string][]portBinding{"8080/tcp": {{HostPort: "59852"}}},
+		NetworkMode:  "tmctl-foo",
+	}, fakeContainer{
+		ID:          "foo-broker",
+		Name:        "foo-broker",
+		NetworkMode: "tmctl-foo",
+	})
+	b, _ := newBuilder(t)
+	require.NoError(t, tmbroker.AddTrigger(b.Config.ConfigHome, "foo", "direct", tmbroker.LocalTriggerSpec{
+		Filters: []eventingbroker.Filter{*tmbroker.FilterAttribute("type", "com.amazon.s3.objectremoved")},
+		Target:  tmbroker.LocalTarget{URL: "http://sockeye:8080", Component: "sockeye"},
+	}))
+	b.WaitReady = func(context.Context, triggermesh.Component) error {
+		return fmt.Errorf("not ready")
+	}
+
+	b.Transaction = &Transaction{}
+	_, err := b.AddTransformation(context.Background(), TransformationSpec{
+		Name:       "bar-transformation",
+		Spec:       []byte("data:\n- operation: add\n  paths:\n  - key: foo\n    value: bar\n"),
+		Target:     "sockeye",
+		EventTypes: []string{"com.amazon.s3.objectremoved"},
+	})
+	err = b.Transaction.Finish(err, true)
+
+	var partial *PartialError
+	require.ErrorAs(t, err, &partial)
+	assert.False(t, partial.RolledBack)
+	assert.Equal(t, partial.Applied, partial.Kept)
+	commands := partial.UndoCommands()
+	assert.Contains(t, commands, "tmctl delete transformation bar-transformation")
+	assert.Contains(t, commands, "docker rm -f bar-transformation")
+
+	// the partial changes are left in place
+	state := stateOf(t, b, d)
+	assert.Contains(t, state.Objects, "Transformation/bar-transformation")
+	assert.Contains(t, state.Containers, "bar-transformation")
+	assert.NotEmpty(t, state.Triggers)
+}
+
+func TestApplyRollback(t *testing.T) {
+	b, _ := newBuilder(t)
+	spec := func(value string) map[string]interface{} {
+		return map[string]interface{}{"data": []interface{}{map[string]interface{}{
+			"operation": "add",
+			"paths":     []interface{}{map[string]interface{}{"key": "foo", "value": value}},
+		}}}
+	}
+	original := transformation.New("bar-transformation", "Transformation", "foo", version, b.CRD["transformation"], spec("bar"))
+	_, err := b.Apply(original)
+	require.NoError(t, err)
+	expected, _, err := b.Manifest.Object("bar-transformation", "Transformation")
+	require.NoError(t, err)
+	// nothing recorded without the transaction
+	assert.Nil(t, b.Transaction)
+
+	b.Transaction = &Transaction{}
+	result, err := b.Apply(transformation.New("bar-transformation", "Transformation", "foo", version, b.CRD["transformation"], spec("baz")))
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Updated, result)
+	result, err = b.Apply(transformation.New("baz-transformation", "Transformation", "foo", version, b.CRD["transformation"], spec("baz")))
+	require.NoError(t, err)
+	assert.Equal(t, manifest.Created, result)
+	require.Len(t, b.Transaction.Steps(), 2)
+
+	kept, errs := b.Transaction.Rollback()
+	assert.Empty(t, kept)
+	assert.Empty(t, errs)
+	m := manifest.New(b.Manifest.Path)
+	require.NoError(t, m.Read())
+	object, exists, err := m.Object("bar-transformation", "Transformation")
+	require.NoError(t, err)
+	require.True(t, exists)
+	equal, err := manifest.Equal(expected, object)
+	require.NoError(t, err)
+	assert.True(t, equal)
+	_, exists, err = m.Object("baz-transformation", "Transformation")
+	require.NoError(t, err)
+	assert.False(t, exists)
+}
+
+func descriptions(steps []Step) []string {
+	var list []string
+	for _, step := range steps {
+		list = append(list, step.Description)
+	}
+	return list
+}
+
+func TestCreateTriggerCommand(t *testing.T) {
+	filter := []eventingbroker.Filter{*tmbroker.FilterAttribute("type", "order.created")}
+	testCases := map[string]struct {
+		spec    tmbroker.LocalTriggerSpec
+		command string
+	}{
+		"root path": {
+			spec: tmbroker.LocalTriggerSpec{
+				Filters: filter,
+				Target:  tmbroker.LocalTarget{URL: "http://sockeye:8080", Component: "sockeye"},
+			},
+			command: `tmctl create trigger --name foo --target sockeye --filter '{"exact":{"type":"order.created"}}'`,
+		},
+		"target path": {
+			spec: tmbroker.LocalTriggerSpec{
+				Filters: filter,
+				Target:  tmbroker.LocalTarget{URL: "http://sockeye:8080/orders?v=1", Component: "sockeye"},
+			},
+			command: `tmctl create trigger --name foo --target sockeye --target-path '/orders?v=1' --filter '{"exact":{"type":"order.created"}}'`,
+		},
+		"structured mode path": {
+			spec: tmbroker.LocalTriggerSpec{
+				Target: tmbroker.LocalTarget{
+					URL:         "http://foo-structured-proxy:8080/?target=http%3A%2F%2Fsockeye%3A8080%2Fa",
+					Component:   "sockeye",
+					ContentMode: tmbroker.ContentModeStructured,
+				},
+			},
+			command: `tmctl create trigger --name foo --target sockeye --target-path '/a'`,
+		},
+		"external target": {
+			spec: tmbroker.LocalTriggerSpec{
+				Target: tmbroker.LocalTarget{URL: "https://example.com/a"},
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.command, createTriggerCommand("foo", tc.spec))
+		})
+	}
+}
@@ -0,0 +1,546 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/itchyny/gojq"
+	"gopkg.in/yaml.v3"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/health"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+// TransformationSpec is the transformation requested from the builder.
+type TransformationSpec struct {
+	Name string
+	// Engine is transformation.EngineBumblebee if empty.
+	Engine string
+	// Spec is the Bumblebee specification or the JQ expression.
+	Spec []byte
+	// Target receives the transformed events.
+	Target     string
+	TargetPath string
+	// EventType overrides the output type template of the context.
+	EventType string
+	// Sources and EventTypes are the events the transformation subscribes to.
+	// Sources are the component names or the "kind:<kind>" selectors.
+	Sources    []string
+	EventTypes []string
+	// WatchKinds records the kinds selected by Sources on the transformation
+	// so that the sources of these kinds created later are subscribed too.
+	WatchKinds bool
+	// Cutover orders the trigger changes when the transformation is inserted
+	// into the existing route, CutoverAddNewFirst if empty.
+	Cutover string
+}
+
+// Orders of the trigger changes when the transformation is inserted
+// between the sources and the target that are already wired.
+const (
+	// CutoverAddNewFirst creates and verifies the new triggers and waits
+	// for the transformation to be ready before the direct triggers are
+	// removed. Events may be delivered twice during the cutover.
+	CutoverAddNewFirst = "add-new-first"
+	// CutoverDropOldFirst removes the direct triggers before the new ones
+	// are created. Events emitted in between are lost.
+	CutoverDropOldFirst = "drop-old-first"
+)
+
+// AddTransformation creates the transformation component, starts its container
+// and moves the triggers of the existing source-to-target routes to it.
+func (b *Builder) AddTransformation(ctx context.Context, opts TransformationSpec) (Result, error) {
+	var result Result
+	engine := opts.Engine
+	if engine == "" {
+		engine = transformation.EngineBumblebee
+	}
+	targetLabel := ""
+
+	var targetComponent triggermesh.Component
+	if opts.Target != "" {
+		targetLabel = opts.Target
+		t, err := b.LookupTarget(opts.Target)
+		if err != nil {
+			return result, err
+		}
+		targetComponent = t
+	}
+	var expectedEventTypes []string
+	if consumer, ok := targetComponent.(triggermesh.Consumer); ok {
+		expectedEventTypes, _ = consumer.ConsumedEventTypes()
+	}
+
+	var watchedKinds []string
+	if opts.WatchKinds {
+		for _, source := range opts.Sources {
+			if kind, ok := SourceKind(source); ok && !containsFold(watchedKinds, kind) {
+				watchedKinds = append(watchedKinds, strings.ToLower(kind))
+			}
+		}
+		if len(watchedKinds) == 0 {
+			return result, fmt.Errorf("watching source kinds requires the \"%s<kind>\" sources", SourceKindSelector)
+		}
+	}
+	sources, err := b.ExpandSources(opts.Sources, watchedKinds...)
+	if err != nil {
+		return result, err
+	}
+	et, err := b.SourcesEventTypes(sources)
+	if err != nil {
+		return result, err
+	}
+	eventTypesFilter := appendUnique(append([]string{}, opts.EventTypes...), et...)
+
+	t, err := b.transformation(opts.Name, engine, opts.Spec)
+	if err != nil {
+		return result, err
+	}
+	if engine != transformation.EngineJQ {
+		for _, finding := range transformation.Lint(t.GetSpec()) {
+			b.warn(&result, "Transformation spec %s", finding)
+		}
+	}
+	t.(*transformation.Transformation).Version = b.ComponentVersion(t)
+
+	transformationEventType, eventTypeOrigin, err := b.OutputEventType(t.GetName(), t.GetKind(), opts.EventType)
+	if err != nil {
+		return result, err
+	}
+	if len(expectedEventTypes) > 0 && eventTypeOrigin != triggermesh.EventTypeFromFlag {
+		transformationEventType = expectedEventTypes[0]
+		eventTypeOrigin = triggermesh.EventTypeFromCRD
+	}
+	producedEventTypes, _ := t.(triggermesh.Producer).GetEventTypes()
+	var stage triggermesh.Component
+	switch {
+	case engine == transformation.EngineJQ:
+		// JQ output keeps the attributes of the input events, the output
+		// stage sets the event type on the way back to the broker
+		if stage, err = b.outputStage(t, transformationEventType); err != nil {
+			return result, err
+		}
+	case len(producedEventTypes) == 0:
+		if err := t.(triggermesh.Producer).SetEventAttributes(map[string]string{
+			"type": transformationEventType,
+		}); err != nil {
+			return result, fmt.Errorf("setting event type: %w", err)
+		}
+	default:
+		transformationEventType = producedEventTypes[0]
+		eventTypeOrigin = triggermesh.EventTypeFromCRD
+		targetLabel = producedEventTypes[0]
+	}
+
+	eventTypesMatch := false
+	if len(expectedEventTypes) == 0 {
+		eventTypesMatch = true
+	}
+	for _, eet := range expectedEventTypes {
+		if eet == transformationEventType {
+			eventTypesMatch = true
+			break
+		}
+	}
+
+	if targetComponent != nil && !eventTypesMatch {
+		b.warn(&result, `The transformation produces events of %q type, while target %q expectes %s. The target adapter may not work in this configuration.`,
+			transformationEventType, targetComponent.GetName(), strings.Join(expectedEventTypes, ","))
+	}
+
+	triggers := len(eventTypesFilter)
+	if targetComponent != nil && transformationEventType != "" {
+		triggers++
+	}
+	if err := b.CheckTriggerName(triggers); err != nil {
+		return result, err
+	}
+
+	t.(*transformation.Transformation).SetLabel(transformation.TransformationContextLabel, transformationContexts(targetLabel, eventTypesFilter))
+
+	b.progress("Updating manifest")
+	stageRestarted := false
+	if stage != nil {
+		// the stage is added first to be started before the transformation
+		if stageRestarted, err = b.startOutputStage(ctx, t, stage); err != nil {
+			return result, err
+		}
+	}
+	applied, err := b.Apply(t)
+	if err != nil {
+		return result, fmt.Errorf("unable to update manifest: %w", err)
+	}
+	restart := applied != manifest.Unchanged || stageRestarted
+	if err := b.PinVersion(t); err != nil {
+		return result, err
+	}
+	if eventTypeOrigin != "" {
+		if err := b.Manifest.Annotate(t.GetName(), t.GetKind(), triggermesh.EventTypeOriginAnnotation, eventTypeOrigin); err != nil {
+			return result, fmt.Errorf("unable to update manifest: %w", err)
+		}
+	}
+	if len(watchedKinds) != 0 {
+		if err := b.Manifest.Annotate(t.GetName(), t.GetKind(), triggermesh.WatchSourceKindsAnnotation, strings.Join(watchedKinds, ",")); err != nil {
+			return result, fmt.Errorf("unable to update manifest: %w", err)
+		}
+	}
+
+	var additionalEnvs map[string]string
+	if stage != nil {
+		var sink string
+		if err := b.Lookup("resolving output stage port", func(ctx context.Context) (err error) {
+			sink, err = b.Config.URLs().ComponentURL(ctx, b.destination(stage).(triggermesh.Consumer), urls.ContainerNetwork)
+			return err
+		}); err != nil {
+			return result, err
+		}
+		additionalEnvs = map[string]string{"K_SINK": sink}
+	}
+
+	additionalEnvs, levelChanged, err := b.LoggingEnv(t, additionalEnvs)
+	if err != nil {
+		return result, err
+	}
+	restart = restart || levelChanged
+
+	b.progress("Starting container")
+	if err := b.start(ctx, t, additionalEnvs, restart); err != nil {
+		return result, err
+	}
+
+	destination := b.destination(t)
+	// update our triggers in case of target container restart
+	if restart {
+		if err := b.UpdateTriggers(destination); err != nil {
+			return result, err
+		}
+	}
+
+	var targetTriggers []triggermesh.Component
+	if targetComponent != nil {
+		if targetTriggers, err = tmbroker.GetTargetTriggers(targetComponent.GetName(), b.Config.Context, b.Config.ConfigHome); err != nil {
+			return result, fmt.Errorf("target triggers: %w", err)
+		}
+	}
+	// direct triggers from the sources to the target replaced by the transformation
+	var superseded []*tmbroker.Trigger
+	for _, component := range targetTriggers {
+		trigger := component.(*tmbroker.Trigger)
+		for _, et := range eventTypesFilter {
+			if et != transformationEventType && hasFilter(trigger, *tmbroker.FilterAttribute("type", et)) {
+				superseded = append(superseded, trigger)
+				break
+			}
+		}
+	}
+	// the transformation watching the source kinds that have no sources yet
+	// must not take over the other routes of the target
+	var retargeted []*tmbroker.Trigger
+	if len(eventTypesFilter) == 0 && len(watchedKinds) == 0 {
+		for _, component := range targetTriggers {
+			trigger := component.(*tmbroker.Trigger)
+			if len(trigger.Filters) == 1 && trigger.Filters[0].Exact["type"] == transformationEventType {
+				continue
+			}
+			retargeted = append(retargeted, trigger)
+		}
+	}
+
+	if opts.Cutover == CutoverDropOldFirst {
+		if err := b.RemoveTriggers(superseded); err != nil {
+			return result, err
+		}
+	}
+
+	var created []triggermesh.Component
+	// creating new trigger from transformation to target
+	if targetComponent != nil && transformationEventType != "" {
+		trigger, err := b.AddTrigger("", targetComponent, opts.TargetPath, tmbroker.FilterAttribute("type", transformationEventType))
+		if err != nil {
+			return result, fmt.Errorf("create trigger: %w", err)
+		}
+		created = append(created, trigger)
+	}
+	// creating new triggers from sources to transformation
+	for _, et := range eventTypesFilter {
+		trigger, err := b.AddTrigger("", destination, "", tmbroker.FilterAttribute("type", et))
+		if err != nil {
+			return result, err
+		}
+		created = append(created, trigger)
+	}
+
+	if opts.Cutover != CutoverDropOldFirst && len(superseded)+len(retargeted) != 0 && !b.DryRun {
+		// the routes are switched only when the new ones are in place,
+		// at worst the events are delivered twice instead of being lost
+		b.progress("Verifying new triggers")
+		if err := verifyTriggers(b.Config.ConfigHome, b.Config.Context, created); err != nil {
+			return result, err
+		}
+		b.progress("Waiting for transformation to be ready")
+		if err := b.waitReady(ctx, t); err != nil {
+			return result, fmt.Errorf("%w, direct triggers %s are kept", err, triggerNames(superseded, retargeted))
+		}
+	}
+	if opts.Cutover != CutoverDropOldFirst {
+		if err := b.RemoveTriggers(superseded); err != nil {
+			return result, err
+		}
+	}
+	for _, trigger := range retargeted {
+		if err := b.retarget(trigger, destination); err != nil {
+			return result, err
+		}
+	}
+	result.Component = t
+	result.EventTypes = eventTypesFilter
+	return result, nil
+}
+
+// ValidateTransformation checks the Bumblebee spec or the JQ expression
+// the way AddTransformation does, without creating the transformation.
+func (b *Builder) ValidateTransformation(engine string, spec []byte) error {
+	t, err := b.transformation("", engine, spec)
+	if err != nil {
+		return err
+	}
+	if _, err := t.AsK8sObject(); err != nil {
+		return fmt.Errorf("transformation spec: %w", err)
+	}
+	return nil
+}
+
+// transformation decodes the spec of the engine into the transformation
+// component of the matching kind.
+func (b *Builder) transformation(name, engine string, data []byte) (triggermesh.Component, error) {
+	if len(bytes.TrimSpace(data)) == 0 {
+		return nil, fmt.Errorf("empty spec")
+	}
+	kind := "transformation"
+	var spec map[string]interface{}
+	switch engine {
+	case transformation.EngineJQ:
+		kind = "jqtransformation"
+		query := strings.TrimSpace(string(data))
+		parsed, err := gojq.Parse(query)
+		if err != nil {
+			return nil, fmt.Errorf("JQ expression: %w", err)
+		}
+		if _, err := gojq.Compile(parsed); err != nil {
+			return nil, fmt.Errorf("JQ expression: %w", err)
+		}
+		spec = map[string]interface{}{
+			"query": query,
+		}
+	default:
+		if err := yaml.Unmarshal(data, &spec); err != nil {
+			return nil, fmt.Errorf("decode spec: %w", err)
+		}
+	}
+	crd, exists := b.CRD[kind]
+	if !exists {
+		return nil, fmt.Errorf("CRD for kind %q not found", kind)
+	}
+	return transformation.New(name, kind, b.Config.Context,
+		b.Config.Triggermesh.ComponentsVersion, crd, spec), nil
+}
+
+// outputStage returns the Bumblebee transformation setting the type of the
+// events produced by the JQ transformation and points the JQ sink to it.
+func (b *Builder) outputStage(t triggermesh.Component, eventType string) (triggermesh.Component, error) {
+	crd, exists := b.CRD["transformation"]
+	if !exists {
+		return nil, fmt.Errorf("CRD for kind %q not found", "transformation")
+	}
+	stage := transformation.New(transformation.OutputStage(t.GetName()), "transformation", b.Config.Context,
+		b.Config.Triggermesh.ComponentsVersion, crd, map[string]interface{}{})
+	stage.(*transformation.Transformation).Version = b.ComponentVersion(stage)
+	if err := stage.(triggermesh.Producer).SetEventAttributes(map[string]string{
+		"type": eventType,
+	}); err != nil {
+		return nil, fmt.Errorf("setting event type: %w", err)
+	}
+	stageObject, err := stage.AsK8sObject()
+	if err != nil {
+		return nil, fmt.Errorf("output stage object: %w", err)
+	}
+	t.(*transformation.Transformation).GetSpec()["sink"] = map[string]interface{}{
+		"ref": map[string]interface{}{
+			"apiVersion": stageObject.APIVersion,
+			"kind":       stageObject.Kind,
+			"name":       stageObject.Metadata.Name,
+		},
+	}
+	return stage, nil
+}
+
+// startOutputStage adds the output stage owned by the JQ transformation
+// to the manifest and starts its container sending the events to the broker.
+func (b *Builder) startOutputStage(ctx context.Context, owner, stage triggermesh.Component) (bool, error) {
+	applied, err := b.Apply(stage)
+	if err != nil {
+		return false, fmt.Errorf("unable to update manifest: %w", err)
+	}
+	restart := applied != manifest.Unchanged
+	if err := b.Manifest.Annotate(stage.GetName(), stage.GetKind(), triggermesh.OwnerAnnotation, owner.GetName()); err != nil {
+		return false, fmt.Errorf("unable to update manifest: %w", err)
+	}
+	sink, err := b.BrokerURL()
+	if err != nil {
+		return false, err
+	}
+	env, levelChanged, err := b.LoggingEnv(stage, map[string]string{"K_SINK": sink})
+	if err != nil {
+		return false, err
+	}
+	restart = restart || levelChanged
+	if err := b.start(ctx, stage, env, restart); err != nil {
+		return false, err
+	}
+	return restart, nil
+}
+
+// RemoveTriggers deletes the triggers from the broker config and the manifest.
+func (b *Builder) RemoveTriggers(triggers []*tmbroker.Trigger) error {
+	for _, trigger := range triggers {
+		if err := b.removeTrigger(trigger); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *Builder) removeTrigger(trigger *tmbroker.Trigger) error {
+	snapshot, err := b.snapshotTrigger(trigger.GetName())
+	if err != nil {
+		return err
+	}
+	defer b.recordTrigger(snapshot)
+	if err := trigger.RemoveFromLocalConfig(); err != nil {
+		return err
+	}
+	return b.Manifest.Remove(trigger.GetName(), trigger.GetKind())
+}
+
+// retarget points the trigger to the new destination.
+func (b *Builder) retarget(trigger *tmbroker.Trigger, destination triggermesh.Component) error {
+	snapshot, err := b.snapshotTrigger(trigger.GetName())
+	if err != nil {
+		return err
+	}
+	defer b.recordTrigger(snapshot)
+	trigger.SetTarget(destination)
+	if err := trigger.UpdateLocalTarget(); err != nil {
+		return err
+	}
+	_, err = b.Manifest.Add(trigger)
+	return err
+}
+
+// waitReady waits for the component container to pass its readiness probe.
+func (b *Builder) waitReady(ctx context.Context, c triggermesh.Component) error {
+	if b.WaitReady != nil {
+		return b.WaitReady(ctx, c)
+	}
+	runnable, ok := c.(triggermesh.Runnable)
+	if !ok {
+		return nil
+	}
+	var container *docker.Container
+	if err := b.Lookup("looking up container", func(ctx context.Context) (err error) {
+		container, err = runnable.Info(ctx)
+		return err
+	}); err != nil {
+		return err
+	}
+	r, err := components.Readiness(c, b.CRD)
+	if err != nil {
+		return err
+	}
+	timeout, err := time.ParseDuration(b.Config.Docker.StartTimeout)
+	if err != nil {
+		timeout = DefaultTimeout
+	}
+	if result := r.Wait(ctx, components.ReadinessTarget(runnable, container), timeout); result.Status == health.Unhealthy {
+		return fmt.Errorf("%q is not ready: %s", c.GetName(), result.Detail)
+	}
+	return nil
+}
+
+// verifyTriggers checks that the triggers are written to the broker config.
+func verifyTriggers(configBase, broker string, triggers []triggermesh.Component) error {
+	configuration, err := tmbroker.ReadConfig(configBase, broker)
+	if err != nil {
+		return fmt.Errorf("broker config: %w", err)
+	}
+	for _, trigger := range triggers {
+		spec, exists := configuration.Triggers[trigger.GetName()]
+		if !exists {
+			return fmt.Errorf("trigger %q is missing in the broker config", trigger.GetName())
+		}
+		if name := trigger.(*tmbroker.Trigger).TargetName(); spec.Target.Component != "" && spec.Target.Component != name {
+			return fmt.Errorf("trigger %q delivers events to %q instead of %q", trigger.GetName(), spec.Target.Component, name)
+		}
+	}
+	return nil
+}
+
+// hasFilter returns true if one of the trigger filters, all of which
+// the event must pass, is the same expression as the filter.
+func hasFilter(trigger *tmbroker.Trigger, filter eventingbroker.Filter) bool {
+	for _, f := range trigger.Filters {
+		if tmbroker.EqualFilter(f, filter) {
+			return true
+		}
+	}
+	return false
+}
+
+func triggerNames(lists ...[]*tmbroker.Trigger) string {
+	var names []string
+	for _, triggers := range lists {
+		for _, trigger := range triggers {
+			names = append(names, fmt.Sprintf("%q", trigger.GetName()))
+		}
+	}
+	return strings.Join(names, ", ")
+}
+
+func transformationContexts(target string, sourceEventTypes []string) string {
+	contexts := []string{}
+	for _, et := range sourceEventTypes {
+		c := et
+		if target != "" {
+			c = fmt.Sprintf("%s-%s", et, target)
+		}
+		contexts = append(contexts, c)
+	}
+	return strings.Join(contexts, ",")
+}
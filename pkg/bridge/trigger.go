@@ -0,0 +1,366 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bridge
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"knative.dev/pkg/apis"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/health"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/replicas"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+// AddTrigger creates the trigger delivering the events to the path
+// of the target address. The existing trigger with the same destination
+// and filters is reused instead of creating a duplicate that would deliver
+// every event twice. The trigger with the requested name keeps its name,
+// the duplicate route is reported instead. Triggers without the explicit
+// name are named after the TriggerName or the trigger name template
+// of the context, if any, such names must not be taken by the triggers
+// with other routes.
+func (b *Builder) AddTrigger(name string, target triggermesh.Component, path string, filter *eventingbroker.Filter) (triggermesh.Component, error) {
+	var err error
+	requested := name != "" || b.TriggerName != ""
+	implicit := false
+	generated := false
+	if name == "" {
+		if name, err = b.implicitTriggerName(target, path, filter); err != nil {
+			return nil, err
+		}
+		implicit = name != ""
+		generated = !implicit
+	}
+	trigger, err := tmbroker.NewTrigger(name, b.Config.Context, b.Config.ConfigHome, target, filter)
+	if err != nil {
+		return nil, err
+	}
+	// links to other brokers share the names with the local components
+	link := trigger.(*tmbroker.Trigger).IsLink()
+	if link {
+		path = ""
+	}
+	// routes to the different paths of the target are different triggers
+	if generated {
+		trigger.(*tmbroker.Trigger).Name = tmbroker.GeneratedName(b.Config.Context, target.GetName(), path, filter)
+	}
+	if existing, exists := tmbroker.FindTrigger(target.GetName(), path, b.Config.Context, b.Config.ConfigHome,
+		trigger.(*tmbroker.Trigger).Filters); !link && exists && existing != trigger.GetName() {
+		if requested {
+			b.progress("WARNING! Trigger %q has the same filters, %q receives the events of both triggers", existing, target.GetName())
+		} else {
+			b.progress("Trigger %q already exists", existing)
+			if trigger, err = tmbroker.NewTrigger(existing, b.Config.Context, b.Config.ConfigHome, target, filter); err != nil {
+				return nil, err
+			}
+		}
+	}
+	if implicit && !link && tmbroker.NameConflict(trigger.GetName(), target.GetName(), path, b.Config.Context, b.Config.ConfigHome,
+		trigger.(*tmbroker.Trigger).Filters) {
+		return nil, fmt.Errorf("trigger %q already exists with another target, path or filter", trigger.GetName())
+	}
+	if !link {
+		if err := trigger.(*tmbroker.Trigger).SetTargetPath(path); err != nil {
+			return nil, err
+		}
+	}
+	// scaled targets receive events through the replicas load balancer
+	proxyPort, scaled := replicas.ProxyPort(context.Background(), target.GetName())
+	if !link && scaled {
+		if err := trigger.(*tmbroker.Trigger).SetTargetPort(proxyPort); err != nil {
+			return nil, err
+		}
+	}
+	if b.VerifyDestination && !link && !b.DryRun {
+		if err := b.verifyDestination(trigger.(*tmbroker.Trigger), target, proxyPort); err != nil {
+			if b.Strict {
+				return nil, fmt.Errorf("trigger %q destination: %w", trigger.GetName(), err)
+			}
+			b.progress("WARNING! Trigger %q destination: %v", trigger.GetName(), err)
+		}
+	}
+	b.setContentMode(trigger.(*tmbroker.Trigger))
+	snapshot, err := b.snapshotTrigger(trigger.GetName())
+	if err != nil {
+		return nil, err
+	}
+	defer b.recordTrigger(snapshot)
+	if err := trigger.(*tmbroker.Trigger).WriteLocalConfig(); err != nil {
+		return nil, err
+	}
+	if err := b.startContentModeProxy(trigger.(*tmbroker.Trigger)); err != nil {
+		return nil, err
+	}
+	if _, err := b.Manifest.Add(trigger); err != nil {
+		return nil, err
+	}
+	return trigger, nil
+}
+
+// implicitTriggerName returns the name of the trigger created without the
+// explicit name: the TriggerName of the build or the name rendered from the
+// trigger name template of the context. Empty name lets the name be generated.
+func (b *Builder) implicitTriggerName(target triggermesh.Component, path string, filter *eventingbroker.Filter) (string, error) {
+	if b.TriggerName != "" {
+		return b.TriggerName, nil
+	}
+	var eventType string
+	if filter != nil {
+		eventType = filter.Exact["type"]
+	}
+	name, err := b.Config.TriggerName(config.TriggerNameData{
+		Broker:     b.Config.Context,
+		Target:     target.GetName(),
+		FilterHash: tmbroker.FilterHash(target.GetName(), path, filter),
+		EventType:  eventType,
+	})
+	if err != nil || name == "" {
+		return "", err
+	}
+	if err := triggermesh.ValidateName(name); err != nil {
+		return "", fmt.Errorf("trigger name template: %w", err)
+	}
+	return name, nil
+}
+
+// CheckTriggerName rejects the TriggerName of the build
+// creating other than exactly one trigger.
+func (b *Builder) CheckTriggerName(triggers int) error {
+	if b.TriggerName != "" && triggers != 1 {
+		return fmt.Errorf("trigger name %q requires exactly one trigger, %d would be created", b.TriggerName, triggers)
+	}
+	return nil
+}
+
+// verifyDestination checks that the target responds at the port written
+// into the trigger and, for the containers run by tmctl, that the port is
+// published by the target container rather than by another service.
+// Scaled targets are checked through the replicas load balancer port.
+func (b *Builder) verifyDestination(trigger *tmbroker.Trigger, target triggermesh.Component, proxyPort string) error {
+	port := proxyPort
+	if port == "" {
+		consumer, ok := target.(triggermesh.Consumer)
+		if !ok {
+			return nil
+		}
+		if err := b.Lookup("destination port", func(ctx context.Context) (err error) {
+			port, err = consumer.GetPort(ctx)
+			return err
+		}); err != nil {
+			return err
+		}
+	}
+	var path string
+	if trigger.LocalURL != nil {
+		path = trigger.LocalURL.Path
+	}
+	if err := health.Ping(context.Background(), urls.New("").URL(urls.Host, port)+path); err != nil {
+		return fmt.Errorf("%q does not respond at port %s: %w", target.GetName(), port, err)
+	}
+	if adopted, ok := target.(interface{ IsAdopted() bool }); proxyPort != "" || ok && adopted.IsAdopted() {
+		return nil
+	}
+	var owner string
+	if err := b.Lookup("destination container", func(ctx context.Context) error {
+		client, err := docker.ClientFrom(ctx)
+		if err != nil {
+			return err
+		}
+		owner, err = docker.PortOwner(ctx, client, port)
+		return err
+	}); err != nil {
+		return err
+	}
+	switch owner {
+	case target.GetName():
+		return nil
+	case "":
+		return fmt.Errorf("no container publishes port %s of %q", port, target.GetName())
+	}
+	return fmt.Errorf("port %s belongs to %q rather than %q", port, owner, target.GetName())
+}
+
+// AddURITrigger creates the trigger delivering the events
+// to the external URI instead of the component of the context.
+func (b *Builder) AddURITrigger(name string, uri *apis.URL, filter *eventingbroker.Filter) (triggermesh.Component, error) {
+	trigger, err := tmbroker.NewURITrigger(name, b.Config.Context, b.Config.ConfigHome, uri, filter)
+	if err != nil {
+		return nil, err
+	}
+	b.setContentMode(trigger.(*tmbroker.Trigger))
+	snapshot, err := b.snapshotTrigger(trigger.GetName())
+	if err != nil {
+		return nil, err
+	}
+	defer b.recordTrigger(snapshot)
+	if err := trigger.(*tmbroker.Trigger).WriteLocalConfig(); err != nil {
+		return nil, err
+	}
+	if err := b.startContentModeProxy(trigger.(*tmbroker.Trigger)); err != nil {
+		return nil, err
+	}
+	if _, err := b.Manifest.Add(trigger); err != nil {
+		return nil, err
+	}
+	return trigger, nil
+}
+
+// setContentMode sets the requested content mode of the trigger,
+// the existing trigger keeps its mode if none is requested.
+func (b *Builder) setContentMode(trigger *tmbroker.Trigger) {
+	switch b.ContentMode {
+	case "":
+		if configuration, err := tmbroker.ReadConfig(b.Config.ConfigHome, b.Config.Context); err == nil {
+			trigger.ContentMode = configuration.Triggers[trigger.Name].Target.ContentMode
+		}
+	case tmbroker.ContentModeBinary:
+		trigger.ContentMode = ""
+	default:
+		trigger.ContentMode = b.ContentMode
+	}
+}
+
+// startContentModeProxy starts the proxy re-encoding the events
+// of the structured content mode trigger.
+func (b *Builder) startContentModeProxy(trigger *tmbroker.Trigger) error {
+	if trigger.ContentMode != tmbroker.ContentModeStructured || b.DryRun {
+		return nil
+	}
+	// no lookup timeout, the proxy image may need to be pulled
+	if err := tmbroker.StartProxy(context.Background(), b.Config.ConfigHome, b.Config.Context); err != nil {
+		return fmt.Errorf("trigger %q: %w", trigger.GetName(), err)
+	}
+	return nil
+}
+
+// UpdateTriggers points the triggers of the target to its current address,
+// e.g. after the target container restart.
+func (b *Builder) UpdateTriggers(target triggermesh.Component) error {
+	triggers, err := tmbroker.GetTargetTriggers(target.GetName(), b.Config.Context, b.Config.ConfigHome)
+	if err != nil {
+		return fmt.Errorf("target triggers: %w", err)
+	}
+	port, scaled := replicas.ProxyPort(context.Background(), target.GetName())
+	for _, trigger := range triggers {
+		trigger.(*tmbroker.Trigger).SetTarget(target)
+		if scaled {
+			if err := trigger.(*tmbroker.Trigger).SetTargetPort(port); err != nil {
+				return fmt.Errorf("trigger %q: %w", trigger.GetName(), err)
+			}
+		}
+		if err := trigger.(*tmbroker.Trigger).UpdateLocalTarget(); err != nil {
+			return fmt.Errorf("broker config update: %w", err)
+		}
+	}
+	return nil
+}
+
+// SyncTargetPort points the triggers of the started target to the live host
+// port of its container and records the port in the component annotation.
+func (b *Builder) SyncTargetPort(target triggermesh.Component, container *docker.Container) ([]tmbroker.PortFix, error) {
+	port := container.HostPort()
+	if port == "" {
+		return nil, nil
+	}
+	fixes, err := tmbroker.SyncTargetPort(target.GetName(), port, b.Config.Context, b.Config.ConfigHome)
+	if err != nil {
+		return fixes, fmt.Errorf("broker config update: %w", err)
+	}
+	for _, fix := range fixes {
+		b.progress("Trigger %q pointed to %s instead of the stale %s", fix.Trigger, fix.To, fix.From)
+	}
+	for _, object := range b.Manifest.Objects {
+		if object.Metadata.Name != target.GetName() || !strings.EqualFold(object.Kind, target.GetKind()) {
+			continue
+		}
+		if recorded := object.Metadata.Annotations[triggermesh.HostPortAnnotation]; recorded != "" && recorded != port {
+			b.progress("%s host port changed from %s to %s", target.GetName(), recorded, port)
+		}
+		if err := b.Manifest.Annotate(object.Metadata.Name, object.Kind, triggermesh.HostPortAnnotation, port); err != nil {
+			return fixes, fmt.Errorf("unable to update manifest: %w", err)
+		}
+		break
+	}
+	return fixes, nil
+}
+
+// ReplyEventType returns the type of the events the target replies with.
+// The requested type must be one of the response types declared by the target,
+// it is required if the target declares more than one type or any type.
+func ReplyEventType(target triggermesh.Component, eventType string) (string, error) {
+	replier, ok := target.(triggermesh.Replier)
+	if !ok {
+		return "", fmt.Errorf("%q does not reply to the events", target.GetName())
+	}
+	declared, err := replier.ProducedEventTypes()
+	if err != nil {
+		return "", fmt.Errorf("%q response event types: %w", target.GetName(), err)
+	}
+	if len(declared) == 0 {
+		return "", fmt.Errorf("%s declares no response event types", target.GetKind())
+	}
+	for _, t := range declared {
+		if eventType != "" && (t == eventType || t == "*") {
+			return eventType, nil
+		}
+	}
+	if eventType != "" {
+		return "", fmt.Errorf("%s does not reply with %q events, declared types: %s",
+			target.GetKind(), eventType, strings.Join(declared, ", "))
+	}
+	if len(declared) != 1 || declared[0] == "*" {
+		return "", fmt.Errorf("reply event type is required, %s replies with %s",
+			target.GetKind(), strings.Join(declared, ", "))
+	}
+	return declared[0], nil
+}
+
+// AddReply creates the trigger delivering the reply events of the target
+// to the consumer and records the relationship in the target annotations.
+func (b *Builder) AddReply(target triggermesh.Component, consumer, eventType string) (triggermesh.Component, error) {
+	if consumer == target.GetName() {
+		return nil, fmt.Errorf("%q cannot consume its own replies", consumer)
+	}
+	replyType, err := ReplyEventType(target, eventType)
+	if err != nil {
+		return nil, err
+	}
+	c, err := b.LookupTarget(consumer)
+	if err != nil {
+		return nil, err
+	}
+	trigger, err := b.AddTrigger("", c, "", tmbroker.FilterAttribute("type", replyType))
+	if err != nil {
+		return nil, fmt.Errorf("reply trigger: %w", err)
+	}
+	if err := b.Manifest.Annotate(target.GetName(), target.GetKind(), triggermesh.ReplyToAnnotation, consumer); err != nil {
+		return nil, fmt.Errorf("unable to update manifest: %w", err)
+	}
+	if err := b.Manifest.Annotate(target.GetName(), target.GetKind(), triggermesh.ReplyEventTypeAnnotation, replyType); err != nil {
+		return nil, fmt.Errorf("unable to update manifest: %w", err)
+	}
+	return trigger, nil
+}
@@ -0,0 +1,274 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package bundle packs the component with its triggers and secrets
+// into the archive that can be imported into another broker context.
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"crypto/aes"
+	"crypto/cipher"
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"io"
+	"time"
+
+	"golang.org/x/crypto/scrypt"
+	"gopkg.in/yaml.v3"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+)
+
+// Version is the current bundle format version.
+const Version = 1
+
+const (
+	metadataFile = "bundle.yaml"
+	objectsFile  = "objects.yaml"
+	secretsFile  = "secrets.enc"
+
+	saltSize = 16
+	keySize  = 32
+)
+
+// ErrPassphrase is returned when the bundle secrets cannot be decrypted.
+var ErrPassphrase = errors.New("wrong passphrase or corrupted secrets")
+
+// Bundle is the exported component.
+type Bundle struct {
+	Version   int       `yaml:"version"`
+	Component string    `yaml:"component"`
+	Kind      string    `yaml:"kind"`
+	Created   time.Time `yaml:"created"`
+	Encrypted bool      `yaml:"encrypted,omitempty"`
+
+	// Objects are the component and its triggers.
+	Objects []kubernetes.Object `yaml:"-"`
+	// Secrets are stored encrypted in the archive.
+	Secrets []kubernetes.Object `yaml:"-"`
+}
+
+// Write packs the bundle into the gzipped tar archive.
+// Secrets are encrypted with the key derived from the passphrase.
+func Write(w io.Writer, b *Bundle, passphrase string) error {
+	b.Version = Version
+	b.Encrypted = len(b.Secrets) != 0
+	if b.Encrypted && passphrase == "" {
+		return fmt.Errorf("passphrase is required to export secrets")
+	}
+
+	gw := gzip.NewWriter(w)
+	tw := tar.NewWriter(gw)
+
+	metadata, err := yaml.Marshal(b)
+	if err != nil {
+		return fmt.Errorf("bundle metadata: %w", err)
+	}
+	if err := writeFile(tw, metadataFile, metadata); err != nil {
+		return err
+	}
+	objects, err := marshalObjects(b.Objects)
+	if err != nil {
+		return fmt.Errorf("bundle objects: %w", err)
+	}
+	if err := writeFile(tw, objectsFile, objects); err != nil {
+		return err
+	}
+	if b.Encrypted {
+		secrets, err := marshalObjects(b.Secrets)
+		if err != nil {
+			return fmt.Errorf("bundle secrets: %w", err)
+		}
+		encrypted, err := encrypt(secrets, passphrase)
+		if err != nil {
+			return fmt.Errorf("encrypting secrets: %w", err)
+		}
+		if err := writeFile(tw, secretsFile, encrypted); err != nil {
+			return err
+		}
+	}
+	if err := tw.Close(); err != nil {
+		return err
+	}
+	return gw.Close()
+}
+
+// Read unpacks the bundle archive. Passphrase function is called
+// only if the bundle contains encrypted secrets.
+func Read(r io.Reader, passphrase func() (string, error)) (*Bundle, error) {
+	gr, err := gzip.NewReader(r)
+	if err != nil {
+		return nil, fmt.Errorf("bundle archive: %w", err)
+	}
+	defer gr.Close()
+
+	files := make(map[string][]byte)
+	tr := tar.NewReader(gr)
+	for {
+		header, err := tr.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, fmt.Errorf("bundle archive: %w", err)
+		}
+		data, err := io.ReadAll(tr)
+		if err != nil {
+			return nil, fmt.Errorf("bundle archive: %w", err)
+		}
+		files[header.Name] = data
+	}
+
+	metadata, exists := files[metadataFile]
+	if !exists {
+		return nil, fmt.Errorf("bundle metadata is missing")
+	}
+	var b Bundle
+	if err := yaml.Unmarshal(metadata, &b); err != nil {
+		return nil, fmt.Errorf("bundle metadata: %w", err)
+	}
+	if err := migrate(&b, files); err != nil {
+		return nil, err
+	}
+	if b.Objects, err = unmarshalObjects(files[objectsFile]); err != nil {
+		return nil, fmt.Errorf("bundle objects: %w", err)
+	}
+	if !b.Encrypted {
+		return &b, nil
+	}
+	key, err := passphrase()
+	if err != nil {
+		return nil, fmt.Errorf("passphrase: %w", err)
+	}
+	secrets, err := decrypt(files[secretsFile], key)
+	if err != nil {
+		return nil, err
+	}
+	if b.Secrets, err = unmarshalObjects(secrets); err != nil {
+		return nil, fmt.Errorf("bundle secrets: %w", err)
+	}
+	return &b, nil
+}
+
+// migrate converts the bundles created by older tmctl versions to the current format.
+func migrate(b *Bundle, files map[string][]byte) error {
+	switch {
+	case b.Version > Version:
+		return fmt.Errorf("bundle version %d is not supported, please upgrade tmctl", b.Version)
+	case b.Version < 1:
+		return fmt.Errorf("invalid bundle version %d", b.Version)
+	}
+	// version 1 is the current format
+	return nil
+}
+
+func writeFile(tw *tar.Writer, name string, data []byte) error {
+	if err := tw.WriteHeader(&tar.Header{
+		Name:    name,
+		Mode:    0o600,
+		Size:    int64(len(data)),
+		ModTime: time.Now(),
+	}); err != nil {
+		return err
+	}
+	_, err := tw.Write(data)
+	return err
+}
+
+func marshalObjects(objects []kubernetes.Object) ([]byte, error) {
+	var out bytes.Buffer
+	encoder := yaml.NewEncoder(&out)
+	for _, object := range objects {
+		if err := encoder.Encode(object); err != nil {
+			return nil, err
+		}
+	}
+	if err := encoder.Close(); err != nil {
+		return nil, err
+	}
+	return out.Bytes(), nil
+}
+
+func unmarshalObjects(data []byte) ([]kubernetes.Object, error) {
+	var result []kubernetes.Object
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var object kubernetes.Object
+		err := decoder.Decode(&object)
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return nil, err
+		}
+		result = append(result, object)
+	}
+	return result, nil
+}
+
+// encrypt seals the data with AES-GCM, the key is derived from
+// the passphrase with scrypt. The output is salt | nonce | ciphertext.
+func encrypt(data []byte, passphrase string) ([]byte, error) {
+	salt := make([]byte, saltSize)
+	if _, err := rand.Read(salt); err != nil {
+		return nil, err
+	}
+	aead, err := newAEAD(passphrase, salt)
+	if err != nil {
+		return nil, err
+	}
+	nonce := make([]byte, aead.NonceSize())
+	if _, err := rand.Read(nonce); err != nil {
+		return nil, err
+	}
+	out := append(salt, nonce...)
+	return aead.Seal(out, nonce, data, nil), nil
+}
+
+func decrypt(data []byte, passphrase string) ([]byte, error) {
+	if len(data) < saltSize {
+		return nil, ErrPassphrase
+	}
+	aead, err := newAEAD(passphrase, data[:saltSize])
+	if err != nil {
+		return nil, err
+	}
+	data = data[saltSize:]
+	if len(data) < aead.NonceSize() {
+		return nil, ErrPassphrase
+	}
+	plain, err := aead.Open(nil, data[:aead.NonceSize()], data[aead.NonceSize():], nil)
+	if err != nil {
+		return nil, ErrPassphrase
+	}
+	return plain, nil
+}
+
+func newAEAD(passphrase string, salt []byte) (cipher.AEAD, error) {
+	key, err := scrypt.Key([]byte(passphrase), salt, 1<<15, 8, 1, keySize)
+	if err != nil {
+		return nil, err
+	}
+	block, err := aes.NewCipher(key)
+	if err != nil {
+		return nil, err
+	}
+	return cipher.NewGCM(block)
+}
@@ -0,0 +1,100 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package bundle
+
+import (
+	"archive/tar"
+	"bytes"
+	"compress/gzip"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+)
+
+func testBundle() *Bundle {
+	return &Bundle{
+		Component: "foo-salesforcesource",
+		Kind:      "SalesforceSource",
+		Objects: []kubernetes.Object{
+			{
+				APIVersion: "sources.triggermesh.io/v1alpha1",
+				Kind:       "SalesforceSource",
+				Metadata: kubernetes.Metadata{
+					Name:   "foo-salesforcesource",
+					Labels: map[string]string{"triggermesh.io/context": "foo"},
+				},
+				Spec: map[string]interface{}{"subscription": map[string]interface{}{"channel": "/data/ChangeEvents"}},
+			},
+		},
+		Secrets: []kubernetes.Object{
+			{
+				APIVersion: "v1",
+				Kind:       "Secret",
+				Metadata: kubernetes.Metadata{
+					Name:   "foo-salesforcesource-secret",
+					Labels: map[string]string{"triggermesh.io/context": "foo"},
+				},
+				Data: map[string]string{"certKey": "c2VjcmV0"},
+			},
+		},
+	}
+}
+
+func TestRoundTrip(t *testing.T) {
+	var archive bytes.Buffer
+	assert.NoError(t, Write(&archive, testBundle(), "s3cret"))
+	assert.NotContains(t, archive.String(), "c2VjcmV0")
+
+	b, err := Read(bytes.NewReader(archive.Bytes()), func() (string, error) { return "s3cret", nil })
+	assert.NoError(t, err)
+	assert.Equal(t, Version, b.Version)
+	assert.Equal(t, "foo-salesforcesource", b.Component)
+	assert.Equal(t, testBundle().Objects, b.Objects)
+	assert.Equal(t, testBundle().Secrets, b.Secrets)
+
+	_, err = Read(bytes.NewReader(archive.Bytes()), func() (string, error) { return "wrong", nil })
+	assert.ErrorIs(t, err, ErrPassphrase)
+}
+
+func TestWithoutSecrets(t *testing.T) {
+	b := testBundle()
+	b.Secrets = nil
+	var archive bytes.Buffer
+	assert.NoError(t, Write(&archive, b, ""))
+	result, err := Read(&archive, func() (string, error) {
+		t.Fatal("passphrase must not be requested")
+		return "", nil
+	})
+	assert.NoError(t, err)
+	assert.Empty(t, result.Secrets)
+
+	assert.Error(t, Write(&bytes.Buffer{}, testBundle(), ""))
+}
+
+func TestUnsupportedVersion(t *testing.T) {
+	var archive bytes.Buffer
+	gw := gzip.NewWriter(&archive)
+	tw := tar.NewWriter(gw)
+	assert.NoError(t, writeFile(tw, metadataFile, []byte("version: 2\ncomponent: foo\n")))
+	assert.NoError(t, tw.Close())
+	assert.NoError(t, gw.Close())
+
+	_, err := Read(&archive, nil)
+	assert.ErrorContains(t, err, "bundle version 2 is not supported")
+}
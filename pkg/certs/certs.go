@@ -0,0 +1,263 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package certs provisions the local certificate authority and
+// the certificates used to encrypt traffic between the broker and components.
+package certs
+
+import (
+	"crypto/ecdsa"
+	"crypto/elliptic"
+	"crypto/rand"
+	"crypto/x509"
+	"crypto/x509/pkix"
+	"encoding/pem"
+	"fmt"
+	"math/big"
+	"net"
+	"os"
+	"path/filepath"
+	"time"
+
+	"github.com/triggermesh/tmctl/pkg/secrets"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+const (
+	// Dir is the certificates directory in the broker context.
+	Dir = "certs"
+	// MountPath is the certificates directory inside the containers.
+	MountPath = "/etc/triggermesh/certs"
+
+	// Container environment variables pointing to the certificates.
+	CertFileEnv = "TLS_CERT_FILE"
+	KeyFileEnv  = "TLS_KEY_FILE"
+	CAFileEnv   = "SSL_CERT_FILE"
+	// HostDirEnv is not passed to the container, it sets
+	// the host directory mounted into the MountPath.
+	HostDirEnv = "TMCTL_CERTS_DIR"
+
+	// Secret fields of the component certificate in the secrets store.
+	CertField = "tls.crt"
+	KeyField  = "tls.key"
+
+	caName = "ca"
+
+	caValidity   = 10 * 365 * 24 * time.Hour
+	certValidity = 365 * 24 * time.Hour
+	// certificates expiring sooner are issued again
+	renewBefore = 30 * 24 * time.Hour
+)
+
+// hosts are the names components use to reach each other locally.
+var hosts = []string{"localhost", urls.DockerHost}
+
+// Provision creates the certificate authority in the directory, if it
+// does not exist yet, and issues the certificates for the named components
+// into the secrets store.
+// Existing certificates are kept unless they expire soon or were issued by another CA.
+func Provision(dir string, store *secrets.Store, names ...string) error {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return fmt.Errorf("certificates directory: %w", err)
+	}
+	ca, caKey, err := loadOrCreateCA(dir)
+	if err != nil {
+		return fmt.Errorf("certificate authority: %w", err)
+	}
+	for _, name := range names {
+		// previous versions kept the component certificates next to the CA
+		_ = os.Remove(filepath.Join(dir, name+".crt"))
+		_ = os.Remove(filepath.Join(dir, name+".key"))
+		if cert, _, err := loadComponent(store, name); err == nil && valid(cert, ca) {
+			continue
+		}
+		if err := issue(store, name, ca, caKey); err != nil {
+			return fmt.Errorf("%q certificate: %w", name, err)
+		}
+	}
+	return nil
+}
+
+// CAFile returns the path of the CA certificate file.
+func CAFile(dir string) string {
+	return filepath.Join(dir, caName+".crt")
+}
+
+func caKeyFile(dir string) string {
+	return filepath.Join(dir, caName+".key")
+}
+
+// Env returns the environment variables pointing to the CA certificate
+// mounted from the directory and to the component's certificate mounted
+// from the secrets store.
+// Empty name returns the CA certificate variables only.
+func Env(dir string, store *secrets.Store, name string) (map[string]string, error) {
+	env := map[string]string{
+		HostDirEnv: dir,
+		CAFileEnv:  CAFile(MountPath),
+	}
+	if name == "" {
+		return env, nil
+	}
+	cert, err := store.MountedPath(name, CertField)
+	if err != nil {
+		return nil, err
+	}
+	key, err := store.MountedPath(name, KeyField)
+	if err != nil {
+		return nil, err
+	}
+	env[secrets.HostDirEnv] = store.Dir()
+	env[CertFileEnv] = cert
+	env[KeyFileEnv] = key
+	return env, nil
+}
+
+func loadOrCreateCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	if cert, key, err := loadCA(dir); err == nil && time.Now().Add(renewBefore).Before(cert.NotAfter) {
+		return cert, key, nil
+	}
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return nil, nil, err
+	}
+	template, err := newTemplate("tmctl local CA", caValidity)
+	if err != nil {
+		return nil, nil, err
+	}
+	template.IsCA = true
+	template.BasicConstraintsValid = true
+	template.KeyUsage = x509.KeyUsageCertSign | x509.KeyUsageCRLSign
+	der, err := x509.CreateCertificate(rand.Reader, template, template, &key.PublicKey, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	certPEM, keyPEM, err := encode(der, key)
+	if err != nil {
+		return nil, nil, err
+	}
+	if err := os.WriteFile(caKeyFile(dir), keyPEM, 0o600); err != nil {
+		return nil, nil, err
+	}
+	// containers may run under a different user
+	if err := os.WriteFile(CAFile(dir), certPEM, 0o644); err != nil {
+		return nil, nil, err
+	}
+	cert, err := x509.ParseCertificate(der)
+	return cert, key, err
+}
+
+func issue(store *secrets.Store, name string, ca *x509.Certificate, caKey *ecdsa.PrivateKey) error {
+	key, err := ecdsa.GenerateKey(elliptic.P256(), rand.Reader)
+	if err != nil {
+		return err
+	}
+	template, err := newTemplate(name, certValidity)
+	if err != nil {
+		return err
+	}
+	template.KeyUsage = x509.KeyUsageDigitalSignature
+	template.ExtKeyUsage = []x509.ExtKeyUsage{x509.ExtKeyUsageServerAuth, x509.ExtKeyUsageClientAuth}
+	template.DNSNames = append([]string{name}, hosts...)
+	template.IPAddresses = []net.IP{net.IPv4(127, 0, 0, 1), net.IPv6loopback}
+	der, err := x509.CreateCertificate(rand.Reader, template, ca, &key.PublicKey, caKey)
+	if err != nil {
+		return err
+	}
+	certPEM, keyPEM, err := encode(der, key)
+	if err != nil {
+		return err
+	}
+	if err := store.Write(name, KeyField, keyPEM, 0o600); err != nil {
+		return err
+	}
+	// containers may run under a different user
+	return store.Write(name, CertField, certPEM, 0o644)
+}
+
+func newTemplate(commonName string, validity time.Duration) (*x509.Certificate, error) {
+	serial, err := rand.Int(rand.Reader, new(big.Int).Lsh(big.NewInt(1), 128))
+	if err != nil {
+		return nil, err
+	}
+	now := time.Now()
+	return &x509.Certificate{
+		SerialNumber: serial,
+		Subject: pkix.Name{
+			Organization: []string{"TriggerMesh"},
+			CommonName:   commonName,
+		},
+		NotBefore: now.Add(-time.Hour),
+		NotAfter:  now.Add(validity),
+	}, nil
+}
+
+func valid(cert, ca *x509.Certificate) bool {
+	if time.Now().Add(renewBefore).After(cert.NotAfter) {
+		return false
+	}
+	return cert.CheckSignatureFrom(ca) == nil
+}
+
+func encode(der []byte, key *ecdsa.PrivateKey) ([]byte, []byte, error) {
+	keyDER, err := x509.MarshalECPrivateKey(key)
+	if err != nil {
+		return nil, nil, err
+	}
+	return pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: der}),
+		pem.EncodeToMemory(&pem.Block{Type: "EC PRIVATE KEY", Bytes: keyDER}), nil
+}
+
+func loadCA(dir string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPEM, err := os.ReadFile(CAFile(dir))
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := os.ReadFile(caKeyFile(dir))
+	if err != nil {
+		return nil, nil, err
+	}
+	return decode(caName, certPEM, keyPEM)
+}
+
+func loadComponent(store *secrets.Store, name string) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certPEM, err := store.Read(name, CertField)
+	if err != nil {
+		return nil, nil, err
+	}
+	keyPEM, err := store.Read(name, KeyField)
+	if err != nil {
+		return nil, nil, err
+	}
+	return decode(name, certPEM, keyPEM)
+}
+
+func decode(name string, certPEM, keyPEM []byte) (*x509.Certificate, *ecdsa.PrivateKey, error) {
+	certBlock, _ := pem.Decode(certPEM)
+	keyBlock, _ := pem.Decode(keyPEM)
+	if certBlock == nil || keyBlock == nil {
+		return nil, nil, fmt.Errorf("%q: invalid PEM data", name)
+	}
+	cert, err := x509.ParseCertificate(certBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	key, err := x509.ParseECPrivateKey(keyBlock.Bytes)
+	if err != nil {
+		return nil, nil, err
+	}
+	return cert, key, nil
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package certs
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/secrets"
+)
+
+func TestProvision(t *testing.T) {
+	dir := t.TempDir()
+	store := secrets.New(t.TempDir())
+	assert.NoError(t, Provision(dir, store, "foo-broker", "sockeye"))
+
+	caPEM, err := os.ReadFile(CAFile(dir))
+	assert.NoError(t, err)
+	pool := x509.NewCertPool()
+	assert.True(t, pool.AppendCertsFromPEM(caPEM))
+
+	certFile, err := store.Path("sockeye", CertField)
+	assert.NoError(t, err)
+	keyFile, err := store.Path("sockeye", KeyField)
+	assert.NoError(t, err)
+	info, err := os.Stat(keyFile)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	pair, err := tls.LoadX509KeyPair(certFile, keyFile)
+	assert.NoError(t, err)
+	cert, err := x509.ParseCertificate(pair.Certificate[0])
+	assert.NoError(t, err)
+	for _, host := range []string{"sockeye", "localhost", "host.docker.internal", "127.0.0.1"} {
+		_, err := cert.Verify(x509.VerifyOptions{DNSName: host, Roots: pool})
+		assert.NoError(t, err, host)
+	}
+
+	// provisioning is idempotent
+	before, err := os.ReadFile(certFile)
+	assert.NoError(t, err)
+	assert.NoError(t, Provision(dir, store, "sockeye"))
+	after, err := os.ReadFile(certFile)
+	assert.NoError(t, err)
+	assert.Equal(t, before, after)
+
+	// certificates of the replaced CA are issued again
+	assert.NoError(t, os.Remove(CAFile(dir)))
+	assert.NoError(t, Provision(dir, store, "sockeye"))
+	after, err = os.ReadFile(certFile)
+	assert.NoError(t, err)
+	assert.NotEqual(t, before, after)
+
+	env, err := Env(dir, store, "sockeye")
+	assert.NoError(t, err)
+	assert.Equal(t, store.Dir(), env[secrets.HostDirEnv])
+	assert.NotContains(t, env[KeyFileEnv], "sockeye")
+
+	_, err = Env(dir, store, "unknown")
+	assert.ErrorIs(t, err, secrets.ErrNotFound)
+}
@@ -0,0 +1,180 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package changeset collects the changes made by the multi-component commands
+// from the lifecycle events, so that the command can print what it did.
+// The changes planned by the dry run share the same representation.
+package changeset
+
+import (
+	"fmt"
+	"io"
+	"strings"
+	"sync"
+	"text/tabwriter"
+
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
+)
+
+// ContainerKind is the kind of the component container changes.
+const ContainerKind = "Container"
+
+// Action is the change made to the object.
+type Action string
+
+const (
+	Created   Action = "created"
+	Updated   Action = "updated"
+	Removed   Action = "removed"
+	Started   Action = "started"
+	Restarted Action = "restarted"
+	Stopped   Action = "stopped"
+)
+
+// Change is the single change of the context object.
+type Change struct {
+	Object string `json:"object"`
+	Kind   string `json:"kind"`
+	Action Action `json:"action"`
+	Detail string `json:"detail,omitempty"`
+}
+
+// ID returns the object reference in the kind/name form.
+func (c Change) ID() string {
+	return strings.ToLower(c.Kind) + "/" + c.Object
+}
+
+// Set is the ordered list of the changes.
+type Set struct {
+	mu      sync.Mutex
+	changes []Change
+}
+
+// Collect records the changes of the lifecycle events emitted until the returned
+// function is called. Planned changes are collected with the events kept
+// out of the log.
+func Collect(plan bool) (*Set, func()) {
+	s := &Set{}
+	record := func(event lifecycle.Event) {
+		if change, ok := FromEvent(event); ok {
+			s.Record(change)
+		}
+	}
+	if plan {
+		return s, lifecycle.Capture(record)
+	}
+	return s, lifecycle.Subscribe(record)
+}
+
+// FromEvent returns the change described by the lifecycle event.
+func FromEvent(event lifecycle.Event) (Change, bool) {
+	change := Change{
+		Object: event.Component,
+		Kind:   event.Kind,
+	}
+	switch event.Type {
+	case lifecycle.ComponentCreated, lifecycle.TriggerAdded:
+		change.Action = Created
+	case lifecycle.ComponentUpdated, lifecycle.TriggerUpdated:
+		change.Action = Updated
+	case lifecycle.ComponentDeleted, lifecycle.TriggerRemoved:
+		change.Action = Removed
+	case lifecycle.ContainerStarted:
+		change.Kind = ContainerKind
+		change.Action = Started
+		if event.Details["restarted"] == "true" {
+			change.Action = Restarted
+		}
+	case lifecycle.ContainerStopped:
+		change.Kind = ContainerKind
+		change.Action = Stopped
+	default:
+		return Change{}, false
+	}
+	if target := event.Details["target"]; target != "" {
+		change.Detail = "target " + target
+	}
+	return change, true
+}
+
+// Record adds the change to the set. Repeated changes of the object are merged:
+// the trigger is reported by both the broker config and the manifest,
+// the object created by the command is not reported as updated.
+func (s *Set) Record(change Change) {
+	if s == nil {
+		return
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	for i, c := range s.changes {
+		if c.Object != change.Object || c.Kind != change.Kind {
+			continue
+		}
+		if c.Action == change.Action || (c.Action == Created && change.Action == Updated) {
+			if change.Detail != "" {
+				s.changes[i].Detail = change.Detail
+			}
+			return
+		}
+	}
+	s.changes = append(s.changes, change)
+}
+
+// Changes returns the recorded changes in their order.
+func (s *Set) Changes() []Change {
+	if s == nil {
+		return nil
+	}
+	s.mu.Lock()
+	defer s.mu.Unlock()
+	return append([]Change{}, s.changes...)
+}
+
+// Empty is true if nothing has been changed.
+func (s *Set) Empty() bool {
+	return len(s.Changes()) == 0
+}
+
+// Print writes the changes table.
+func (s *Set) Print(w io.Writer) {
+	changes := s.Changes()
+	if len(changes) == 0 {
+		fmt.Fprintln(w, "No changes")
+		return
+	}
+	table := tabwriter.NewWriter(w, 10, 5, 5, ' ', 0)
+	fmt.Fprintln(table, "Object\tAction\tDetail")
+	for _, c := range changes {
+		fmt.Fprintf(table, "%s\t%s\t%s\n", c.ID(), c.Action, c.Detail)
+	}
+	table.Flush()
+}
+
+// Emit writes the summary event with the changes to the lifecycle log.
+func (s *Set) Emit() {
+	details := make(map[string]string)
+	for _, c := range s.Changes() {
+		action := string(c.Action)
+		if c.Detail != "" {
+			action += " (" + c.Detail + ")"
+		}
+		if previous, exists := details[c.ID()]; exists {
+			action = previous + ", " + action
+		}
+		details[c.ID()] = action
+	}
+	lifecycle.Emit(lifecycle.Summary, "", "", details)
+}
@@ -0,0 +1,97 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package changeset
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
+)
+
+func TestFromEvent(t *testing.T) {
+	cases := map[string]struct {
+		event    lifecycle.Event
+		expected Change
+		ok       bool
+	}{
+		"component created": {
+			event:    lifecycle.Event{Type: lifecycle.ComponentCreated, Component: "foo", Kind: "Transformation"},
+			expected: Change{Object: "foo", Kind: "Transformation", Action: Created},
+			ok:       true,
+		},
+		"trigger updated": {
+			event: lifecycle.Event{Type: lifecycle.TriggerUpdated, Component: "foo-trigger", Kind: "Trigger",
+				Details: map[string]string{"target": "sockeye"}},
+			expected: Change{Object: "foo-trigger", Kind: "Trigger", Action: Updated, Detail: "target sockeye"},
+			ok:       true,
+		},
+		"container restarted": {
+			event: lifecycle.Event{Type: lifecycle.ContainerStarted, Component: "foo",
+				Details: map[string]string{"image": "foo:latest", "restarted": "true"}},
+			expected: Change{Object: "foo", Kind: ContainerKind, Action: Restarted},
+			ok:       true,
+		},
+		"manifest written": {
+			event: lifecycle.Event{Type: lifecycle.ManifestWritten},
+		},
+	}
+	for name, tc := range cases {
+		t.Run(name, func(t *testing.T) {
+			change, ok := FromEvent(tc.event)
+			assert.Equal(t, tc.ok, ok)
+			assert.Equal(t, tc.expected, change)
+		})
+	}
+}
+
+func TestCollect(t *testing.T) {
+	s, stop := Collect(false)
+	// the trigger is reported by both the broker config and the manifest
+	lifecycle.Emit(lifecycle.TriggerAdded, "foo-trigger", "Trigger", map[string]string{"target": "sockeye"})
+	lifecycle.Emit(lifecycle.ComponentCreated, "foo-trigger", "Trigger", nil)
+	lifecycle.Emit(lifecycle.ComponentUpdated, "foo-trigger", "Trigger", nil)
+	lifecycle.Emit(lifecycle.TriggerRemoved, "bar-trigger", "Trigger", nil)
+	lifecycle.Emit(lifecycle.ContainerStarted, "foo", "", nil)
+	stop()
+	lifecycle.Emit(lifecycle.ComponentDeleted, "foo", "Transformation", nil)
+
+	assert.Equal(t, []Change{
+		{Object: "foo-trigger", Kind: "Trigger", Action: Created, Detail: "target sockeye"},
+		{Object: "bar-trigger", Kind: "Trigger", Action: Removed},
+		{Object: "foo", Kind: ContainerKind, Action: Started},
+	}, s.Changes())
+
+	var out bytes.Buffer
+	s.Print(&out)
+	assert.Equal(t, "Object                  Action      Detail\n"+
+		"trigger/foo-trigger     created     target sockeye\n"+
+		"trigger/bar-trigger     removed     \n"+
+		"container/foo           started     \n", out.String())
+}
+
+func TestEmpty(t *testing.T) {
+	var s *Set
+	s.Record(Change{Object: "foo", Action: Created})
+	assert.True(t, s.Empty())
+
+	var out bytes.Buffer
+	(&Set{}).Print(&out)
+	assert.Equal(t, "No changes\n", out.String())
+}
@@ -17,11 +17,20 @@ limitations under the License.
 package completion
 
 import (
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/observed"
+	"github.com/triggermesh/tmctl/pkg/templates"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/filesink"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/function"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 )
@@ -45,11 +54,30 @@ func ListSources(m *manifest.Manifest) []string {
 	return list
 }
 
+// ListSourceKinds returns the lowercase kinds of the manifest sources.
+func ListSourceKinds(m *manifest.Manifest) []string {
+	var list []string
+	seen := make(map[string]struct{})
+	for _, object := range m.Objects {
+		if object.APIVersion != "sources.triggermesh.io/v1alpha1" {
+			continue
+		}
+		kind := strings.ToLower(object.Kind)
+		if _, exists := seen[kind]; !exists {
+			seen[kind] = struct{}{}
+			list = append(list, kind)
+		}
+	}
+	return list
+}
+
 func ListTargets(m *manifest.Manifest) []string {
 	var list []string
 	for _, object := range m.Objects {
 		if object.APIVersion == "targets.triggermesh.io/v1alpha1" ||
-			object.APIVersion == "flow.triggermesh.io/v1alpha1" {
+			object.APIVersion == "flow.triggermesh.io/v1alpha1" ||
+			object.APIVersion == function.APIVersion ||
+			object.APIVersion == filesink.APIVersion {
 			list = append(list, object.Metadata.Name)
 		}
 		if object.APIVersion == service.APIVersion {
@@ -89,7 +117,8 @@ func ListAll(m *manifest.Manifest) []string {
 	return list
 }
 
-func ListEventTypes(m *manifest.Manifest, c *config.Config, crds map[string]crd.CRD) []string {
+func ListEventTypes(m *manifest.Manifest, c *config.Config, index *crd.Index) []string {
+	crds := manifestCRDs(m, index)
 	var eventTypes []string
 	for _, object := range m.Objects {
 		c, err := components.GetObject(object.Metadata.Name, c, m, crds)
@@ -103,6 +132,110 @@ func ListEventTypes(m *manifest.Manifest, c *config.Config, crds map[string]crd.
 	return eventTypes
 }
 
+// ListEventTypesWithOrigin merges the event types produced by the manifest
+// components, the ones observed at the broker and the ones declared in the
+// CRD annotations. Each type is listed once, with its origin in the
+// completion description.
+func ListEventTypesWithOrigin(m *manifest.Manifest, c *config.Config, index *crd.Index) []string {
+	var result []string
+	seen := make(map[string]struct{})
+	add := func(eventType, origin string) {
+		if _, exists := seen[eventType]; exists || eventType == "" {
+			return
+		}
+		seen[eventType] = struct{}{}
+		result = append(result, eventType+"\t"+origin)
+	}
+	crds := manifestCRDs(m, index)
+	for _, object := range m.Objects {
+		component, err := components.GetObject(object.Metadata.Name, c, m, crds)
+		if err != nil {
+			continue
+		}
+		if producer, ok := component.(triggermesh.Producer); ok {
+			et, _ := producer.GetEventTypes()
+			for _, eventType := range et {
+				add(eventType, "produced by "+object.Metadata.Name+" ("+object.Kind+")")
+			}
+		}
+	}
+	if types, err := observed.Load(c.ConfigHome, c.Context); err == nil {
+		for _, e := range types.Entries() {
+			add(e.Type, "observed from "+e.Component)
+		}
+	}
+	if index == nil {
+		return result
+	}
+	kinds := make([]string, 0, len(index.Kinds))
+	for kind := range index.Kinds {
+		kinds = append(kinds, kind)
+	}
+	sort.Strings(kinds)
+	for _, kind := range kinds {
+		for _, eventType := range index.Kinds[kind].ProducedEventTypes {
+			add(eventType, kind+" CRD")
+		}
+	}
+	return result
+}
+
+// Index returns the CRD index of the configured components version.
+// Completion never parses the CRDs, nil index means that the CRDs
+// of the version have not been fetched by any command yet.
+func Index(c *config.Config) *crd.Index {
+	index, err := crd.LoadIndex(c.ConfigHome, c.Triggermesh.ComponentsVersion)
+	if err != nil {
+		return nil
+	}
+	return index
+}
+
+// manifestCRDs restores the definitions of the manifest component kinds
+// from the index, the rest of the CRDs is not needed to create the components.
+func manifestCRDs(m *manifest.Manifest, index *crd.Index) map[string]crd.CRD {
+	kinds := make([]string, 0, len(m.Objects))
+	for _, object := range m.Objects {
+		kinds = append(kinds, strings.ToLower(object.Kind))
+	}
+	crds, err := index.CRDs(kinds...)
+	if err != nil {
+		return map[string]crd.CRD{}
+	}
+	return crds
+}
+
+// ListBrokers returns the brokers of the contexts other than the current one.
+func ListBrokers(configBase, current string) []string {
+	dirs, err := os.ReadDir(configBase)
+	if err != nil {
+		return []string{}
+	}
+	var result []string
+	for _, dir := range dirs {
+		if !dir.IsDir() || dir.Name() == current {
+			continue
+		}
+		if _, err := os.Stat(filepath.Join(configBase, dir.Name(), triggermesh.ManifestFile)); err == nil {
+			result = append(result, dir.Name())
+		}
+	}
+	return result
+}
+
+// ListTemplates returns the names of the saved component templates.
+func ListTemplates(configBase string) []string {
+	list, err := templates.New(configBase).List()
+	if err != nil {
+		return []string{}
+	}
+	result := make([]string, 0, len(list))
+	for _, t := range list {
+		result = append(result, t.Name)
+	}
+	return result
+}
+
 func ListFilteredEventTypes(broker, configBase string, m *manifest.Manifest) []string {
 	var eventTypes []string
 	for _, object := range m.Objects {
@@ -17,12 +17,19 @@ limitations under the License.
 package completion
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
+	"time"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/observed"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 	"github.com/triggermesh/tmctl/test"
 )
@@ -62,7 +69,38 @@ func TestListEventTypes(t *testing.T) {
 	c := &config.Config{
 		Triggermesh: config.TmConfig{ComponentsVersion: version},
 	}
-	assert.Equal(t, expectedEventTypes, ListEventTypes(m, c, test.CRD()))
+	assert.Equal(t, expectedEventTypes, ListEventTypes(m, c, testIndex(t)))
+}
+
+func TestListEventTypesWithOrigin(t *testing.T) {
+	m := manifest.New(test.Manifest())
+	assert.NoError(t, m.Read())
+	c := &config.Config{
+		ConfigHome:  t.TempDir(),
+		Context:     "foo",
+		Triggermesh: config.TmConfig{ComponentsVersion: version},
+	}
+	assert.NoError(t, os.MkdirAll(filepath.Join(c.ConfigHome, c.Context), 0o755))
+	types, err := observed.Load(c.ConfigHome, c.Context)
+	assert.NoError(t, err)
+	types.Add("foo-awss3source", "com.amazon.s3.objectcreated", time.Now())
+	types.Add("foo-awss3source", "com.example.observed", time.Now())
+	assert.NoError(t, types.Save())
+
+	eventTypes := ListEventTypesWithOrigin(m, c, testIndex(t))
+	assert.Contains(t, eventTypes, "foo-transformation.output\tproduced by foo-transformation (Transformation)")
+	// manifest components take precedence over the observed types and the CRD annotations
+	assert.Contains(t, eventTypes, "com.amazon.s3.objectcreated\tproduced by foo-awss3source (AWSS3Source)")
+	assert.Contains(t, eventTypes, "com.example.observed\tobserved from foo-awss3source")
+
+	seen := make(map[string]struct{}, len(eventTypes))
+	for _, et := range eventTypes {
+		name, origin, found := strings.Cut(et, "\t")
+		assert.True(t, found)
+		assert.NotEmpty(t, origin)
+		assert.NotContains(t, seen, name, "duplicate event type")
+		seen[name] = struct{}{}
+	}
 }
 
 func TestFilteredEventTypes(t *testing.T) {
@@ -97,3 +135,156 @@ func TestSpecFromCRD(t *testing.T) {
 	assert.False(t, exists)
 	assert.Equal(t, map[string]crd.Property{}, r)
 }
+
+func TestSpecFromIndex(t *testing.T) {
+	crds := test.CRD()
+	index, err := crd.BuildIndex(crds)
+	assert.NoError(t, err)
+
+	for _, path := range [][]string{{}, {"auth"}, {"auth", "credentials"}, {"nonexisting"}} {
+		expectedExists, expectedProperties := SpecFromCRD(crds["awss3source"], path...)
+		exists, properties := index.Spec("awss3source", path...)
+		assert.Equal(t, expectedExists, exists)
+		assert.Equal(t, expectedProperties, properties)
+	}
+	assert.Equal(t, []string{"awss3"}, index.ListGroup("sources.triggermesh.io", "source"))
+	assert.Contains(t, index.Kinds["awss3source"].ProducedEventTypes, "com.amazon.s3.objectcreated")
+}
+
+func TestLoadIndex(t *testing.T) {
+	dir := t.TempDir()
+	crdDir := filepath.Join(dir, "crd", version)
+	assert.NoError(t, os.MkdirAll(crdDir, os.ModePerm))
+	data, err := os.ReadFile(filepath.Join(test.ConfigBase(), "crd.yaml"))
+	assert.NoError(t, err)
+	assert.NoError(t, os.WriteFile(filepath.Join(crdDir, "crd.yaml"), data, os.ModePerm))
+
+	index, err := crd.LoadIndex(dir, version)
+	assert.NoError(t, err)
+	assert.FileExists(t, filepath.Join(crdDir, "index.json"))
+	assert.Contains(t, index.Kinds, "awss3source")
+
+	// index must be invalidated when the CRD cache changes
+	assert.NoError(t, os.WriteFile(filepath.Join(crdDir, "crd.yaml"), []byte("---\n"), os.ModePerm))
+	index, err = crd.LoadIndex(dir, version)
+	assert.NoError(t, err)
+	assert.Empty(t, index.Kinds)
+}
+
+func BenchmarkSpecFromCRD(b *testing.B) {
+	crds := test.CRD()
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		for kind := range crds {
+			SpecFromCRD(crds[kind])
+		}
+	}
+}
+
+func BenchmarkSpecFromIndex(b *testing.B) {
+	c := benchmarkConfig(b)
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		index := Index(c)
+		if index == nil {
+			b.Fatal("index not loaded")
+		}
+		for kind := range index.Kinds {
+			index.Spec(kind)
+		}
+	}
+}
+
+// BenchmarkListEventTypesWithOrigin times the event types completion
+// of the commands, from the CRD cache lookup to the completion list.
+func BenchmarkListEventTypesWithOrigin(b *testing.B) {
+	c := benchmarkConfig(b)
+	m := manifest.New(test.Manifest())
+	if err := m.Read(); err != nil {
+		b.Fatal(err)
+	}
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		if len(ListEventTypesWithOrigin(m, c, Index(c))) == 0 {
+			b.Fatal("no event types")
+		}
+	}
+}
+
+func TestIndexCRDs(t *testing.T) {
+	dir := t.TempDir()
+	assert.Nil(t, Index(&config.Config{ConfigHome: dir, Triggermesh: config.TmConfig{ComponentsVersion: version}}))
+
+	// components created from the index produce the same event types
+	m := manifest.New(test.Manifest())
+	assert.NoError(t, m.Read())
+	c := &config.Config{
+		Triggermesh: config.TmConfig{ComponentsVersion: version},
+	}
+	assert.Equal(t, ListEventTypes(m, c, testIndex(t)), producedEventTypes(t, m, c, test.CRD()))
+}
+
+func producedEventTypes(t *testing.T, m *manifest.Manifest, c *config.Config, crds map[string]crd.CRD) []string {
+	var result []string
+	for _, object := range m.Objects {
+		component, err := components.GetObject(object.Metadata.Name, c, m, crds)
+		assert.NoError(t, err)
+		if producer, ok := component.(triggermesh.Producer); ok {
+			et, err := producer.GetEventTypes()
+			assert.NoError(t, err)
+			result = append(result, et...)
+		}
+	}
+	return result
+}
+
+func testIndex(t testing.TB) *crd.Index {
+	index, err := crd.BuildIndex(test.CRD())
+	if err != nil {
+		t.Fatal(err)
+	}
+	return index
+}
+
+// benchmarkConfig returns the configuration with the test CRDs cached
+// and indexed the way the commands leave them for the completion.
+func benchmarkConfig(b *testing.B) *config.Config {
+	dir := b.TempDir()
+	crdDir := filepath.Join(dir, "crd", version)
+	if err := os.MkdirAll(crdDir, os.ModePerm); err != nil {
+		b.Fatal(err)
+	}
+	data, err := os.ReadFile(filepath.Join(test.ConfigBase(), "crd.yaml"))
+	if err != nil {
+		b.Fatal(err)
+	}
+	if err := os.WriteFile(filepath.Join(crdDir, "crd.yaml"), data, os.ModePerm); err != nil {
+		b.Fatal(err)
+	}
+	if _, err := crd.LoadIndex(dir, version); err != nil {
+		b.Fatal(err)
+	}
+	return &config.Config{
+		ConfigHome:  dir,
+		Triggermesh: config.TmConfig{ComponentsVersion: version},
+	}
+}
+
+func TestListSpecFiles(t *testing.T) {
+	dir := t.TempDir()
+	for _, file := range []string{"spec.yaml", "spec.json", "notes.txt", ".hidden.yaml", "nested/op.yml"} {
+		path := filepath.Join(dir, file)
+		assert.NoError(t, os.MkdirAll(filepath.Dir(path), os.ModePerm))
+		assert.NoError(t, os.WriteFile(path, []byte{}, os.ModePerm))
+	}
+	recent := []string{filepath.Join(dir, "nested", "op.yml"), filepath.Join(dir, "removed.yaml")}
+
+	assert.Equal(t, []string{
+		filepath.Join(dir, "nested", "op.yml"),
+		dir + "/nested/",
+		dir + "/spec.json",
+		dir + "/spec.yaml",
+	}, ListSpecFiles(dir+"/", recent, "yaml", "yml", "json"))
+	assert.Equal(t, []string{dir + "/nested/op.yml"}, ListSpecFiles(dir+"/nested/", nil, "yaml", "yml"))
+	assert.Equal(t, []string{dir + "/spec.json"}, ListSpecFiles(dir+"/s", nil, "json"))
+}
@@ -0,0 +1,150 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package completion
+
+import (
+	"context"
+	"time"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// StatusTimeout limits the Docker lookup of the completion descriptions,
+// the completion must not block on the unresponsive daemon.
+const StatusTimeout = 300 * time.Millisecond
+
+// Status is the state of the containers indexed by their names.
+// Nil status means that the states are unknown.
+type Status map[string]string
+
+// ContainerStatus lists the containers once with the short timeout.
+// The states are unknown if the daemon did not respond in time.
+func ContainerStatus() Status {
+	ctx, cancel := context.WithTimeout(context.Background(), StatusTimeout)
+	defer cancel()
+	client, err := docker.NewClient()
+	if err != nil {
+		return nil
+	}
+	defer client.Close()
+	states, err := docker.ContainerStates(ctx, client)
+	if err != nil {
+		return nil
+	}
+	return states
+}
+
+// Of returns the state of the container, "stopped" if it does not exist.
+func (s Status) Of(container string) string {
+	if s == nil {
+		return ""
+	}
+	if state, exists := s[container]; exists {
+		return state
+	}
+	return "stopped"
+}
+
+// Describe returns the completions of the manifest components
+// in the "name<TAB>description" form, described by their kind
+// and the state of their containers.
+func Describe(names []string, m *manifest.Manifest, status Status) []string {
+	result := make([]string, 0, len(names))
+	for _, name := range names {
+		description := ""
+		for _, object := range m.Objects {
+			if object.Metadata.Name != name {
+				continue
+			}
+			description = object.Kind
+			container := name
+			switch object.Kind {
+			case tmbroker.TriggerKind, "Secret":
+				// not running in the container
+				container = ""
+			case tmbroker.BrokerKind:
+				container = name + "-broker"
+			}
+			if state := status.Of(container); container != "" && state != "" {
+				description += ", " + state
+			}
+			break
+		}
+		if description == "" {
+			result = append(result, name)
+			continue
+		}
+		result = append(result, name+"\t"+description)
+	}
+	return result
+}
+
+// DescribeBrokers returns the completions of the contexts
+// described by the state of their broker containers.
+func DescribeBrokers(brokers []string, status Status) []string {
+	result := make([]string, 0, len(brokers))
+	for _, broker := range brokers {
+		if state := status.Of(broker + "-broker"); state != "" {
+			result = append(result, broker+"\tbroker "+state)
+			continue
+		}
+		result = append(result, broker)
+	}
+	return result
+}
+
+// ListEventTypesWithKind returns the event types produced by the manifest
+// components, described by the kind of the producer.
+func ListEventTypesWithKind(m *manifest.Manifest, c *config.Config, index *crd.Index) []string {
+	crds := manifestCRDs(m, index)
+	var result []string
+	seen := make(map[string]struct{})
+	for _, object := range m.Objects {
+		component, err := components.GetObject(object.Metadata.Name, c, m, crds)
+		if err != nil {
+			continue
+		}
+		producer, ok := component.(triggermesh.Producer)
+		if !ok {
+			continue
+		}
+		et, _ := producer.GetEventTypes()
+		for _, eventType := range et {
+			if _, exists := seen[eventType]; exists || eventType == "" {
+				continue
+			}
+			seen[eventType] = struct{}{}
+			result = append(result, eventType+"\t"+object.Kind)
+		}
+	}
+	return result
+}
+
+// Components returns the described completions of the listed components.
+func Components(m *manifest.Manifest, lists ...[]string) []string {
+	var names []string
+	for _, list := range lists {
+		names = append(names, list...)
+	}
+	return Describe(names, m, ContainerStatus())
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package completion
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/test"
+)
+
+func TestDescribe(t *testing.T) {
+	m := manifest.New(test.Manifest())
+	assert.NoError(t, m.Read())
+	status := Status{"foo-broker": "running", "sockeye": "exited"}
+
+	assert.Equal(t, []string{
+		"foo\tRedisBroker, running",
+		"sockeye\tService, exited",
+		"foo-awss3source\tAWSS3Source, stopped",
+		"foo-trigger-9dad7875\tTrigger",
+		"missing",
+	}, Describe([]string{"foo", "sockeye", "foo-awss3source", "foo-trigger-9dad7875", "missing"}, m, status))
+
+	// unknown state is not shown
+	assert.Equal(t, []string{"sockeye\tService"}, Describe([]string{"sockeye"}, m, nil))
+}
+
+func TestDescribeBrokers(t *testing.T) {
+	status := Status{"foo-broker": "running"}
+	assert.Equal(t, []string{"foo\tbroker running", "bar\tbroker stopped"}, DescribeBrokers([]string{"foo", "bar"}, status))
+	assert.Equal(t, []string{"foo"}, DescribeBrokers([]string{"foo"}, nil))
+}
+
+func TestContainerStatusUnavailable(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://127.0.0.1:1")
+	assert.Nil(t, ContainerStatus())
+}
+
+func TestListEventTypesWithKind(t *testing.T) {
+	m := manifest.New(test.Manifest())
+	assert.NoError(t, m.Read())
+	c := &config.Config{
+		Triggermesh: config.TmConfig{ComponentsVersion: version},
+	}
+	eventTypes := ListEventTypesWithKind(m, c, testIndex(t))
+	assert.Contains(t, eventTypes, "com.amazon.s3.objectcreated\tAWSS3Source")
+	assert.Contains(t, eventTypes, "foo-transformation.output\tTransformation")
+}
@@ -0,0 +1,74 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package completion
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// ListSpecFiles returns the recently used spec files followed by the directories
+// and the files with one of the extensions matching the path being completed.
+// Directories have the trailing separator to continue the completion inside them.
+func ListSpecFiles(toComplete string, recent []string, extensions ...string) []string {
+	var list []string
+	for _, spec := range recent {
+		if !strings.HasPrefix(spec, toComplete) || !hasExtension(spec, extensions) {
+			continue
+		}
+		if _, err := os.Stat(spec); err == nil {
+			list = append(list, spec)
+		}
+	}
+
+	dir, prefix := filepath.Split(toComplete)
+	readDir := dir
+	if readDir == "" {
+		readDir = "."
+	}
+	entries, err := os.ReadDir(readDir)
+	if err != nil {
+		return list
+	}
+	for _, entry := range entries {
+		if !strings.HasPrefix(entry.Name(), prefix) {
+			continue
+		}
+		if strings.HasPrefix(entry.Name(), ".") && !strings.HasPrefix(prefix, ".") {
+			continue
+		}
+		path := dir + entry.Name()
+		switch {
+		case entry.IsDir():
+			list = append(list, path+string(filepath.Separator))
+		case hasExtension(path, extensions):
+			list = append(list, path)
+		}
+	}
+	return list
+}
+
+func hasExtension(path string, extensions []string) bool {
+	ext := strings.TrimPrefix(filepath.Ext(path), ".")
+	for _, e := range extensions {
+		if strings.EqualFold(ext, e) {
+			return true
+		}
+	}
+	return false
+}
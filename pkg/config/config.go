@@ -24,16 +24,21 @@ import (
 	"path/filepath"
 	"reflect"
 	"runtime"
+	"strconv"
 	"strings"
 
 	"gopkg.in/yaml.v3"
+
+	"github.com/triggermesh/tmctl/pkg/httpclient"
+	"github.com/triggermesh/tmctl/pkg/urls"
 )
 
 const (
-	defaultConfigPath = ".triggermesh/cli"
 	defaultConfigFile = "config.yaml"
 	defaultContext    = ""
 
+	// the CRDs of this version are embedded into the binary,
+	// see hack/embed-crds.sh
 	defaultTmVersion     = "v1.23.0"
 	defaultBrokerVersion = "v1.1.0"
 
@@ -50,6 +55,9 @@ const (
 
 	defaultSchemaRegistryURL = "https://registry.staging.triggermesh.io"
 
+	// number of the recently used spec files kept per context
+	recentSpecsLimit = 5
+
 	// TriggerMesh DockerHub Registry
 	DockerRegistry = "triggermesh"
 )
@@ -57,12 +65,44 @@ const (
 type Config struct {
 	// Calculated attributes
 	ConfigHome string `yaml:"-"`
+	// ConfigFile is the path of the persisted configuration.
+	ConfigFile string `yaml:"-"`
+	// StrictKinds makes the commands fail instead of skipping
+	// the manifest objects of the kinds unknown to the CRD set.
+	StrictKinds bool `yaml:"-"`
+	// OnNameConflict is the policy of the components whose container
+	// names are taken by the foreign containers, see docker.ManageNames.
+	OnNameConflict string `yaml:"-"`
 
 	// Persisted attributes
 	Context        string   `yaml:"context"`
 	SchemaRegistry string   `yaml:"schemaRegistry"`
 	Triggermesh    TmConfig `yaml:"triggermesh"`
 	Docker         Docker   `yaml:"docker"`
+	// CABundle is the PEM file with extra CAs trusted by the outbound requests.
+	CABundle string `yaml:"ca-bundle,omitempty"`
+	// RecentSpecs are the last spec files used with --from, indexed by the context.
+	RecentSpecs map[string][]string `yaml:"recent-specs,omitempty"`
+	// OutputTypeTemplates are the templates of the transformations
+	// output event types, indexed by the context.
+	OutputTypeTemplates map[string]string `yaml:"output-type-templates,omitempty"`
+	// TriggerNameTemplates are the templates of the names of the triggers
+	// created without the explicit name, indexed by the context.
+	TriggerNameTemplates map[string]string `yaml:"trigger-name-templates,omitempty"`
+	// StrictSpec makes the create commands fail instead of warning
+	// about the spec fields unknown to the component schema.
+	StrictSpec bool `yaml:"strict-spec,omitempty"`
+	// AdvertiseAddress is the host name of the URLs printed for
+	// the clients outside of the Docker host, e.g. webhook senders.
+	AdvertiseAddress string `yaml:"advertise-address,omitempty"`
+	// Defaults are the command flags default values indexed by the context,
+	// or AllContexts, and the flag path, see FlagDefault.
+	Defaults map[string]map[string]string `yaml:"defaults,omitempty"`
+}
+
+// URLs returns the resolver of the broker and components URLs.
+func (c *Config) URLs() urls.Resolver {
+	return urls.New(c.AdvertiseAddress)
 }
 
 type Docker struct {
@@ -79,6 +119,12 @@ type BrokerConfig struct {
 	Version string                `yaml:"version"`
 	Memory  *InMemoryBrokerConfig `yaml:"memory,omitempty"`
 	Redis   *RedisBrokerConfig    `yaml:"redis,omitempty"`
+	// Images overrides the default broker image, indexed by the broker name.
+	Images map[string]string `yaml:"images,omitempty"`
+	// Limits protect the broker ingest endpoint, indexed by the broker name.
+	Limits map[string]IngestLimits `yaml:"limits,omitempty"`
+	// InternalTLS enables TLS between the broker and components.
+	InternalTLS bool `yaml:"internal-tls,omitempty"`
 	// for Windows only
 	ConfigPollingPeriod string `yaml:"config-polling-period,omitempty"`
 }
@@ -97,6 +143,9 @@ type RedisBrokerConfig struct {
 }
 
 func New() (*Config, error) {
+	if err := MigrateLegacyPaths(); err != nil {
+		fmt.Fprintf(os.Stderr, "WARNING! Legacy %s directory is still in use: %v\n", legacyPath, err)
+	}
 	c, err := loadDefaultConfig()
 	if os.IsNotExist(err) {
 		if err := c.createDefault(); err != nil {
@@ -105,6 +154,9 @@ func New() (*Config, error) {
 	} else if err != nil {
 		return nil, err
 	}
+	if err := httpclient.Configure(c.CABundle); err != nil {
+		return nil, fmt.Errorf("ca-bundle in %s: %w", c.ConfigFile, err)
+	}
 	if err := c.applyOverrides(); err != nil {
 		return nil, err
 	}
@@ -115,6 +167,9 @@ func (c *Config) createDefault() error {
 	if err := os.MkdirAll(c.ConfigHome, os.ModePerm); err != nil {
 		return err
 	}
+	if err := os.MkdirAll(filepath.Dir(c.ConfigFile), os.ModePerm); err != nil {
+		return err
+	}
 	c.Context = defaultContext
 	c.Docker.StartTimeout = defaultDockerTimeout
 	c.Triggermesh.ComponentsVersion = latestOrDefaultTag("triggermesh", defaultTmVersion)
@@ -130,7 +185,7 @@ func (c *Config) createDefault() error {
 }
 
 func latestOrDefaultTag(project, defaultVersion string) string {
-	r, err := http.Get("https://api.github.com/repos/triggermesh/" + project + "/releases/latest")
+	r, err := httpclient.Get("https://api.github.com/repos/triggermesh/" + project + "/releases/latest")
 	if err != nil {
 		return defaultVersion
 	}
@@ -152,7 +207,7 @@ func (c *Config) Save() error {
 	if err != nil {
 		return err
 	}
-	return os.WriteFile(filepath.Join(c.ConfigHome, defaultConfigFile), data, 0644)
+	return os.WriteFile(c.ConfigFile, data, 0644)
 }
 
 func Get(key string) (string, error) {
@@ -163,7 +218,7 @@ func Get(key string) (string, error) {
 	if key == "" {
 		out, err := yaml.Marshal(c)
 		if err != nil {
-			panic(err)
+			return "", fmt.Errorf("unable to encode config: %w", err)
 		}
 		return string(out), nil
 	}
@@ -179,16 +234,105 @@ func Set(key, value string) error {
 	return c.Save()
 }
 
-func HomeAbsPath() string {
-	home, err := os.UserHomeDir()
+// SetBrokerImage overrides the image of the current context broker.
+// Empty image restores the default one.
+func SetBrokerImage(image string) error {
+	c, err := loadDefaultConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load config: %w", err)
+	}
+	if c.Context == "" {
+		return fmt.Errorf("broker is not selected")
+	}
+	c.Triggermesh.Broker.SetImage(c.Context, image)
+	return c.Save()
+}
+
+// SetInternalTLS enables or disables TLS between the broker and components.
+func SetInternalTLS(value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid internal-tls value %q: %w", value, err)
+	}
+	c, err := loadDefaultConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load config: %w", err)
+	}
+	c.Triggermesh.Broker.InternalTLS = enabled
+	return c.Save()
+}
+
+// SetStrictSpec enables or disables the failure of the create commands
+// on the spec fields unknown to the component schema.
+func SetStrictSpec(value string) error {
+	enabled, err := strconv.ParseBool(value)
+	if err != nil {
+		return fmt.Errorf("invalid strict-spec value %q: %w", value, err)
+	}
+	c, err := loadDefaultConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load config: %w", err)
+	}
+	c.StrictSpec = enabled
+	return c.Save()
+}
+
+// SetCABundle sets the file with extra CA certificates trusted
+// by the outbound requests. Empty path removes the setting.
+func SetCABundle(path string) error {
+	if path != "" {
+		var err error
+		if path, err = filepath.Abs(path); err != nil {
+			return err
+		}
+		if _, err := httpclient.LoadCABundle(path); err != nil {
+			return err
+		}
+	}
+	c, err := loadDefaultConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load config: %w", err)
+	}
+	c.CABundle = path
+	return c.Save()
+}
+
+// AddRecentSpec records the spec file used in the context.
+func AddRecentSpec(context, path string) error {
+	if context == "" {
+		return nil
+	}
+	path, err := filepath.Abs(path)
 	if err != nil {
-		return ""
+		return err
 	}
-	absHome, err := filepath.Abs(home)
+	c, err := loadDefaultConfig()
 	if err != nil {
-		return ""
+		return fmt.Errorf("unable to load config: %w", err)
+	}
+	recent := []string{path}
+	for _, spec := range c.RecentSpecs[context] {
+		if spec != path && len(recent) < recentSpecsLimit {
+			recent = append(recent, spec)
+		}
+	}
+	if c.RecentSpecs == nil {
+		c.RecentSpecs = make(map[string][]string)
+	}
+	c.RecentSpecs[context] = recent
+	return c.Save()
+}
+
+// SetImage sets the broker image override.
+func (b *BrokerConfig) SetImage(broker, image string) {
+	if image == "" {
+		delete(b.Images, broker)
+		return
+	}
+	if b.Images == nil {
+		b.Images = make(map[string]string)
 	}
-	return filepath.Join(absHome, defaultConfigPath)
+	b.Images[broker] = image
 }
 
 func setValue(keys []string, value string, t reflect.Type, v reflect.Value) {
@@ -246,10 +390,12 @@ func readValue(keys []string, t reflect.Type, v reflect.Value) string {
 }
 
 func loadDefaultConfig() (*Config, error) {
+	paths := ResolvePaths()
 	c := &Config{
-		ConfigHome: HomeAbsPath(),
+		ConfigHome: paths.State,
+		ConfigFile: filepath.Join(paths.Config, defaultConfigFile),
 	}
-	configFile, err := os.ReadFile(filepath.Join(c.ConfigHome, defaultConfigFile))
+	configFile, err := os.ReadFile(c.ConfigFile)
 	if err != nil {
 		return c, err
 	}
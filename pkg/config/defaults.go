@@ -0,0 +1,110 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"sort"
+)
+
+// AllContexts is the scope of the flag defaults shared by all contexts.
+const AllContexts = "*"
+
+// FlagDefault is the default value of the command flag
+// stored in the config.
+type FlagDefault struct {
+	// Key is the path of the flag, the command names without "tmctl"
+	// followed by the flag name, e.g. "create.source.timeout".
+	// The persistent flags of the root command have the "global" path.
+	Key   string
+	Value string
+	// Scope is the name of the context the default belongs to or AllContexts.
+	Scope string
+}
+
+// SetFlagDefault stores the default value of the flag in the scope, the current
+// context if empty. The key must be validated against the commands tree.
+func SetFlagDefault(scope, key, value string) error {
+	c, err := loadDefaultConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load config: %w", err)
+	}
+	if scope, err = c.defaultsScope(scope); err != nil {
+		return err
+	}
+	if c.Defaults == nil {
+		c.Defaults = make(map[string]map[string]string)
+	}
+	if c.Defaults[scope] == nil {
+		c.Defaults[scope] = make(map[string]string)
+	}
+	c.Defaults[scope][key] = value
+	return c.Save()
+}
+
+// UnsetFlagDefault removes the default value of the flag from the scope,
+// the current context if empty.
+func UnsetFlagDefault(scope, key string) error {
+	c, err := loadDefaultConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load config: %w", err)
+	}
+	if scope, err = c.defaultsScope(scope); err != nil {
+		return err
+	}
+	if _, exists := c.Defaults[scope][key]; !exists {
+		return fmt.Errorf("flag %q has no default", key)
+	}
+	delete(c.Defaults[scope], key)
+	if len(c.Defaults[scope]) == 0 {
+		delete(c.Defaults, scope)
+	}
+	return c.Save()
+}
+
+// FlagDefaults returns the flag defaults effective in the current context
+// sorted by the key. The defaults of the context take precedence over
+// the ones shared by all contexts.
+func (c *Config) FlagDefaults() []FlagDefault {
+	effective := make(map[string]FlagDefault)
+	for _, scope := range []string{AllContexts, c.Context} {
+		if scope == "" {
+			continue
+		}
+		for key, value := range c.Defaults[scope] {
+			effective[key] = FlagDefault{Key: key, Value: value, Scope: scope}
+		}
+	}
+	defaults := make([]FlagDefault, 0, len(effective))
+	for _, d := range effective {
+		defaults = append(defaults, d)
+	}
+	sort.Slice(defaults, func(i, j int) bool {
+		return defaults[i].Key < defaults[j].Key
+	})
+	return defaults
+}
+
+func (c *Config) defaultsScope(scope string) (string, error) {
+	if scope != "" {
+		return scope, nil
+	}
+	if c.Context == "" {
+		return "", fmt.Errorf("broker is not selected")
+	}
+	return c.Context, nil
+}
@@ -0,0 +1,72 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFlagDefaults(t *testing.T) {
+	c := &Config{Context: "foo", Defaults: map[string]map[string]string{
+		AllContexts: {"global.version": "v1.24.0", "create.timeout": "5s"},
+		"foo":       {"create.timeout": "10s", "create.transformation.target": "logger"},
+		"bar":       {"create.source.log-level": "debug"},
+	}}
+	assert.Equal(t, []FlagDefault{
+		{Key: "create.timeout", Value: "10s", Scope: "foo"},
+		{Key: "create.transformation.target", Value: "logger", Scope: "foo"},
+		{Key: "global.version", Value: "v1.24.0", Scope: AllContexts},
+	}, c.FlagDefaults())
+
+	c.Context = ""
+	assert.Equal(t, []FlagDefault{
+		{Key: "create.timeout", Value: "5s", Scope: AllContexts},
+		{Key: "global.version", Value: "v1.24.0", Scope: AllContexts},
+	}, c.FlagDefaults())
+}
+
+func TestSetFlagDefault(t *testing.T) {
+	isolate(t)
+	c, err := loadDefaultConfig()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	require.NoError(t, os.MkdirAll(filepath.Dir(c.ConfigFile), os.ModePerm))
+	require.NoError(t, c.Save())
+
+	assert.EqualError(t, SetFlagDefault("", "create.timeout", "10s"), "broker is not selected")
+	assert.NoError(t, SetFlagDefault(AllContexts, "global.version", "v1.24.0"))
+
+	require.NoError(t, Set("context", "foo"))
+	assert.NoError(t, SetFlagDefault("", "create.timeout", "10s"))
+	c, err = loadDefaultConfig()
+	require.NoError(t, err)
+	assert.Equal(t, map[string]map[string]string{
+		AllContexts: {"global.version": "v1.24.0"},
+		"foo":       {"create.timeout": "10s"},
+	}, c.Defaults)
+
+	assert.Error(t, UnsetFlagDefault("", "global.version"))
+	assert.NoError(t, UnsetFlagDefault("", "create.timeout"))
+	assert.NoError(t, UnsetFlagDefault(AllContexts, "global.version"))
+	c, err = loadDefaultConfig()
+	require.NoError(t, err)
+	assert.Empty(t, c.Defaults)
+}
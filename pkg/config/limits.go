@@ -0,0 +1,144 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+)
+
+// keys of the broker ingest limits
+const (
+	MaxPayloadSizeKey  = "max-payload-size"
+	IngestRateLimitKey = "ingest-rate-limit"
+)
+
+// size units of the payload limit, nginx treats them as binary multiples too
+var sizeUnits = []struct {
+	suffix     string
+	multiplier int64
+}{
+	{suffix: "GB", multiplier: 1 << 30},
+	{suffix: "MB", multiplier: 1 << 20},
+	{suffix: "KB", multiplier: 1 << 10},
+	{suffix: "B", multiplier: 1},
+}
+
+// IngestLimits protect the broker ingest endpoint from the misbehaving senders.
+type IngestLimits struct {
+	// MaxPayloadSize is the largest accepted event, e.g. "1MB".
+	MaxPayloadSize string `yaml:"max-payload-size,omitempty"`
+	// RateLimit is the number of accepted events per second or minute, e.g. "500/s".
+	RateLimit string `yaml:"ingest-rate-limit,omitempty"`
+}
+
+// Empty reports whether the ingest is not limited.
+func (l IngestLimits) Empty() bool {
+	return l.MaxPayloadSize == "" && l.RateLimit == ""
+}
+
+// Validate checks the format of the limits.
+func (l IngestLimits) Validate() error {
+	if l.MaxPayloadSize != "" {
+		if _, err := ParsePayloadSize(l.MaxPayloadSize); err != nil {
+			return err
+		}
+	}
+	if l.RateLimit != "" {
+		if _, _, err := ParseRateLimit(l.RateLimit); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// Set sets the limit by its config key.
+func (l *IngestLimits) Set(key, value string) error {
+	switch key {
+	case MaxPayloadSizeKey:
+		l.MaxPayloadSize = value
+	case IngestRateLimitKey:
+		l.RateLimit = value
+	default:
+		return fmt.Errorf("unknown broker limit %q", key)
+	}
+	return l.Validate()
+}
+
+// ParsePayloadSize returns the number of bytes of the size, e.g. "512KB" or "1MB".
+func ParsePayloadSize(size string) (int64, error) {
+	value := strings.ToUpper(strings.TrimSpace(size))
+	multiplier := int64(1)
+	for _, unit := range sizeUnits {
+		if strings.HasSuffix(value, unit.suffix) {
+			value = strings.TrimSpace(strings.TrimSuffix(value, unit.suffix))
+			multiplier = unit.multiplier
+			break
+		}
+	}
+	n, err := strconv.ParseInt(value, 10, 64)
+	if err != nil || n <= 0 {
+		return 0, fmt.Errorf("invalid payload size %q, expected a positive number with optional B, KB, MB or GB unit", size)
+	}
+	return n * multiplier, nil
+}
+
+// ParseRateLimit returns the number of requests and the period unit
+// of the rate limit, "s" for "500/s" and "m" for "100/m".
+func ParseRateLimit(rate string) (int, string, error) {
+	count, unit, found := strings.Cut(strings.TrimSpace(rate), "/")
+	if !found {
+		unit = "s"
+	}
+	n, err := strconv.Atoi(strings.TrimSpace(count))
+	unit = strings.ToLower(strings.TrimSpace(unit))
+	if err != nil || n <= 0 || (unit != "s" && unit != "m") {
+		return 0, "", fmt.Errorf("invalid rate limit %q, expected requests per second or minute, e.g. \"500/s\"", rate)
+	}
+	return n, unit, nil
+}
+
+// SetLimits sets the ingest limits of the broker, empty limits remove the entry.
+func (b *BrokerConfig) SetLimits(broker string, limits IngestLimits) {
+	if limits.Empty() {
+		delete(b.Limits, broker)
+		return
+	}
+	if b.Limits == nil {
+		b.Limits = make(map[string]IngestLimits)
+	}
+	b.Limits[broker] = limits
+}
+
+// SetBrokerLimit sets the ingest limit of the current context broker
+// by its key. Empty value removes the limit.
+func SetBrokerLimit(key, value string) error {
+	c, err := loadDefaultConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load config: %w", err)
+	}
+	if c.Context == "" {
+		return fmt.Errorf("broker is not selected")
+	}
+	limits := c.Triggermesh.Broker.Limits[c.Context]
+	if err := limits.Set(key, value); err != nil {
+		return err
+	}
+	c.Triggermesh.Broker.SetLimits(c.Context, limits)
+	return c.Save()
+}
@@ -0,0 +1,89 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParsePayloadSize(t *testing.T) {
+	for size, bytes := range map[string]int64{
+		"1MB":    1 << 20,
+		"512kb":  512 << 10,
+		"2 GB":   2 << 30,
+		"100":    100,
+		"100B":   100,
+		" 1mb  ": 1 << 20,
+	} {
+		n, err := ParsePayloadSize(size)
+		assert.NoError(t, err, size)
+		assert.Equal(t, bytes, n, size)
+	}
+	for _, size := range []string{"", "MB", "-1MB", "0", "1TB", "1.5MB"} {
+		_, err := ParsePayloadSize(size)
+		assert.Error(t, err, size)
+	}
+}
+
+func TestParseRateLimit(t *testing.T) {
+	n, unit, err := ParseRateLimit("500/s")
+	assert.NoError(t, err)
+	assert.Equal(t, 500, n)
+	assert.Equal(t, "s", unit)
+
+	n, unit, err = ParseRateLimit("100/M")
+	assert.NoError(t, err)
+	assert.Equal(t, 100, n)
+	assert.Equal(t, "m", unit)
+
+	// requests per second by default
+	_, unit, err = ParseRateLimit("10")
+	assert.NoError(t, err)
+	assert.Equal(t, "s", unit)
+
+	for _, rate := range []string{"", "/s", "0/s", "10/h", "fast"} {
+		_, _, err := ParseRateLimit(rate)
+		assert.Error(t, err, rate)
+	}
+}
+
+func TestSetBrokerLimit(t *testing.T) {
+	isolate(t)
+	c, err := loadDefaultConfig()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	c.Context = "foo"
+	assert.NoError(t, os.MkdirAll(filepath.Dir(c.ConfigFile), os.ModePerm))
+	assert.NoError(t, c.Save())
+
+	assert.Error(t, SetBrokerLimit(MaxPayloadSizeKey, "large"))
+	assert.Error(t, SetBrokerLimit("max-events", "10"))
+	assert.NoError(t, SetBrokerLimit(MaxPayloadSizeKey, "1MB"))
+	assert.NoError(t, SetBrokerLimit(IngestRateLimitKey, "500/s"))
+	c, err = loadDefaultConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]IngestLimits{"foo": {MaxPayloadSize: "1MB", RateLimit: "500/s"}}, c.Triggermesh.Broker.Limits)
+
+	assert.NoError(t, SetBrokerLimit(MaxPayloadSizeKey, ""))
+	assert.NoError(t, SetBrokerLimit(IngestRateLimitKey, ""))
+	c, err = loadDefaultConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, c.Triggermesh.Broker.Limits)
+}
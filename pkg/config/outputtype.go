@@ -0,0 +1,86 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// defaultOutputTypeTemplate is the event type produced by the transformations
+// that do not set it explicitly.
+const defaultOutputTypeTemplate = "{{.Name}}.output"
+
+// OutputTypeData is the data of the output event type template.
+type OutputTypeData struct {
+	Name    string
+	Kind    string
+	Context string
+}
+
+// SetOutputTypeTemplate sets the template of the event types produced
+// by the transformations of the current context. Empty template restores
+// the default one. Existing components keep their event types.
+func SetOutputTypeTemplate(value string) error {
+	if value != "" {
+		if _, err := renderOutputType(value, OutputTypeData{Name: "test", Kind: "transformation", Context: "test"}); err != nil {
+			return err
+		}
+	}
+	c, err := loadDefaultConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load config: %w", err)
+	}
+	if c.Context == "" {
+		return fmt.Errorf("broker is not selected")
+	}
+	if value == "" {
+		delete(c.OutputTypeTemplates, c.Context)
+		return c.Save()
+	}
+	if c.OutputTypeTemplates == nil {
+		c.OutputTypeTemplates = make(map[string]string)
+	}
+	c.OutputTypeTemplates[c.Context] = value
+	return c.Save()
+}
+
+// OutputType returns the event type produced by the component
+// according to the output type template of the current context.
+func (c *Config) OutputType(name, kind string) (string, error) {
+	tmpl := defaultOutputTypeTemplate
+	if t, set := c.OutputTypeTemplates[c.Context]; set {
+		tmpl = t
+	}
+	return renderOutputType(tmpl, OutputTypeData{Name: name, Kind: kind, Context: c.Context})
+}
+
+func renderOutputType(tmpl string, data OutputTypeData) (string, error) {
+	t, err := template.New("output-type").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("output type template: %w", err)
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("output type template: %w", err)
+	}
+	if out.Len() == 0 {
+		return "", fmt.Errorf("output type template %q renders empty event type", tmpl)
+	}
+	return out.String(), nil
+}
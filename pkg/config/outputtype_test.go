@@ -0,0 +1,68 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestOutputType(t *testing.T) {
+	c := &Config{Context: "foo"}
+	eventType, err := c.OutputType("bar-transformation", "transformation")
+	assert.NoError(t, err)
+	assert.Equal(t, "bar-transformation.output", eventType)
+
+	c.OutputTypeTemplates = map[string]string{
+		"foo":   "corp.events.{{.Name}}.v1",
+		"other": "other.{{.Kind}}",
+	}
+	eventType, err = c.OutputType("bar-transformation", "transformation")
+	assert.NoError(t, err)
+	assert.Equal(t, "corp.events.bar-transformation.v1", eventType)
+
+	c.OutputTypeTemplates["foo"] = "{{.Context}}.{{.Unknown}}"
+	_, err = c.OutputType("bar-transformation", "transformation")
+	assert.Error(t, err)
+
+	c.OutputTypeTemplates["foo"] = "{{if false}}x{{end}}"
+	_, err = c.OutputType("bar-transformation", "transformation")
+	assert.Error(t, err)
+}
+
+func TestSetOutputTypeTemplate(t *testing.T) {
+	isolate(t)
+	c, err := loadDefaultConfig()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	c.Context = "foo"
+	assert.NoError(t, os.MkdirAll(filepath.Dir(c.ConfigFile), os.ModePerm))
+	assert.NoError(t, c.Save())
+
+	assert.Error(t, SetOutputTypeTemplate("{{.Name"))
+	assert.NoError(t, SetOutputTypeTemplate("corp.events.{{.Name}}.v1"))
+	c, err = loadDefaultConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "corp.events.{{.Name}}.v1"}, c.OutputTypeTemplates)
+
+	assert.NoError(t, SetOutputTypeTemplate(""))
+	c, err = loadDefaultConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, c.OutputTypeTemplates)
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+const (
+	// EnvHome overrides the directory of the CLI configuration and state.
+	EnvHome = "TMCTL_HOME"
+
+	envXDGConfigHome = "XDG_CONFIG_HOME"
+	envXDGStateHome  = "XDG_STATE_HOME"
+
+	appDir = "tmctl"
+	// legacyPath is the home relative directory used by the older versions.
+	legacyPath = ".triggermesh/cli"
+)
+
+// Paths are the directories of the CLI files.
+type Paths struct {
+	// Config is the directory of the configuration file.
+	Config string
+	// State is the directory of the contexts: manifests,
+	// broker configs, function code and the CRD cache.
+	State string
+}
+
+// ResolvePaths returns the CLI directories: TMCTL_HOME for everything
+// if it is set, otherwise the XDG config and state directories. The legacy
+// directory is used until its contents are moved by MigrateLegacyPaths.
+func ResolvePaths() Paths {
+	if home := os.Getenv(EnvHome); home != "" {
+		home = absPath(home)
+		return Paths{Config: home, State: home}
+	}
+	paths := Paths{
+		Config: filepath.Join(xdgDir(envXDGConfigHome, ".config"), appDir),
+		State:  filepath.Join(xdgDir(envXDGStateHome, filepath.Join(".local", "state")), appDir),
+	}
+	if legacy := legacyHome(); legacy != "" && !exists(paths.Config) && !exists(paths.State) && exists(legacy) {
+		return Paths{Config: legacy, State: legacy}
+	}
+	return paths
+}
+
+// HomeAbsPath returns the directory of the CLI state.
+func HomeAbsPath() string {
+	return ResolvePaths().State
+}
+
+// MigrateLegacyPaths moves the configuration file and the contexts from
+// the legacy directory to the XDG directories. Nothing is moved if the
+// TMCTL_HOME is set or the XDG directories already exist.
+func MigrateLegacyPaths() error {
+	if os.Getenv(EnvHome) != "" {
+		return nil
+	}
+	legacy := legacyHome()
+	current := ResolvePaths()
+	if legacy == "" || current.State != legacy {
+		return nil
+	}
+	target := Paths{
+		Config: filepath.Join(xdgDir(envXDGConfigHome, ".config"), appDir),
+		State:  filepath.Join(xdgDir(envXDGStateHome, filepath.Join(".local", "state")), appDir),
+	}
+	// contexts are moved first, the legacy directory
+	// stays in use if they cannot be moved
+	if err := os.MkdirAll(filepath.Dir(target.State), os.ModePerm); err != nil {
+		return fmt.Errorf("creating state directory: %w", err)
+	}
+	if err := os.Rename(legacy, target.State); err != nil {
+		return fmt.Errorf("moving contexts: %w", err)
+	}
+	if err := os.MkdirAll(target.Config, os.ModePerm); err != nil {
+		return fmt.Errorf("creating config directory: %w", err)
+	}
+	configFile := filepath.Join(target.State, defaultConfigFile)
+	if !exists(configFile) {
+		return nil
+	}
+	if err := os.Rename(configFile, filepath.Join(target.Config, defaultConfigFile)); err != nil {
+		return fmt.Errorf("moving config file: %w", err)
+	}
+	return nil
+}
+
+func xdgDir(env, homeRelative string) string {
+	if dir := os.Getenv(env); filepath.IsAbs(dir) {
+		return dir
+	}
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(absPath(home), homeRelative)
+}
+
+func legacyHome() string {
+	home, err := os.UserHomeDir()
+	if err != nil {
+		return ""
+	}
+	return filepath.Join(absPath(home), legacyPath)
+}
+
+func absPath(path string) string {
+	if abs, err := filepath.Abs(path); err == nil {
+		return abs
+	}
+	return path
+}
+
+func exists(path string) bool {
+	_, err := os.Stat(path)
+	return err == nil
+}
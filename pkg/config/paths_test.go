@@ -0,0 +1,100 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func isolate(t *testing.T) string {
+	home := t.TempDir()
+	t.Setenv("HOME", home)
+	t.Setenv(EnvHome, "")
+	t.Setenv(envXDGConfigHome, "")
+	t.Setenv(envXDGStateHome, "")
+	return home
+}
+
+func TestResolvePaths(t *testing.T) {
+	home := isolate(t)
+
+	assert.Equal(t, Paths{
+		Config: filepath.Join(home, ".config", "tmctl"),
+		State:  filepath.Join(home, ".local", "state", "tmctl"),
+	}, ResolvePaths())
+
+	t.Setenv(envXDGConfigHome, filepath.Join(home, "config"))
+	t.Setenv(envXDGStateHome, filepath.Join(home, "state"))
+	assert.Equal(t, Paths{
+		Config: filepath.Join(home, "config", "tmctl"),
+		State:  filepath.Join(home, "state", "tmctl"),
+	}, ResolvePaths())
+
+	// relative XDG paths are ignored as the spec requires
+	t.Setenv(envXDGStateHome, "state")
+	assert.Equal(t, filepath.Join(home, ".local", "state", "tmctl"), ResolvePaths().State)
+
+	t.Setenv(EnvHome, filepath.Join(home, "tmctl"))
+	assert.Equal(t, Paths{
+		Config: filepath.Join(home, "tmctl"),
+		State:  filepath.Join(home, "tmctl"),
+	}, ResolvePaths())
+	assert.Equal(t, filepath.Join(home, "tmctl"), HomeAbsPath())
+}
+
+func TestMigrateLegacyPaths(t *testing.T) {
+	home := isolate(t)
+	legacy := filepath.Join(home, legacyPath)
+	assert.NoError(t, os.MkdirAll(filepath.Join(legacy, "foo"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(legacy, defaultConfigFile), []byte("context: foo\n"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(legacy, "foo", "manifest.yaml"), []byte{}, os.ModePerm))
+
+	// legacy directory is used until migrated
+	assert.Equal(t, Paths{Config: legacy, State: legacy}, ResolvePaths())
+
+	assert.NoError(t, MigrateLegacyPaths())
+	paths := ResolvePaths()
+	assert.Equal(t, filepath.Join(home, ".local", "state", "tmctl"), paths.State)
+	assert.FileExists(t, filepath.Join(paths.Config, defaultConfigFile))
+	assert.FileExists(t, filepath.Join(paths.State, "foo", "manifest.yaml"))
+	assert.NoFileExists(t, filepath.Join(paths.State, defaultConfigFile))
+	assert.NoDirExists(t, legacy)
+
+	c, err := loadDefaultConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, "foo", c.Context)
+	assert.Equal(t, paths.State, c.ConfigHome)
+
+	// migration runs once
+	assert.NoError(t, MigrateLegacyPaths())
+	assert.Equal(t, paths, ResolvePaths())
+}
+
+func TestMigrateLegacyPathsWithHomeOverride(t *testing.T) {
+	home := isolate(t)
+	legacy := filepath.Join(home, legacyPath)
+	assert.NoError(t, os.MkdirAll(legacy, os.ModePerm))
+	t.Setenv(EnvHome, filepath.Join(home, "override"))
+
+	assert.NoError(t, MigrateLegacyPaths())
+	assert.DirExists(t, legacy)
+	assert.Equal(t, filepath.Join(home, "override"), ResolvePaths().State)
+}
@@ -0,0 +1,86 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"bytes"
+	"fmt"
+	"text/template"
+)
+
+// TriggerNameData is the data of the trigger name template.
+type TriggerNameData struct {
+	Broker string
+	Target string
+	// FilterHash is the short hash of the trigger target and filter.
+	FilterHash string
+	// EventType is the event type the trigger filters, if any.
+	EventType string
+}
+
+// SetTriggerNameTemplate sets the template of the names of the triggers
+// created by the commands of the current context without the explicit name.
+// Empty template restores the generated names. Existing triggers keep their names.
+func SetTriggerNameTemplate(value string) error {
+	if value != "" {
+		if _, err := renderTriggerName(value, TriggerNameData{Broker: "test", Target: "test", FilterHash: "0123abcd", EventType: "test"}); err != nil {
+			return err
+		}
+	}
+	c, err := loadDefaultConfig()
+	if err != nil {
+		return fmt.Errorf("unable to load config: %w", err)
+	}
+	if c.Context == "" {
+		return fmt.Errorf("broker is not selected")
+	}
+	if value == "" {
+		delete(c.TriggerNameTemplates, c.Context)
+		return c.Save()
+	}
+	if c.TriggerNameTemplates == nil {
+		c.TriggerNameTemplates = make(map[string]string)
+	}
+	c.TriggerNameTemplates[c.Context] = value
+	return c.Save()
+}
+
+// TriggerName returns the name of the trigger according to the trigger name
+// template of the current context, empty string is returned if the context
+// has no template.
+func (c *Config) TriggerName(data TriggerNameData) (string, error) {
+	tmpl, set := c.TriggerNameTemplates[c.Context]
+	if !set {
+		return "", nil
+	}
+	return renderTriggerName(tmpl, data)
+}
+
+func renderTriggerName(tmpl string, data TriggerNameData) (string, error) {
+	t, err := template.New("trigger-name").Option("missingkey=error").Parse(tmpl)
+	if err != nil {
+		return "", fmt.Errorf("trigger name template: %w", err)
+	}
+	var out bytes.Buffer
+	if err := t.Execute(&out, data); err != nil {
+		return "", fmt.Errorf("trigger name template: %w", err)
+	}
+	if out.Len() == 0 {
+		return "", fmt.Errorf("trigger name template %q renders empty name", tmpl)
+	}
+	return out.String(), nil
+}
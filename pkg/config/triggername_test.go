@@ -0,0 +1,66 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package config
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestTriggerName(t *testing.T) {
+	c := &Config{Context: "foo"}
+	data := TriggerNameData{Broker: "foo", Target: "sockeye", FilterHash: "0123abcd", EventType: "order.created"}
+	name, err := c.TriggerName(data)
+	assert.NoError(t, err)
+	assert.Empty(t, name)
+
+	c.TriggerNameTemplates = map[string]string{"foo": "{{.Target}}-{{.FilterHash}}"}
+	name, err = c.TriggerName(data)
+	assert.NoError(t, err)
+	assert.Equal(t, "sockeye-0123abcd", name)
+
+	c.TriggerNameTemplates["foo"] = "{{.Unknown}}"
+	_, err = c.TriggerName(data)
+	assert.Error(t, err)
+
+	c.TriggerNameTemplates["foo"] = "{{if false}}x{{end}}"
+	_, err = c.TriggerName(data)
+	assert.Error(t, err)
+}
+
+func TestSetTriggerNameTemplate(t *testing.T) {
+	isolate(t)
+	c, err := loadDefaultConfig()
+	assert.ErrorIs(t, err, os.ErrNotExist)
+	c.Context = "foo"
+	assert.NoError(t, os.MkdirAll(filepath.Dir(c.ConfigFile), os.ModePerm))
+	assert.NoError(t, c.Save())
+
+	assert.Error(t, SetTriggerNameTemplate("{{.Target"))
+	assert.NoError(t, SetTriggerNameTemplate("{{.Target}}-{{.FilterHash}}"))
+	c, err = loadDefaultConfig()
+	assert.NoError(t, err)
+	assert.Equal(t, map[string]string{"foo": "{{.Target}}-{{.FilterHash}}"}, c.TriggerNameTemplates)
+
+	assert.NoError(t, SetTriggerNameTemplate(""))
+	c, err = loadDefaultConfig()
+	assert.NoError(t, err)
+	assert.Empty(t, c.TriggerNameTemplates)
+}
@@ -0,0 +1,142 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package crashlog keeps the output of the exited component containers,
+// so that it survives the container being recreated.
+package crashlog
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+const (
+	// Dir is the directory of the captures in the context directory.
+	Dir = "logs"
+	// MaxSize is the size of the output tail kept in the capture.
+	MaxSize = 64 << 10
+	// Keep is the number of the captures kept per container.
+	Keep = 5
+
+	timeFormat = "20060102T150405.000Z"
+	extension  = ".log"
+)
+
+// ErrNotFound is returned when the container has no capture with the index.
+var ErrNotFound = errors.New("previous logs not found")
+
+// Capture is the saved output of the exited container.
+type Capture struct {
+	Container string
+	Exited    time.Time
+	Path      string
+}
+
+// Save writes the tail of the container output into the capture file named
+// by the exit time and removes the oldest captures of the container.
+// Saving the output of the same exit again overwrites the capture.
+func Save(dir, container string, exited time.Time, output []byte) (string, error) {
+	if err := os.MkdirAll(dir, 0o700); err != nil {
+		return "", fmt.Errorf("logs directory: %w", err)
+	}
+	if len(output) > MaxSize {
+		output = output[len(output)-MaxSize:]
+		// drop the partial first line
+		if i := bytes.IndexByte(output, '\n'); i != -1 {
+			output = output[i+1:]
+		}
+	}
+	path := filepath.Join(dir, fmt.Sprintf("%s-%s%s", container, exited.UTC().Format(timeFormat), extension))
+	if err := os.WriteFile(path, output, 0o600); err != nil {
+		return "", fmt.Errorf("writing logs: %w", err)
+	}
+	captures, err := List(dir, container)
+	if err != nil {
+		return path, err
+	}
+	for i := Keep; i < len(captures); i++ {
+		if err := os.Remove(captures[i].Path); err != nil {
+			return path, fmt.Errorf("rotating logs: %w", err)
+		}
+	}
+	return path, nil
+}
+
+// List returns the captures of the container, the most recent first.
+func List(dir, container string) ([]Capture, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result []Capture
+	for _, entry := range entries {
+		name := entry.Name()
+		if entry.IsDir() || !strings.HasPrefix(name, container+"-") || !strings.HasSuffix(name, extension) {
+			continue
+		}
+		// prefix of the longer container name is not a match
+		exited, err := time.Parse(timeFormat, strings.TrimSuffix(strings.TrimPrefix(name, container+"-"), extension))
+		if err != nil {
+			continue
+		}
+		result = append(result, Capture{
+			Container: container,
+			Exited:    exited,
+			Path:      filepath.Join(dir, name),
+		})
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Exited.After(result[j].Exited)
+	})
+	return result, nil
+}
+
+// Get returns the capture of the container by its index, 0 is the most recent.
+func Get(dir, container string, index int) (Capture, error) {
+	captures, err := List(dir, container)
+	if err != nil {
+		return Capture{}, err
+	}
+	if index < 0 || index >= len(captures) {
+		return Capture{}, ErrNotFound
+	}
+	return captures[index], nil
+}
+
+// Tail returns the last lines of the capture.
+func (c Capture) Tail(lines int) ([]string, error) {
+	data, err := os.ReadFile(c.Path)
+	if err != nil {
+		return nil, err
+	}
+	if len(data) == 0 {
+		return nil, nil
+	}
+	result := strings.Split(strings.TrimRight(string(data), "\n"), "\n")
+	if len(result) > lines {
+		result = result[len(result)-lines:]
+	}
+	return result, nil
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crashlog
+
+import (
+	"fmt"
+	"os"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSave(t *testing.T) {
+	dir := t.TempDir()
+	exited := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+
+	output := strings.Repeat("0123456789abcde\n", MaxSize/16+10)
+	path, err := Save(dir, "foo", exited, []byte(output))
+	require.NoError(t, err)
+	data, err := os.ReadFile(path)
+	require.NoError(t, err)
+	assert.LessOrEqual(t, len(data), MaxSize)
+	assert.True(t, strings.HasPrefix(string(data), "0123456789abcde\n"), "partial first line is dropped")
+
+	// the same exit is saved once
+	_, err = Save(dir, "foo", exited, []byte("panic: boom\n"))
+	require.NoError(t, err)
+	captures, err := List(dir, "foo")
+	require.NoError(t, err)
+	require.Len(t, captures, 1)
+	lines, err := captures[0].Tail(3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"panic: boom"}, lines)
+
+	for i := 1; i <= Keep+2; i++ {
+		_, err := Save(dir, "foo", exited.Add(time.Duration(i)*time.Minute), []byte(fmt.Sprintf("exit %d\n", i)))
+		require.NoError(t, err)
+	}
+	_, err = Save(dir, "foo-bar", exited, []byte("other container\n"))
+	require.NoError(t, err)
+
+	captures, err = List(dir, "foo")
+	require.NoError(t, err)
+	require.Len(t, captures, Keep)
+	assert.Equal(t, exited.Add(time.Duration(Keep+2)*time.Minute), captures[0].Exited)
+
+	latest, err := Get(dir, "foo", 0)
+	require.NoError(t, err)
+	lines, err = latest.Tail(3)
+	require.NoError(t, err)
+	assert.Equal(t, []string{fmt.Sprintf("exit %d", Keep+2)}, lines)
+
+	_, err = Get(dir, "foo", Keep)
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = Get(dir, "baz", 0)
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	captures, err = List(dir, "foo-bar")
+	require.NoError(t, err)
+	assert.Len(t, captures, 1)
+}
+
+func TestTail(t *testing.T) {
+	dir := t.TempDir()
+	path, err := Save(dir, "foo", time.Now(), []byte("one\ntwo\nthree\nfour\n"))
+	require.NoError(t, err)
+	lines, err := Capture{Path: path}.Tail(2)
+	require.NoError(t, err)
+	assert.Equal(t, []string{"three", "four"}, lines)
+}
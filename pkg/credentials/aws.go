@@ -0,0 +1,108 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package credentials resolves the cloud provider credentials
+// and maps them to the component parameters and secrets.
+package credentials
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/aws/aws-sdk-go/aws/session"
+)
+
+const (
+	// AWSSessionTokenEnv is the adapter environment variable with the
+	// session token of the temporary credentials. AWS components spec has
+	// no session token field, the SDK in the adapter reads it from the environment.
+	AWSSessionTokenEnv = "AWS_SESSION_TOKEN"
+
+	// DefaultAWSProfile is recorded when the credentials are resolved
+	// without the explicit profile.
+	DefaultAWSProfile = "default"
+)
+
+// AWS are the credentials resolved through the AWS SDK default chain:
+// environment, shared config and credentials files, SSO.
+type AWS struct {
+	Profile         string
+	Provider        string
+	AccessKeyID     string
+	SecretAccessKey string
+	SessionToken    string
+	// Expires is zero if the credentials do not expire.
+	Expires time.Time
+}
+
+// ResolveAWS returns the credentials of the AWS profile,
+// empty profile means the SDK default.
+func ResolveAWS(profile string) (*AWS, error) {
+	sess, err := session.NewSessionWithOptions(session.Options{
+		Profile:           profile,
+		SharedConfigState: session.SharedConfigEnable,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("aws session: %w", err)
+	}
+	value, err := sess.Config.Credentials.Get()
+	if err != nil {
+		return nil, fmt.Errorf("resolving aws credentials: %w", err)
+	}
+	if profile == "" {
+		profile = DefaultAWSProfile
+	}
+	result := &AWS{
+		Profile:         profile,
+		Provider:        value.ProviderName,
+		AccessKeyID:     value.AccessKeyID,
+		SecretAccessKey: value.SecretAccessKey,
+		SessionToken:    value.SessionToken,
+	}
+	// static credentials do not support expiration
+	if expires, err := sess.Config.Credentials.ExpiresAt(); err == nil {
+		result.Expires = expires
+	}
+	return result, nil
+}
+
+// Values returns the credentials values mapped to the component spec.
+func (c *AWS) Values() map[string]string {
+	return map[string]string{
+		"accessKeyID":     c.AccessKeyID,
+		"secretAccessKey": c.SecretAccessKey,
+	}
+}
+
+// SecretData returns the plain values of the component secret keys.
+func (c *AWS) SecretData() map[string]string {
+	data := map[string]string{
+		"accessKeyID":     c.AccessKeyID,
+		"secretAccessKey": c.SecretAccessKey,
+	}
+	if c.SessionToken != "" {
+		data[AWSSessionTokenEnv] = c.SessionToken
+	}
+	return data
+}
+
+// Expiry returns the RFC 3339 expiration time, empty if credentials do not expire.
+func (c *AWS) Expiry() string {
+	if c.Expires.IsZero() {
+		return ""
+	}
+	return c.Expires.UTC().Format(time.RFC3339)
+}
@@ -0,0 +1,83 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const sharedCredentials = `[default]
+aws_access_key_id = AKIADEFAULT
+aws_secret_access_key = default-secret
+
+[dev]
+aws_access_key_id = AKIADEV
+aws_secret_access_key = dev-secret
+aws_session_token = dev-token
+`
+
+func setupAWS(t *testing.T) {
+	dir := t.TempDir()
+	credentialsFile := filepath.Join(dir, "credentials")
+	require.NoError(t, os.WriteFile(credentialsFile, []byte(sharedCredentials), 0o600))
+	t.Setenv("AWS_SHARED_CREDENTIALS_FILE", credentialsFile)
+	t.Setenv("AWS_CONFIG_FILE", filepath.Join(dir, "config"))
+	t.Setenv("AWS_EC2_METADATA_DISABLED", "true")
+	for _, env := range []string{"AWS_PROFILE", "AWS_ACCESS_KEY_ID", "AWS_SECRET_ACCESS_KEY", "AWS_SESSION_TOKEN"} {
+		t.Setenv(env, "")
+		os.Unsetenv(env)
+	}
+}
+
+func TestResolveAWSProfile(t *testing.T) {
+	setupAWS(t)
+
+	creds, err := ResolveAWS("dev")
+	require.NoError(t, err)
+	assert.Equal(t, "dev", creds.Profile)
+	assert.Equal(t, map[string]string{
+		"accessKeyID":     "AKIADEV",
+		"secretAccessKey": "dev-secret",
+	}, creds.Values())
+	assert.Equal(t, "dev-token", creds.SecretData()[AWSSessionTokenEnv])
+	assert.Empty(t, creds.Expiry())
+
+	creds, err = ResolveAWS("")
+	require.NoError(t, err)
+	assert.Equal(t, DefaultAWSProfile, creds.Profile)
+	assert.Equal(t, "AKIADEFAULT", creds.AccessKeyID)
+	assert.NotContains(t, creds.SecretData(), AWSSessionTokenEnv)
+
+	_, err = ResolveAWS("missing")
+	assert.Error(t, err)
+}
+
+func TestResolveAWSEnv(t *testing.T) {
+	setupAWS(t)
+	t.Setenv("AWS_ACCESS_KEY_ID", "AKIAENV")
+	t.Setenv("AWS_SECRET_ACCESS_KEY", "env-secret")
+
+	creds, err := ResolveAWS("")
+	require.NoError(t, err)
+	assert.Equal(t, "AKIAENV", creds.AccessKeyID)
+	assert.Equal(t, "env-secret", creds.SecretAccessKey)
+}
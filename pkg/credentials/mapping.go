@@ -0,0 +1,140 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package credentials
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// Cloud credentials providers.
+const (
+	ProviderAWS   = "aws"
+	ProviderGCP   = "gcp"
+	ProviderAzure = "azure"
+)
+
+// field maps the credentials value to the candidate spec paths.
+// The first path that exists in the component CRD is used.
+type field struct {
+	value string
+	paths []string
+}
+
+// shapes describes how the provider credentials are set in the components spec.
+// New components with the same spec layout need no changes here.
+var shapes = map[string][]field{
+	ProviderAWS: {
+		{value: "accessKeyID", paths: []string{"auth.credentials.accessKeyID"}},
+		{value: "secretAccessKey", paths: []string{"auth.credentials.secretAccessKey"}},
+	},
+	ProviderGCP: {
+		// sources and targets name the service account key field differently
+		{value: "serviceAccountKey", paths: []string{"serviceAccountKey", "credentialsJson"}},
+	},
+	ProviderAzure: {
+		{value: azureTenantIDEnv, paths: []string{"auth.servicePrincipal.tenantID"}},
+		{value: azureClientIDEnv, paths: []string{"auth.servicePrincipal.clientID"}},
+		{value: azureClientSecretEnv, paths: []string{"auth.servicePrincipal.clientSecret"}},
+	},
+}
+
+const (
+	azureTenantIDEnv     = "AZURE_TENANT_ID"
+	azureClientIDEnv     = "AZURE_CLIENT_ID"
+	azureClientSecretEnv = "AZURE_CLIENT_SECRET"
+)
+
+// serviceAccountFields are the service account key fields required by the GCP clients.
+var serviceAccountFields = []string{"type", "project_id", "private_key_id", "private_key", "client_email", "client_id"}
+
+// Params maps the provider credentials values to the component parameters
+// according to the spec of the component CRD.
+func Params(provider string, c crd.CRD, values map[string]string) (map[string]string, error) {
+	shape, exists := shapes[provider]
+	if !exists {
+		return nil, fmt.Errorf("unknown credentials provider %q", provider)
+	}
+	schema, _, err := c.ServedSchema()
+	if err != nil {
+		return nil, err
+	}
+	result := make(map[string]string, len(shape))
+	for _, f := range shape {
+		path := ""
+		for _, p := range f.paths {
+			if exists, _ := schema.GetAttributesCompletion(strings.Split(p, ".")...); exists {
+				path = p
+				break
+			}
+		}
+		if path == "" {
+			return nil, fmt.Errorf("%s does not accept %s credentials", c.Spec.Names.Kind, provider)
+		}
+		result[path] = values[f.value]
+	}
+	return result, nil
+}
+
+// GCPServiceAccount reads the GCP service account key file.
+func GCPServiceAccount(path string) (map[string]string, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("service account key: %w", err)
+	}
+	var key map[string]interface{}
+	if err := json.Unmarshal(data, &key); err != nil {
+		return nil, fmt.Errorf("service account key %q is not a valid JSON: %w", path, err)
+	}
+	var missing []string
+	for _, f := range serviceAccountFields {
+		if v, ok := key[f].(string); !ok || v == "" {
+			missing = append(missing, f)
+		}
+	}
+	if len(missing) != 0 {
+		return nil, fmt.Errorf("service account key %q is incomplete, missing fields: %s", path, strings.Join(missing, ", "))
+	}
+	if key["type"] != "service_account" {
+		return nil, fmt.Errorf("%q is not a service account key, type is %q", path, key["type"])
+	}
+	return map[string]string{"serviceAccountKey": string(data)}, nil
+}
+
+// AzureEnv reads the Azure service principal from the environment.
+func AzureEnv() (map[string]string, error) {
+	result := make(map[string]string, 3)
+	var missing []string
+	for _, env := range []string{azureTenantIDEnv, azureClientIDEnv, azureClientSecretEnv} {
+		value := os.Getenv(env)
+		if value == "" {
+			missing = append(missing, env)
+			continue
+		}
+		result[env] = value
+	}
+	if len(missing) != 0 {
+		sort.Strings(missing)
+		return nil, fmt.Errorf("azure credentials are incomplete, missing environment variables: %s", strings.Join(missing, ", "))
+	}
+	return result, nil
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package datafilter parses and evaluates the trigger filters on the event
+// payload fields, e.g. `data.region == "eu-west-1"`. The broker filters match
+// the event attributes only, so the data filter is evaluated by the function
+// component that tmctl inserts between the broker and the trigger target.
+package datafilter
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"strings"
+)
+
+// Comparison operators of the expression.
+const (
+	OperatorEqual    = "=="
+	OperatorNotEqual = "!="
+)
+
+// Expression is the parsed data filter. The expression is either the
+// comparison of the payload field with the literal or the list of
+// the nested expressions joined with "&&" (All) or "||" (Any).
+type Expression struct {
+	All []Expression `json:"all,omitempty"`
+	Any []Expression `json:"any,omitempty"`
+
+	// Path is the payload field without the "data" prefix,
+	// empty path compares the whole payload.
+	Path     []string    `json:"path,omitempty"`
+	Operator string      `json:"operator,omitempty"`
+	Value    interface{} `json:"value"`
+}
+
+// Parse parses the expression of the "data.<field>" comparisons with the
+// JSON literals joined with "&&", "||" and grouped with the parentheses.
+func Parse(text string) (*Expression, error) {
+	p := &parser{text: text}
+	p.next()
+	expression, err := p.or()
+	if err != nil {
+		return nil, fmt.Errorf("data filter %q: %w", text, err)
+	}
+	if p.token != "" {
+		return nil, fmt.Errorf("data filter %q: unexpected %q at %d", text, p.token, p.start)
+	}
+	return expression, nil
+}
+
+// String returns the expression in the canonical form.
+func (e Expression) String() string {
+	switch {
+	case len(e.All) != 0:
+		return join(e.All, " && ")
+	case len(e.Any) != 0:
+		return join(e.Any, " || ")
+	}
+	literal, _ := json.Marshal(e.Value)
+	return fmt.Sprintf("%s %s %s", e.field(), e.Operator, literal)
+}
+
+func join(expressions []Expression, operator string) string {
+	parts := make([]string, 0, len(expressions))
+	for _, nested := range expressions {
+		part := nested.String()
+		if len(nested.All) != 0 || len(nested.Any) != 0 {
+			part = "(" + part + ")"
+		}
+		parts = append(parts, part)
+	}
+	return strings.Join(parts, operator)
+}
+
+func (e Expression) field() string {
+	return strings.Join(append([]string{"data"}, e.Path...), ".")
+}
+
+// Match evaluates the expression against the JSON payload of the event.
+// The reason explains why the payload does not match.
+func (e Expression) Match(data []byte) (bool, string) {
+	var payload interface{}
+	decoder := json.NewDecoder(bytes.NewReader(data))
+	if err := decoder.Decode(&payload); err != nil {
+		return false, "data is not JSON"
+	}
+	return e.evaluate(payload)
+}
+
+func (e Expression) evaluate(payload interface{}) (bool, string) {
+	switch {
+	case len(e.All) != 0:
+		for _, nested := range e.All {
+			if matched, reason := nested.evaluate(payload); !matched {
+				return false, reason
+			}
+		}
+		return true, ""
+	case len(e.Any) != 0:
+		var reasons []string
+		for _, nested := range e.Any {
+			matched, reason := nested.evaluate(payload)
+			if matched {
+				return true, ""
+			}
+			reasons = append(reasons, reason)
+		}
+		return false, strings.Join(reasons, " and ")
+	}
+	value, set := lookup(payload, e.Path)
+	literal, _ := json.Marshal(e.Value)
+	switch {
+	case e.Operator == OperatorNotEqual && (!set || !reflect.DeepEqual(value, e.Value)):
+		return true, ""
+	case e.Operator == OperatorNotEqual:
+		return false, fmt.Sprintf("%s is %s", e.field(), literal)
+	case !set:
+		return false, fmt.Sprintf("%s is not set", e.field())
+	case !reflect.DeepEqual(value, e.Value):
+		actual, _ := json.Marshal(value)
+		return false, fmt.Sprintf("%s %s is not %s", e.field(), actual, literal)
+	}
+	return true, ""
+}
+
+// lookup returns the payload field, the path through
+// the value that is not an object is not set.
+func lookup(payload interface{}, path []string) (interface{}, bool) {
+	for _, key := range path {
+		object, ok := payload.(map[string]interface{})
+		if !ok {
+			return nil, false
+		}
+		if payload, ok = object[key]; !ok {
+			return nil, false
+		}
+	}
+	return payload, true
+}
+
+// parser is the recursive descent parser of the expression,
+// token is the current token that starts at the start offset.
+type parser struct {
+	text  string
+	pos   int
+	start int
+	token string
+}
+
+func (p *parser) next() {
+	for p.pos < len(p.text) && strings.ContainsRune(" \t\r\n", rune(p.text[p.pos])) {
+		p.pos++
+	}
+	p.start = p.pos
+	if p.pos == len(p.text) {
+		p.token = ""
+		return
+	}
+	rest := p.text[p.pos:]
+	for _, symbol := range []string{"&&", "||", OperatorEqual, OperatorNotEqual, "(", ")"} {
+		if strings.HasPrefix(rest, symbol) {
+			p.pos += len(symbol)
+			p.token = symbol
+			return
+		}
+	}
+	if rest[0] == '"' {
+		escaped := false
+		for i := 1; i < len(rest); i++ {
+			switch {
+			case escaped:
+				escaped = false
+			case rest[i] == '\\':
+				escaped = true
+			case rest[i] == '"':
+				p.pos += i + 1
+				p.token = rest[:i+1]
+				return
+			}
+		}
+		p.pos = len(p.text)
+		p.token = rest
+		return
+	}
+	end := strings.IndexAny(rest, " \t\r\n&|=!()\"")
+	switch end {
+	case -1:
+		end = len(rest)
+	case 0:
+		// single character of the unknown operator
+		end = 1
+	}
+	p.pos += end
+	p.token = rest[:end]
+}
+
+func (p *parser) or() (*Expression, error) {
+	return p.list("||", p.and, func(e []Expression) *Expression { return &Expression{Any: e} })
+}
+
+func (p *parser) and() (*Expression, error) {
+	return p.list("&&", p.operand, func(e []Expression) *Expression { return &Expression{All: e} })
+}
+
+func (p *parser) list(operator string, operand func() (*Expression, error), group func([]Expression) *Expression) (*Expression, error) {
+	first, err := operand()
+	if err != nil {
+		return nil, err
+	}
+	expressions := []Expression{*first}
+	for p.token == operator {
+		p.next()
+		e, err := operand()
+		if err != nil {
+			return nil, err
+		}
+		expressions = append(expressions, *e)
+	}
+	if len(expressions) == 1 {
+		return first, nil
+	}
+	return group(expressions), nil
+}
+
+func (p *parser) operand() (*Expression, error) {
+	if p.token == "(" {
+		p.next()
+		e, err := p.or()
+		if err != nil {
+			return nil, err
+		}
+		if p.token != ")" {
+			return nil, p.expected(`")"`)
+		}
+		p.next()
+		return e, nil
+	}
+	path, err := p.path()
+	if err != nil {
+		return nil, err
+	}
+	operator := p.token
+	if operator != OperatorEqual && operator != OperatorNotEqual {
+		return nil, p.expected(fmt.Sprintf("%q or %q", OperatorEqual, OperatorNotEqual))
+	}
+	p.next()
+	var value interface{}
+	if p.token == "" || json.Unmarshal([]byte(p.token), &value) != nil {
+		return nil, p.expected("JSON string, number, boolean or null")
+	}
+	p.next()
+	return &Expression{Path: path, Operator: operator, Value: value}, nil
+}
+
+func (p *parser) path() ([]string, error) {
+	fields := strings.Split(p.token, ".")
+	if fields[0] != "data" {
+		return nil, p.expected(`"data" field`)
+	}
+	for _, field := range fields[1:] {
+		if field == "" {
+			return nil, p.expected("field name")
+		}
+	}
+	p.next()
+	return fields[1:], nil
+}
+
+func (p *parser) expected(what string) error {
+	if p.token == "" {
+		return fmt.Errorf("expected %s at the end", what)
+	}
+	return fmt.Errorf("expected %s, got %q at %d", what, p.token, p.start)
+}
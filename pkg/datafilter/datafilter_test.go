@@ -0,0 +1,184 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datafilter
+
+import (
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestParse(t *testing.T) {
+	testCases := map[string]struct {
+		expression string
+		canonical  string
+		err        string
+	}{
+		"string comparison": {
+			expression: `data.region=="eu-west-1"`,
+			canonical:  `data.region == "eu-west-1"`,
+		},
+		"precedence": {
+			expression: `data.a == 1 || data.b.c != true && data.d == null`,
+			canonical:  `data.a == 1 || (data.b.c != true && data.d == null)`,
+		},
+		"parentheses": {
+			expression: `(data.a == "x" || data.a == "y") && data.n == 2.5`,
+			canonical:  `(data.a == "x" || data.a == "y") && data.n == 2.5`,
+		},
+		"escaped quote": {
+			expression: `data.name == "say \"hi\""`,
+			canonical:  `data.name == "say \"hi\""`,
+		},
+		"attribute": {
+			expression: `type == "foo"`,
+			err:        `data filter "type == \"foo\"": expected "data" field, got "type" at 0`,
+		},
+		"unknown operator": {
+			expression: `data.a = "x"`,
+			err:        `data filter "data.a = \"x\"": expected "==" or "!=", got "=" at 7`,
+		},
+		"unquoted string": {
+			expression: `data.a == x`,
+			err:        `data filter "data.a == x": expected JSON string, number, boolean or null, got "x" at 10`,
+		},
+		"unclosed parenthesis": {
+			expression: `(data.a == 1`,
+			err:        `data filter "(data.a == 1": expected ")" at the end`,
+		},
+		"trailing token": {
+			expression: `data.a == 1 data.b == 2`,
+			err:        `data filter "data.a == 1 data.b == 2": unexpected "data.b" at 12`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			expression, err := Parse(tc.expression)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.canonical, expression.String())
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	testCases := map[string]struct {
+		expression string
+		data       string
+		matched    bool
+		reason     string
+	}{
+		"equal": {
+			expression: `data.region == "eu-west-1"`,
+			data:       `{"region":"eu-west-1"}`,
+			matched:    true,
+		},
+		"not equal value": {
+			expression: `data.region == "eu-west-1"`,
+			data:       `{"region":"us-east-1"}`,
+			reason:     `data.region "us-east-1" is not "eu-west-1"`,
+		},
+		"missing field": {
+			expression: `data.order.region == "eu-west-1"`,
+			data:       `{"order":"eu-west-1"}`,
+			reason:     `data.order.region is not set`,
+		},
+		"missing field is not equal": {
+			expression: `data.region != "eu-west-1"`,
+			data:       `{}`,
+			matched:    true,
+		},
+		"boolean is not number": {
+			expression: `data.paid == 1`,
+			data:       `{"paid":true}`,
+			reason:     `data.paid true is not 1`,
+		},
+		"any": {
+			expression: `data.a == 1 || data.b == 2`,
+			data:       `{"a":2,"b":1}`,
+			reason:     `data.a 2 is not 1 and data.b 1 is not 2`,
+		},
+		"all": {
+			expression: `data.a == 1 && data.b != 2`,
+			data:       `{"a":1,"b":2}`,
+			reason:     `data.b is 2`,
+		},
+		"not JSON": {
+			expression: `data.a == 1`,
+			data:       `a=1`,
+			reason:     `data is not JSON`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			expression, err := Parse(tc.expression)
+			require.NoError(t, err)
+			matched, reason := expression.Match([]byte(tc.data))
+			assert.Equal(t, tc.matched, matched)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
+
+func TestFunctionCode(t *testing.T) {
+	python, err := exec.LookPath("python3")
+	if err != nil {
+		t.Skip("python3 is not installed")
+	}
+	expression, err := Parse(`(data.region == "eu-\"west\"-1" || data.region == null) && data.paid != 1`)
+	require.NoError(t, err)
+	code, err := expression.FunctionCode()
+	require.NoError(t, err)
+
+	// the function must agree with Match
+	dir := t.TempDir()
+	require.NoError(t, os.WriteFile(filepath.Join(dir, "source.py"), []byte(code), 0o600))
+	check := `import json, sys
+from source import handler
+for line in sys.stdin:
+    print(handler(json.loads(line), None) is not None)
+`
+	payloads := []string{
+		`{"region":"eu-\"west\"-1","paid":true}`,
+		`{"region":null}`,
+		`{"region":"eu-\"west\"-1","paid":1}`,
+		`{"region":"us-east-1"}`,
+	}
+	cmd := exec.Command(python, "-c", check)
+	cmd.Dir = dir
+	cmd.Stdin = strings.NewReader(strings.Join(payloads, "\n"))
+	output, err := cmd.CombinedOutput()
+	require.NoError(t, err, string(output))
+
+	results := strings.Fields(string(output))
+	require.Len(t, results, len(payloads))
+	for i, payload := range payloads {
+		expected := "False"
+		if matched, _ := expression.Match([]byte(payload)); matched {
+			expected = "True"
+		}
+		assert.Equal(t, expected, results[i], payload)
+	}
+}
@@ -0,0 +1,84 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package datafilter
+
+import (
+	"encoding/json"
+	"fmt"
+)
+
+// Runtime and Entrypoint of the filter function component.
+const (
+	Runtime    = "python"
+	Entrypoint = "handler"
+)
+
+// functionCode evaluates the expression like Match does: the missing
+// fields do not equal any literal and booleans do not equal numbers.
+// The function replies with the payload of the matched event only.
+const functionCode = `import json
+
+EXPRESSION = json.loads(%s)
+MISSING = object()
+
+
+def lookup(data, path):
+    for key in path:
+        if not isinstance(data, dict) or key not in data:
+            return MISSING
+        data = data[key]
+    return data
+
+
+def equal(value, expected):
+    if isinstance(value, bool) or isinstance(expected, bool):
+        return type(value) == type(expected) and value == expected
+    return value == expected
+
+
+def evaluate(expression, data):
+    if expression.get("all"):
+        return all(evaluate(e, data) for e in expression["all"])
+    if expression.get("any"):
+        return any(evaluate(e, data) for e in expression["any"])
+    value = lookup(data, expression.get("path", []))
+    matched = value is not MISSING and equal(value, expression["value"])
+    if expression["operator"] == "!=":
+        return not matched
+    return matched
+
+
+def handler(event, context):
+    if evaluate(EXPRESSION, event):
+        return event
+    return None
+`
+
+// FunctionCode returns the source of the Python function
+// that drops the events not matching the expression.
+func (e Expression) FunctionCode() (string, error) {
+	expression, err := json.Marshal(e)
+	if err != nil {
+		return "", fmt.Errorf("encode expression: %w", err)
+	}
+	// JSON string is the valid Python string literal
+	literal, err := json.Marshal(string(expression))
+	if err != nil {
+		return "", fmt.Errorf("encode expression: %w", err)
+	}
+	return fmt.Sprintf(functionCode, literal), nil
+}
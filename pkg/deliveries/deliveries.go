@@ -0,0 +1,340 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package deliveries inspects the events awaiting the delivery
+// in the broker backend.
+package deliveries
+
+import (
+	"context"
+	"crypto/tls"
+	"errors"
+	"fmt"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/rickb777/date/period"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+const (
+	// redis broker defaults, tmctl does not override them
+	stream      = "triggermesh"
+	groupPrefix = "default."
+	eventKey    = "ce"
+
+	pendingPageSize = "1000"
+	rangePageSize   = 1000
+)
+
+// ErrUnsupported is returned for the brokers that do not keep
+// the undelivered events in an inspectable backend.
+var ErrUnsupported = errors.New("pending deliveries are available only with the redis broker backend")
+
+// ErrNotFound is returned when no pending delivery matches the event ID.
+var ErrNotFound = errors.New("pending delivery not found")
+
+// Pending is the event that has been read by the broker
+// but was not acknowledged by the trigger target yet.
+type Pending struct {
+	Trigger string
+	Target  string
+	URL     string
+	// ContentMode is the CloudEvents content mode of the trigger deliveries.
+	ContentMode string
+	MessageID   string
+	Event       cloudevents.Event
+	// Attempts is the estimated number of the delivery attempts
+	// based on the trigger backoff policy.
+	Attempts int
+	// NextAttempt is zero when the retries are exhausted.
+	NextAttempt time.Time
+	// Deliveries is the number of times the backend handed the event
+	// to the broker, i.e. the broker restarts while delivering.
+	Deliveries int64
+}
+
+// Queue is the broker backend connection.
+type Queue struct {
+	conn     *conn
+	triggers map[string]tmbroker.LocalTriggerSpec
+}
+
+// Open connects to the backend of the broker.
+func Open(configBase, broker string, c config.BrokerConfig) (*Queue, error) {
+	if c.Redis == nil {
+		return nil, ErrUnsupported
+	}
+	configuration, err := tmbroker.ReadConfig(configBase, broker)
+	if err != nil {
+		return nil, fmt.Errorf("broker config: %w", err)
+	}
+	var tlsConfig *tls.Config
+	if c.Redis.TLSEnabled {
+		tlsConfig = &tls.Config{InsecureSkipVerify: c.Redis.SkipVerify}
+	}
+	redis, err := dial(hostAddress(c.Redis.Address), c.Redis.Username, c.Redis.Password, tlsConfig)
+	if err != nil {
+		return nil, fmt.Errorf("redis: %w", err)
+	}
+	return &Queue{
+		conn:     redis,
+		triggers: configuration.Triggers,
+	}, nil
+}
+
+// Close closes the backend connection.
+func (q *Queue) Close() error {
+	return q.conn.Close()
+}
+
+// Pending returns the events awaiting the delivery, sorted by the trigger name.
+// Empty trigger name returns the events of all triggers.
+func (q *Queue) Pending(trigger string) ([]Pending, error) {
+	var names []string
+	for name := range q.triggers {
+		if trigger == "" || trigger == name {
+			names = append(names, name)
+		}
+	}
+	if trigger != "" && len(names) == 0 {
+		return nil, fmt.Errorf("trigger %q not found", trigger)
+	}
+	sort.Strings(names)
+
+	now := time.Now()
+	var result []Pending
+	for _, name := range names {
+		reply, err := q.conn.do("XPENDING", stream, groupPrefix+name, "-", "+", pendingPageSize)
+		if isNoGroup(err) {
+			continue
+		}
+		if err != nil {
+			return nil, fmt.Errorf("trigger %q: %w", name, err)
+		}
+		entries, _ := reply.([]interface{})
+		for _, e := range entries {
+			entry, ok := e.([]interface{})
+			if !ok || len(entry) != 4 {
+				continue
+			}
+			id, _ := entry[0].(string)
+			idle, _ := entry[2].(int64)
+			count, _ := entry[3].(int64)
+			event, err := q.event(id)
+			if err != nil {
+				return nil, fmt.Errorf("message %q: %w", id, err)
+			}
+			spec := q.triggers[name]
+			p := Pending{
+				Trigger:     name,
+				Target:      target(spec.Target),
+				URL:         spec.Target.TargetURL(),
+				ContentMode: spec.Target.ContentMode,
+				MessageID:   id,
+				Event:       event,
+				Deliveries:  count,
+			}
+			p.Attempts, p.NextAttempt = Schedule(spec.Target.DeliveryOptions, now.Add(-time.Duration(idle)*time.Millisecond), now)
+			result = append(result, p)
+		}
+	}
+	return result, nil
+}
+
+// Find returns the pending delivery of the event.
+func (q *Queue) Find(trigger, eventID string) (Pending, error) {
+	pending, err := q.Pending(trigger)
+	if err != nil {
+		return Pending{}, err
+	}
+	for _, p := range pending {
+		if p.Event.ID() == eventID {
+			return p, nil
+		}
+	}
+	return Pending{}, ErrNotFound
+}
+
+// Cancel acknowledges the pending event so that the broker stops delivering it.
+func (q *Queue) Cancel(p Pending) error {
+	_, err := q.conn.do("XACK", stream, groupPrefix+p.Trigger, p.MessageID)
+	return err
+}
+
+// Deliver sends the pending event to the trigger target immediately
+// and acknowledges it once the target accepts the event.
+func (q *Queue) Deliver(ctx context.Context, p Pending) error {
+	if p.URL == "" {
+		return fmt.Errorf("trigger %q has no target URL", p.Trigger)
+	}
+	client, err := cloudevents.NewClientHTTP()
+	if err != nil {
+		return fmt.Errorf("cloudevents client: %w", err)
+	}
+	ctx = cloudevents.ContextWithTarget(ctx, hostURL(p.URL))
+	if p.ContentMode == tmbroker.ContentModeStructured {
+		ctx = cloudevents.WithEncodingStructured(ctx)
+	}
+	if result := client.Send(ctx, p.Event); !cloudevents.IsACK(result) {
+		return fmt.Errorf("delivery to %s: %w", p.Target, result)
+	}
+	return q.Cancel(p)
+}
+
+func (q *Queue) event(id string) (cloudevents.Event, error) {
+	reply, err := q.conn.do("XRANGE", stream, id, id)
+	if err != nil {
+		return cloudevents.NewEvent(), err
+	}
+	messages, _ := reply.([]interface{})
+	if len(messages) != 1 {
+		return cloudevents.NewEvent(), fmt.Errorf("message was trimmed from the stream")
+	}
+	_, event, err := message(messages[0])
+	return event, err
+}
+
+// Entry is the event accepted by the broker.
+type Entry struct {
+	Time  time.Time
+	Event cloudevents.Event
+}
+
+// Since returns the events accepted by the broker after the given time.
+// The acceptance time is the timestamp of the backend stream message ID.
+func (q *Queue) Since(t time.Time) ([]Entry, error) {
+	var result []Entry
+	start := strconv.FormatInt(t.UnixMilli(), 10)
+	for {
+		reply, err := q.conn.do("XRANGE", stream, start, "+", "COUNT", strconv.Itoa(rangePageSize))
+		if err != nil {
+			return nil, err
+		}
+		messages, _ := reply.([]interface{})
+		var id string
+		for _, m := range messages {
+			if id, err = messageID(m); err != nil {
+				return nil, err
+			}
+			// foreign stream messages are not the events
+			if _, event, err := message(m); err == nil {
+				ms, _ := strconv.ParseInt(strings.SplitN(id, "-", 2)[0], 10, 64)
+				result = append(result, Entry{Time: time.UnixMilli(ms), Event: event})
+			}
+		}
+		if len(messages) < rangePageSize {
+			return result, nil
+		}
+		start = "(" + id
+	}
+}
+
+// Triggers returns the trigger specs of the broker configuration.
+func (q *Queue) Triggers() map[string]tmbroker.LocalTriggerSpec {
+	return q.triggers
+}
+
+func messageID(reply interface{}) (string, error) {
+	message, _ := reply.([]interface{})
+	if len(message) != 2 {
+		return "", fmt.Errorf("unexpected message format")
+	}
+	id, _ := message[0].(string)
+	return id, nil
+}
+
+// message parses the stream message reply into its ID and the event.
+func message(reply interface{}) (string, cloudevents.Event, error) {
+	event := cloudevents.NewEvent()
+	id, err := messageID(reply)
+	if err != nil {
+		return "", event, err
+	}
+	fields, _ := reply.([]interface{})[1].([]interface{})
+	for i := 0; i+1 < len(fields); i += 2 {
+		if key, _ := fields[i].(string); key == eventKey {
+			value, _ := fields[i+1].(string)
+			return id, event, event.UnmarshalJSON([]byte(value))
+		}
+	}
+	return id, event, fmt.Errorf("message has no event")
+}
+
+// Schedule estimates the number of the delivery attempts made since the start
+// and the time of the next attempt following the broker backoff policy.
+// Request durations are not accounted, so the values are approximate.
+func Schedule(delivery *eventingbroker.DeliveryOptions, start, now time.Time) (int, time.Time) {
+	if delivery == nil || delivery.Retry == nil || *delivery.Retry == 0 {
+		return 1, time.Time{}
+	}
+	var delay time.Duration
+	if delivery.BackoffDelay != nil {
+		if p, err := period.Parse(*delivery.BackoffDelay); err == nil {
+			delay = p.DurationApprox()
+		}
+	}
+	policy := eventingbroker.BackoffPolicyConstant
+	if delivery.BackoffPolicy != nil {
+		policy = *delivery.BackoffPolicy
+	}
+	attempts, next := 1, start
+	for retry := 1; retry <= int(*delivery.Retry); retry++ {
+		backoff := delay
+		switch policy {
+		case eventingbroker.BackoffPolicyLinear:
+			backoff = delay * time.Duration(retry)
+		case eventingbroker.BackoffPolicyExponential:
+			backoff = delay * time.Duration(1<<retry)
+		}
+		next = next.Add(backoff)
+		if next.After(now) {
+			return attempts, next
+		}
+		attempts++
+	}
+	return attempts, time.Time{}
+}
+
+func target(t tmbroker.LocalTarget) string {
+	if t.Component != "" {
+		return t.Component
+	}
+	return t.TargetURL()
+}
+
+// hostAddress replaces the docker host alias with the host address
+// since tmctl connects to the backend from the host.
+func hostAddress(address string) string {
+	return urls.New("").HostAddress(address)
+}
+
+func hostURL(address string) string {
+	return urls.New("").HostURL(address)
+}
+
+func isNoGroup(err error) bool {
+	var rerr redisError
+	return errors.As(err, &rerr) && strings.HasPrefix(string(rerr), "NOGROUP")
+}
@@ -0,0 +1,289 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package deliveries
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+// fakeRedis serves the stream commands used by the queue.
+type fakeRedis struct {
+	sync.Mutex
+	// pending message IDs indexed by the consumer group
+	pending map[string][]string
+	events  map[string]string
+}
+
+func (f *fakeRedis) serve(t *testing.T) string {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	require.NoError(t, err)
+	t.Cleanup(func() { listener.Close() })
+	go func() {
+		for {
+			c, err := listener.Accept()
+			if err != nil {
+				return
+			}
+			go f.handle(&conn{Conn: c, r: bufio.NewReader(c)})
+		}
+	}()
+	return listener.Addr().String()
+}
+
+func (f *fakeRedis) handle(c *conn) {
+	defer c.Close()
+	for {
+		request, err := c.read()
+		if err != nil {
+			return
+		}
+		var args []string
+		for _, arg := range request.([]interface{}) {
+			args = append(args, arg.(string))
+		}
+		fmt.Fprint(c, f.reply(args))
+	}
+}
+
+func (f *fakeRedis) reply(args []string) string {
+	f.Lock()
+	defer f.Unlock()
+	switch args[0] {
+	case "XPENDING":
+		ids, exists := f.pending[args[2]]
+		if !exists {
+			return "-NOGROUP No such key or consumer group\r\n"
+		}
+		reply := fmt.Sprintf("*%d\r\n", len(ids))
+		for _, id := range ids {
+			reply += fmt.Sprintf("*4\r\n$%d\r\n%s\r\n$8\r\nconsumer\r\n:1500\r\n:1\r\n", len(id), id)
+		}
+		return reply
+	case "XRANGE":
+		if args[3] == "+" {
+			return f.xrange(args[2])
+		}
+		event := f.events[args[2]]
+		return fmt.Sprintf("*1\r\n*2\r\n$%d\r\n%s\r\n*2\r\n$2\r\nce\r\n$%d\r\n%s\r\n",
+			len(args[2]), args[2], len(event), event)
+	case "XACK":
+		var left []string
+		for _, id := range f.pending[args[2]] {
+			if id != args[3] {
+				left = append(left, id)
+			}
+		}
+		f.pending[args[2]] = left
+		return ":1\r\n"
+	}
+	return "-ERR unknown command\r\n"
+}
+
+// xrange returns the events with the IDs after the start, the start
+// is either the milliseconds timestamp or the exclusive message ID.
+func (f *fakeRedis) xrange(start string) string {
+	var ids []string
+	for id := range f.events {
+		ms, _ := strconv.ParseInt(strings.SplitN(id, "-", 2)[0], 10, 64)
+		switch {
+		case strings.HasPrefix(start, "("):
+			if id <= start[1:] {
+				continue
+			}
+		default:
+			if from, _ := strconv.ParseInt(start, 10, 64); ms < from {
+				continue
+			}
+		}
+		ids = append(ids, id)
+	}
+	sort.Strings(ids)
+	reply := fmt.Sprintf("*%d\r\n", len(ids))
+	for _, id := range ids {
+		event := f.events[id]
+		reply += fmt.Sprintf("*2\r\n$%d\r\n%s\r\n*2\r\n$2\r\nce\r\n$%d\r\n%s\r\n",
+			len(id), id, len(event), event)
+	}
+	return reply
+}
+
+func writeBrokerConfig(t *testing.T, configBase, targetURL string) {
+	dir := filepath.Join(configBase, "foo")
+	require.NoError(t, os.MkdirAll(dir, os.ModePerm))
+	conf := fmt.Sprintf(`triggers:
+  foo-trigger:
+    target:
+      url: %s
+      component: sink
+      deliveryOptions:
+        retry: 3
+        backoffdelay: PT1S
+  bar-trigger:
+    target:
+      url: http://localhost:1
+`, targetURL)
+	require.NoError(t, os.WriteFile(filepath.Join(dir, triggermesh.BrokerConfigFile), []byte(conf), 0644))
+}
+
+func TestQueue(t *testing.T) {
+	received := make(chan string, 1)
+	sink := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		received <- r.Header.Get("Ce-Id")
+		w.WriteHeader(http.StatusAccepted)
+	}))
+	defer sink.Close()
+
+	redis := &fakeRedis{
+		pending: map[string][]string{"default.foo-trigger": {"1-0"}},
+		events: map[string]string{
+			"1-0": `{"specversion":"1.0","id":"event-1","type":"order.created","source":"shop"}`,
+		},
+	}
+	configBase := t.TempDir()
+	writeBrokerConfig(t, configBase, sink.URL)
+
+	_, err := Open(configBase, "foo", config.BrokerConfig{Memory: &config.InMemoryBrokerConfig{}})
+	assert.ErrorIs(t, err, ErrUnsupported)
+
+	q, err := Open(configBase, "foo", config.BrokerConfig{Redis: &config.RedisBrokerConfig{Address: redis.serve(t)}})
+	require.NoError(t, err)
+	defer q.Close()
+
+	pending, err := q.Pending("")
+	require.NoError(t, err)
+	require.Len(t, pending, 1)
+	assert.Equal(t, "foo-trigger", pending[0].Trigger)
+	assert.Equal(t, "sink", pending[0].Target)
+	assert.Equal(t, "order.created", pending[0].Event.Type())
+	assert.Equal(t, 2, pending[0].Attempts)
+	assert.False(t, pending[0].NextAttempt.IsZero())
+
+	_, err = q.Pending("baz-trigger")
+	assert.Error(t, err)
+	_, err = q.Find("", "event-2")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	p, err := q.Find("foo-trigger", "event-1")
+	require.NoError(t, err)
+	require.NoError(t, q.Deliver(context.Background(), p))
+	assert.Equal(t, "event-1", <-received)
+
+	pending, err = q.Pending("")
+	require.NoError(t, err)
+	assert.Empty(t, pending)
+}
+
+func TestQueueSince(t *testing.T) {
+	now := time.Now()
+	old := strconv.FormatInt(now.Add(-10*time.Minute).UnixMilli(), 10)
+	recent := strconv.FormatInt(now.Add(-time.Minute).UnixMilli(), 10)
+	redis := &fakeRedis{
+		events: map[string]string{
+			old + "-0":    `{"specversion":"1.0","id":"event-1","type":"order.created","source":"shop"}`,
+			recent + "-0": `{"specversion":"1.0","id":"event-2","type":"order.created","source":"shop"}`,
+			recent + "-1": `{"specversion":"1.0","id":"event-3","type":"order.paid","source":"shop"}`,
+		},
+	}
+	configBase := t.TempDir()
+	writeBrokerConfig(t, configBase, "http://localhost:1")
+
+	q, err := Open(configBase, "foo", config.BrokerConfig{Redis: &config.RedisBrokerConfig{Address: redis.serve(t)}})
+	require.NoError(t, err)
+	defer q.Close()
+
+	entries, err := q.Since(now.Add(-5 * time.Minute))
+	require.NoError(t, err)
+	require.Len(t, entries, 2)
+	assert.Equal(t, "event-2", entries[0].Event.ID())
+	assert.Equal(t, "event-3", entries[1].Event.ID())
+	assert.Equal(t, now.Add(-time.Minute).UnixMilli(), entries[0].Time.UnixMilli())
+	assert.Len(t, q.Triggers(), 2)
+}
+
+func TestSchedule(t *testing.T) {
+	retry := int32(3)
+	delay := "PT1S"
+	linear := eventingbroker.BackoffPolicyLinear
+	exponential := eventingbroker.BackoffPolicyExponential
+	start := time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC)
+
+	testCases := map[string]struct {
+		delivery *eventingbroker.DeliveryOptions
+		elapsed  time.Duration
+		attempts int
+		next     time.Duration
+	}{
+		"no retries": {
+			attempts: 1,
+		},
+		"constant first retry": {
+			delivery: &eventingbroker.DeliveryOptions{Retry: &retry, BackoffDelay: &delay},
+			elapsed:  500 * time.Millisecond,
+			attempts: 1,
+			next:     time.Second,
+		},
+		"linear second retry": {
+			delivery: &eventingbroker.DeliveryOptions{Retry: &retry, BackoffDelay: &delay, BackoffPolicy: &linear},
+			elapsed:  2 * time.Second,
+			attempts: 2,
+			next:     3 * time.Second,
+		},
+		"exponential retries exhausted": {
+			delivery: &eventingbroker.DeliveryOptions{Retry: &retry, BackoffDelay: &delay, BackoffPolicy: &exponential},
+			elapsed:  time.Minute,
+			attempts: 4,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			attempts, next := Schedule(tc.delivery, start, start.Add(tc.elapsed))
+			assert.Equal(t, tc.attempts, attempts)
+			if tc.next == 0 {
+				assert.True(t, next.IsZero())
+				return
+			}
+			assert.Equal(t, start.Add(tc.next), next)
+		})
+	}
+}
+
+func TestHostAddress(t *testing.T) {
+	assert.Equal(t, "localhost:6379", hostAddress("host.docker.internal:6379"))
+	assert.Equal(t, "redis:6379", hostAddress("redis:6379"))
+	assert.True(t, strings.HasPrefix(hostURL("http://host.docker.internal:8080/path"), "http://localhost:8080/path"))
+}
@@ -0,0 +1,235 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"strconv"
+	"strings"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/mount"
+	"github.com/docker/docker/client"
+)
+
+// ContainerNames returns the names of all containers, including stopped ones,
+// accepted by the match function.
+func ContainerNames(ctx context.Context, client *client.Client, match func(name string) bool) ([]string, error) {
+	containers, err := client.ContainerList(ctx, types.ContainerListOptions{
+		All: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, container := range containers {
+		for _, name := range container.Names {
+			if name = strings.TrimPrefix(name, "/"); match(name) {
+				result = append(result, name)
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// Labeled is the container created by tmctl.
+type Labeled struct {
+	ID        string
+	Name      string
+	Component string
+	// Context is empty for the containers created by the older
+	// tmctl versions outside of the context network.
+	Context string
+	State   string
+	ImageID string
+	// Size is the size of the container writable layer.
+	Size int64
+}
+
+// LabeledContainers returns the containers created by tmctl, including
+// stopped ones, in the contexts accepted by the match function. Containers
+// without the tmctl component label are never returned.
+func LabeledContainers(ctx context.Context, client *client.Client, match func(context string) bool) ([]Labeled, error) {
+	containers, err := client.ContainerList(ctx, types.ContainerListOptions{
+		All:     true,
+		Size:    true,
+		Filters: filters.NewArgs(filters.Arg("label", ComponentLabel)),
+	})
+	if err != nil {
+		return nil, daemonError(ctx, err)
+	}
+	var result []Labeled
+	for i := range containers {
+		component, labeled := containers[i].Labels[ComponentLabel]
+		if !labeled {
+			continue
+		}
+		context, _ := containerContext(&containers[i])
+		if !match(context) {
+			continue
+		}
+		var name string
+		if len(containers[i].Names) != 0 {
+			name = strings.TrimPrefix(containers[i].Names[0], "/")
+		}
+		result = append(result, Labeled{
+			ID:        containers[i].ID,
+			Name:      name,
+			Component: component,
+			Context:   context,
+			State:     containers[i].State,
+			ImageID:   containers[i].ImageID,
+			Size:      containers[i].SizeRw,
+		})
+	}
+	return result, nil
+}
+
+// RemoveContainer deletes the stopped container by its ID.
+func RemoveContainer(ctx context.Context, id string, client *client.Client) error {
+	return client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{})
+}
+
+// UsedImages returns the number of the containers using the image indexed by
+// the image ID. All containers are counted, including stopped ones and
+// the ones not created by tmctl.
+func UsedImages(ctx context.Context, client *client.Client) (map[string]int, error) {
+	containers, err := client.ContainerList(ctx, types.ContainerListOptions{
+		All: true,
+	})
+	if err != nil {
+		return nil, daemonError(ctx, err)
+	}
+	result := make(map[string]int, len(containers))
+	for _, container := range containers {
+		result[container.ImageID]++
+	}
+	return result, nil
+}
+
+// Image is the local image with its tags.
+type Image struct {
+	ID   string
+	Tags []string
+	Size int64
+}
+
+// Images returns the local images having at least one tag
+// of the repository with the prefix, e.g. "gcr.io/triggermesh/".
+func Images(ctx context.Context, client *client.Client, repositoryPrefix string) ([]Image, error) {
+	images, err := client.ImageList(ctx, types.ImageListOptions{})
+	if err != nil {
+		return nil, daemonError(ctx, err)
+	}
+	var result []Image
+	for _, image := range images {
+		for _, tag := range image.RepoTags {
+			if strings.HasPrefix(tag, repositoryPrefix) {
+				result = append(result, Image{ID: image.ID, Tags: image.RepoTags, Size: image.Size})
+				break
+			}
+		}
+	}
+	return result, nil
+}
+
+// RemoveImage untags the image reference, the image is deleted
+// along with its last tag.
+func RemoveImage(ctx context.Context, reference string, client *client.Client) error {
+	_, err := client.ImageRemove(ctx, reference, types.ImageRemoveOptions{PruneChildren: true})
+	return err
+}
+
+// ContainerStates returns the state of all containers, including stopped ones,
+// indexed by the container name and by the component name of the current
+// context containers named differently, see ContainerName.
+func ContainerStates(ctx context.Context, client *client.Client) (map[string]string, error) {
+	containers, err := client.ContainerList(ctx, types.ContainerListOptions{
+		All: true,
+	})
+	if err != nil {
+		return nil, daemonError(ctx, err)
+	}
+	result := make(map[string]string, len(containers))
+	for _, container := range containers {
+		for _, name := range container.Names {
+			result[strings.TrimPrefix(name, "/")] = container.State
+		}
+	}
+	// components of the current context running under the different names
+	names.Lock()
+	defer names.Unlock()
+	for component, container := range names.read(names.context()) {
+		if state, set := result[container]; set {
+			result[component] = state
+		}
+	}
+	return result, nil
+}
+
+// PortOwner returns the component of the container publishing the host port,
+// empty string is returned if no container publishes it. Containers created
+// by the older tmctl versions have no component label and are named after
+// their components.
+func PortOwner(ctx context.Context, client *client.Client, port string) (string, error) {
+	containers, err := client.ContainerList(ctx, types.ContainerListOptions{})
+	if err != nil {
+		return "", daemonError(ctx, err)
+	}
+	for _, container := range containers {
+		for _, p := range container.Ports {
+			if strconv.Itoa(int(p.PublicPort)) != port {
+				continue
+			}
+			if component, set := container.Labels[ComponentLabel]; set {
+				return component, nil
+			}
+			if len(container.Names) != 0 {
+				return strings.TrimPrefix(container.Names[0], "/"), nil
+			}
+		}
+	}
+	return "", nil
+}
+
+// NamedVolumes returns the named volumes mounted into the container,
+// bind mounts and anonymous volumes are not included.
+func NamedVolumes(ctx context.Context, name string, client *client.Client) ([]string, error) {
+	existing, err := lookup(ctx, name, client)
+	if err != nil || existing == nil {
+		return nil, err
+	}
+	container, err := client.ContainerInspect(ctx, existing.ID)
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, m := range container.Mounts {
+		// anonymous volumes are named with their 64 characters ID
+		if m.Type == mount.TypeVolume && m.Name != "" && len(m.Name) != 64 {
+			result = append(result, m.Name)
+		}
+	}
+	return result, nil
+}
+
+// RemoveVolume deletes the volume that is not used by any container.
+func RemoveVolume(ctx context.Context, name string, client *client.Client) error {
+	return client.VolumeRemove(ctx, name, false)
+}
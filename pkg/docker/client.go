@@ -18,8 +18,10 @@ package docker
 
 import (
 	"bufio"
+	"bytes"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
 	"io"
 	"strings"
@@ -27,14 +29,33 @@ import (
 
 	"github.com/docker/docker/api/types"
 	"github.com/docker/docker/api/types/container"
+	"github.com/docker/docker/api/types/network"
 	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+	"github.com/docker/go-connections/nat"
 
 	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
+	"github.com/triggermesh/tmctl/pkg/urls"
 )
 
+// dockerDesktop is the operating system reported by the Docker Desktop engines.
+const dockerDesktop = "Docker Desktop"
+
+// ComponentLabel names the component the container runs.
+const ComponentLabel = "triggermesh.io/component"
+
 // time to wait for adapter init logs to show up.
 var initLogsWaitPeriod time.Duration = 2 * time.Second
 
+var (
+	// ErrNotFound is returned when the component container does not exist.
+	ErrNotFound = errors.New("container not found")
+	// ErrNotResponding is returned when the Docker daemon
+	// did not reply before the request context deadline.
+	ErrNotResponding = errors.New("docker not responding")
+)
+
 type imagePullEvent struct {
 	Status         string `json:"status"`
 	Error          string `json:"error"`
@@ -50,25 +71,78 @@ type Container struct {
 	Name   string
 	Image  string
 	Online bool
+	// Finished is the time the stopped container exited.
+	Finished time.Time
 
 	CreateContainerOptions []ContainerOption
 	CreateHostOptions      []HostOption
 
+	// Network is the network the container joins under the Aliases names.
+	Network string
+	Aliases []string
+
+	// Adopted containers are managed outside of tmctl,
+	// they are looked up without the ownership checks.
+	Adopted bool
+
+	// component is the name of the component if the container
+	// runs under the different name, see ContainerName.
+	component string
+
 	runtimeHostConfig      container.HostConfig
 	runtimeContainerConfig container.Config
 }
 
 func NewClient() (*client.Client, error) {
-	return client.NewClientWithOpts(client.FromEnv, client.WithAPIVersionNegotiation())
+	return client.NewClientWithOpts(client.WithHost(defaultHost), client.FromEnv, client.WithAPIVersionNegotiation())
+}
+
+type clientKey struct{}
+
+// WithClient returns the context carrying the Docker client
+// shared by the components lookups running in parallel.
+func WithClient(ctx context.Context, c *client.Client) context.Context {
+	return context.WithValue(ctx, clientKey{}, c)
+}
+
+// ClientFrom returns the Docker client of the context or the new one.
+func ClientFrom(ctx context.Context) (*client.Client, error) {
+	if c, ok := ctx.Value(clientKey{}).(*client.Client); ok && c != nil {
+		return c, nil
+	}
+	return NewClient()
+}
+
+// ImageDigest returns the repository digest of the local image,
+// or the image ID if the image has not been pulled from a registry.
+func ImageDigest(ctx context.Context, c *client.Client, image string) (string, error) {
+	inspect, _, err := c.ImageInspectWithRaw(ctx, image)
+	if err != nil {
+		return "", daemonError(ctx, err)
+	}
+	for _, digest := range inspect.RepoDigests {
+		if _, d, found := strings.Cut(digest, "@"); found {
+			return d, nil
+		}
+	}
+	return inspect.ID, nil
 }
 
 func CheckDaemon() error {
+	return CheckDaemonContext(context.Background())
+}
+
+// CheckDaemonContext verifies that the Docker engine responds
+// failing with ErrNotResponding when the context deadline expires.
+func CheckDaemonContext(ctx context.Context) error {
 	c, err := NewClient()
 	if err != nil {
 		return err
 	}
-	_, err = c.ServerVersion(context.Background())
-	return err
+	if _, err := c.ServerVersion(ctx); err != nil {
+		return daemonError(ctx, err)
+	}
+	return nil
 }
 
 func (c *Container) Logs(ctx context.Context, client *client.Client, since time.Time, follow bool) (io.ReadCloser, error) {
@@ -81,14 +155,25 @@ func (c *Container) Logs(ctx context.Context, client *client.Client, since time.
 }
 
 func (c *Container) Remove(ctx context.Context, client *client.Client) error {
-	id, err := nameToID(ctx, c.Name, client)
-	if err != nil {
-		return err
+	c.resolveName()
+	return forceStop(ctx, c.context(), c.Name, client)
+}
+
+// containerConfig returns the container configuration
+// labeled with the component and the context names.
+func (c *Container) containerConfig() container.Config {
+	cc := container.Config{}
+	for _, opt := range c.CreateContainerOptions {
+		opt(&cc)
 	}
-	return client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{
-		RemoveVolumes: true,
-		Force:         true,
-	})
+	if cc.Labels == nil {
+		cc.Labels = make(map[string]string, 2)
+	}
+	cc.Labels[ComponentLabel] = c.component
+	if context := c.context(); context != "" {
+		cc.Labels[ContextLabel] = context
+	}
+	return cc
 }
 
 func (c *Container) pullImage(ctx context.Context, client *client.Client) error {
@@ -120,16 +205,25 @@ func (c *Container) pullImage(ctx context.Context, client *client.Client) error
 }
 
 func (c *Container) Start(ctx context.Context, client *client.Client, restart bool) (*Container, error) {
-	cc := container.Config{}
-	for _, opt := range c.CreateContainerOptions {
-		opt(&cc)
+	// foreign containers must not be reused or removed
+	if err := c.claimName(ctx, client); err != nil {
+		return nil, err
 	}
 
+	cc := c.containerConfig()
+
 	hc := container.HostConfig{}
 	for _, opt := range c.CreateHostOptions {
 		opt(&hc)
 	}
 
+	if c.Network != "" {
+		if err := EnsureNetwork(ctx, c.Network, client); err != nil {
+			return nil, err
+		}
+		hc.NetworkMode = container.NetworkMode(c.Network)
+	}
+
 	if err := c.pullImage(ctx, client); err != nil {
 		return nil, fmt.Errorf("pulling image: %w", err)
 	}
@@ -140,8 +234,15 @@ func (c *Container) Start(ctx context.Context, client *client.Client, restart bo
 		if c.Image != existingContainer.Image {
 			restart = true
 		}
+		// containers created before the context network was introduced
+		if c.Network != "" && existingContainer.runtimeHostConfig.NetworkMode != hc.NetworkMode {
+			restart = true
+		}
 		if existingContainer.Online {
 			containerIsRunning = true
+		} else {
+			// output of the exited container is lost once it is recreated
+			existingContainer.CaptureExit(ctx, client)
 		}
 	}
 	if restart {
@@ -152,7 +253,7 @@ func (c *Container) Start(ctx context.Context, client *client.Client, restart bo
 		return existingContainer, nil
 	}
 
-	resp, err := client.ContainerCreate(ctx, &cc, &hc, nil, nil, c.Name)
+	resp, err := c.create(ctx, client, &cc, &hc, c.networkingConfig())
 	if err != nil {
 		return nil, fmt.Errorf("docker create: %w", err)
 	}
@@ -174,6 +275,7 @@ func (c *Container) Start(ctx context.Context, client *client.Client, restart bo
 		return nil, fmt.Errorf("config timeout value: %w", err)
 	}
 	if err := c.isRunning(ctx, client, timeout); err != nil {
+		c.CaptureExit(ctx, client)
 		return nil, fmt.Errorf("docker connect: %w", err)
 	}
 	time.Sleep(initLogsWaitPeriod)
@@ -188,52 +290,156 @@ func (c *Container) Start(ctx context.Context, client *client.Client, restart bo
 		if err := json.Unmarshal([]byte(log), &l); err != nil {
 			// unstructured log output, e.g. go's panic dump
 			if strings.Contains(log, "panic: ") {
+				c.CaptureExit(ctx, client)
 				return nil, fmt.Errorf("container log: %s", log)
 			}
 			continue
 		}
 		if isError(l) {
+			c.CaptureExit(ctx, client)
 			return nil, fmt.Errorf("container log: %s", log)
 		}
 	}
+	details := map[string]string{"image": c.Image}
+	if existingContainer != nil {
+		details["restarted"] = "true"
+	}
+	lifecycle.Emit(lifecycle.ContainerStarted, c.Name, "", details)
 	return c, nil
 }
 
-func nameToID(ctx context.Context, name string, client *client.Client) (string, error) {
-	containers, err := client.ContainerList(ctx, types.ContainerListOptions{
-		All: true,
-	})
-	if err != nil {
+// Run starts the container, waits for it to exit and removes it.
+// The combined container output is returned.
+func (c *Container) Run(ctx context.Context, client *client.Client) (string, error) {
+	if err := c.claimName(ctx, client); err != nil {
 		return "", err
 	}
-	for _, container := range containers {
-		for _, cName := range container.Names {
-			if cName == "/"+name {
-				return container.ID, nil
-			}
-		}
+	cc := c.containerConfig()
+	hc := container.HostConfig{}
+	for _, opt := range c.CreateHostOptions {
+		opt(&hc)
+	}
+	if err := c.pullImage(ctx, client); err != nil {
+		return "", fmt.Errorf("pulling image: %w", err)
+	}
+	// leftovers of the previous run
+	_ = c.Remove(ctx, client)
+
+	resp, err := c.create(ctx, client, &cc, &hc, nil)
+	if err != nil {
+		return "", fmt.Errorf("docker create: %w", err)
+	}
+	c.ID = resp.ID
+	defer func() {
+		_ = c.Remove(context.Background(), client)
+	}()
+
+	if err := client.ContainerStart(ctx, c.ID, types.ContainerStartOptions{}); err != nil {
+		return "", fmt.Errorf("docker start: %w", err)
+	}
+	var exitCode int64
+	statusCh, errCh := client.ContainerWait(ctx, c.ID, container.WaitConditionNotRunning)
+	select {
+	case err := <-errCh:
+		return "", fmt.Errorf("docker wait: %w", err)
+	case status := <-statusCh:
+		exitCode = status.StatusCode
+	}
+
+	logs, err := client.ContainerLogs(ctx, c.ID, types.ContainerLogsOptions{ShowStdout: true, ShowStderr: true})
+	if err != nil {
+		return "", fmt.Errorf("docker read logs: %w", err)
+	}
+	defer logs.Close()
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, logs); err != nil {
+		return "", fmt.Errorf("docker read logs: %w", err)
 	}
-	return "", nil
+	if exitCode != 0 {
+		return output.String(), fmt.Errorf("container exited with code %d: %s", exitCode, strings.TrimSpace(output.String()))
+	}
+	return output.String(), nil
 }
 
 func (c *Container) LookupHostConfig(ctx context.Context, client *client.Client) (*Container, error) {
-	id, err := nameToID(ctx, c.Name, client)
+	c.resolveName()
+	existing, err := lookup(ctx, c.Name, client)
 	if err != nil {
-		return nil, err
+		return nil, daemonError(ctx, err)
+	}
+	if existing == nil {
+		return nil, fmt.Errorf("%q: %w", c.Name, ErrNotFound)
+	}
+	if !c.Adopted {
+		if err := checkOwner(c.Name, c.context(), existing); err != nil {
+			return nil, err
+		}
 	}
+	id := existing.ID
 	jsn, err := client.ContainerInspect(ctx, id)
 	if err != nil {
-		return nil, err
+		return nil, daemonError(ctx, err)
 	}
 	c.ID = id
 	if jsn.State.Running {
 		c.Online = true
+	} else if finished, err := time.Parse(time.RFC3339Nano, jsn.State.FinishedAt); err == nil {
+		c.Finished = finished
 	}
 	c.runtimeHostConfig = *jsn.HostConfig
 	c.runtimeContainerConfig = *jsn.Config
 	return c, nil
 }
 
+// create creates the container. Engines older than Docker 20.10 reject
+// the host-gateway mapping, it is dropped if the engine resolves
+// DockerHost by itself as Docker Desktop does.
+func (c *Container) create(ctx context.Context, client *client.Client, cc *container.Config, hc *container.HostConfig, nc *network.NetworkingConfig) (container.CreateResponse, error) {
+	resp, err := client.ContainerCreate(ctx, cc, hc, nc, nil, c.Name)
+	if err == nil || !rejectsHostGateway(err, hc) {
+		return resp, err
+	}
+	info, infoErr := client.Info(ctx)
+	if infoErr != nil {
+		return resp, daemonError(ctx, infoErr)
+	}
+	if !strings.HasPrefix(info.OperatingSystem, dockerDesktop) {
+		return resp, fmt.Errorf("%s does not resolve in the containers, the engine does not support the host-gateway mapping: %w", urls.DockerHost, err)
+	}
+	var hosts []string
+	for _, host := range hc.ExtraHosts {
+		if host != urls.HostGateway {
+			hosts = append(hosts, host)
+		}
+	}
+	hc.ExtraHosts = hosts
+	return client.ContainerCreate(ctx, cc, hc, nc, nil, c.Name)
+}
+
+// rejectsHostGateway returns true if the create request
+// failed because the engine does not know the host-gateway value.
+func rejectsHostGateway(err error, hc *container.HostConfig) bool {
+	for _, host := range hc.ExtraHosts {
+		if host == urls.HostGateway {
+			return strings.Contains(err.Error(), "host-gateway")
+		}
+	}
+	return false
+}
+
+// daemonError marks the request errors caused by the expired context deadline.
+func daemonError(ctx context.Context, err error) error {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) || errors.Is(err, context.DeadlineExceeded) {
+		return fmt.Errorf("%w: %v", ErrNotResponding, err)
+	}
+	return err
+}
+
+// RuntimeImage returns the image of the existing container.
+func (c *Container) RuntimeImage() string {
+	return c.runtimeContainerConfig.Image
+}
+
 func (c *Container) HostPort() string {
 	for _, bindings := range c.runtimeHostConfig.PortBindings {
 		for _, binding := range bindings {
@@ -243,6 +449,14 @@ func (c *Container) HostPort() string {
 	return ""
 }
 
+// PublishedPort returns the host port bound to the container port.
+func (c *Container) PublishedPort(containerPort nat.Port) string {
+	for _, binding := range c.runtimeHostConfig.PortBindings[containerPort] {
+		return binding.HostPort
+	}
+	return ""
+}
+
 func (c *Container) isRunning(ctx context.Context, client *client.Client, timeout time.Duration) error {
 	ticker := time.NewTicker(500 * time.Millisecond)
 	defer ticker.Stop()
@@ -263,15 +477,33 @@ func (c *Container) isRunning(ctx context.Context, client *client.Client, timeou
 	}
 }
 
+// ForceStop removes the container of the component of the current context.
+// Foreign containers and the containers of other contexts are not removed.
 func ForceStop(ctx context.Context, name string, client *client.Client) error {
-	id, err := nameToID(ctx, name, client)
+	context := names.context()
+	return forceStop(ctx, context, ContainerName(context, name), client)
+}
+
+func forceStop(ctx context.Context, context, name string, client *client.Client) error {
+	existing, err := lookup(ctx, name, client)
 	if err != nil {
 		return err
 	}
-	return client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{
+	if err := checkOwner(name, context, existing); err != nil {
+		return err
+	}
+	var id string
+	if existing != nil {
+		id = existing.ID
+	}
+	if err := client.ContainerRemove(ctx, id, types.ContainerRemoveOptions{
 		RemoveVolumes: true,
 		Force:         true,
-	})
+	}); err != nil {
+		return err
+	}
+	lifecycle.Emit(lifecycle.ContainerStopped, name, "", nil)
+	return nil
 }
 
 func readLogs(logs io.ReadCloser) []string {
@@ -0,0 +1,264 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+func TestDaemonNotResponding(t *testing.T) {
+	wedged := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer wedged.Close()
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(wedged.URL, "http://"))
+
+	ctx, cancel := context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	err := CheckDaemonContext(ctx)
+	assert.ErrorIs(t, err, ErrNotResponding)
+
+	c, err := NewClient()
+	assert.NoError(t, err)
+	ctx, cancel = context.WithTimeout(context.Background(), 100*time.Millisecond)
+	defer cancel()
+	_, err = (&Container{Name: "foo"}).LookupHostConfig(ctx, c)
+	assert.ErrorIs(t, err, ErrNotResponding)
+}
+
+func TestContainerNotFound(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if strings.HasSuffix(r.URL.Path, "/containers/json") {
+			w.Header().Set("Content-Type", "application/json")
+			fmt.Fprint(w, `[{"Id":"123","Names":["/bar"]}]`)
+			return
+		}
+		w.WriteHeader(http.StatusInternalServerError)
+	}))
+	defer daemon.Close()
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+
+	c, err := NewClient()
+	assert.NoError(t, err)
+	_, err = (&Container{Name: "foo"}).LookupHostConfig(context.Background(), c)
+	assert.ErrorIs(t, err, ErrNotFound)
+	assert.False(t, errors.Is(err, ErrNotResponding))
+}
+
+func TestImageDigest(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/images/gcr.io/foo/bar:v1/json"):
+			fmt.Fprint(w, `{"Id":"sha256:111","RepoDigests":["gcr.io/foo/bar@sha256:222"]}`)
+		case strings.HasSuffix(r.URL.Path, "/images/local/json"):
+			fmt.Fprint(w, `{"Id":"sha256:333"}`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+			fmt.Fprint(w, `{"message":"no such image"}`)
+		}
+	}))
+	defer daemon.Close()
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+
+	c, err := NewClient()
+	assert.NoError(t, err)
+	ctx := WithClient(context.Background(), c)
+	shared, err := ClientFrom(ctx)
+	assert.NoError(t, err)
+	assert.Same(t, c, shared)
+
+	digest, err := ImageDigest(ctx, shared, "gcr.io/foo/bar:v1")
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:222", digest)
+
+	digest, err = ImageDigest(ctx, shared, "local")
+	assert.NoError(t, err)
+	assert.Equal(t, "sha256:333", digest)
+
+	_, err = ImageDigest(ctx, shared, "missing")
+	assert.Error(t, err)
+}
+
+func TestCleanupLookups(t *testing.T) {
+	anonymous := strings.Repeat("a", 64)
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			fmt.Fprint(w, `[{"Id":"1","Names":["/foo-broker"]},{"Id":"2","Names":["/sockeye-replica-3"]},{"Id":"3","Names":["/other"]}]`)
+		case strings.HasSuffix(r.URL.Path, "/containers/1/json"):
+			fmt.Fprintf(w, `{"Id":"1","Mounts":[{"Type":"volume","Name":"redis-data"},{"Type":"volume","Name":%q},{"Type":"bind","Source":"/tmp"}]}`, anonymous)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer daemon.Close()
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+
+	c, err := NewClient()
+	assert.NoError(t, err)
+	names, err := ContainerNames(context.Background(), c, func(name string) bool {
+		return name == "foo-broker" || strings.HasPrefix(name, "sockeye-replica-")
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"foo-broker", "sockeye-replica-3"}, names)
+
+	volumes, err := NamedVolumes(context.Background(), "foo-broker", c)
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"redis-data"}, volumes)
+
+	volumes, err = NamedVolumes(context.Background(), "missing", c)
+	assert.NoError(t, err)
+	assert.Empty(t, volumes)
+}
+
+func TestLabeledContainers(t *testing.T) {
+	c, _ := fakeDaemon(t,
+		`{"Id":"1","Names":["/foo-broker"],"State":"running","ImageID":"broker","Labels":{"triggermesh.io/component":"foo-broker","triggermesh.io/context":"foo"}}`,
+		`{"Id":"2","Names":["/sockeye-foo"],"State":"exited","ImageID":"sockeye","SizeRw":42,"Labels":{"triggermesh.io/component":"sockeye"},"HostConfig":{"NetworkMode":"tmctl-foo"}}`,
+		`{"Id":"3","Names":["/bar-broker"],"State":"exited","Labels":{"triggermesh.io/component":"bar-broker","triggermesh.io/context":"bar"}}`,
+		`{"Id":"4","Names":["/legacy"],"State":"exited","Labels":{"triggermesh.io/component":"legacy"}}`,
+		// foreign containers are never listed, even in the context network
+		`{"Id":"5","Names":["/redis"],"State":"exited","ImageID":"sockeye","HostConfig":{"NetworkMode":"tmctl-foo"}}`,
+	)
+	containers, err := LabeledContainers(context.Background(), c, func(context string) bool {
+		return context == "foo"
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, []Labeled{
+		{ID: "1", Name: "foo-broker", Component: "foo-broker", Context: "foo", State: "running", ImageID: "broker"},
+		{ID: "2", Name: "sockeye-foo", Component: "sockeye", Context: "foo", State: "exited", ImageID: "sockeye", Size: 42},
+	}, containers)
+
+	containers, err = LabeledContainers(context.Background(), c, func(string) bool { return true })
+	assert.NoError(t, err)
+	assert.Len(t, containers, 4)
+
+	used, err := UsedImages(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, 2, used["sockeye"])
+}
+
+func TestImages(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		if strings.HasSuffix(r.URL.Path, "/images/json") {
+			fmt.Fprint(w, `[{"Id":"1","RepoTags":["gcr.io/triggermesh/webhooksource-adapter:v1.25.0"],"Size":100},`+
+				`{"Id":"2","RepoTags":["docker.io/library/redis:7"],"Size":200},`+
+				`{"Id":"3","RepoTags":null,"Size":300}]`)
+			return
+		}
+		w.WriteHeader(http.StatusNotFound)
+	}))
+	defer daemon.Close()
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+
+	c, err := NewClient()
+	assert.NoError(t, err)
+	images, err := Images(context.Background(), c, "gcr.io/triggermesh/")
+	assert.NoError(t, err)
+	assert.Equal(t, []Image{{ID: "1", Tags: []string{"gcr.io/triggermesh/webhooksource-adapter:v1.25.0"}, Size: 100}}, images)
+}
+
+func TestCheckDaemonOldEngine(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		fmt.Fprint(w, `{"Version":"19.03.15","ApiVersion":"1.40"}`)
+	}))
+	defer daemon.Close()
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+
+	assert.NoError(t, CheckDaemonContext(context.Background()))
+}
+
+func TestCreateWithoutHostGateway(t *testing.T) {
+	testCases := map[string]struct {
+		operatingSystem string
+		err             string
+	}{
+		"docker desktop resolves the host": {
+			operatingSystem: "Docker Desktop",
+		},
+		"linux engine": {
+			operatingSystem: "Ubuntu 18.04.6 LTS",
+			err:             `host.docker.internal does not resolve in the containers, the engine does not support the host-gateway mapping: Error response from daemon: invalid IP address in add-host: "host-gateway"`,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var created [][]string
+			daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+				w.Header().Set("Content-Type", "application/json")
+				switch {
+				case strings.HasSuffix(r.URL.Path, "/containers/create"):
+					var body struct {
+						HostConfig container.HostConfig
+					}
+					if err := json.NewDecoder(r.Body).Decode(&body); err != nil {
+						w.WriteHeader(http.StatusBadRequest)
+						return
+					}
+					created = append(created, body.HostConfig.ExtraHosts)
+					for _, host := range body.HostConfig.ExtraHosts {
+						if strings.HasSuffix(host, ":host-gateway") {
+							w.WriteHeader(http.StatusBadRequest)
+							fmt.Fprint(w, `{"message":"invalid IP address in add-host: \"host-gateway\""}`)
+							return
+						}
+					}
+					fmt.Fprint(w, `{"Id":"123"}`)
+				case strings.HasSuffix(r.URL.Path, "/info"):
+					fmt.Fprintf(w, `{"OperatingSystem":%q}`, tc.operatingSystem)
+				default:
+					w.WriteHeader(http.StatusNotFound)
+				}
+			}))
+			defer daemon.Close()
+			t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+
+			c, err := NewClient()
+			assert.NoError(t, err)
+			cc := container.Config{}
+			hc := container.HostConfig{}
+			WithExtraHost()(&hc)
+			resp, err := (&Container{Name: "foo"}).create(context.Background(), c, &cc, &hc, nil)
+			if tc.err != "" {
+				assert.EqualError(t, err, tc.err)
+				assert.Len(t, created, 1)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, "123", resp.ID)
+			assert.Equal(t, [][]string{{urls.HostGateway}, nil}, created)
+			assert.Empty(t, hc.ExtraHosts)
+		})
+	}
+}
@@ -23,6 +23,7 @@ import (
 	"github.com/docker/go-connections/nat"
 
 	"github.com/triggermesh/tmctl/pkg/triggermesh/pkg"
+	"github.com/triggermesh/tmctl/pkg/urls"
 )
 
 const errorLoggingLevel = `K_LOGGING_CONFIG={"zap-logger-config":"{\"level\": \"error\"}"}`
@@ -56,19 +57,37 @@ func WithEntrypoint(entrypoint []string) ContainerOption {
 	}
 }
 
+func WithCmd(cmd []string) ContainerOption {
+	return func(cc *container.Config) {
+		cc.Cmd = cmd
+	}
+}
+
+// WithUser runs the container process as the "uid:gid" user.
+func WithUser(user string) ContainerOption {
+	return func(cc *container.Config) {
+		cc.User = user
+	}
+}
+
 func WithVolumeBind(bind string) HostOption {
 	return func(hc *container.HostConfig) {
-		hc.Binds = []string{bind}
+		hc.Binds = append(hc.Binds, bind)
 	}
 }
 
 func WithHostPortBinding(containerPort nat.Port) HostOption {
 	return func(hc *container.HostConfig) {
+		// empty host port lets docker pick the free one
+		var hostPort string
+		if port, err := pkg.OpenPort(); err == nil {
+			hostPort = strconv.Itoa(port)
+		}
 		hc.PortBindings = nat.PortMap{
 			containerPort: []nat.PortBinding{
 				{
 					HostIP:   "0.0.0.0",
-					HostPort: strconv.Itoa(pkg.OpenPort()),
+					HostPort: hostPort,
 				},
 			},
 		}
@@ -77,7 +96,7 @@ func WithHostPortBinding(containerPort nat.Port) HostOption {
 
 func WithExtraHost() HostOption {
 	return func(hc *container.HostConfig) {
-		hc.ExtraHosts = []string{"host.docker.internal:host-gateway"}
+		hc.ExtraHosts = []string{urls.HostGateway}
 	}
 }
 
@@ -58,11 +58,20 @@ func TestWithEntrypoint(t *testing.T) {
 	assert.Equal(t, strslice.StrSlice(entrypoint), cc.Entrypoint)
 }
 
+func TestWithCmd(t *testing.T) {
+	cmd := []string{"-b", "localhost:9092"}
+	cc := &container.Config{}
+	WithCmd(cmd)(cc)
+	assert.Equal(t, strslice.StrSlice(cmd), cc.Cmd)
+}
+
 func TestWithVolumeBind(t *testing.T) {
 	bind := "foo:bar"
 	hc := &container.HostConfig{}
 	WithVolumeBind(bind)(hc)
 	assert.Equal(t, []string{bind}, hc.Binds)
+	WithVolumeBind("baz:qux:ro")(hc)
+	assert.Equal(t, []string{bind, "baz:qux:ro"}, hc.Binds)
 }
 
 func TestWithHostPortBinding(t *testing.T) {
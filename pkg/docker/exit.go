@@ -0,0 +1,69 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"time"
+
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/pkg/stdcopy"
+)
+
+// ExitHandler receives the output of the exited or failed container.
+type ExitHandler func(container string, exited time.Time, output []byte)
+
+var (
+	exitMu      sync.Mutex
+	exitHandler ExitHandler
+)
+
+// OnExit sets the handler of the exited containers output,
+// nil handler disables the capture.
+func OnExit(handler ExitHandler) {
+	exitMu.Lock()
+	defer exitMu.Unlock()
+	exitHandler = handler
+}
+
+// CaptureExit passes the output of the container to the exit handler
+// before the container is recreated or after it failed to start.
+// The capture is best effort and does not affect the caller.
+func (c *Container) CaptureExit(ctx context.Context, client *client.Client) {
+	exitMu.Lock()
+	handler := exitHandler
+	exitMu.Unlock()
+	if handler == nil || c.ID == "" {
+		return
+	}
+	logs, err := c.Logs(ctx, client, time.Unix(0, 0), false)
+	if err != nil {
+		return
+	}
+	defer logs.Close()
+	var output bytes.Buffer
+	if _, err := stdcopy.StdCopy(&output, &output, logs); err != nil && output.Len() == 0 {
+		return
+	}
+	exited := c.Finished
+	if exited.IsZero() {
+		exited = time.Now()
+	}
+	handler(c.Name, exited, output.Bytes())
+}
@@ -0,0 +1,32 @@
+//go:build !windows
+
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestNewClientDefaultHost(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "")
+	c, err := NewClient()
+	assert.NoError(t, err)
+	assert.Equal(t, "unix:///var/run/docker.sock", c.DaemonHost())
+}
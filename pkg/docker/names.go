@@ -0,0 +1,257 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+	"sync"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"gopkg.in/yaml.v3"
+)
+
+const (
+	// ContextLabel names the context of the container.
+	ContextLabel = "triggermesh.io/context"
+	// NamesFile is the file of the context directory mapping the components
+	// to their containers named differently because of the name conflicts.
+	NamesFile = "containers.yaml"
+
+	// NameConflictFail makes the component start fail
+	// if its container name is taken by the foreign container.
+	NameConflictFail = "fail"
+	// NameConflictSuffix makes the component run in the container
+	// with the context name suffix if its name is taken.
+	NameConflictSuffix = "suffix"
+)
+
+// NameConflictPolicies are the accepted name conflict policies.
+var NameConflictPolicies = []string{NameConflictFail, NameConflictSuffix}
+
+// NameConflictError is returned when the container named after the component
+// was not created by tmctl or belongs to another context. Such containers are
+// never reused or removed.
+type NameConflictError struct {
+	Container string
+	// Context is the context of the container created by tmctl,
+	// empty if the container is foreign, e.g. docker-compose service.
+	Context string
+}
+
+func (e *NameConflictError) Error() string {
+	owner := "was not created by tmctl"
+	if e.Context != "" {
+		owner = fmt.Sprintf("belongs to the context %q", e.Context)
+	}
+	return fmt.Sprintf("container %q %s: remove or rename it, or use \"--on-name-conflict %s\" to run the component in the container with another name",
+		e.Container, owner, NameConflictSuffix)
+}
+
+type nameRegistry struct {
+	sync.Mutex
+	home    string
+	current func() string
+	policy  string
+}
+
+var names = nameRegistry{policy: NameConflictFail}
+
+// ManageNames sets the directory of the contexts, where the container names
+// differing from the component names are recorded, the function returning
+// the current context and the policy of the container name conflicts.
+func ManageNames(home string, current func() string, policy string) error {
+	valid := false
+	for _, p := range NameConflictPolicies {
+		valid = valid || p == policy
+	}
+	if !valid {
+		return fmt.Errorf("unknown name conflict policy %q, expected one of: %s", policy, strings.Join(NameConflictPolicies, ", "))
+	}
+	names.Lock()
+	defer names.Unlock()
+	names.home, names.current, names.policy = home, current, policy
+	return nil
+}
+
+// ContainerName returns the name of the container running the component of the context.
+func ContainerName(context, component string) string {
+	names.Lock()
+	defer names.Unlock()
+	if container, set := names.read(context)[component]; set {
+		return container
+	}
+	return component
+}
+
+func (r *nameRegistry) context() string {
+	if r.current == nil {
+		return ""
+	}
+	return r.current()
+}
+
+func (r *nameRegistry) file(context string) string {
+	if r.home == "" || context == "" {
+		return ""
+	}
+	return filepath.Join(r.home, context, NamesFile)
+}
+
+func (r *nameRegistry) read(context string) map[string]string {
+	result := make(map[string]string)
+	file := r.file(context)
+	if file == "" {
+		return result
+	}
+	data, err := os.ReadFile(file)
+	if err != nil {
+		return result
+	}
+	_ = yaml.Unmarshal(data, &result)
+	return result
+}
+
+func (r *nameRegistry) record(context, component, container string) error {
+	r.Lock()
+	defer r.Unlock()
+	file := r.file(context)
+	if file == "" {
+		return fmt.Errorf("container names directory is not set")
+	}
+	mapping := r.read(context)
+	mapping[component] = container
+	data, err := yaml.Marshal(mapping)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(file), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(file, data, 0o600)
+}
+
+// containerContext returns the context of the existing container and whether
+// it was created by tmctl. Containers created before the context label was
+// introduced are recognized by the component label or the context network.
+func containerContext(container *types.Container) (string, bool) {
+	if context, set := container.Labels[ContextLabel]; set {
+		return context, true
+	}
+	if strings.HasPrefix(container.HostConfig.NetworkMode, NetworkName("")) {
+		return strings.TrimPrefix(container.HostConfig.NetworkMode, NetworkName("")), true
+	}
+	_, set := container.Labels[ComponentLabel]
+	return "", set
+}
+
+// checkOwner returns NameConflictError if the existing container
+// is not the tmctl container of the context.
+func checkOwner(name, context string, container *types.Container) error {
+	if container == nil {
+		return nil
+	}
+	owner, tmctl := containerContext(container)
+	if !tmctl {
+		return &NameConflictError{Container: name}
+	}
+	if owner != "" && context != "" && owner != context {
+		return &NameConflictError{Container: name, Context: owner}
+	}
+	return nil
+}
+
+// lookup returns the container with the name, nil if it does not exist.
+func lookup(ctx context.Context, name string, client *client.Client) (*types.Container, error) {
+	containers, err := client.ContainerList(ctx, types.ContainerListOptions{
+		All: true,
+	})
+	if err != nil {
+		return nil, err
+	}
+	for i, container := range containers {
+		for _, cName := range container.Names {
+			if cName == "/"+name {
+				return &containers[i], nil
+			}
+		}
+	}
+	return nil, nil
+}
+
+// context returns the context of the container network, or the current context.
+func (c *Container) context() string {
+	if strings.HasPrefix(c.Network, NetworkName("")) {
+		return strings.TrimPrefix(c.Network, NetworkName(""))
+	}
+	return names.context()
+}
+
+// resolveName switches the container to the name recorded for its component.
+func (c *Container) resolveName() {
+	if c.component != "" || c.Adopted {
+		return
+	}
+	c.component = c.Name
+	c.Name = ContainerName(c.context(), c.Name)
+}
+
+// claimName makes sure that the container name is not taken by the foreign
+// container or the container of another context. Depending on the policy,
+// the conflict is either returned or the component container is renamed.
+func (c *Container) claimName(ctx context.Context, client *client.Client) error {
+	c.resolveName()
+	context := c.context()
+	existing, err := lookup(ctx, c.Name, client)
+	if err != nil {
+		return daemonError(ctx, err)
+	}
+	err = checkOwner(c.Name, context, existing)
+	var conflict *NameConflictError
+	if !errors.As(err, &conflict) || names.policy != NameConflictSuffix {
+		return err
+	}
+	base := c.component
+	if context != "" {
+		base += "-" + context
+	}
+	for i := 0; ; i++ {
+		candidate := base
+		if i > 0 || context == "" {
+			candidate += "-" + strconv.Itoa(i+2)
+		}
+		existing, err := lookup(ctx, candidate, client)
+		if err != nil {
+			return daemonError(ctx, err)
+		}
+		if existing != nil && (checkOwner(candidate, context, existing) != nil || existing.Labels[ComponentLabel] != c.component) {
+			continue
+		}
+		if err := names.record(context, c.component, candidate); err != nil {
+			return fmt.Errorf("recording container name of %q: %w", c.component, err)
+		}
+		fmt.Printf("Container name %q is taken, %q runs in the container %q\n", c.Name, c.component, candidate)
+		c.Name = candidate
+		return nil
+	}
+}
@@ -0,0 +1,222 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/client"
+	"github.com/stretchr/testify/assert"
+)
+
+const (
+	// docker-compose service of the user project
+	composeContainer = `{"Id":"1","Names":["/foo-broker"],"State":"running","Labels":{"com.docker.compose.project":"shop"},"HostConfig":{"NetworkMode":"shop_default"}}`
+	// container of the "bar" context
+	otherContextContainer = `{"Id":"2","Names":["/sockeye"],"State":"running","Labels":{"triggermesh.io/component":"sockeye","triggermesh.io/context":"bar"},"HostConfig":{"NetworkMode":"tmctl-bar"}}`
+	// container of the "foo" context running under the suffixed name
+	suffixedContainer = `{"Id":"3","Names":["/foo-broker-foo"],"State":"exited","Labels":{"triggermesh.io/component":"foo-broker","triggermesh.io/context":"foo"},"HostConfig":{"NetworkMode":"tmctl-foo"}}`
+)
+
+// fakeDaemon serves the containers list and records the removed containers.
+func fakeDaemon(t *testing.T, containers ...string) (*client.Client, *[]string) {
+	var removed []string
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/containers/json"):
+			fmt.Fprintf(w, "[%s]", strings.Join(containers, ","))
+		case r.Method == http.MethodDelete:
+			removed = append(removed, r.URL.Path[strings.LastIndex(r.URL.Path, "/")+1:])
+			w.WriteHeader(http.StatusNoContent)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	t.Cleanup(daemon.Close)
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+	c, err := NewClient()
+	assert.NoError(t, err)
+	return c, &removed
+}
+
+// manageNames sets the names registry of the "foo" context for the test.
+func manageNames(t *testing.T, policy string) string {
+	home := t.TempDir()
+	assert.NoError(t, ManageNames(home, func() string { return "foo" }, policy))
+	t.Cleanup(func() {
+		names = nameRegistry{policy: NameConflictFail}
+	})
+	return home
+}
+
+func TestCheckOwner(t *testing.T) {
+	testCases := map[string]struct {
+		container *types.Container
+		conflict  bool
+		owner     string
+	}{
+		"missing": {},
+		"owned": {
+			container: &types.Container{Labels: map[string]string{ComponentLabel: "foo-broker", ContextLabel: "foo"}},
+		},
+		"unlabeled context network": {
+			container: &types.Container{HostConfig: struct {
+				NetworkMode string `json:",omitempty"`
+			}{NetworkMode: "tmctl-foo"}},
+		},
+		"component label only": {
+			container: &types.Container{Labels: map[string]string{ComponentLabel: "foo-broker"}},
+		},
+		"foreign": {
+			container: &types.Container{Labels: map[string]string{"com.docker.compose.project": "shop"}},
+			conflict:  true,
+		},
+		"other context": {
+			container: &types.Container{Labels: map[string]string{ComponentLabel: "foo-broker", ContextLabel: "bar"}},
+			conflict:  true,
+			owner:     "bar",
+		},
+		"other context network": {
+			container: &types.Container{HostConfig: struct {
+				NetworkMode string `json:",omitempty"`
+			}{NetworkMode: "tmctl-bar"}},
+			conflict: true,
+			owner:    "bar",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := checkOwner("foo-broker", "foo", tc.container)
+			if !tc.conflict {
+				assert.NoError(t, err)
+				return
+			}
+			var conflict *NameConflictError
+			assert.True(t, errors.As(err, &conflict))
+			assert.Equal(t, "foo-broker", conflict.Container)
+			assert.Equal(t, tc.owner, conflict.Context)
+		})
+	}
+}
+
+func TestManageNamesPolicy(t *testing.T) {
+	manageNames(t, NameConflictFail)
+	assert.Error(t, ManageNames("", nil, "rename"))
+}
+
+func TestForeignContainerConflict(t *testing.T) {
+	manageNames(t, NameConflictFail)
+	c, removed := fakeDaemon(t, composeContainer)
+
+	container := &Container{Name: "foo-broker", Network: NetworkName("foo")}
+	err := container.claimName(context.Background(), c)
+	var conflict *NameConflictError
+	assert.True(t, errors.As(err, &conflict))
+	assert.Empty(t, conflict.Context)
+	assert.Contains(t, err.Error(), "not created by tmctl")
+	assert.Contains(t, err.Error(), "--on-name-conflict suffix")
+
+	_, err = container.Start(context.Background(), c, true)
+	assert.True(t, errors.As(err, &conflict))
+	_, err = (&Container{Name: "foo-broker"}).LookupHostConfig(context.Background(), c)
+	assert.True(t, errors.As(err, &conflict))
+	assert.True(t, errors.As(ForceStop(context.Background(), "foo-broker", c), &conflict))
+	assert.True(t, errors.As(container.Remove(context.Background(), c), &conflict))
+	assert.Empty(t, *removed)
+}
+
+func TestOtherContextConflict(t *testing.T) {
+	manageNames(t, NameConflictFail)
+	c, removed := fakeDaemon(t, otherContextContainer)
+
+	container := &Container{Name: "sockeye", Network: NetworkName("foo")}
+	_, err := container.Start(context.Background(), c, true)
+	var conflict *NameConflictError
+	assert.True(t, errors.As(err, &conflict))
+	assert.Equal(t, "bar", conflict.Context)
+	assert.Contains(t, err.Error(), `belongs to the context "bar"`)
+
+	assert.True(t, errors.As(ForceStop(context.Background(), "sockeye", c), &conflict))
+	assert.Empty(t, *removed)
+
+	// the container is removed within its own context
+	assert.NoError(t, ManageNames(names.home, func() string { return "bar" }, NameConflictFail))
+	assert.NoError(t, ForceStop(context.Background(), "sockeye", c))
+	assert.Equal(t, []string{"2"}, *removed)
+}
+
+func TestSuffixOnNameConflict(t *testing.T) {
+	home := manageNames(t, NameConflictSuffix)
+	c, removed := fakeDaemon(t, composeContainer, otherContextContainer)
+
+	container := &Container{Name: "foo-broker", Network: NetworkName("foo")}
+	assert.NoError(t, container.claimName(context.Background(), c))
+	assert.Equal(t, "foo-broker-foo", container.Name)
+	cc := container.containerConfig()
+	assert.Equal(t, "foo-broker", cc.Labels[ComponentLabel])
+	assert.Equal(t, "foo", cc.Labels[ContextLabel])
+
+	// the mapping is persisted in the context directory
+	assert.Equal(t, "foo-broker-foo", ContainerName("foo", "foo-broker"))
+	assert.Equal(t, "foo-broker", ContainerName("bar", "foo-broker"))
+	assert.FileExists(t, filepath.Join(home, "foo", NamesFile))
+
+	other := &Container{Name: "sockeye", Network: NetworkName("foo")}
+	assert.NoError(t, other.claimName(context.Background(), c))
+	assert.Equal(t, "sockeye-foo", other.Name)
+	assert.Empty(t, *removed)
+}
+
+func TestSuffixedContainerLookups(t *testing.T) {
+	home := manageNames(t, NameConflictSuffix)
+	assert.NoError(t, os.MkdirAll(filepath.Join(home, "foo"), os.ModePerm))
+	assert.NoError(t, os.WriteFile(filepath.Join(home, "foo", NamesFile), []byte("foo-broker: foo-broker-foo\n"), 0o600))
+	c, removed := fakeDaemon(t, composeContainer, suffixedContainer)
+
+	// the existing suffixed container is reused
+	container := &Container{Name: "foo-broker", Network: NetworkName("foo")}
+	assert.NoError(t, container.claimName(context.Background(), c))
+	assert.Equal(t, "foo-broker-foo", container.Name)
+
+	states, err := ContainerStates(context.Background(), c)
+	assert.NoError(t, err)
+	assert.Equal(t, "exited", states["foo-broker"])
+	assert.Equal(t, "exited", states["foo-broker-foo"])
+	assert.NoError(t, ForceStop(context.Background(), "foo-broker", c))
+	assert.Equal(t, []string{"3"}, *removed)
+}
+
+func TestAdoptedContainerLookup(t *testing.T) {
+	manageNames(t, NameConflictFail)
+	c, _ := fakeDaemon(t, composeContainer)
+
+	_, err := (&Container{Name: "foo-broker", Adopted: true}).LookupHostConfig(context.Background(), c)
+	// inspect is not served by the fake daemon
+	var conflict *NameConflictError
+	assert.Error(t, err)
+	assert.False(t, errors.As(err, &conflict))
+}
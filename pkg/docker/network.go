@@ -0,0 +1,85 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/docker/docker/api/types"
+	"github.com/docker/docker/api/types/filters"
+	"github.com/docker/docker/api/types/network"
+	"github.com/docker/docker/client"
+	"github.com/docker/docker/errdefs"
+)
+
+const networkLabel = "triggermesh.io/context"
+
+// NetworkName returns the name of the Docker network
+// shared by the containers of the context.
+func NetworkName(context string) string {
+	return "tmctl-" + context
+}
+
+// EnsureNetwork creates the bridge network of the context if it does not exist.
+func EnsureNetwork(ctx context.Context, name string, client *client.Client) error {
+	networks, err := client.NetworkList(ctx, types.NetworkListOptions{
+		Filters: filters.NewArgs(filters.Arg("name", name)),
+	})
+	if err != nil {
+		return daemonError(ctx, err)
+	}
+	// name filter matches the substrings
+	for _, n := range networks {
+		if n.Name == name {
+			return nil
+		}
+	}
+	if _, err := client.NetworkCreate(ctx, name, types.NetworkCreate{
+		CheckDuplicate: true,
+		Driver:         "bridge",
+		Labels:         map[string]string{networkLabel: name},
+	}); err != nil {
+		return fmt.Errorf("creating network %q: %w", name, daemonError(ctx, err))
+	}
+	return nil
+}
+
+// RemoveNetwork deletes the network of the context. Missing network is not an error.
+func RemoveNetwork(ctx context.Context, name string, client *client.Client) error {
+	if err := client.NetworkRemove(ctx, name); err != nil && !errdefs.IsNotFound(err) {
+		return err
+	}
+	return nil
+}
+
+// Joined returns true if the existing container is attached to the network.
+func (c *Container) Joined(network string) bool {
+	return string(c.runtimeHostConfig.NetworkMode) == network
+}
+
+// networkingConfig returns the endpoint of the container in its network.
+func (c *Container) networkingConfig() *network.NetworkingConfig {
+	if c.Network == "" {
+		return nil
+	}
+	return &network.NetworkingConfig{
+		EndpointsConfig: map[string]*network.EndpointSettings{
+			c.Network: {Aliases: c.Aliases},
+		},
+	}
+}
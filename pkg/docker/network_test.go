@@ -0,0 +1,77 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package docker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestEnsureNetwork(t *testing.T) {
+	var created []string
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		switch {
+		case strings.HasSuffix(r.URL.Path, "/networks/create"):
+			var request struct{ Name string }
+			_ = json.NewDecoder(r.Body).Decode(&request)
+			created = append(created, request.Name)
+			w.WriteHeader(http.StatusCreated)
+			fmt.Fprint(w, `{"Id":"123"}`)
+		case strings.HasSuffix(r.URL.Path, "/networks"):
+			// name filter matches the substrings
+			fmt.Fprint(w, `[{"Id":"456","Name":"tmctl-foo-bar"},{"Id":"789","Name":"tmctl-baz"}]`)
+		default:
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer daemon.Close()
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+
+	c, err := NewClient()
+	assert.NoError(t, err)
+	assert.NoError(t, EnsureNetwork(context.Background(), NetworkName("foo"), c))
+	assert.NoError(t, EnsureNetwork(context.Background(), NetworkName("baz"), c))
+	assert.Equal(t, []string{"tmctl-foo"}, created)
+}
+
+func TestRemoveMissingNetwork(t *testing.T) {
+	daemon := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.Header().Set("Content-Type", "application/json")
+		w.WriteHeader(http.StatusNotFound)
+		fmt.Fprint(w, `{"message":"network tmctl-foo not found"}`)
+	}))
+	defer daemon.Close()
+	t.Setenv("DOCKER_HOST", "tcp://"+strings.TrimPrefix(daemon.URL, "http://"))
+
+	c, err := NewClient()
+	assert.NoError(t, err)
+	assert.NoError(t, RemoveNetwork(context.Background(), NetworkName("foo"), c))
+}
+
+func TestNetworkingConfig(t *testing.T) {
+	assert.Nil(t, (&Container{Name: "foo"}).networkingConfig())
+	nc := (&Container{Name: "foo", Network: "tmctl-bar", Aliases: []string{"foo", "foo.bar.local"}}).networkingConfig()
+	assert.Equal(t, []string{"foo", "foo.bar.local"}, nc.EndpointsConfig["tmctl-bar"].Aliases)
+}
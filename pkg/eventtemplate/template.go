@@ -0,0 +1,131 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package eventtemplate renders event payload and attribute templates
+// with the built-in generators of random, realistic looking values.
+package eventtemplate
+
+import (
+	"fmt"
+	"math/rand"
+	"strings"
+	"text/template"
+	"time"
+)
+
+var (
+	firstNames = []string{"Alice", "Bob", "Carol", "Dave", "Erin", "Frank", "Grace", "Heidi", "Ivan", "Judy", "Mallory", "Oscar", "Peggy", "Trent", "Victor", "Wendy"}
+	lastNames  = []string{"Anderson", "Brown", "Garcia", "Johnson", "Jones", "Lee", "Martin", "Miller", "Moore", "Smith", "Taylor", "Thomas", "Walker", "White", "Williams", "Wilson"}
+	domains    = []string{"example.com", "example.org", "example.net"}
+)
+
+// Generator holds the random source shared by the templates
+// so that the same seed produces the same sequence of values.
+type Generator struct {
+	rand *rand.Rand
+	now  func() time.Time
+}
+
+// Template is the parsed template bound to the generator.
+type Template struct {
+	name string
+	text string
+	tmpl *template.Template
+}
+
+// New returns the generator seeded with the value.
+func New(seed int64) *Generator {
+	return &Generator{
+		rand: rand.New(rand.NewSource(seed)),
+		now:  time.Now,
+	}
+}
+
+// Parse parses the template text. The name is used in the error messages.
+func (g *Generator) Parse(name, text string) (*Template, error) {
+	tmpl, err := template.New(name).Option("missingkey=error").Funcs(g.funcs()).Parse(text)
+	if err != nil {
+		return nil, fmt.Errorf("%s template \"%s\": %w", name, text, err)
+	}
+	return &Template{
+		name: name,
+		text: text,
+		tmpl: tmpl,
+	}, nil
+}
+
+// Execute renders the template, generators are evaluated on every call.
+func (t *Template) Execute() (string, error) {
+	var out strings.Builder
+	if err := t.tmpl.Execute(&out, nil); err != nil {
+		return "", fmt.Errorf("%s template \"%s\": %w", t.name, t.text, err)
+	}
+	return out.String(), nil
+}
+
+func (g *Generator) funcs() template.FuncMap {
+	return template.FuncMap{
+		"uuid":       g.uuid,
+		"now":        g.timestamp,
+		"randInt":    g.randInt,
+		"randChoice": g.randChoice,
+		"name":       g.name,
+		"email":      g.email,
+	}
+}
+
+// uuid returns the random version 4 UUID.
+func (g *Generator) uuid() string {
+	var u [16]byte
+	g.rand.Read(u[:])
+	u[6] = (u[6] & 0x0f) | 0x40
+	u[8] = (u[8] & 0x3f) | 0x80
+	return fmt.Sprintf("%x-%x-%x-%x-%x", u[0:4], u[4:6], u[6:8], u[8:10], u[10:])
+}
+
+func (g *Generator) timestamp() string {
+	return g.now().UTC().Format(time.RFC3339)
+}
+
+// randInt returns the random integer in the [min, max] range.
+func (g *Generator) randInt(min, max int) (int, error) {
+	if max < min {
+		return 0, fmt.Errorf("invalid range [%d, %d]", min, max)
+	}
+	return min + g.rand.Intn(max-min+1), nil
+}
+
+func (g *Generator) randChoice(choices ...string) (string, error) {
+	if len(choices) == 0 {
+		return "", fmt.Errorf("no choices")
+	}
+	return choices[g.rand.Intn(len(choices))], nil
+}
+
+func (g *Generator) name() string {
+	return fmt.Sprintf("%s %s", g.pick(firstNames), g.pick(lastNames))
+}
+
+func (g *Generator) email() string {
+	return fmt.Sprintf("%s.%s@%s",
+		strings.ToLower(g.pick(firstNames)),
+		strings.ToLower(g.pick(lastNames)),
+		g.pick(domains))
+}
+
+func (g *Generator) pick(list []string) string {
+	return list[g.rand.Intn(len(list))]
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package eventtemplate
+
+import (
+	"regexp"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestExecute(t *testing.T) {
+	g := New(42)
+	g.now = func() time.Time { return time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC) }
+
+	testCases := map[string]struct {
+		template string
+		match    string
+	}{
+		"uuid":        {`{"id":"{{uuid}}"}`, `^{"id":"[0-9a-f]{8}-[0-9a-f]{4}-4[0-9a-f]{3}-[89ab][0-9a-f]{3}-[0-9a-f]{12}"}$`},
+		"now":         {`{{now}}`, `^2023-05-01T10:00:00Z$`},
+		"random int":  {`{{randInt 1 3}}`, `^[1-3]$`},
+		"same bounds": {`{{randInt 7 7}}`, `^7$`},
+		"choice":      {`{{randChoice "a" "b"}}`, `^(a|b)$`},
+		"name":        {`{{name}}`, `^[A-Z][a-z]+ [A-Z][a-z]+$`},
+		"email":       {`{{email}}`, `^[a-z]+\.[a-z]+@example\.(com|org|net)$`},
+		"plain text":  {`{"hello":"world"}`, `^{"hello":"world"}$`},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			tmpl, err := g.Parse("payload", tc.template)
+			assert.NoError(t, err)
+			for i := 0; i < 10; i++ {
+				out, err := tmpl.Execute()
+				assert.NoError(t, err)
+				assert.Regexp(t, regexp.MustCompile(tc.match), out)
+			}
+		})
+	}
+}
+
+func TestSeed(t *testing.T) {
+	render := func(seed int64) []string {
+		tmpl, err := New(seed).Parse("payload", `{{uuid}} {{randInt 1 1000}} {{name}}`)
+		assert.NoError(t, err)
+		var result []string
+		for i := 0; i < 5; i++ {
+			out, err := tmpl.Execute()
+			assert.NoError(t, err)
+			result = append(result, out)
+		}
+		return result
+	}
+	first := render(1)
+	assert.Equal(t, first, render(1))
+	assert.NotEqual(t, first, render(2))
+	// values are evaluated per execution
+	assert.NotEqual(t, first[0], first[1])
+}
+
+func TestErrors(t *testing.T) {
+	g := New(1)
+	_, err := g.Parse("payload", `{"id":"{{uuidd}}"}`)
+	assert.ErrorContains(t, err, `{"id":"{{uuidd}}"}`)
+	assert.ErrorContains(t, err, `function "uuidd" not defined`)
+
+	tmpl, err := g.Parse("eventType", `order.{{randInt 5 1}}`)
+	assert.NoError(t, err)
+	_, err = tmpl.Execute()
+	assert.ErrorContains(t, err, "<randInt 5 1>")
+	assert.ErrorContains(t, err, "invalid range [5, 1]")
+}
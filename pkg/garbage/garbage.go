@@ -0,0 +1,179 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package garbage selects the tmctl containers and the TriggerMesh images
+// left behind by the previous iterations over the contexts.
+package garbage
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+// References are the images that the contexts run or may run again.
+type References struct {
+	// Versions are the image tags of the context version and of the
+	// versions pinned by the components.
+	Versions map[string]bool
+	// Images are the images set explicitly, e.g. the custom broker images
+	// or the images of the services.
+	Images map[string]bool
+}
+
+// ContextReferences collects the references of the configuration and of
+// the manifests of all contexts. Unreadable manifest is an error since
+// its images could not be told from the unused ones.
+func ContextReferences(c *config.Config) (References, error) {
+	refs := References{
+		Versions: map[string]bool{
+			c.Triggermesh.ComponentsVersion: true,
+			c.Triggermesh.Broker.Version:    true,
+		},
+		Images: make(map[string]bool),
+	}
+	for _, image := range c.Triggermesh.Broker.Images {
+		refs.Images[image] = true
+	}
+	dirs, err := os.ReadDir(c.ConfigHome)
+	if err != nil {
+		return References{}, fmt.Errorf("listing contexts: %w", err)
+	}
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		data, err := os.ReadFile(filepath.Join(c.ConfigHome, dir.Name(), triggermesh.ManifestFile))
+		if errors.Is(err, os.ErrNotExist) {
+			continue
+		}
+		if err != nil {
+			return References{}, fmt.Errorf("context %q manifest: %w", dir.Name(), err)
+		}
+		objects, err := manifest.Decode(data)
+		if err != nil {
+			return References{}, fmt.Errorf("context %q manifest: %w", dir.Name(), err)
+		}
+		for _, object := range objects {
+			if pinned := object.Metadata.Annotations[triggermesh.AdapterVersionAnnotation]; pinned != "" {
+				refs.Versions[pinned] = true
+			}
+			specImages(object.Spec, refs.Images)
+		}
+	}
+	return refs, nil
+}
+
+// specImages adds the values of the "image" fields of the spec to the set.
+func specImages(value interface{}, images map[string]bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			if image, ok := nested.(string); ok && key == "image" {
+				images[image] = true
+				continue
+			}
+			specImages(nested, images)
+		}
+	case []interface{}:
+		for _, item := range v {
+			specImages(item, images)
+		}
+	}
+}
+
+// referenced returns true if the image tag is referenced by the contexts.
+func (r References) referenced(tag string) bool {
+	if r.Images[tag] {
+		return true
+	}
+	// registry port is not the version
+	name := tag[strings.LastIndex(tag, "/")+1:]
+	if i := strings.LastIndex(name, ":"); i != -1 {
+		return r.Versions[name[i+1:]]
+	}
+	return false
+}
+
+// Plan is the garbage to collect.
+type Plan struct {
+	Containers []docker.Labeled
+	Images     []docker.Image
+}
+
+// NewPlan selects the exited tmctl containers and the images of the
+// repository that are neither referenced by the contexts nor used by
+// the containers left in place. The images tagged outside of the repository
+// are never selected. used is the number of containers per image ID,
+// see docker.UsedImages.
+func NewPlan(containers []docker.Labeled, used map[string]int, images []docker.Image, repository string, refs References) Plan {
+	var plan Plan
+	remaining := make(map[string]int, len(used))
+	for id, count := range used {
+		remaining[id] = count
+	}
+	for _, container := range containers {
+		if container.State != "exited" {
+			continue
+		}
+		plan.Containers = append(plan.Containers, container)
+		remaining[container.ImageID]--
+	}
+	for _, image := range images {
+		if remaining[image.ID] > 0 || !collectable(image, repository, refs) {
+			continue
+		}
+		plan.Images = append(plan.Images, image)
+	}
+	return plan
+}
+
+func collectable(image docker.Image, repository string, refs References) bool {
+	if len(image.Tags) == 0 {
+		return false
+	}
+	for _, tag := range image.Tags {
+		if !strings.HasPrefix(tag, repository) || refs.referenced(tag) {
+			return false
+		}
+	}
+	return true
+}
+
+// Empty returns true if there is nothing to collect.
+func (p Plan) Empty() bool {
+	return len(p.Containers) == 0 && len(p.Images) == 0
+}
+
+// Size returns the disk space taken by the garbage. The layers shared
+// by the images are counted for each of them, the size is an upper estimate.
+func (p Plan) Size() int64 {
+	var size int64
+	for _, container := range p.Containers {
+		size += container.Size
+	}
+	for _, image := range p.Images {
+		size += image.Size
+	}
+	return size
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package garbage
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+const repository = "gcr.io/triggermesh/"
+
+const pinnedManifest = `apiVersion: sources.triggermesh.io/v1alpha1
+kind: WebhookSource
+metadata:
+  name: bar-webhooksource
+  labels:
+    triggermesh.io/context: bar
+  annotations:
+    triggermesh.io/adapter-version: v1.24.3
+spec:
+  eventType: bar.webhook
+---
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: sockeye
+  labels:
+    triggermesh.io/context: bar
+    triggermesh.io/role: target
+spec:
+  template:
+    spec:
+      containers:
+      - image: gcr.io/triggermesh/sockeye:v0.7.0
+`
+
+func newConfig(t *testing.T, manifests map[string]string) *config.Config {
+	home := t.TempDir()
+	for context, manifest := range manifests {
+		require.NoError(t, os.MkdirAll(filepath.Join(home, context), os.ModePerm))
+		require.NoError(t, os.WriteFile(filepath.Join(home, context, triggermesh.ManifestFile), []byte(manifest), 0600))
+	}
+	// context directory without the manifest
+	require.NoError(t, os.MkdirAll(filepath.Join(home, "empty"), os.ModePerm))
+	return &config.Config{
+		ConfigHome: home,
+		Triggermesh: config.TmConfig{
+			ComponentsVersion: "v1.25.0",
+			Broker: config.BrokerConfig{
+				Version: "v1.3.0",
+				Images:  map[string]string{"foo": "gcr.io/triggermesh/memory-broker:custom"},
+			},
+		},
+	}
+}
+
+func TestContextReferences(t *testing.T) {
+	refs, err := ContextReferences(newConfig(t, map[string]string{"bar": pinnedManifest}))
+	require.NoError(t, err)
+	assert.Equal(t, map[string]bool{"v1.25.0": true, "v1.3.0": true, "v1.24.3": true}, refs.Versions)
+	assert.Equal(t, map[string]bool{
+		"gcr.io/triggermesh/memory-broker:custom": true,
+		"gcr.io/triggermesh/sockeye:v0.7.0":       true,
+	}, refs.Images)
+
+	_, err = ContextReferences(newConfig(t, map[string]string{"bar": "kind: [broken"}))
+	assert.ErrorContains(t, err, `context "bar" manifest`)
+}
+
+func TestNewPlan(t *testing.T) {
+	refs, err := ContextReferences(newConfig(t, map[string]string{"bar": pinnedManifest}))
+	require.NoError(t, err)
+
+	containers := []docker.Labeled{
+		{ID: "1", Name: "foo-webhooksource", State: "exited", ImageID: "old", Size: 10},
+		{ID: "2", Name: "foo-broker", State: "running", ImageID: "running"},
+		{ID: "3", Name: "foo-target", State: "exited", ImageID: "shared", Size: 20},
+	}
+	used := map[string]int{
+		"old":     1,
+		"running": 1,
+		// the image of the exited tmctl container and of the foreign container
+		"shared":  2,
+		"foreign": 1,
+	}
+	images := []docker.Image{
+		{ID: "old", Tags: []string{repository + "webhooksource-adapter:v1.20.0"}, Size: 100},
+		{ID: "unused", Tags: []string{repository + "httppollersource-adapter:v1.21.0", repository + "httppollersource-adapter:latest"}, Size: 200},
+		{ID: "running", Tags: []string{repository + "memory-broker:v1.2.0"}},
+		{ID: "shared", Tags: []string{repository + "cloudeventstarget-adapter:v1.20.0"}},
+		{ID: "foreign", Tags: []string{repository + "kafkatarget-adapter:v1.20.0"}},
+		{ID: "context version", Tags: []string{repository + "webhooksource-adapter:v1.25.0"}},
+		{ID: "pinned", Tags: []string{repository + "webhooksource-adapter:v1.24.3"}},
+		{ID: "custom broker", Tags: []string{repository + "memory-broker:custom"}},
+		{ID: "service", Tags: []string{repository + "sockeye:v0.7.0"}},
+		{ID: "retagged", Tags: []string{repository + "webhooksource-adapter:v1.19.0", "registry.local:5000/webhooksource-adapter:v1.19.0"}},
+		{ID: "untagged"},
+	}
+
+	plan := NewPlan(containers, used, images, repository, refs)
+	assert.False(t, plan.Empty())
+	var planned []string
+	for _, container := range plan.Containers {
+		planned = append(planned, container.ID)
+	}
+	assert.Equal(t, []string{"1", "3"}, planned)
+	planned = nil
+	for _, image := range plan.Images {
+		planned = append(planned, image.ID)
+	}
+	assert.Equal(t, []string{"old", "unused"}, planned)
+	assert.EqualValues(t, 330, plan.Size())
+
+	assert.True(t, NewPlan(nil, nil, nil, repository, refs).Empty())
+}
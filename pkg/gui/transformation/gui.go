@@ -30,6 +30,7 @@ import (
 	"sigs.k8s.io/yaml"
 
 	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/httpclient"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
@@ -273,7 +274,7 @@ func preloadRegistryData(componentsList map[string]meta, registryUrl string, cac
 			if err != nil {
 				return fmt.Errorf("registry path error: %v", err)
 			}
-			resp, err := http.Get(registryEndpoint)
+			resp, err := httpclient.Get(registryEndpoint)
 			if err != nil {
 				return fmt.Errorf("registry request error: %v", err)
 			}
@@ -0,0 +1,139 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package health probes the HTTP endpoints of the running components
+// to tell the serving adapters from the merely running containers.
+package health
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/url"
+	"time"
+)
+
+// Status is the result of the component endpoint probe.
+type Status string
+
+const (
+	Healthy   Status = "healthy"
+	Unhealthy Status = "unhealthy"
+	// Unknown status is reported for the components that were not probed.
+	Unknown Status = "unknown"
+)
+
+// Path is the health endpoint served by the TriggerMesh adapters.
+const Path = "/healthz"
+
+// Timeout of the single probe request.
+const Timeout = 2 * time.Second
+
+// PingTimeout limits the request of Ping.
+const PingTimeout = time.Second
+
+// Result is the outcome of the component probe.
+type Result struct {
+	Status Status
+	// Detail is the HTTP status or the error text of the failed probe.
+	Detail string
+}
+
+func (r Result) String() string {
+	if r.Detail == "" {
+		return string(r.Status)
+	}
+	return fmt.Sprintf("%s (%s)", r.Status, r.Detail)
+}
+
+// local requests are never sent through the proxy.
+var client = &http.Client{
+	Transport: &http.Transport{DisableKeepAlives: true},
+	CheckRedirect: func(*http.Request, []*http.Request) error {
+		return http.ErrUseLastResponse
+	},
+}
+
+// Probe sends GET request to the health endpoint of the address.
+// Components without the endpoint are probed with HEAD request to the root
+// path and are considered healthy if they respond without the server error.
+func Probe(ctx context.Context, address string) Result {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	code, err := request(ctx, http.MethodGet, address+Path, Timeout)
+	if err != nil {
+		return Result{Status: Unhealthy, Detail: err.Error()}
+	}
+	switch {
+	case code >= 200 && code < 300:
+		return Result{Status: Healthy}
+	case code != http.StatusNotFound && code != http.StatusMethodNotAllowed:
+		return Result{Status: Unhealthy, Detail: fmt.Sprintf("GET %s: %d %s", Path, code, http.StatusText(code))}
+	}
+
+	code, err = request(ctx, http.MethodHead, address+"/", Timeout)
+	if err != nil {
+		return Result{Status: Unhealthy, Detail: err.Error()}
+	}
+	if code >= 500 {
+		return Result{Status: Unhealthy, Detail: fmt.Sprintf("HEAD /: %d %s", code, http.StatusText(code))}
+	}
+	return Result{Status: Healthy}
+}
+
+// Ping sends HEAD request to the address and returns the error if there is
+// no response or the response is the server error. Unlike Probe, Ping only
+// tells that something listens at the address, e.g. the trigger destination.
+func Ping(ctx context.Context, address string) error {
+	ctx, cancel := context.WithTimeout(ctx, PingTimeout)
+	defer cancel()
+
+	code, err := request(ctx, http.MethodHead, address, PingTimeout)
+	if err != nil {
+		return err
+	}
+	if code >= 500 {
+		return fmt.Errorf("HEAD: %d %s", code, http.StatusText(code))
+	}
+	return nil
+}
+
+func request(ctx context.Context, method, address string, timeout time.Duration) (int, error) {
+	req, err := http.NewRequestWithContext(ctx, method, address, nil)
+	if err != nil {
+		return 0, err
+	}
+	resp, err := client.Do(req)
+	if err != nil {
+		if ctx.Err() == context.DeadlineExceeded {
+			return 0, fmt.Errorf("no response after %s", timeout)
+		}
+		return 0, fmt.Errorf("%s %s: %w", method, req.URL.Path, unwrap(err))
+	}
+	resp.Body.Close()
+	return resp.StatusCode, nil
+}
+
+// unwrap drops the method and URL repeated by the client errors.
+func unwrap(err error) error {
+	var urlErr *url.Error
+	if errors.As(err, &urlErr) {
+		return urlErr.Err
+	}
+	return err
+}
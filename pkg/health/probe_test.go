@@ -0,0 +1,110 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestProbe(t *testing.T) {
+	testCases := map[string]struct {
+		handler http.HandlerFunc
+		status  Status
+		detail  string
+	}{
+		"health endpoint": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				assert.Equal(t, Path, r.URL.Path)
+			},
+			status: Healthy,
+		},
+		"failing health endpoint": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				w.WriteHeader(http.StatusServiceUnavailable)
+			},
+			status: Unhealthy,
+			detail: "GET /healthz: 503 Service Unavailable",
+		},
+		"root fallback": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == Path {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				assert.Equal(t, http.MethodHead, r.Method)
+				w.WriteHeader(http.StatusBadRequest)
+			},
+			status: Healthy,
+		},
+		"failing root fallback": {
+			handler: func(w http.ResponseWriter, r *http.Request) {
+				if r.URL.Path == Path {
+					w.WriteHeader(http.StatusNotFound)
+					return
+				}
+				w.WriteHeader(http.StatusBadGateway)
+			},
+			status: Unhealthy,
+			detail: "HEAD /: 502 Bad Gateway",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			server := httptest.NewServer(tc.handler)
+			defer server.Close()
+			result := Probe(context.Background(), server.URL)
+			assert.Equal(t, tc.status, result.Status)
+			assert.Equal(t, tc.detail, result.Detail)
+		})
+	}
+}
+
+func TestProbeNotServing(t *testing.T) {
+	server := httptest.NewServer(http.NotFoundHandler())
+	address := server.URL
+	server.Close()
+
+	result := Probe(context.Background(), address)
+	assert.Equal(t, Unhealthy, result.Status)
+	assert.Contains(t, result.Detail, "GET /healthz: ")
+	assert.Contains(t, result.Detail, "connection refused")
+}
+
+func TestPing(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		assert.Equal(t, http.MethodHead, r.Method)
+		switch r.URL.Path {
+		case "/post-only":
+			w.WriteHeader(http.StatusMethodNotAllowed)
+		case "/broken":
+			w.WriteHeader(http.StatusBadGateway)
+		}
+	}))
+	address := server.URL
+	assert.NoError(t, Ping(context.Background(), address))
+	// the destination rejecting the method still responds
+	assert.NoError(t, Ping(context.Background(), address+"/post-only"))
+	assert.EqualError(t, Ping(context.Background(), address+"/broken"), "HEAD: 502 Bad Gateway")
+
+	server.Close()
+	assert.Error(t, Ping(context.Background(), address))
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"strings"
+	"time"
+)
+
+// Strategy is the way the component signals that it is ready.
+type Strategy string
+
+const (
+	// TCP strategy waits for the published port to accept connections.
+	TCP Strategy = "tcp"
+	// HTTP strategy waits for the successful response of the endpoint.
+	HTTP Strategy = "http"
+	// Log strategy waits for the line in the container log.
+	Log Strategy = "log"
+)
+
+// waitInterval is the pause between the probes of the readiness wait.
+var waitInterval = 500 * time.Millisecond
+
+// Readiness is the readiness probe of the component kind
+// declared in the CRD annotation, e.g. "http:/healthz" or "log:Started receiver".
+type Readiness struct {
+	Strategy Strategy
+	// Argument is the HTTP path or the log line substring.
+	Argument string
+}
+
+// DefaultReadiness is used for the kinds without the readiness annotation.
+var DefaultReadiness = Readiness{Strategy: TCP}
+
+// Target is the component container checked by the readiness probe.
+type Target struct {
+	// Address is the published host address of the container, e.g. localhost:8080.
+	Address string
+	// Logs opens the container log stream.
+	Logs func(ctx context.Context, follow bool) (io.ReadCloser, error)
+}
+
+// ParseReadiness parses the readiness probe annotation value.
+// Empty value returns the default TCP probe.
+func ParseReadiness(value string) (Readiness, error) {
+	value = strings.TrimSpace(value)
+	if value == "" {
+		return DefaultReadiness, nil
+	}
+	strategy, argument, _ := strings.Cut(value, ":")
+	r := Readiness{Strategy: Strategy(strings.ToLower(strategy)), Argument: argument}
+	switch r.Strategy {
+	case TCP:
+		if argument != "" {
+			return Readiness{}, fmt.Errorf("readiness probe %q: tcp probe takes no argument", value)
+		}
+	case HTTP:
+		if r.Argument == "" {
+			r.Argument = Path
+		}
+		if !strings.HasPrefix(r.Argument, "/") {
+			return Readiness{}, fmt.Errorf("readiness probe %q: path must start with \"/\"", value)
+		}
+	case Log:
+		if strings.TrimSpace(argument) == "" {
+			return Readiness{}, fmt.Errorf("readiness probe %q: log line is not set", value)
+		}
+	default:
+		return Readiness{}, fmt.Errorf("unknown readiness probe %q, expected tcp, http:<path> or log:<line>", value)
+	}
+	return r, nil
+}
+
+func (r Readiness) String() string {
+	if r.Argument == "" {
+		return string(r.Strategy)
+	}
+	return string(r.Strategy) + ":" + r.Argument
+}
+
+// Probe checks the readiness of the target once.
+func (r Readiness) Probe(ctx context.Context, t Target) Result {
+	ctx, cancel := context.WithTimeout(ctx, Timeout)
+	defer cancel()
+
+	switch r.Strategy {
+	case Log:
+		if t.Logs == nil {
+			return Result{Status: Unknown}
+		}
+		logs, err := t.Logs(ctx, false)
+		if err != nil {
+			return Result{Status: Unhealthy, Detail: err.Error()}
+		}
+		defer logs.Close()
+		if containsLine(logs, r.Argument) {
+			return Result{Status: Healthy}
+		}
+		return Result{Status: Unhealthy, Detail: fmt.Sprintf("log line %q not found", r.Argument)}
+	case HTTP:
+		if t.Address == "" {
+			return Result{Status: Unhealthy, Detail: "no published port"}
+		}
+		code, err := request(ctx, http.MethodGet, "http://"+t.Address+r.Argument, Timeout)
+		if err != nil {
+			return Result{Status: Unhealthy, Detail: err.Error()}
+		}
+		if code < 200 || code >= 300 {
+			return Result{Status: Unhealthy, Detail: fmt.Sprintf("GET %s: %d %s", r.Argument, code, http.StatusText(code))}
+		}
+		return Result{Status: Healthy}
+	default:
+		if t.Address == "" {
+			return Result{Status: Unhealthy, Detail: "no published port"}
+		}
+		var dialer net.Dialer
+		conn, err := dialer.DialContext(ctx, "tcp", t.Address)
+		if err != nil {
+			return Result{Status: Unhealthy, Detail: unwrap(err).Error()}
+		}
+		conn.Close()
+		return Result{Status: Healthy}
+	}
+}
+
+// Wait probes the target until it is ready or the timeout expires.
+// Log probe follows the container log stream instead of polling it.
+func (r Readiness) Wait(ctx context.Context, t Target, timeout time.Duration) Result {
+	ctx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	if r.Strategy == Log && t.Logs != nil {
+		logs, err := t.Logs(ctx, true)
+		if err != nil {
+			return Result{Status: Unhealthy, Detail: err.Error()}
+		}
+		// closing the stream unblocks the scanner on timeout
+		go func() {
+			<-ctx.Done()
+			logs.Close()
+		}()
+		if containsLine(logs, r.Argument) {
+			return Result{Status: Healthy}
+		}
+		return Result{Status: Unhealthy, Detail: fmt.Sprintf("log line %q not found after %s", r.Argument, timeout)}
+	}
+
+	ticker := time.NewTicker(waitInterval)
+	defer ticker.Stop()
+	for {
+		result := r.Probe(ctx, t)
+		if result.Status != Unhealthy {
+			return result
+		}
+		select {
+		case <-ctx.Done():
+			result.Detail = fmt.Sprintf("not ready after %s: %s", timeout, result.Detail)
+			return result
+		case <-ticker.C:
+		}
+	}
+}
+
+// containsLine reads the log until the line with the substring.
+func containsLine(logs io.Reader, substring string) bool {
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), substring) {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,144 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package health
+
+import (
+	"context"
+	"io"
+	"net"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestParseReadiness(t *testing.T) {
+	testCases := map[string]struct {
+		value    string
+		expected Readiness
+		err      bool
+	}{
+		"default":          {value: "", expected: DefaultReadiness},
+		"tcp":              {value: "tcp", expected: Readiness{Strategy: TCP}},
+		"http":             {value: "http:/healthz", expected: Readiness{Strategy: HTTP, Argument: "/healthz"}},
+		"http default":     {value: "http", expected: Readiness{Strategy: HTTP, Argument: Path}},
+		"log":              {value: "log:Started receiver", expected: Readiness{Strategy: Log, Argument: "Started receiver"}},
+		"log with colon":   {value: "log:listening on :8080", expected: Readiness{Strategy: Log, Argument: "listening on :8080"}},
+		"relative path":    {value: "http:healthz", err: true},
+		"empty log line":   {value: "log: ", err: true},
+		"tcp argument":     {value: "tcp:8080", err: true},
+		"unknown strategy": {value: "exec:/bin/true", err: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			r, err := ParseReadiness(tc.value)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, r)
+		})
+	}
+}
+
+func TestReadinessProbe(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/ready" {
+			w.WriteHeader(http.StatusNotFound)
+		}
+	}))
+	defer server.Close()
+	address := strings.TrimPrefix(server.URL, "http://")
+
+	logs := func(ctx context.Context, follow bool) (io.ReadCloser, error) {
+		return io.NopCloser(strings.NewReader("{\"msg\":\"Starting\"}\n{\"msg\":\"Started receiver\"}\n")), nil
+	}
+
+	testCases := map[string]struct {
+		readiness Readiness
+		target    Target
+		status    Status
+	}{
+		"tcp":              {readiness: DefaultReadiness, target: Target{Address: address}, status: Healthy},
+		"tcp without port": {readiness: DefaultReadiness, target: Target{}, status: Unhealthy},
+		"http":             {readiness: Readiness{Strategy: HTTP, Argument: "/ready"}, target: Target{Address: address}, status: Healthy},
+		"http not found":   {readiness: Readiness{Strategy: HTTP, Argument: "/healthz"}, target: Target{Address: address}, status: Unhealthy},
+		"log":              {readiness: Readiness{Strategy: Log, Argument: "Started receiver"}, target: Target{Logs: logs}, status: Healthy},
+		"log not found":    {readiness: Readiness{Strategy: Log, Argument: "Listening"}, target: Target{Logs: logs}, status: Unhealthy},
+		// sources that never listen on a port are probed by their logs only
+		"log without port": {readiness: Readiness{Strategy: Log, Argument: "Started"}, target: Target{Logs: logs}, status: Healthy},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			assert.Equal(t, tc.status, tc.readiness.Probe(context.Background(), tc.target).Status)
+		})
+	}
+}
+
+func TestReadinessWaitLog(t *testing.T) {
+	reader, writer := io.Pipe()
+	defer writer.Close()
+	target := Target{Logs: func(ctx context.Context, follow bool) (io.ReadCloser, error) {
+		assert.True(t, follow)
+		return reader, nil
+	}}
+	go func() {
+		_, _ = writer.Write([]byte("Starting\n"))
+		time.Sleep(100 * time.Millisecond)
+		_, _ = writer.Write([]byte("Started receiver\n"))
+	}()
+	r := Readiness{Strategy: Log, Argument: "Started receiver"}
+	assert.Equal(t, Healthy, r.Wait(context.Background(), target, 5*time.Second).Status)
+
+	// the stream that never logs the line is closed on timeout
+	idle, _ := io.Pipe()
+	target.Logs = func(ctx context.Context, follow bool) (io.ReadCloser, error) {
+		return idle, nil
+	}
+	result := r.Wait(context.Background(), target, 100*time.Millisecond)
+	assert.Equal(t, Unhealthy, result.Status)
+	assert.Contains(t, result.Detail, "not found after 100ms")
+}
+
+func TestReadinessWaitPort(t *testing.T) {
+	listener, err := net.Listen("tcp", "127.0.0.1:0")
+	assert.NoError(t, err)
+	address := listener.Addr().String()
+	listener.Close()
+
+	// the port opens after the first probe
+	go func() {
+		time.Sleep(200 * time.Millisecond)
+		l, err := net.Listen("tcp", address)
+		if err != nil {
+			return
+		}
+		defer l.Close()
+		time.Sleep(2 * time.Second)
+	}()
+	defer func(interval time.Duration) { waitInterval = interval }(waitInterval)
+	waitInterval = 50 * time.Millisecond
+	assert.Equal(t, Healthy, DefaultReadiness.Wait(context.Background(), Target{Address: address}, 2*time.Second).Status)
+
+	result := DefaultReadiness.Wait(context.Background(), Target{}, 100*time.Millisecond)
+	assert.Equal(t, Unhealthy, result.Status)
+	assert.Contains(t, result.Detail, "not ready after 100ms")
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package httpclient provides the HTTP client shared by the outbound
+// requests: CRD and release lookups, remote manifests, schema registry
+// and tunnels. Docker API calls use the Docker SDK transport instead.
+package httpclient
+
+import (
+	"crypto/tls"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"strings"
+	"sync"
+)
+
+var (
+	// ErrProxyAuth is returned when the proxy rejects the request credentials.
+	ErrProxyAuth = errors.New("proxy authentication required, check the credentials in HTTP_PROXY/HTTPS_PROXY")
+	// ErrTLS is returned when the server certificate cannot be verified.
+	ErrTLS = errors.New("TLS verification failed, add the issuing CA with \"tmctl config set ca-bundle <file>\"")
+)
+
+var (
+	mu     sync.RWMutex
+	client = &http.Client{Transport: newTransport(nil)}
+)
+
+// Configure sets the extra CA certificates trusted by the shared client
+// in addition to the system ones. Empty path resets to the system pool.
+func Configure(caBundle string) error {
+	var pool *x509.CertPool
+	if caBundle != "" {
+		var err error
+		if pool, err = LoadCABundle(caBundle); err != nil {
+			return err
+		}
+	}
+	mu.Lock()
+	client = &http.Client{Transport: newTransport(pool)}
+	mu.Unlock()
+	return nil
+}
+
+// LoadCABundle returns the system certificate pool extended with
+// the PEM encoded certificates from the file.
+func LoadCABundle(path string) (*x509.CertPool, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("reading CA bundle: %w", err)
+	}
+	pool, err := x509.SystemCertPool()
+	if err != nil {
+		pool = x509.NewCertPool()
+	}
+	if !pool.AppendCertsFromPEM(data) {
+		return nil, fmt.Errorf("no PEM certificates found in %q", path)
+	}
+	return pool, nil
+}
+
+// Client returns the shared HTTP client.
+func Client() *http.Client {
+	mu.RLock()
+	defer mu.RUnlock()
+	return client
+}
+
+// Get issues a GET request with the shared client.
+func Get(url string) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, url, nil)
+	if err != nil {
+		return nil, err
+	}
+	return Do(req)
+}
+
+// Do sends the request with the shared client. Proxy authentication
+// and certificate verification failures are reported as ErrProxyAuth
+// and ErrTLS respectively.
+func Do(req *http.Request) (*http.Response, error) {
+	resp, err := Client().Do(req)
+	if err != nil {
+		return nil, classify(err)
+	}
+	if resp.StatusCode == http.StatusProxyAuthRequired {
+		resp.Body.Close()
+		return nil, fmt.Errorf("%s: %w", req.URL.Host, ErrProxyAuth)
+	}
+	return resp, nil
+}
+
+func newTransport(pool *x509.CertPool) *http.Transport {
+	t := http.DefaultTransport.(*http.Transport).Clone()
+	t.Proxy = http.ProxyFromEnvironment
+	if pool != nil {
+		t.TLSClientConfig = &tls.Config{RootCAs: pool}
+	}
+	return t
+}
+
+func classify(err error) error {
+	var (
+		unknownAuthority x509.UnknownAuthorityError
+		invalidCert      x509.CertificateInvalidError
+		hostname         x509.HostnameError
+		recordHeader     tls.RecordHeaderError
+	)
+	switch {
+	// CONNECT tunnel rejections are not exposed as a typed error
+	case strings.Contains(err.Error(), http.StatusText(http.StatusProxyAuthRequired)):
+		return fmt.Errorf("%w: %v", ErrProxyAuth, err)
+	case errors.As(err, &unknownAuthority),
+		errors.As(err, &invalidCert),
+		errors.As(err, &hostname),
+		errors.As(err, &recordHeader):
+		return fmt.Errorf("%w: %v", ErrTLS, err)
+	}
+	return err
+}
@@ -0,0 +1,87 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package httpclient
+
+import (
+	"encoding/pem"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+func TestCABundle(t *testing.T) {
+	server := httptest.NewTLSServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {}))
+	defer server.Close()
+	defer func() { _ = Configure("") }()
+
+	if err := Configure(""); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	if _, err := Get(server.URL); !errors.Is(err, ErrTLS) {
+		t.Fatalf("Get() error = %v, want %v", err, ErrTLS)
+	}
+
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	cert := pem.EncodeToMemory(&pem.Block{Type: "CERTIFICATE", Bytes: server.Certificate().Raw})
+	if err := os.WriteFile(bundle, cert, 0600); err != nil {
+		t.Fatal(err)
+	}
+	if err := Configure(bundle); err != nil {
+		t.Fatalf("Configure() error = %v", err)
+	}
+	resp, err := Get(server.URL)
+	if err != nil {
+		t.Fatalf("Get() error = %v", err)
+	}
+	resp.Body.Close()
+}
+
+func TestLoadCABundleInvalid(t *testing.T) {
+	bundle := filepath.Join(t.TempDir(), "ca.pem")
+	if err := os.WriteFile(bundle, []byte("not a certificate"), 0600); err != nil {
+		t.Fatal(err)
+	}
+	if _, err := LoadCABundle(bundle); err == nil {
+		t.Error("LoadCABundle() expected error")
+	}
+	if _, err := LoadCABundle(filepath.Join(t.TempDir(), "missing.pem")); err == nil {
+		t.Error("LoadCABundle() expected error for missing file")
+	}
+}
+
+func TestProxyAuth(t *testing.T) {
+	proxy := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusProxyAuthRequired)
+	}))
+	defer proxy.Close()
+	defer func() { _ = Configure("") }()
+
+	// proxy environment variables are read once per process,
+	// so the test proxy is set on the transport directly
+	transport := newTransport(nil)
+	transport.Proxy = func(*http.Request) (*url.URL, error) {
+		return url.Parse(proxy.URL)
+	}
+	client = &http.Client{Transport: transport}
+	if _, err := Get("http://example.com"); !errors.Is(err, ErrProxyAuth) {
+		t.Fatalf("Get() error = %v, want %v", err, ErrProxyAuth)
+	}
+}
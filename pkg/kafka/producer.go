@@ -0,0 +1,182 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kafka produces CloudEvents to Kafka topics
+// using the kcat container and the Kafka protocol binary content mode.
+package kafka
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"sort"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+const (
+	image = "edenhill/kcat:1.7.1"
+
+	dockerHost = urls.DockerHost
+	dataEnv    = "CE_DATA"
+)
+
+// Producer holds the Kafka connection parameters.
+type Producer struct {
+	BootstrapServers []string
+	Topic            string
+
+	SASLMechanism string
+	SASLUser      string
+	SASLPassword  string
+
+	TLS bool
+	// CA is the PEM encoded certificate authority.
+	CA string
+}
+
+// Produce writes the event to the topic.
+func (p *Producer) Produce(ctx context.Context, name string, event cloudevents.Event) error {
+	args, err := p.Args(event)
+	if err != nil {
+		return err
+	}
+	client, err := docker.NewClient()
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	container := &docker.Container{
+		Name:  name,
+		Image: image,
+		CreateContainerOptions: []docker.ContainerOption{
+			docker.WithImage(image),
+			// payload is passed through the environment to avoid the shell quoting
+			docker.WithEntrypoint([]string{"sh", "-c", fmt.Sprintf(`printf '%%s' "$%s" | kcat "$@"`, dataEnv), "kcat"}),
+			docker.WithCmd(args),
+			docker.WithEnv([]string{fmt.Sprintf("%s=%s", dataEnv, event.Data())}),
+		},
+		CreateHostOptions: []docker.HostOption{
+			docker.WithExtraHost(),
+		},
+	}
+	if _, err := container.Run(ctx, client); err != nil {
+		return fmt.Errorf("kafka producer: %w", err)
+	}
+	return nil
+}
+
+// Args returns kcat producer arguments with the event attributes set as the message headers.
+func (p *Producer) Args(event cloudevents.Event) ([]string, error) {
+	if len(p.BootstrapServers) == 0 {
+		return nil, fmt.Errorf("bootstrap servers are not set")
+	}
+	if p.Topic == "" {
+		return nil, fmt.Errorf("topic is not set")
+	}
+	if p.SASLMechanism == "" && (p.SASLUser != "" || p.SASLPassword != "") {
+		return nil, fmt.Errorf("SASL credentials require the mechanism")
+	}
+	servers := make([]string, len(p.BootstrapServers))
+	for i, server := range p.BootstrapServers {
+		servers[i] = containerAddress(server)
+	}
+	args := []string{"-P", "-b", strings.Join(servers, ","), "-t", p.Topic}
+	for _, header := range headers(event) {
+		args = append(args, "-H", header)
+	}
+	for _, property := range p.properties() {
+		args = append(args, "-X", property)
+	}
+	return args, nil
+}
+
+func (p *Producer) properties() []string {
+	protocol := "PLAINTEXT"
+	switch {
+	case p.SASLMechanism != "" && p.TLS:
+		protocol = "SASL_SSL"
+	case p.SASLMechanism != "":
+		protocol = "SASL_PLAINTEXT"
+	case p.TLS:
+		protocol = "SSL"
+	}
+	properties := []string{"security.protocol=" + protocol}
+	if p.SASLMechanism != "" {
+		properties = append(properties, "sasl.mechanisms="+p.SASLMechanism)
+	}
+	if p.SASLUser != "" {
+		properties = append(properties, "sasl.username="+p.SASLUser)
+	}
+	if p.SASLPassword != "" {
+		properties = append(properties, "sasl.password="+p.SASLPassword)
+	}
+	if p.TLS && p.CA != "" {
+		properties = append(properties, "ssl.ca.pem="+p.CA)
+	}
+	return properties
+}
+
+// headers maps the event attributes to the Kafka protocol binding headers.
+func headers(event cloudevents.Event) []string {
+	result := []string{
+		"ce_specversion=" + event.SpecVersion(),
+		"ce_id=" + event.ID(),
+		"ce_source=" + event.Source(),
+		"ce_type=" + event.Type(),
+	}
+	if !event.Time().IsZero() {
+		result = append(result, "ce_time="+event.Time().UTC().Format(time.RFC3339Nano))
+	}
+	if event.Subject() != "" {
+		result = append(result, "ce_subject="+event.Subject())
+	}
+	if event.DataSchema() != "" {
+		result = append(result, "ce_dataschema="+event.DataSchema())
+	}
+	var extensions []string
+	for name, value := range event.Extensions() {
+		v, err := types.Format(value)
+		if err != nil {
+			continue
+		}
+		extensions = append(extensions, fmt.Sprintf("ce_%s=%s", name, v))
+	}
+	sort.Strings(extensions)
+	result = append(result, extensions...)
+	if event.DataContentType() != "" {
+		result = append(result, "content-type="+event.DataContentType())
+	}
+	return result
+}
+
+// containerAddress replaces the loopback host with the address
+// that resolves to the host machine from inside the container.
+func containerAddress(server string) string {
+	host, port, err := net.SplitHostPort(server)
+	if err != nil {
+		return server
+	}
+	if host == "localhost" || net.ParseIP(host).IsLoopback() {
+		return net.JoinHostPort(dockerHost, port)
+	}
+	return server
+}
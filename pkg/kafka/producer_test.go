@@ -0,0 +1,108 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kafka
+
+import (
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func TestArgs(t *testing.T) {
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetSource("triggermesh-cli")
+	event.SetType("foo.bar")
+	event.SetTime(time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC))
+	event.SetExtension("partitionkey", "abc")
+	assert.NoError(t, event.SetData(cloudevents.ApplicationJSON, []byte(`{"foo":"bar"}`)))
+
+	testCases := map[string]struct {
+		producer Producer
+		expected []string
+		err      bool
+	}{
+		"plaintext": {
+			producer: Producer{
+				BootstrapServers: []string{"localhost:9092", "kafka.example.com:9092"},
+				Topic:            "orders",
+			},
+			expected: []string{
+				"-P", "-b", "host.docker.internal:9092,kafka.example.com:9092", "-t", "orders",
+				"-H", "ce_specversion=1.0",
+				"-H", "ce_id=1",
+				"-H", "ce_source=triggermesh-cli",
+				"-H", "ce_type=foo.bar",
+				"-H", "ce_time=2023-01-02T03:04:05Z",
+				"-H", "ce_partitionkey=abc",
+				"-H", "content-type=application/json",
+				"-X", "security.protocol=PLAINTEXT",
+			},
+		},
+		"sasl over tls": {
+			producer: Producer{
+				BootstrapServers: []string{"127.0.0.1:9093"},
+				Topic:            "orders",
+				SASLMechanism:    "PLAIN",
+				SASLUser:         "user",
+				SASLPassword:     "secret",
+				TLS:              true,
+				CA:               "-----BEGIN CERTIFICATE-----",
+			},
+			expected: []string{
+				"-P", "-b", "host.docker.internal:9093", "-t", "orders",
+				"-H", "ce_specversion=1.0",
+				"-H", "ce_id=1",
+				"-H", "ce_source=triggermesh-cli",
+				"-H", "ce_type=foo.bar",
+				"-H", "ce_time=2023-01-02T03:04:05Z",
+				"-H", "ce_partitionkey=abc",
+				"-H", "content-type=application/json",
+				"-X", "security.protocol=SASL_SSL",
+				"-X", "sasl.mechanisms=PLAIN",
+				"-X", "sasl.username=user",
+				"-X", "sasl.password=secret",
+				"-X", "ssl.ca.pem=-----BEGIN CERTIFICATE-----",
+			},
+		},
+		"no topic": {
+			producer: Producer{BootstrapServers: []string{"localhost:9092"}},
+			err:      true,
+		},
+		"credentials without mechanism": {
+			producer: Producer{
+				BootstrapServers: []string{"localhost:9092"},
+				Topic:            "orders",
+				SASLUser:         "user",
+			},
+			err: true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			args, err := tc.producer.Args(event)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, args)
+		})
+	}
+}
@@ -176,16 +176,7 @@ func CreateService(name string) interface{} {
 }
 
 func getObjectCRD(crdObject crd.CRD) (*crd.Schema, string, error) {
-	for _, v := range crdObject.Spec.Versions {
-		if v.Served {
-			schema, err := crd.GetSchema(v.Schema.OpenAPIV3Schema.Properties.Spec)
-			if err != nil {
-				return nil, "", fmt.Errorf("CRD schema: %w", err)
-			}
-			return schema, v.Name, nil
-		}
-	}
-	return nil, "", fmt.Errorf("CRD schema not found")
+	return crdObject.ServedSchema()
 }
 
 // ExtractSecrets looks up resource schema, extracts secret objects
@@ -0,0 +1,261 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package kustomize writes the manifest objects as the kustomize base
+// with the overlays for the deployment environments.
+package kustomize
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	kyaml "sigs.k8s.io/yaml"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+)
+
+const (
+	// BaseDir is the directory with the manifest objects.
+	BaseDir = "base"
+	// OverlaysDir is the directory with the environment overlays.
+	OverlaysDir = "overlays"
+
+	kustomizationFile = "kustomization.yaml"
+	patchesDir        = "patches"
+
+	apiVersion = "kustomize.config.k8s.io/v1beta1"
+	kind       = "Kustomization"
+
+	brokerAPIVersion = "eventing.triggermesh.io/v1alpha1"
+)
+
+// secretRefKeys are the spec keys that point to the secret values.
+var secretRefKeys = map[string]struct{}{
+	"valueFromSecret": {},
+	"secretKeyRef":    {},
+}
+
+type kustomization struct {
+	APIVersion string   `json:"apiVersion"`
+	Kind       string   `json:"kind"`
+	Resources  []string `json:"resources"`
+	Patches    []patch  `json:"patches,omitempty"`
+	Images     []image  `json:"images,omitempty"`
+}
+
+type patch struct {
+	Path string `json:"path"`
+}
+
+type image struct {
+	Name   string `json:"name"`
+	NewTag string `json:"newTag,omitempty"`
+	Digest string `json:"digest,omitempty"`
+}
+
+// Write stores the objects in the base directory under dir and creates
+// the overlay for each environment. Overlays patch the fields that usually
+// differ between environments: secret references, broker backend and
+// container images. The first overlay keeps the current values, patches
+// in the rest of the overlays are left empty to be filled in by the user.
+func Write(dir string, objects []kubernetes.Object, overlays []string) error {
+	if err := validOverlays(overlays); err != nil {
+		return err
+	}
+	base := kustomization{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Resources:  []string{},
+	}
+	for _, object := range objects {
+		file := fileName(object)
+		if err := writeYAML(filepath.Join(dir, BaseDir, file), object); err != nil {
+			return fmt.Errorf("writing %q: %w", object.Metadata.Name, err)
+		}
+		base.Resources = append(base.Resources, file)
+	}
+	if err := writeYAML(filepath.Join(dir, BaseDir, kustomizationFile), base); err != nil {
+		return fmt.Errorf("writing base kustomization: %w", err)
+	}
+
+	for i, overlay := range overlays {
+		if err := writeOverlay(filepath.Join(dir, OverlaysDir, overlay), objects, i == 0); err != nil {
+			return fmt.Errorf("overlay %q: %w", overlay, err)
+		}
+	}
+	return nil
+}
+
+func writeOverlay(dir string, objects []kubernetes.Object, current bool) error {
+	k := kustomization{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Resources:  []string{filepath.ToSlash(filepath.Join("..", "..", BaseDir))},
+	}
+	for _, object := range objects {
+		fields, ok := patchFields(object)
+		if !ok {
+			continue
+		}
+		p := map[string]interface{}{
+			"apiVersion": object.APIVersion,
+			"kind":       object.Kind,
+			"metadata": map[string]interface{}{
+				"name": object.Metadata.Name,
+			},
+		}
+		if current {
+			for key, value := range fields {
+				p[key] = value
+			}
+		}
+		file := filepath.Join(patchesDir, fileName(object))
+		if err := writeYAML(filepath.Join(dir, file), p); err != nil {
+			return fmt.Errorf("writing %q patch: %w", object.Metadata.Name, err)
+		}
+		k.Patches = append(k.Patches, patch{Path: filepath.ToSlash(file)})
+	}
+	if current {
+		k.Images = images(objects)
+	}
+	return writeYAML(filepath.Join(dir, kustomizationFile), k)
+}
+
+// patchFields returns the object fields that are likely to be different
+// in each environment.
+func patchFields(object kubernetes.Object) (map[string]interface{}, bool) {
+	switch {
+	case object.APIVersion == "v1" && object.Kind == "Secret":
+		data := make(map[string]interface{}, len(object.Data))
+		for key, value := range object.Data {
+			data[key] = value
+		}
+		return map[string]interface{}{"data": data}, true
+	case object.APIVersion == brokerAPIVersion && strings.HasSuffix(object.Kind, "Broker"):
+		if len(object.Spec) == 0 {
+			return map[string]interface{}{}, true
+		}
+		return map[string]interface{}{"spec": object.Spec}, true
+	}
+	refs, ok := secretRefs(object.Spec)
+	if !ok {
+		return nil, false
+	}
+	return map[string]interface{}{"spec": refs}, true
+}
+
+// secretRefs returns the part of the spec tree that leads to the secret
+// references.
+func secretRefs(value interface{}) (interface{}, bool) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{})
+		for key, item := range v {
+			if _, ok := secretRefKeys[key]; ok {
+				result[key] = item
+				continue
+			}
+			if refs, ok := secretRefs(item); ok {
+				result[key] = refs
+			}
+		}
+		return result, len(result) != 0
+	}
+	return nil, false
+}
+
+// images collects the container images of the objects.
+func images(objects []kubernetes.Object) []image {
+	seen := make(map[string]image)
+	for _, object := range objects {
+		collectImages(object.Spec, seen)
+	}
+	result := make([]image, 0, len(seen))
+	for _, i := range seen {
+		result = append(result, i)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		return result[i].Name < result[j].Name
+	})
+	return result
+}
+
+func collectImages(value interface{}, seen map[string]image) {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, item := range v {
+			if key == "containers" {
+				containers, _ := item.([]interface{})
+				for _, container := range containers {
+					c, _ := container.(map[string]interface{})
+					if ref, ok := c["image"].(string); ok && ref != "" {
+						i := parseImage(ref)
+						seen[i.Name] = i
+					}
+				}
+				continue
+			}
+			collectImages(item, seen)
+		}
+	case []interface{}:
+		for _, item := range v {
+			collectImages(item, seen)
+		}
+	}
+}
+
+// parseImage splits the image reference into the name and the tag or digest.
+func parseImage(ref string) image {
+	if name, digest, ok := strings.Cut(ref, "@"); ok {
+		return image{Name: name, Digest: digest}
+	}
+	if i := strings.LastIndex(ref, ":"); i > strings.LastIndex(ref, "/") {
+		return image{Name: ref[:i], NewTag: ref[i+1:]}
+	}
+	return image{Name: ref}
+}
+
+func validOverlays(overlays []string) error {
+	seen := make(map[string]struct{}, len(overlays))
+	for _, overlay := range overlays {
+		if overlay == "" || overlay == "." || overlay == ".." || strings.ContainsAny(overlay, `/\`) {
+			return fmt.Errorf("invalid overlay name %q", overlay)
+		}
+		if _, ok := seen[overlay]; ok {
+			return fmt.Errorf("duplicate overlay %q", overlay)
+		}
+		seen[overlay] = struct{}{}
+	}
+	return nil
+}
+
+func fileName(object kubernetes.Object) string {
+	return fmt.Sprintf("%s-%s.yaml", strings.ToLower(object.Kind), object.Metadata.Name)
+}
+
+func writeYAML(path string, value interface{}) error {
+	data, err := kyaml.Marshal(value)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, data, os.ModePerm)
+}
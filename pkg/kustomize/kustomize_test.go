@@ -0,0 +1,137 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package kustomize
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"sigs.k8s.io/kustomize/api/krusty"
+	"sigs.k8s.io/kustomize/api/resmap"
+	"sigs.k8s.io/kustomize/kyaml/filesys"
+	kyaml "sigs.k8s.io/yaml"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/test"
+)
+
+func build(t *testing.T, dir string) resmap.ResMap {
+	t.Helper()
+	k := krusty.MakeKustomizer(krusty.MakeDefaultOptions())
+	resources, err := k.Run(filesys.MakeFsOnDisk(), dir)
+	assert.NoError(t, err)
+	return resources
+}
+
+func resource(t *testing.T, resources resmap.ResMap, name string) map[string]interface{} {
+	t.Helper()
+	for _, r := range resources.Resources() {
+		if r.GetName() == name {
+			m, err := r.Map()
+			assert.NoError(t, err)
+			return m
+		}
+	}
+	t.Fatalf("resource %q not found", name)
+	return nil
+}
+
+func TestWrite(t *testing.T) {
+	m := manifest.New(test.Manifest())
+	assert.NoError(t, m.Read())
+	m.Objects[0].Spec = map[string]interface{}{
+		"redis": map[string]interface{}{"address": "redis:6379"},
+	}
+
+	dir := t.TempDir()
+	assert.NoError(t, Write(dir, m.Objects, []string{"dev", "staging", "prod"}))
+
+	base := build(t, filepath.Join(dir, BaseDir))
+	assert.Equal(t, len(m.Objects), base.Size())
+
+	for _, overlay := range []string{"dev", "staging", "prod"} {
+		resources := build(t, filepath.Join(dir, OverlaysDir, overlay))
+		assert.Equal(t, len(m.Objects), resources.Size(), overlay)
+	}
+
+	var k kustomization
+	data, err := os.ReadFile(filepath.Join(dir, OverlaysDir, "dev", kustomizationFile))
+	assert.NoError(t, err)
+	assert.NoError(t, kyaml.Unmarshal(data, &k))
+	assert.Equal(t, []string{"../../base"}, k.Resources)
+	assert.Equal(t, []image{{Name: "docker.io/n3wscott/sockeye", NewTag: "v0.7.0"}}, k.Images)
+	assert.ElementsMatch(t, []patch{
+		{Path: "patches/redisbroker-foo.yaml"},
+		{Path: "patches/secret-foo-awss3source-secret.yaml"},
+		{Path: "patches/awss3source-foo-awss3source.yaml"},
+	}, k.Patches)
+
+	var source kubernetes.Object
+	data, err = os.ReadFile(filepath.Join(dir, OverlaysDir, "dev", "patches", "awss3source-foo-awss3source.yaml"))
+	assert.NoError(t, err)
+	assert.NoError(t, kyaml.Unmarshal(data, &source))
+	assert.Equal(t, map[string]interface{}{
+		"auth": map[string]interface{}{
+			"credentials": map[string]interface{}{
+				"accessKeyID": map[string]interface{}{
+					"valueFromSecret": map[string]interface{}{"key": "accessKeyID", "name": "foo-awss3source-secret"},
+				},
+				"secretAccessKey": map[string]interface{}{
+					"valueFromSecret": map[string]interface{}{"key": "secretAccessKey", "name": "foo-awss3source-secret"},
+				},
+			},
+		},
+	}, source.Spec)
+
+	data, err = os.ReadFile(filepath.Join(dir, OverlaysDir, "staging", "patches", "awss3source-foo-awss3source.yaml"))
+	assert.NoError(t, err)
+	source = kubernetes.Object{}
+	assert.NoError(t, kyaml.Unmarshal(data, &source))
+	assert.Empty(t, source.Spec)
+	assert.Equal(t, "foo-awss3source", source.Metadata.Name)
+
+	// the overlay with the current values renders the same objects as the base
+	dev := build(t, filepath.Join(dir, OverlaysDir, "dev"))
+	for _, name := range []string{"foo", "foo-awss3source-secret", "foo-awss3source", "sockeye"} {
+		assert.Equal(t, resource(t, base, name), resource(t, dev, name), name)
+	}
+}
+
+func TestWriteInvalidOverlays(t *testing.T) {
+	for _, overlays := range [][]string{
+		{""},
+		{"../prod"},
+		{"dev", "dev"},
+	} {
+		assert.Error(t, Write(t.TempDir(), nil, overlays), overlays)
+	}
+}
+
+func TestParseImage(t *testing.T) {
+	testCases := map[string]image{
+		"docker.io/n3wscott/sockeye:v0.7.0": {Name: "docker.io/n3wscott/sockeye", NewTag: "v0.7.0"},
+		"localhost:5000/sockeye":            {Name: "localhost:5000/sockeye"},
+		"localhost:5000/sockeye:latest":     {Name: "localhost:5000/sockeye", NewTag: "latest"},
+		"sockeye@sha256:abc":                {Name: "sockeye", Digest: "sha256:abc"},
+	}
+	for ref, expected := range testCases {
+		assert.Equal(t, expected, parseImage(ref), ref)
+	}
+}
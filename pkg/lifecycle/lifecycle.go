@@ -0,0 +1,184 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package lifecycle writes the machine-readable log of the lifecycle
+// changes for the tools wrapping the CLI. Every event is a single line
+// JSON object appended to the log, event types are stable across versions.
+package lifecycle
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"strconv"
+	"sync"
+	"time"
+)
+
+// EnvEventsLog is the environment variable with the default events log destination.
+const EnvEventsLog = "TMCTL_EVENTS_LOG"
+
+// Type is the lifecycle event type.
+type Type string
+
+const (
+	// ComponentCreated is emitted when the new component is added to the manifest.
+	ComponentCreated Type = "component.created"
+	// ComponentUpdated is emitted when the component manifest object is changed.
+	ComponentUpdated Type = "component.updated"
+	// ComponentDeleted is emitted when the component is removed from the manifest.
+	ComponentDeleted Type = "component.deleted"
+	// ContainerStarted is emitted when the component container is (re)started.
+	ContainerStarted Type = "container.started"
+	// ContainerStopped is emitted when the component container is removed.
+	ContainerStopped Type = "container.stopped"
+	// TriggerAdded is emitted when the new trigger is written to the broker config.
+	TriggerAdded Type = "trigger.added"
+	// TriggerUpdated is emitted when the existing broker config trigger is changed.
+	TriggerUpdated Type = "trigger.updated"
+	// TriggerRemoved is emitted when the trigger is removed from the broker config.
+	TriggerRemoved Type = "trigger.removed"
+	// ManifestWritten is emitted every time the manifest file is written.
+	ManifestWritten Type = "manifest.written"
+	// Error is emitted when the command fails.
+	Error Type = "error"
+	// Summary is emitted at the end of the command with the changes it made.
+	Summary Type = "command.summary"
+)
+
+// Event is the lifecycle log entry.
+type Event struct {
+	Type      Type              `json:"type"`
+	Timestamp time.Time         `json:"timestamp"`
+	Component string            `json:"component,omitempty"`
+	Kind      string            `json:"kind,omitempty"`
+	Details   map[string]string `json:"details,omitempty"`
+}
+
+var (
+	mu  sync.Mutex
+	out io.WriteCloser
+
+	subscribers = make(map[int]subscriber)
+	lastID      int
+)
+
+type subscriber struct {
+	notify func(Event)
+	// capturing subscribers keep the events out of the log
+	capture bool
+}
+
+// Open sets the events log destination. Destination is either
+// the file path, the events are appended to, or the open file
+// descriptor number. Empty destination disables the log.
+func Open(destination string) error {
+	mu.Lock()
+	defer mu.Unlock()
+	if out != nil {
+		out.Close()
+		out = nil
+	}
+	if destination == "" {
+		return nil
+	}
+	if fd, err := strconv.ParseUint(destination, 10, 32); err == nil {
+		file := os.NewFile(uintptr(fd), "fd"+destination)
+		if file == nil {
+			return fmt.Errorf("invalid events log descriptor %q", destination)
+		}
+		out = file
+		return nil
+	}
+	file, err := os.OpenFile(destination, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0o644)
+	if err != nil {
+		return fmt.Errorf("events log: %w", err)
+	}
+	out = file
+	return nil
+}
+
+// SetOutput sets the writer to append the events to.
+func SetOutput(w io.WriteCloser) {
+	mu.Lock()
+	defer mu.Unlock()
+	out = w
+}
+
+// Close closes the events log.
+func Close() error {
+	mu.Lock()
+	defer mu.Unlock()
+	if out == nil {
+		return nil
+	}
+	err := out.Close()
+	out = nil
+	return err
+}
+
+// Subscribe calls the function for every emitted event until the returned
+// cancel function is called. The function must not emit the events itself.
+func Subscribe(notify func(Event)) func() {
+	return subscribe(subscriber{notify: notify})
+}
+
+// Capture is the Subscribe that also keeps the events out of the log,
+// e.g. while planning the changes that are not applied.
+func Capture(notify func(Event)) func() {
+	return subscribe(subscriber{notify: notify, capture: true})
+}
+
+func subscribe(s subscriber) func() {
+	mu.Lock()
+	defer mu.Unlock()
+	lastID++
+	id := lastID
+	subscribers[id] = s
+	return func() {
+		mu.Lock()
+		defer mu.Unlock()
+		delete(subscribers, id)
+	}
+}
+
+// Emit notifies the subscribers and appends the event to the log if it is
+// enabled. Write errors are ignored as the log must not affect the command execution.
+func Emit(eventType Type, component, kind string, details map[string]string) {
+	mu.Lock()
+	defer mu.Unlock()
+	event := Event{
+		Type:      eventType,
+		Timestamp: time.Now().UTC(),
+		Component: component,
+		Kind:      kind,
+		Details:   details,
+	}
+	captured := false
+	for _, s := range subscribers {
+		s.notify(event)
+		captured = captured || s.capture
+	}
+	if out == nil || captured {
+		return
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		return
+	}
+	_, _ = out.Write(append(line, '\n'))
+}
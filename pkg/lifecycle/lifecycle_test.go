@@ -0,0 +1,116 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package lifecycle
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+// TestTypes guards the event types wrappers rely on.
+func TestTypes(t *testing.T) {
+	types := map[Type]string{
+		ComponentCreated: "component.created",
+		ComponentUpdated: "component.updated",
+		ComponentDeleted: "component.deleted",
+		ContainerStarted: "container.started",
+		ContainerStopped: "container.stopped",
+		TriggerAdded:     "trigger.added",
+		TriggerUpdated:   "trigger.updated",
+		TriggerRemoved:   "trigger.removed",
+		ManifestWritten:  "manifest.written",
+		Error:            "error",
+		Summary:          "command.summary",
+	}
+	for eventType, expected := range types {
+		assert.Equal(t, expected, string(eventType))
+	}
+}
+
+func TestEmit(t *testing.T) {
+	// disabled log
+	require.NoError(t, Open(""))
+	Emit(Error, "", "", nil)
+
+	path := filepath.Join(t.TempDir(), "events.log")
+	require.NoError(t, os.WriteFile(path, []byte(`{"type":"error"}`+"\n"), 0o644))
+	require.NoError(t, Open(path))
+	Emit(ComponentCreated, "sockeye", "Service", nil)
+	Emit(ContainerStarted, "sockeye", "", map[string]string{"image": "n3wscott/sockeye"})
+	require.NoError(t, Close())
+	Emit(Error, "", "", nil)
+
+	events := readLog(t, path)
+	require.Len(t, events, 3)
+	assert.Equal(t, Error, events[0].Type)
+	assert.Equal(t, ComponentCreated, events[1].Type)
+	assert.Equal(t, "sockeye", events[1].Component)
+	assert.Equal(t, "Service", events[1].Kind)
+	assert.False(t, events[1].Timestamp.IsZero())
+	assert.Equal(t, "n3wscott/sockeye", events[2].Details["image"])
+}
+
+func TestSubscribe(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "events.log")
+	require.NoError(t, Open(path))
+	defer Close()
+
+	var subscribed, captured []Type
+	cancel := Subscribe(func(e Event) { subscribed = append(subscribed, e.Type) })
+	Emit(ComponentCreated, "sockeye", "Service", nil)
+
+	release := Capture(func(e Event) { captured = append(captured, e.Type) })
+	Emit(ComponentUpdated, "sockeye", "Service", nil)
+	release()
+	cancel()
+	Emit(ComponentDeleted, "sockeye", "Service", nil)
+
+	assert.Equal(t, []Type{ComponentCreated, ComponentUpdated}, subscribed)
+	assert.Equal(t, []Type{ComponentUpdated}, captured)
+	events := readLog(t, path)
+	require.Len(t, events, 2)
+	// captured events are not written to the log
+	assert.Equal(t, ComponentCreated, events[0].Type)
+	assert.Equal(t, ComponentDeleted, events[1].Type)
+}
+
+func TestOpenError(t *testing.T) {
+	assert.Error(t, Open(filepath.Join(t.TempDir(), "missing", "events.log")))
+	assert.NoError(t, Close())
+}
+
+// readLog returns the events from the log file.
+func readLog(t *testing.T, path string) []Event {
+	file, err := os.Open(path)
+	require.NoError(t, err)
+	defer file.Close()
+	var events []Event
+	scanner := bufio.NewScanner(file)
+	for scanner.Scan() {
+		var e Event
+		require.NoError(t, json.Unmarshal(scanner.Bytes(), &e))
+		events = append(events, e)
+	}
+	require.NoError(t, scanner.Err())
+	return events
+}
@@ -0,0 +1,404 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package load
+
+import (
+	"fmt"
+	"net/url"
+	"sort"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+)
+
+const (
+	knativeEventingGroup = "eventing.knative.dev"
+	knativeSourcesGroup  = "sources.knative.dev"
+	knativeServingGroup  = "serving.knative.dev"
+
+	targetsAPIVersion = "targets.triggermesh.io/v1alpha1"
+	externalTarget    = "HTTPTarget"
+)
+
+// Report lists the objects that could not be converted
+// or were converted with the loss of some of their settings.
+type Report []string
+
+func (r *Report) add(object kubernetes.Object, format string, args ...interface{}) {
+	*r = append(*r, fmt.Sprintf("%s %q: %s", object.Kind, object.Metadata.Name, fmt.Sprintf(format, args...)))
+}
+
+// ConvertKnative maps Knative Eventing objects into the local TriggerMesh
+// equivalents: Broker becomes the local broker, Trigger filters become the
+// broker trigger filters, ContainerSources and Knative Services become
+// the producer and consumer services. Subscribers that are not defined in
+// the manifest are replaced with HTTP targets pointing at their addresses.
+// TriggerMesh components are kept as is. Everything else is reported.
+func ConvertKnative(objects []kubernetes.Object) ([]kubernetes.Object, Report, error) {
+	var report Report
+	broker := knativeBrokerName(objects)
+	if broker == "" {
+		return nil, nil, fmt.Errorf("no Knative Broker or Trigger found")
+	}
+	broker = triggermesh.SanitizeName(broker)
+	if err := triggermesh.ValidateName(broker); err != nil {
+		return nil, nil, fmt.Errorf("broker: %w", err)
+	}
+
+	result := []kubernetes.Object{localObject(tmbroker.APIVersion, tmbroker.BrokerKind, broker, broker, nil)}
+	converted := make(map[string]string)
+	externals := make(map[string]string)
+	var triggers []kubernetes.Object
+
+	// services are converted only when some trigger delivers events to them
+	services := make(map[string]kubernetes.Object)
+	for _, object := range objects {
+		if group(object.APIVersion) == knativeServingGroup && object.Kind == "Service" {
+			services[object.Metadata.Name] = object
+		}
+	}
+
+	for _, object := range objects {
+		switch group(object.APIVersion) {
+		case knativeEventingGroup:
+			switch object.Kind {
+			case "Broker":
+				if object.Metadata.Name != broker {
+					report.add(object, "only one broker is supported, its triggers are skipped")
+				}
+			case "Trigger":
+				triggers = append(triggers, object)
+			default:
+				report.add(object, "unknown kind")
+			}
+		case knativeSourcesGroup:
+			if object.Kind != "ContainerSource" {
+				report.add(object, "no local equivalent")
+				continue
+			}
+			source, err := convertContainerSource(object, broker, &report)
+			if err != nil {
+				report.add(object, "%v", err)
+				continue
+			}
+			converted[source.Metadata.Name] = source.Kind
+			result = append(result, source)
+		case knativeServingGroup:
+			// converted along with the triggers
+		case "sources.triggermesh.io", "targets.triggermesh.io", "flow.triggermesh.io", "extensions.triggermesh.io":
+			if _, hasSink := object.Spec["sink"]; hasSink {
+				delete(object.Spec, "sink")
+			}
+			object = localObject(object.APIVersion, object.Kind, object.Metadata.Name, broker, object.Spec)
+			converted[object.Metadata.Name] = object.Kind
+			result = append(result, object)
+		default:
+			if object.Kind == "Secret" && object.APIVersion == "v1" {
+				secret := localObject(object.APIVersion, object.Kind, object.Metadata.Name, broker, nil)
+				secret.Data = object.Data
+				secret.Type = object.Type
+				result = append(result, secret)
+				continue
+			}
+			report.add(object, "unknown kind")
+		}
+	}
+
+	for _, trigger := range triggers {
+		if b, _ := trigger.Spec["broker"].(string); b != "" && triggermesh.SanitizeName(b) != broker {
+			report.add(trigger, "broker %q is not converted", b)
+			continue
+		}
+		filters, err := triggerFilters(trigger.Spec)
+		if err != nil {
+			report.add(trigger, "%v", err)
+			continue
+		}
+		if _, set := trigger.Spec["delivery"]; set {
+			report.add(trigger, "delivery options are not supported and dropped")
+		}
+		subscriber, _ := trigger.Spec["subscriber"].(map[string]interface{})
+		name, kind, apiVersion, err := subscriberTarget(subscriber, converted)
+		if err != nil {
+			report.add(trigger, "%v", err)
+			continue
+		}
+		if name == "" {
+			if svc, exists := services[subscriberRefName(subscriber)]; exists {
+				consumer, err := convertService(svc, broker, &report)
+				if err != nil {
+					report.add(svc, "%v", err)
+					continue
+				}
+				name, kind, apiVersion = consumer.Metadata.Name, consumer.Kind, consumer.APIVersion
+				converted[name] = kind
+				result = append(result, consumer)
+			} else {
+				address := subscriberAddress(subscriber)
+				if address == "" {
+					report.add(trigger, "subscriber is not set")
+					continue
+				}
+				if name = externals[address]; name == "" {
+					name = triggermesh.SanitizeName(trigger.Metadata.Name + "-external")
+					externals[address] = name
+					result = append(result, localObject(targetsAPIVersion, externalTarget, name, broker, map[string]interface{}{
+						"endpoint": address,
+						"method":   "POST",
+					}))
+					if !reachable(address) {
+						report.add(trigger, "subscriber %s is not reachable locally, update the endpoint of the %q target", address, name)
+					}
+				}
+				kind, apiVersion = externalTarget, targetsAPIVersion
+			}
+		}
+		spec := map[string]interface{}{
+			"broker": map[string]interface{}{
+				"kind":  tmbroker.BrokerKind,
+				"name":  broker,
+				"group": "eventing.triggermesh.io",
+			},
+			"target": map[string]interface{}{
+				"ref": map[string]interface{}{
+					"kind":       kind,
+					"name":       name,
+					"apiVersion": apiVersion,
+				},
+			},
+		}
+		if filters != nil {
+			spec["filters"] = filters
+		}
+		result = append(result, localObject(tmbroker.APIVersion, tmbroker.TriggerKind, triggermesh.SanitizeName(trigger.Metadata.Name), broker, spec))
+	}
+	for name, svc := range services {
+		if _, exists := converted[triggermesh.SanitizeName(name)]; !exists {
+			report.add(svc, "not subscribed to the broker, skipped")
+		}
+	}
+	return result, report, nil
+}
+
+func knativeBrokerName(objects []kubernetes.Object) string {
+	for _, object := range objects {
+		if group(object.APIVersion) == knativeEventingGroup && object.Kind == "Broker" {
+			return object.Metadata.Name
+		}
+	}
+	for _, object := range objects {
+		if group(object.APIVersion) == knativeEventingGroup && object.Kind == "Trigger" {
+			if broker, ok := object.Spec["broker"].(string); ok {
+				return broker
+			}
+		}
+	}
+	return ""
+}
+
+// triggerFilters converts Knative attributes filter and the new filters
+// dialect into the single broker filter expression.
+func triggerFilters(spec map[string]interface{}) ([]interface{}, error) {
+	var expressions []interface{}
+	if filter, ok := spec["filter"].(map[string]interface{}); ok {
+		if attributes, ok := filter["attributes"].(map[string]interface{}); ok && len(attributes) != 0 {
+			exact := make(map[string]interface{}, len(attributes))
+			for k, v := range attributes {
+				value, ok := v.(string)
+				if !ok {
+					return nil, fmt.Errorf("filter attribute %q is not a string", k)
+				}
+				exact[k] = value
+			}
+			expressions = append(expressions, map[string]interface{}{"exact": exact})
+		}
+	}
+	if filters, ok := spec["filters"].([]interface{}); ok {
+		for _, f := range filters {
+			if _, cesql := f.(map[string]interface{})["cesql"]; cesql {
+				return nil, fmt.Errorf("CESQL filters are not supported")
+			}
+		}
+		expressions = append(expressions, filters...)
+	}
+	switch len(expressions) {
+	case 0:
+		return nil, nil
+	case 1:
+		return expressions, nil
+	default:
+		return []interface{}{map[string]interface{}{"all": expressions}}, nil
+	}
+}
+
+// subscriberTarget returns the converted component referenced by the subscriber.
+// Empty name is returned if the reference is not resolved.
+func subscriberTarget(subscriber map[string]interface{}, converted map[string]string) (string, string, string, error) {
+	ref, ok := subscriber["ref"].(map[string]interface{})
+	if !ok {
+		return "", "", "", nil
+	}
+	name, _ := ref["name"].(string)
+	kind, _ := ref["kind"].(string)
+	apiVersion, _ := ref["apiVersion"].(string)
+	if group(apiVersion) == knativeEventingGroup {
+		return "", "", "", fmt.Errorf("delivery to %s %q is not supported", kind, name)
+	}
+	name = triggermesh.SanitizeName(name)
+	if convertedKind, exists := converted[name]; exists && convertedKind == kind {
+		return name, kind, apiVersion, nil
+	}
+	return "", "", "", nil
+}
+
+func subscriberRefName(subscriber map[string]interface{}) string {
+	ref, ok := subscriber["ref"].(map[string]interface{})
+	if !ok {
+		return ""
+	}
+	if group, _ := ref["apiVersion"].(string); !strings.HasPrefix(group, knativeServingGroup) {
+		return ""
+	}
+	name, _ := ref["name"].(string)
+	return name
+}
+
+// subscriberAddress returns the subscriber URI or the cluster address
+// of the referenced object.
+func subscriberAddress(subscriber map[string]interface{}) string {
+	address, _ := subscriber["uri"].(string)
+	ref, ok := subscriber["ref"].(map[string]interface{})
+	if !ok {
+		return address
+	}
+	name, _ := ref["name"].(string)
+	if name == "" {
+		return address
+	}
+	namespace, _ := ref["namespace"].(string)
+	if namespace == "" {
+		namespace = "default"
+	}
+	base := fmt.Sprintf("http://%s.%s.svc.cluster.local", name, namespace)
+	if address == "" {
+		return base
+	}
+	// relative URI is resolved against the referenced object address
+	return strings.TrimSuffix(base, "/") + "/" + strings.TrimPrefix(address, "/")
+}
+
+func reachable(address string) bool {
+	u, err := url.Parse(address)
+	if err != nil {
+		return false
+	}
+	host := u.Hostname()
+	return host != "" && !strings.HasSuffix(host, ".cluster.local") && !strings.HasSuffix(host, ".svc") && strings.Contains(host, ".")
+}
+
+func convertContainerSource(object kubernetes.Object, broker string, report *Report) (kubernetes.Object, error) {
+	image, params, err := containerParams(object, report)
+	if err != nil {
+		return kubernetes.Object{}, err
+	}
+	return serviceObject(object.Metadata.Name, image, broker, service.Producer, params)
+}
+
+func convertService(object kubernetes.Object, broker string, report *Report) (kubernetes.Object, error) {
+	image, params, err := containerParams(object, report)
+	if err != nil {
+		return kubernetes.Object{}, err
+	}
+	return serviceObject(object.Metadata.Name, image, broker, service.Consumer, params)
+}
+
+func serviceObject(name, image, broker string, role service.Role, params map[string]string) (kubernetes.Object, error) {
+	name = triggermesh.SanitizeName(name)
+	if err := triggermesh.ValidateComponentName(name, broker); err != nil {
+		return kubernetes.Object{}, err
+	}
+	return service.New(name, image, broker, role, params).AsK8sObject()
+}
+
+// containerParams returns the image and the plain environment variables
+// of the first pod template container.
+func containerParams(object kubernetes.Object, report *Report) (string, map[string]string, error) {
+	template, _ := object.Spec["template"].(map[string]interface{})
+	podSpec, _ := template["spec"].(map[string]interface{})
+	containers, _ := podSpec["containers"].([]interface{})
+	if len(containers) == 0 {
+		return "", nil, fmt.Errorf("pod template has no containers")
+	}
+	if len(containers) > 1 {
+		report.add(object, "only the first container is converted")
+	}
+	container, _ := containers[0].(map[string]interface{})
+	image, _ := container["image"].(string)
+	if image == "" {
+		return "", nil, fmt.Errorf("container image is not set")
+	}
+	for _, key := range []string{"command", "args"} {
+		if _, set := container[key]; set {
+			report.add(object, "container %s is not supported and dropped", key)
+		}
+	}
+	params := make(map[string]string)
+	env, _ := container["env"].([]interface{})
+	for _, e := range env {
+		variable, _ := e.(map[string]interface{})
+		name, _ := variable["name"].(string)
+		value, isString := variable["value"].(string)
+		if name == "" {
+			continue
+		}
+		if !isString {
+			report.add(object, "environment variable %q has no literal value and is dropped", name)
+			continue
+		}
+		params[name] = value
+	}
+	return image, params, nil
+}
+
+func localObject(apiVersion, kind, name, broker string, spec map[string]interface{}) kubernetes.Object {
+	return kubernetes.Object{
+		APIVersion: apiVersion,
+		Kind:       kind,
+		Metadata: kubernetes.Metadata{
+			Name:      triggermesh.SanitizeName(name),
+			Namespace: triggermesh.Namespace,
+			Labels: map[string]string{
+				"triggermesh.io/context": broker,
+			},
+		},
+		Spec: spec,
+	}
+}
+
+func group(apiVersion string) string {
+	return strings.Split(apiVersion, "/")[0]
+}
+
+// String returns the sorted report lines.
+func (r Report) String() string {
+	lines := append([]string{}, r...)
+	sort.Strings(lines)
+	return strings.Join(lines, "\n")
+}
@@ -0,0 +1,178 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package load
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+)
+
+const knativeManifest = `apiVersion: eventing.knative.dev/v1
+kind: Broker
+metadata:
+  name: default
+  namespace: demo
+---
+apiVersion: sources.knative.dev/v1
+kind: ContainerSource
+metadata:
+  name: heartbeats
+spec:
+  template:
+    spec:
+      containers:
+      - image: gcr.io/knative-releases/heartbeats
+        env:
+        - name: PERIOD
+          value: "5"
+        - name: POD_NAME
+          valueFrom:
+            fieldRef:
+              fieldPath: metadata.name
+  sink:
+    ref:
+      apiVersion: eventing.knative.dev/v1
+      kind: Broker
+      name: default
+---
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: display
+spec:
+  template:
+    spec:
+      containers:
+      - image: gcr.io/knative-releases/event-display
+---
+apiVersion: eventing.knative.dev/v1
+kind: Trigger
+metadata:
+  name: heartbeats-display
+spec:
+  broker: default
+  filter:
+    attributes:
+      type: dev.knative.eventing.samples.heartbeat
+  subscriber:
+    ref:
+      apiVersion: serving.knative.dev/v1
+      kind: Service
+      name: display
+---
+apiVersion: eventing.knative.dev/v1
+kind: Trigger
+metadata:
+  name: audit
+spec:
+  broker: default
+  subscriber:
+    uri: https://audit.example.com/events
+---
+apiVersion: eventing.knative.dev/v1
+kind: Trigger
+metadata:
+  name: internal
+spec:
+  broker: default
+  subscriber:
+    ref:
+      apiVersion: v1
+      kind: Service
+      name: internal-api
+      namespace: demo
+---
+apiVersion: sources.knative.dev/v1
+kind: SinkBinding
+metadata:
+  name: binding
+spec:
+  subject:
+    apiVersion: apps/v1
+    kind: Deployment
+    name: producer
+`
+
+func TestConvertKnative(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "knative.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(knativeManifest), 0o644))
+	m := manifest.New(path)
+	require.NoError(t, m.Read())
+
+	objects, report, err := ConvertKnative(m.Objects)
+	require.NoError(t, err)
+
+	byName := make(map[string]kubernetes.Object, len(objects))
+	for _, object := range objects {
+		assert.Equal(t, "default", object.Metadata.Labels["triggermesh.io/context"], object.Metadata.Name)
+		byName[object.Kind+"/"+object.Metadata.Name] = object
+	}
+	assert.Len(t, objects, 8)
+	assert.Contains(t, byName, "RedisBroker/default")
+	assert.Contains(t, byName, "Service/heartbeats")
+	assert.Equal(t, "source", byName["Service/heartbeats"].Metadata.Labels["triggermesh.io/role"])
+	assert.Equal(t, "target", byName["Service/display"].Metadata.Labels["triggermesh.io/role"])
+	assert.Equal(t, "https://audit.example.com/events", byName["HTTPTarget/audit-external"].Spec["endpoint"])
+	assert.Equal(t, "http://internal-api.demo.svc.cluster.local", byName["HTTPTarget/internal-external"].Spec["endpoint"])
+
+	trigger := byName["Trigger/heartbeats-display"]
+	assert.Equal(t, []interface{}{map[string]interface{}{
+		"exact": map[string]interface{}{"type": "dev.knative.eventing.samples.heartbeat"},
+	}}, trigger.Spec["filters"])
+	assert.Equal(t, "display", trigger.Spec["target"].(map[string]interface{})["ref"].(map[string]interface{})["name"])
+	assert.NotContains(t, byName["Trigger/audit"].Spec, "filters")
+
+	assert.Len(t, report, 3)
+	assert.Contains(t, report.String(), `SinkBinding "binding": no local equivalent`)
+	assert.Contains(t, report.String(), `environment variable "POD_NAME"`)
+	assert.Contains(t, report.String(), `Trigger "internal": subscriber http://internal-api.demo.svc.cluster.local is not reachable locally`)
+}
+
+func TestTriggerFilters(t *testing.T) {
+	filters, err := triggerFilters(map[string]interface{}{
+		"filter": map[string]interface{}{
+			"attributes": map[string]interface{}{"type": "foo"},
+		},
+		"filters": []interface{}{
+			map[string]interface{}{"prefix": map[string]interface{}{"source": "bar"}},
+		},
+	})
+	require.NoError(t, err)
+	require.Len(t, filters, 1)
+	assert.Len(t, filters[0].(map[string]interface{})["all"], 2)
+
+	_, err = triggerFilters(map[string]interface{}{
+		"filters": []interface{}{map[string]interface{}{"cesql": "type = 'foo'"}},
+	})
+	assert.Error(t, err)
+
+	filters, err = triggerFilters(map[string]interface{}{})
+	assert.NoError(t, err)
+	assert.Nil(t, filters)
+}
+
+func TestConvertKnativeNoBroker(t *testing.T) {
+	_, _, err := ConvertKnative([]kubernetes.Object{{APIVersion: "v1", Kind: "ConfigMap"}})
+	assert.Error(t, err)
+}
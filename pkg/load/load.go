@@ -19,6 +19,7 @@ package load
 import (
 	"bufio"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"io"
 	"log"
@@ -28,20 +29,54 @@ import (
 
 	"github.com/triggermesh/tmctl/cmd/describe"
 	cliconfig "github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/httpclient"
 	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/sops"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 )
 
+const reportFile = "import-report.txt"
+
 // Import creates the integration from provided YAML manifest.
-func Import(from string, config *cliconfig.Config, crd map[string]crd.CRD) error {
-	m, err := getManifest(from)
+// The manifest encrypted with SOPS is decrypted according to the decrypt mode.
+func Import(from, decrypt string, config *cliconfig.Config, crd map[string]crd.CRD) error {
+	m, err := getManifest(from, decrypt)
 	if err != nil {
 		return fmt.Errorf("manifest %q: %w", from, err)
 	}
+	return importManifest(m, config, crd)
+}
+
+// ImportKnative converts Knative Eventing manifest into the local
+// integration and writes the report of the objects that were not converted.
+func ImportKnative(from, decrypt string, config *cliconfig.Config, crd map[string]crd.CRD) error {
+	m, err := getManifest(from, decrypt)
+	if err != nil {
+		return fmt.Errorf("manifest %q: %w", from, err)
+	}
+	objects, report, err := ConvertKnative(m.Objects)
+	if err != nil {
+		return fmt.Errorf("converting %q: %w", from, err)
+	}
+	m.Objects = objects
+	if err := importManifest(m, config, crd); err != nil {
+		return err
+	}
+	if len(report) == 0 {
+		return nil
+	}
+	reportPath := filepath.Join(filepath.Dir(m.Path), reportFile)
+	if err := os.WriteFile(reportPath, []byte(report.String()+"\n"), os.ModePerm); err != nil {
+		return fmt.Errorf("writing report: %w", err)
+	}
+	fmt.Printf("\nSome objects were not fully converted:\n%s\nReport saved to %s\n", report, reportPath)
+	return nil
+}
 
+func importManifest(m *manifest.Manifest, config *cliconfig.Config, crd map[string]crd.CRD) error {
 	contextName := ""
 	// create broker and its configs first
 	for _, object := range m.Objects {
@@ -60,12 +95,27 @@ func Import(from string, config *cliconfig.Config, crd map[string]crd.CRD) error
 
 	m.Path = filepath.Join(config.ConfigHome, contextName, triggermesh.ManifestFile)
 
+	if config.StrictKinds {
+		if err := components.CheckKinds(m, crd); err != nil {
+			return err
+		}
+	}
+
 	// fill in user input, update broker config
 	for i, object := range m.Objects {
 		component, err := components.GetObject(object.Metadata.Name, config, m, crd)
+		var unsupported *components.UnsupportedKindError
+		if errors.As(err, &unsupported) {
+			// kept as is, the newer CRD set may support it
+			log.Printf("WARNING! Importing %s %q as is: %s", object.Kind, object.Metadata.Name, unsupported.Status())
+			continue
+		}
 		if err != nil {
 			return err
 		}
+		if component == nil {
+			return fmt.Errorf("%s %q: unsupported object", object.Kind, object.Metadata.Name)
+		}
 		filledSpec, err := parseUserInputTags(component.GetName(), component.GetKind(), component.GetSpec())
 		if err != nil {
 			return err
@@ -99,7 +149,8 @@ func Import(from string, config *cliconfig.Config, crd map[string]crd.CRD) error
 	return cliconfig.Set("context", contextName)
 }
 
-func getManifest(from string) (*manifest.Manifest, error) {
+func getManifest(from, decrypt string) (*manifest.Manifest, error) {
+	path := from
 	_, err := os.Stat(from)
 	if os.IsNotExist(err) {
 		tempPath, err := fetch(from)
@@ -107,17 +158,28 @@ func getManifest(from string) (*manifest.Manifest, error) {
 			return nil, err
 		}
 		defer os.Remove(tempPath)
-		m := manifest.New(tempPath)
-		return m, m.Read()
+		path = tempPath
 	} else if err != nil {
 		return nil, err
 	}
-	m := manifest.New(from)
-	return m, m.Read()
+	data, decrypted, err := sops.ReadFile(path, decrypt)
+	if err != nil {
+		return nil, err
+	}
+	m := manifest.New(path)
+	if !decrypted {
+		return m, m.Read()
+	}
+	if decrypt == sops.DecryptAuto {
+		log.Printf("Decrypting %s encrypted with SOPS", from)
+	}
+	// Read would back up the decrypted contents on migration
+	m.Objects, err = manifest.Decode(data)
+	return m, err
 }
 
 func fetch(url string) (string, error) {
-	resp, err := http.Get(url)
+	resp, err := httpclient.Get(url)
 	if err != nil {
 		return "", err
 	}
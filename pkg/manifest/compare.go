@@ -0,0 +1,58 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"bytes"
+	"encoding/json"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+)
+
+// Equal compares the canonical forms of two objects. Provenance annotations,
+// map ordering, empty metadata maps and numeric types of the decoded spec
+// values do not count as differences.
+func Equal(a, b kubernetes.Object) (bool, error) {
+	ca, err := canonical(a)
+	if err != nil {
+		return false, err
+	}
+	cb, err := canonical(b)
+	if err != nil {
+		return false, err
+	}
+	return bytes.Equal(ca, cb), nil
+}
+
+// canonical returns the JSON encoding of the object, which sorts the map keys
+// and encodes the numbers the same way regardless of their Go type.
+func canonical(object kubernetes.Object) ([]byte, error) {
+	object = StripProvenance(object)
+	if len(object.Metadata.Labels) == 0 {
+		object.Metadata.Labels = nil
+	}
+	if len(object.Metadata.Annotations) == 0 {
+		object.Metadata.Annotations = nil
+	}
+	if len(object.Spec) == 0 {
+		object.Spec = nil
+	}
+	if len(object.Data) == 0 {
+		object.Data = nil
+	}
+	return json.Marshal(object)
+}
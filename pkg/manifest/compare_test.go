@@ -0,0 +1,132 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
+	"github.com/triggermesh/tmctl/test"
+)
+
+func TestEqual(t *testing.T) {
+	object := func(spec map[string]interface{}, annotations map[string]string) kubernetes.Object {
+		return kubernetes.Object{
+			APIVersion: "sources.triggermesh.io/v1alpha1",
+			Kind:       "HTTPPollerSource",
+			Metadata: kubernetes.Metadata{
+				Name:        "foo-httppollersource",
+				Labels:      map[string]string{"triggermesh.io/context": "foo"},
+				Annotations: annotations,
+			},
+			Spec: spec,
+		}
+	}
+
+	testCases := map[string]struct {
+		a, b  kubernetes.Object
+		equal bool
+	}{
+		"map ordering": {
+			a: object(map[string]interface{}{
+				"endpoint": "https://example.com",
+				"headers":  map[string]interface{}{"a": "1", "b": "2", "c": "3"},
+			}, nil),
+			b: object(map[string]interface{}{
+				"headers":  map[string]interface{}{"c": "3", "b": "2", "a": "1"},
+				"endpoint": "https://example.com",
+			}, nil),
+			equal: true,
+		},
+		"decoded value types": {
+			a:     object(map[string]interface{}{"interval": 10, "methods": []string{"GET", "POST"}}, nil),
+			b:     object(map[string]interface{}{"interval": float64(10), "methods": []interface{}{"GET", "POST"}}, nil),
+			equal: true,
+		},
+		"provenance annotations": {
+			a: object(map[string]interface{}{"endpoint": "https://example.com"}, nil),
+			b: object(map[string]interface{}{"endpoint": "https://example.com"}, map[string]string{
+				triggermesh.ProvenanceVersionAnnotation:  "v1.0.0",
+				triggermesh.ProvenanceCreatedAnnotation:  "2023-01-02T03:04:05Z",
+				triggermesh.ProvenanceModifiedAnnotation: "2023-01-02T04:04:05Z",
+			}),
+			equal: true,
+		},
+		"empty spec": {
+			a:     object(nil, map[string]string{}),
+			b:     object(map[string]interface{}{}, nil),
+			equal: true,
+		},
+		"changed spec value": {
+			a:     object(map[string]interface{}{"headers": map[string]interface{}{"a": "1"}}, nil),
+			b:     object(map[string]interface{}{"headers": map[string]interface{}{"a": "2"}}, nil),
+			equal: false,
+		},
+		"changed list order": {
+			a:     object(map[string]interface{}{"methods": []string{"GET", "POST"}}, nil),
+			b:     object(map[string]interface{}{"methods": []string{"POST", "GET"}}, nil),
+			equal: false,
+		},
+		"changed annotation": {
+			a:     object(nil, map[string]string{triggermesh.ReplicasAnnotation: "2"}),
+			b:     object(nil, map[string]string{triggermesh.ReplicasAnnotation: "3"}),
+			equal: false,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			equal, err := Equal(tc.a, tc.b)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.equal, equal)
+		})
+	}
+}
+
+func TestApply(t *testing.T) {
+	data, err := os.ReadFile(test.Manifest())
+	require.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	require.NoError(t, os.WriteFile(path, data, os.ModePerm))
+
+	m := New(path)
+	require.NoError(t, m.Read())
+	params := map[string]string{"b": "2", "a": "1"}
+	result, err := m.Apply(service.New("test-service", "triggermesh/image", "foo", service.Consumer, params))
+	assert.NoError(t, err)
+	assert.Equal(t, Created, result)
+
+	// the object decoded from the file is compared with the new one
+	m = New(path)
+	require.NoError(t, m.Read())
+	m.Version = "v1.1.0"
+	result, err = m.Apply(service.New("test-service", "triggermesh/image", "foo", service.Consumer, map[string]string{"a": "1", "b": "2"}))
+	assert.NoError(t, err)
+	assert.Equal(t, Unchanged, result)
+
+	result, err = m.Apply(service.New("test-service", "triggermesh/image", "foo", service.Consumer, map[string]string{"a": "1"}))
+	assert.NoError(t, err)
+	assert.Equal(t, Updated, result)
+	assert.Equal(t, "updated", result.String())
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"crypto/sha256"
+	"fmt"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+// ChangeType is the kind of the object difference between two manifests.
+type ChangeType string
+
+const (
+	Added   ChangeType = "added"
+	Removed ChangeType = "removed"
+	Changed ChangeType = "changed"
+)
+
+// Change is the difference of a single object.
+type Change struct {
+	Type ChangeType
+	Kind string
+	Name string
+	// Diff is the unified diff of the object YAML, set for the changed objects.
+	Diff string
+}
+
+// Diff compares the objects of two manifests and returns the list of changes
+// required to turn the first list into the second one. Context labels are
+// ignored and Secret values are compared by their hashes, so that the
+// result can be printed without disclosing them.
+func Diff(from, to []kubernetes.Object) ([]Change, error) {
+	var changes []Change
+	for _, f := range from {
+		if _, exists := find(to, f); !exists {
+			changes = append(changes, Change{Type: Removed, Kind: f.Kind, Name: f.Metadata.Name})
+		}
+	}
+	for _, t := range to {
+		f, exists := find(from, t)
+		if !exists {
+			changes = append(changes, Change{Type: Added, Kind: t.Kind, Name: t.Metadata.Name})
+			continue
+		}
+		a, err := render(f)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", f.Metadata.Name, err)
+		}
+		b, err := render(t)
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", t.Metadata.Name, err)
+		}
+		if a == b {
+			continue
+		}
+		diff, err := difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+			A:        difflib.SplitLines(a),
+			B:        difflib.SplitLines(b),
+			FromFile: "current",
+			ToFile:   "other",
+			Context:  3,
+		})
+		if err != nil {
+			return nil, fmt.Errorf("%q: %w", t.Metadata.Name, err)
+		}
+		changes = append(changes, Change{Type: Changed, Kind: t.Kind, Name: t.Metadata.Name, Diff: diff})
+	}
+	return changes, nil
+}
+
+func find(objects []kubernetes.Object, object kubernetes.Object) (kubernetes.Object, bool) {
+	for _, o := range objects {
+		if matchObjects(o, object) {
+			return o, true
+		}
+	}
+	return kubernetes.Object{}, false
+}
+
+func render(object kubernetes.Object) (string, error) {
+	var labels map[string]string
+	for k, v := range object.Metadata.Labels {
+		if k == triggermesh.ContextLabel {
+			continue
+		}
+		if labels == nil {
+			labels = make(map[string]string, len(object.Metadata.Labels))
+		}
+		labels[k] = v
+	}
+	object.Metadata.Labels = labels
+	if len(object.Data) != 0 {
+		data := make(map[string]string, len(object.Data))
+		for k, v := range object.Data {
+			sum := sha256.Sum256([]byte(v))
+			data[k] = fmt.Sprintf("<sha256:%x>", sum[:6])
+		}
+		object.Data = data
+	}
+	out, err := yaml.Marshal(object)
+	return string(out), err
+}
@@ -0,0 +1,80 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/test"
+)
+
+func TestDiff(t *testing.T) {
+	current := New(test.Manifest())
+	assert.NoError(t, current.Read())
+	other := New(test.Manifest())
+	assert.NoError(t, other.Read())
+
+	changes, err := Diff(current.Objects, other.Objects)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	// context label is not compared
+	for i := range other.Objects {
+		if other.Objects[i].Metadata.Labels == nil {
+			other.Objects[i].Metadata.Labels = make(map[string]string)
+		}
+		other.Objects[i].Metadata.Labels[triggermesh.ContextLabel] = "bar"
+	}
+	changes, err = Diff(current.Objects, other.Objects)
+	assert.NoError(t, err)
+	assert.Empty(t, changes)
+
+	var secret *kubernetes.Object
+	for i, o := range other.Objects {
+		if o.Kind == "Secret" {
+			secret = &other.Objects[i]
+		}
+	}
+	assert.NotNil(t, secret)
+	for k := range secret.Data {
+		secret.Data[k] = "bmV3LXNlY3JldC12YWx1ZQ=="
+	}
+	removed := other.Objects[len(other.Objects)-1]
+	other.Objects = append(other.Objects[:len(other.Objects)-1], kubernetes.Object{
+		APIVersion: "v1",
+		Kind:       "Service",
+		Metadata:   kubernetes.Metadata{Name: "new-service"},
+	})
+
+	changes, err = Diff(current.Objects, other.Objects)
+	assert.NoError(t, err)
+	assert.Len(t, changes, 3)
+	types := make(map[ChangeType]Change, len(changes))
+	for _, c := range changes {
+		types[c.Type] = c
+	}
+	assert.Equal(t, removed.Metadata.Name, types[Removed].Name)
+	assert.Equal(t, "new-service", types[Added].Name)
+	assert.Equal(t, secret.Metadata.Name, types[Changed].Name)
+	assert.Contains(t, types[Changed].Diff, "<sha256:")
+	assert.False(t, strings.Contains(types[Changed].Diff, "bmV3LXNlY3JldC12YWx1ZQ=="), "secret value is disclosed")
+}
@@ -17,16 +17,18 @@ limitations under the License.
 package manifest
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"os"
-	"reflect"
+	"strings"
 	"sync"
 
 	"gopkg.in/yaml.v3"
 	kyaml "sigs.k8s.io/yaml"
 
 	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 )
 
@@ -35,6 +37,17 @@ type Manifest struct {
 	mut     sync.Mutex
 	Path    string
 	Objects []kubernetes.Object
+
+	// Version and Command of the CLI are recorded
+	// in the provenance annotations of the added objects.
+	Version string
+	Command string
+
+	// Migrations applied on Read to the objects of the manifest
+	// written by the older CLI version.
+	Migrations []Migration
+	// Backup is the copy of the manifest file made before the migration.
+	Backup string
 }
 
 func New(path string) *Manifest {
@@ -46,19 +59,37 @@ func New(path string) *Manifest {
 func (m *Manifest) Read() error {
 	m.mut.Lock()
 	defer m.mut.Unlock()
-	o, err := parseYAML(m.Path)
+	data, err := os.ReadFile(m.Path)
 	if err != nil {
 		if os.IsNotExist(err) {
 			return fmt.Errorf("manifest does not exist, please create the broker")
 		}
 		return err
 	}
+	o, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return err
+	}
+	version := fileVersion(data)
 	m.Objects = o
+	m.Migrations = migrate(m.Objects, version)
+	m.Backup = ""
+	if len(m.Migrations) != 0 {
+		m.Backup = backupPath(m.Path, version)
+		if _, err := os.Stat(m.Backup); os.IsNotExist(err) {
+			if err := os.WriteFile(m.Backup, data, os.ModePerm); err != nil {
+				return fmt.Errorf("manifest backup: %w", err)
+			}
+		}
+	}
 	return nil
 }
 
+// Write stores the objects in the manifest file in the canonical order,
+// so that the file content does not depend on the order of the changes.
 func (m *Manifest) Write() error {
-	var output []byte
+	Sort(m.Objects)
+	output := []byte(fmt.Sprintf("%s%d\n", versionMarker, CurrentVersion))
 	for _, object := range m.Objects {
 		body, err := kyaml.Marshal(object)
 		if err != nil {
@@ -70,28 +101,105 @@ func (m *Manifest) Write() error {
 	if err := os.WriteFile(m.Path, output, os.ModePerm); err != nil {
 		return err
 	}
+	lifecycle.Emit(lifecycle.ManifestWritten, "", "", map[string]string{"path": m.Path})
 	return nil
 }
 
+// Result is the outcome of adding the object to the manifest.
+type Result int
+
+const (
+	// Unchanged means that the identical object is already in the manifest.
+	Unchanged Result = iota
+	// Created means that the object is new to the manifest.
+	Created
+	// Updated means that the existing object has been replaced.
+	Updated
+)
+
+func (r Result) String() string {
+	switch r {
+	case Created:
+		return "created"
+	case Updated:
+		return "updated"
+	}
+	return "unchanged"
+}
+
+// Add writes the object to the manifest and reports
+// whether it has been created or changed.
 func (m *Manifest) Add(object triggermesh.Component) (bool, error) {
+	result, err := m.Apply(object)
+	return result != Unchanged, err
+}
+
+// Apply writes the object to the manifest and returns the result of the
+// operation. Objects are compared in their canonical form, so that re-running
+// the same command does not count as a change.
+func (m *Manifest) Apply(object triggermesh.Component) (Result, error) {
 	m.mut.Lock()
 	defer m.mut.Unlock()
 	k8sObject, err := object.AsK8sObject()
 	if err != nil {
-		return false, fmt.Errorf("creating k8s object: %w", err)
+		return Unchanged, fmt.Errorf("creating k8s object: %w", err)
 	}
 	k8sObject.Metadata.Namespace = "" // local manifest should not set namespace
 	for i, o := range m.Objects {
 		if matchObjects(k8sObject, o) {
-			if reflect.DeepEqual(k8sObject, o) {
-				return false, nil
+			preserveAnnotations(&k8sObject, o)
+			equal, err := Equal(k8sObject, o)
+			if err != nil {
+				return Unchanged, fmt.Errorf("comparing objects: %w", err)
 			}
+			if equal {
+				return Unchanged, nil
+			}
+			m.stampModified(&k8sObject)
 			m.Objects[i] = k8sObject
-			return true, m.Write()
+			if err := m.Write(); err != nil {
+				return Unchanged, err
+			}
+			lifecycle.Emit(lifecycle.ComponentUpdated, k8sObject.Metadata.Name, k8sObject.Kind, nil)
+			return Updated, nil
 		}
 	}
+	m.stampCreated(&k8sObject)
 	m.Objects = append(m.Objects, k8sObject)
-	return true, m.Write()
+	if err := m.Write(); err != nil {
+		return Unchanged, err
+	}
+	lifecycle.Emit(lifecycle.ComponentCreated, k8sObject.Metadata.Name, k8sObject.Kind, nil)
+	return Created, nil
+}
+
+// Annotate sets the annotation on the manifest object.
+// Empty value removes the annotation. Kind is matched case-insensitively
+// since some components report their kind in the lowercase CRD form.
+func (m *Manifest) Annotate(name, kind, key, value string) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	for i, o := range m.Objects {
+		if o.Metadata.Name != name || !strings.EqualFold(o.Kind, kind) {
+			continue
+		}
+		if current, set := o.Metadata.Annotations[key]; current == value && (set || value == "") {
+			return nil
+		}
+		if value == "" {
+			delete(m.Objects[i].Metadata.Annotations, key)
+			if len(m.Objects[i].Metadata.Annotations) == 0 {
+				m.Objects[i].Metadata.Annotations = nil
+			}
+			return m.Write()
+		}
+		if m.Objects[i].Metadata.Annotations == nil {
+			m.Objects[i].Metadata.Annotations = make(map[string]string)
+		}
+		m.Objects[i].Metadata.Annotations[key] = value
+		return m.Write()
+	}
+	return fmt.Errorf("object %q not found", name)
 }
 
 func (m *Manifest) Remove(name, kind string) error {
@@ -104,19 +212,70 @@ func (m *Manifest) Remove(name, kind string) error {
 		}
 		objects = append(objects, o)
 	}
+	removed := len(objects) != len(m.Objects)
 	m.Objects = objects
+	if err := m.Write(); err != nil {
+		return err
+	}
+	if removed {
+		lifecycle.Emit(lifecycle.ComponentDeleted, name, kind, nil)
+	}
+	return nil
+}
+
+// Object returns the copy of the manifest object. Kind is matched
+// case-insensitively, as in Annotate.
+func (m *Manifest) Object(name, kind string) (kubernetes.Object, bool, error) {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	for _, o := range m.Objects {
+		if o.Metadata.Name != name || !strings.EqualFold(o.Kind, kind) {
+			continue
+		}
+		// the objects maps are changed in place by Annotate
+		data, err := kyaml.Marshal(o)
+		if err != nil {
+			return kubernetes.Object{}, false, err
+		}
+		var object kubernetes.Object
+		if err := kyaml.Unmarshal(data, &object); err != nil {
+			return kubernetes.Object{}, false, err
+		}
+		return object, true, nil
+	}
+	return kubernetes.Object{}, false, nil
+}
+
+// Restore puts the object copy back into the manifest as it is,
+// replacing the object of the same name and kind, e.g. to revert
+// the failed change of the object.
+func (m *Manifest) Restore(object kubernetes.Object) error {
+	m.mut.Lock()
+	defer m.mut.Unlock()
+	for i, o := range m.Objects {
+		if o.Metadata.Name == object.Metadata.Name && strings.EqualFold(o.Kind, object.Kind) {
+			m.Objects[i] = object
+			return m.Write()
+		}
+	}
+	m.Objects = append(m.Objects, object)
 	return m.Write()
 }
 
-func parseYAML(path string) ([]kubernetes.Object, error) {
-	file, err := os.Open(path)
+// Decode returns the objects of the manifest file contents migrated
+// to the current version in memory. Unlike Read, it makes no backups.
+func Decode(data []byte) ([]kubernetes.Object, error) {
+	objects, err := decode(bytes.NewReader(data))
 	if err != nil {
 		return nil, err
 	}
-	defer file.Close()
+	migrate(objects, fileVersion(data))
+	return objects, nil
+}
 
+func decode(reader io.Reader) ([]kubernetes.Object, error) {
 	var result []kubernetes.Object
-	decoder := yaml.NewDecoder(file)
+	decoder := yaml.NewDecoder(reader)
 	for {
 		o := new(kubernetes.Object)
 		err := decoder.Decode(&o)
@@ -134,6 +293,32 @@ func parseYAML(path string) ([]kubernetes.Object, error) {
 	return result, nil
 }
 
+// preserveAnnotations copies the annotations that are set by
+// the CLI commands rather than the components themselves.
+func preserveAnnotations(new *kubernetes.Object, old kubernetes.Object) {
+	for _, key := range append([]string{
+		triggermesh.ReplicasAnnotation,
+		triggermesh.OwnerAnnotation,
+		triggermesh.LogLevelAnnotation,
+		triggermesh.EventTypeOriginAnnotation,
+		triggermesh.AdapterVersionAnnotation,
+		triggermesh.CredentialsProfileAnnotation,
+		triggermesh.CredentialsExpiryAnnotation,
+		triggermesh.CredentialsRefreshAnnotation,
+	}, triggermesh.ProvenanceAnnotations...) {
+		value, set := old.Metadata.Annotations[key]
+		if !set {
+			continue
+		}
+		if new.Metadata.Annotations == nil {
+			new.Metadata.Annotations = make(map[string]string)
+		}
+		if _, set := new.Metadata.Annotations[key]; !set {
+			new.Metadata.Annotations[key] = value
+		}
+	}
+}
+
 func matchObjects(a, b kubernetes.Object) bool {
 	return (a.APIVersion == b.APIVersion) &&
 		(a.Kind == b.Kind) &&
@@ -0,0 +1,201 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"strconv"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+)
+
+// CurrentVersion is the version of the manifest format written by this CLI.
+const CurrentVersion = 2
+
+// versionMarker is the comment on the first line of the manifest file,
+// manifests without the marker are written by the older CLI versions.
+const versionMarker = "# manifestVersion: "
+
+// Migration is the object rewrite made by the manifest migration.
+type Migration struct {
+	Version     int
+	Kind        string
+	Name        string
+	Description string
+}
+
+func (c Migration) String() string {
+	return fmt.Sprintf("v%d: %s %q: %s", c.Version, c.Kind, c.Name, c.Description)
+}
+
+type step struct {
+	version     int
+	description string
+	// apply rewrites the object in place and reports if it was changed.
+	apply func(object *kubernetes.Object) bool
+}
+
+// migrations are applied in order to the objects
+// of the manifests older than the migration version.
+var steps = []step{
+	{
+		version:     1,
+		description: "Knative trigger filter and subscriber converted to the filters and target",
+		apply:       migrateTriggerShape,
+	},
+	{
+		version:     2,
+		description: "tmcli label and annotation keys renamed",
+		apply:       migrateLegacyKeys,
+	},
+	{
+		version:     2,
+		description: "service role label renamed",
+		apply:       migrateServiceRole,
+	},
+}
+
+// Plan returns the changes that the migration would make to the manifest file
+// and the format version of the file.
+func Plan(path string) ([]Migration, int, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, 0, err
+	}
+	objects, err := decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, 0, err
+	}
+	version := fileVersion(data)
+	return migrate(objects, version), version, nil
+}
+
+// migrate applies the migrations newer than the version to the objects.
+func migrate(objects []kubernetes.Object, version int) []Migration {
+	var changes []Migration
+	for _, m := range steps {
+		if m.version <= version {
+			continue
+		}
+		for i := range objects {
+			if m.apply(&objects[i]) {
+				changes = append(changes, Migration{
+					Version:     m.version,
+					Kind:        objects[i].Kind,
+					Name:        objects[i].Metadata.Name,
+					Description: m.description,
+				})
+			}
+		}
+	}
+	return changes
+}
+
+// backupPath returns the path of the manifest copy made before the migration.
+func backupPath(path string, version int) string {
+	return fmt.Sprintf("%s.v%d.bak", path, version)
+}
+
+func fileVersion(data []byte) int {
+	line, _, _ := bufio.NewReader(bytes.NewReader(data)).ReadLine()
+	if !strings.HasPrefix(string(line), versionMarker) {
+		return 0
+	}
+	version, err := strconv.Atoi(strings.TrimSpace(strings.TrimPrefix(string(line), versionMarker)))
+	if err != nil {
+		return 0
+	}
+	return version
+}
+
+// migrateTriggerShape converts the broker triggers written in the Knative
+// Eventing shape with the single attributes filter and the subscriber.
+// Knative Eventing objects themselves are left for the import conversion.
+func migrateTriggerShape(object *kubernetes.Object) bool {
+	if object.Kind != "Trigger" || object.APIVersion != "eventing.triggermesh.io/v1alpha1" || object.Spec == nil {
+		return false
+	}
+	changed := false
+	if broker, ok := object.Spec["broker"].(string); ok {
+		object.Spec["broker"] = map[string]interface{}{
+			"name":  broker,
+			"kind":  "RedisBroker",
+			"group": "eventing.triggermesh.io",
+		}
+		changed = true
+	}
+	if filter, ok := object.Spec["filter"].(map[string]interface{}); ok {
+		delete(object.Spec, "filter")
+		if attributes, ok := filter["attributes"].(map[string]interface{}); ok && len(attributes) != 0 {
+			object.Spec["filters"] = []interface{}{
+				map[string]interface{}{"exact": attributes},
+			}
+		}
+		changed = true
+	}
+	if subscriber, ok := object.Spec["subscriber"]; ok {
+		delete(object.Spec, "subscriber")
+		if _, set := object.Spec["target"]; !set {
+			object.Spec["target"] = subscriber
+		}
+		changed = true
+	}
+	return changed
+}
+
+// migrateLegacyKeys renames the labels and annotations
+// set by tmcli with its own key prefix.
+func migrateLegacyKeys(object *kubernetes.Object) bool {
+	const legacyPrefix = "tmcli.triggermesh.io/"
+	changed := false
+	for _, keys := range []map[string]string{object.Metadata.Labels, object.Metadata.Annotations} {
+		for key, value := range keys {
+			if !strings.HasPrefix(key, legacyPrefix) {
+				continue
+			}
+			current := "triggermesh.io/" + strings.TrimPrefix(key, legacyPrefix)
+			if _, set := keys[current]; !set {
+				keys[current] = value
+			}
+			delete(keys, key)
+			changed = true
+		}
+	}
+	return changed
+}
+
+// migrateServiceRole renames the producer and consumer service roles.
+func migrateServiceRole(object *kubernetes.Object) bool {
+	const roleLabel = "triggermesh.io/role"
+	roles := map[string]string{
+		"producer": "source",
+		"consumer": "target",
+	}
+	role, set := object.Metadata.Labels[roleLabel]
+	if !set {
+		return false
+	}
+	if current, old := roles[role]; old {
+		object.Metadata.Labels[roleLabel] = current
+		return true
+	}
+	return false
+}
@@ -0,0 +1,123 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/test"
+)
+
+func legacyCopy(t *testing.T, version int) string {
+	data, err := os.ReadFile(test.LegacyManifest(version))
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	assert.NoError(t, os.WriteFile(path, data, os.ModePerm))
+	return path
+}
+
+func TestPlan(t *testing.T) {
+	testCases := []struct {
+		path    string
+		version int
+		changes []string
+	}{
+		{
+			path:    test.LegacyManifest(0),
+			version: 0,
+			changes: []string{
+				`v1: Trigger "foo-trigger-9dad7875": Knative trigger filter and subscriber converted to the filters and target`,
+				`v2: RedisBroker "foo": tmcli label and annotation keys renamed`,
+				`v2: Service "sockeye": tmcli label and annotation keys renamed`,
+				`v2: Service "foo-producer-service": service role label renamed`,
+				`v2: Service "sockeye": service role label renamed`,
+			},
+		}, {
+			path:    test.LegacyManifest(1),
+			version: 1,
+			changes: []string{
+				`v2: Service "sockeye": service role label renamed`,
+			},
+		}, {
+			path:    test.Manifest(),
+			version: 0,
+		},
+	}
+	for _, tc := range testCases {
+		t.Run(filepath.Base(tc.path), func(t *testing.T) {
+			changes, version, err := Plan(tc.path)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.version, version)
+			var result []string
+			for _, c := range changes {
+				result = append(result, c.String())
+			}
+			assert.Equal(t, tc.changes, result)
+		})
+	}
+}
+
+func TestMigrate(t *testing.T) {
+	path := legacyCopy(t, 0)
+	original, err := os.ReadFile(path)
+	assert.NoError(t, err)
+
+	m := New(path)
+	assert.NoError(t, m.Read())
+	assert.Len(t, m.Migrations, 5)
+	assert.Equal(t, path+".v0.bak", m.Backup)
+
+	backup, err := os.ReadFile(m.Backup)
+	assert.NoError(t, err)
+	assert.Equal(t, original, backup)
+
+	trigger := m.Objects[3]
+	assert.Equal(t, map[string]interface{}{
+		"name":  "foo",
+		"kind":  "RedisBroker",
+		"group": "eventing.triggermesh.io",
+	}, trigger.Spec["broker"])
+	assert.Equal(t, []interface{}{
+		map[string]interface{}{"exact": map[string]interface{}{"type": "com.example.sample"}},
+	}, trigger.Spec["filters"])
+	assert.NotContains(t, trigger.Spec, "filter")
+	assert.NotContains(t, trigger.Spec, "subscriber")
+	assert.Contains(t, trigger.Spec, "target")
+
+	assert.Equal(t, "foo", m.Objects[0].Metadata.Labels["triggermesh.io/context"])
+	assert.NotContains(t, m.Objects[0].Metadata.Labels, "tmcli.triggermesh.io/context")
+	assert.Equal(t, "foo", m.Objects[2].Metadata.Annotations["triggermesh.io/owner"])
+	assert.Equal(t, "source", m.Objects[1].Metadata.Labels["triggermesh.io/role"])
+	assert.Equal(t, "target", m.Objects[2].Metadata.Labels["triggermesh.io/role"])
+
+	assert.NoError(t, m.Write())
+	data, err := os.ReadFile(path)
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(data), "# manifestVersion: 2\n"))
+
+	// migrated manifest is read as is
+	m = New(path)
+	assert.NoError(t, m.Read())
+	assert.Empty(t, m.Migrations)
+	assert.Empty(t, m.Backup)
+	assert.Len(t, m.Objects, 4)
+}
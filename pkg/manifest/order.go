@@ -0,0 +1,81 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+)
+
+// serviceRoleLabel tells whether the service component is a source or a target.
+const serviceRoleLabel = "triggermesh.io/role"
+
+// kind precedence of the manifest objects
+const (
+	rankBroker = iota
+	rankSource
+	rankTransformation
+	rankTarget
+	rankTrigger
+	rankUnknown
+)
+
+// Sort puts the objects in the canonical order: brokers, sources,
+// transformations, targets and triggers, each group sorted by name.
+// Objects of the other kinds follow them in their original order.
+func Sort(objects []kubernetes.Object) {
+	sort.SliceStable(objects, func(i, j int) bool {
+		ri, rj := rank(objects[i]), rank(objects[j])
+		if ri != rj {
+			return ri < rj
+		}
+		if ri == rankUnknown {
+			return false
+		}
+		if objects[i].Metadata.Name != objects[j].Metadata.Name {
+			return objects[i].Metadata.Name < objects[j].Metadata.Name
+		}
+		return objects[i].Kind < objects[j].Kind
+	})
+}
+
+func rank(object kubernetes.Object) int {
+	group := strings.SplitN(object.APIVersion, "/", 2)[0]
+	switch group {
+	case "eventing.triggermesh.io":
+		if object.Kind == "Trigger" {
+			return rankTrigger
+		}
+		return rankBroker
+	case "sources.triggermesh.io":
+		return rankSource
+	case "flow.triggermesh.io":
+		return rankTransformation
+	case "targets.triggermesh.io":
+		return rankTarget
+	case "serving.knative.dev":
+		switch object.Metadata.Labels[serviceRoleLabel] {
+		case "source":
+			return rankSource
+		case "target":
+			return rankTarget
+		}
+	}
+	return rankUnknown
+}
@@ -0,0 +1,122 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/test"
+)
+
+// object is the manifest component built from the raw object.
+type object struct {
+	kubernetes.Object
+}
+
+func (o object) AsK8sObject() (kubernetes.Object, error) { return o.Object, nil }
+func (o object) GetName() string                         { return o.Metadata.Name }
+func (o object) GetKind() string                         { return o.Kind }
+func (o object) GetAPIVersion() string                   { return o.APIVersion }
+func (o object) GetSpec() map[string]interface{}         { return o.Spec }
+func (o object) SetSpec(spec map[string]interface{})     { o.Spec = spec }
+
+func newObject(apiVersion, kind, name string, labels map[string]string, spec map[string]interface{}) object {
+	o := object{}
+	o.APIVersion = apiVersion
+	o.Kind = kind
+	o.Metadata.Name = name
+	o.Metadata.Labels = map[string]string{triggermesh.ContextLabel: "foo"}
+	for k, v := range labels {
+		o.Metadata.Labels[k] = v
+	}
+	o.Spec = spec
+	return o
+}
+
+func TestCanonicalOrder(t *testing.T) {
+	created := time.Date(2023, 1, 2, 3, 4, 5, 0, time.UTC)
+	now = func() time.Time { return created }
+	defer func() { now = time.Now }()
+
+	broker := newObject("eventing.triggermesh.io/v1alpha1", "RedisBroker", "foo", nil, nil)
+	webhook := newObject("sources.triggermesh.io/v1alpha1", "WebhookSource", "foo-webhooksource", nil,
+		map[string]interface{}{"eventType": "order.created", "sink": map[string]interface{}{"uri": "http://host.docker.internal:8080"}})
+	s3 := newObject("sources.triggermesh.io/v1alpha1", "AWSS3Source", "bar-awss3source", nil,
+		map[string]interface{}{"arn": "arn:aws:s3:::bar"})
+	service := newObject("serving.knative.dev/v1", "Service", "sockeye", map[string]string{serviceRoleLabel: "target"},
+		map[string]interface{}{"template": map[string]interface{}{"spec": map[string]interface{}{}}})
+	transformation := newObject("flow.triggermesh.io/v1alpha1", "Transformation", "foo-transformation", nil,
+		map[string]interface{}{"data": []interface{}{map[string]interface{}{"operation": "add"}}})
+	target := newObject("targets.triggermesh.io/v1alpha1", "HTTPTarget", "foo-httptarget", nil,
+		map[string]interface{}{"endpoint": "https://example.com", "method": "POST"})
+	trigger := newObject("eventing.triggermesh.io/v1alpha1", "Trigger", "foo-trigger-a", nil,
+		map[string]interface{}{"broker": map[string]interface{}{"kind": "RedisBroker", "name": "foo"}})
+	otherTrigger := newObject("eventing.triggermesh.io/v1alpha1", "Trigger", "foo-trigger-b", nil,
+		map[string]interface{}{"broker": map[string]interface{}{"kind": "RedisBroker", "name": "foo"}})
+	secret := newObject("v1", "Secret", "zzz-secret", nil, nil)
+	function := newObject("extensions.triggermesh.io/v1alpha1", "Function", "aaa-function", nil,
+		map[string]interface{}{"runtime": "python"})
+
+	expected, err := os.ReadFile(test.CanonicalManifest())
+	require.NoError(t, err)
+
+	type step struct {
+		remove bool
+		object object
+	}
+	add := func(o object) step { return step{object: o} }
+	remove := func(o object) step { return step{remove: true, object: o} }
+
+	for name, steps := range map[string][]step{
+		"in order": {
+			add(broker), add(s3), add(webhook), add(transformation), add(service), add(target),
+			add(trigger), add(otherTrigger), add(secret), add(function),
+		},
+		"reversed": {
+			add(otherTrigger), add(trigger), add(target), add(service), add(secret), add(transformation),
+			add(function), add(webhook), add(s3), add(broker),
+		},
+		"add remove add": {
+			add(trigger), add(target), add(secret), add(broker), remove(trigger), add(webhook),
+			add(otherTrigger), remove(broker), add(service), add(s3), add(trigger), add(function),
+			remove(target), add(transformation), add(broker), add(target),
+		},
+	} {
+		t.Run(name, func(t *testing.T) {
+			m := New(filepath.Join(t.TempDir(), "manifest.yaml"))
+			for _, s := range steps {
+				if s.remove {
+					require.NoError(t, m.Remove(s.object.GetName(), s.object.GetKind()))
+					continue
+				}
+				_, err := m.Apply(s.object)
+				require.NoError(t, err)
+			}
+			actual, err := os.ReadFile(m.Path)
+			require.NoError(t, err)
+			assert.Equal(t, string(expected), string(actual))
+		})
+	}
+}
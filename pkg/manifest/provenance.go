@@ -0,0 +1,142 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package manifest
+
+import (
+	"net/url"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+const redacted = "<redacted>"
+
+// now is replaced in tests.
+var now = time.Now
+
+// flag names that may carry the secret values.
+var sensitiveFlags = []string{"secret", "password", "passwd", "token", "key", "auth", "credential", "cert"}
+
+// stampCreated records the CLI version and the command in the new object.
+func (m *Manifest) stampCreated(object *kubernetes.Object) {
+	if object.Metadata.Annotations == nil {
+		object.Metadata.Annotations = make(map[string]string)
+	}
+	if m.Version != "" {
+		object.Metadata.Annotations[triggermesh.ProvenanceVersionAnnotation] = m.Version
+	}
+	if m.Command != "" {
+		object.Metadata.Annotations[triggermesh.ProvenanceCommandAnnotation] = m.Command
+	}
+	object.Metadata.Annotations[triggermesh.ProvenanceCreatedAnnotation] = now().UTC().Format(time.RFC3339)
+}
+
+// stampModified updates the CLI version and the modification time
+// keeping the record of the command that created the object.
+func (m *Manifest) stampModified(object *kubernetes.Object) {
+	if object.Metadata.Annotations == nil {
+		object.Metadata.Annotations = make(map[string]string)
+	}
+	if m.Version != "" {
+		object.Metadata.Annotations[triggermesh.ProvenanceVersionAnnotation] = m.Version
+	}
+	object.Metadata.Annotations[triggermesh.ProvenanceModifiedAnnotation] = now().UTC().Format(time.RFC3339)
+}
+
+// StripProvenance returns the copy of the object without the provenance annotations.
+func StripProvenance(object kubernetes.Object) kubernetes.Object {
+	if len(object.Metadata.Annotations) == 0 {
+		return object
+	}
+	annotations := make(map[string]string, len(object.Metadata.Annotations))
+	for k, v := range object.Metadata.Annotations {
+		annotations[k] = v
+	}
+	for _, key := range triggermesh.ProvenanceAnnotations {
+		delete(annotations, key)
+	}
+	if len(annotations) == 0 {
+		annotations = nil
+	}
+	object.Metadata.Annotations = annotations
+	return object
+}
+
+// RedactCommand returns the command line with the values
+// of the flags that may carry the secrets replaced.
+func RedactCommand(args []string) string {
+	if len(args) == 0 {
+		return ""
+	}
+	result := []string{filepath.Base(args[0])}
+	sensitive := false
+	for _, arg := range args[1:] {
+		if strings.HasPrefix(arg, "-") {
+			name, _, hasValue := strings.Cut(strings.TrimLeft(arg, "-"), "=")
+			sensitive = isSensitive(name)
+			if sensitive && hasValue {
+				arg = arg[:strings.Index(arg, "=")+1] + redacted
+				sensitive = false
+			}
+		} else if sensitive {
+			arg = redacted
+		}
+		result = append(result, quote(redactURL(arg)))
+	}
+	return strings.Join(result, " ")
+}
+
+func isSensitive(flag string) bool {
+	flag = strings.ToLower(flag)
+	for _, s := range sensitiveFlags {
+		if strings.Contains(flag, s) {
+			return true
+		}
+	}
+	return false
+}
+
+// redactURL hides the password of the URL user info.
+func redactURL(arg string) string {
+	if !strings.Contains(arg, "://") || !strings.Contains(arg, "@") {
+		return arg
+	}
+	prefix, value, _ := strings.Cut(arg, "=")
+	if value == "" || strings.Contains(prefix, "://") {
+		prefix, value = "", arg
+	} else {
+		prefix += "="
+	}
+	u, err := url.Parse(value)
+	if err != nil || u.User == nil {
+		return arg
+	}
+	if _, set := u.User.Password(); !set {
+		return arg
+	}
+	return prefix + strings.Replace(value, u.User.String()+"@", u.User.Username()+":"+redacted+"@", 1)
+}
+
+func quote(arg string) string {
+	if strings.ContainsAny(arg, " \t\n'\"") {
+		return "'" + strings.ReplaceAll(arg, "'", `'\''`) + "'"
+	}
+	return arg
+}
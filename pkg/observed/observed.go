@@ -0,0 +1,137 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package observed keeps the event types actually produced by the
+// components, as opposed to the types declared in their CRDs.
+package observed
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"time"
+)
+
+const file = "observed-types.json"
+
+// Entry is the event type seen coming from the component.
+type Entry struct {
+	Component string    `json:"component"`
+	Type      string    `json:"type"`
+	FirstSeen time.Time `json:"firstSeen"`
+	LastSeen  time.Time `json:"lastSeen"`
+	Count     int       `json:"count"`
+}
+
+// Types is the set of the observed event types of the broker context.
+type Types struct {
+	path    string
+	entries map[string]*Entry
+}
+
+// Load reads the observed event types of the broker context.
+func Load(configBase, broker string) (*Types, error) {
+	t := &Types{
+		path:    filepath.Join(configBase, broker, file),
+		entries: make(map[string]*Entry),
+	}
+	data, err := os.ReadFile(t.path)
+	if os.IsNotExist(err) {
+		return t, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var entries []*Entry
+	if err := json.Unmarshal(data, &entries); err != nil {
+		return nil, fmt.Errorf("observed types: %w", err)
+	}
+	for _, e := range entries {
+		t.entries[key(e.Component, e.Type)] = e
+	}
+	return t, nil
+}
+
+// Add records the event type seen at the given time.
+// It returns true if the pair was not observed before.
+func (t *Types) Add(component, eventType string, at time.Time) bool {
+	k := key(component, eventType)
+	if e, exists := t.entries[k]; exists {
+		if at.After(e.LastSeen) {
+			e.LastSeen = at
+		}
+		e.Count++
+		return false
+	}
+	t.entries[k] = &Entry{
+		Component: component,
+		Type:      eventType,
+		FirstSeen: at,
+		LastSeen:  at,
+		Count:     1,
+	}
+	return true
+}
+
+// Of returns the sorted event types observed from the component.
+func (t *Types) Of(component string) []string {
+	var result []string
+	for _, e := range t.entries {
+		if e.Component == component {
+			result = append(result, e.Type)
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Entries returns the observed types sorted by the component and type.
+func (t *Types) Entries() []Entry {
+	result := make([]Entry, 0, len(t.entries))
+	for _, e := range t.entries {
+		result = append(result, *e)
+	}
+	sort.Slice(result, func(i, j int) bool {
+		if result[i].Component != result[j].Component {
+			return result[i].Component < result[j].Component
+		}
+		return result[i].Type < result[j].Type
+	})
+	return result
+}
+
+// Save writes the observed types to the context directory.
+func (t *Types) Save() error {
+	data, err := json.MarshalIndent(t.Entries(), "", "  ")
+	if err != nil {
+		return err
+	}
+	return os.WriteFile(t.path, data, 0o644)
+}
+
+// Reset removes the observed types of the broker context.
+func Reset(configBase, broker string) error {
+	if err := os.Remove(filepath.Join(configBase, broker, file)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func key(component, eventType string) string {
+	return component + "\x00" + eventType
+}
@@ -0,0 +1,64 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package observed
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestTypes(t *testing.T) {
+	configBase := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configBase, "foo"), os.ModePerm))
+
+	types, err := Load(configBase, "foo")
+	require.NoError(t, err)
+	assert.Empty(t, types.Entries())
+
+	first := time.Date(2023, 1, 1, 0, 0, 0, 0, time.UTC)
+	assert.True(t, types.Add("webhook", "order.created", first))
+	assert.False(t, types.Add("webhook", "order.created", first.Add(time.Minute)))
+	assert.True(t, types.Add("webhook", "order.cancelled", first))
+	assert.True(t, types.Add("poller", "order.created", first))
+	require.NoError(t, types.Save())
+
+	types, err = Load(configBase, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, []string{"order.cancelled", "order.created"}, types.Of("webhook"))
+	assert.Empty(t, types.Of("missing"))
+	entries := types.Entries()
+	require.Len(t, entries, 3)
+	assert.Equal(t, "poller", entries[0].Component)
+	assert.Equal(t, Entry{
+		Component: "webhook",
+		Type:      "order.created",
+		FirstSeen: first,
+		LastSeen:  first.Add(time.Minute),
+		Count:     2,
+	}, entries[2])
+
+	require.NoError(t, Reset(configBase, "foo"))
+	require.NoError(t, Reset(configBase, "foo"))
+	types, err = Load(configBase, "foo")
+	require.NoError(t, err)
+	assert.Empty(t, types.Entries())
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bytes"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"strconv"
+	"strings"
+	"time"
+	"unicode"
+	"unicode/utf8"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+const attributeColorCode = "\033[36m"
+
+// EventFormat renders CloudEvents as a compact attribute header
+// followed by the human readable payload.
+type EventFormat struct {
+	// MaxPayload limits the number of the printed payload bytes,
+	// zero value disables the truncation.
+	MaxPayload int
+	// Color highlights the attribute names.
+	Color bool
+}
+
+// Format returns the event rendered for the terminal.
+func (f EventFormat) Format(event cloudevents.Event) string {
+	var b strings.Builder
+	b.WriteString(event.Time().UTC().Format(time.RFC3339))
+	f.attribute(&b, "type", event.Type())
+	f.attribute(&b, "source", event.Source())
+	f.attribute(&b, "subject", event.Subject())
+	f.attribute(&b, "id", event.ID())
+	b.WriteString("\n")
+
+	data := event.Data()
+	if len(data) == 0 {
+		return b.String()
+	}
+	payload := data
+	if json.Valid(data) {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, data, "", "  "); err == nil {
+			payload = indented.Bytes()
+		}
+	}
+	truncated := f.MaxPayload > 0 && len(payload) > f.MaxPayload
+	if truncated {
+		payload = payload[:f.MaxPayload]
+	}
+	if isBinary(payload) {
+		b.WriteString(hex.Dump(payload))
+	} else {
+		b.Write(payload)
+		b.WriteString("\n")
+	}
+	if truncated {
+		fmt.Fprintf(&b, "... truncated, payload is %d bytes long\n", len(data))
+	}
+	return b.String()
+}
+
+func (f EventFormat) attribute(b *strings.Builder, name, value string) {
+	if value == "" {
+		return
+	}
+	if f.Color {
+		name = attributeColorCode + name + defaultColorCode
+	}
+	fmt.Fprintf(b, " %s=%s", name, value)
+}
+
+// isBinary reports whether the data cannot be printed as text.
+// Truncated multi-byte characters at the end of the data are tolerated.
+func isBinary(data []byte) bool {
+	for len(data) > 0 {
+		r, size := utf8.DecodeRune(data)
+		if r == utf8.RuneError && size == 1 {
+			return len(data) >= utf8.UTFMax || utf8.FullRune(data)
+		}
+		if unicode.IsControl(r) && !unicode.IsSpace(r) {
+			return true
+		}
+		data = data[size:]
+	}
+	return false
+}
+
+// ParseSize converts the human readable size, e.g. "512", "4k" or "1m",
+// to the number of bytes.
+func ParseSize(size string) (int, error) {
+	s := strings.ToLower(strings.TrimSpace(size))
+	multiplier := 1
+	switch {
+	case strings.HasSuffix(s, "k"):
+		multiplier, s = 1<<10, strings.TrimSuffix(s, "k")
+	case strings.HasSuffix(s, "m"):
+		multiplier, s = 1<<20, strings.TrimSuffix(s, "m")
+	}
+	n, err := strconv.Atoi(s)
+	if err != nil || n < 0 {
+		return 0, fmt.Errorf("invalid size %q", size)
+	}
+	return n * multiplier, nil
+}
@@ -0,0 +1,105 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"strings"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+)
+
+func testEvent(t *testing.T, contentType string, data []byte) cloudevents.Event {
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetType("order.created")
+	event.SetSource("shop")
+	event.SetTime(time.Date(2023, 5, 1, 10, 0, 0, 0, time.UTC))
+	if data != nil {
+		assert.NoError(t, event.SetData(contentType, data))
+	}
+	return event
+}
+
+func TestEventFormat(t *testing.T) {
+	testCases := map[string]struct {
+		format   EventFormat
+		data     []byte
+		expected string
+	}{
+		"no payload": {
+			expected: "2023-05-01T10:00:00Z type=order.created source=shop id=1\n",
+		},
+		"json payload": {
+			data:     []byte(`{"id":42}`),
+			expected: "2023-05-01T10:00:00Z type=order.created source=shop id=1\n{\n  \"id\": 42\n}\n",
+		},
+		"text payload": {
+			data:     []byte("hello"),
+			expected: "2023-05-01T10:00:00Z type=order.created source=shop id=1\nhello\n",
+		},
+		"binary payload": {
+			data:     []byte{0x00, 0x01, 0xff},
+			expected: "2023-05-01T10:00:00Z type=order.created source=shop id=1\n00000000  00 01 ff                                          |...|\n",
+		},
+		"truncated payload": {
+			format:   EventFormat{MaxPayload: 4},
+			data:     []byte("hello world"),
+			expected: "2023-05-01T10:00:00Z type=order.created source=shop id=1\nhell\n... truncated, payload is 11 bytes long\n",
+		},
+		"colored attributes": {
+			format:   EventFormat{Color: true},
+			expected: "2023-05-01T10:00:00Z \033[36mtype\033[39m=order.created \033[36msource\033[39m=shop \033[36mid\033[39m=1\n",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			contentType := cloudevents.TextPlain
+			if strings.HasPrefix(string(tc.data), "{") {
+				contentType = cloudevents.ApplicationJSON
+			}
+			assert.Equal(t, tc.expected, tc.format.Format(testEvent(t, contentType, tc.data)))
+		})
+	}
+}
+
+func TestParseSize(t *testing.T) {
+	testCases := map[string]struct {
+		size     string
+		expected int
+		err      bool
+	}{
+		"bytes":     {size: "512", expected: 512},
+		"kilobytes": {size: "4k", expected: 4096},
+		"megabytes": {size: "1M", expected: 1 << 20},
+		"invalid":   {size: "4kb", err: true},
+		"negative":  {size: "-1", err: true},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			size, err := ParseSize(tc.size)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, size)
+		})
+	}
+}
@@ -0,0 +1,214 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"sort"
+	"strconv"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+const (
+	dimColorCode   = "\033[90m"
+	errorColorCode = "\033[91m"
+	boldCode       = "\033[1m"
+	resetCode      = "\033[0m"
+)
+
+// Item is the entry of the interactive list.
+type Item struct {
+	Name   string
+	Group  string
+	Status string
+}
+
+// List is the numbered multi-select list that reads the choice
+// from the input.
+type List struct {
+	In    io.Reader
+	Out   io.Writer
+	Color bool
+
+	reader *bufio.Reader
+}
+
+// NewList returns the list that prompts in the terminal.
+func NewList(noColor bool) *List {
+	return &List{
+		In:    os.Stdin,
+		Out:   os.Stderr,
+		Color: ColorEnabled(noColor),
+	}
+}
+
+// Interactive reports whether both stdin and stdout are terminals.
+func Interactive() bool {
+	return term.IsTerminal(int(os.Stdin.Fd())) && term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// ColorEnabled reports whether the output can be colored. Colors are
+// disabled by the flag, NO_COLOR variable or if stdout is not a terminal.
+func ColorEnabled(noColor bool) bool {
+	if noColor {
+		return false
+	}
+	if _, set := os.LookupEnv("NO_COLOR"); set {
+		return false
+	}
+	return term.IsTerminal(int(os.Stdout.Fd()))
+}
+
+// Select prints the items grouped by the group name and returns the
+// items chosen by their numbers. Empty input cancels the selection.
+func (l *List) Select(title string, items []Item) ([]Item, error) {
+	if len(items) == 0 {
+		return nil, fmt.Errorf("nothing to select")
+	}
+	sorted := make([]Item, len(items))
+	copy(sorted, items)
+	sort.SliceStable(sorted, func(i, j int) bool {
+		if sorted[i].Group != sorted[j].Group {
+			return sorted[i].Group < sorted[j].Group
+		}
+		return sorted[i].Name < sorted[j].Name
+	})
+
+	fmt.Fprintln(l.Out, l.paint(boldCode, title))
+	width := 0
+	for _, item := range sorted {
+		if len(item.Name) > width {
+			width = len(item.Name)
+		}
+	}
+	group := ""
+	for i, item := range sorted {
+		if i == 0 || item.Group != group {
+			group = item.Group
+			fmt.Fprintf(l.Out, "  %s\n", l.paint(boldCode, group))
+		}
+		fmt.Fprintf(l.Out, "    %3d) %-*s  %s\n", i+1, width, item.Name, l.status(item.Status))
+	}
+
+	for {
+		answer, err := l.ask(`Enter numbers or ranges (e.g. 1,3-4), "all", or leave empty to cancel: `)
+		if err != nil {
+			return nil, err
+		}
+		if answer == "" {
+			return nil, nil
+		}
+		indexes, err := parseSelection(answer, len(sorted))
+		if err != nil {
+			fmt.Fprintln(l.Out, l.paint(errorColorCode, err.Error()))
+			continue
+		}
+		selected := make([]Item, 0, len(indexes))
+		for _, i := range indexes {
+			selected = append(selected, sorted[i])
+		}
+		return selected, nil
+	}
+}
+
+// Confirm asks the yes/no question, the default answer is no.
+func (l *List) Confirm(question string) (bool, error) {
+	answer, err := l.ask(question + " [y/N]: ")
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(answer)
+	return answer == "y" || answer == "yes", nil
+}
+
+func (l *List) ask(question string) (string, error) {
+	if l.reader == nil {
+		l.reader = bufio.NewReader(l.In)
+	}
+	fmt.Fprint(l.Out, question)
+	answer, err := l.reader.ReadString('\n')
+	if err != nil && err != io.EOF {
+		return "", err
+	}
+	if err == io.EOF {
+		// closed input is the same as the empty answer
+		fmt.Fprintln(l.Out)
+	}
+	return strings.TrimSpace(answer), nil
+}
+
+func (l *List) status(status string) string {
+	switch status {
+	case "":
+		return ""
+	case "online":
+		return l.paint(successColorCode, status)
+	}
+	return l.paint(dimColorCode, status)
+}
+
+func (l *List) paint(code, text string) string {
+	if !l.Color {
+		return text
+	}
+	return code + text + resetCode
+}
+
+// parseSelection converts the comma or space separated numbers and
+// ranges into the sorted unique zero-based indexes.
+func parseSelection(input string, size int) ([]int, error) {
+	if strings.EqualFold(input, "all") {
+		indexes := make([]int, size)
+		for i := range indexes {
+			indexes[i] = i
+		}
+		return indexes, nil
+	}
+	seen := make(map[int]struct{})
+	for _, field := range strings.FieldsFunc(input, func(r rune) bool {
+		return r == ',' || r == ' '
+	}) {
+		from, to, isRange := strings.Cut(field, "-")
+		first, err := strconv.Atoi(from)
+		if err != nil {
+			return nil, fmt.Errorf("invalid number %q", field)
+		}
+		last := first
+		if isRange {
+			if last, err = strconv.Atoi(to); err != nil {
+				return nil, fmt.Errorf("invalid range %q", field)
+			}
+		}
+		if first < 1 || last > size || first > last {
+			return nil, fmt.Errorf("%q is out of range 1-%d", field, size)
+		}
+		for i := first; i <= last; i++ {
+			seen[i-1] = struct{}{}
+		}
+	}
+	indexes := make([]int, 0, len(seen))
+	for i := range seen {
+		indexes = append(indexes, i)
+	}
+	sort.Ints(indexes)
+	return indexes, nil
+}
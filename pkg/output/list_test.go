@@ -0,0 +1,119 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package output
+
+import (
+	"bytes"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func testItems() []Item {
+	return []Item{
+		{Name: "sockeye", Group: "Service", Status: "online"},
+		{Name: "foo-awss3source", Group: "AWSS3Source", Status: "offline"},
+		{Name: "foo-trigger-9dad7875", Group: "Trigger"},
+		{Name: "foo-transformation", Group: "Transformation", Status: "online"},
+	}
+}
+
+func TestSelect(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected []string
+	}{
+		"single": {
+			input:    "2\n",
+			expected: []string{"sockeye"},
+		},
+		"list and range": {
+			input:    "1, 3-4\n",
+			expected: []string{"foo-awss3source", "foo-transformation", "foo-trigger-9dad7875"},
+		},
+		"all": {
+			input:    "all\n",
+			expected: []string{"foo-awss3source", "sockeye", "foo-transformation", "foo-trigger-9dad7875"},
+		},
+		"retry after invalid input": {
+			input:    "5\n4\n",
+			expected: []string{"foo-trigger-9dad7875"},
+		},
+		"cancel": {
+			input: "\n",
+		},
+		"closed input": {
+			input: "",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var out bytes.Buffer
+			l := &List{In: strings.NewReader(tc.input), Out: &out}
+			selected, err := l.Select("Select components:", testItems())
+			assert.NoError(t, err)
+			var names []string
+			for _, item := range selected {
+				names = append(names, item.Name)
+			}
+			assert.Equal(t, tc.expected, names)
+			assert.NotContains(t, out.String(), "\033[")
+		})
+	}
+}
+
+func TestSelectGroups(t *testing.T) {
+	var out bytes.Buffer
+	l := &List{In: strings.NewReader("\n"), Out: &out, Color: true}
+	_, err := l.Select("Select components:", testItems())
+	assert.NoError(t, err)
+	assert.Contains(t, out.String(), "\033[")
+	groups := []string{"AWSS3Source", "Service", "Transformation", "Trigger"}
+	last := -1
+	for _, group := range groups {
+		i := strings.Index(out.String(), group)
+		assert.Greater(t, i, last, group)
+		last = i
+	}
+}
+
+func TestConfirm(t *testing.T) {
+	for input, expected := range map[string]bool{
+		"y\n":   true,
+		"YES\n": true,
+		"n\n":   false,
+		"\n":    false,
+		"":      false,
+	} {
+		l := &List{In: strings.NewReader(input), Out: &bytes.Buffer{}}
+		confirmed, err := l.Confirm("Delete?")
+		assert.NoError(t, err)
+		assert.Equal(t, expected, confirmed, input)
+	}
+}
+
+func TestParseSelection(t *testing.T) {
+	indexes, err := parseSelection("3 1,2-3", 4)
+	assert.NoError(t, err)
+	assert.Equal(t, []int{0, 1, 2}, indexes)
+
+	for _, input := range []string{"0", "5", "a", "3-1", "1-x"} {
+		_, err := parseSelection(input, 4)
+		assert.Error(t, err, input)
+	}
+}
@@ -22,6 +22,7 @@ import (
 	"strings"
 
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/urls"
 )
 
 const (
@@ -64,7 +65,7 @@ func PrintStatus(kind string, object triggermesh.Component, eventSourcesFilter,
 			result = fmt.Sprintf("%s\nSubscribed to:\t\t%s", result, filter)
 		}
 		if port, err := object.(triggermesh.Consumer).GetPort(context.Background()); err == nil {
-			result = fmt.Sprintf("%s\nListening on:\t\thttp://localhost:%s", result, port)
+			result = fmt.Sprintf("%s\nListening on:\t\t%s", result, urls.New("").URL(urls.Host, port))
 		}
 
 		result = fmt.Sprintf("%s%s\n%s", successColorCode, result, defaultColorCode)
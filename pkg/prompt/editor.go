@@ -0,0 +1,113 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"os/exec"
+	"strings"
+)
+
+// DefaultEditor is the editor used when $EDITOR is not set.
+const DefaultEditor = "vi"
+
+// Edit opens the text in the user's editor and returns the edited text with
+// the comment lines stripped. The template is shown as the comment on top of
+// the text. If the result does not pass the validation, the editor is opened
+// again with the error comment on top of the text. Empty result cancels the edit.
+func Edit(pattern, template string, validate func(string) error) (string, error) {
+	f, err := os.CreateTemp("", pattern)
+	if err != nil {
+		return "", fmt.Errorf("temporary file: %w", err)
+	}
+	f.Close()
+	defer os.Remove(f.Name())
+
+	var text string
+	var validationErr error
+	for {
+		var content strings.Builder
+		if validationErr != nil {
+			content.WriteString(comment("Validation error, please fix the text below:\n" + validationErr.Error()))
+			content.WriteString("#\n")
+		}
+		content.WriteString(comment(template))
+		content.WriteString("\n")
+		content.WriteString(text)
+		if err := os.WriteFile(f.Name(), []byte(content.String()), 0o600); err != nil {
+			return "", fmt.Errorf("temporary file write: %w", err)
+		}
+		if err := runEditor(f.Name()); err != nil {
+			return "", err
+		}
+		data, err := os.ReadFile(f.Name())
+		if err != nil {
+			return "", fmt.Errorf("temporary file read: %w", err)
+		}
+		if text = stripComments(string(data)); text == "" {
+			return "", fmt.Errorf("edit cancelled, empty input")
+		}
+		if validationErr = validate(text); validationErr == nil {
+			return text, nil
+		}
+		text += "\n"
+	}
+}
+
+func runEditor(path string) error {
+	args := strings.Fields(os.Getenv("EDITOR"))
+	if len(args) == 0 {
+		args = []string{DefaultEditor}
+	}
+	cmd := exec.Command(args[0], append(args[1:], path)...)
+	cmd.Stdin = os.Stdin
+	cmd.Stdout = os.Stdout
+	cmd.Stderr = os.Stderr
+	if err := cmd.Run(); err != nil {
+		return fmt.Errorf("editor %q: %w", args[0], err)
+	}
+	return nil
+}
+
+// comment prefixes every line of the text with the "#" sign.
+func comment(text string) string {
+	var b strings.Builder
+	for _, line := range strings.Split(strings.TrimRight(text, "\n"), "\n") {
+		if line == "" {
+			b.WriteString("#\n")
+			continue
+		}
+		b.WriteString("# " + line + "\n")
+	}
+	return b.String()
+}
+
+// stripComments removes the lines starting with the "#" sign,
+// leading and trailing empty lines of the result are trimmed.
+func stripComments(text string) string {
+	var lines []string
+	scn := bufio.NewScanner(strings.NewReader(strings.ReplaceAll(text, "\r\n", "\n")))
+	for scn.Scan() {
+		if strings.HasPrefix(strings.TrimSpace(scn.Text()), "#") {
+			continue
+		}
+		lines = append(lines, scn.Text())
+	}
+	return strings.Trim(strings.Join(lines, "\n"), "\n")
+}
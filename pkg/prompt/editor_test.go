@@ -0,0 +1,69 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prompt
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestComment(t *testing.T) {
+	assert.Equal(t, "# example:\n#\n# data: []\n", comment("example:\n\ndata: []\n"))
+}
+
+func TestStripComments(t *testing.T) {
+	text := "# Validation error\r\n#\r\n\r\ndata:\n  # nested comment\n- operation: add\n\n"
+	assert.Equal(t, "data:\n- operation: add", stripComments(text))
+	assert.Empty(t, stripComments(comment(spec)))
+}
+
+func TestEdit(t *testing.T) {
+	if runtime.GOOS == "windows" {
+		t.Skip("shell script editor")
+	}
+	// the editor appends the line to the file on every run
+	dir := t.TempDir()
+	editor := filepath.Join(dir, "editor.sh")
+	script := "#!/bin/sh\necho \"$(cat " + filepath.Join(dir, "line") + ")\" >> \"$1\"\ncp \"$1\" " + filepath.Join(dir, "last") + "\n"
+	assert.NoError(t, os.WriteFile(editor, []byte(script), 0o700))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "line"), []byte("data: []"), 0o600))
+	t.Setenv("EDITOR", editor)
+
+	runs := 0
+	text, err := Edit("spec-*.yaml", "Transformation example", func(text string) error {
+		if runs++; runs == 1 {
+			return fmt.Errorf("invalid spec")
+		}
+		return nil
+	})
+	assert.NoError(t, err)
+	assert.Equal(t, "data: []\ndata: []", text)
+
+	last, err := os.ReadFile(filepath.Join(dir, "last"))
+	assert.NoError(t, err)
+	assert.True(t, strings.HasPrefix(string(last), "# Validation error, please fix the text below:\n# invalid spec\n#\n# Transformation example\n"))
+
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "line"), []byte("# cancelled"), 0o600))
+	_, err = Edit("spec-*.yaml", "Transformation example", func(string) error { return nil })
+	assert.EqualError(t, err, "edit cancelled, empty input")
+}
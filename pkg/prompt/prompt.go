@@ -0,0 +1,107 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package prompt reads the user input from the terminal.
+package prompt
+
+import (
+	"bufio"
+	"fmt"
+	"io"
+	"os"
+	"strings"
+
+	"golang.org/x/term"
+)
+
+// Password reads the value without echoing it.
+func Password(label string) (string, error) {
+	fd := int(os.Stdin.Fd())
+	if !term.IsTerminal(fd) {
+		return "", fmt.Errorf("cannot prompt for %s, stdin is not a terminal", label)
+	}
+	fmt.Fprintf(os.Stderr, "%s: ", label)
+	value, err := term.ReadPassword(fd)
+	fmt.Fprintln(os.Stderr)
+	if err != nil {
+		return "", err
+	}
+	return string(value), nil
+}
+
+// NewPassword reads the new value twice and checks that both inputs match.
+func NewPassword(label string) (string, error) {
+	value, err := Password(label)
+	if err != nil {
+		return "", err
+	}
+	if value == "" {
+		return "", fmt.Errorf("empty %s", label)
+	}
+	confirmation, err := Password("Repeat " + label)
+	if err != nil {
+		return "", err
+	}
+	if value != confirmation {
+		return "", fmt.Errorf("%s does not match", label)
+	}
+	return value, nil
+}
+
+// Confirm asks the yes/no question, the default answer is no.
+func Confirm(question string) (bool, error) {
+	if !term.IsTerminal(int(os.Stdin.Fd())) {
+		return false, fmt.Errorf("cannot ask %q, stdin is not a terminal", question)
+	}
+	fmt.Fprintf(os.Stderr, "%s [y/N]: ", question)
+	answer, err := bufio.NewReader(os.Stdin).ReadString('\n')
+	if err != nil {
+		return false, err
+	}
+	answer = strings.ToLower(strings.TrimSpace(answer))
+	return answer == "y" || answer == "yes", nil
+}
+
+// Multiline reads the text typed in the terminal until the empty line.
+// Non-interactive input, e.g. the piped document, is read until EOF with
+// its empty lines preserved. Leading and trailing empty lines are trimmed.
+func Multiline(r io.Reader, interactive bool) (string, error) {
+	var text string
+	if interactive {
+		var lines []string
+		scn := bufio.NewScanner(r)
+		for scn.Scan() {
+			// trim carriage return so that CRLF-terminated empty line
+			// on Windows is treated as the end of input.
+			line := strings.TrimSuffix(scn.Text(), "\r")
+			if len(line) == 0 {
+				break
+			}
+			lines = append(lines, line)
+		}
+		if err := scn.Err(); err != nil {
+			return "", err
+		}
+		text = strings.Join(lines, "\n")
+	} else {
+		data, err := io.ReadAll(r)
+		if err != nil {
+			return "", err
+		}
+		text = strings.ReplaceAll(string(data), "\r\n", "\n")
+	}
+	return strings.Trim(text, "\n"), nil
+}
@@ -0,0 +1,82 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package prompt
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const spec = `
+context:
+- operation: add
+  paths:
+  - key: source
+    value: tmctl
+
+data:
+- operation: store
+  paths:
+  - key: $id
+    value: id
+
+- operation: add
+  paths:
+  - key: id
+    value: $id
+`
+
+func TestMultilinePiped(t *testing.T) {
+	testCases := map[string]struct {
+		input    string
+		expected string
+	}{
+		"multi-section spec": {
+			input:    spec + "\n\n",
+			expected: strings.Trim(spec, "\n"),
+		},
+		"CRLF line endings": {
+			input:    strings.ReplaceAll(spec, "\n", "\r\n"),
+			expected: strings.Trim(spec, "\n"),
+		},
+		"JQ expression": {
+			input:    ".data | {id: .id}\n",
+			expected: ".data | {id: .id}",
+		},
+		"empty": {},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			text, err := Multiline(strings.NewReader(tc.input), false)
+			assert.NoError(t, err)
+			assert.Equal(t, tc.expected, text)
+		})
+	}
+}
+
+func TestMultilineInteractive(t *testing.T) {
+	// the first empty line ends the typed input
+	text, err := Multiline(strings.NewReader("context:\r\n- operation: add\r\n\r\ndata:\n"), true)
+	assert.NoError(t, err)
+	assert.Equal(t, "context:\n- operation: add", text)
+
+	text, err = Multiline(strings.NewReader(spec), true)
+	assert.NoError(t, err)
+	assert.Empty(t, text)
+}
@@ -0,0 +1,293 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package route traces the path of the event through the broker triggers
+// and the components replying to the broker. The analysis is static:
+// the trigger filters are evaluated against the hypothetical event
+// attributes and no events are sent.
+package route
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/cloudevents/sdk-go/v2/types"
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+// Decision is the result of the trigger filters evaluation.
+type Decision string
+
+// Filter evaluation results. Unknown decision means that the filters
+// depend on the event attribute that is not set.
+const (
+	Matched    Decision = "matched"
+	NotMatched Decision = "not matched"
+	Unknown    Decision = "unknown"
+)
+
+// Event is the set of the hypothetical event attributes.
+type Event map[string]string
+
+// Observed returns the attributes of the event received from the broker.
+func Observed(event cloudevents.Event) Event {
+	attributes := Event{
+		"specversion": event.SpecVersion(),
+		"id":          event.ID(),
+		"source":      event.Source(),
+		"type":        event.Type(),
+	}
+	optional := map[string]string{
+		"subject":         event.Subject(),
+		"datacontenttype": event.DataContentType(),
+		"dataschema":      event.DataSchema(),
+	}
+	if !event.Time().IsZero() {
+		optional["time"] = types.FormatTime(event.Time())
+	}
+	for name, value := range optional {
+		if value != "" {
+			attributes[name] = value
+		}
+	}
+	for name, value := range event.Extensions() {
+		if formatted, err := types.Format(value); err == nil {
+			attributes[name] = formatted
+		}
+	}
+	return attributes
+}
+
+// Consumer describes the component receiving the events from the trigger.
+type Consumer struct {
+	// Running is false if the component container is not running.
+	Running bool
+	// Replies is true if the component sends the events back to the broker.
+	Replies bool
+	// Type and Source are the declared attributes of the reply events,
+	// empty values are not declared by the component.
+	Type   string
+	Source string
+}
+
+// Resolver returns the trigger destination component,
+// nil if the destination is not the component of the context.
+type Resolver func(component string) *Consumer
+
+// Hop is the trigger evaluated against the event.
+type Hop struct {
+	Trigger string
+	// Destination is the component name or the URL of the trigger target.
+	Destination string
+	Decision    Decision
+	// Reason explains the not matched and unknown decisions.
+	Reason string
+	// Consumer is nil if the destination is not the component of the context.
+	Consumer *Consumer
+	// Reply is the event the consumer sends back to the broker,
+	// Next are the triggers evaluated against it.
+	Reply Event
+	Next  []Hop
+	// Loop is set if the reply event has already passed the trigger.
+	Loop bool
+}
+
+// Down returns true if the trigger would deliver the event
+// to the component that is not running.
+func (h Hop) Down() bool {
+	return h.Decision != NotMatched && h.Consumer != nil && !h.Consumer.Running
+}
+
+// Trace evaluates the triggers against the event and follows
+// the reply events of the matched consumers.
+func Trace(triggers map[string]tmbroker.LocalTriggerSpec, event Event, resolve Resolver) []Hop {
+	return trace(triggers, event, resolve, map[string]bool{})
+}
+
+func trace(triggers map[string]tmbroker.LocalTriggerSpec, event Event, resolve Resolver, visited map[string]bool) []Hop {
+	names := make([]string, 0, len(triggers))
+	for name := range triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var hops []Hop
+	for _, name := range names {
+		trigger := triggers[name]
+		hop := Hop{
+			Trigger:     name,
+			Destination: trigger.Target.Component,
+		}
+		if hop.Destination == "" {
+			hop.Destination = trigger.Target.TargetURL()
+		} else {
+			hop.Consumer = resolve(hop.Destination)
+		}
+		hop.Decision, hop.Reason = Evaluate(trigger.Filters, event)
+		if hop.Decision == NotMatched || hop.Consumer == nil || !hop.Consumer.Running || !hop.Consumer.Replies || hop.Consumer.Type == "" {
+			hops = append(hops, hop)
+			continue
+		}
+		hop.Reply = event.reply(hop.Consumer)
+		key := name + " " + hop.Reply["type"]
+		if visited[key] {
+			hop.Loop = true
+			hops = append(hops, hop)
+			continue
+		}
+		visited[key] = true
+		hop.Next = trace(triggers, hop.Reply, resolve, visited)
+		delete(visited, key)
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+func (e Event) reply(c *Consumer) Event {
+	reply := make(Event, len(e))
+	for key, value := range e {
+		reply[key] = value
+	}
+	reply["type"] = c.Type
+	if c.Source != "" {
+		reply["source"] = c.Source
+	}
+	return reply
+}
+
+// Evaluate checks the trigger filters against the event attributes.
+// All filters must match, the trigger without the filters matches any event.
+// The reason explains the first filter that did not match.
+func Evaluate(filters []eventingbroker.Filter, event Event) (Decision, string) {
+	return allOf(filters, event, false)
+}
+
+// Match checks the trigger filters against the attributes of the observed
+// event, the filters of the attributes that are not set do not match.
+func Match(filters []eventingbroker.Filter, event Event) (Decision, string) {
+	return allOf(filters, event, true)
+}
+
+// Predict returns the triggers that match the observed event. Unlike the
+// hypothetical event of Trace, the observed event attributes are complete:
+// the filters of the attributes that are not set do not match.
+// The prediction is made client-side, the broker may have other triggers
+// if its configuration has not been reloaded yet.
+func Predict(triggers map[string]tmbroker.LocalTriggerSpec, event Event) []Hop {
+	names := make([]string, 0, len(triggers))
+	for name := range triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var hops []Hop
+	for _, name := range names {
+		trigger := triggers[name]
+		decision, _ := Match(trigger.Filters, event)
+		if decision != Matched {
+			continue
+		}
+		hop := Hop{
+			Trigger:     name,
+			Destination: trigger.Target.Component,
+			Decision:    decision,
+		}
+		if hop.Destination == "" {
+			hop.Destination = trigger.Target.TargetURL()
+		}
+		hops = append(hops, hop)
+	}
+	return hops
+}
+
+func allOf(filters []eventingbroker.Filter, event Event, complete bool) (Decision, string) {
+	result, reason := Matched, ""
+	for _, f := range filters {
+		switch decision, why := evaluate(f, event, complete); decision {
+		case NotMatched:
+			return NotMatched, why
+		case Unknown:
+			if result == Matched {
+				result, reason = Unknown, why
+			}
+		}
+	}
+	return result, reason
+}
+
+func anyOf(filters []eventingbroker.Filter, event Event, complete bool) (Decision, string) {
+	var reasons []string
+	result := NotMatched
+	for _, f := range filters {
+		switch decision, why := evaluate(f, event, complete); decision {
+		case Matched:
+			return Matched, ""
+		case Unknown:
+			result = Unknown
+			reasons = append(reasons, why)
+		default:
+			reasons = append(reasons, why)
+		}
+	}
+	return result, strings.Join(reasons, " and ")
+}
+
+// evaluate checks the filter against the event attributes, the complete
+// event does not match the filters of the attributes that are not set.
+func evaluate(f eventingbroker.Filter, event Event, complete bool) (Decision, string) {
+	switch {
+	case len(f.Exact) != 0:
+		return attribute(f.Exact, event, complete, "is not", func(value, expected string) bool { return value == expected })
+	case len(f.Prefix) != 0:
+		return attribute(f.Prefix, event, complete, "does not start with", strings.HasPrefix)
+	case len(f.Suffix) != 0:
+		return attribute(f.Suffix, event, complete, "does not end with", strings.HasSuffix)
+	case len(f.All) != 0:
+		return allOf(f.All, event, complete)
+	case len(f.Any) != 0:
+		return anyOf(f.Any, event, complete)
+	case f.Not != nil:
+		switch decision, why := evaluate(*f.Not, event, complete); decision {
+		case Matched:
+			return NotMatched, "negated expression matched"
+		case Unknown:
+			return Unknown, why
+		}
+		return Matched, ""
+	}
+	// empty filter matches any event
+	return Matched, ""
+}
+
+func attribute(expression map[string]string, event Event, complete bool, relation string, match func(value, expected string) bool) (Decision, string) {
+	for name, expected := range expression {
+		value, set := event[name]
+		if !set && complete {
+			return NotMatched, fmt.Sprintf("%s attribute is not set", name)
+		}
+		if !set {
+			return Unknown, fmt.Sprintf("%s attribute is not set", name)
+		}
+		if !match(value, expected) {
+			return NotMatched, fmt.Sprintf("%s %q %s %q", name, value, relation, expected)
+		}
+	}
+	return Matched, ""
+}
@@ -0,0 +1,226 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package route
+
+import (
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+func TestEvaluate(t *testing.T) {
+	event := Event{"type": "order.created", "source": "shop"}
+	testCases := map[string]struct {
+		filters  []eventingbroker.Filter
+		decision Decision
+		reason   string
+	}{
+		"no filters": {
+			decision: Matched,
+		},
+		"exact": {
+			filters:  []eventingbroker.Filter{{Exact: map[string]string{"type": "order.created"}}},
+			decision: Matched,
+		},
+		"exact mismatch": {
+			filters:  []eventingbroker.Filter{{Exact: map[string]string{"type": "order.paid"}}},
+			decision: NotMatched,
+			reason:   `type "order.created" is not "order.paid"`,
+		},
+		"prefix and suffix": {
+			filters: []eventingbroker.Filter{
+				{Prefix: map[string]string{"type": "order."}},
+				{Suffix: map[string]string{"source": "hop"}},
+			},
+			decision: Matched,
+		},
+		"second filter mismatch": {
+			filters: []eventingbroker.Filter{
+				{Prefix: map[string]string{"type": "order."}},
+				{Suffix: map[string]string{"source": "store"}},
+			},
+			decision: NotMatched,
+			reason:   `source "shop" does not end with "store"`,
+		},
+		"attribute not set": {
+			filters:  []eventingbroker.Filter{{Exact: map[string]string{"subject": "foo"}}},
+			decision: Unknown,
+			reason:   "subject attribute is not set",
+		},
+		"mismatch wins over unknown": {
+			filters: []eventingbroker.Filter{
+				{Exact: map[string]string{"subject": "foo"}},
+				{Exact: map[string]string{"type": "order.paid"}},
+			},
+			decision: NotMatched,
+			reason:   `type "order.created" is not "order.paid"`,
+		},
+		"any": {
+			filters: []eventingbroker.Filter{{Any: []eventingbroker.Filter{
+				{Exact: map[string]string{"type": "order.paid"}},
+				{Exact: map[string]string{"type": "order.created"}},
+			}}},
+			decision: Matched,
+		},
+		"not": {
+			filters:  []eventingbroker.Filter{{Not: &eventingbroker.Filter{Prefix: map[string]string{"type": "order."}}}},
+			decision: NotMatched,
+			reason:   "negated expression matched",
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			decision, reason := Evaluate(tc.filters, event)
+			assert.Equal(t, tc.decision, decision)
+			assert.Equal(t, tc.reason, reason)
+		})
+	}
+}
+
+func TestMatch(t *testing.T) {
+	event := Event{"type": "order.created"}
+	decision, reason := Match([]eventingbroker.Filter{{Exact: map[string]string{"type": "order.created"}}}, event)
+	assert.Equal(t, Matched, decision)
+	assert.Empty(t, reason)
+
+	// observed event is complete, unset attributes do not match
+	decision, reason = Match([]eventingbroker.Filter{{Exact: map[string]string{"subject": "foo"}}}, event)
+	assert.Equal(t, NotMatched, decision)
+	assert.Equal(t, "subject attribute is not set", reason)
+}
+
+func TestTrace(t *testing.T) {
+	triggers := map[string]tmbroker.LocalTriggerSpec{
+		"to-transformation": {
+			Filters: []eventingbroker.Filter{*tmbroker.FilterAttribute("type", "order.created")},
+			Target:  tmbroker.LocalTarget{Component: "foo-transformation", URL: "http://host.docker.internal:40001"},
+		},
+		"to-sockeye": {
+			Filters: []eventingbroker.Filter{*tmbroker.FilterAttribute("type", "order.enriched")},
+			Target:  tmbroker.LocalTarget{Component: "sockeye", URL: "http://host.docker.internal:40002"},
+		},
+		"to-webhook": {
+			Filters: []eventingbroker.Filter{*tmbroker.FilterAttribute("type", "order.*")},
+			Target:  tmbroker.LocalTarget{URL: "https://example.com/hooks"},
+		},
+	}
+	consumers := map[string]*Consumer{
+		"foo-transformation": {Running: true, Replies: true, Type: "order.enriched", Source: "foo-transformation"},
+		"sockeye":            {Running: false},
+	}
+	resolve := func(component string) *Consumer {
+		return consumers[component]
+	}
+
+	hops := Trace(triggers, Event{"type": "order.created"}, resolve)
+	require.Len(t, hops, 3)
+	// triggers are sorted by name
+	assert.Equal(t, "to-sockeye", hops[0].Trigger)
+	assert.Equal(t, NotMatched, hops[0].Decision)
+
+	transformation := hops[1]
+	assert.Equal(t, "to-transformation", transformation.Trigger)
+	assert.Equal(t, Matched, transformation.Decision)
+	assert.False(t, transformation.Down())
+	assert.Equal(t, Event{"type": "order.enriched", "source": "foo-transformation"}, transformation.Reply)
+	require.Len(t, transformation.Next, 3)
+	assert.Equal(t, Matched, transformation.Next[0].Decision)
+	assert.True(t, transformation.Next[0].Down())
+	assert.Equal(t, Matched, transformation.Next[2].Decision)
+
+	webhook := hops[2]
+	assert.Equal(t, "https://example.com/hooks", webhook.Destination)
+	assert.Equal(t, Matched, webhook.Decision)
+	assert.Nil(t, webhook.Consumer)
+	assert.False(t, webhook.Down())
+}
+
+func TestTraceLoop(t *testing.T) {
+	triggers := map[string]tmbroker.LocalTriggerSpec{
+		"echo": {Target: tmbroker.LocalTarget{Component: "echo"}},
+	}
+	resolve := func(string) *Consumer {
+		return &Consumer{Running: true, Replies: true, Type: "echo.reply"}
+	}
+	hops := Trace(triggers, Event{"type": "ping"}, resolve)
+	require.Len(t, hops, 1)
+	require.Len(t, hops[0].Next, 1)
+	assert.True(t, hops[0].Next[0].Loop)
+	assert.Empty(t, hops[0].Next[0].Next)
+}
+
+func TestPredict(t *testing.T) {
+	triggers := map[string]tmbroker.LocalTriggerSpec{
+		"orders": {
+			Filters: []eventingbroker.Filter{*tmbroker.FilterAttribute("type", "order.created")},
+			Target:  tmbroker.LocalTarget{Component: "sockeye", URL: "http://sockeye:8080"},
+		},
+		"not-eu": {
+			Filters: []eventingbroker.Filter{{Not: &eventingbroker.Filter{Exact: map[string]string{"region": "eu"}}}},
+			Target:  tmbroker.LocalTarget{URL: "https://example.com/hooks"},
+		},
+		"eu": {
+			Filters: []eventingbroker.Filter{{Exact: map[string]string{"region": "eu"}}},
+			Target:  tmbroker.LocalTarget{URL: "https://example.com/eu"},
+		},
+	}
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetSource("shop")
+	event.SetType("order.created")
+
+	// the region extension is not set: the negated filter matches
+	hops := Predict(triggers, Observed(event))
+	require.Len(t, hops, 2)
+	assert.Equal(t, "not-eu", hops[0].Trigger)
+	assert.Equal(t, "https://example.com/hooks", hops[0].Destination)
+	assert.Equal(t, "orders", hops[1].Trigger)
+	assert.Equal(t, "sockeye", hops[1].Destination)
+
+	event.SetType("order.paid")
+	event.SetExtension("region", "eu")
+	hops = Predict(triggers, Observed(event))
+	require.Len(t, hops, 1)
+	assert.Equal(t, "eu", hops[0].Trigger)
+
+	delete(triggers, "not-eu")
+	event.SetExtension("region", "us")
+	assert.Empty(t, Predict(triggers, Observed(event)))
+}
+
+func TestObserved(t *testing.T) {
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetSource("shop")
+	event.SetType("order.created")
+	event.SetSubject("42")
+	event.SetExtension("priority", 3)
+	assert.Equal(t, Event{
+		"specversion": "1.0",
+		"id":          "1",
+		"source":      "shop",
+		"type":        "order.created",
+		"subject":     "42",
+		"priority":    "3",
+	}, Observed(event))
+}
@@ -0,0 +1,136 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package schema stores JSON schemas of the event payloads
+// and validates events against them.
+package schema
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+	"k8s.io/kube-openapi/pkg/validation/strfmt"
+	"k8s.io/kube-openapi/pkg/validation/validate"
+)
+
+const (
+	registryDir = "schemas"
+	schemaExt   = ".json"
+)
+
+// ErrNotFound is returned when event type has no registered schema.
+var ErrNotFound = errors.New("schema not found")
+
+// Registry is the local storage of the event types payload schemas.
+type Registry struct {
+	path string
+}
+
+// New returns the schema registry of the broker context.
+func New(configBase, broker string) *Registry {
+	return &Registry{
+		path: filepath.Join(configBase, broker, registryDir),
+	}
+}
+
+// Set validates and stores the JSON schema for the event type.
+func (r *Registry) Set(eventType string, schema []byte) error {
+	if _, err := parse(schema); err != nil {
+		return err
+	}
+	if err := os.MkdirAll(r.path, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(r.file(eventType), schema, os.ModePerm)
+}
+
+// Get returns the schema registered for the event type.
+func (r *Registry) Get(eventType string) ([]byte, error) {
+	schema, err := os.ReadFile(r.file(eventType))
+	if os.IsNotExist(err) {
+		return nil, ErrNotFound
+	}
+	return schema, err
+}
+
+// Delete removes the event type schema from the registry.
+func (r *Registry) Delete(eventType string) error {
+	if err := os.Remove(r.file(eventType)); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+// List returns the sorted list of event types with registered schemas.
+func (r *Registry) List() ([]string, error) {
+	entries, err := os.ReadDir(r.path)
+	if os.IsNotExist(err) {
+		return []string{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result []string
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != schemaExt {
+			continue
+		}
+		eventType, err := url.PathUnescape(strings.TrimSuffix(entry.Name(), schemaExt))
+		if err != nil {
+			continue
+		}
+		result = append(result, eventType)
+	}
+	sort.Strings(result)
+	return result, nil
+}
+
+// Validate checks the event payload against the registered schema.
+// ErrNotFound is returned if event type has no schema.
+func (r *Registry) Validate(eventType string, data []byte) error {
+	raw, err := r.Get(eventType)
+	if err != nil {
+		return err
+	}
+	schema, err := parse(raw)
+	if err != nil {
+		return err
+	}
+	var payload interface{}
+	if err := json.Unmarshal(data, &payload); err != nil {
+		return fmt.Errorf("payload is not a valid JSON: %w", err)
+	}
+	return validate.AgainstSchema(schema, payload, strfmt.Default)
+}
+
+func (r *Registry) file(eventType string) string {
+	return filepath.Join(r.path, url.PathEscape(eventType)+schemaExt)
+}
+
+func parse(data []byte) (*spec.Schema, error) {
+	var schema spec.Schema
+	if err := json.Unmarshal(data, &schema); err != nil {
+		return nil, fmt.Errorf("invalid JSON schema: %w", err)
+	}
+	return &schema, nil
+}
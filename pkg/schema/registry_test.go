@@ -0,0 +1,140 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const orderSchema = `{
+  "type": "object",
+  "required": ["id", "amount"],
+  "properties": {
+    "id": {"type": "string"},
+    "amount": {"type": "number", "minimum": 0}
+  }
+}`
+
+func TestRegistry(t *testing.T) {
+	r := New(t.TempDir(), "foo")
+
+	list, err := r.List()
+	assert.NoError(t, err)
+	assert.Empty(t, list)
+
+	_, err = r.Get("orders.transformed")
+	assert.ErrorIs(t, err, ErrNotFound)
+
+	assert.Error(t, r.Set("orders.transformed", []byte("not a schema")))
+	assert.NoError(t, r.Set("orders.transformed", []byte(orderSchema)))
+	assert.NoError(t, r.Set("io.triggermesh/orders", []byte(orderSchema)))
+
+	schema, err := r.Get("orders.transformed")
+	assert.NoError(t, err)
+	assert.JSONEq(t, orderSchema, string(schema))
+
+	list, err = r.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"io.triggermesh/orders", "orders.transformed"}, list)
+
+	assert.NoError(t, r.Delete("io.triggermesh/orders"))
+	list, err = r.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []string{"orders.transformed"}, list)
+}
+
+func TestValidate(t *testing.T) {
+	r := New(t.TempDir(), "foo")
+	assert.NoError(t, r.Set("orders.transformed", []byte(orderSchema)))
+
+	testCases := map[string]struct {
+		eventType string
+		data      string
+		valid     bool
+		notFound  bool
+	}{
+		"valid payload": {
+			eventType: "orders.transformed",
+			data:      `{"id":"42","amount":10}`,
+			valid:     true,
+		},
+		"missing property": {
+			eventType: "orders.transformed",
+			data:      `{"id":"42"}`,
+		},
+		"wrong type": {
+			eventType: "orders.transformed",
+			data:      `{"id":42,"amount":10}`,
+		},
+		"not a JSON": {
+			eventType: "orders.transformed",
+			data:      `hello`,
+		},
+		"unknown event type": {
+			eventType: "orders.created",
+			data:      `{}`,
+			notFound:  true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := r.Validate(tc.eventType, []byte(tc.data))
+			switch {
+			case tc.notFound:
+				assert.ErrorIs(t, err, ErrNotFound)
+			case tc.valid:
+				assert.NoError(t, err)
+			default:
+				assert.Error(t, err)
+				assert.NotErrorIs(t, err, ErrNotFound)
+			}
+		})
+	}
+}
+
+func TestSample(t *testing.T) {
+	r := New(t.TempDir(), "foo")
+	schema := `{
+		"type": "object",
+		"properties": {
+			"id": {"type": "integer"},
+			"name": {"type": "string", "example": "alice"},
+			"status": {"type": "string", "enum": ["new", "paid"]},
+			"tags": {"type": "array", "items": {"type": "string"}},
+			"address": {"type": "object", "properties": {"zip": {"type": "string", "default": "10001"}}}
+		}
+	}`
+	if err := r.Set("order.created", []byte(schema)); err != nil {
+		t.Fatal(err)
+	}
+	data, err := r.Sample("order.created")
+	if err != nil {
+		t.Fatal(err)
+	}
+	expected := `{"address":{"zip":"10001"},"id":0,"name":"alice","status":"new","tags":["string"]}`
+	if string(data) != expected {
+		t.Errorf("expected %s, got %s", expected, data)
+	}
+	if err := r.Validate("order.created", data); err != nil {
+		t.Errorf("sample does not match the schema: %v", err)
+	}
+	if _, err := r.Sample("order.paid"); err != ErrNotFound {
+		t.Errorf("expected %v, got %v", ErrNotFound, err)
+	}
+}
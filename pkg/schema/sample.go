@@ -0,0 +1,71 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package schema
+
+import (
+	"encoding/json"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+// Sample returns the example payload generated from the event type schema.
+// Schema examples, defaults and enums are preferred over the type placeholders.
+func (r *Registry) Sample(eventType string) ([]byte, error) {
+	raw, err := r.Get(eventType)
+	if err != nil {
+		return nil, err
+	}
+	schema, err := parse(raw)
+	if err != nil {
+		return nil, err
+	}
+	return json.Marshal(sample(schema))
+}
+
+func sample(s *spec.Schema) interface{} {
+	switch {
+	case s == nil:
+		return nil
+	case s.Example != nil:
+		return s.Example
+	case s.Default != nil:
+		return s.Default
+	case len(s.Enum) != 0:
+		return s.Enum[0]
+	}
+	switch {
+	case s.Type.Contains("object") || len(s.Properties) != 0:
+		object := make(map[string]interface{}, len(s.Properties))
+		for name, property := range s.Properties {
+			property := property
+			object[name] = sample(&property)
+		}
+		return object
+	case s.Type.Contains("array"):
+		if s.Items == nil || s.Items.Schema == nil {
+			return []interface{}{}
+		}
+		return []interface{}{sample(s.Items.Schema)}
+	case s.Type.Contains("string"):
+		return "string"
+	case s.Type.Contains("integer"), s.Type.Contains("number"):
+		return 0
+	case s.Type.Contains("boolean"):
+		return false
+	}
+	return nil
+}
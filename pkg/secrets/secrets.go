@@ -0,0 +1,232 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package secrets stores the secret material written for the containers.
+// Files are named after the hash of the component and the field so that
+// the directory listing does not reveal the components, the index file
+// maps the names back to let the files be listed and cleaned up.
+package secrets
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+)
+
+const (
+	// Dir is the secrets directory in the broker context.
+	Dir = "secrets"
+	// MountPath is the secrets directory inside the containers.
+	MountPath = "/etc/triggermesh/secrets"
+	// HostDirEnv is not passed to the container, it sets
+	// the host directory mounted into the MountPath.
+	HostDirEnv = "TMCTL_SECRETS_DIR"
+
+	indexFile = "index.json"
+)
+
+// ErrNotFound is returned for the secrets missing in the index.
+var ErrNotFound = errors.New("secret not found")
+
+// Entry is the index record of the secret file.
+type Entry struct {
+	Name      string `json:"-"`
+	Component string `json:"component"`
+	Field     string `json:"field"`
+}
+
+// Store is the directory of the secret files and their index.
+type Store struct {
+	dir string
+}
+
+// New returns the store of the secrets in the directory.
+func New(dir string) *Store {
+	return &Store{dir: dir}
+}
+
+// Context returns the store of the broker context.
+func Context(configHome, context string) *Store {
+	return New(filepath.Join(configHome, context, Dir))
+}
+
+// Dir returns the host directory of the store.
+func (s *Store) Dir() string {
+	return s.dir
+}
+
+// Name returns the file name of the component's secret field.
+func Name(component, field string) string {
+	sum := sha256.Sum256([]byte(component + "/" + field))
+	return hex.EncodeToString(sum[:])
+}
+
+// Path returns the host path of the indexed secret field.
+func (s *Store) Path(component, field string) (string, error) {
+	name, err := s.lookup(component, field)
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(s.dir, name), nil
+}
+
+// MountedPath returns the container path of the indexed secret field.
+func (s *Store) MountedPath(component, field string) (string, error) {
+	name, err := s.lookup(component, field)
+	if err != nil {
+		return "", err
+	}
+	return path.Join(MountPath, name), nil
+}
+
+// Read returns the content of the component's secret field.
+func (s *Store) Read(component, field string) ([]byte, error) {
+	p, err := s.Path(component, field)
+	if err != nil {
+		return nil, err
+	}
+	return os.ReadFile(p)
+}
+
+// Write stores the component's secret field and records it in the index.
+// Secret files are created 0600 unless the perm is set.
+func (s *Store) Write(component, field string, data []byte, perm os.FileMode) error {
+	if err := os.MkdirAll(s.dir, 0o700); err != nil {
+		return fmt.Errorf("secrets directory: %w", err)
+	}
+	if perm == 0 {
+		perm = 0o600
+	}
+	index, err := s.index()
+	if err != nil {
+		return err
+	}
+	name := Name(component, field)
+	if err := os.WriteFile(filepath.Join(s.dir, name), data, perm); err != nil {
+		return err
+	}
+	// permissions of the existing file are not updated by WriteFile
+	if err := os.Chmod(filepath.Join(s.dir, name), perm); err != nil {
+		return err
+	}
+	if entry, exists := index[name]; exists && entry.Component == component && entry.Field == field {
+		return nil
+	}
+	index[name] = Entry{Component: component, Field: field}
+	return s.writeIndex(index)
+}
+
+// Remove deletes the secret files of the component and their index entries.
+func (s *Store) Remove(component string) error {
+	index, err := s.index()
+	if err != nil {
+		return err
+	}
+	removed := false
+	for name, entry := range index {
+		if entry.Component != component {
+			continue
+		}
+		if err := os.Remove(filepath.Join(s.dir, name)); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		delete(index, name)
+		removed = true
+	}
+	if !removed {
+		return nil
+	}
+	return s.writeIndex(index)
+}
+
+// List returns the indexed secrets sorted by the component and field.
+func (s *Store) List() ([]Entry, error) {
+	index, err := s.index()
+	if err != nil {
+		return nil, err
+	}
+	entries := make([]Entry, 0, len(index))
+	for name, entry := range index {
+		entry.Name = name
+		entries = append(entries, entry)
+	}
+	sort.Slice(entries, func(i, j int) bool {
+		if entries[i].Component != entries[j].Component {
+			return entries[i].Component < entries[j].Component
+		}
+		return entries[i].Field < entries[j].Field
+	})
+	return entries, nil
+}
+
+// Orphans returns the indexed secrets of the components that do not exist.
+func (s *Store) Orphans(exists func(component string) bool) ([]Entry, error) {
+	entries, err := s.List()
+	if err != nil {
+		return nil, err
+	}
+	var orphans []Entry
+	for _, entry := range entries {
+		if !exists(entry.Component) {
+			orphans = append(orphans, entry)
+		}
+	}
+	return orphans, nil
+}
+
+func (s *Store) lookup(component, field string) (string, error) {
+	index, err := s.index()
+	if err != nil {
+		return "", err
+	}
+	name := Name(component, field)
+	if entry, exists := index[name]; !exists || entry.Component != component || entry.Field != field {
+		return "", fmt.Errorf("%q %s: %w", component, field, ErrNotFound)
+	}
+	return name, nil
+}
+
+func (s *Store) index() (map[string]Entry, error) {
+	index := make(map[string]Entry)
+	data, err := os.ReadFile(filepath.Join(s.dir, indexFile))
+	if os.IsNotExist(err) {
+		return index, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("reading secrets index: %w", err)
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return nil, fmt.Errorf("decoding secrets index: %w", err)
+	}
+	return index, nil
+}
+
+func (s *Store) writeIndex(index map[string]Entry) error {
+	data, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return err
+	}
+	if err := os.WriteFile(filepath.Join(s.dir, indexFile), data, 0o600); err != nil {
+		return fmt.Errorf("writing secrets index: %w", err)
+	}
+	return nil
+}
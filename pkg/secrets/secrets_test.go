@@ -0,0 +1,84 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package secrets
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestStore(t *testing.T) {
+	dir := filepath.Join(t.TempDir(), Dir)
+	store := New(dir)
+	assert.NoError(t, store.Write("foo", "tls.key", []byte("key"), 0))
+	assert.NoError(t, store.Write("foo", "tls.crt", []byte("crt"), 0o644))
+	assert.NoError(t, store.Write("bar", "tls.key", []byte("key"), 0))
+
+	info, err := os.Stat(dir)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o700), info.Mode().Perm())
+
+	files, err := os.ReadDir(dir)
+	assert.NoError(t, err)
+	assert.Len(t, files, 4)
+	for _, file := range files {
+		assert.NotContains(t, file.Name(), "foo")
+		assert.NotContains(t, file.Name(), "bar")
+	}
+
+	path, err := store.Path("foo", "tls.key")
+	assert.NoError(t, err)
+	assert.Equal(t, filepath.Join(dir, Name("foo", "tls.key")), path)
+	info, err = os.Stat(path)
+	assert.NoError(t, err)
+	assert.Equal(t, os.FileMode(0o600), info.Mode().Perm())
+	data, err := store.Read("foo", "tls.key")
+	assert.NoError(t, err)
+	assert.Equal(t, []byte("key"), data)
+
+	mounted, err := store.MountedPath("foo", "tls.crt")
+	assert.NoError(t, err)
+	assert.Equal(t, MountPath+"/"+Name("foo", "tls.crt"), mounted)
+
+	entries, err := store.List()
+	assert.NoError(t, err)
+	assert.Equal(t, []Entry{
+		{Name: Name("bar", "tls.key"), Component: "bar", Field: "tls.key"},
+		{Name: Name("foo", "tls.crt"), Component: "foo", Field: "tls.crt"},
+		{Name: Name("foo", "tls.key"), Component: "foo", Field: "tls.key"},
+	}, entries)
+
+	orphans, err := store.Orphans(func(component string) bool { return component == "bar" })
+	assert.NoError(t, err)
+	assert.Len(t, orphans, 2)
+
+	assert.NoError(t, store.Remove("foo"))
+	_, err = store.Path("foo", "tls.key")
+	assert.ErrorIs(t, err, ErrNotFound)
+	_, err = os.Stat(path)
+	assert.True(t, os.IsNotExist(err))
+	entries, err = store.List()
+	assert.NoError(t, err)
+	assert.Len(t, entries, 1)
+
+	// removing the unknown component is a no-op
+	assert.NoError(t, store.Remove("foo"))
+	assert.NoError(t, New(t.TempDir()).Remove("foo"))
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package selftest verifies that the broker accepts and routes the events
+// by delivering the test event through it to the in-process receiver.
+package selftest
+
+import (
+	"bufio"
+	"context"
+	"fmt"
+	"io"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/wiretap"
+)
+
+const (
+	// EventType is the reserved type of the events sent by the broker self-test.
+	EventType = "io.triggermesh.tmctl.healthcheck"
+	// EventSource is the source of the self-test events.
+	EventSource = "tmctl-self-test"
+
+	// LogTail is the number of broker log lines attached to the failure.
+	LogTail = 20
+)
+
+// Probe is the delivery of the test events through the broker.
+type Probe struct {
+	EventType string
+	Source    string
+	// Timeout limits the wait for the delivered event.
+	Timeout time.Duration
+	// Interval is the pause between the resent events,
+	// the broker picks up the configuration changes periodically.
+	Interval time.Duration
+}
+
+// New returns the probe of the broker self-test.
+func New(timeout time.Duration) Probe {
+	return Probe{
+		EventType: EventType,
+		Source:    EventSource,
+		Timeout:   timeout,
+		Interval:  2 * time.Second,
+	}
+}
+
+// Verify registers the temporary trigger of the probe event type in the
+// broker config, delivers the event through the broker and removes the
+// trigger. The ID of the received event is returned.
+func (p Probe) Verify(ctx context.Context, configBase, broker, brokerURL string) (string, error) {
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	w, err := wiretap.New(broker, configBase)
+	if err != nil {
+		return "", err
+	}
+	w.Name = fmt.Sprintf("%s-self-test", broker)
+	w.Filters = append(w.Filters, *tmbroker.FilterAttribute("type", p.EventType))
+	events, err := w.Listen(ctx)
+	if err != nil {
+		return "", err
+	}
+	// trigger is removed on any exit path
+	defer func() {
+		if err := w.Cleanup(context.Background()); err != nil {
+			log.Printf("Cleanup: %v", err)
+		}
+	}()
+	if err := w.CreateTrigger(); err != nil {
+		return "", fmt.Errorf("trigger: %w", err)
+	}
+	return p.Deliver(ctx, brokerURL, events)
+}
+
+// Deliver sends the test events to the broker until one of them is received.
+func (p Probe) Deliver(ctx context.Context, brokerURL string, events <-chan cloudevents.Event) (string, error) {
+	c, err := cloudevents.NewClientHTTP()
+	if err != nil {
+		return "", fmt.Errorf("cloudevents client: %w", err)
+	}
+	timeout := time.After(p.Timeout)
+	ticker := time.NewTicker(p.Interval)
+	defer ticker.Stop()
+
+	sent := make(map[string]struct{})
+	send := func() error {
+		event := cloudevents.NewEvent()
+		event.SetID(fmt.Sprintf("%s-%d", p.Source, len(sent)+1))
+		event.SetType(p.EventType)
+		event.SetSource(p.Source)
+		if err := event.SetData(cloudevents.ApplicationJSON, map[string]string{"check": "ping"}); err != nil {
+			return err
+		}
+		if result := c.Send(cloudevents.ContextWithTarget(ctx, brokerURL), event); !cloudevents.IsACK(result) {
+			return fmt.Errorf("broker rejected the event: %w", result)
+		}
+		sent[event.ID()] = struct{}{}
+		return nil
+	}
+	if err := send(); err != nil {
+		return "", err
+	}
+	for {
+		select {
+		case <-timeout:
+			return "", fmt.Errorf("no event received in %s", p.Timeout)
+		case <-ticker.C:
+			if err := send(); err != nil {
+				return "", err
+			}
+		case event, ok := <-events:
+			if !ok {
+				return "", fmt.Errorf("receiver stopped")
+			}
+			if _, ok := sent[event.ID()]; ok && event.Type() == p.EventType {
+				return event.ID(), nil
+			}
+		}
+	}
+}
+
+// Logs returns the last lines of the component log written since the time.
+func Logs(ctx context.Context, c triggermesh.Runnable, since time.Time) []string {
+	logs, err := c.Logs(ctx, since, false)
+	if err != nil {
+		return []string{fmt.Sprintf("logs are not available: %v", err)}
+	}
+	defer logs.Close()
+	return Tail(logs, LogTail)
+}
+
+// Tail returns the last n lines of the reader.
+func Tail(r io.Reader, n int) []string {
+	var lines []string
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lines = append(lines, scanner.Text())
+		if len(lines) > n {
+			lines = lines[1:]
+		}
+	}
+	return lines
+}
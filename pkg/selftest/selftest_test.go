@@ -0,0 +1,159 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package selftest
+
+import (
+	"context"
+	"io"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/test"
+)
+
+func probe(timeout time.Duration) Probe {
+	p := New(timeout)
+	p.Interval = 50 * time.Millisecond
+	return p
+}
+
+// fakeIngest replies with the status and passes the accepted events
+// to the channel, if any.
+func fakeIngest(t *testing.T, status int, events chan<- cloudevents.Event) string {
+	ingest := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		if status < 300 && events != nil {
+			event := cloudevents.NewEvent()
+			event.SetID(r.Header.Get("Ce-Id"))
+			event.SetType(r.Header.Get("Ce-Type"))
+			event.SetSource(r.Header.Get("Ce-Source"))
+			events <- event
+		}
+		w.WriteHeader(status)
+	}))
+	t.Cleanup(ingest.Close)
+	return ingest.URL
+}
+
+func TestDeliver(t *testing.T) {
+	events := make(chan cloudevents.Event, 10)
+	id, err := probe(time.Second).Deliver(context.Background(), fakeIngest(t, http.StatusAccepted, events), events)
+	require.NoError(t, err)
+	assert.Equal(t, EventSource+"-1", id)
+}
+
+func TestDeliverRejected(t *testing.T) {
+	_, err := probe(time.Second).Deliver(context.Background(), fakeIngest(t, http.StatusBadRequest, nil), nil)
+	assert.ErrorContains(t, err, "broker rejected the event")
+}
+
+func TestDeliverNotRouted(t *testing.T) {
+	events := make(chan cloudevents.Event, 10)
+	// events of the other types are not the proof of the delivery
+	events <- cloudevents.NewEvent()
+	_, err := probe(200*time.Millisecond).Deliver(context.Background(), fakeIngest(t, http.StatusAccepted, nil), events)
+	assert.ErrorContains(t, err, "no event received in 200ms")
+}
+
+// fakeBroker accepts the events and routes them to the destinations
+// of the triggers in the broker config matching the event type.
+func fakeBroker(t *testing.T, configBase, broker string) string {
+	b := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		body, err := io.ReadAll(r.Body)
+		require.NoError(t, err)
+		configuration, err := tmbroker.ReadConfig(configBase, broker)
+		require.NoError(t, err)
+		var destinations []string
+		for _, trigger := range configuration.Triggers {
+			if len(trigger.Filters) == 1 && trigger.Filters[0].Exact["type"] == r.Header.Get("Ce-Type") {
+				destinations = append(destinations, trigger.Target.URL)
+			}
+		}
+		header := r.Header.Clone()
+		w.WriteHeader(http.StatusAccepted)
+		go func() {
+			for _, destination := range destinations {
+				// the destination is addressed from the container network
+				u, err := url.Parse(destination)
+				if err != nil {
+					continue
+				}
+				u.Host = "127.0.0.1:" + u.Port()
+				req, err := http.NewRequest(http.MethodPost, u.String(), strings.NewReader(string(body)))
+				if err != nil {
+					continue
+				}
+				req.Header = header
+				if resp, err := http.DefaultClient.Do(req); err == nil {
+					resp.Body.Close()
+				}
+			}
+		}()
+	}))
+	t.Cleanup(b.Close)
+	return b.URL
+}
+
+func TestVerify(t *testing.T) {
+	configBase := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configBase, "foo"), os.ModePerm))
+	data, err := os.ReadFile(filepath.Join(test.ConfigBase(), "broker.conf"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(tmbroker.ConfigPath(configBase, "foo"), data, 0600))
+	initial, err := tmbroker.ReadConfig(configBase, "foo")
+	require.NoError(t, err)
+
+	id, err := probe(5*time.Second).Verify(context.Background(), configBase, "foo", fakeBroker(t, configBase, "foo"))
+	require.NoError(t, err)
+	assert.Equal(t, EventSource+"-1", id)
+
+	// the temporary trigger is removed
+	configuration, err := tmbroker.ReadConfig(configBase, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, initial.Triggers, configuration.Triggers)
+}
+
+func TestVerifyNotRouted(t *testing.T) {
+	configBase := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configBase, "foo"), os.ModePerm))
+	data, err := os.ReadFile(filepath.Join(test.ConfigBase(), "broker.conf"))
+	require.NoError(t, err)
+	require.NoError(t, os.WriteFile(tmbroker.ConfigPath(configBase, "foo"), data, 0600))
+
+	// the broker accepts the events but does not deliver them
+	_, err = probe(300*time.Millisecond).Verify(context.Background(), configBase, "foo", fakeIngest(t, http.StatusAccepted, nil))
+	assert.ErrorContains(t, err, "no event received")
+
+	configuration, err := tmbroker.ReadConfig(configBase, "foo")
+	require.NoError(t, err)
+	assert.NotContains(t, configuration.Triggers, "foo-self-test")
+}
+
+func TestTail(t *testing.T) {
+	assert.Equal(t, []string{"3", "4"}, Tail(strings.NewReader("1\n2\n3\n4\n"), 2))
+	assert.Empty(t, Tail(strings.NewReader(""), 2))
+}
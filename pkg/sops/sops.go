@@ -0,0 +1,232 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package sops reads the files encrypted with SOPS, https://github.com/getsops/sops.
+// The decryption is delegated to the sops binary so that the key material
+// configured for it, e.g. the age identities, is used as is.
+package sops
+
+import (
+	"bytes"
+	"errors"
+	"fmt"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+)
+
+// Decryption modes of the encrypted files.
+const (
+	// DecryptAuto decrypts the files that have the SOPS metadata.
+	DecryptAuto = "auto"
+	// DecryptAlways fails if the file is not encrypted.
+	DecryptAlways = "always"
+	// DecryptNever reads the files as is.
+	DecryptNever = "never"
+)
+
+// Modes are the supported decryption modes.
+var Modes = []string{DecryptAuto, DecryptAlways, DecryptNever}
+
+// couldNotRetrieveKey is the exit code of sops that failed to decrypt
+// the data key with any of the available keys.
+const couldNotRetrieveKey = 128
+
+// command is the sops binary.
+var command = "sops"
+
+// metadata is the part of the sops section describing the master keys.
+type metadata struct {
+	Age []struct {
+		Recipient string `yaml:"recipient"`
+	} `yaml:"age"`
+	PGP []struct {
+		Fingerprint string `yaml:"fp"`
+	} `yaml:"pgp"`
+	KMS []struct {
+		ARN string `yaml:"arn"`
+	} `yaml:"kms"`
+	GCPKMS []struct {
+		ResourceID string `yaml:"resource_id"`
+	} `yaml:"gcp_kms"`
+	AzureKV []struct {
+		VaultURL string `yaml:"vault_url"`
+		Name     string `yaml:"name"`
+	} `yaml:"azure_kv"`
+	HCVault []struct {
+		Address string `yaml:"vault_address"`
+		Engine  string `yaml:"engine_path"`
+		Name    string `yaml:"key_name"`
+	} `yaml:"hc_vault"`
+	KeyGroups []metadata `yaml:"key_groups"`
+	MAC       string     `yaml:"mac"`
+}
+
+// ValidateMode checks the decryption mode.
+func ValidateMode(mode string) error {
+	for _, m := range Modes {
+		if mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown decryption mode %q, must be one of %s", mode, strings.Join(Modes, ", "))
+}
+
+// ReadFile returns the contents of the file, decrypted if it is encrypted
+// with SOPS and the mode allows it. The decrypted contents are kept
+// in memory only.
+func ReadFile(path, mode string) (data []byte, decrypted bool, err error) {
+	if data, err = os.ReadFile(path); err != nil {
+		return nil, false, err
+	}
+	if mode == DecryptNever {
+		return data, false, nil
+	}
+	keys, encrypted := Keys(data)
+	if !encrypted {
+		if mode == DecryptAlways {
+			return nil, false, fmt.Errorf("file is not encrypted with SOPS")
+		}
+		return data, false, nil
+	}
+	if data, err = decrypt(path, format(data), keys); err != nil {
+		return nil, false, err
+	}
+	return data, true, nil
+}
+
+// Keys returns the master keys the SOPS encrypted contents can be decrypted
+// with. False is returned if the contents have no SOPS metadata.
+func Keys(data []byte) ([]string, bool) {
+	decoder := yaml.NewDecoder(bytes.NewReader(data))
+	for {
+		var document struct {
+			SOPS *metadata `yaml:"sops"`
+		}
+		if err := decoder.Decode(&document); err != nil {
+			// not a YAML or JSON document, nothing to decrypt
+			return nil, false
+		}
+		if document.SOPS != nil && document.SOPS.MAC != "" {
+			return document.SOPS.keys(), true
+		}
+	}
+}
+
+func (m metadata) keys() []string {
+	var keys []string
+	for _, k := range m.Age {
+		keys = append(keys, "age recipient "+k.Recipient)
+	}
+	for _, k := range m.PGP {
+		keys = append(keys, "PGP key "+k.Fingerprint)
+	}
+	for _, k := range m.KMS {
+		keys = append(keys, "AWS KMS key "+k.ARN)
+	}
+	for _, k := range m.GCPKMS {
+		keys = append(keys, "GCP KMS key "+k.ResourceID)
+	}
+	for _, k := range m.AzureKV {
+		keys = append(keys, "Azure Key Vault key "+strings.TrimSuffix(k.VaultURL, "/")+"/keys/"+k.Name)
+	}
+	for _, k := range m.HCVault {
+		keys = append(keys, "Vault transit key "+strings.TrimSuffix(k.Address, "/")+"/v1/"+k.Engine+"/keys/"+k.Name)
+	}
+	for _, group := range m.KeyGroups {
+		keys = append(keys, group.keys()...)
+	}
+	return keys
+}
+
+// format returns the sops input type of the contents. The files encrypted
+// in the binary mode are JSON documents with the data and sops fields.
+func format(data []byte) string {
+	trimmed := bytes.TrimSpace(data)
+	if len(trimmed) == 0 || trimmed[0] != '{' {
+		return "yaml"
+	}
+	var document map[string]interface{}
+	if err := yaml.Unmarshal(trimmed, &document); err == nil && len(document) == 2 {
+		if _, ok := document["data"].(string); ok {
+			return "binary"
+		}
+	}
+	return "json"
+}
+
+func decrypt(path, format string, keys []string) ([]byte, error) {
+	var stdout, stderr bytes.Buffer
+	cmd := exec.Command(command, "--decrypt", "--input-type", format, "--output-type", format, path)
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	err := cmd.Run()
+	if errors.Is(err, exec.ErrNotFound) {
+		return nil, fmt.Errorf("sops binary is required to decrypt the file encrypted for %s: %w", expected(keys), err)
+	}
+	var exitErr *exec.ExitError
+	if errors.As(err, &exitErr) {
+		if exitErr.ExitCode() == couldNotRetrieveKey || strings.Contains(stderr.String(), "Failed to get the data key") {
+			return nil, fmt.Errorf("none of the keys the file is encrypted for is available: %s%s", expected(keys), ageHint(keys))
+		}
+		if line := lastLine(stderr.Bytes()); line != "" {
+			return nil, fmt.Errorf("sops decryption failed: %s", line)
+		}
+		return nil, fmt.Errorf("sops decryption failed: %w", err)
+	}
+	if err != nil {
+		return nil, fmt.Errorf("sops: %w", err)
+	}
+	return stdout.Bytes(), nil
+}
+
+func expected(keys []string) string {
+	if len(keys) == 0 {
+		return "unknown keys"
+	}
+	return strings.Join(keys, ", ")
+}
+
+// ageHint tells where sops looks for the age identities.
+func ageHint(keys []string) string {
+	for _, key := range keys {
+		if !strings.HasPrefix(key, "age ") {
+			continue
+		}
+		if path := os.Getenv("SOPS_AGE_KEY_FILE"); path != "" {
+			return fmt.Sprintf(". The age identities are read from %s", path)
+		}
+		if os.Getenv("SOPS_AGE_KEY") != "" {
+			return ". The age identities are read from SOPS_AGE_KEY"
+		}
+		dir, err := os.UserConfigDir()
+		if err != nil {
+			return ". Set SOPS_AGE_KEY_FILE to the age identities file"
+		}
+		return fmt.Sprintf(". The age identities are read from %s or SOPS_AGE_KEY_FILE", filepath.Join(dir, "sops", "age", "keys.txt"))
+	}
+	return ""
+}
+
+// lastLine returns the last non-empty line of the sops output,
+// the summary of the failure.
+func lastLine(output []byte) string {
+	lines := strings.Split(strings.TrimSpace(string(output)), "\n")
+	return strings.TrimSpace(lines[len(lines)-1])
+}
@@ -0,0 +1,140 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package sops
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+const encrypted = `apiVersion: v1
+kind: Secret
+metadata:
+  name: foo-awss3source-secret
+data:
+  awsApiKey: ENC[AES256_GCM,data:RkFLRQ==,iv:aXY=,tag:dGFn,type:str]
+sops:
+  age:
+    - recipient: age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p
+      enc: |
+        -----BEGIN AGE ENCRYPTED FILE-----
+        -----END AGE ENCRYPTED FILE-----
+  key_groups:
+    - pgp:
+        - fp: FBC7B9E2A4F9289AC0C1D4843D16CEE4A27381B4
+  lastmodified: "2023-01-01T00:00:00Z"
+  mac: ENC[AES256_GCM,data:bWFj,iv:aXY=,tag:dGFn,type:str]
+  version: 3.7.3
+`
+
+// fakeSops replaces the sops binary with the script.
+func fakeSops(t *testing.T, script string) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "sops")
+	require.NoError(t, os.WriteFile(path, []byte("#!/bin/sh\n"+script+"\n"), 0755))
+	command = path
+	t.Cleanup(func() { command = "sops" })
+}
+
+func writeFile(t *testing.T, data string) string {
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	require.NoError(t, os.WriteFile(path, []byte(data), 0600))
+	return path
+}
+
+func TestKeys(t *testing.T) {
+	keys, ok := Keys([]byte(encrypted))
+	assert.True(t, ok)
+	assert.Equal(t, []string{
+		"age recipient age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p",
+		"PGP key FBC7B9E2A4F9289AC0C1D4843D16CEE4A27381B4",
+	}, keys)
+
+	_, ok = Keys([]byte(`{"data": "ENC[AES256_GCM,data:RkFLRQ==,iv:aXY=,tag:dGFn,type:str]", "sops": {"kms": [{"arn": "arn:aws:kms:us-east-1:123:key/abc"}], "mac": "ENC[...]"}}`))
+	assert.True(t, ok)
+
+	for _, data := range []string{
+		"apiVersion: v1\nkind: Secret\n",
+		"---\nfoo: bar\n---\nsops: {}\n",
+		"data:\n- operation: add\n",
+		". as $in | $in",
+	} {
+		_, ok := Keys([]byte(data))
+		assert.False(t, ok, data)
+	}
+}
+
+func TestFormat(t *testing.T) {
+	assert.Equal(t, "yaml", format([]byte(encrypted)))
+	assert.Equal(t, "json", format([]byte(`{"spec": {"token": "ENC[...]"}, "sops": {}}`)))
+	assert.Equal(t, "binary", format([]byte(`{"data": "ENC[...]", "sops": {}}`)))
+}
+
+func TestReadFile(t *testing.T) {
+	fakeSops(t, `echo "$@" >&2; printf 'apiVersion: v1\nkind: Secret\n'`)
+
+	path := writeFile(t, encrypted)
+	data, decrypted, err := ReadFile(path, DecryptAuto)
+	assert.NoError(t, err)
+	assert.True(t, decrypted)
+	assert.Equal(t, "apiVersion: v1\nkind: Secret\n", string(data))
+
+	data, decrypted, err = ReadFile(path, DecryptNever)
+	assert.NoError(t, err)
+	assert.False(t, decrypted)
+	assert.Equal(t, encrypted, string(data))
+
+	plain := writeFile(t, "apiVersion: v1\nkind: Secret\n")
+	data, decrypted, err = ReadFile(plain, DecryptAuto)
+	assert.NoError(t, err)
+	assert.False(t, decrypted)
+	assert.Equal(t, "apiVersion: v1\nkind: Secret\n", string(data))
+
+	_, _, err = ReadFile(plain, DecryptAlways)
+	assert.EqualError(t, err, "file is not encrypted with SOPS")
+}
+
+func TestReadFileErrors(t *testing.T) {
+	t.Setenv("SOPS_AGE_KEY_FILE", "/home/foo/keys.txt")
+	path := writeFile(t, encrypted)
+
+	fakeSops(t, `echo "Failed to get the data key required to decrypt the SOPS file." >&2; echo "ENC[AES256_GCM,data:RkFLRQ==]" >&2; exit 128`)
+	_, _, err := ReadFile(path, DecryptAuto)
+	assert.EqualError(t, err, "none of the keys the file is encrypted for is available: "+
+		"age recipient age1ql3z7hjy54pw3hyww5ayyfg7zqgvc7w3j2elw8zmrj2kg5sfn9aqmcac8p, "+
+		"PGP key FBC7B9E2A4F9289AC0C1D4843D16CEE4A27381B4. "+
+		"The age identities are read from /home/foo/keys.txt")
+
+	fakeSops(t, `echo "details" >&2; echo "Error: MAC mismatch" >&2; exit 51`)
+	_, _, err = ReadFile(path, DecryptAuto)
+	assert.EqualError(t, err, "sops decryption failed: Error: MAC mismatch")
+
+	command = "tmctl-test-missing-sops"
+	_, _, err = ReadFile(path, DecryptAuto)
+	assert.ErrorContains(t, err, "sops binary is required to decrypt the file encrypted for age recipient")
+}
+
+func TestValidateMode(t *testing.T) {
+	for _, mode := range Modes {
+		assert.NoError(t, ValidateMode(mode))
+	}
+	assert.Error(t, ValidateMode("true"))
+}
@@ -0,0 +1,432 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package templates stores the parameterized component definitions
+// shared by the broker contexts.
+package templates
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+const (
+	storeDir    = "templates"
+	templateExt = ".yaml"
+)
+
+// Types of the templated field values.
+const (
+	TypeString  = "string"
+	TypeNumber  = "number"
+	TypeBoolean = "boolean"
+)
+
+// ErrNotFound is returned when the template does not exist.
+var ErrNotFound = errors.New("template not found")
+
+// Param is the templated spec field.
+type Param struct {
+	Name string `yaml:"name"`
+	// Path is the dotted path of the spec field.
+	Path string `yaml:"path"`
+	// Type is the type of the field value, string if empty.
+	Type string `yaml:"type,omitempty"`
+	// Secret values are kept in the component secret
+	// and are never stored in the template.
+	Secret bool `yaml:"secret,omitempty"`
+}
+
+// Template is the component spec with the templated fields.
+type Template struct {
+	Name       string                 `yaml:"name"`
+	APIVersion string                 `yaml:"apiVersion"`
+	Kind       string                 `yaml:"kind"`
+	Params     []Param                `yaml:"params,omitempty"`
+	Spec       map[string]interface{} `yaml:"spec"`
+}
+
+// Store is the local storage of the templates in the tmctl home.
+type Store struct {
+	path string
+}
+
+// New returns the template store of the config home.
+func New(configBase string) *Store {
+	return &Store{
+		path: filepath.Join(configBase, storeDir),
+	}
+}
+
+// Save writes the template to the store, the existing
+// template is replaced only if overwrite is set.
+func (s *Store) Save(t *Template, overwrite bool) error {
+	if err := t.Validate(); err != nil {
+		return err
+	}
+	if _, err := os.Stat(s.file(t.Name)); err == nil && !overwrite {
+		return fmt.Errorf("template %q already exists", t.Name)
+	}
+	data, err := t.Marshal()
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(s.path, os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(s.file(t.Name), data, 0600)
+}
+
+// Get returns the stored template.
+func (s *Store) Get(name string) (*Template, error) {
+	data, err := os.ReadFile(s.file(name))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%q: %w", name, ErrNotFound)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return Parse(data)
+}
+
+// Delete removes the template from the store.
+func (s *Store) Delete(name string) error {
+	err := os.Remove(s.file(name))
+	if os.IsNotExist(err) {
+		return fmt.Errorf("%q: %w", name, ErrNotFound)
+	}
+	return err
+}
+
+// List returns the stored templates sorted by name.
+func (s *Store) List() ([]Template, error) {
+	entries, err := os.ReadDir(s.path)
+	if os.IsNotExist(err) {
+		return []Template{}, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	var result []Template
+	for _, entry := range entries {
+		if entry.IsDir() || filepath.Ext(entry.Name()) != templateExt {
+			continue
+		}
+		t, err := s.Get(strings.TrimSuffix(entry.Name(), templateExt))
+		if err != nil {
+			return nil, fmt.Errorf("template %q: %w", entry.Name(), err)
+		}
+		result = append(result, *t)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Name < result[j].Name })
+	return result, nil
+}
+
+func (s *Store) file(name string) string {
+	return filepath.Join(s.path, name+templateExt)
+}
+
+// Parse decodes and validates the template, e.g. the exported one.
+func Parse(data []byte) (*Template, error) {
+	var t Template
+	if err := yaml.Unmarshal(data, &t); err != nil {
+		return nil, fmt.Errorf("decoding template: %w", err)
+	}
+	if err := t.Validate(); err != nil {
+		return nil, err
+	}
+	return &t, nil
+}
+
+// Marshal encodes the template.
+func (t *Template) Marshal() ([]byte, error) {
+	return yaml.Marshal(t)
+}
+
+// Validate checks that the template is complete and its params
+// point to the distinct spec fields.
+func (t *Template) Validate() error {
+	if err := triggermesh.ValidateName(t.Name); err != nil {
+		return fmt.Errorf("template name: %w", err)
+	}
+	if t.APIVersion == "" || t.Kind == "" {
+		return fmt.Errorf("template %q: apiVersion and kind are required", t.Name)
+	}
+	names := make(map[string]bool, len(t.Params))
+	paths := make(map[string]bool, len(t.Params))
+	for _, p := range t.Params {
+		switch {
+		case p.Name == "" || p.Path == "":
+			return fmt.Errorf("template %q: param name and path are required", t.Name)
+		case names[p.Name]:
+			return fmt.Errorf("template %q: duplicate param %q", t.Name, p.Name)
+		case paths[p.Path]:
+			return fmt.Errorf("template %q: field %q is templated twice", t.Name, p.Path)
+		}
+		switch p.Type {
+		case "", TypeString, TypeNumber, TypeBoolean:
+		default:
+			return fmt.Errorf("template %q: param %q has unknown type %q", t.Name, p.Name, p.Type)
+		}
+		names[p.Name] = true
+		paths[p.Path] = true
+	}
+	return nil
+}
+
+// FromObject creates the template of the manifest object. The params select
+// the templated fields: "name" matches the spec field of the same name,
+// "name=path" selects the field by its dotted path. The secret fields of the
+// object are always templated, the returned params are the ones added
+// for them. The sink of the source object is set when the template is used.
+func FromObject(name string, object kubernetes.Object, params, secretFields []string) (*Template, []Param, error) {
+	spec := copyMap(object.Spec)
+	delete(spec, "sink")
+	t := &Template{
+		Name:       name,
+		APIVersion: object.APIVersion,
+		Kind:       object.Kind,
+		Spec:       spec,
+	}
+	fields := leaves("", spec, secretFields)
+	secret := make(map[string]bool, len(secretFields))
+	for _, field := range secretFields {
+		secret[field] = true
+	}
+	for _, param := range params {
+		p := Param{Name: param}
+		if kv := strings.SplitN(param, "=", 2); len(kv) == 2 {
+			p.Name, p.Path = kv[0], kv[1]
+			if _, exists := fields[p.Path]; !exists {
+				return nil, nil, fmt.Errorf("param %q: %s has no field %q, available fields: %s",
+					p.Name, object.Metadata.Name, p.Path, strings.Join(sortedKeys(fields), ", "))
+			}
+		} else {
+			path, err := lookupField(p.Name, fields)
+			if err != nil {
+				return nil, nil, fmt.Errorf("param %q: %w", p.Name, err)
+			}
+			p.Path = path
+		}
+		p.Type = valueType(fields[p.Path])
+		p.Secret = secret[p.Path]
+		t.Params = append(t.Params, p)
+	}
+	var added []Param
+	for _, field := range sortedKeys(fields) {
+		if !secret[field] || t.param(field) != nil {
+			continue
+		}
+		p := Param{Name: field[strings.LastIndex(field, ".")+1:], Path: field, Secret: true}
+		if t.paramNamed(p.Name) != nil {
+			p.Name = strings.ReplaceAll(field, ".", "-")
+		}
+		t.Params = append(t.Params, p)
+		added = append(added, p)
+	}
+	for _, p := range t.Params {
+		setField(t.Spec, p.Path, "{{ "+p.Name+" }}")
+	}
+	if err := t.Validate(); err != nil {
+		return nil, nil, err
+	}
+	return t, added, nil
+}
+
+// Instantiate returns the component spec with the param values.
+// Every param requires the value.
+func (t *Template) Instantiate(values map[string]string) (map[string]interface{}, error) {
+	for name := range values {
+		if t.paramNamed(name) == nil {
+			return nil, fmt.Errorf("template %q has no param %q", t.Name, name)
+		}
+	}
+	var missing []string
+	spec := copyMap(t.Spec)
+	for _, p := range t.Params {
+		value, set := values[p.Name]
+		if !set {
+			missing = append(missing, p.Name)
+			continue
+		}
+		typed, err := p.parse(value)
+		if err != nil {
+			return nil, err
+		}
+		setField(spec, p.Path, typed)
+	}
+	if len(missing) != 0 {
+		return nil, fmt.Errorf("template %q params are not set: %s", t.Name, strings.Join(missing, ", "))
+	}
+	return spec, nil
+}
+
+func (t *Template) param(path string) *Param {
+	for i := range t.Params {
+		if t.Params[i].Path == path {
+			return &t.Params[i]
+		}
+	}
+	return nil
+}
+
+func (t *Template) paramNamed(name string) *Param {
+	for i := range t.Params {
+		if t.Params[i].Name == name {
+			return &t.Params[i]
+		}
+	}
+	return nil
+}
+
+func (p Param) parse(value string) (interface{}, error) {
+	switch p.Type {
+	case TypeNumber:
+		if i, err := strconv.ParseInt(value, 10, 64); err == nil {
+			return i, nil
+		}
+		f, err := strconv.ParseFloat(value, 64)
+		if err != nil {
+			return nil, fmt.Errorf("param %q: %q is not a number", p.Name, value)
+		}
+		return f, nil
+	case TypeBoolean:
+		b, err := strconv.ParseBool(value)
+		if err != nil {
+			return nil, fmt.Errorf("param %q: %q is not a boolean", p.Name, value)
+		}
+		return b, nil
+	}
+	return value, nil
+}
+
+// leaves returns the spec fields that can be templated by their dotted paths:
+// the scalar values and the secret fields.
+func leaves(prefix string, spec map[string]interface{}, secretFields []string) map[string]interface{} {
+	result := make(map[string]interface{})
+	for key, value := range spec {
+		path := prefix + key
+		nested, isMap := value.(map[string]interface{})
+		switch {
+		case contains(secretFields, path):
+			result[path] = ""
+		case isMap:
+			for k, v := range leaves(path+".", nested, secretFields) {
+				result[k] = v
+			}
+		default:
+			if _, isList := value.([]interface{}); !isList {
+				result[path] = value
+			}
+		}
+	}
+	return result
+}
+
+// lookupField returns the path of the field with the name,
+// the name must match exactly one field.
+func lookupField(name string, fields map[string]interface{}) (string, error) {
+	if _, exists := fields[name]; exists {
+		return name, nil
+	}
+	var matches []string
+	for path := range fields {
+		if strings.EqualFold(path[strings.LastIndex(path, ".")+1:], name) {
+			matches = append(matches, path)
+		}
+	}
+	sort.Strings(matches)
+	switch len(matches) {
+	case 0:
+		return "", fmt.Errorf("no such field, use \"%s=<path>\" with one of: %s", name, strings.Join(sortedKeys(fields), ", "))
+	case 1:
+		return matches[0], nil
+	}
+	return "", fmt.Errorf("ambiguous field, use \"%s=<path>\" with one of: %s", name, strings.Join(matches, ", "))
+}
+
+func setField(spec map[string]interface{}, path string, value interface{}) {
+	keys := strings.Split(path, ".")
+	for _, key := range keys[:len(keys)-1] {
+		nested, ok := spec[key].(map[string]interface{})
+		if !ok {
+			nested = make(map[string]interface{})
+			spec[key] = nested
+		}
+		spec = nested
+	}
+	spec[keys[len(keys)-1]] = value
+}
+
+func valueType(value interface{}) string {
+	switch value.(type) {
+	case bool:
+		return TypeBoolean
+	case int, int64, float64:
+		return TypeNumber
+	}
+	return ""
+}
+
+func copyMap(m map[string]interface{}) map[string]interface{} {
+	result := make(map[string]interface{}, len(m))
+	for k, v := range m {
+		result[k] = copyValue(v)
+	}
+	return result
+}
+
+func copyValue(v interface{}) interface{} {
+	switch value := v.(type) {
+	case map[string]interface{}:
+		return copyMap(value)
+	case []interface{}:
+		list := make([]interface{}, len(value))
+		for i := range value {
+			list[i] = copyValue(value[i])
+		}
+		return list
+	}
+	return v
+}
+
+func sortedKeys(m map[string]interface{}) []string {
+	keys := make([]string, 0, len(m))
+	for k := range m {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+func contains(list []string, value string) bool {
+	for _, v := range list {
+		if v == value {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,135 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package templates
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+)
+
+func httpTarget() kubernetes.Object {
+	return kubernetes.Object{
+		APIVersion: "targets.triggermesh.io/v1alpha1",
+		Kind:       "HTTPTarget",
+		Metadata:   kubernetes.Metadata{Name: "orders"},
+		Spec: map[string]interface{}{
+			"endpoint": "https://example.com/orders",
+			"method":   "POST",
+			"headers": map[string]interface{}{
+				"Authorization": "Bearer abc",
+			},
+			"skipVerify": false,
+			"basicAuthPassword": map[string]interface{}{
+				"valueFromSecret": map[string]interface{}{"name": "orders-secret", "key": "basicAuthPassword"},
+			},
+		},
+	}
+}
+
+func TestFromObject(t *testing.T) {
+	tmpl, added, err := FromObject("corp-http", httpTarget(), []string{"url=endpoint", "authorization", "skipVerify"}, []string{"basicAuthPassword"})
+	require.NoError(t, err)
+	assert.Equal(t, []Param{{Name: "basicAuthPassword", Path: "basicAuthPassword", Secret: true}}, added)
+	assert.Equal(t, []Param{
+		{Name: "url", Path: "endpoint"},
+		{Name: "authorization", Path: "headers.Authorization"},
+		{Name: "skipVerify", Path: "skipVerify", Type: TypeBoolean},
+		{Name: "basicAuthPassword", Path: "basicAuthPassword", Secret: true},
+	}, tmpl.Params)
+	assert.Equal(t, map[string]interface{}{
+		"endpoint":          "{{ url }}",
+		"method":            "POST",
+		"headers":           map[string]interface{}{"Authorization": "{{ authorization }}"},
+		"skipVerify":        "{{ skipVerify }}",
+		"basicAuthPassword": "{{ basicAuthPassword }}",
+	}, tmpl.Spec)
+
+	_, _, err = FromObject("corp-http", httpTarget(), []string{"token"}, nil)
+	assert.ErrorContains(t, err, `param "token": no such field`)
+	_, _, err = FromObject("corp-http", httpTarget(), []string{"token=headers.Token"}, nil)
+	assert.ErrorContains(t, err, `orders has no field "headers.Token"`)
+	_, _, err = FromObject("corp-http", httpTarget(), []string{"url=endpoint", "endpoint"}, nil)
+	assert.ErrorContains(t, err, `field "endpoint" is templated twice`)
+}
+
+func TestInstantiate(t *testing.T) {
+	tmpl, _, err := FromObject("corp-http", httpTarget(), []string{"url=endpoint", "authorization", "skipVerify"}, []string{"basicAuthPassword"})
+	require.NoError(t, err)
+
+	spec, err := tmpl.Instantiate(map[string]string{
+		"url":               "https://example.com/billing",
+		"authorization":     "Bearer xyz",
+		"skipVerify":        "true",
+		"basicAuthPassword": "secret",
+	})
+	require.NoError(t, err)
+	assert.Equal(t, map[string]interface{}{
+		"endpoint":          "https://example.com/billing",
+		"method":            "POST",
+		"headers":           map[string]interface{}{"Authorization": "Bearer xyz"},
+		"skipVerify":        true,
+		"basicAuthPassword": "secret",
+	}, spec)
+	// the template is not changed
+	assert.Equal(t, "{{ url }}", tmpl.Spec["endpoint"])
+
+	_, err = tmpl.Instantiate(map[string]string{"url": "https://example.com"})
+	assert.EqualError(t, err, `template "corp-http" params are not set: authorization, skipVerify, basicAuthPassword`)
+	_, err = tmpl.Instantiate(map[string]string{"region": "eu"})
+	assert.EqualError(t, err, `template "corp-http" has no param "region"`)
+	_, err = tmpl.Instantiate(map[string]string{"url": "u", "authorization": "a", "skipVerify": "maybe", "basicAuthPassword": "p"})
+	assert.EqualError(t, err, `param "skipVerify": "maybe" is not a boolean`)
+}
+
+func TestStore(t *testing.T) {
+	store := New(t.TempDir())
+	list, err := store.List()
+	require.NoError(t, err)
+	assert.Empty(t, list)
+
+	tmpl, _, err := FromObject("corp-http", httpTarget(), []string{"url=endpoint"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, store.Save(tmpl, false))
+	assert.EqualError(t, store.Save(tmpl, false), `template "corp-http" already exists`)
+	require.NoError(t, store.Save(tmpl, true))
+
+	saved, err := store.Get("corp-http")
+	require.NoError(t, err)
+	assert.Equal(t, tmpl, saved)
+	list, err = store.List()
+	require.NoError(t, err)
+	assert.Equal(t, []Template{*tmpl}, list)
+
+	data, err := saved.Marshal()
+	require.NoError(t, err)
+	imported, err := Parse(data)
+	require.NoError(t, err)
+	assert.Equal(t, tmpl, imported)
+
+	require.NoError(t, store.Delete("corp-http"))
+	_, err = store.Get("corp-http")
+	assert.True(t, errors.Is(err, ErrNotFound))
+	assert.True(t, errors.Is(store.Delete("corp-http"), ErrNotFound))
+
+	_, err = Parse([]byte("name: corp-http\nkind: HTTPTarget\n"))
+	assert.EqualError(t, err, `template "corp-http": apiVersion and kind are required`)
+}
@@ -0,0 +1,224 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package throughput computes the rates of the events flowing
+// through the broker components from the broker backend stream.
+package throughput
+
+import (
+	"fmt"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/deliveries"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/route"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// Rate windows.
+const (
+	Short = time.Minute
+	Long  = 5 * time.Minute
+)
+
+// NotAvailable is printed instead of the rates when the broker
+// does not keep the accepted events in an inspectable backend.
+const NotAvailable = "n/a"
+
+// Rate is the number of events per minute averaged over the rate windows.
+type Rate struct {
+	In1m  float64 `json:"in1m"`
+	In5m  float64 `json:"in5m"`
+	Out1m float64 `json:"out1m"`
+	Out5m float64 `json:"out5m"`
+}
+
+// In returns the incoming rates formatted as "1m/5m".
+func (r Rate) In() string {
+	return format(r.In1m, r.In5m)
+}
+
+// Out returns the outgoing rates formatted as "1m/5m".
+func (r Rate) Out() string {
+	return format(r.Out1m, r.Out5m)
+}
+
+func format(short, long float64) string {
+	return fmt.Sprintf("%.1f/%.1f", short, long)
+}
+
+// Rates are the event rates of the context components.
+type Rates struct {
+	counts map[string]*counts
+	// routed are the components the triggers deliver the events to
+	routed map[string]bool
+}
+
+// Load reads the events accepted by the context broker during the long
+// window and computes the component rates. deliveries.ErrUnsupported
+// is returned if the broker backend does not expose the events.
+func Load(c *config.Config, m *manifest.Manifest, crds map[string]crd.CRD) (*Rates, error) {
+	q, err := deliveries.Open(c.ConfigHome, c.Context, c.Triggermesh.Broker)
+	if err != nil {
+		return nil, err
+	}
+	defer q.Close()
+	now := time.Now()
+	entries, err := q.Since(now.Add(-Long))
+	if err != nil {
+		return nil, fmt.Errorf("broker events: %w", err)
+	}
+	return Compute(entries, now, c.Context, q.Triggers(), Producers(c, m, crds)), nil
+}
+
+// Compute counts the events accepted by the broker as the output of their
+// producers and the events matched by the triggers as the input of the
+// triggers and their target components. The broker receives all events and
+// sends out the matched ones.
+func Compute(entries []deliveries.Entry, now time.Time, broker string, triggers map[string]tmbroker.LocalTriggerSpec, producers map[string]string) *Rates {
+	r := &Rates{
+		counts: make(map[string]*counts),
+		routed: make(map[string]bool),
+	}
+	for _, spec := range triggers {
+		if spec.Target.Component != "" {
+			r.routed[spec.Target.Component] = true
+		}
+	}
+	for _, entry := range entries {
+		age := now.Sub(entry.Time)
+		if age > Long {
+			continue
+		}
+		recent := age <= Short
+		r.count(broker, recent, true)
+		if producer := producers[entry.Event.Source()]; producer != "" {
+			r.count(producer, recent, false)
+		}
+		event := attributes(entry.Event)
+		for name, spec := range triggers {
+			if decision, _ := route.Evaluate(spec.Filters, event); decision != route.Matched {
+				continue
+			}
+			r.count(name, recent, true)
+			r.count(name, recent, false)
+			r.count(broker, recent, false)
+			if spec.Target.Component != "" {
+				r.count(spec.Target.Component, recent, true)
+			}
+		}
+	}
+	return r
+}
+
+// counts are the numbers of the events in the short and long windows.
+type counts struct {
+	in1m, in5m, out1m, out5m int
+}
+
+func (r *Rates) count(component string, recent, in bool) {
+	c, exists := r.counts[component]
+	if !exists {
+		c = &counts{}
+		r.counts[component] = c
+	}
+	switch {
+	case in && recent:
+		c.in1m++
+		c.in5m++
+	case in:
+		c.in5m++
+	case recent:
+		c.out1m++
+		c.out5m++
+	default:
+		c.out5m++
+	}
+}
+
+// Of returns the rates of the component.
+func (r *Rates) Of(component string) Rate {
+	c, exists := r.counts[component]
+	if !exists {
+		return Rate{}
+	}
+	return Rate{
+		In1m:  float64(c.in1m) / Short.Minutes(),
+		In5m:  float64(c.in5m) / Long.Minutes(),
+		Out1m: float64(c.out1m) / Short.Minutes(),
+		Out5m: float64(c.out5m) / Long.Minutes(),
+	}
+}
+
+// Idle returns true if the triggers route the events to the component
+// but it has not received any during the long window.
+func (r *Rates) Idle(component string) bool {
+	c, exists := r.counts[component]
+	return r.routed[component] && (!exists || c.in5m == 0)
+}
+
+// Producers returns the names of the components indexed by their event source.
+func Producers(c *config.Config, m *manifest.Manifest, crds map[string]crd.CRD) map[string]string {
+	result := make(map[string]string)
+	for _, object := range m.Objects {
+		component, err := components.GetObject(object.Metadata.Name, c, m, crds)
+		if err != nil || component == nil {
+			continue
+		}
+		producer, ok := component.(triggermesh.Producer)
+		if !ok {
+			continue
+		}
+		if source, err := producer.GetEventSource(); err == nil && source != "" {
+			result[source] = component.GetName()
+		}
+	}
+	return result
+}
+
+// attributes returns the context attributes and extensions
+// of the event in the form evaluated by the trigger filters.
+func attributes(event cloudevents.Event) route.Event {
+	result := route.Event{
+		"specversion": event.SpecVersion(),
+		"id":          event.ID(),
+		"type":        event.Type(),
+		"source":      event.Source(),
+	}
+	optional := map[string]string{
+		"subject":         event.Subject(),
+		"datacontenttype": event.DataContentType(),
+		"dataschema":      event.DataSchema(),
+	}
+	for name, value := range optional {
+		if value != "" {
+			result[name] = value
+		}
+	}
+	if !event.Time().IsZero() {
+		result["time"] = event.Time().Format(time.RFC3339Nano)
+	}
+	for name, value := range event.Extensions() {
+		result[name] = fmt.Sprint(value)
+	}
+	return result
+}
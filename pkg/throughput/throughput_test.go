@@ -0,0 +1,72 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package throughput
+
+import (
+	"testing"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+	"github.com/stretchr/testify/assert"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/deliveries"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+func entry(at time.Time, eventType string) deliveries.Entry {
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetType(eventType)
+	event.SetSource("shop")
+	return deliveries.Entry{Time: at, Event: event}
+}
+
+func TestCompute(t *testing.T) {
+	now := time.Now()
+	entries := []deliveries.Entry{
+		entry(now.Add(-10*time.Minute), "order.created"),
+		entry(now.Add(-3*time.Minute), "order.created"),
+		entry(now.Add(-30*time.Second), "order.created"),
+		entry(now.Add(-20*time.Second), "order.paid"),
+	}
+	triggers := map[string]tmbroker.LocalTriggerSpec{
+		"foo-trigger": {
+			Filters: []eventingbroker.Filter{{Exact: map[string]string{"type": "order.created"}}},
+			Target:  tmbroker.LocalTarget{Component: "foo-target"},
+		},
+		"bar-trigger": {
+			Filters: []eventingbroker.Filter{{Exact: map[string]string{"type": "order.shipped"}}},
+			Target:  tmbroker.LocalTarget{Component: "bar-target"},
+		},
+	}
+	rates := Compute(entries, now, "foo", triggers, map[string]string{"shop": "foo-source"})
+
+	assert.Equal(t, Rate{In1m: 2, In5m: 0.6, Out1m: 1, Out5m: 0.4}, rates.Of("foo"))
+	assert.Equal(t, Rate{Out1m: 2, Out5m: 0.6}, rates.Of("foo-source"))
+	assert.Equal(t, Rate{In1m: 1, In5m: 0.4, Out1m: 1, Out5m: 0.4}, rates.Of("foo-trigger"))
+	assert.Equal(t, Rate{In1m: 1, In5m: 0.4}, rates.Of("foo-target"))
+	assert.Equal(t, Rate{}, rates.Of("bar-target"))
+
+	assert.False(t, rates.Idle("foo-target"))
+	assert.True(t, rates.Idle("bar-target"))
+	assert.False(t, rates.Idle("foo-source"))
+
+	assert.Equal(t, "1.0/0.4", rates.Of("foo-target").In())
+	assert.Equal(t, "0.0/0.0", rates.Of("foo-target").Out())
+}
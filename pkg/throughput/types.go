@@ -0,0 +1,193 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package throughput
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"time"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/deliveries"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/observed"
+	"github.com/triggermesh/tmctl/pkg/route"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// origins of the event type counts
+const (
+	FromBroker   = "broker"
+	FromObserved = "observed"
+)
+
+// TypeCount is the number of the events of the type
+// that the broker accepted from the event source.
+type TypeCount struct {
+	Type   string `json:"type"`
+	Source string `json:"source,omitempty"`
+	// Component is the manifest component producing the events, if known.
+	Component string    `json:"component,omitempty"`
+	Count     int       `json:"count"`
+	LastSeen  time.Time `json:"lastSeen"`
+	// Triggers are the triggers matching the events.
+	Triggers []string `json:"triggers"`
+	// Dropped is set if no trigger matches the events.
+	Dropped bool `json:"dropped"`
+}
+
+// LoadByType counts the events accepted by the context broker since the time
+// per their type and source. The brokers that do not expose the accepted
+// events fall back to the event types recorded by "tmctl stats observe",
+// whose counts are the totals of the types last seen since the time.
+// Returned origin tells which of the two the counts are taken from.
+func LoadByType(c *config.Config, m *manifest.Manifest, crds map[string]crd.CRD, since time.Time) ([]TypeCount, string, error) {
+	producers := Producers(c, m, crds)
+	q, err := deliveries.Open(c.ConfigHome, c.Context, c.Triggermesh.Broker)
+	if errors.Is(err, deliveries.ErrUnsupported) {
+		configuration, err := tmbroker.ReadConfig(c.ConfigHome, c.Context)
+		if err != nil {
+			return nil, "", fmt.Errorf("broker config: %w", err)
+		}
+		types, err := observed.Load(c.ConfigHome, c.Context)
+		if err != nil {
+			return nil, "", err
+		}
+		return ObservedByType(types.Entries(), since, configuration.Triggers, producers), FromObserved, nil
+	}
+	if err != nil {
+		return nil, "", err
+	}
+	defer q.Close()
+	entries, err := q.Since(since)
+	if err != nil {
+		return nil, "", fmt.Errorf("broker events: %w", err)
+	}
+	return ByType(entries, q.Triggers(), producers), FromBroker, nil
+}
+
+// ByType aggregates the events accepted by the broker per their type and source.
+func ByType(entries []deliveries.Entry, triggers map[string]tmbroker.LocalTriggerSpec, producers map[string]string) []TypeCount {
+	counts := make(map[[2]string]*TypeCount)
+	var result []*TypeCount
+	for _, entry := range entries {
+		key := [2]string{entry.Event.Type(), entry.Event.Source()}
+		count, exists := counts[key]
+		if !exists {
+			count = &TypeCount{
+				Type:      entry.Event.Type(),
+				Source:    entry.Event.Source(),
+				Component: producers[entry.Event.Source()],
+				Triggers:  []string{},
+				Dropped:   true,
+			}
+			counts[key] = count
+			result = append(result, count)
+		}
+		count.Count++
+		if entry.Time.After(count.LastSeen) {
+			count.LastSeen = entry.Time
+		}
+		count.match(triggers, attributes(entry.Event))
+	}
+	return sorted(result)
+}
+
+// ObservedByType converts the observed event types last seen since the time.
+// Observed types do not keep the event attributes, the triggers are matched
+// against the type and the source of the producing component.
+func ObservedByType(entries []observed.Entry, since time.Time, triggers map[string]tmbroker.LocalTriggerSpec, producers map[string]string) []TypeCount {
+	sources := make(map[string]string, len(producers))
+	for source, component := range producers {
+		sources[component] = source
+	}
+	var result []*TypeCount
+	for _, entry := range entries {
+		if entry.LastSeen.Before(since) {
+			continue
+		}
+		count := &TypeCount{
+			Type:      entry.Type,
+			Source:    sources[entry.Component],
+			Component: entry.Component,
+			Count:     entry.Count,
+			LastSeen:  entry.LastSeen,
+			Triggers:  []string{},
+			Dropped:   true,
+		}
+		event := route.Event{"type": entry.Type}
+		if count.Source != "" {
+			event["source"] = count.Source
+		}
+		count.match(triggers, event)
+		result = append(result, count)
+	}
+	return sorted(result)
+}
+
+// match records the triggers the event may be delivered by. Only the
+// events that every trigger certainly rejects are considered dropped.
+func (t *TypeCount) match(triggers map[string]tmbroker.LocalTriggerSpec, event route.Event) {
+	for name, spec := range triggers {
+		decision, _ := route.Evaluate(spec.Filters, event)
+		if decision == route.NotMatched {
+			continue
+		}
+		t.Dropped = false
+		if decision == route.Matched && !contains(t.Triggers, name) {
+			t.Triggers = append(t.Triggers, name)
+			sort.Strings(t.Triggers)
+		}
+	}
+}
+
+// Top returns the n largest counts, all counts if n is not positive.
+func Top(counts []TypeCount, n int) []TypeCount {
+	if n <= 0 || n >= len(counts) {
+		return counts
+	}
+	return counts[:n]
+}
+
+// sorted orders the counts from the largest one.
+func sorted(counts []*TypeCount) []TypeCount {
+	result := make([]TypeCount, 0, len(counts))
+	for _, count := range counts {
+		result = append(result, *count)
+	}
+	sort.SliceStable(result, func(i, j int) bool {
+		if result[i].Count != result[j].Count {
+			return result[i].Count > result[j].Count
+		}
+		if result[i].Type != result[j].Type {
+			return result[i].Type < result[j].Type
+		}
+		return result[i].Source < result[j].Source
+	})
+	return result
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
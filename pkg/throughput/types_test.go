@@ -0,0 +1,87 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package throughput
+
+import (
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/deliveries"
+	"github.com/triggermesh/tmctl/pkg/observed"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+)
+
+var typeTriggers = map[string]tmbroker.LocalTriggerSpec{
+	"foo-trigger": {
+		Filters: []eventingbroker.Filter{{Exact: map[string]string{"type": "order.created"}}},
+	},
+	"bar-trigger": {
+		Filters: []eventingbroker.Filter{{Exact: map[string]string{"source": "shop"}}},
+	},
+}
+
+func TestByType(t *testing.T) {
+	now := time.Now()
+	other := entry(now, "order.created")
+	other.Event.SetSource("legacy")
+	entries := []deliveries.Entry{
+		entry(now.Add(-3*time.Minute), "order.created"),
+		entry(now.Add(-2*time.Minute), "order.created"),
+		entry(now.Add(-time.Minute), "order.paid"),
+		other,
+	}
+	legacy := entry(now, "order.refunded")
+	legacy.Event.SetSource("legacy")
+	entries = append(entries, legacy, legacy)
+
+	counts := ByType(entries, typeTriggers, map[string]string{"shop": "foo-source"})
+	assert.Equal(t, []TypeCount{
+		{Type: "order.created", Source: "shop", Component: "foo-source", Count: 2, LastSeen: now.Add(-2 * time.Minute), Triggers: []string{"bar-trigger", "foo-trigger"}},
+		{Type: "order.refunded", Source: "legacy", Count: 2, LastSeen: now, Triggers: []string{}, Dropped: true},
+		{Type: "order.created", Source: "legacy", Count: 1, LastSeen: now, Triggers: []string{"foo-trigger"}},
+		{Type: "order.paid", Source: "shop", Component: "foo-source", Count: 1, LastSeen: now.Add(-time.Minute), Triggers: []string{"bar-trigger"}},
+	}, counts)
+
+	assert.Len(t, Top(counts, 2), 2)
+	assert.Equal(t, "order.refunded", Top(counts, 2)[1].Type)
+	assert.Len(t, Top(counts, 0), 4)
+	assert.Len(t, Top(counts, 10), 4)
+}
+
+func TestObservedByType(t *testing.T) {
+	now := time.Now()
+	entries := []observed.Entry{
+		{Component: "foo-source", Type: "order.paid", Count: 5, LastSeen: now},
+		{Type: "order.refunded", Count: 7, LastSeen: now},
+		{Component: "foo-source", Type: "order.created", Count: 9, LastSeen: now.Add(-2 * time.Hour)},
+	}
+	counts := ObservedByType(entries, now.Add(-time.Hour), typeTriggers, map[string]string{"shop": "foo-source"})
+	assert.Equal(t, []TypeCount{
+		// the unknown source may match the source filter
+		{Type: "order.refunded", Count: 7, LastSeen: now, Triggers: []string{}},
+		{Type: "order.paid", Source: "shop", Component: "foo-source", Count: 5, LastSeen: now, Triggers: []string{"bar-trigger"}},
+	}, counts)
+
+	counts = ObservedByType(entries[1:2], time.Time{}, map[string]tmbroker.LocalTriggerSpec{
+		"foo-trigger": typeTriggers["foo-trigger"],
+	}, nil)
+	assert.True(t, counts[0].Dropped)
+}
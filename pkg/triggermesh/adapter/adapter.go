@@ -24,34 +24,51 @@ import (
 	corev1 "k8s.io/api/core/v1"
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
+	"github.com/triggermesh/tmctl/pkg/certs"
 	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/secrets"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/ce"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/env"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter/reconciler"
 )
 
 const (
-	registry    = "gcr.io/triggermesh"
+	// Registry hosts the TriggerMesh adapter images.
+	Registry    = "gcr.io/triggermesh"
 	adapterPort = "8080/tcp"
 )
 
+// FunctionRuntimes maps supported Function runtimes to their images.
+var FunctionRuntimes = map[string]string{
+	"python": "knative-lambda-python310",
+	"node":   "knative-lambda-node18",
+	"ruby":   "knative-lambda-ruby32",
+}
+
 func Image(object unstructured.Unstructured, version string) string {
 	// components with custom images
 	switch object.GetKind() {
 	case "AWSS3Source",
 		"AWSEventBridgeSource":
-		return fmt.Sprintf("%s/awssqssource-adapter:%s", registry, version)
+		return fmt.Sprintf("%s/awssqssource-adapter:%s", Registry, version)
 	case "AzureServiceBusTopicSource",
 		"AzureServiceBusQueueSource":
-		return fmt.Sprintf("%s/azureservicebussource-adapter:%s", registry, version)
+		return fmt.Sprintf("%s/azureservicebussource-adapter:%s", Registry, version)
 	case "AzureBlobStorageSource":
-		return fmt.Sprintf("%s/azureeventhubssource-adapter:%s", registry, version)
+		return fmt.Sprintf("%s/azureeventhubssource-adapter:%s", Registry, version)
 	case "GoogleCloudAuditLogsSource",
 		"GoogleCloudStorageSource",
 		"GoogleCloudSourceRepositoriesSource":
-		return fmt.Sprintf("%s/googlecloudpubsubsource-adapter:%s", registry, version)
+		return fmt.Sprintf("%s/googlecloudpubsubsource-adapter:%s", Registry, version)
+	case "Function":
+		runtime, _, _ := unstructured.NestedString(object.Object, "spec", "runtime")
+		for name, image := range FunctionRuntimes {
+			if strings.Contains(strings.ToLower(runtime), name) {
+				return fmt.Sprintf("%s/%s:%s", Registry, image, version)
+			}
+		}
 	}
-	return fmt.Sprintf("%s/%s-adapter:%s", registry, strings.ToLower(object.GetKind()), version)
+	return fmt.Sprintf("%s/%s-adapter:%s", Registry, strings.ToLower(object.GetKind()), version)
 }
 
 func RuntimeParams(object unstructured.Unstructured, image string, additionalEnvs map[string]string) ([]docker.ContainerOption, []docker.HostOption, error) {
@@ -65,6 +82,15 @@ func RuntimeParams(object unstructured.Unstructured, image string, additionalEnv
 		docker.WithExtraHost(),
 	}
 
+	if dir, set := additionalEnvs[certs.HostDirEnv]; set {
+		ho = append(ho, docker.WithVolumeBind(dir+":"+certs.MountPath+":ro"))
+		delete(additionalEnvs, certs.HostDirEnv)
+	}
+	if dir, set := additionalEnvs[secrets.HostDirEnv]; set {
+		ho = append(ho, docker.WithVolumeBind(dir+":"+secrets.MountPath+":ro"))
+		delete(additionalEnvs, secrets.HostDirEnv)
+	}
+
 	finalEnv := []corev1.EnvVar{}
 
 	if object.GetKind() != "RedisBroker" &&
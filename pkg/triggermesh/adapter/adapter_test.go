@@ -113,3 +113,12 @@ func TestEventAttributes(t *testing.T) {
 	assert.Equal(t, "arn:aws:s3:::dev", attributes.ProducedEventSource)
 	assert.Equal(t, "com.amazon.s3.testevent", attributes.ProducedEventTypes[0])
 }
+
+func TestFunctionImage(t *testing.T) {
+	function := newUnstructured(t, "test-function", "Function", "extensions.triggermesh.io/v1alpha1", map[string]interface{}{
+		"runtime":    "python",
+		"entrypoint": "handler",
+		"code":       "def handler(event, context):\n  return event",
+	})
+	assert.Equal(t, "gcr.io/triggermesh/knative-lambda-python310:v1.25.0", Image(function, "v1.25.0"))
+}
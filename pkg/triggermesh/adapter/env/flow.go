@@ -26,6 +26,7 @@ import (
 	flowv1alpha1 "github.com/triggermesh/triggermesh/pkg/apis/flow/v1alpha1"
 
 	"github.com/triggermesh/triggermesh/pkg/flow/reconciler/jqtransformation"
+	"github.com/triggermesh/triggermesh/pkg/flow/reconciler/synchronizer"
 	"github.com/triggermesh/triggermesh/pkg/flow/reconciler/transformation"
 	"github.com/triggermesh/triggermesh/pkg/flow/reconciler/xmltojsontransformation"
 	"github.com/triggermesh/triggermesh/pkg/flow/reconciler/xslttransformation"
@@ -58,6 +59,12 @@ func flow(object unstructured.Unstructured) ([]corev1.EnvVar, error) {
 			return nil, err
 		}
 		return jqtransformation.MakeAppEnv(o), nil
+	case "Synchronizer":
+		var o *flowv1alpha1.Synchronizer
+		if err := runtime.DefaultUnstructuredConverter.FromUnstructured(object.Object, &o); err != nil {
+			return nil, err
+		}
+		return synchronizer.MakeAppEnv(o), nil
 	}
 	return nil, fmt.Errorf("kind %q is not supported", object.GetKind())
 }
@@ -87,11 +87,15 @@ func (b *Broker) AsDockerComposeObject(additionalEnvs map[string]string) (interf
 	for k, v := range additionalEnvs {
 		env = append(env, fmt.Sprintf("%s=%s", k, v))
 	}
+	port, err := pkg.OpenPort()
+	if err != nil {
+		return nil, err
+	}
 	return &docker.ComposeService{
 		ContainerName: b.Name,
 		Image:         b.image,
 		Entrypoint:    b.entrypoint,
-		Ports:         []string{strconv.Itoa(pkg.OpenPort()) + ":8080"},
+		Ports:         []string{strconv.Itoa(port) + ":8080"},
 		Environment:   env,
 	}, nil
 }
@@ -160,13 +164,24 @@ func (b *Broker) asContainer(additionalEnvs map[string]string) (*docker.Containe
 		Image:                  b.image,
 		CreateHostOptions:      ho,
 		CreateContainerOptions: co,
+		Network:                docker.NetworkName(b.Name),
+		Aliases:                b.NetworkAliases(),
 	}, nil
 }
 
+// Image returns the broker container image.
+func (b *Broker) Image() string {
+	return b.image
+}
+
 func (b *Broker) GetKind() string {
 	return BrokerKind
 }
 
+func (b *Broker) NetworkAliases() []string {
+	return triggermesh.NetworkAliases(b.Name, b.Name)
+}
+
 func (b *Broker) GetName() string {
 	return b.Name
 }
@@ -204,7 +219,14 @@ func (b *Broker) Start(ctx context.Context, additionalEnvs map[string]string, re
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
-	return container.Start(ctx, client, restart)
+	container, err = container.Start(ctx, client, restart)
+	if err != nil {
+		return nil, err
+	}
+	if err := StartProxy(ctx, config.HomeAbsPath(), b.Name); err != nil {
+		return nil, err
+	}
+	return container, nil
 }
 
 func (b *Broker) Stop(ctx context.Context) error {
@@ -216,11 +238,14 @@ func (b *Broker) Stop(ctx context.Context) error {
 	if err != nil {
 		return fmt.Errorf("container object: %w", err)
 	}
+	if err := StopProxy(ctx, b.Name); err != nil {
+		return fmt.Errorf("stopping content mode proxy: %w", err)
+	}
 	return container.Remove(ctx, client)
 }
 
 func (b *Broker) Info(ctx context.Context) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	client, err := docker.ClientFrom(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("docker client: %w", err)
 	}
@@ -276,12 +301,15 @@ func New(name string, brokerConfig config.BrokerConfig) (triggermesh.Component,
 	return &Broker{
 		Name: name,
 
-		image:      image(brokerConfig),
+		image:      image(name, brokerConfig),
 		entrypoint: brokerEntrypoint(brokerConfig),
 	}, nil
 }
 
-func image(c config.BrokerConfig) string {
+func image(name string, c config.BrokerConfig) string {
+	if image, set := c.Images[name]; set {
+		return image
+	}
 	switch {
 	case c.Memory != nil:
 		return config.MemoryBrokerImage + ":" + c.Version
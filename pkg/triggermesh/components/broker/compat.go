@@ -0,0 +1,62 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"fmt"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/version"
+)
+
+// brokerFeatures lists the broker versions required by the CLI features.
+var brokerFeatures = []struct {
+	name    string
+	version string
+}{
+	{name: "CESQL filters", version: "v1.1.0"},
+	{name: "delivery stats", version: "v1.2.0"},
+}
+
+// CompatibilityWarnings compares the version reported by the image tag
+// with the versions that CLI features expect from the broker.
+func CompatibilityWarnings(image string) []string {
+	tag := imageTag(image)
+	v, err := version.ParseGeneric(tag)
+	if err != nil {
+		return []string{fmt.Sprintf("unable to determine %q broker version, features compatibility is not checked", image)}
+	}
+	var warnings []string
+	for _, feature := range brokerFeatures {
+		if v.LessThan(version.MustParseGeneric(feature.version)) {
+			warnings = append(warnings, fmt.Sprintf("%s require broker %s or newer, %q is %s", feature.name, feature.version, image, tag))
+		}
+	}
+	return warnings
+}
+
+func imageTag(image string) string {
+	// registry port is not the tag
+	name := image[strings.LastIndex(image, "/")+1:]
+	if i := strings.LastIndex(name, "@"); i != -1 {
+		name = name[:i]
+	}
+	if i := strings.LastIndex(name, ":"); i != -1 {
+		return name[i+1:]
+	}
+	return "latest"
+}
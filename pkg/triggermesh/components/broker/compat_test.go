@@ -0,0 +1,54 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+)
+
+func TestCompatibilityWarnings(t *testing.T) {
+	testCases := map[string]int{
+		"gcr.io/triggermesh/memory-broker:v1.3.0":  0,
+		"gcr.io/triggermesh/memory-broker:v1.1.5":  1,
+		"localhost:5000/corp/broker:1.0.0":         2,
+		"ghcr.io/corp/broker:dev":                  1,
+		"ghcr.io/corp/broker":                      1,
+		"ghcr.io/corp/broker:v1.2.0@sha256:abcdef": 0,
+	}
+	for image, warnings := range testCases {
+		t.Run(image, func(t *testing.T) {
+			assert.Len(t, CompatibilityWarnings(image), warnings)
+		})
+	}
+}
+
+func TestImageOverride(t *testing.T) {
+	c := config.BrokerConfig{
+		Version: "v1.1.0",
+		Memory:  &config.InMemoryBrokerConfig{},
+	}
+	c.SetImage("foo", "ghcr.io/corp/broker:dev")
+	assert.Equal(t, "ghcr.io/corp/broker:dev", image("foo", c))
+	assert.Equal(t, "gcr.io/triggermesh/memory-broker:v1.1.0", image("bar", c))
+
+	c.SetImage("foo", "")
+	assert.Equal(t, "gcr.io/triggermesh/memory-broker:v1.1.0", image("foo", c))
+}
@@ -17,14 +17,22 @@ limitations under the License.
 package broker
 
 import (
+	"bytes"
+	"encoding/json"
+	"errors"
 	"fmt"
+	"net"
 	"os"
 	"path/filepath"
+	"reflect"
+	"sort"
+	"time"
 
 	"gopkg.in/yaml.v3"
 
 	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
 
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 )
 
@@ -41,6 +49,14 @@ type LocalTarget struct {
 	URL             string                          `yaml:"url,omitempty" json:"url,omitempty"`
 	Component       string                          `yaml:"component,omitempty" json:"component,omitempty"`
 	DeliveryOptions *eventingbroker.DeliveryOptions `yaml:"deliveryOptions,omitempty" json:"deliveryOptions,omitempty"`
+	// ContentMode is the CloudEvents content mode of the deliveries,
+	// the structured mode URL is the address of the content mode proxy.
+	ContentMode string `yaml:"contentMode,omitempty" json:"contentMode,omitempty"`
+}
+
+// ReadConfig returns the local configuration of the broker.
+func ReadConfig(configBase, broker string) (Configuration, error) {
+	return readBrokerConfig(ConfigPath(configBase, broker))
 }
 
 func readBrokerConfig(path string) (Configuration, error) {
@@ -60,44 +76,206 @@ func writeBrokerConfig(path string, configuration *Configuration) error {
 	return os.WriteFile(path, out, os.ModePerm)
 }
 
-func (t *Trigger) WriteLocalConfig() error {
-	configFile := filepath.Join(t.ConfigBase, t.Broker.Name, triggermesh.BrokerConfigFile)
-	configuration, err := readBrokerConfig(configFile)
-	if err != nil {
-		return fmt.Errorf("broker config: %w", err)
-	}
+const (
+	// number of attempts to apply the change to the broker config
+	// that keeps changing while the change is being applied
+	configUpdateAttempts = 5
 
-	trigger, exists := configuration.Triggers[t.Name]
-	if exists {
-		trigger.Filters = t.Filters
-		trigger.Target = LocalTarget{
-			URL:       t.LocalURL.String(),
-			Component: t.Target.Ref.Name,
+	configLockSuffix = ".lock"
+	configLockPeriod = 20 * time.Millisecond
+	configLockWait   = 10 * time.Second
+	// lock left by the crashed process
+	configLockStale = time.Minute
+)
+
+// ErrConfigConflict is returned when the broker config keeps changing
+// while the update is being applied.
+var ErrConfigConflict = errors.New("broker config was modified concurrently")
+
+// AddTrigger adds the trigger to the broker config or replaces the existing one.
+// Delivery options of the existing trigger are kept unless the new spec sets them.
+func AddTrigger(configBase, broker, name string, spec LocalTriggerSpec) error {
+	var exists, changed bool
+	if err := updateConfig(ConfigPath(configBase, broker), func(configuration *Configuration) bool {
+		var existing LocalTriggerSpec
+		existing, exists = configuration.Triggers[name]
+		if exists && spec.Target.DeliveryOptions == nil {
+			spec.Target.DeliveryOptions = existing.Target.DeliveryOptions
+		}
+		// identical trigger is not rewritten to avoid the broker config reload
+		if changed = !exists || !sameTrigger(existing, spec); !changed {
+			return false
 		}
-		configuration.Triggers[t.Name] = trigger
-	} else {
 		if configuration.Triggers == nil {
 			configuration.Triggers = make(map[string]LocalTriggerSpec, 1)
 		}
-		configuration.Triggers[t.Name] = LocalTriggerSpec{
-			Filters: t.Filters,
-			Target: LocalTarget{
-				URL:       t.LocalURL.String(),
-				Component: t.Target.Ref.Name,
-			},
+		configuration.Triggers[name] = spec
+		return true
+	}); err != nil {
+		return err
+	}
+	if !changed {
+		return nil
+	}
+	eventType := lifecycle.TriggerAdded
+	if exists {
+		eventType = lifecycle.TriggerUpdated
+	}
+	lifecycle.Emit(eventType, name, TriggerKind, map[string]string{"target": spec.Target.Component})
+	return nil
+}
+
+func sameTrigger(a, b LocalTriggerSpec) bool {
+	return equalFilters(a.Filters, b.Filters) && reflect.DeepEqual(a.Target, b.Target)
+}
+
+// RemoveTrigger deletes the trigger from the broker config.
+// Missing trigger is not an error.
+func RemoveTrigger(configBase, broker, name string) error {
+	var exists bool
+	if err := updateConfig(ConfigPath(configBase, broker), func(configuration *Configuration) bool {
+		if _, exists = configuration.Triggers[name]; exists {
+			delete(configuration.Triggers, name)
 		}
+		return exists
+	}); err != nil {
+		return err
+	}
+	if exists {
+		lifecycle.Emit(lifecycle.TriggerRemoved, name, TriggerKind, nil)
 	}
-	return writeBrokerConfig(configFile, &configuration)
+	return nil
 }
 
-func (t *Trigger) RemoveFromLocalConfig() error {
-	configFile := filepath.Join(t.ConfigBase, t.Broker.Name, triggermesh.BrokerConfigFile)
-	configuration, err := readBrokerConfig(configFile)
+// UpdateTriggerTarget changes the destination of the existing trigger
+// keeping its filters and delivery options as they are in the broker config.
+func UpdateTriggerTarget(configBase, broker, name string, target LocalTarget) error {
+	var exists bool
+	if err := updateConfig(ConfigPath(configBase, broker), func(configuration *Configuration) bool {
+		var trigger LocalTriggerSpec
+		if trigger, exists = configuration.Triggers[name]; !exists {
+			return false
+		}
+		if target.DeliveryOptions == nil {
+			target.DeliveryOptions = trigger.Target.DeliveryOptions
+		}
+		trigger.Target = target
+		configuration.Triggers[name] = trigger
+		return true
+	}); err != nil {
+		return err
+	}
+	if !exists {
+		return fmt.Errorf("trigger %q not found", name)
+	}
+	lifecycle.Emit(lifecycle.TriggerUpdated, name, TriggerKind, map[string]string{"target": target.Component})
+	return nil
+}
+
+// updateConfig applies the change to the latest broker config. The file is
+// locked for the time of the update and re-read on every attempt, the write
+// is retried if the file was changed since it was read by the writer that
+// does not respect the lock. The change function returns false if there is
+// nothing to write. Written changes are recorded in the broker config history.
+func updateConfig(path string, change func(*Configuration) bool) error {
+	unlock, err := lockConfig(path)
 	if err != nil {
-		return fmt.Errorf("broker config: %w", err)
+		return err
+	}
+	defer unlock()
+
+	for attempt := 0; attempt < configUpdateAttempts; attempt++ {
+		data, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("broker config: %w", err)
+		}
+		var configuration, previous Configuration
+		if err := yaml.Unmarshal(data, &configuration); err != nil {
+			return fmt.Errorf("broker config: %w", err)
+		}
+		// the change may modify the triggers map in place
+		if err := yaml.Unmarshal(data, &previous); err != nil {
+			return fmt.Errorf("broker config: %w", err)
+		}
+		if !change(&configuration) {
+			return nil
+		}
+		current, err := os.ReadFile(path)
+		if err != nil {
+			return fmt.Errorf("broker config: %w", err)
+		}
+		if !bytes.Equal(data, current) {
+			continue
+		}
+		if err := writeBrokerConfig(path, &configuration); err != nil {
+			return err
+		}
+		if err := recordRevision(path, &previous, &configuration); err != nil {
+			return fmt.Errorf("broker config history: %w", err)
+		}
+		return nil
+	}
+	return fmt.Errorf("%w, giving up after %d attempts", ErrConfigConflict, configUpdateAttempts)
+}
+
+// lockConfig creates the lock file next to the broker config.
+// The config itself is not replaced, as it is bind-mounted into the broker container.
+func lockConfig(path string) (func(), error) {
+	lock := path + configLockSuffix
+	deadline := time.Now().Add(configLockWait)
+	for {
+		file, err := os.OpenFile(lock, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0o644)
+		if err == nil {
+			file.Close()
+			return func() { os.Remove(lock) }, nil
+		}
+		if !os.IsExist(err) {
+			return nil, fmt.Errorf("broker config lock: %w", err)
+		}
+		if info, err := os.Stat(lock); err == nil && time.Since(info.ModTime()) > configLockStale {
+			os.Remove(lock)
+			continue
+		}
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("broker config is locked by another process, remove %s if it is not running", lock)
+		}
+		time.Sleep(configLockPeriod)
+	}
+}
+
+// WriteLocalConfig adds the trigger to the broker config or replaces the existing one.
+func (t *Trigger) WriteLocalConfig() error {
+	return AddTrigger(t.ConfigBase, t.Broker.Name, t.Name, LocalTriggerSpec{
+		Filters: t.Filters,
+		Target:  t.localTarget(),
+	})
+}
+
+// UpdateLocalTarget changes the trigger destination in the broker config.
+func (t *Trigger) UpdateLocalTarget() error {
+	return UpdateTriggerTarget(t.ConfigBase, t.Broker.Name, t.Name, t.localTarget())
+}
+
+// RemoveFromLocalConfig deletes the trigger from the broker config.
+func (t *Trigger) RemoveFromLocalConfig() error {
+	return RemoveTrigger(t.ConfigBase, t.Broker.Name, t.Name)
+}
+
+func (t *Trigger) localTarget() LocalTarget {
+	target := LocalTarget{URL: t.LocalURL.String()}
+	if t.ContentMode == ContentModeStructured {
+		if proxy, err := proxyURL(t.Broker.Name, t.LocalURL); err == nil {
+			target.URL = proxy.String()
+			target.ContentMode = t.ContentMode
+		}
+	}
+	// the broker of another context is not the component
+	// of this context, it is addressed by the URL only
+	if t.IsLink() || t.IsExternal() {
+		return target
 	}
-	delete(configuration.Triggers, t.Name)
-	return writeBrokerConfig(configFile, &configuration)
+	target.Component = t.Target.Ref.Name
+	return target
 }
 
 func GetTargetTriggers(target, broker, configBase string) ([]triggermesh.Component, error) {
@@ -119,3 +297,102 @@ func GetTargetTriggers(target, broker, configBase string) ([]triggermesh.Compone
 	}
 	return triggers, nil
 }
+
+// PortFix is the trigger moved from the stale host port of the target.
+type PortFix struct {
+	Trigger string
+	From    string
+	To      string
+}
+
+// SyncTargetPort points the triggers that deliver the events to the published
+// host port of the target to its live port, e.g. after Docker assigned
+// the different port to the restarted container. The addresses in the context
+// network are not changed. The list of the rewritten triggers is returned.
+func SyncTargetPort(target, port, broker, configBase string) ([]PortFix, error) {
+	if port == "" {
+		return nil, nil
+	}
+	triggers, err := GetTargetTriggers(target, broker, configBase)
+	if err != nil {
+		return nil, err
+	}
+	var fixes []PortFix
+	for _, trigger := range triggers {
+		t := trigger.(*Trigger)
+		if t.LocalURL == nil {
+			continue
+		}
+		address := t.LocalURL.URL()
+		if address.Hostname() != dockerHostname || address.Port() == port {
+			continue
+		}
+		fix := PortFix{Trigger: t.Name, From: t.LocalURL.String()}
+		t.LocalURL.Host = net.JoinHostPort(address.Hostname(), port)
+		fix.To = t.LocalURL.String()
+		if err := t.UpdateLocalTarget(); err != nil {
+			return fixes, fmt.Errorf("trigger %q: %w", t.Name, err)
+		}
+		fixes = append(fixes, fix)
+	}
+	sort.Slice(fixes, func(i, j int) bool { return fixes[i].Trigger < fixes[j].Trigger })
+	return fixes, nil
+}
+
+// FindTrigger returns the name of the broker config trigger that delivers
+// events to the path of the target and has structurally equal filters.
+func FindTrigger(target, path, broker, configBase string, filters []eventingbroker.Filter) (string, bool) {
+	config, err := readBrokerConfig(filepath.Join(configBase, broker, triggermesh.BrokerConfigFile))
+	if err != nil {
+		return "", false
+	}
+	var names []string
+	for name, trigger := range config.Triggers {
+		if trigger.Target.Component == target && trigger.Target.Path() == normalizePath(path) &&
+			equalFilters(trigger.Filters, filters) {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return "", false
+	}
+	sort.Strings(names)
+	return names[0], true
+}
+
+// NameConflict returns true if the broker has the trigger with the name
+// that delivers the events to another target, path or with other filters.
+func NameConflict(name, target, path, broker, configBase string, filters []eventingbroker.Filter) bool {
+	config, err := readBrokerConfig(filepath.Join(configBase, broker, triggermesh.BrokerConfigFile))
+	if err != nil {
+		return false
+	}
+	trigger, exists := config.Triggers[name]
+	if !exists {
+		return false
+	}
+	return trigger.Target.Component != target || trigger.Target.Path() != normalizePath(path) ||
+		!equalFilters(trigger.Filters, filters)
+}
+
+// EqualFilter compares the attributes and the values of the filter
+// expressions rather than their in-memory representation.
+func EqualFilter(a, b eventingbroker.Filter) bool {
+	return equalFilters([]eventingbroker.Filter{a}, []eventingbroker.Filter{b})
+}
+
+func equalFilters(a, b []eventingbroker.Filter) bool {
+	if len(a) == 0 || len(b) == 0 {
+		return len(a) == len(b)
+	}
+	// JSON encoding omits empty expressions and sorts map keys
+	ja, err := json.Marshal(a)
+	if err != nil {
+		return false
+	}
+	jb, err := json.Marshal(b)
+	if err != nil {
+		return false
+	}
+	return string(ja) == string(jb)
+}
@@ -0,0 +1,234 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+type fakeTarget struct {
+	name string
+}
+
+func (f *fakeTarget) AsK8sObject() (kubernetes.Object, error) { return kubernetes.Object{}, nil }
+func (f *fakeTarget) GetName() string                         { return f.name }
+func (f *fakeTarget) GetKind() string                         { return "FakeTarget" }
+func (f *fakeTarget) GetAPIVersion() string                   { return "targets.triggermesh.io/v1alpha1" }
+func (f *fakeTarget) GetSpec() map[string]interface{}         { return nil }
+func (f *fakeTarget) SetSpec(map[string]interface{})          {}
+func (f *fakeTarget) ConsumedEventTypes() ([]string, error)   { return nil, nil }
+func (f *fakeTarget) GetPort(context.Context) (string, error) { return "8080", nil }
+
+func TestEqualFilters(t *testing.T) {
+	assert.True(t, equalFilters(nil, []eventingbroker.Filter{}))
+	assert.True(t, equalFilters(
+		[]eventingbroker.Filter{{Exact: map[string]string{"type": "a", "source": "b"}}},
+		[]eventingbroker.Filter{{Exact: map[string]string{"source": "b", "type": "a"}, Prefix: map[string]string{}}},
+	))
+	assert.False(t, equalFilters(
+		[]eventingbroker.Filter{{Exact: map[string]string{"type": "a"}}},
+		[]eventingbroker.Filter{{Prefix: map[string]string{"type": "a"}}},
+	))
+	assert.False(t, equalFilters(nil, []eventingbroker.Filter{{Exact: map[string]string{"type": "a"}}}))
+}
+
+func TestEqualFilter(t *testing.T) {
+	// the filters parsed from the config are equal to the same new filters
+	assert.True(t, EqualFilter(*FilterAttribute("type", "a"), eventingbroker.Filter{Exact: map[string]string{"type": "a"}, Suffix: map[string]string{}}))
+	assert.False(t, EqualFilter(*FilterAttribute("type", "a"), *FilterAttribute("type", "b")))
+	assert.False(t, EqualFilter(*FilterAttribute("type", "a"), *FilterAttribute("source", "a")))
+	assert.False(t, EqualFilter(*FilterAttribute("type", "a"), eventingbroker.Filter{Exact: map[string]string{"type": "a", "source": "b"}}))
+}
+
+func TestTriggerLifecycleEvents(t *testing.T) {
+	configBase := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configBase, "foo"), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(configBase, "foo", triggermesh.BrokerConfigFile), nil, os.ModePerm))
+	eventsLog := filepath.Join(configBase, "events.log")
+	require.NoError(t, lifecycle.Open(eventsLog))
+	defer lifecycle.Close()
+
+	trigger, err := NewTrigger("foo-trigger", "foo", configBase, &fakeTarget{name: "sockeye"}, nil)
+	require.NoError(t, err)
+	require.NoError(t, trigger.(*Trigger).WriteLocalConfig())
+	// identical trigger is not rewritten
+	require.NoError(t, trigger.(*Trigger).WriteLocalConfig())
+	trigger, err = NewTrigger("foo-trigger", "foo", configBase, &fakeTarget{name: "sockeye"}, FilterAttribute("type", "order.created"))
+	require.NoError(t, err)
+	require.NoError(t, trigger.(*Trigger).WriteLocalConfig())
+	require.NoError(t, trigger.(*Trigger).RemoveFromLocalConfig())
+	// removing the missing trigger is not an event
+	require.NoError(t, trigger.(*Trigger).RemoveFromLocalConfig())
+	require.NoError(t, lifecycle.Close())
+
+	log, err := os.ReadFile(eventsLog)
+	require.NoError(t, err)
+	var types []lifecycle.Type
+	for _, line := range strings.Split(strings.TrimSpace(string(log)), "\n") {
+		var e lifecycle.Event
+		require.NoError(t, json.Unmarshal([]byte(line), &e))
+		assert.Equal(t, "foo-trigger", e.Component)
+		types = append(types, e.Type)
+	}
+	assert.Equal(t, []lifecycle.Type{lifecycle.TriggerAdded, lifecycle.TriggerUpdated, lifecycle.TriggerRemoved}, types)
+}
+
+func TestConcurrentConfigUpdates(t *testing.T) {
+	configBase := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configBase, "foo"), os.ModePerm))
+	require.NoError(t, os.WriteFile(ConfigPath(configBase, "foo"), nil, os.ModePerm))
+	require.NoError(t, AddTrigger(configBase, "foo", "doomed", LocalTriggerSpec{
+		Target: LocalTarget{URL: "http://localhost:8080", Component: "sockeye"},
+	}))
+
+	const writers = 20
+	var wg sync.WaitGroup
+	errs := make(chan error, writers*2+1)
+	for i := 0; i < writers; i++ {
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+			name := fmt.Sprintf("trigger-%d", i)
+			errs <- AddTrigger(configBase, "foo", name, LocalTriggerSpec{
+				Filters: []eventingbroker.Filter{*FilterAttribute("type", name)},
+				Target:  LocalTarget{URL: "http://localhost:8080", Component: "sockeye"},
+			})
+			errs <- UpdateTriggerTarget(configBase, "foo", name, LocalTarget{URL: "http://localhost:9090", Component: "display"})
+		}(i)
+	}
+	wg.Add(1)
+	go func() {
+		defer wg.Done()
+		errs <- RemoveTrigger(configBase, "foo", "doomed")
+	}()
+	wg.Wait()
+	close(errs)
+	for err := range errs {
+		require.NoError(t, err)
+	}
+
+	config, err := ReadConfig(configBase, "foo")
+	require.NoError(t, err)
+	assert.Len(t, config.Triggers, writers)
+	for i := 0; i < writers; i++ {
+		name := fmt.Sprintf("trigger-%d", i)
+		trigger, exists := config.Triggers[name]
+		require.True(t, exists, name)
+		assert.Equal(t, "display", trigger.Target.Component)
+		// target update must not lose the filters
+		assert.Equal(t, name, trigger.Filters[0].Exact["type"])
+	}
+	_, err = os.Stat(ConfigPath(configBase, "foo") + configLockSuffix)
+	assert.True(t, os.IsNotExist(err))
+}
+
+func TestUpdateMissingTrigger(t *testing.T) {
+	configBase := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configBase, "foo"), os.ModePerm))
+	require.NoError(t, os.WriteFile(ConfigPath(configBase, "foo"), nil, os.ModePerm))
+	assert.Error(t, UpdateTriggerTarget(configBase, "foo", "missing", LocalTarget{}))
+	assert.NoError(t, RemoveTrigger(configBase, "foo", "missing"))
+}
+
+func TestTemplateTriggerName(t *testing.T) {
+	name := TemplateTriggerName("sockeye", "com.amazon.s3.objectcreated")
+	assert.Equal(t, name, TemplateTriggerName("sockeye", "com.amazon.s3.objectcreated"))
+	assert.True(t, strings.HasPrefix(name, "sockeye-"))
+	assert.NotEqual(t, name, TemplateTriggerName("sockeye", "com.amazon.s3.objectremoved"))
+
+	long := TemplateTriggerName(strings.Repeat("a", triggermesh.MaxNameLength), "foo")
+	assert.LessOrEqual(t, len(long), triggermesh.MaxNameLength)
+	assert.Equal(t, TemplateTriggerName("a", "foo")[1:], long[len(long)-9:])
+}
+
+func TestTargetPath(t *testing.T) {
+	configBase := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configBase, "foo"), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(configBase, "foo", triggermesh.BrokerConfigFile), nil, os.ModePerm))
+
+	sockeye := &fakeTarget{name: "sockeye"}
+	trigger, err := NewTrigger("foo-trigger", "foo", configBase, sockeye, nil)
+	require.NoError(t, err)
+	assert.Error(t, trigger.(*Trigger).SetTargetPath("events/in"))
+	require.NoError(t, trigger.(*Trigger).SetTargetPath("/events/in"))
+	// the same path is not appended twice
+	require.NoError(t, trigger.(*Trigger).SetTargetPath("/events/in"))
+	assert.Error(t, trigger.(*Trigger).SetTargetPath("/other"))
+	assert.Equal(t, "http://host.docker.internal:8080/events/in", trigger.(*Trigger).LocalURL.String())
+
+	require.NoError(t, trigger.(*Trigger).SetTargetPort("8081"))
+	trigger.(*Trigger).SetTarget(sockeye)
+	assert.Equal(t, "http://host.docker.internal:8080/events/in", trigger.(*Trigger).LocalURL.String())
+
+	object, err := trigger.AsK8sObject()
+	require.NoError(t, err)
+	assert.Equal(t, "/events/in", object.Spec["target"].(duckv1.Destination).URI.String())
+
+	require.NoError(t, trigger.(*Trigger).WriteLocalConfig())
+	config, err := ReadConfig(configBase, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "http://host.docker.internal:8080/events/in", config.Triggers["foo-trigger"].Target.URL)
+
+	triggers, err := GetTargetTriggers("sockeye", "foo", configBase)
+	require.NoError(t, err)
+	require.Len(t, triggers, 1)
+	assert.Equal(t, "/events/in", triggers[0].(*Trigger).TargetPath())
+}
+
+func TestSyncTargetPort(t *testing.T) {
+	configBase := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configBase, "foo"), os.ModePerm))
+	require.NoError(t, os.WriteFile(ConfigPath(configBase, "foo"), nil, os.ModePerm))
+	require.NoError(t, AddTrigger(configBase, "foo", "path-trigger", LocalTriggerSpec{
+		Target: LocalTarget{URL: "https://host.docker.internal:8080/events/in", Component: "sockeye"},
+	}))
+	require.NoError(t, AddTrigger(configBase, "foo", "internal-trigger", LocalTriggerSpec{
+		Target: LocalTarget{URL: "http://sockeye:8080", Component: "sockeye"},
+	}))
+
+	fixes, err := SyncTargetPort("sockeye", "8081", "foo", configBase)
+	require.NoError(t, err)
+	assert.Equal(t, []PortFix{{
+		Trigger: "path-trigger",
+		From:    "https://host.docker.internal:8080/events/in",
+		To:      "https://host.docker.internal:8081/events/in",
+	}}, fixes)
+
+	config, err := ReadConfig(configBase, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, "https://host.docker.internal:8081/events/in", config.Triggers["path-trigger"].Target.URL)
+	// addresses in the context network do not depend on the host port
+	assert.Equal(t, "http://sockeye:8080", config.Triggers["internal-trigger"].Target.URL)
+}
@@ -0,0 +1,164 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"context"
+	_ "embed"
+	"errors"
+	"fmt"
+	"net/url"
+
+	"knative.dev/pkg/apis"
+
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+// CloudEvents HTTP content modes of the trigger deliveries.
+const (
+	// ContentModeBinary keeps the event attributes in the headers,
+	// it is the mode the broker delivers the events in.
+	ContentModeBinary = "binary"
+	// ContentModeStructured delivers the event as the JSON envelope.
+	ContentModeStructured = "structured"
+
+	// ContentModeAnnotation keeps the content mode of the manifest trigger.
+	ContentModeAnnotation = "triggermesh.io/content-mode"
+)
+
+// ContentModes are the supported content modes.
+var ContentModes = []string{ContentModeBinary, ContentModeStructured}
+
+// The broker config has no content mode setting, the events of the triggers
+// with the structured content mode are delivered through the proxy that
+// re-encodes them. The proxy is the plain Python container running the script.
+const (
+	proxyImage       = "python:3.11-alpine"
+	proxyTargetParam = "target"
+)
+
+//go:embed proxy/structured.py
+var proxyScript string
+
+// ValidateContentMode checks the trigger content mode.
+func ValidateContentMode(mode string) error {
+	for _, m := range ContentModes {
+		if mode == m {
+			return nil
+		}
+	}
+	return fmt.Errorf("unknown content mode %q, must be %q or %q", mode, ContentModeBinary, ContentModeStructured)
+}
+
+// ProxyName returns the name of the content mode proxy container of the broker.
+func ProxyName(broker string) string {
+	return broker + "-structured-proxy"
+}
+
+// proxyURL returns the proxy address delivering the events to the target URL.
+func proxyURL(broker string, target *apis.URL) (*apis.URL, error) {
+	proxy, err := InternalURL(ProxyName(broker), "/")
+	if err != nil {
+		return nil, err
+	}
+	proxy.RawQuery = url.Values{proxyTargetParam: []string{target.String()}}.Encode()
+	return proxy, nil
+}
+
+// TargetURL returns the address of the trigger target,
+// the structured mode deliveries go through the proxy.
+func (t LocalTarget) TargetURL() string {
+	if t.ContentMode != ContentModeStructured {
+		return t.URL
+	}
+	u, err := url.Parse(t.URL)
+	if err != nil {
+		return t.URL
+	}
+	if target := u.Query().Get(proxyTargetParam); target != "" {
+		return target
+	}
+	return t.URL
+}
+
+// Path returns the path of the trigger target address,
+// empty for the root path.
+func (t LocalTarget) Path() string {
+	return normalizePath(t.TargetURL())
+}
+
+// usesProxy returns true if any trigger of the broker config
+// delivers the events through the content mode proxy.
+func usesProxy(configuration Configuration) bool {
+	for _, trigger := range configuration.Triggers {
+		if trigger.Target.ContentMode == ContentModeStructured {
+			return true
+		}
+	}
+	return false
+}
+
+func proxyContainer(broker string) *docker.Container {
+	name := ProxyName(broker)
+	return &docker.Container{
+		Name:  name,
+		Image: proxyImage,
+		CreateContainerOptions: []docker.ContainerOption{
+			docker.WithImage(proxyImage),
+			docker.WithCmd([]string{"python3", "-u", "-c", proxyScript}),
+		},
+		CreateHostOptions: []docker.HostOption{
+			docker.WithExtraHost(),
+		},
+		Network: docker.NetworkName(broker),
+		Aliases: triggermesh.NetworkAliases(name, broker),
+	}
+}
+
+// StartProxy starts the content mode proxy of the broker if its triggers
+// need it and the broker is running.
+func StartProxy(ctx context.Context, configBase, broker string) error {
+	configuration, err := ReadConfig(configBase, broker)
+	if err != nil || !usesProxy(configuration) {
+		return nil
+	}
+	client, err := docker.NewClient()
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	if b, err := (&docker.Container{Name: broker + "-broker"}).LookupHostConfig(ctx, client); err != nil || !b.Online {
+		return nil
+	}
+	if _, err := proxyContainer(broker).Start(ctx, client, false); err != nil {
+		return fmt.Errorf("starting content mode proxy: %w", err)
+	}
+	return nil
+}
+
+// StopProxy removes the content mode proxy of the broker, if any.
+func StopProxy(ctx context.Context, broker string) error {
+	client, err := docker.NewClient()
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	proxy := proxyContainer(broker)
+	if _, err := proxy.LookupHostConfig(ctx, client); errors.Is(err, docker.ErrNotFound) {
+		return nil
+	}
+	return proxy.Remove(ctx, client)
+}
@@ -0,0 +1,51 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+	"knative.dev/pkg/apis"
+)
+
+func TestValidateContentMode(t *testing.T) {
+	assert.NoError(t, ValidateContentMode("binary"))
+	assert.NoError(t, ValidateContentMode("structured"))
+	assert.EqualError(t, ValidateContentMode("batched"), `unknown content mode "batched", must be "binary" or "structured"`)
+}
+
+func TestTargetURL(t *testing.T) {
+	target, err := apis.ParseURL("http://sockeye:8080/events?id=1")
+	require.NoError(t, err)
+	proxy, err := proxyURL("foo", target)
+	require.NoError(t, err)
+	assert.True(t, strings.HasPrefix(proxy.String(), "http://foo-structured-proxy:8080/?target="))
+
+	structured := LocalTarget{URL: proxy.String(), ContentMode: ContentModeStructured}
+	assert.Equal(t, "http://sockeye:8080/events?id=1", structured.TargetURL())
+	binary := LocalTarget{URL: target.String()}
+	assert.Equal(t, "http://sockeye:8080/events?id=1", binary.TargetURL())
+
+	assert.True(t, usesProxy(Configuration{Triggers: map[string]LocalTriggerSpec{
+		"a": {Target: binary},
+		"b": {Target: structured},
+	}}))
+	assert.False(t, usesProxy(Configuration{Triggers: map[string]LocalTriggerSpec{"a": {Target: binary}}}))
+}
@@ -0,0 +1,69 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"fmt"
+	"strings"
+
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+)
+
+// ClusterDestination returns the trigger destination addressing the target
+// object of the manifest in the cluster. The local address of the target is
+// never exported: the reference is completed with the kind and API version
+// of the manifest object, the path and the external URIs are kept as is.
+func ClusterDestination(target duckv1.Destination, objects []kubernetes.Object) (duckv1.Destination, error) {
+	if target.Ref == nil {
+		if target.URI == nil || target.URI.Host == "" {
+			return target, fmt.Errorf("destination has neither the reference nor the absolute URI")
+		}
+		return target, nil
+	}
+	if target.Ref.Kind == BrokerKind {
+		// link to the broker of another context
+		return target, nil
+	}
+	var candidates []kubernetes.Object
+	for _, object := range objects {
+		if object.Metadata.Name != target.Ref.Name || object.Kind == TriggerKind || object.Kind == "Secret" {
+			continue
+		}
+		if strings.EqualFold(object.Kind, target.Ref.Kind) {
+			candidates = []kubernetes.Object{object}
+			break
+		}
+		candidates = append(candidates, object)
+	}
+	switch len(candidates) {
+	case 0:
+		return target, fmt.Errorf("target %q not found", target.Ref.Name)
+	case 1:
+	default:
+		return target, fmt.Errorf("target %q is ambiguous, %d objects have this name", target.Ref.Name, len(candidates))
+	}
+	return duckv1.Destination{
+		Ref: &duckv1.KReference{
+			Kind:       candidates[0].Kind,
+			Name:       candidates[0].Metadata.Name,
+			APIVersion: candidates[0].APIVersion,
+		},
+		URI: target.URI,
+	}, nil
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+)
+
+func TestClusterDestination(t *testing.T) {
+	objects := []kubernetes.Object{
+		{APIVersion: "flow.triggermesh.io/v1alpha1", Kind: "Transformation", Metadata: kubernetes.Metadata{Name: "foo-transformation"}},
+		{APIVersion: "targets.triggermesh.io/v1alpha1", Kind: "CloudEventsTarget", Metadata: kubernetes.Metadata{Name: "foo-cloudeventstarget"}},
+		{APIVersion: "serving.knative.dev/v1", Kind: "Service", Metadata: kubernetes.Metadata{Name: "sockeye"}},
+		{APIVersion: "v1", Kind: "Secret", Metadata: kubernetes.Metadata{Name: "sockeye"}},
+		{APIVersion: APIVersion, Kind: TriggerKind, Metadata: kubernetes.Metadata{Name: "foo-cloudeventstarget"}},
+	}
+	external, err := apis.ParseURL("https://example.com/hooks")
+	require.NoError(t, err)
+
+	testCases := map[string]struct {
+		target   duckv1.Destination
+		expected duckv1.Destination
+		err      bool
+	}{
+		"transformation with the lowercase kind": {
+			target:   duckv1.Destination{Ref: &duckv1.KReference{Kind: "transformation", Name: "foo-transformation", APIVersion: "flow.triggermesh.io/v1alpha1"}},
+			expected: duckv1.Destination{Ref: &duckv1.KReference{Kind: "Transformation", Name: "foo-transformation", APIVersion: "flow.triggermesh.io/v1alpha1"}},
+		},
+		"target with the path": {
+			target: duckv1.Destination{
+				Ref: &duckv1.KReference{Kind: "cloudeventstarget", Name: "foo-cloudeventstarget"},
+				URI: &apis.URL{Path: "/events"},
+			},
+			expected: duckv1.Destination{
+				Ref: &duckv1.KReference{Kind: "CloudEventsTarget", Name: "foo-cloudeventstarget", APIVersion: "targets.triggermesh.io/v1alpha1"},
+				URI: &apis.URL{Path: "/events"},
+			},
+		},
+		"service by name only": {
+			target:   duckv1.Destination{Ref: &duckv1.KReference{Name: "sockeye"}},
+			expected: duckv1.Destination{Ref: &duckv1.KReference{Kind: "Service", Name: "sockeye", APIVersion: "serving.knative.dev/v1"}},
+		},
+		"external URI": {
+			target:   duckv1.Destination{URI: external},
+			expected: duckv1.Destination{URI: external},
+		},
+		"link to another broker": {
+			target:   duckv1.Destination{Ref: &duckv1.KReference{Kind: BrokerKind, Name: "bar", APIVersion: APIVersion}},
+			expected: duckv1.Destination{Ref: &duckv1.KReference{Kind: BrokerKind, Name: "bar", APIVersion: APIVersion}},
+		},
+		"relative URI without reference": {
+			target: duckv1.Destination{URI: &apis.URL{Path: "/events"}},
+			err:    true,
+		},
+		"missing target": {
+			target: duckv1.Destination{Ref: &duckv1.KReference{Kind: "Transformation", Name: "bar-transformation"}},
+			err:    true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			destination, err := ClusterDestination(tc.target, objects)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			assert.Equal(t, tc.expected, destination)
+		})
+	}
+}
+
+func TestURITrigger(t *testing.T) {
+	configBase := t.TempDir()
+	uri, err := apis.ParseURL("https://example.com/hooks?token=foo")
+	require.NoError(t, err)
+
+	_, err = NewURITrigger("", "foo", configBase, &apis.URL{Path: "/hooks"}, nil)
+	assert.Error(t, err)
+
+	trigger, err := NewURITrigger("", "foo", configBase, uri, FilterAttribute("type", "order.created"))
+	require.NoError(t, err)
+	assert.True(t, trigger.(*Trigger).IsExternal())
+	assert.False(t, trigger.(*Trigger).IsLink())
+	assert.Equal(t, uri.String(), trigger.(*Trigger).TargetName())
+	assert.Empty(t, trigger.(*Trigger).TargetPath())
+	assert.Equal(t, LocalTarget{URL: uri.String()}, trigger.(*Trigger).localTarget())
+
+	other, err := NewURITrigger("", "foo", configBase, &apis.URL{Scheme: "https", Host: "example.org"}, FilterAttribute("type", "order.created"))
+	require.NoError(t, err)
+	assert.NotEqual(t, trigger.GetName(), other.GetName())
+}
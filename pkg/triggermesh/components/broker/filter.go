@@ -0,0 +1,68 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// filterFields are the attributes of the broker filter expression.
+var filterFields = []string{"all", "any", "exact", "not", "prefix", "suffix"}
+
+// FilterUnknownFields returns the fields of the raw JSON filter
+// that the broker does not recognize and silently ignores.
+func FilterUnknownFields(raw string) ([]crd.UnknownField, error) {
+	var filter interface{}
+	if err := json.Unmarshal([]byte(raw), &filter); err != nil {
+		return nil, err
+	}
+	fields := filterUnknownFields("filter", filter)
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Path < fields[j].Path
+	})
+	return fields, nil
+}
+
+func filterUnknownFields(path string, value interface{}) []crd.UnknownField {
+	expression, ok := value.(map[string]interface{})
+	if !ok {
+		return nil
+	}
+	var result []crd.UnknownField
+	for key, nested := range expression {
+		switch key {
+		case "all", "any":
+			items, _ := nested.([]interface{})
+			for i, item := range items {
+				result = append(result, filterUnknownFields(fmt.Sprintf("%s.%s[%d]", path, key, i), item)...)
+			}
+		case "not":
+			result = append(result, filterUnknownFields(path+".not", nested)...)
+		case "exact", "prefix", "suffix":
+		default:
+			result = append(result, crd.UnknownField{
+				Path:       path + "." + key,
+				Suggestion: crd.Closest(key, filterFields),
+			})
+		}
+	}
+	return result
+}
@@ -0,0 +1,66 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestFilterUnknownFields(t *testing.T) {
+	testCases := map[string]struct {
+		filter   string
+		expected []string
+		err      bool
+	}{
+		"known fields": {
+			filter: `{"any":[{"exact":{"type":"foo"}},{"not":{"prefix":{"source":"bar"}}}]}`,
+		},
+		"misspelled expression": {
+			filter:   `{"exacts":{"type":"foo"}}`,
+			expected: []string{`unknown field filter.exacts, did you mean "exact"?`},
+		},
+		"nested unknown expressions": {
+			filter: `{"all":[{"suffix":{"type":".v1"}},{"not":{"cesql":"type = 'foo'"}}],"regex":{"type":"foo.*"}}`,
+			expected: []string{
+				"unknown field filter.all[1].not.cesql",
+				"unknown field filter.regex",
+			},
+		},
+		"invalid JSON": {
+			filter: `{"exact":`,
+			err:    true,
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			unknown, err := FilterUnknownFields(tc.filter)
+			if tc.err {
+				assert.Error(t, err)
+				return
+			}
+			require.NoError(t, err)
+			var fields []string
+			for _, field := range unknown {
+				fields = append(fields, field.String())
+			}
+			assert.Equal(t, tc.expected, fields)
+		})
+	}
+}
@@ -0,0 +1,339 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/pmezard/go-difflib/difflib"
+	"gopkg.in/yaml.v3"
+	"knative.dev/pkg/apis"
+	duckv1 "knative.dev/pkg/apis/duck/v1"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
+)
+
+const (
+	// HistoryLimit is the number of the broker config revisions kept.
+	HistoryLimit = 50
+
+	historyDir       = "history"
+	revisionFileExt  = ".yaml"
+	revisionNameSize = 6
+)
+
+// historyCommand is the command recorded in the new revisions.
+var historyCommand string
+
+// SetHistoryCommand sets the command that is recorded
+// with the broker config revisions made by the process.
+func SetHistoryCommand(command string) {
+	historyCommand = command
+}
+
+// Revision is the recorded state of the broker config.
+type Revision struct {
+	Number  int           `yaml:"revision"`
+	Time    time.Time     `yaml:"time"`
+	Command string        `yaml:"command,omitempty"`
+	Config  Configuration `yaml:"config"`
+}
+
+// TriggerChangeType is the kind of the trigger difference between revisions.
+type TriggerChangeType string
+
+const (
+	TriggerAdded      TriggerChangeType = "added"
+	TriggerRemoved    TriggerChangeType = "removed"
+	TriggerRetargeted TriggerChangeType = "retargeted"
+	TriggerModified   TriggerChangeType = "modified"
+)
+
+// TriggerChange is the difference of a single trigger between revisions.
+type TriggerChange struct {
+	Type    TriggerChangeType
+	Trigger string
+	// Diff is the unified diff of the trigger spec.
+	Diff string
+}
+
+// History returns the recorded broker config revisions, oldest first.
+func History(configBase, broker string) ([]Revision, error) {
+	return readHistory(historyPath(ConfigPath(configBase, broker)))
+}
+
+// ReadRevision returns the broker config revision by its number.
+func ReadRevision(configBase, broker string, number int) (Revision, error) {
+	path := filepath.Join(historyPath(ConfigPath(configBase, broker)), revisionFile(number))
+	revision, err := readRevision(path)
+	if os.IsNotExist(err) {
+		return Revision{}, fmt.Errorf("revision %d not found", number)
+	}
+	return revision, err
+}
+
+// Rollback restores the broker config of the revision. The config file is
+// rewritten in place, so that the running broker reloads it, and the restored
+// state is recorded as the new revision. The config that was replaced is returned.
+func Rollback(configBase, broker string, number int) (Configuration, error) {
+	revision, err := ReadRevision(configBase, broker, number)
+	if err != nil {
+		return Configuration{}, err
+	}
+	var previous Configuration
+	if err := updateConfig(ConfigPath(configBase, broker), func(configuration *Configuration) bool {
+		previous = *configuration
+		if reflect.DeepEqual(configuration.Triggers, revision.Config.Triggers) {
+			return false
+		}
+		*configuration = revision.Config
+		return true
+	}); err != nil {
+		return Configuration{}, err
+	}
+	changes, err := Changes(previous, revision.Config)
+	if err != nil {
+		return previous, nil
+	}
+	for _, change := range changes {
+		switch change.Type {
+		case TriggerAdded:
+			lifecycle.Emit(lifecycle.TriggerAdded, change.Trigger, TriggerKind, nil)
+		case TriggerRemoved:
+			lifecycle.Emit(lifecycle.TriggerRemoved, change.Trigger, TriggerKind, nil)
+		default:
+			lifecycle.Emit(lifecycle.TriggerUpdated, change.Trigger, TriggerKind, nil)
+		}
+	}
+	return previous, nil
+}
+
+// recordRevision stores the broker config as the new revision and drops
+// the oldest revisions beyond the limit. The config before the first recorded
+// change is stored as the initial revision.
+func recordRevision(configPath string, previous, current *Configuration) error {
+	dir := historyPath(configPath)
+	revisions, err := revisionNumbers(dir)
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return err
+	}
+	next := 1
+	if len(revisions) == 0 {
+		if err := writeRevision(dir, Revision{Number: next, Time: time.Now(), Config: *previous}); err != nil {
+			return err
+		}
+		revisions = append(revisions, next)
+	}
+	next = revisions[len(revisions)-1] + 1
+	if err := writeRevision(dir, Revision{
+		Number:  next,
+		Time:    time.Now(),
+		Command: historyCommand,
+		Config:  *current,
+	}); err != nil {
+		return err
+	}
+	revisions = append(revisions, next)
+	for len(revisions) > HistoryLimit {
+		if err := os.Remove(filepath.Join(dir, revisionFile(revisions[0]))); err != nil && !os.IsNotExist(err) {
+			return err
+		}
+		revisions = revisions[1:]
+	}
+	return nil
+}
+
+// Changes returns the trigger changes required to turn
+// the first config into the second one, sorted by the trigger name.
+func Changes(from, to Configuration) ([]TriggerChange, error) {
+	names := make(map[string]struct{}, len(from.Triggers)+len(to.Triggers))
+	for name := range from.Triggers {
+		names[name] = struct{}{}
+	}
+	for name := range to.Triggers {
+		names[name] = struct{}{}
+	}
+	sorted := make([]string, 0, len(names))
+	for name := range names {
+		sorted = append(sorted, name)
+	}
+	sort.Strings(sorted)
+
+	var changes []TriggerChange
+	for _, name := range sorted {
+		a, inFrom := from.Triggers[name]
+		b, inTo := to.Triggers[name]
+		change := TriggerChange{Trigger: name}
+		switch {
+		case !inFrom:
+			change.Type = TriggerAdded
+		case !inTo:
+			change.Type = TriggerRemoved
+		case sameTrigger(a, b):
+			continue
+		case !reflect.DeepEqual(a.Target, b.Target) && equalFilters(a.Filters, b.Filters):
+			change.Type = TriggerRetargeted
+		default:
+			change.Type = TriggerModified
+		}
+		diff, err := triggerDiff(a, b, inFrom, inTo)
+		if err != nil {
+			return nil, fmt.Errorf("trigger %q: %w", name, err)
+		}
+		change.Diff = diff
+		changes = append(changes, change)
+	}
+	return changes, nil
+}
+
+// ManifestTrigger returns the trigger component for the broker config trigger.
+// Target component is referenced by its manifest object, triggers that do not
+// deliver the events to the component of the context keep the target URL.
+func ManifestTrigger(name, broker, configBase string, spec LocalTriggerSpec, objects []kubernetes.Object) (*Trigger, error) {
+	trigger, err := NewTrigger(name, broker, configBase, nil, nil)
+	if err != nil {
+		return nil, err
+	}
+	t := trigger.(*Trigger)
+	t.Filters = spec.Filters
+	if spec.Target.URL != "" {
+		if t.LocalURL, err = apis.ParseURL(spec.Target.TargetURL()); err != nil {
+			return nil, fmt.Errorf("target URL: %w", err)
+		}
+	}
+	t.ContentMode = spec.Target.ContentMode
+	if spec.Target.Component == "" {
+		if t.LocalURL == nil {
+			return nil, fmt.Errorf("trigger %q has no destination", name)
+		}
+		t.Target = duckv1.Destination{URI: t.LocalURL}
+		return t, nil
+	}
+	for _, object := range objects {
+		if object.Metadata.Name != spec.Target.Component || object.Kind == "Secret" || object.Kind == TriggerKind {
+			continue
+		}
+		t.Target = duckv1.Destination{
+			Ref: &duckv1.KReference{
+				Kind:       object.Kind,
+				Name:       object.Metadata.Name,
+				APIVersion: object.APIVersion,
+			},
+		}
+		if t.LocalURL != nil && strings.TrimSuffix(t.LocalURL.Path, "/") != "" {
+			t.Target.URI = &apis.URL{Path: t.LocalURL.Path, RawQuery: t.LocalURL.RawQuery}
+		}
+		return t, nil
+	}
+	return nil, fmt.Errorf("target component %q not found", spec.Target.Component)
+}
+
+func triggerDiff(a, b LocalTriggerSpec, inFrom, inTo bool) (string, error) {
+	var from, to string
+	if inFrom {
+		data, err := yaml.Marshal(a)
+		if err != nil {
+			return "", err
+		}
+		from = string(data)
+	}
+	if inTo {
+		data, err := yaml.Marshal(b)
+		if err != nil {
+			return "", err
+		}
+		to = string(data)
+	}
+	return difflib.GetUnifiedDiffString(difflib.UnifiedDiff{
+		A:       difflib.SplitLines(from),
+		B:       difflib.SplitLines(to),
+		Context: 3,
+	})
+}
+
+func readHistory(dir string) ([]Revision, error) {
+	numbers, err := revisionNumbers(dir)
+	if err != nil {
+		return nil, err
+	}
+	revisions := make([]Revision, 0, len(numbers))
+	for _, number := range numbers {
+		revision, err := readRevision(filepath.Join(dir, revisionFile(number)))
+		if err != nil {
+			return nil, fmt.Errorf("revision %d: %w", number, err)
+		}
+		revisions = append(revisions, revision)
+	}
+	return revisions, nil
+}
+
+// revisionNumbers returns the sorted numbers of the stored revisions.
+func revisionNumbers(dir string) ([]int, error) {
+	entries, err := os.ReadDir(dir)
+	if os.IsNotExist(err) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, fmt.Errorf("broker config history: %w", err)
+	}
+	var numbers []int
+	for _, entry := range entries {
+		number, err := strconv.Atoi(strings.TrimSuffix(entry.Name(), revisionFileExt))
+		if err != nil || entry.IsDir() {
+			continue
+		}
+		numbers = append(numbers, number)
+	}
+	sort.Ints(numbers)
+	return numbers, nil
+}
+
+func readRevision(path string) (Revision, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return Revision{}, err
+	}
+	var revision Revision
+	return revision, yaml.Unmarshal(data, &revision)
+}
+
+func writeRevision(dir string, revision Revision) error {
+	data, err := yaml.Marshal(revision)
+	if err != nil {
+		return fmt.Errorf("marshal revision: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, revisionFile(revision.Number)), data, os.ModePerm)
+}
+
+func revisionFile(number int) string {
+	return fmt.Sprintf("%0*d%s", revisionNameSize, number, revisionFileExt)
+}
+
+func historyPath(configPath string) string {
+	return filepath.Join(filepath.Dir(configPath), historyDir)
+}
@@ -0,0 +1,125 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+func historyConfigBase(t *testing.T) string {
+	configBase := t.TempDir()
+	require.NoError(t, os.MkdirAll(filepath.Join(configBase, "foo"), os.ModePerm))
+	require.NoError(t, os.WriteFile(filepath.Join(configBase, "foo", triggermesh.BrokerConfigFile), []byte("triggers: {}\n"), os.ModePerm))
+	return configBase
+}
+
+func TestHistory(t *testing.T) {
+	configBase := historyConfigBase(t)
+	SetHistoryCommand("tmctl create trigger --target sockeye")
+	defer SetHistoryCommand("")
+
+	sockeye := LocalTriggerSpec{Target: LocalTarget{URL: "http://sockeye:8080", Component: "sockeye"}}
+	display := LocalTriggerSpec{Target: LocalTarget{URL: "http://display:8080", Component: "display"}}
+
+	require.NoError(t, AddTrigger(configBase, "foo", "t1", sockeye))
+	// unchanged config is not recorded
+	require.NoError(t, AddTrigger(configBase, "foo", "t1", sockeye))
+	require.NoError(t, UpdateTriggerTarget(configBase, "foo", "t1", display.Target))
+	require.NoError(t, RemoveTrigger(configBase, "foo", "t1"))
+
+	revisions, err := History(configBase, "foo")
+	require.NoError(t, err)
+	require.Len(t, revisions, 4)
+	assert.Empty(t, revisions[0].Command)
+	assert.Equal(t, "tmctl create trigger --target sockeye", revisions[1].Command)
+
+	var types []TriggerChangeType
+	for i := 1; i < len(revisions); i++ {
+		assert.Equal(t, i+1, revisions[i].Number)
+		changes, err := Changes(revisions[i-1].Config, revisions[i].Config)
+		require.NoError(t, err)
+		require.Len(t, changes, 1)
+		assert.Equal(t, "t1", changes[0].Trigger)
+		assert.NotEmpty(t, changes[0].Diff)
+		types = append(types, changes[0].Type)
+	}
+	assert.Equal(t, []TriggerChangeType{TriggerAdded, TriggerRetargeted, TriggerRemoved}, types)
+
+	previous, err := Rollback(configBase, "foo", 2)
+	require.NoError(t, err)
+	assert.Empty(t, previous.Triggers)
+	config, err := ReadConfig(configBase, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, sockeye, config.Triggers["t1"])
+
+	revisions, err = History(configBase, "foo")
+	require.NoError(t, err)
+	assert.Len(t, revisions, 5)
+
+	_, err = Rollback(configBase, "foo", 42)
+	assert.Error(t, err)
+}
+
+func TestHistoryLimit(t *testing.T) {
+	configBase := historyConfigBase(t)
+	for i := 0; i < HistoryLimit+5; i++ {
+		require.NoError(t, AddTrigger(configBase, "foo", "t1", LocalTriggerSpec{
+			Target: LocalTarget{URL: fmt.Sprintf("http://sockeye:8080/%d", i)},
+		}))
+	}
+	revisions, err := History(configBase, "foo")
+	require.NoError(t, err)
+	assert.Len(t, revisions, HistoryLimit)
+	assert.Equal(t, HistoryLimit+6, revisions[len(revisions)-1].Number)
+}
+
+func TestManifestTrigger(t *testing.T) {
+	objects := []kubernetes.Object{
+		{APIVersion: "serving.knative.dev/v1", Kind: "Service", Metadata: kubernetes.Metadata{Name: "sockeye"}},
+	}
+
+	trigger, err := ManifestTrigger("t1", "foo", "", LocalTriggerSpec{
+		Filters: []eventingbroker.Filter{*FilterAttribute("type", "order.created")},
+		Target:  LocalTarget{URL: "http://sockeye:8080/events", Component: "sockeye"},
+	}, objects)
+	require.NoError(t, err)
+	assert.Equal(t, "Service", trigger.Target.Ref.Kind)
+	assert.Equal(t, "/events", trigger.TargetPath())
+	assert.Len(t, trigger.Filters, 1)
+
+	trigger, err = ManifestTrigger("t2", "foo", "", LocalTriggerSpec{
+		Target: LocalTarget{URL: "https://example.com/hook"},
+	}, objects)
+	require.NoError(t, err)
+	assert.True(t, trigger.IsExternal())
+
+	_, err = ManifestTrigger("t3", "foo", "", LocalTriggerSpec{
+		Target: LocalTarget{URL: "http://display:8080", Component: "display"},
+	}, objects)
+	assert.Error(t, err)
+}
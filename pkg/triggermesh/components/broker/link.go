@@ -0,0 +1,110 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+// Link is the trigger that forwards the events
+// from the broker of one context to the broker of another.
+type Link struct {
+	Trigger string
+	From    string
+	To      string
+	Filters []eventingbroker.Filter
+}
+
+// LinkTarget returns the broker of another context as the trigger target.
+func LinkTarget(configBase, context string, brokerConfig config.BrokerConfig) (triggermesh.Component, error) {
+	if _, err := os.Stat(filepath.Join(configBase, context, triggermesh.ManifestFile)); err != nil {
+		return nil, fmt.Errorf("broker %q does not exist", context)
+	}
+	return New(context, brokerConfig)
+}
+
+// IsLink returns true if the trigger delivers events to the broker of another context.
+func (t *Trigger) IsLink() bool {
+	return t.Target.Ref != nil && t.Target.Ref.Kind == BrokerKind && t.Target.Ref.Name != t.Broker.Name
+}
+
+// Links returns the links from and to the broker
+// found in the manifests of all contexts.
+func Links(configBase, broker string) ([]Link, error) {
+	dirs, err := os.ReadDir(configBase)
+	if err != nil {
+		return nil, fmt.Errorf("listing contexts: %w", err)
+	}
+	var links []Link
+	for _, dir := range dirs {
+		if !dir.IsDir() {
+			continue
+		}
+		m := manifest.New(filepath.Join(configBase, dir.Name(), triggermesh.ManifestFile))
+		if err := m.Read(); err != nil {
+			continue
+		}
+		for _, object := range m.Objects {
+			if object.Kind != TriggerKind {
+				continue
+			}
+			kind, _, _ := unstructured.NestedString(object.Spec, "target", "ref", "kind")
+			to, _, _ := unstructured.NestedString(object.Spec, "target", "ref", "name")
+			from := object.Metadata.Labels[triggermesh.ContextLabel]
+			if kind != BrokerKind || from == to || (from != broker && to != broker) {
+				continue
+			}
+			links = append(links, Link{
+				Trigger: object.Metadata.Name,
+				From:    from,
+				To:      to,
+				Filters: objectFilters(object.Spec),
+			})
+		}
+	}
+	sort.Slice(links, func(i, j int) bool {
+		if links[i].From != links[j].From {
+			return links[i].From < links[j].From
+		}
+		return links[i].Trigger < links[j].Trigger
+	})
+	return links, nil
+}
+
+func objectFilters(spec map[string]interface{}) []eventingbroker.Filter {
+	data, err := json.Marshal(spec["filters"])
+	if err != nil {
+		return nil
+	}
+	var filters []eventingbroker.Filter
+	if err := json.Unmarshal(data, &filters); err != nil {
+		return nil
+	}
+	return filters
+}
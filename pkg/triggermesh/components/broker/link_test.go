@@ -0,0 +1,87 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"context"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+// fakeBroker is the broker of another context with the known host port.
+type fakeBroker struct {
+	fakeTarget
+}
+
+func (f *fakeBroker) GetKind() string                         { return BrokerKind }
+func (f *fakeBroker) GetAPIVersion() string                   { return APIVersion }
+func (f *fakeBroker) GetPort(context.Context) (string, error) { return "8081", nil }
+
+func TestLinks(t *testing.T) {
+	configBase := t.TempDir()
+	for _, broker := range []string{"foo", "bar", "baz"} {
+		require.NoError(t, os.MkdirAll(filepath.Join(configBase, broker), os.ModePerm))
+		require.NoError(t, os.WriteFile(filepath.Join(configBase, broker, triggermesh.BrokerConfigFile), nil, os.ModePerm))
+		require.NoError(t, os.WriteFile(filepath.Join(configBase, broker, triggermesh.ManifestFile), nil, os.ModePerm))
+	}
+
+	_, err := LinkTarget(configBase, "missing", config.BrokerConfig{})
+	assert.Error(t, err)
+
+	m := manifest.New(filepath.Join(configBase, "foo", triggermesh.ManifestFile))
+	require.NoError(t, m.Read())
+	link, err := NewTrigger("", "foo", configBase, &fakeBroker{fakeTarget{name: "bar"}}, FilterAttribute("type", "order.created"))
+	require.NoError(t, err)
+	assert.True(t, link.(*Trigger).IsLink())
+	require.NoError(t, link.(*Trigger).WriteLocalConfig())
+	_, err = m.Add(link)
+	require.NoError(t, err)
+
+	local, err := NewTrigger("", "foo", configBase, &fakeTarget{name: "sockeye"}, nil)
+	require.NoError(t, err)
+	assert.False(t, local.(*Trigger).IsLink())
+	_, err = m.Add(local)
+	require.NoError(t, err)
+
+	// the other broker is addressed by the URL only
+	configuration, err := ReadConfig(configBase, "foo")
+	require.NoError(t, err)
+	assert.Equal(t, LocalTarget{URL: "http://host.docker.internal:8081"}, configuration.Triggers[link.GetName()].Target)
+
+	for _, broker := range []string{"foo", "bar"} {
+		links, err := Links(configBase, broker)
+		require.NoError(t, err)
+		require.Len(t, links, 1)
+		assert.Equal(t, Link{
+			Trigger: link.GetName(),
+			From:    "foo",
+			To:      "bar",
+			Filters: link.(*Trigger).Filters,
+		}, links[0])
+	}
+	links, err := Links(configBase, "baz")
+	require.NoError(t, err)
+	assert.Empty(t, links)
+}
@@ -0,0 +1,103 @@
+# Copyright 2022 TriggerMesh Inc.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+# Re-encodes the binary mode CloudEvents delivered by the broker into the
+# structured mode and forwards them to the URL of the "target" query parameter.
+# Only the Python standard library is used.
+
+import base64
+import json
+import sys
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+from urllib.error import HTTPError, URLError
+from urllib.parse import parse_qs, unquote, urlparse
+from urllib.request import Request, urlopen
+
+STRUCTURED = "application/cloudevents+json"
+TIMEOUT = 30
+
+
+def is_json(content_type):
+    media_type = content_type.split(";")[0].strip().lower()
+    return media_type == "application/json" or media_type.endswith("+json") or media_type == "text/json"
+
+
+def is_text(content_type):
+    media_type = content_type.split(";")[0].strip().lower()
+    return media_type.startswith("text/") or media_type in ("application/xml", "application/yaml")
+
+
+def structured(headers, body):
+    event = {}
+    for key, value in headers.items():
+        if key.lower().startswith("ce-"):
+            event[key[3:].lower()] = unquote(value)
+    if "specversion" not in event:
+        raise ValueError("request is not a binary mode CloudEvent")
+    content_type = headers.get("Content-Type", "")
+    if content_type:
+        event["datacontenttype"] = content_type
+    if body:
+        if is_json(content_type):
+            event["data"] = json.loads(body)
+        elif is_text(content_type):
+            event["data"] = body.decode("utf-8")
+        else:
+            event["data_base64"] = base64.b64encode(body).decode("ascii")
+    return json.dumps(event).encode("utf-8")
+
+
+class Handler(BaseHTTPRequestHandler):
+    protocol_version = "HTTP/1.1"
+
+    def do_POST(self):
+        body = self.rfile.read(int(self.headers.get("Content-Length") or 0))
+        target = parse_qs(urlparse(self.path).query).get("target", [""])[0]
+        if not target:
+            self.reply(400, b"target query parameter is required")
+            return
+        if self.headers.get("Content-Type", "").startswith(STRUCTURED):
+            envelope = body
+        else:
+            try:
+                envelope = structured(self.headers, body)
+            except ValueError as err:
+                self.reply(400, str(err).encode("utf-8"))
+                return
+        request = Request(target, data=envelope, method="POST",
+                          headers={"Content-Type": STRUCTURED + "; charset=utf-8"})
+        try:
+            with urlopen(request, timeout=TIMEOUT) as response:
+                self.reply(response.status, response.read(), response.headers)
+        except HTTPError as err:
+            self.reply(err.code, err.read(), err.headers)
+        except (URLError, OSError) as err:
+            self.reply(502, str(err).encode("utf-8"))
+
+    def reply(self, status, body, headers=None):
+        self.send_response(status)
+        if headers is not None:
+            # the reply events are passed back to the broker as is
+            for key, value in headers.items():
+                if key.lower() == "content-type" or key.lower().startswith("ce-"):
+                    self.send_header(key, value)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, format, *args):
+        sys.stdout.write("%s\n" % (format % args))
+
+
+ThreadingHTTPServer(("", 8080), Handler).serve_forever()
@@ -21,6 +21,7 @@ import (
 	"crypto/md5"
 	"encoding/hex"
 	"fmt"
+	"net/url"
 	"path/filepath"
 	"strings"
 
@@ -32,12 +33,15 @@ import (
 	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
 	eventingv1alpha1 "github.com/triggermesh/triggermesh-core/pkg/apis/eventing/v1alpha1"
 
+	"github.com/triggermesh/tmctl/pkg/docker"
 	"github.com/triggermesh/tmctl/pkg/kubernetes"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/urls"
 )
 
 const (
-	dockerHost = "http://host.docker.internal"
+	dockerHostname = urls.DockerHost
+	dockerHost     = "http://" + dockerHostname
 )
 
 var _ triggermesh.Component = (*Trigger)(nil)
@@ -46,6 +50,9 @@ type Trigger struct {
 	Name       string
 	ConfigBase string
 	LocalURL   *apis.URL
+	// ContentMode is the CloudEvents content mode of the deliveries,
+	// the binary mode of the broker if empty.
+	ContentMode string
 
 	eventingv1alpha1.TriggerSpec `yaml:"spec,omitempty"`
 }
@@ -58,6 +65,10 @@ func (t *Trigger) AsK8sObject() (kubernetes.Object, error) {
 	if len(t.Filters) != 0 {
 		spec["filters"] = t.Filters
 	}
+	var annotations map[string]string
+	if t.ContentMode == ContentModeStructured {
+		annotations = map[string]string{ContentModeAnnotation: t.ContentMode}
+	}
 	return kubernetes.Object{
 		APIVersion: APIVersion,
 		Kind:       TriggerKind,
@@ -67,6 +78,7 @@ func (t *Trigger) AsK8sObject() (kubernetes.Object, error) {
 			Labels: map[string]string{
 				"triggermesh.io/context": t.Broker.Name,
 			},
+			Annotations: annotations,
 		},
 		Spec: spec,
 	}, nil
@@ -114,27 +126,17 @@ func NewTrigger(name, broker, configBase string, target triggermesh.Component, f
 	}
 
 	if name == "" {
-		filterStruct, _ := yaml.Marshal(filter)
-		// in case of event types hash collision, replace with sha256
-		hash := md5.Sum([]byte(fmt.Sprintf("%s-%s", target.GetName(), string(filterStruct))))
-		trigger.Name = fmt.Sprintf("%s-trigger-%s", broker, hex.EncodeToString(hash[:4]))
+		trigger.Name = GeneratedName(broker, target.GetName(), "", filter)
 	}
 
 	if target != nil {
-		targetPort, err := target.(triggermesh.Consumer).GetPort(context.Background())
-		if err != nil {
-			return nil, fmt.Errorf("target local port: %w", err)
-		}
-		trigger.LocalURL, err = apis.ParseURL(fmt.Sprintf("%s:%s", dockerHost, targetPort))
+		var err error
+		trigger.LocalURL, err = targetURL(target, broker, "")
 		if err != nil {
-			return nil, fmt.Errorf("target local URL: %w", err)
+			return nil, err
 		}
 		trigger.Target = duckv1.Destination{
-			Ref: &duckv1.KReference{
-				Kind:       target.GetKind(),
-				Name:       target.GetName(),
-				APIVersion: target.GetAPIVersion(),
-			},
+			Ref: reference(target),
 		}
 	}
 
@@ -144,24 +146,238 @@ func NewTrigger(name, broker, configBase string, target triggermesh.Component, f
 	return trigger, nil
 }
 
+// NewURITrigger returns the trigger delivering the events to the external
+// absolute URI rather than to the component of the context.
+func NewURITrigger(name, broker, configBase string, uri *apis.URL, filter *eventingbroker.Filter) (triggermesh.Component, error) {
+	if uri == nil || uri.Scheme == "" || uri.Host == "" {
+		return nil, fmt.Errorf("target URI %q must be absolute", uri)
+	}
+	trigger := &Trigger{
+		Name:       name,
+		ConfigBase: configBase,
+		LocalURL:   uri,
+		TriggerSpec: eventingv1alpha1.TriggerSpec{
+			Broker: duckv1.KReference{
+				Name:  broker,
+				Kind:  BrokerKind,
+				Group: "eventing.triggermesh.io",
+			},
+			Target: duckv1.Destination{
+				URI: uri,
+			},
+		},
+	}
+	if name == "" {
+		trigger.Name = GeneratedName(broker, uri.String(), "", filter)
+	}
+	if filter != nil {
+		trigger.Filters = []eventingbroker.Filter{*filter}
+	}
+	return trigger, nil
+}
+
+// GeneratedName returns the name of the trigger derived
+// from the trigger destination, its path and filter.
+func GeneratedName(broker, destination, path string, filter *eventingbroker.Filter) string {
+	suffix := "-" + FilterHash(destination, path, filter)
+	// keep the hash suffix intact if the broker name is too long
+	prefix := triggermesh.SanitizeName(broker + "-trigger")
+	if len(prefix)+len(suffix) > triggermesh.MaxNameLength {
+		prefix = strings.TrimRight(prefix[:triggermesh.MaxNameLength-len(suffix)], "-")
+	}
+	return prefix + suffix
+}
+
+// FilterHash returns the short hash of the trigger destination, its path
+// and filter used in the generated trigger names. The root path is not
+// hashed so that the names of the triggers without the path do not change.
+func FilterHash(destination, path string, filter *eventingbroker.Filter) string {
+	destination += normalizePath(path)
+	filterStruct, _ := yaml.Marshal(filter)
+	// in case of event types hash collision, replace with sha256
+	hash := md5.Sum([]byte(fmt.Sprintf("%s-%s", destination, string(filterStruct))))
+	return hex.EncodeToString(hash[:4])
+}
+
+// reference returns the reference to the target component. The kind of
+// the component object is preferred as some components report their kind
+// in the lowercase CRD form that is not valid in the cluster.
+func reference(target triggermesh.Component) *duckv1.KReference {
+	kind := target.GetKind()
+	if object, err := target.AsK8sObject(); err == nil && object.Kind != "" {
+		kind = object.Kind
+	}
+	return &duckv1.KReference{
+		Kind:       kind,
+		Name:       target.GetName(),
+		APIVersion: target.GetAPIVersion(),
+	}
+}
+
+// IsExternal returns true if the trigger delivers the events
+// to the absolute URI instead of the component.
+func (t *Trigger) IsExternal() bool {
+	return t.Target.Ref == nil && t.Target.URI != nil && t.Target.URI.Host != ""
+}
+
+// TargetName returns the name of the target component
+// or the URI of the external destination.
+func (t *Trigger) TargetName() string {
+	switch {
+	case t.Target.Ref != nil:
+		return t.Target.Ref.Name
+	case t.Target.URI != nil:
+		return t.Target.URI.String()
+	}
+	return ""
+}
+
+// TemplateTriggerName returns the deterministic name of the trigger
+// delivering the events of the type to the target.
+func TemplateTriggerName(target, eventType string) string {
+	hash := md5.Sum([]byte(eventType))
+	suffix := "-" + hex.EncodeToString(hash[:4])
+	prefix := triggermesh.SanitizeName(target)
+	if len(prefix)+len(suffix) > triggermesh.MaxNameLength {
+		prefix = strings.TrimRight(prefix[:triggermesh.MaxNameLength-len(suffix)], "-")
+	}
+	return prefix + suffix
+}
+
+// SetTarget changes the trigger destination component,
+// the target path is kept.
 func (t *Trigger) SetTarget(target triggermesh.Component) {
 	t.Target = duckv1.Destination{
-		Ref: &duckv1.KReference{
-			Kind:       target.GetKind(),
-			Name:       target.GetName(),
-			APIVersion: target.GetAPIVersion(),
-		},
+		Ref: reference(target),
+		URI: t.Target.URI,
 	}
-	if consumer, ok := target.(triggermesh.Consumer); ok {
-		port, err := consumer.GetPort(context.Background())
-		if err != nil {
-			return
+	if _, ok := target.(triggermesh.Consumer); ok {
+		if url, err := targetURL(target, t.Broker.Name, t.TargetPath()); err == nil {
+			t.LocalURL = url
 		}
-		t.LocalURL, err = apis.ParseURL(fmt.Sprintf("%s:%s", dockerHost, port))
-		if err != nil {
-			return
+	}
+}
+
+// SetTargetPort overrides the local target address port,
+// e.g. to deliver events through the target replicas load balancer.
+func (t *Trigger) SetTargetPort(port string) error {
+	url, err := localURL(port, t.TargetPath())
+	if err != nil {
+		return err
+	}
+	t.LocalURL = url
+	return nil
+}
+
+// SetTargetPath sets the path of the target component address that the events
+// are delivered to. Following Knative Destination semantics, the path is
+// stored as the relative URI resolved against the target reference.
+// Target URLs that already have the path are not changed.
+func (t *Trigger) SetTargetPath(path string) error {
+	if path == "" {
+		return nil
+	}
+	if !strings.HasPrefix(path, "/") {
+		return fmt.Errorf("target path %q must start with \"/\"", path)
+	}
+	uri, err := apis.ParseURL(path)
+	if err != nil || uri.Host != "" || uri.Scheme != "" {
+		return fmt.Errorf("invalid target path %q", path)
+	}
+	if t.LocalURL != nil {
+		switch current := strings.TrimSuffix(t.LocalURL.Path, "/"); current {
+		case "":
+			t.LocalURL.Path = uri.Path
+			t.LocalURL.RawQuery = uri.RawQuery
+		case strings.TrimSuffix(uri.Path, "/"):
+		default:
+			return fmt.Errorf("target URL %s already has the path", t.LocalURL)
+		}
+	}
+	t.Target.URI = uri
+	return nil
+}
+
+// normalizePath returns the path and query of the address
+// without the trailing slash, empty for the root path.
+func normalizePath(address string) string {
+	u, err := url.Parse(address)
+	if err != nil {
+		return address
+	}
+	path := strings.TrimSuffix(u.EscapedPath(), "/")
+	if u.RawQuery != "" {
+		path += "?" + u.RawQuery
+	}
+	return path
+}
+
+// TargetPath returns the path of the target address, if set.
+func (t *Trigger) TargetPath() string {
+	if t.Target.URI == nil || t.Target.URI.Host != "" {
+		return ""
+	}
+	return t.Target.URI.String()
+}
+
+func localURL(port, path string) (*apis.URL, error) {
+	return apis.ParseURL(fmt.Sprintf("%s:%s%s", dockerHost, port, path))
+}
+
+// targetURL returns the address the broker delivers the events to.
+// Components of the context are resolved by their name in the context
+// network, while the brokers of other contexts and the containers not
+// created by tmctl are reached through the published host port.
+func targetURL(target triggermesh.Component, broker, path string) (*apis.URL, error) {
+	if networked, ok := target.(triggermesh.Networked); ok && !isOtherBroker(target, broker) {
+		if aliases := networked.NetworkAliases(); len(aliases) != 0 && inContextNetwork(broker, target.GetName()) {
+			return InternalURL(aliases[0], path)
+		}
+	}
+	port, err := target.(triggermesh.Consumer).GetPort(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("target local port: %w", err)
+	}
+	url, err := localURL(port, path)
+	if err != nil {
+		return nil, fmt.Errorf("target local URL: %w", err)
+	}
+	return url, nil
+}
+
+// InternalURL returns the address of the component in the context network.
+func InternalURL(name, path string) (*apis.URL, error) {
+	return apis.ParseURL(fmt.Sprintf("http://%s:%s%s", name, triggermesh.ContainerPort, path))
+}
+
+// inContextNetwork returns true if both the broker and the target containers
+// joined the context network. Containers created by the older tmctl versions
+// join it when the context is started again.
+func inContextNetwork(broker, target string) bool {
+	client, err := docker.NewClient()
+	if err != nil {
+		return false
+	}
+	ctx := context.Background()
+	network := docker.NetworkName(broker)
+	for _, name := range []string{broker + "-broker", target} {
+		container, err := (&docker.Container{Name: name}).LookupHostConfig(ctx, client)
+		if err != nil || !container.Joined(network) {
+			return false
 		}
 	}
+	return true
+}
+
+func isOtherBroker(target triggermesh.Component, broker string) bool {
+	return target.GetKind() == BrokerKind && target.GetName() != broker
+}
+
+// SetTLS switches the local target address to HTTPS.
+func (t *Trigger) SetTLS() {
+	if t.LocalURL != nil {
+		t.LocalURL.Scheme = "https"
+	}
 }
 
 func (t *Trigger) LookupTarget() {
@@ -173,15 +389,19 @@ func (t *Trigger) LookupTarget() {
 	if !exists {
 		return
 	}
-	if url, _ := apis.ParseURL(localTrigger.Target.URL); url != nil {
+	if url, _ := apis.ParseURL(localTrigger.Target.TargetURL()); url != nil {
 		t.LocalURL = url
 	}
+	t.ContentMode = localTrigger.Target.ContentMode
 	t.Filters = localTrigger.Filters
 	t.Target = duckv1.Destination{
 		Ref: &duckv1.KReference{
 			Name: localTrigger.Target.Component,
 		},
 	}
+	if t.LocalURL != nil && strings.TrimSuffix(t.LocalURL.Path, "/") != "" {
+		t.Target.URI = &apis.URL{Path: t.LocalURL.Path, RawQuery: t.LocalURL.RawQuery}
+	}
 }
 
 func FilterAttribute(attribute, value string) *eventingbroker.Filter {
@@ -0,0 +1,261 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+
+	"knative.dev/pkg/apis"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/lifecycle"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+// Problem is the broker configuration issue found by ValidateConfig.
+type Problem struct {
+	// Trigger is empty if the problem is not related to a single trigger.
+	Trigger string
+	Line    int
+	Message string
+	// UnknownDestination is set if the trigger delivers events to
+	// the component that does not exist.
+	UnknownDestination bool
+}
+
+func (p Problem) String() string {
+	var prefix string
+	if p.Line != 0 {
+		prefix = fmt.Sprintf("line %d: ", p.Line)
+	}
+	if p.Trigger != "" {
+		prefix = fmt.Sprintf("%strigger %q: ", prefix, p.Trigger)
+	}
+	return prefix + p.Message
+}
+
+// ConfigPath returns the path of the broker configuration file.
+func ConfigPath(configBase, broker string) string {
+	return filepath.Join(configBase, broker, triggermesh.BrokerConfigFile)
+}
+
+// ValidateConfig checks that the broker configuration can be parsed,
+// that the trigger destinations refer to the known components or valid URIs
+// and that the trigger filters are well-formed.
+func ValidateConfig(path string, components []string) ([]Problem, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("read file: %w", err)
+	}
+	var root yaml.Node
+	if err := yaml.Unmarshal(data, &root); err != nil {
+		// parser errors include the line number
+		return []Problem{{Message: err.Error()}}, nil
+	}
+	lines := triggerLines(&root)
+
+	var config Configuration
+	if err := root.Decode(&config); err != nil {
+		return []Problem{{Message: err.Error()}}, nil
+	}
+
+	known := make(map[string]struct{}, len(components))
+	for _, c := range components {
+		known[c] = struct{}{}
+	}
+	names := make([]string, 0, len(config.Triggers))
+	for name := range config.Triggers {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	var problems []Problem
+	for _, name := range names {
+		trigger := config.Triggers[name]
+		report := func(unknown bool, format string, a ...interface{}) {
+			problems = append(problems, Problem{
+				Trigger:            name,
+				Line:               lines[name],
+				Message:            fmt.Sprintf(format, a...),
+				UnknownDestination: unknown,
+			})
+		}
+		switch {
+		case trigger.Target.Component == "" && trigger.Target.URL == "":
+			report(false, "destination is not set")
+		case trigger.Target.Component != "":
+			if _, exists := known[trigger.Target.Component]; !exists {
+				report(true, "destination component %q does not exist", trigger.Target.Component)
+			}
+		}
+		if trigger.Target.URL != "" {
+			if err := validateURL(trigger.Target.URL); err != nil {
+				report(false, "destination URL %q: %v", trigger.Target.URL, err)
+			}
+		} else if trigger.Target.Component != "" {
+			report(false, "destination URL of component %q is not set", trigger.Target.Component)
+		}
+		for i, filter := range trigger.Filters {
+			if err := validateFilter(filter); err != nil {
+				report(false, "filter %d: %v", i+1, err)
+			}
+		}
+	}
+	return problems, nil
+}
+
+// Destinations returns the names of the manifest objects
+// that can be referenced as the trigger destinations.
+func Destinations(objects []kubernetes.Object) []string {
+	var result []string
+	for _, object := range objects {
+		if object.Kind == TriggerKind {
+			continue
+		}
+		result = append(result, object.Metadata.Name)
+	}
+	return result
+}
+
+// RemoveTriggers deletes the triggers from the broker configuration file.
+func RemoveTriggers(path string, triggers []string) error {
+	var removed []string
+	if err := updateConfig(path, func(configuration *Configuration) bool {
+		removed = removed[:0]
+		for _, trigger := range triggers {
+			if _, exists := configuration.Triggers[trigger]; exists {
+				delete(configuration.Triggers, trigger)
+				removed = append(removed, trigger)
+			}
+		}
+		return len(removed) != 0
+	}); err != nil {
+		return err
+	}
+	for _, trigger := range removed {
+		lifecycle.Emit(lifecycle.TriggerRemoved, trigger, TriggerKind, nil)
+	}
+	return nil
+}
+
+func validateURL(rawURL string) error {
+	url, err := apis.ParseURL(rawURL)
+	if err != nil {
+		return err
+	}
+	if url.Scheme != "http" && url.Scheme != "https" {
+		return fmt.Errorf("unsupported scheme %q", url.Scheme)
+	}
+	if url.Host == "" {
+		return errors.New("host is not set")
+	}
+	return nil
+}
+
+// validateFilter checks the filter dialect rules described in the
+// broker Filter type: every filter has exactly one expression,
+// the attribute expressions have exactly one non-empty property
+// and the nested expressions are valid.
+func validateFilter(f eventingbroker.Filter) error {
+	var expressions []string
+	if len(f.All) != 0 {
+		expressions = append(expressions, "all")
+	}
+	if len(f.Any) != 0 {
+		expressions = append(expressions, "any")
+	}
+	if f.Not != nil {
+		expressions = append(expressions, "not")
+	}
+	attributes := map[string]map[string]string{
+		"exact":  f.Exact,
+		"prefix": f.Prefix,
+		"suffix": f.Suffix,
+	}
+	for dialect, attribute := range attributes {
+		if len(attribute) != 0 {
+			expressions = append(expressions, dialect)
+		}
+	}
+	switch len(expressions) {
+	case 0:
+		return errors.New("filter has no expression")
+	case 1:
+	default:
+		sort.Strings(expressions)
+		return fmt.Errorf("filter has multiple expressions: %s", strings.Join(expressions, ", "))
+	}
+
+	switch expressions[0] {
+	case "all", "any":
+		nested := f.All
+		if expressions[0] == "any" {
+			nested = f.Any
+		}
+		for i, n := range nested {
+			if err := validateFilter(n); err != nil {
+				return fmt.Errorf("%s[%d]: %w", expressions[0], i, err)
+			}
+		}
+	case "not":
+		if err := validateFilter(*f.Not); err != nil {
+			return fmt.Errorf("not: %w", err)
+		}
+	default:
+		attribute := attributes[expressions[0]]
+		if len(attribute) != 1 {
+			return fmt.Errorf("%s must contain exactly one attribute", expressions[0])
+		}
+		for k, v := range attribute {
+			if k == "" || v == "" {
+				return fmt.Errorf("%s attribute name and value must not be empty", expressions[0])
+			}
+		}
+	}
+	return nil
+}
+
+// triggerLines returns the config file line numbers of the triggers.
+func triggerLines(root *yaml.Node) map[string]int {
+	result := make(map[string]int)
+	if root.Kind != yaml.DocumentNode || len(root.Content) == 0 {
+		return result
+	}
+	config := root.Content[0]
+	if config.Kind != yaml.MappingNode {
+		return result
+	}
+	for i := 0; i+1 < len(config.Content); i += 2 {
+		if config.Content[i].Value != "triggers" || config.Content[i+1].Kind != yaml.MappingNode {
+			continue
+		}
+		triggers := config.Content[i+1]
+		for j := 0; j+1 < len(triggers.Content); j += 2 {
+			result[triggers.Content[j].Value] = triggers.Content[j].Line
+		}
+	}
+	return result
+}
@@ -0,0 +1,133 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package broker
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
+)
+
+const brokerConfig = `triggers:
+  foo-trigger-valid:
+    filters:
+      - exact:
+          type: order.created
+    target:
+      url: http://host.docker.internal:55001
+      component: sockeye
+  foo-trigger-unknown:
+    target:
+      url: http://host.docker.internal:55002
+      component: deleted
+  foo-trigger-filter:
+    filters:
+      - exactt:
+          type: order.created
+      - any:
+          - prefix:
+              type: ""
+    target:
+      url: http://host.docker.internal:55001
+      component: sockeye
+  foo-trigger-url:
+    target:
+      url: "ftp://example.com"
+`
+
+func TestValidateConfig(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broker.conf")
+	require.NoError(t, os.WriteFile(path, []byte(brokerConfig), os.ModePerm))
+
+	problems, err := ValidateConfig(path, []string{"sockeye"})
+	require.NoError(t, err)
+
+	reported := make(map[string][]Problem)
+	for _, p := range problems {
+		reported[p.Trigger] = append(reported[p.Trigger], p)
+	}
+	assert.NotContains(t, reported, "foo-trigger-valid")
+	require.Len(t, reported["foo-trigger-unknown"], 1)
+	assert.True(t, reported["foo-trigger-unknown"][0].UnknownDestination)
+	assert.Equal(t, 9, reported["foo-trigger-unknown"][0].Line)
+	assert.Len(t, reported["foo-trigger-filter"], 2)
+	assert.Equal(t, 13, reported["foo-trigger-filter"][0].Line)
+	require.Len(t, reported["foo-trigger-url"], 1)
+	assert.False(t, reported["foo-trigger-url"][0].UnknownDestination)
+
+	require.NoError(t, RemoveTriggers(path, []string{"foo-trigger-unknown"}))
+	problems, err = ValidateConfig(path, []string{"sockeye"})
+	require.NoError(t, err)
+	assert.Len(t, problems, 3)
+}
+
+func TestValidateConfigSyntax(t *testing.T) {
+	path := filepath.Join(t.TempDir(), "broker.conf")
+	require.NoError(t, os.WriteFile(path, []byte("triggers:\n  foo: [\n"), os.ModePerm))
+	problems, err := ValidateConfig(path, nil)
+	require.NoError(t, err)
+	require.Len(t, problems, 1)
+	assert.Empty(t, problems[0].Trigger)
+}
+
+func TestValidateFilter(t *testing.T) {
+	testCases := map[string]struct {
+		filter eventingbroker.Filter
+		valid  bool
+	}{
+		"exact": {
+			filter: eventingbroker.Filter{Exact: map[string]string{"type": "foo"}},
+			valid:  true,
+		},
+		"nested": {
+			filter: eventingbroker.Filter{All: []eventingbroker.Filter{
+				{Prefix: map[string]string{"type": "foo."}},
+				{Not: &eventingbroker.Filter{Suffix: map[string]string{"source": ".test"}}},
+			}},
+			valid: true,
+		},
+		"empty": {
+			filter: eventingbroker.Filter{},
+		},
+		"multiple expressions": {
+			filter: eventingbroker.Filter{
+				Exact:  map[string]string{"type": "foo"},
+				Prefix: map[string]string{"type": "foo"},
+			},
+		},
+		"multiple attributes": {
+			filter: eventingbroker.Filter{Exact: map[string]string{"type": "foo", "source": "bar"}},
+		},
+		"empty value": {
+			filter: eventingbroker.Filter{Suffix: map[string]string{"type": ""}},
+		},
+		"invalid nested": {
+			filter: eventingbroker.Filter{Not: &eventingbroker.Filter{}},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := validateFilter(tc.filter)
+			assert.Equal(t, tc.valid, err == nil, err)
+		})
+	}
+}
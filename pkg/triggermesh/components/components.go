@@ -24,12 +24,16 @@ import (
 
 	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
 	"gopkg.in/yaml.v3"
+	"knative.dev/pkg/apis"
 	duckv1 "knative.dev/pkg/apis/duck/v1"
 
 	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/filesink"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/function"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/secret"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/service"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/source"
@@ -38,16 +42,26 @@ import (
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
 )
 
-func GetObject(name string, config *config.Config, manifest *manifest.Manifest, crds map[string]crd.CRD) (triggermesh.Component, error) {
+func GetObject(name string, config *config.Config, manifest *manifest.Manifest, crds map[string]crd.CRD) (component triggermesh.Component, err error) {
+	// malformed object must not take down the whole command
+	defer func() {
+		if r := recover(); r != nil {
+			component, err = nil, fmt.Errorf("object %q: %v", name, r)
+		}
+	}()
 	for _, object := range manifest.Objects {
 		if object.Metadata.Name != name {
 			continue
 		}
+		if err := UnsupportedKind(object, crds); err != nil {
+			return nil, err
+		}
 		broker, set := object.Metadata.Labels["triggermesh.io/context"]
 		if !set {
 			return nil, fmt.Errorf("context label not set")
 		}
 		crd := crds[strings.ToLower(object.Kind)]
+		version := AdapterVersion(object, config.Triggermesh.ComponentsVersion)
 		switch object.APIVersion {
 		case "sources.triggermesh.io/v1alpha1":
 			status := make(map[string]interface{}, 0)
@@ -60,11 +74,15 @@ func GetObject(name string, config *config.Config, manifest *manifest.Manifest,
 					}
 				}
 			}
-			return source.New(object.Metadata.Name, object.Kind, broker, config.Triggermesh.ComponentsVersion, crd, object.Spec, status), nil
+			return source.New(object.Metadata.Name, object.Kind, broker, version, crd, object.Spec, status), nil
 		case "targets.triggermesh.io/v1alpha1":
-			return target.New(object.Metadata.Name, object.Kind, broker, config.Triggermesh.ComponentsVersion, crd, object.Spec), nil
+			return target.New(object.Metadata.Name, object.Kind, broker, version, crd, object.Spec), nil
 		case "flow.triggermesh.io/v1alpha1":
-			return transformation.New(object.Metadata.Name, object.Kind, broker, config.Triggermesh.ComponentsVersion, crd, object.Spec), nil
+			return transformation.New(object.Metadata.Name, object.Kind, broker, version, crd, object.Spec), nil
+		case function.APIVersion:
+			return function.New(object.Metadata.Name, broker, version, crd, object.Spec), nil
+		case filesink.APIVersion:
+			return filesink.New(object.Metadata.Name, broker, object.Spec), nil
 		case "eventing.triggermesh.io/v1alpha1":
 			switch object.Kind {
 			case "RedisBroker":
@@ -72,14 +90,32 @@ func GetObject(name string, config *config.Config, manifest *manifest.Manifest,
 			case "Trigger":
 				brokerConfigPath := filepath.Dir(manifest.Path)
 				baseConfigPath := filepath.Dir(brokerConfigPath)
-				targetName, filter, err := parseTriggerSpec(object.Spec)
+				targetName, targetPath, filter, err := parseTriggerSpec(object.Spec)
 				if err != nil {
 					return nil, fmt.Errorf("trigger spec: %w", err)
 				}
+				contentMode := object.Metadata.Annotations[tmbroker.ContentModeAnnotation]
+				if targetName == "" {
+					// external destination
+					uri, err := apis.ParseURL(targetPath)
+					if err != nil {
+						return nil, fmt.Errorf("trigger spec: %w", err)
+					}
+					trigger, err := tmbroker.NewURITrigger(object.Metadata.Name, broker, baseConfigPath, uri, filter)
+					if err != nil {
+						return nil, err
+					}
+					trigger.(*tmbroker.Trigger).ContentMode = contentMode
+					return trigger, nil
+				}
 				trigger, err := tmbroker.NewTrigger(object.Metadata.Name, broker, baseConfigPath, nil, filter)
 				if err != nil {
 					return nil, fmt.Errorf("trigger object: %w", err)
 				}
+				trigger.(*tmbroker.Trigger).ContentMode = contentMode
+				if err := trigger.(*tmbroker.Trigger).SetTargetPath(targetPath); err != nil {
+					return nil, fmt.Errorf("trigger spec: %w", err)
+				}
 				if target, _ := GetObject(targetName, config, manifest, crds); target != nil {
 					trigger.(*tmbroker.Trigger).SetTarget(target)
 				}
@@ -90,27 +126,13 @@ func GetObject(name string, config *config.Config, manifest *manifest.Manifest,
 			if !set {
 				break
 			}
-			// TODO: Fix this
-			params := make(map[string]string)
-			container := object.Spec["template"].(map[string]interface{})["spec"].(map[string]interface{})["containers"].([]interface{})[0]
-			image := container.(map[string]interface{})["image"].(string)
-			env := container.(map[string]interface{})["env"]
-			if env != nil {
-				for _, v := range env.([]interface{}) {
-					val, ok := v.(map[string]interface{})
-					if !ok {
-						continue
-					}
-					name, ok := val["name"]
-					if !ok {
-						continue
-					}
-					value, ok := val["value"]
-					if !ok {
-						continue
-					}
-					params[name.(string)] = value.(string)
-				}
+			image, params, err := serviceContainer(object.Spec)
+			if err != nil {
+				return nil, fmt.Errorf("service %q: %w", name, err)
+			}
+			if adopted, set := object.Metadata.Annotations[service.AdoptedContainerAnnotation]; set {
+				port := object.Metadata.Annotations[service.AdoptedPortAnnotation]
+				return service.NewAdopted(name, adopted, image, port, broker), nil
 			}
 			return service.New(name, image, broker, service.Role(role), params), nil
 		case "v1":
@@ -122,6 +144,15 @@ func GetObject(name string, config *config.Config, manifest *manifest.Manifest,
 	return nil, nil
 }
 
+// AdapterVersion returns the adapter image version of the manifest object:
+// the version pinned in the object annotation or the version of the context.
+func AdapterVersion(object kubernetes.Object, contextVersion string) string {
+	if pinned := object.Metadata.Annotations[triggermesh.AdapterVersionAnnotation]; pinned != "" {
+		return pinned
+	}
+	return contextVersion
+}
+
 func ProcessSecrets(p triggermesh.Parent, manifest *manifest.Manifest) ([]triggermesh.Component, map[string]string, error) {
 	secrets := readSecrets(p, manifest)
 	plainSecretsEnv, err := decodeSecrets(secrets)
@@ -162,21 +193,66 @@ func decodeSecrets(secrets []triggermesh.Component) (map[string]string, error) {
 	return result, nil
 }
 
-func parseTriggerSpec(spec map[string]interface{}) (string, *eventingbroker.Filter, error) {
+// serviceContainer returns the image and the environment
+// of the first container of the service spec.
+func serviceContainer(spec map[string]interface{}) (string, map[string]string, error) {
+	template, _ := spec["template"].(map[string]interface{})
+	podSpec, _ := template["spec"].(map[string]interface{})
+	containers, _ := podSpec["containers"].([]interface{})
+	if len(containers) == 0 {
+		return "", nil, fmt.Errorf("containers not found")
+	}
+	container, _ := containers[0].(map[string]interface{})
+	image, _ := container["image"].(string)
+	if image == "" {
+		return "", nil, fmt.Errorf("container image not set")
+	}
+	params := make(map[string]string)
+	env, _ := container["env"].([]interface{})
+	for _, v := range env {
+		val, ok := v.(map[string]interface{})
+		if !ok {
+			continue
+		}
+		name, ok := val["name"].(string)
+		if !ok {
+			continue
+		}
+		value, ok := val["value"].(string)
+		if !ok {
+			continue
+		}
+		params[name] = value
+	}
+	return image, params, nil
+}
+
+func parseTriggerSpec(spec map[string]interface{}) (string, string, *eventingbroker.Filter, error) {
 	triggerSpec, err := yaml.Marshal(spec)
 	if err != nil {
-		return "", nil, err
+		return "", "", nil, err
 	}
 	t := struct {
 		Filters []eventingbroker.Filter `yaml:"filters,omitempty"`
-		Target  duckv1.Destination      `yaml:"target"`
+		Target  struct {
+			Ref *duckv1.KReference `yaml:"ref"`
+			// URI is the path relative to the target reference address
+			// or the absolute URI of the external destination.
+			URI string `yaml:"uri,omitempty"`
+		} `yaml:"target"`
 	}{}
 	if err := yaml.Unmarshal(triggerSpec, &t); err != nil {
-		return "", nil, err
+		return "", "", nil, err
 	}
 	var filter *eventingbroker.Filter
 	if len(t.Filters) == 1 {
 		filter = &t.Filters[0]
 	}
-	return t.Target.Ref.Name, filter, nil
+	if t.Target.Ref == nil {
+		if strings.Contains(t.Target.URI, "://") {
+			return "", t.Target.URI, filter, nil
+		}
+		return "", "", nil, fmt.Errorf("target reference is not set")
+	}
+	return t.Target.Ref.Name, t.Target.URI, filter, nil
 }
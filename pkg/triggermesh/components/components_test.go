@@ -17,13 +17,19 @@ limitations under the License.
 package components
 
 import (
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
 
 	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/health"
 	"github.com/triggermesh/tmctl/pkg/manifest"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/filesink"
 	"github.com/triggermesh/tmctl/pkg/triggermesh/components/source"
 	"github.com/triggermesh/tmctl/test"
 )
@@ -52,6 +58,40 @@ func TestGetObject(t *testing.T) {
 	}
 }
 
+func TestGetObjectPinnedVersion(t *testing.T) {
+	m := manifest.New(test.Manifest())
+	assert.NoError(t, m.Read())
+	c := &config.Config{
+		Triggermesh: config.TmConfig{ComponentsVersion: version},
+	}
+	var pinned string
+	for i, object := range m.Objects {
+		if object.APIVersion != "sources.triggermesh.io/v1alpha1" {
+			continue
+		}
+		if m.Objects[i].Metadata.Annotations == nil {
+			m.Objects[i].Metadata.Annotations = make(map[string]string)
+		}
+		m.Objects[i].Metadata.Annotations[triggermesh.AdapterVersionAnnotation] = "v1.20.0"
+		pinned = object.Metadata.Name
+		break
+	}
+	assert.NotEmpty(t, pinned)
+	for _, object := range m.Objects {
+		component, err := GetObject(object.Metadata.Name, c, m, test.CRD())
+		assert.NoError(t, err)
+		versioned, ok := component.(triggermesh.Versioned)
+		if !ok {
+			continue
+		}
+		expected := version
+		if object.Metadata.Name == pinned {
+			expected = "v1.20.0"
+		}
+		assert.Equal(t, expected, versioned.GetVersion(), object.Metadata.Name)
+	}
+}
+
 func TestProcessSecrets(t *testing.T) {
 	m := manifest.New(test.Manifest())
 	assert.NoError(t, m.Read())
@@ -82,3 +122,135 @@ func TestProcessSecrets(t *testing.T) {
 		})
 	}
 }
+
+func TestGetObjectBrokenManifest(t *testing.T) {
+	data, err := os.ReadFile(test.Manifest())
+	assert.NoError(t, err)
+	broken := `
+---
+apiVersion: serving.knative.dev/v1
+kind: Service
+metadata:
+  name: broken-service
+  labels:
+    triggermesh.io/context: foo
+    triggermesh.io/role: target
+spec:
+  template: nginx
+---
+apiVersion: eventing.triggermesh.io/v1alpha1
+kind: Trigger
+metadata:
+  name: broken-trigger
+  labels:
+    triggermesh.io/context: foo
+spec:
+  broker:
+    kind: RedisBroker
+    name: foo
+`
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	assert.NoError(t, os.WriteFile(path, append(data, []byte(broken)...), os.ModePerm))
+
+	m := manifest.New(path)
+	assert.NoError(t, m.Read())
+	c := &config.Config{
+		Triggermesh: config.TmConfig{ComponentsVersion: version},
+	}
+	healthy := 0
+	for _, object := range m.Objects {
+		component, err := GetObject(object.Metadata.Name, c, m, test.CRD())
+		if strings.HasPrefix(object.Metadata.Name, "broken-") {
+			assert.Error(t, err, object.Metadata.Name)
+			assert.Nil(t, component)
+			continue
+		}
+		assert.NoError(t, err, object.Metadata.Name)
+		healthy++
+	}
+	assert.Equal(t, 7, healthy)
+}
+
+func TestGetObjectExternalTrigger(t *testing.T) {
+	external := `
+---
+apiVersion: eventing.triggermesh.io/v1alpha1
+kind: Trigger
+metadata:
+  name: foo-trigger-external
+  labels:
+    triggermesh.io/context: foo
+spec:
+  broker:
+    kind: RedisBroker
+    name: foo
+  target:
+    uri: https://example.com/hooks
+`
+	data, err := os.ReadFile(test.Manifest())
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	assert.NoError(t, os.WriteFile(path, append(data, []byte(external)...), os.ModePerm))
+
+	m := manifest.New(path)
+	assert.NoError(t, m.Read())
+	c := &config.Config{
+		Triggermesh: config.TmConfig{ComponentsVersion: version},
+	}
+	component, err := GetObject("foo-trigger-external", c, m, test.CRD())
+	assert.NoError(t, err)
+	trigger, ok := component.(*tmbroker.Trigger)
+	assert.True(t, ok)
+	assert.True(t, trigger.IsExternal())
+	assert.Equal(t, "https://example.com/hooks", trigger.LocalURL.String())
+}
+
+func TestReadiness(t *testing.T) {
+	crds := test.CRD()
+	s := source.New("foo-webhooksource", "webhooksource", "foo", version, crds["webhooksource"], nil, nil)
+
+	r, err := Readiness(s, crds)
+	assert.NoError(t, err)
+	assert.Equal(t, health.DefaultReadiness, r)
+	assert.False(t, HasReadiness(s, crds))
+
+	annotated := crds["webhooksource"]
+	annotated.Metadata.Annotations.Readiness = "log:Started receiver"
+	crds["webhooksource"] = annotated
+	r, err = Readiness(s, crds)
+	assert.NoError(t, err)
+	assert.Equal(t, health.Readiness{Strategy: health.Log, Argument: "Started receiver"}, r)
+	assert.True(t, HasReadiness(s, crds))
+
+	annotated.Metadata.Annotations.Readiness = "exec:true"
+	crds["webhooksource"] = annotated
+	_, err = Readiness(s, crds)
+	assert.ErrorContains(t, err, "webhooksource CRD")
+}
+
+func TestGetObjectFileSink(t *testing.T) {
+	sink := `
+---
+apiVersion: tmctl.triggermesh.io/v1alpha1
+kind: FileSink
+metadata:
+  name: audit-log
+  labels:
+    triggermesh.io/context: foo
+spec:
+  path: /tmp/events
+  format: jsonl
+  maxFileSize: 50MB
+`
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	assert.NoError(t, os.WriteFile(path, []byte(sink), os.ModePerm))
+	m := manifest.New(path)
+	assert.NoError(t, m.Read())
+
+	assert.Nil(t, UnsupportedKind(m.Objects[0], test.CRD()))
+	component, err := GetObject("audit-log", &config.Config{}, m, test.CRD())
+	assert.NoError(t, err)
+	assert.IsType(t, &filesink.FileSink{}, component)
+	assert.Implements(t, (*triggermesh.Consumer)(nil), component)
+	assert.Equal(t, "/tmp/events/audit-log.jsonl", component.(*filesink.FileSink).CurrentFile())
+}
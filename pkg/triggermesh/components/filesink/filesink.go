@@ -0,0 +1,300 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filesink
+
+import (
+	"context"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strconv"
+	"time"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+var (
+	_ triggermesh.Component = (*FileSink)(nil)
+	_ triggermesh.Consumer  = (*FileSink)(nil)
+	_ triggermesh.Runnable  = (*FileSink)(nil)
+	_ triggermesh.Networked = (*FileSink)(nil)
+)
+
+const (
+	Kind       = "FileSink"
+	APIVersion = "tmctl.triggermesh.io/v1alpha1"
+
+	// FormatJSONL writes one structured mode event per line.
+	FormatJSONL = "jsonl"
+
+	// file sink is the plain Python container running the bundled script,
+	// the events directory of the host is mounted into it.
+	image     = "python:3.11-alpine"
+	mountPath = "/events"
+	port      = triggermesh.ContainerPort + "/tcp"
+)
+
+// Formats are the supported event file formats.
+var Formats = []string{FormatJSONL}
+
+//go:embed sink/sink.py
+var sinkScript string
+
+// FileSink is the local pseudo-target that appends the received events
+// to the rotating files in the host directory.
+type FileSink struct {
+	Name   string
+	Broker string
+
+	spec map[string]interface{}
+}
+
+// Validate checks the file sink spec.
+func Validate(spec map[string]interface{}) error {
+	if path, _ := spec["path"].(string); path == "" {
+		return fmt.Errorf("events directory path is required")
+	}
+	format, _ := spec["format"].(string)
+	supported := false
+	for _, f := range Formats {
+		if format == f {
+			supported = true
+			break
+		}
+	}
+	if !supported {
+		return fmt.Errorf("unsupported format %q, expected %q", format, FormatJSONL)
+	}
+	if _, err := maxFileSize(spec); err != nil {
+		return err
+	}
+	return nil
+}
+
+// maxFileSize returns the number of bytes the files are rotated at,
+// zero if the files are not rotated.
+func maxFileSize(spec map[string]interface{}) (int64, error) {
+	size, _ := spec["maxFileSize"].(string)
+	if size == "" {
+		return 0, nil
+	}
+	n, err := config.ParsePayloadSize(size)
+	if err != nil {
+		return 0, fmt.Errorf("max file size: %w", err)
+	}
+	return n, nil
+}
+
+// Path returns the host directory of the event files.
+func (f *FileSink) Path() string {
+	path, _ := f.spec["path"].(string)
+	return path
+}
+
+// CurrentFile returns the path of the file the events are appended to.
+func (f *FileSink) CurrentFile() string {
+	return filepath.Join(f.Path(), f.Name+"."+FormatJSONL)
+}
+
+// CurrentSize returns the size of the current file, zero if
+// the sink has not received any events since the last rotation.
+func (f *FileSink) CurrentSize() (int64, error) {
+	stat, err := os.Stat(f.CurrentFile())
+	if os.IsNotExist(err) {
+		return 0, nil
+	}
+	if err != nil {
+		return 0, err
+	}
+	return stat.Size(), nil
+}
+
+// FormatSize returns the human readable file size in the units
+// of the max file size parameter, e.g. "12.5MB".
+func FormatSize(size int64) string {
+	units := []string{"B", "KB", "MB", "GB"}
+	value := float64(size)
+	unit := 0
+	for value >= 1024 && unit < len(units)-1 {
+		value /= 1024
+		unit++
+	}
+	if unit == 0 {
+		return fmt.Sprintf("%d%s", size, units[unit])
+	}
+	return strconv.FormatFloat(value, 'f', 1, 64) + units[unit]
+}
+
+func (f *FileSink) AsK8sObject() (kubernetes.Object, error) {
+	return kubernetes.Object{
+		APIVersion: APIVersion,
+		Kind:       Kind,
+		Metadata: kubernetes.Metadata{
+			Name:      f.Name,
+			Namespace: triggermesh.Namespace,
+			Labels: map[string]string{
+				triggermesh.ContextLabel: f.Broker,
+			},
+		},
+		Spec: f.spec,
+	}, nil
+}
+
+func (f *FileSink) asContainer(additionalEnvs map[string]string) (*docker.Container, error) {
+	size, err := maxFileSize(f.spec)
+	if err != nil {
+		return nil, err
+	}
+	env := []string{
+		"SINK_NAME=" + f.Name,
+		"MAX_FILE_SIZE=" + strconv.FormatInt(size, 10),
+	}
+	for k, v := range additionalEnvs {
+		env = append(env, k+"="+v)
+	}
+	co := []docker.ContainerOption{
+		docker.WithImage(image),
+		docker.WithPort(port),
+		docker.WithEnv(env),
+		docker.WithCmd([]string{"python3", "-u", "-c", sinkScript}),
+	}
+	if runtime.GOOS == "linux" {
+		// the files in the bind mount are owned by the host user
+		co = append(co, docker.WithUser(fmt.Sprintf("%d:%d", os.Getuid(), os.Getgid())))
+	}
+	return &docker.Container{
+		Name:                   f.Name,
+		Image:                  image,
+		CreateContainerOptions: co,
+		CreateHostOptions: []docker.HostOption{
+			docker.WithHostPortBinding(port),
+			docker.WithExtraHost(),
+			docker.WithVolumeBind(f.Path() + ":" + mountPath),
+		},
+		Network: docker.NetworkName(f.Broker),
+		Aliases: f.NetworkAliases(),
+	}, nil
+}
+
+func (f *FileSink) NetworkAliases() []string {
+	return triggermesh.NetworkAliases(f.Name, f.Broker)
+}
+
+func (f *FileSink) GetName() string {
+	return f.Name
+}
+
+func (f *FileSink) GetKind() string {
+	return Kind
+}
+
+func (f *FileSink) GetAPIVersion() string {
+	return APIVersion
+}
+
+func (f *FileSink) GetSpec() map[string]interface{} {
+	return f.spec
+}
+
+func (f *FileSink) SetSpec(spec map[string]interface{}) {
+	f.spec = spec
+}
+
+func (f *FileSink) GetPort(ctx context.Context) (string, error) {
+	container, err := f.Info(ctx)
+	if err != nil {
+		return "", fmt.Errorf("container object: %w", err)
+	}
+	return container.HostPort(), nil
+}
+
+// ConsumedEventTypes returns empty list since the sink accepts any events.
+func (f *FileSink) ConsumedEventTypes() ([]string, error) {
+	return []string{}, nil
+}
+
+func (f *FileSink) Start(ctx context.Context, additionalEnvs map[string]string, restart bool) (*docker.Container, error) {
+	client, err := docker.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("docker client: %w", err)
+	}
+	if err := os.MkdirAll(f.Path(), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("events directory: %w", err)
+	}
+	container, err := f.asContainer(additionalEnvs)
+	if err != nil {
+		return nil, fmt.Errorf("container object: %w", err)
+	}
+	return container.Start(ctx, client, restart)
+}
+
+// Stop removes the sink container, the event files are kept.
+func (f *FileSink) Stop(ctx context.Context) error {
+	client, err := docker.NewClient()
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	container, err := f.asContainer(nil)
+	if err != nil {
+		return fmt.Errorf("container object: %w", err)
+	}
+	return container.Remove(ctx, client)
+}
+
+func (f *FileSink) Info(ctx context.Context) (*docker.Container, error) {
+	client, err := docker.ClientFrom(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("docker client: %w", err)
+	}
+	container, err := f.asContainer(nil)
+	if err != nil {
+		return nil, fmt.Errorf("container object: %w", err)
+	}
+	return container.LookupHostConfig(ctx, client)
+}
+
+func (f *FileSink) Logs(ctx context.Context, since time.Time, follow bool) (io.ReadCloser, error) {
+	client, err := docker.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("docker client: %w", err)
+	}
+	container, err := f.asContainer(nil)
+	if err != nil {
+		return nil, fmt.Errorf("container object: %w", err)
+	}
+	if _, err := container.LookupHostConfig(ctx, client); err != nil {
+		return nil, fmt.Errorf("container config: %w", err)
+	}
+	return container.Logs(ctx, client, since, follow)
+}
+
+func New(name, broker string, spec map[string]interface{}) triggermesh.Component {
+	if name == "" {
+		name = triggermesh.SanitizeName(fmt.Sprintf("%s-filesink", broker))
+	}
+	return &FileSink{
+		Name:   name,
+		Broker: broker,
+		spec:   spec,
+	}
+}
@@ -0,0 +1,124 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package filesink
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/docker/docker/api/types/container"
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+func TestValidate(t *testing.T) {
+	testCases := map[string]struct {
+		spec  map[string]interface{}
+		valid bool
+	}{
+		"valid": {
+			spec:  map[string]interface{}{"path": "/tmp/events", "format": "jsonl", "maxFileSize": "50MB"},
+			valid: true,
+		},
+		"no rotation": {
+			spec:  map[string]interface{}{"path": "/tmp/events", "format": "jsonl"},
+			valid: true,
+		},
+		"missing path": {
+			spec: map[string]interface{}{"format": "jsonl"},
+		},
+		"unknown format": {
+			spec: map[string]interface{}{"path": "/tmp/events", "format": "csv"},
+		},
+		"invalid size": {
+			spec: map[string]interface{}{"path": "/tmp/events", "format": "jsonl", "maxFileSize": "big"},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := Validate(tc.spec)
+			if tc.valid {
+				assert.NoError(t, err)
+				return
+			}
+			assert.Error(t, err)
+		})
+	}
+}
+
+func TestFormatSize(t *testing.T) {
+	assert.Equal(t, "0B", FormatSize(0))
+	assert.Equal(t, "512B", FormatSize(512))
+	assert.Equal(t, "1.5KB", FormatSize(1536))
+	assert.Equal(t, "50.0MB", FormatSize(50<<20))
+	assert.Equal(t, "2.0GB", FormatSize(2<<30))
+}
+
+func TestCurrentFile(t *testing.T) {
+	dir := t.TempDir()
+	sink := New("audit-log", "foo", map[string]interface{}{"path": dir, "format": FormatJSONL}).(*FileSink)
+	assert.Equal(t, filepath.Join(dir, "audit-log.jsonl"), sink.CurrentFile())
+
+	size, err := sink.CurrentSize()
+	assert.NoError(t, err)
+	assert.Zero(t, size)
+
+	assert.NoError(t, os.WriteFile(sink.CurrentFile(), []byte("{}\n"), 0o600))
+	size, err = sink.CurrentSize()
+	assert.NoError(t, err)
+	assert.EqualValues(t, 3, size)
+}
+
+func TestAsK8sObject(t *testing.T) {
+	spec := map[string]interface{}{"path": "/tmp/events", "format": FormatJSONL, "maxFileSize": "1MB"}
+	object, err := New("audit-log", "foo", spec).AsK8sObject()
+	assert.NoError(t, err)
+	assert.Equal(t, APIVersion, object.APIVersion)
+	assert.Equal(t, Kind, object.Kind)
+	assert.Equal(t, "audit-log", object.Metadata.Name)
+	assert.Equal(t, "foo", object.Metadata.Labels[triggermesh.ContextLabel])
+	assert.Equal(t, spec, object.Spec)
+
+	assert.Equal(t, "foo-filesink", New("", "foo", spec).GetName())
+}
+
+func TestAsContainer(t *testing.T) {
+	sink := New("audit-log", "foo", map[string]interface{}{
+		"path":        "/tmp/events",
+		"format":      FormatJSONL,
+		"maxFileSize": "1KB",
+	}).(*FileSink)
+	c, err := sink.asContainer(nil)
+	assert.NoError(t, err)
+	assert.Equal(t, "audit-log", c.Name)
+
+	var config container.Config
+	for _, option := range c.CreateContainerOptions {
+		option(&config)
+	}
+	assert.Contains(t, config.Env, "SINK_NAME=audit-log")
+	assert.Contains(t, config.Env, "MAX_FILE_SIZE=1024")
+	assert.Equal(t, image, config.Image)
+
+	var host container.HostConfig
+	for _, option := range c.CreateHostOptions {
+		option(&host)
+	}
+	assert.Contains(t, host.Binds, "/tmp/events:"+mountPath)
+}
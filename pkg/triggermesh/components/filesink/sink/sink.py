@@ -0,0 +1,121 @@
+# Copyright 2022 TriggerMesh Inc.
+#
+# Licensed under the Apache License, Version 2.0 (the "License");
+# you may not use this file except in compliance with the License.
+# You may obtain a copy of the License at
+#
+#     http://www.apache.org/licenses/LICENSE-2.0
+#
+# Unless required by applicable law or agreed to in writing, software
+# distributed under the License is distributed on an "AS IS" BASIS,
+# WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+# See the License for the specific language governing permissions and
+# limitations under the License.
+
+
+# Appends the received CloudEvents to the JSONL file of the sink, one
+# structured mode envelope per line. The file is rotated when the next
+# event would grow it past the MAX_FILE_SIZE bytes, the rotated files
+# get the UTC timestamp suffix. Only the Python standard library is used.
+
+import base64
+import json
+import os
+import sys
+import threading
+import time
+from http.server import BaseHTTPRequestHandler, ThreadingHTTPServer
+from urllib.parse import unquote
+
+DIRECTORY = os.environ.get("EVENTS_DIR", "/events")
+NAME = os.environ.get("SINK_NAME", "events")
+MAX_FILE_SIZE = int(os.environ.get("MAX_FILE_SIZE") or 0)
+STRUCTURED = "application/cloudevents+json"
+
+lock = threading.Lock()
+
+
+def is_json(content_type):
+    media_type = content_type.split(";")[0].strip().lower()
+    return media_type == "application/json" or media_type.endswith("+json") or media_type == "text/json"
+
+
+def is_text(content_type):
+    media_type = content_type.split(";")[0].strip().lower()
+    return media_type.startswith("text/") or media_type in ("application/xml", "application/yaml")
+
+
+def envelope(headers, body):
+    content_type = headers.get("Content-Type", "")
+    if content_type.startswith(STRUCTURED):
+        return json.loads(body)
+    event = {}
+    for key, value in headers.items():
+        if key.lower().startswith("ce-"):
+            event[key[3:].lower()] = unquote(value)
+    if "specversion" not in event:
+        raise ValueError("request is not a CloudEvent")
+    if content_type:
+        event["datacontenttype"] = content_type
+    if body:
+        if is_json(content_type):
+            event["data"] = json.loads(body)
+        elif is_text(content_type):
+            event["data"] = body.decode("utf-8")
+        else:
+            event["data_base64"] = base64.b64encode(body).decode("ascii")
+    return event
+
+
+def rotate(path):
+    stamp = time.strftime("%Y%m%dT%H%M%SZ", time.gmtime())
+    rotated = os.path.join(DIRECTORY, "%s-%s.jsonl" % (NAME, stamp))
+    suffix = 1
+    while os.path.exists(rotated):
+        rotated = os.path.join(DIRECTORY, "%s-%s-%d.jsonl" % (NAME, stamp, suffix))
+        suffix += 1
+    os.rename(path, rotated)
+
+
+def append(line):
+    path = os.path.join(DIRECTORY, NAME + ".jsonl")
+    with lock:
+        if MAX_FILE_SIZE and os.path.exists(path):
+            size = os.path.getsize(path)
+            if size > 0 and size + len(line) > MAX_FILE_SIZE:
+                rotate(path)
+        with open(path, "ab") as f:
+            f.write(line)
+
+
+class Handler(BaseHTTPRequestHandler):
+    protocol_version = "HTTP/1.1"
+
+    def do_POST(self):
+        body = self.rfile.read(int(self.headers.get("Content-Length") or 0))
+        try:
+            event = envelope(self.headers, body)
+        except ValueError as err:
+            self.reply(400, str(err).encode("utf-8"))
+            return
+        try:
+            append(json.dumps(event, separators=(",", ":")).encode("utf-8") + b"\n")
+        except OSError as err:
+            self.reply(500, str(err).encode("utf-8"))
+            return
+        self.reply(202, b"")
+
+    def do_GET(self):
+        self.reply(200, b"ok")
+
+    def reply(self, status, body):
+        self.send_response(status)
+        self.send_header("Content-Length", str(len(body)))
+        self.end_headers()
+        self.wfile.write(body)
+
+    def log_message(self, format, *args):
+        sys.stdout.write("%s\n" % (format % args))
+
+
+ThreadingHTTPServer(("", 8080), Handler).serve_forever()
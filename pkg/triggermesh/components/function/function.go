@@ -0,0 +1,265 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package function
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/adapter"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/pkg"
+)
+
+var (
+	_ triggermesh.Component = (*Function)(nil)
+	_ triggermesh.Consumer  = (*Function)(nil)
+	_ triggermesh.Runnable  = (*Function)(nil)
+)
+
+const (
+	Kind       = "Function"
+	APIVersion = "extensions.triggermesh.io/v1alpha1"
+
+	// runtime entrypoint and source code location expected by the
+	// TriggerMesh function runtime images.
+	runtimeEntrypoint = "/opt/aws-custom-runtime"
+	sourceCodePath    = "/opt/source"
+)
+
+type Function struct {
+	Name string
+
+	CRD crd.CRD
+
+	Broker  string
+	Version string
+
+	spec map[string]interface{}
+}
+
+func (f *Function) asUnstructured() (unstructured.Unstructured, error) {
+	return kubernetes.CreateUnstructured(f.CRD, f.getMeta(), f.spec, nil)
+}
+
+func (f *Function) AsK8sObject() (kubernetes.Object, error) {
+	return kubernetes.CreateObject(f.CRD, f.getMeta(), f.spec)
+}
+
+func (f *Function) getMeta() kubernetes.Metadata {
+	return kubernetes.Metadata{
+		Name:      f.GetName(),
+		Namespace: triggermesh.Namespace,
+		Labels: map[string]string{
+			triggermesh.ContextLabel: f.Broker,
+		},
+	}
+}
+
+func (f *Function) asContainer(additionalEnvs map[string]string) (*docker.Container, error) {
+	o, err := f.asUnstructured()
+	if err != nil {
+		return nil, fmt.Errorf("creating object: %w", err)
+	}
+	image := adapter.Image(o, f.Version)
+	co, ho, err := adapter.RuntimeParams(o, image, additionalEnvs)
+	if err != nil {
+		return nil, fmt.Errorf("creating adapter params: %w", err)
+	}
+	co = append(co, docker.WithEntrypoint([]string{runtimeEntrypoint}))
+
+	bind := fmt.Sprintf("%s:%s.%s", f.codeFile(), sourceCodePath, FileExtension(f.runtime()))
+	ho = append(ho, docker.WithVolumeBind(bind))
+
+	return &docker.Container{
+		Name:                   f.GetName(),
+		Image:                  image,
+		CreateHostOptions:      ho,
+		CreateContainerOptions: co,
+		Network:                docker.NetworkName(f.Broker),
+		Aliases:                f.NetworkAliases(),
+	}, nil
+}
+
+// codeFile returns the path of the local copy of the function code
+// that is mounted into the runtime container.
+func (f *Function) codeFile() string {
+	return filepath.Join(config.HomeAbsPath(), f.Broker, "functions", f.Name+"."+FileExtension(f.runtime()))
+}
+
+func (f *Function) writeCode() error {
+	code, _ := f.spec["code"].(string)
+	path := f.codeFile()
+	if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+		return err
+	}
+	return os.WriteFile(path, []byte(code), os.ModePerm)
+}
+
+func (f *Function) runtime() string {
+	runtime, _ := f.spec["runtime"].(string)
+	return runtime
+}
+
+func (f *Function) NetworkAliases() []string {
+	return triggermesh.NetworkAliases(f.Name, f.Broker)
+}
+
+func (f *Function) GetName() string {
+	return f.Name
+}
+
+func (f *Function) GetKind() string {
+	return Kind
+}
+
+func (f *Function) GetVersion() string {
+	return f.Version
+}
+
+func (f *Function) GetAPIVersion() string {
+	return APIVersion
+}
+
+func (f *Function) GetSpec() map[string]interface{} {
+	return f.spec
+}
+
+func (f *Function) SetSpec(spec map[string]interface{}) {
+	f.spec = spec
+}
+
+func (f *Function) GetPort(ctx context.Context) (string, error) {
+	container, err := f.Info(ctx)
+	if err != nil {
+		return "", fmt.Errorf("container object: %w", err)
+	}
+	return container.HostPort(), nil
+}
+
+// ConsumedEventTypes returns empty list since functions accept any events.
+func (f *Function) ConsumedEventTypes() ([]string, error) {
+	return []string{}, nil
+}
+
+func (f *Function) Start(ctx context.Context, additionalEnvs map[string]string, restart bool) (*docker.Container, error) {
+	client, err := docker.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("docker client: %w", err)
+	}
+	if err := f.writeCode(); err != nil {
+		return nil, fmt.Errorf("writing function code: %w", err)
+	}
+	container, err := f.asContainer(additionalEnvs)
+	if err != nil {
+		return nil, fmt.Errorf("container object: %w", err)
+	}
+	return container.Start(ctx, client, restart)
+}
+
+func (f *Function) Stop(ctx context.Context) error {
+	client, err := docker.NewClient()
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	container, err := f.asContainer(nil)
+	if err != nil {
+		return fmt.Errorf("container object: %w", err)
+	}
+	return container.Remove(ctx, client)
+}
+
+func (f *Function) Info(ctx context.Context) (*docker.Container, error) {
+	client, err := docker.ClientFrom(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("docker client: %w", err)
+	}
+	container, err := f.asContainer(nil)
+	if err != nil {
+		return nil, fmt.Errorf("container object: %w", err)
+	}
+	return container.LookupHostConfig(ctx, client)
+}
+
+func (f *Function) Logs(ctx context.Context, since time.Time, follow bool) (io.ReadCloser, error) {
+	client, err := docker.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("docker client: %w", err)
+	}
+	container, err := f.asContainer(nil)
+	if err != nil {
+		return nil, fmt.Errorf("container object: %w", err)
+	}
+	if _, err := container.LookupHostConfig(ctx, client); err != nil {
+		return nil, fmt.Errorf("container config: %w", err)
+	}
+	return container.Logs(ctx, client, since, follow)
+}
+
+// FileExtension returns the source code file extension
+// expected by the function runtime.
+func FileExtension(runtime string) string {
+	runtime = strings.ToLower(runtime)
+	switch {
+	case strings.Contains(runtime, "python"):
+		return "py"
+	case strings.Contains(runtime, "node") ||
+		strings.Contains(runtime, "js"):
+		return "js"
+	case strings.Contains(runtime, "ruby"):
+		return "rb"
+	case strings.Contains(runtime, "sh"):
+		return "sh"
+	}
+	return "txt"
+}
+
+func New(name, broker, version string, crd crd.CRD, params interface{}) triggermesh.Component {
+	var spec map[string]interface{}
+	switch p := params.(type) {
+	case map[string]string:
+		// cli args
+		spec = pkg.ParseArgs(p)
+	case map[string]interface{}:
+		// spec map
+		spec = p
+	default:
+	}
+
+	if name == "" {
+		name = triggermesh.SanitizeName(fmt.Sprintf("%s-function", broker))
+	}
+
+	return &Function{
+		Name:    name,
+		CRD:     crd,
+		Broker:  broker,
+		Version: version,
+		spec:    spec,
+	}
+}
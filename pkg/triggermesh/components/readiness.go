@@ -0,0 +1,65 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"context"
+	"fmt"
+	"io"
+	"strings"
+	"time"
+
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/health"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+// Readiness returns the readiness probe declared in the CRD of the component
+// kind. Kinds without the annotation are probed on their published port.
+func Readiness(c triggermesh.Component, crds map[string]crd.CRD) (health.Readiness, error) {
+	definition, exists := crds[strings.ToLower(c.GetKind())]
+	if !exists {
+		return health.DefaultReadiness, nil
+	}
+	r, err := health.ParseReadiness(definition.Readiness())
+	if err != nil {
+		return health.Readiness{}, fmt.Errorf("%s CRD: %w", c.GetKind(), err)
+	}
+	return r, nil
+}
+
+// HasReadiness returns true if the component kind declares its readiness probe.
+func HasReadiness(c triggermesh.Component, crds map[string]crd.CRD) bool {
+	definition, exists := crds[strings.ToLower(c.GetKind())]
+	return exists && definition.Readiness() != ""
+}
+
+// ReadinessTarget returns the readiness probe target of the component container.
+func ReadinessTarget(c triggermesh.Runnable, container *docker.Container) health.Target {
+	target := health.Target{
+		Logs: func(ctx context.Context, follow bool) (io.ReadCloser, error) {
+			// the whole log since the container start
+			return c.Logs(ctx, time.Unix(0, 0), follow)
+		},
+	}
+	if port := container.HostPort(); port != "" {
+		target.Address = urls.New("").Address(urls.Host, port)
+	}
+	return target
+}
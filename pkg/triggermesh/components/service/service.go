@@ -20,6 +20,7 @@ import (
 	"context"
 	"fmt"
 	"io"
+	"sort"
 	"strconv"
 	"strings"
 	"time"
@@ -28,6 +29,7 @@ import (
 	"k8s.io/apimachinery/pkg/apis/meta/v1/unstructured"
 
 	"github.com/digitalocean/godo"
+	"github.com/docker/go-connections/nat"
 
 	"github.com/triggermesh/tmctl/pkg/docker"
 	"github.com/triggermesh/tmctl/pkg/kubernetes"
@@ -41,6 +43,11 @@ const (
 	Kind       = "Service"
 
 	RoleLabel = "triggermesh.io/role"
+
+	// adopted containers are not managed by tmctl,
+	// only their addresses are used for event delivery.
+	AdoptedContainerAnnotation = "triggermesh.io/adopted-container"
+	AdoptedPortAnnotation      = "triggermesh.io/adopted-port"
 )
 
 var (
@@ -63,6 +70,9 @@ type Service struct {
 
 	role   Role
 	params map[string]string
+
+	adoptedContainer string
+	adoptedPort      string
 }
 
 func (s *Service) asUnstructured() (unstructured.Unstructured, error) {
@@ -82,6 +92,13 @@ func (s *Service) AsK8sObject() (kubernetes.Object, error) {
 	if s.IsSource() {
 		manifestParams["K_SINK"] = fmt.Sprintf("http://%s-rb-broker:8080", s.Broker)
 	}
+	var annotations map[string]string
+	if s.IsAdopted() {
+		annotations = map[string]string{
+			AdoptedContainerAnnotation: s.adoptedContainer,
+			AdoptedPortAnnotation:      s.adoptedPort,
+		}
+	}
 	return kubernetes.Object{
 		APIVersion: APIVersion,
 		Kind:       Kind,
@@ -92,6 +109,7 @@ func (s *Service) AsK8sObject() (kubernetes.Object, error) {
 				triggermesh.ContextLabel: s.Broker,
 				RoleLabel:                string(s.role),
 			},
+			Annotations: annotations,
 		},
 		Spec: kserviceSpec(s.Image, manifestParams),
 	}, nil
@@ -103,11 +121,15 @@ func (s *Service) AsDockerComposeObject(additionalEnvs map[string]string) (inter
 		envs = append(envs, corev1.EnvVar{Name: k, Value: v})
 	}
 
+	port, err := pkg.OpenPort()
+	if err != nil {
+		return nil, err
+	}
 	return &docker.ComposeService{
 		ContainerName: s.Name,
 		Image:         s.Image,
 		Environment:   pkg.EnvsToString(envs),
-		Ports:         []string{strconv.Itoa(pkg.OpenPort()) + ":8080"},
+		Ports:         []string{strconv.Itoa(port) + ":8080"},
 	}, nil
 }
 
@@ -167,11 +189,18 @@ func (s *Service) asContainer(additionalEnvs map[string]string) (*docker.Contain
 	if err != nil {
 		return nil, fmt.Errorf("creating adapter params: %w", err)
 	}
+	name := s.Name
+	if s.IsAdopted() {
+		name = s.adoptedContainer
+	}
 	return &docker.Container{
-		Name:                   s.Name,
+		Name:                   name,
 		Image:                  s.Image,
 		CreateHostOptions:      ho,
 		CreateContainerOptions: co,
+		Network:                docker.NetworkName(s.Broker),
+		Aliases:                s.NetworkAliases(),
+		Adopted:                s.IsAdopted(),
 	}, nil
 }
 
@@ -179,6 +208,15 @@ func (s *Service) GetKind() string {
 	return Kind
 }
 
+// NetworkAliases returns the names of the service in the context network.
+// Adopted containers are not created by tmctl and are reached by their ports.
+func (s *Service) NetworkAliases() []string {
+	if s.IsAdopted() {
+		return nil
+	}
+	return triggermesh.NetworkAliases(s.Name, s.Broker)
+}
+
 func (s *Service) GetName() string {
 	return s.Name
 }
@@ -209,11 +247,29 @@ func (s *Service) IsTarget() bool {
 	return s.role == Consumer
 }
 
+// IsAdopted returns true if the service is an existing container
+// that is not managed by tmctl.
+func (s *Service) IsAdopted() bool {
+	return s.adoptedContainer != ""
+}
+
+// AdoptedContainer returns the name of the adopted container.
+func (s *Service) AdoptedContainer() string {
+	return s.adoptedContainer
+}
+
 func (s *Service) GetPort(ctx context.Context) (string, error) {
 	container, err := s.Info(ctx)
 	if err != nil {
 		return "", fmt.Errorf("container object: %w", err)
 	}
+	if s.IsAdopted() {
+		port := container.PublishedPort(nat.Port(s.adoptedPort + "/tcp"))
+		if port == "" {
+			return "", fmt.Errorf("port %s of container %q is not published", s.adoptedPort, s.adoptedContainer)
+		}
+		return port, nil
+	}
 	return container.HostPort(), nil
 }
 
@@ -248,10 +304,17 @@ func (s *Service) Start(ctx context.Context, additionalEnvs map[string]string, r
 	if err != nil {
 		return nil, fmt.Errorf("container object: %w", err)
 	}
+	if s.IsAdopted() {
+		// adopted containers lifecycle is managed outside of tmctl
+		return container.LookupHostConfig(ctx, client)
+	}
 	return container.Start(ctx, client, restart)
 }
 
 func (s *Service) Stop(ctx context.Context) error {
+	if s.IsAdopted() {
+		return nil
+	}
 	client, err := docker.NewClient()
 	if err != nil {
 		return fmt.Errorf("docker client: %w", err)
@@ -264,7 +327,7 @@ func (s *Service) Stop(ctx context.Context) error {
 }
 
 func (s *Service) Info(ctx context.Context) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	client, err := docker.ClientFrom(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("docker client: %w", err)
 	}
@@ -292,7 +355,7 @@ func (s *Service) Logs(ctx context.Context, since time.Time, follow bool) (io.Re
 
 func New(name, image, broker string, role Role, params map[string]string) triggermesh.Component {
 	if name == "" {
-		name = fmt.Sprintf("%s-%s-service", broker, role)
+		name = triggermesh.SanitizeName(fmt.Sprintf("%s-%s-service", broker, role))
 	}
 	return &Service{
 		Name:   name,
@@ -303,12 +366,32 @@ func New(name, image, broker string, role Role, params map[string]string) trigge
 	}
 }
 
+// NewAdopted creates the consumer service object for the existing container.
+func NewAdopted(name, container, image, port, broker string) triggermesh.Component {
+	return &Service{
+		Name:             name,
+		Broker:           broker,
+		Image:            image,
+		params:           map[string]string{},
+		role:             Consumer,
+		adoptedContainer: container,
+		adoptedPort:      port,
+	}
+}
+
+// paramsToEnv returns the container env sorted by the variable names,
+// so that the same parameters always produce the same object.
 func paramsToEnv(params map[string]string) []interface{} {
+	keys := make([]string, 0, len(params))
+	for k := range params {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
 	env := make([]interface{}, 0, len(params))
-	for k, v := range params {
+	for _, k := range keys {
 		env = append(env, map[string]interface{}{
 			"name":  strings.ToUpper(k),
-			"value": v,
+			"value": params[k],
 		})
 	}
 	return env
@@ -234,9 +234,15 @@ func (s *Source) asContainer(additionalEnvs map[string]string) (*docker.Containe
 		Image:                  image,
 		CreateHostOptions:      ho,
 		CreateContainerOptions: co,
+		Network:                docker.NetworkName(s.Broker),
+		Aliases:                s.NetworkAliases(),
 	}, nil
 }
 
+func (s *Source) NetworkAliases() []string {
+	return triggermesh.NetworkAliases(s.Name, s.Broker)
+}
+
 func (s *Source) GetName() string {
 	return s.Name
 }
@@ -245,6 +251,10 @@ func (s *Source) GetKind() string {
 	return s.Kind
 }
 
+func (s *Source) GetVersion() string {
+	return s.Version
+}
+
 func (s *Source) GetAPIVersion() string {
 	o, err := s.AsK8sObject()
 	if err != nil {
@@ -345,7 +355,7 @@ func (s *Source) Stop(ctx context.Context) error {
 }
 
 func (s *Source) Info(ctx context.Context) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	client, err := docker.ClientFrom(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("docker client: %w", err)
 	}
@@ -413,7 +423,7 @@ func New(name, kind, broker, version string, crd crd.CRD, params interface{}, st
 	}
 
 	if name == "" {
-		name = fmt.Sprintf("%s-%s", broker, k)
+		name = triggermesh.SanitizeName(fmt.Sprintf("%s-%s", broker, k))
 	}
 
 	return &Source{
@@ -107,11 +107,15 @@ func (t *Target) AsDockerComposeObject(additionalEnvs map[string]string) (interf
 		envs = append(envs, corev1.EnvVar{Name: k, Value: v})
 	}
 
+	port, err := pkg.OpenPort()
+	if err != nil {
+		return nil, err
+	}
 	return &docker.ComposeService{
 		ContainerName: t.Name,
 		Image:         image,
 		Environment:   pkg.EnvsToString(envs),
-		Ports:         []string{strconv.Itoa(pkg.OpenPort()) + ":8080"},
+		Ports:         []string{strconv.Itoa(port) + ":8080"},
 	}, nil
 }
 
@@ -206,9 +210,15 @@ func (t *Target) asContainer(additionalEnvs map[string]string) (*docker.Containe
 		Image:                  image,
 		CreateHostOptions:      ho,
 		CreateContainerOptions: co,
+		Network:                docker.NetworkName(t.Broker),
+		Aliases:                t.NetworkAliases(),
 	}, nil
 }
 
+func (t *Target) NetworkAliases() []string {
+	return triggermesh.NetworkAliases(t.Name, t.Broker)
+}
+
 func (t *Target) GetName() string {
 	return t.Name
 }
@@ -217,6 +227,10 @@ func (t *Target) GetKind() string {
 	return t.Kind
 }
 
+func (t *Target) GetVersion() string {
+	return t.Version
+}
+
 func (t *Target) GetAPIVersion() string {
 	o, err := t.AsK8sObject()
 	if err != nil {
@@ -264,6 +278,19 @@ func (t *Target) ConsumedEventTypes() ([]string, error) {
 	return eventAttributes.AcceptedEventTypes, nil
 }
 
+// ProducedEventTypes returns the types of the events the target replies with.
+func (t *Target) ProducedEventTypes() ([]string, error) {
+	o, err := t.asUnstructured()
+	if err != nil {
+		return t.CRD.ProducedEventTypes(), nil
+	}
+	eventAttributes, err := adapter.EventAttributes(o)
+	if err != nil || len(eventAttributes.ProducedEventTypes) == 0 {
+		return t.CRD.ProducedEventTypes(), nil
+	}
+	return eventAttributes.ProducedEventTypes, nil
+}
+
 func (t *Target) tryCRDEventTypes() ([]string, error) {
 	var et crd.EventTypes
 	if err := json.Unmarshal([]byte(t.CRD.Metadata.Annotations.ConsumedEventTypes), &et); err != nil {
@@ -301,7 +328,7 @@ func (t *Target) Stop(ctx context.Context) error {
 }
 
 func (t *Target) Info(ctx context.Context) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	client, err := docker.ClientFrom(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("docker client: %w", err)
 	}
@@ -346,7 +373,7 @@ func New(name, kind, broker, version string, crd crd.CRD, params interface{}) tr
 	}
 
 	if name == "" {
-		name = fmt.Sprintf("%s-%s", broker, k)
+		name = triggermesh.SanitizeName(fmt.Sprintf("%s-%s", broker, k))
 	}
 
 	return &Target{
@@ -0,0 +1,259 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transformation
+
+import (
+	"fmt"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// Severity is the level of the linter finding.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// Finding is the single linter report entry.
+type Finding struct {
+	Severity Severity
+	// Section is the transformation spec section, "context" or "data".
+	Section string
+	// Operation is the index of the operation inside the section.
+	Operation int
+	Message   string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s[%d]: %s", f.Severity, f.Section, f.Operation, f.Message)
+}
+
+type operation struct {
+	section string
+	index   int
+	name    string
+	paths   []map[string]interface{}
+}
+
+// Lint checks Bumblebee transformation spec for the semantic problems
+// that are not caught by the schema validation.
+func Lint(spec map[string]interface{}) []Finding {
+	var findings []Finding
+	var operations []operation
+	for _, section := range []string{"context", "data"} {
+		ops, ok := spec[section].([]interface{})
+		if !ok {
+			continue
+		}
+		for i, op := range ops {
+			o, ok := op.(map[string]interface{})
+			if !ok {
+				continue
+			}
+			name, _ := o["operation"].(string)
+			var paths []map[string]interface{}
+			if pp, ok := o["paths"].([]interface{}); ok {
+				for _, p := range pp {
+					if path, ok := p.(map[string]interface{}); ok {
+						paths = append(paths, path)
+					}
+				}
+			}
+			operations = append(operations, operation{
+				section: section,
+				index:   i,
+				name:    name,
+				paths:   paths,
+			})
+		}
+	}
+
+	findings = append(findings, duplicateKeys(operations)...)
+	findings = append(findings, unusedVariables(operations)...)
+	findings = append(findings, deletedAfterAdd(operations)...)
+	findings = append(findings, parseDeleted(operations)...)
+
+	sort.SliceStable(findings, func(i, j int) bool {
+		if findings[i].Section != findings[j].Section {
+			return findings[i].Section == "context"
+		}
+		return findings[i].Operation < findings[j].Operation
+	})
+	return findings
+}
+
+func duplicateKeys(operations []operation) []Finding {
+	var findings []Finding
+	for _, op := range operations {
+		keys := make(map[string]struct{}, len(op.paths))
+		for _, path := range op.paths {
+			key := pathKey(path)
+			if _, exists := keys[key]; exists {
+				findings = append(findings, Finding{
+					Severity:  SeverityWarning,
+					Section:   op.section,
+					Operation: op.index,
+					Message:   fmt.Sprintf("duplicate key %q in %q operation", key, op.name),
+				})
+			}
+			keys[key] = struct{}{}
+		}
+	}
+	return findings
+}
+
+func unusedVariables(operations []operation) []Finding {
+	var findings []Finding
+	for _, op := range operations {
+		if op.name != "store" {
+			continue
+		}
+		for _, path := range op.paths {
+			variable := pathKey(path)
+			if !strings.HasPrefix(variable, "$") {
+				continue
+			}
+			if variableUsed(variable, operations) {
+				continue
+			}
+			findings = append(findings, Finding{
+				Severity:  SeverityWarning,
+				Section:   op.section,
+				Operation: op.index,
+				Message:   fmt.Sprintf("variable %q is stored but never used", variable),
+			})
+		}
+	}
+	return findings
+}
+
+// variableUsed returns true if the variable is referenced by the paths
+// of the operations as the whole word, so that "$foo" is not counted
+// as used by "$foobar" while "$person-$id" uses both variables.
+func variableUsed(variable string, operations []operation) bool {
+	reference := regexp.MustCompile(regexp.QuoteMeta(variable) + `(\W|$)`)
+	for _, op := range operations {
+		if op.name == "store" {
+			continue
+		}
+		for _, path := range op.paths {
+			if value, ok := path["value"].(string); ok && reference.MatchString(value) {
+				return true
+			}
+			if reference.MatchString(pathKey(path)) {
+				return true
+			}
+		}
+	}
+	return false
+}
+
+func deletedAfterAdd(operations []operation) []Finding {
+	var findings []Finding
+	for i, op := range operations {
+		if op.name != "add" {
+			continue
+		}
+		for _, path := range op.paths {
+			key := pathKey(path)
+			for _, next := range operations[i+1:] {
+				if next.name != "delete" || next.section != op.section {
+					continue
+				}
+				if deleted, ok := deletes(next, key); ok {
+					findings = append(findings, Finding{
+						Severity:  SeverityWarning,
+						Section:   op.section,
+						Operation: op.index,
+						Message: fmt.Sprintf("key %q is removed by the later delete operation %s[%d] on %q",
+							key, next.section, next.index, deleted),
+					})
+					break
+				}
+			}
+		}
+	}
+	return findings
+}
+
+func parseDeleted(operations []operation) []Finding {
+	var findings []Finding
+	for i, op := range operations {
+		if op.name != "parse" {
+			continue
+		}
+		for _, path := range op.paths {
+			key := pathKey(path)
+			for j := i - 1; j >= 0; j-- {
+				prev := operations[j]
+				if prev.section != op.section {
+					continue
+				}
+				if prev.name == "add" && addsKey(prev, key) {
+					break
+				}
+				if prev.name != "delete" {
+					continue
+				}
+				if deleted, ok := deletes(prev, key); ok {
+					findings = append(findings, Finding{
+						Severity:  SeverityError,
+						Section:   op.section,
+						Operation: op.index,
+						Message: fmt.Sprintf("key %q is parsed after it was removed by delete operation %s[%d] on %q",
+							key, prev.section, prev.index, deleted),
+					})
+					break
+				}
+			}
+		}
+	}
+	return findings
+}
+
+// deletes checks if delete operation removes the key and
+// returns the deleted path.
+func deletes(op operation, key string) (string, bool) {
+	for _, path := range op.paths {
+		deleted := pathKey(path)
+		if value, _ := path["value"].(string); value != "" {
+			// value-based deletion removes only matching values.
+			continue
+		}
+		if deleted == "" || deleted == key || strings.HasPrefix(key, deleted+".") {
+			return deleted, true
+		}
+	}
+	return "", false
+}
+
+func addsKey(op operation, key string) bool {
+	for _, path := range op.paths {
+		if pathKey(path) == key {
+			return true
+		}
+	}
+	return false
+}
+
+func pathKey(path map[string]interface{}) string {
+	key, _ := path["key"].(string)
+	return key
+}
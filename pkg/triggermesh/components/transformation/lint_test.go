@@ -0,0 +1,177 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package transformation
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"gopkg.in/yaml.v3"
+)
+
+func TestLint(t *testing.T) {
+	testCases := map[string]struct {
+		spec     string
+		findings []Finding
+	}{
+		"bumblebee sample": {
+			// https://github.com/triggermesh/triggermesh/blob/main/config/samples/bumblebee/complex-transformation/
+			spec: `
+context:
+- operation: store
+  paths:
+  - key: $id
+    value: id
+  - key: $type
+    value: type
+- operation: add
+  paths:
+  - key: id
+    value: $person-$id
+  - key: type
+    value: io.triggermesh.transformation.pingsource
+data:
+- operation: store
+  paths:
+  - key: $person
+    value: First Name
+- operation: add
+  paths:
+  - key: event.ID
+    value: $id
+  - key: event.type
+    value: $type
+  - key: event.person
+    value: $person
+- operation: delete
+  paths:
+  - key: First Name`,
+		},
+		"unused variable": {
+			spec: `
+data:
+- operation: store
+  paths:
+  - key: $foo
+    value: foo
+- operation: add
+  paths:
+  - key: bar
+    value: baz`,
+			findings: []Finding{
+				{Severity: SeverityWarning, Section: "data", Operation: 0, Message: `variable "$foo" is stored but never used`},
+			},
+		},
+		"variable name prefix": {
+			spec: `
+data:
+- operation: store
+  paths:
+  - key: $foo
+    value: foo
+  - key: $foobar
+    value: foobar
+  - key: $id
+    value: id
+- operation: add
+  paths:
+  - key: bar
+    value: $foobar
+  - key: baz
+    value: ($id)-suffix`,
+			findings: []Finding{
+				{Severity: SeverityWarning, Section: "data", Operation: 0, Message: `variable "$foo" is stored but never used`},
+			},
+		},
+		"add then delete": {
+			spec: `
+data:
+- operation: add
+  paths:
+  - key: foo.bar
+    value: baz
+- operation: delete
+  paths:
+  - key: foo`,
+			findings: []Finding{
+				{Severity: SeverityWarning, Section: "data", Operation: 0, Message: `key "foo.bar" is removed by the later delete operation data[1] on "foo"`},
+			},
+		},
+		"parse deleted path": {
+			spec: `
+data:
+- operation: delete
+  paths:
+  - key: payload
+- operation: parse
+  paths:
+  - key: payload
+    value: application/json`,
+			findings: []Finding{
+				{Severity: SeverityError, Section: "data", Operation: 1, Message: `key "payload" is parsed after it was removed by delete operation data[0] on "payload"`},
+			},
+		},
+		"parse re-added path": {
+			spec: `
+data:
+- operation: delete
+  paths:
+  - key: payload
+- operation: add
+  paths:
+  - key: payload
+    value: '{"foo":"bar"}'
+- operation: parse
+  paths:
+  - key: payload
+    value: application/json`,
+		},
+		"duplicate keys": {
+			spec: `
+context:
+- operation: add
+  paths:
+  - key: type
+    value: foo
+  - key: type
+    value: bar`,
+			findings: []Finding{
+				{Severity: SeverityWarning, Section: "context", Operation: 0, Message: `duplicate key "type" in "add" operation`},
+			},
+		},
+		"delete by value": {
+			spec: `
+data:
+- operation: add
+  paths:
+  - key: foo
+    value: bar
+- operation: delete
+  paths:
+  - key: foo
+    value: baz`,
+		},
+	}
+
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			var spec map[string]interface{}
+			assert.NoError(t, yaml.Unmarshal([]byte(tc.spec), &spec))
+			assert.Equal(t, tc.findings, Lint(spec))
+		})
+	}
+}
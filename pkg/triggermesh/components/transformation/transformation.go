@@ -41,6 +41,12 @@ import (
 
 const TransformationContextLabel = "triggermesh.io/transformation-context"
 
+// Transformation engines.
+const (
+	EngineBumblebee = "bumblebee"
+	EngineJQ        = "jq"
+)
+
 var (
 	_ triggermesh.Component  = (*Transformation)(nil)
 	_ triggermesh.Consumer   = (*Transformation)(nil)
@@ -54,6 +60,7 @@ type Transformation struct {
 	CRD     crd.CRD
 	Broker  string
 	Version string
+	Kind    string
 
 	spec   map[string]interface{}
 	labels map[string]string
@@ -103,11 +110,15 @@ func (t *Transformation) AsDockerComposeObject(additionalEnvs map[string]string)
 		envs = append(envs, corev1.EnvVar{Name: k, Value: v})
 	}
 
+	port, err := pkg.OpenPort()
+	if err != nil {
+		return nil, err
+	}
 	return &docker.ComposeService{
 		ContainerName: t.Name,
 		Image:         image,
 		Environment:   pkg.EnvsToString(envs),
-		Ports:         []string{strconv.Itoa(pkg.OpenPort()) + ":8080"},
+		Ports:         []string{strconv.Itoa(port) + ":8080"},
 	}, nil
 }
 
@@ -202,15 +213,40 @@ func (t *Transformation) asContainer(additionalEnvs map[string]string) (*docker.
 		Image:                  image,
 		CreateHostOptions:      ho,
 		CreateContainerOptions: co,
+		Network:                docker.NetworkName(t.Broker),
+		Aliases:                t.NetworkAliases(),
 	}, nil
 }
 
+func (t *Transformation) NetworkAliases() []string {
+	return triggermesh.NetworkAliases(t.Name, t.Broker)
+}
+
 func (t *Transformation) GetName() string {
 	return t.Name
 }
 
 func (t *Transformation) GetKind() string {
-	return "transformation"
+	return t.Kind
+}
+
+// OutputStage returns the name of the Bumblebee transformation that sets
+// the output event type of the JQ transformation. The JQ engine changes
+// the event payload only, the attributes of its output are unchanged.
+func OutputStage(name string) string {
+	return name + "-output-type"
+}
+
+// Engine returns the name of the transformation engine.
+func (t *Transformation) Engine() string {
+	if t.Kind == "jqtransformation" {
+		return EngineJQ
+	}
+	return EngineBumblebee
+}
+
+func (t *Transformation) GetVersion() string {
+	return t.Version
 }
 
 func (t *Transformation) GetAPIVersion() string {
@@ -249,6 +285,9 @@ func (t *Transformation) ConsumedEventTypes() ([]string, error) {
 
 // SetEventType sets events context attributes.
 func (t *Transformation) SetEventAttributes(attributes map[string]string) error {
+	if t.Engine() != EngineBumblebee {
+		return fmt.Errorf("%s transformation engine does not support event attributes override", t.Engine())
+	}
 	var paths []interface{}
 	for key, value := range attributes {
 		paths = append(paths, map[string]interface{}{
@@ -303,7 +342,7 @@ func (t *Transformation) Stop(ctx context.Context) error {
 }
 
 func (t *Transformation) Info(ctx context.Context) (*docker.Container, error) {
-	client, err := docker.NewClient()
+	client, err := docker.ClientFrom(ctx)
 	if err != nil {
 		return nil, fmt.Errorf("docker client: %w", err)
 	}
@@ -330,14 +369,20 @@ func (t *Transformation) Logs(ctx context.Context, since time.Time, follow bool)
 }
 
 func New(name, kind, broker, version string, crd crd.CRD, spec map[string]interface{}) triggermesh.Component {
+	// kind can be "transformation", "jqtransformation", etc.
+	k := strings.ToLower(kind)
+	if k == "" {
+		k = "transformation"
+	}
 	if name == "" {
-		name = fmt.Sprintf("%s-transformation", broker)
+		name = triggermesh.SanitizeName(fmt.Sprintf("%s-%s", broker, k))
 	}
 	return &Transformation{
 		Name:    name,
 		CRD:     crd,
 		Broker:  broker,
 		Version: version,
+		Kind:    k,
 
 		spec: spec,
 		labels: map[string]string{
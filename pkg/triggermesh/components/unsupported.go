@@ -0,0 +1,95 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"fmt"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/filesink"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/function"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+const triggermeshGroupSuffix = ".triggermesh.io"
+
+// UnsupportedKindError is returned for the manifest objects of the TriggerMesh
+// kinds that are unknown to the CRD set of the context, e.g. the components
+// added in the newer releases or the manifests edited by hand.
+type UnsupportedKindError struct {
+	Name  string
+	Group string
+	Kind  string
+}
+
+func (e *UnsupportedKindError) Error() string {
+	return fmt.Sprintf("%s %q is %s", e.Kind, e.Name, e.Status())
+}
+
+// Status returns the status of the object printed in the components lists.
+func (e *UnsupportedKindError) Status() string {
+	return fmt.Sprintf("unsupported (missing CRD %s/%s)", e.Group, e.Kind)
+}
+
+// UnsupportedKind returns the error if the object kind belongs to
+// the TriggerMesh API groups but is not known to the CRD set,
+// nil otherwise. Objects of other API groups are not checked.
+func UnsupportedKind(object kubernetes.Object, crds map[string]crd.CRD) *UnsupportedKindError {
+	group := strings.Split(object.APIVersion, "/")[0]
+	if !strings.HasSuffix(group, triggermeshGroupSuffix) {
+		return nil
+	}
+	supported := false
+	switch group {
+	case "sources.triggermesh.io", "targets.triggermesh.io", "flow.triggermesh.io":
+		c, exists := crds[strings.ToLower(object.Kind)]
+		supported = exists && c.Spec.Group == group
+	case strings.Split(function.APIVersion, "/")[0]:
+		supported = object.Kind == function.Kind
+	case strings.Split(filesink.APIVersion, "/")[0]:
+		supported = object.Kind == filesink.Kind
+	case strings.Split(tmbroker.APIVersion, "/")[0]:
+		supported = object.Kind == tmbroker.BrokerKind || object.Kind == tmbroker.TriggerKind
+	}
+	if supported {
+		return nil
+	}
+	return &UnsupportedKindError{
+		Name:  object.Metadata.Name,
+		Group: group,
+		Kind:  object.Kind,
+	}
+}
+
+// CheckKinds fails listing the manifest objects of the unsupported kinds.
+// It is used by the commands running with --strict-kinds, where the objects
+// would be skipped with a warning otherwise.
+func CheckKinds(m *manifest.Manifest, crds map[string]crd.CRD) error {
+	var unsupported []string
+	for _, object := range m.Objects {
+		if err := UnsupportedKind(object, crds); err != nil {
+			unsupported = append(unsupported, "\t"+err.Error())
+		}
+	}
+	if len(unsupported) == 0 {
+		return nil
+	}
+	return fmt.Errorf("manifest has objects of unsupported kinds:\n%s", strings.Join(unsupported, "\n"))
+}
@@ -0,0 +1,153 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package components
+
+import (
+	"errors"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/test"
+)
+
+const unknownKinds = `
+---
+apiVersion: sources.triggermesh.io/v1alpha1
+kind: FutureSource
+metadata:
+  name: foo-futuresource
+  labels:
+    triggermesh.io/context: foo
+spec:
+  endpoint: https://example.com
+  sink:
+    ref:
+      apiVersion: eventing.triggermesh.io/v1alpha1
+      kind: RedisBroker
+      name: foo
+---
+apiVersion: eventing.triggermesh.io/v1alpha1
+kind: MemoryBroker
+metadata:
+  name: foo-memorybroker
+  labels:
+    triggermesh.io/context: foo
+spec: {}
+---
+apiVersion: experimental.triggermesh.io/v1alpha1
+kind: Widget
+metadata:
+  name: foo-widget
+  labels:
+    triggermesh.io/context: foo
+`
+
+func mixedKindsManifest(t *testing.T) *manifest.Manifest {
+	data, err := os.ReadFile(test.Manifest())
+	assert.NoError(t, err)
+	path := filepath.Join(t.TempDir(), "manifest.yaml")
+	assert.NoError(t, os.WriteFile(path, append(data, []byte(unknownKinds)...), os.ModePerm))
+	m := manifest.New(path)
+	assert.NoError(t, m.Read())
+	return m
+}
+
+func TestUnsupportedKind(t *testing.T) {
+	m := mixedKindsManifest(t)
+	c := &config.Config{
+		Triggermesh: config.TmConfig{ComponentsVersion: version},
+	}
+	unsupported := map[string]string{
+		"foo-futuresource": "unsupported (missing CRD sources.triggermesh.io/FutureSource)",
+		"foo-memorybroker": "unsupported (missing CRD eventing.triggermesh.io/MemoryBroker)",
+		"foo-widget":       "unsupported (missing CRD experimental.triggermesh.io/Widget)",
+	}
+	supported := 0
+	for _, object := range m.Objects {
+		component, err := GetObject(object.Metadata.Name, c, m, test.CRD())
+		status, expected := unsupported[object.Metadata.Name]
+		if !expected {
+			assert.NoError(t, err, object.Metadata.Name)
+			assert.Nil(t, UnsupportedKind(object, test.CRD()), object.Metadata.Name)
+			supported++
+			continue
+		}
+		var kindErr *UnsupportedKindError
+		assert.True(t, errors.As(err, &kindErr), object.Metadata.Name)
+		assert.Nil(t, component)
+		assert.Equal(t, status, kindErr.Status())
+		assert.Equal(t, kindErr, UnsupportedKind(object, test.CRD()))
+	}
+	assert.Equal(t, 7, supported)
+}
+
+func TestUnsupportedKindMismatchedGroup(t *testing.T) {
+	m := mixedKindsManifest(t)
+	for _, object := range m.Objects {
+		if object.Metadata.Name != "foo-awss3source" {
+			continue
+		}
+		// the kind is known but declared in another group
+		object.APIVersion = "targets.triggermesh.io/v1alpha1"
+		err := UnsupportedKind(object, test.CRD())
+		assert.NotNil(t, err)
+		assert.Equal(t, "targets.triggermesh.io", err.Group)
+		return
+	}
+	t.Fatal("source object not found")
+}
+
+func TestCheckKinds(t *testing.T) {
+	m := mixedKindsManifest(t)
+	err := CheckKinds(m, test.CRD())
+	assert.EqualError(t, err, "manifest has objects of unsupported kinds:\n"+
+		"\tFutureSource \"foo-futuresource\" is unsupported (missing CRD sources.triggermesh.io/FutureSource)\n"+
+		"\tMemoryBroker \"foo-memorybroker\" is unsupported (missing CRD eventing.triggermesh.io/MemoryBroker)\n"+
+		"\tWidget \"foo-widget\" is unsupported (missing CRD experimental.triggermesh.io/Widget)")
+
+	known := manifest.New(test.Manifest())
+	assert.NoError(t, known.Read())
+	assert.NoError(t, CheckKinds(known, test.CRD()))
+}
+
+func TestUnsupportedKindRoundTrip(t *testing.T) {
+	m := mixedKindsManifest(t)
+	count := len(m.Objects)
+
+	// unknown objects are kept as is when the manifest is written
+	assert.NoError(t, m.Write())
+	reread := manifest.New(m.Path)
+	assert.NoError(t, reread.Read())
+	assert.Len(t, reread.Objects, count)
+	for _, object := range reread.Objects {
+		if object.Metadata.Name == "foo-futuresource" {
+			assert.Equal(t, "https://example.com", object.Spec["endpoint"])
+		}
+	}
+
+	// and can be removed
+	assert.NoError(t, reread.Remove("foo-futuresource", "FutureSource"))
+	assert.NoError(t, reread.Write())
+	assert.NoError(t, reread.Read())
+	assert.Len(t, reread.Objects, count-1)
+	assert.Error(t, CheckKinds(reread, test.CRD()))
+}
@@ -27,4 +27,56 @@ const (
 	// objects meta
 	ContextLabel                = "triggermesh.io/context"
 	ExternalResourcesAnnotation = "triggermesh.io/external-resources"
+	ReplicasAnnotation          = "triggermesh.io/replicas"
+	OwnerAnnotation             = "triggermesh.io/owner"
+	LogLevelAnnotation          = "triggermesh.io/log-level"
+	// DataFilterAnnotation holds the expression on the event payload evaluated
+	// by the filter function owned by the trigger.
+	DataFilterAnnotation = "triggermesh.io/data-filter"
+	// EventTypeOriginAnnotation tells where the produced event type came from.
+	EventTypeOriginAnnotation = "triggermesh.io/event-type-origin"
+	// AdapterVersionAnnotation pins the adapter image version of the component,
+	// the components without it follow the version of the context.
+	AdapterVersionAnnotation = "triggermesh.io/adapter-version"
+	// HostPortAnnotation is the host port published by the component container
+	// when it was started last time.
+	HostPortAnnotation = "triggermesh.io/host-port"
+	// ReplyToAnnotation and ReplyEventTypeAnnotation record the consumer
+	// of the target reply events created with "--reply-to".
+	ReplyToAnnotation        = "triggermesh.io/reply-to"
+	ReplyEventTypeAnnotation = "triggermesh.io/reply-event-type"
+	// SignatureHeaderAnnotation and SignatureAlgorithmAnnotation record
+	// the request signature verification of the webhook source.
+	SignatureHeaderAnnotation    = "triggermesh.io/signature-header"
+	SignatureAlgorithmAnnotation = "triggermesh.io/signature-algorithm"
+	// WatchSourceKindsAnnotation lists the source kinds whose event types
+	// the component subscribes to, including the sources created later.
+	WatchSourceKindsAnnotation = "triggermesh.io/watch-source-kinds"
+
+	// cloud credentials resolved by tmctl
+	CredentialsProfileAnnotation = "triggermesh.io/credentials-profile"
+	CredentialsExpiryAnnotation  = "triggermesh.io/credentials-expiry"
+	CredentialsRefreshAnnotation = "triggermesh.io/credentials-refresh"
+
+	// provenance of the manifest objects
+	ProvenanceVersionAnnotation  = "triggermesh.io/tmctl-version"
+	ProvenanceCommandAnnotation  = "triggermesh.io/created-by"
+	ProvenanceCreatedAnnotation  = "triggermesh.io/created"
+	ProvenanceModifiedAnnotation = "triggermesh.io/last-modified"
+)
+
+// origins of the produced event types
+const (
+	EventTypeFromFlag     = "flag"
+	EventTypeFromTemplate = "template"
+	EventTypeFromCRD      = "crd"
 )
+
+// ProvenanceAnnotations are set on the manifest objects by tmctl
+// to record the CLI version and the command that created the object.
+var ProvenanceAnnotations = []string{
+	ProvenanceVersionAnnotation,
+	ProvenanceCommandAnnotation,
+	ProvenanceCreatedAnnotation,
+	ProvenanceModifiedAnnotation,
+}
@@ -17,6 +17,7 @@ limitations under the License.
 package crd
 
 import (
+	"bytes"
 	"fmt"
 	"io"
 	"net/http"
@@ -27,10 +28,16 @@ import (
 
 	"gopkg.in/yaml.v3"
 
+	"github.com/triggermesh/tmctl/pkg/httpclient"
 	"github.com/triggermesh/tmctl/pkg/log"
 )
 
-const crdsURL = "https://github.com/triggermesh/triggermesh/releases/download/$VERSION/triggermesh-crds.yaml"
+// crdsURL is the release asset with the CRDs of all TriggerMesh components.
+var crdsURL = "https://github.com/triggermesh/triggermesh/releases/download/$VERSION/triggermesh-crds.yaml"
+
+// FetchedByCommand is the annotation of the CLI commands that fetch the CRDs
+// of the version passed in their arguments instead of the configured version.
+const FetchedByCommand = "tmctl.triggermesh.io/fetches-crd"
 
 // CRD represents the custom resource definition for CLI functions.
 type CRD struct {
@@ -41,6 +48,8 @@ type CRD struct {
 		Annotations struct {
 			ProducedEventTypes string `yaml:"registry.knative.dev/eventTypes"`
 			ConsumedEventTypes string `yaml:"registry.triggermesh.io/acceptedEventTypes"`
+			TLS                string `yaml:"registry.triggermesh.io/tls"`
+			Readiness          string `yaml:"tmctl.triggermesh.io/readiness"`
 		} `yaml:"annotations"`
 	} `yaml:"metadata"`
 	Spec struct {
@@ -51,53 +60,106 @@ type CRD struct {
 			Plural     string   `yaml:"plural"`
 			Categories []string `yaml:"categories"`
 		} `yaml:"names"`
-		Versions []struct {
-			Name         string `yaml:"name"`
-			Served       bool   `yaml:"served"`
-			Storage      bool   `yaml:"storage"`
-			Subresources struct {
-				Status struct {
-				} `yaml:"status"`
-			} `yaml:"subresources"`
-			Schema struct {
-				OpenAPIV3Schema struct {
-					Properties struct {
-						Spec map[string]interface{} `yaml:"spec"`
-					} `yaml:"properties"`
-				} `yaml:"openAPIV3Schema"`
-			} `yaml:"schema"`
-		} `yaml:"versions"`
+		Versions []Version `yaml:"versions"`
 	} `yaml:"spec"`
 }
 
+// Version is the API version of the custom resource.
+type Version struct {
+	Name         string `yaml:"name"`
+	Served       bool   `yaml:"served"`
+	Storage      bool   `yaml:"storage"`
+	Subresources struct {
+		Status struct {
+		} `yaml:"status"`
+	} `yaml:"subresources"`
+	Schema struct {
+		OpenAPIV3Schema struct {
+			Properties struct {
+				Spec map[string]interface{} `yaml:"spec"`
+			} `yaml:"properties"`
+		} `yaml:"openAPIV3Schema"`
+	} `yaml:"schema"`
+}
+
+// ServesTLS returns true if the component adapter is able to serve TLS.
+func (c CRD) ServesTLS() bool {
+	return c.Metadata.Annotations.TLS == "true"
+}
+
+// Readiness returns the readiness probe of the component kind
+// declared in the CRD annotation, e.g. "http:/healthz" or "log:Started receiver".
+func (c CRD) Readiness() string {
+	return c.Metadata.Annotations.Readiness
+}
+
+// ProducedEventTypes returns the event types listed in the CRD annotation.
+func (c CRD) ProducedEventTypes() []string {
+	return annotationEventTypes(c.Metadata.Annotations.ProducedEventTypes)
+}
+
 type EventTypes []struct {
 	Type   string `json:"type"`
 	Schema string `json:"schema"`
 }
 
-// Fetch downloads the release version of TriggerMesh CRDs for specified version.
+// Fetch returns the release version of TriggerMesh CRDs. The CRDs are read
+// from the local cache or downloaded from the release page. If the download
+// fails, the CRDs embedded into the binary are used instead.
 func Fetch(configDir, version string) (map[string]CRD, error) {
 	crdDir := filepath.Join(configDir, "crd", version)
-	crdFile := filepath.Join(crdDir, "crd.yaml")
+	crdFile := filepath.Join(crdDir, crdFileName)
 	if stat, err := os.Stat(crdFile); err == nil && stat.Size() != 0 {
-		f, err := os.Open(crdFile)
+		crds, err := readCache(crdDir)
 		if err != nil {
 			return nil, err
 		}
-		defer f.Close()
-		return Parse(f)
+		loadedFrom = SourceCache
+		return crds, nil
+	}
+	crds, err := Refresh(configDir, version)
+	if err == nil {
+		return crds, nil
+	}
+	if version != EmbeddedVersion() {
+		crds, embeddedErr := Embedded()
+		if embeddedErr != nil {
+			return nil, err
+		}
+		log.Printf("WARNING! Unable to fetch %s CRD (%v), using the embedded %s definitions", version, err, EmbeddedVersion())
+		loadedFrom = SourceEmbedded
+		return crds, nil
+	}
+	log.Printf("Unable to fetch %s CRD (%v), using the embedded definitions", version, err)
+	data, embeddedErr := embeddedCRDs()
+	if embeddedErr != nil {
+		return nil, err
 	}
 	if err := os.MkdirAll(crdDir, os.ModePerm); err != nil {
 		return nil, err
 	}
-	log.Printf("Fetching %s CRD", version)
-	out, err := os.Create(crdFile)
-	if err != nil {
+	if err := os.WriteFile(crdFile, data, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := writeOrigin(crdDir, SourceEmbedded); err != nil {
+		return nil, err
+	}
+	if crds, err = readCache(crdDir); err != nil {
 		return nil, err
 	}
-	defer out.Close()
+	loadedFrom = SourceEmbedded
+	return crds, nil
+}
 
-	resp, err := http.Get(strings.ReplaceAll(crdsURL, "$VERSION", version))
+// Refresh downloads the release version of TriggerMesh CRDs
+// and replaces the cached CRDs if the download succeeds.
+func Refresh(configDir, version string) (map[string]CRD, error) {
+	crdDir := filepath.Join(configDir, "crd", version)
+	if err := os.MkdirAll(crdDir, os.ModePerm); err != nil {
+		return nil, err
+	}
+	log.Printf("Fetching %s CRD", version)
+	resp, err := httpclient.Get(strings.ReplaceAll(crdsURL, "$VERSION", version))
 	if err != nil {
 		return nil, err
 	}
@@ -106,16 +168,44 @@ func Fetch(configDir, version string) (map[string]CRD, error) {
 	if resp.StatusCode != http.StatusOK {
 		return nil, fmt.Errorf("CRD request failed: %s", resp.Status)
 	}
-	_, err = io.Copy(out, resp.Body)
+	data, err := io.ReadAll(resp.Body)
 	if err != nil {
 		return nil, err
 	}
-	f, err := os.Open(crdFile)
+	if _, err := Parse(io.NopCloser(bytes.NewReader(data))); err != nil {
+		return nil, fmt.Errorf("downloaded CRD: %w", err)
+	}
+	if err := os.WriteFile(filepath.Join(crdDir, crdFileName), data, os.ModePerm); err != nil {
+		return nil, err
+	}
+	if err := writeOrigin(crdDir, SourceNetwork); err != nil {
+		return nil, err
+	}
+	crds, err := readCache(crdDir)
+	if err != nil {
+		return nil, err
+	}
+	loadedFrom = SourceNetwork
+	return crds, nil
+}
+
+// readCache parses the cached CRD file and keeps its index up to date.
+func readCache(crdDir string) (map[string]CRD, error) {
+	f, err := os.Open(filepath.Join(crdDir, crdFileName))
 	if err != nil {
 		return nil, err
 	}
 	defer f.Close()
-	return Parse(f)
+	crds, err := Parse(f)
+	if err != nil {
+		return nil, err
+	}
+	if !indexIsFresh(crdDir) {
+		if _, err := writeIndex(crdDir, crds); err != nil {
+			log.Printf("Unable to write CRD index: %v", err)
+		}
+	}
+	return crds, nil
 }
 
 // Parse reads the CRD file contents into the map.
@@ -0,0 +1,106 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"bytes"
+	"compress/gzip"
+	_ "embed"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+//go:generate sh ../../../hack/embed-crds.sh
+
+// Source is where the CRDs were loaded from.
+type Source string
+
+const (
+	// SourceNetwork means the CRDs were downloaded from the release page.
+	SourceNetwork Source = "network"
+	// SourceCache means the CRDs were read from the local cache.
+	SourceCache Source = "cache"
+	// SourceEmbedded means the CRDs bundled into the binary were used.
+	SourceEmbedded Source = "embedded"
+
+	// originFileName keeps the source of the cached CRD file.
+	originFileName = "origin"
+)
+
+var (
+	//go:embed bundle/triggermesh-crds.yaml.gz
+	embeddedBundle []byte
+	//go:embed bundle/VERSION
+	embeddedVersion string
+
+	// loadedFrom is the source of the CRDs returned by the last Fetch call.
+	loadedFrom Source
+)
+
+// EmbeddedVersion returns the TriggerMesh version of the CRDs bundled into the binary.
+func EmbeddedVersion() string {
+	return strings.TrimSpace(embeddedVersion)
+}
+
+// Embedded returns the CRDs bundled into the binary.
+func Embedded() (map[string]CRD, error) {
+	data, err := embeddedCRDs()
+	if err != nil {
+		return nil, err
+	}
+	return Parse(io.NopCloser(bytes.NewReader(data)))
+}
+
+// LoadedFrom returns the source of the CRDs loaded by the process.
+func LoadedFrom() Source {
+	return loadedFrom
+}
+
+// Origin returns where the cached CRDs of the version were originally
+// obtained, either from the network or from the embedded bundle.
+func Origin(configDir, version string) (Source, bool) {
+	crdDir := filepath.Join(configDir, "crd", version)
+	if stat, err := os.Stat(filepath.Join(crdDir, crdFileName)); err != nil || stat.Size() == 0 {
+		return "", false
+	}
+	data, err := os.ReadFile(filepath.Join(crdDir, originFileName))
+	if err != nil {
+		// caches created before the origin was recorded are always downloaded
+		return SourceNetwork, true
+	}
+	return Source(strings.TrimSpace(string(data))), true
+}
+
+func embeddedCRDs() ([]byte, error) {
+	reader, err := gzip.NewReader(bytes.NewReader(embeddedBundle))
+	if err != nil {
+		return nil, fmt.Errorf("embedded CRDs: %w", err)
+	}
+	defer reader.Close()
+	data, err := io.ReadAll(reader)
+	if err != nil {
+		return nil, fmt.Errorf("embedded CRDs: %w", err)
+	}
+	return data, nil
+}
+
+func writeOrigin(crdDir string, source Source) error {
+	return os.WriteFile(filepath.Join(crdDir, originFileName), []byte(source), os.ModePerm)
+}
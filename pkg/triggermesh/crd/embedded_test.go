@@ -0,0 +1,107 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"regexp"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestEmbedded(t *testing.T) {
+	assert.NotEmpty(t, EmbeddedVersion())
+	crds, err := Embedded()
+	require.NoError(t, err)
+	for _, kind := range []string{"awss3source", "httptarget", "transformation", "webhooksource"} {
+		assert.Contains(t, crds, kind)
+	}
+}
+
+// TestEmbeddedVersion keeps the embedded CRDs in line with the default
+// components version, run "go generate ./pkg/triggermesh/crd" after bumping it.
+func TestEmbeddedVersion(t *testing.T) {
+	source, err := os.ReadFile(filepath.Join("..", "..", "config", "config.go"))
+	require.NoError(t, err)
+	match := regexp.MustCompile(`(?m)^\s*defaultTmVersion\s*= "(.*)"`).FindSubmatch(source)
+	require.NotNil(t, match, "defaultTmVersion is not found")
+	assert.Equal(t, string(match[1]), EmbeddedVersion())
+}
+
+func offline(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		w.WriteHeader(http.StatusBadGateway)
+	}))
+	url := crdsURL
+	crdsURL = server.URL + "/$VERSION/triggermesh-crds.yaml"
+	t.Cleanup(func() {
+		crdsURL = url
+		server.Close()
+	})
+}
+
+func TestFetchEmbeddedFallback(t *testing.T) {
+	offline(t)
+	configDir := t.TempDir()
+
+	crds, err := Fetch(configDir, EmbeddedVersion())
+	require.NoError(t, err)
+	assert.Contains(t, crds, "awss3source")
+	assert.Equal(t, SourceEmbedded, LoadedFrom())
+
+	// embedded definitions are cached for the next runs
+	source, cached := Origin(configDir, EmbeddedVersion())
+	assert.True(t, cached)
+	assert.Equal(t, SourceEmbedded, source)
+	_, err = Fetch(configDir, EmbeddedVersion())
+	require.NoError(t, err)
+	assert.Equal(t, SourceCache, LoadedFrom())
+
+	// definitions of another version are not cached
+	crds, err = Fetch(configDir, "v0.0.1")
+	require.NoError(t, err)
+	assert.Contains(t, crds, "awss3source")
+	assert.Equal(t, SourceEmbedded, LoadedFrom())
+	_, cached = Origin(configDir, "v0.0.1")
+	assert.False(t, cached)
+}
+
+func TestRefresh(t *testing.T) {
+	data, err := os.ReadFile(filepath.Join("..", "..", "..", "test", "fixtures", "crd.yaml"))
+	require.NoError(t, err)
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		_, _ = w.Write(data)
+	}))
+	defer server.Close()
+	url := crdsURL
+	crdsURL = server.URL + "/$VERSION/triggermesh-crds.yaml"
+	defer func() { crdsURL = url }()
+
+	configDir := t.TempDir()
+	crds, err := Refresh(configDir, "v1.0.0")
+	require.NoError(t, err)
+	assert.Contains(t, crds, "awss3source")
+	assert.Equal(t, SourceNetwork, LoadedFrom())
+	source, cached := Origin(configDir, "v1.0.0")
+	assert.True(t, cached)
+	assert.Equal(t, SourceNetwork, source)
+}
@@ -0,0 +1,272 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+const (
+	crdFileName   = "crd.yaml"
+	indexFileName = "index.json"
+
+	// maxIndexDepth limits the nesting level of indexed spec properties.
+	maxIndexDepth = 10
+	// indexFormat is increased when the index content changes,
+	// the indexes of the other formats are rebuilt.
+	indexFormat = 2
+)
+
+// Index is the precomputed subset of CRD metadata used by shell completion.
+// It is stored next to the CRD cache file and rebuilt when the cache changes.
+type Index struct {
+	Format      int                   `json:"format"`
+	Fingerprint string                `json:"fingerprint"`
+	Kinds       map[string]IndexEntry `json:"kinds"`
+}
+
+// IndexEntry contains the event types and spec properties of a single kind.
+type IndexEntry struct {
+	Group              string   `json:"group"`
+	Kind               string   `json:"kind"`
+	ProducedEventTypes []string `json:"producedEventTypes,omitempty"`
+	ConsumedEventTypes []string `json:"consumedEventTypes,omitempty"`
+	// Properties maps the dot-separated spec path to the completion
+	// options available under that path. Root properties have empty path.
+	Properties map[string]map[string]Property `json:"properties"`
+	// Version and Schema are the served version of the kind and its spec
+	// schema, decoded only for the kinds of the manifest components.
+	Version string          `json:"version,omitempty"`
+	Schema  json.RawMessage `json:"schema,omitempty"`
+}
+
+// BuildIndex walks CRD schemas and annotations and creates completion index.
+func BuildIndex(crds map[string]CRD) (*Index, error) {
+	index := &Index{
+		Format: indexFormat,
+		Kinds:  make(map[string]IndexEntry, len(crds)),
+	}
+	for kind, c := range crds {
+		entry := IndexEntry{
+			Group:              c.Spec.Group,
+			Kind:               c.Spec.Names.Kind,
+			ProducedEventTypes: annotationEventTypes(c.Metadata.Annotations.ProducedEventTypes),
+			ConsumedEventTypes: annotationEventTypes(c.Metadata.Annotations.ConsumedEventTypes),
+			Properties:         make(map[string]map[string]Property),
+		}
+		for _, version := range c.Spec.Versions {
+			if !version.Served {
+				continue
+			}
+			schema, err := GetSchema(version.Schema.OpenAPIV3Schema.Properties.Spec)
+			if err != nil {
+				return nil, fmt.Errorf("%q schema: %w", kind, err)
+			}
+			schema.indexProperties(nil, schema.schema.Properties, entry.Properties)
+			if entry.Schema, err = json.Marshal(version.Schema.OpenAPIV3Schema.Properties.Spec); err != nil {
+				return nil, fmt.Errorf("%q schema: %w", kind, err)
+			}
+			entry.Version = version.Name
+			break
+		}
+		index.Kinds[kind] = entry
+	}
+	return index, nil
+}
+
+func (s *Schema) indexProperties(path []string, properties map[string]spec.Schema, result map[string]map[string]Property) {
+	if len(path) > maxIndexDepth {
+		return
+	}
+	_, result[strings.Join(path, ".")] = s.GetAttributesCompletion(path...)
+	for name, property := range properties {
+		s.indexProperties(append(path[:len(path):len(path)], name), property.Properties, result)
+	}
+}
+
+// Spec returns the completion options of the kind for requested spec path.
+// The result is the same as the one returned by the schema GetAttributesCompletion.
+func (i *Index) Spec(kind string, path ...string) (bool, map[string]Property) {
+	entry, exists := i.Kinds[kind]
+	if !exists {
+		return false, map[string]Property{}
+	}
+	var keys []string
+	for _, key := range path {
+		if key != "" {
+			keys = append(keys, key)
+		}
+	}
+	properties, exists := entry.Properties[strings.Join(keys, ".")]
+	if !exists {
+		return false, map[string]Property{}
+	}
+	result := make(map[string]Property, len(properties))
+	for k, v := range properties {
+		result[k] = v
+	}
+	return true, result
+}
+
+// ListGroup returns the list of indexed kinds belonging to the API group
+// with the group-specific suffix trimmed, e.g. "awss3" for "awss3source".
+func (i *Index) ListGroup(group, suffix string) []string {
+	var result []string
+	for kind, entry := range i.Kinds {
+		if entry.Group == group {
+			result = append(result, strings.TrimSuffix(kind, suffix))
+		}
+	}
+	sort.Strings(result)
+	return result
+}
+
+// CRDs returns the definitions of the requested kinds restored from the index,
+// so that the components can be created without parsing the CRD cache.
+// The definitions carry the group, kind, event types and spec schema only.
+func (i *Index) CRDs(kinds ...string) (map[string]CRD, error) {
+	result := make(map[string]CRD, len(kinds))
+	if i == nil {
+		return result, nil
+	}
+	for _, kind := range kinds {
+		entry, exists := i.Kinds[kind]
+		if !exists {
+			continue
+		}
+		var c CRD
+		c.Spec.Group = entry.Group
+		c.Spec.Names.Kind = entry.Kind
+		c.Metadata.Annotations.ProducedEventTypes = eventTypesAnnotation(entry.ProducedEventTypes)
+		c.Metadata.Annotations.ConsumedEventTypes = eventTypesAnnotation(entry.ConsumedEventTypes)
+		if entry.Version != "" {
+			version := Version{Name: entry.Version, Served: true}
+			if err := json.Unmarshal(entry.Schema, &version.Schema.OpenAPIV3Schema.Properties.Spec); err != nil {
+				return nil, fmt.Errorf("%q schema: %w", kind, err)
+			}
+			c.Spec.Versions = []Version{version}
+		}
+		result[kind] = c
+	}
+	return result, nil
+}
+
+// LoadIndex reads the completion index of the cached CRD version. If the index
+// is missing or outdated, it is rebuilt from the CRD cache.
+func LoadIndex(configDir, version string) (*Index, error) {
+	crdDir := filepath.Join(configDir, "crd", version)
+	fingerprint, err := cacheFingerprint(filepath.Join(crdDir, crdFileName))
+	if err != nil {
+		return nil, fmt.Errorf("CRD cache: %w", err)
+	}
+	if data, err := os.ReadFile(filepath.Join(crdDir, indexFileName)); err == nil {
+		var index Index
+		if err := json.Unmarshal(data, &index); err == nil && index.Format == indexFormat && index.Fingerprint == fingerprint {
+			return &index, nil
+		}
+	}
+	f, err := os.Open(filepath.Join(crdDir, crdFileName))
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+	crds, err := Parse(f)
+	if err != nil {
+		return nil, fmt.Errorf("parsing CRD: %w", err)
+	}
+	return writeIndex(crdDir, crds)
+}
+
+func writeIndex(crdDir string, crds map[string]CRD) (*Index, error) {
+	fingerprint, err := cacheFingerprint(filepath.Join(crdDir, crdFileName))
+	if err != nil {
+		return nil, fmt.Errorf("CRD cache: %w", err)
+	}
+	index, err := BuildIndex(crds)
+	if err != nil {
+		return nil, fmt.Errorf("building index: %w", err)
+	}
+	index.Fingerprint = fingerprint
+	data, err := json.Marshal(index)
+	if err != nil {
+		return nil, fmt.Errorf("encoding index: %w", err)
+	}
+	return index, os.WriteFile(filepath.Join(crdDir, indexFileName), data, os.ModePerm)
+}
+
+// indexIsFresh checks if the index file matches current CRD cache.
+func indexIsFresh(crdDir string) bool {
+	fingerprint, err := cacheFingerprint(filepath.Join(crdDir, crdFileName))
+	if err != nil {
+		return false
+	}
+	data, err := os.ReadFile(filepath.Join(crdDir, indexFileName))
+	if err != nil {
+		return false
+	}
+	var index struct {
+		Format      int    `json:"format"`
+		Fingerprint string `json:"fingerprint"`
+	}
+	if err := json.Unmarshal(data, &index); err != nil {
+		return false
+	}
+	return index.Format == indexFormat && index.Fingerprint == fingerprint
+}
+
+func cacheFingerprint(crdFile string) (string, error) {
+	stat, err := os.Stat(crdFile)
+	if err != nil {
+		return "", err
+	}
+	return fmt.Sprintf("%d-%d", stat.Size(), stat.ModTime().UnixNano()), nil
+}
+
+func annotationEventTypes(annotation string) []string {
+	var et EventTypes
+	if err := json.Unmarshal([]byte(annotation), &et); err != nil {
+		return nil
+	}
+	var result []string
+	for _, e := range et {
+		result = append(result, e.Type)
+	}
+	return result
+}
+
+// eventTypesAnnotation encodes the event types as the CRD annotation value.
+func eventTypesAnnotation(eventTypes []string) string {
+	if len(eventTypes) == 0 {
+		return ""
+	}
+	et := make(EventTypes, len(eventTypes))
+	for i, eventType := range eventTypes {
+		et[i].Type = eventType
+	}
+	data, err := json.Marshal(et)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
@@ -50,9 +50,18 @@ func GetSchema(schema map[string]interface{}) (*Schema, error) {
 }
 
 func (s *Schema) Process(spec map[string]interface{}) (map[string]interface{}, error) {
+	return s.process(spec, false)
+}
+
+// process converts the spec values to the schema types. Lenient processing
+// keeps the unknown properties for UnknownFields instead of failing.
+func (s *Schema) process(spec map[string]interface{}, lenient bool) (map[string]interface{}, error) {
 	for k, v := range spec {
 		schemaKey, exists := s.schema.Properties[k]
 		if !exists {
+			if lenient {
+				continue
+			}
 			return nil, fmt.Errorf("property %q does not exist, available values are: %s",
 				k, propertyKeysAsString(s.schema.Properties))
 		}
@@ -86,9 +95,11 @@ func (s *Schema) Process(spec map[string]interface{}) (map[string]interface{}, e
 							}
 							var array []interface{}
 							for _, nestedObject := range items {
-								nestedArrayItem, err := nestedArraySchema.Process(nestedObject)
+								nestedArrayItem, err := nestedArraySchema.process(nestedObject, lenient)
 								if err == nil {
 									array = append(array, nestedArrayItem)
+								} else if lenient {
+									array = append(array, nestedObject)
 								}
 							}
 							spec[k] = array
@@ -121,7 +132,7 @@ func (s *Schema) Process(spec map[string]interface{}) (map[string]interface{}, e
 			nestedSchema := Schema{
 				schema: schemaKey,
 			}
-			nestedValue, err := nestedSchema.Process(value)
+			nestedValue, err := nestedSchema.process(value, lenient)
 			if err != nil {
 				return nil, err
 			}
@@ -133,9 +144,11 @@ func (s *Schema) Process(spec map[string]interface{}) (map[string]interface{}, e
 					nestedSchema := Schema{
 						schema: *schemaKey.Items.Schema,
 					}
-					nestedArrayItem, err := nestedSchema.Process(nestedObject)
+					nestedArrayItem, err := nestedSchema.process(nestedObject, lenient)
 					if err == nil {
 						array = append(array, nestedArrayItem)
+					} else if lenient {
+						array = append(array, nestedObject)
 					}
 				} else {
 					array = append(array, v)
@@ -19,6 +19,7 @@ package crd
 import (
 	"encoding/base64"
 	"fmt"
+	"sort"
 	"strings"
 
 	"k8s.io/kube-openapi/pkg/validation/spec"
@@ -27,6 +28,27 @@ import (
 // secret objects in TriggerMesh are named
 // secretKeyRef or valueFromSecret
 
+// SecretFields returns the dotted paths of the spec fields
+// that keep the values in the secrets.
+func (s *Schema) SecretFields() []string {
+	fields := secretFields("", s.schema)
+	sort.Strings(fields)
+	return fields
+}
+
+func secretFields(prefix string, schema spec.Schema) []string {
+	var fields []string
+	for name, property := range schema.Properties {
+		path := prefix + name
+		if _, ok := isSecretRef(property); ok {
+			fields = append(fields, path)
+			continue
+		}
+		fields = append(fields, secretFields(path+".", property)...)
+	}
+	return fields
+}
+
 func isSecretRef(s spec.Schema) (string, bool) {
 	for k := range s.Properties {
 		if k == "valueFromSecret" || k == "secretKeyRef" {
@@ -0,0 +1,49 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func TestSecretFields(t *testing.T) {
+	secretRef := map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"valueFromSecret": map[string]interface{}{"type": "object"},
+		},
+	}
+	schema, err := GetSchema(map[string]interface{}{
+		"type": "object",
+		"properties": map[string]interface{}{
+			"endpoint":          map[string]interface{}{"type": "string"},
+			"basicAuthPassword": secretRef,
+			"auth": map[string]interface{}{
+				"type": "object",
+				"properties": map[string]interface{}{
+					"token":    secretRef,
+					"username": map[string]interface{}{"type": "string"},
+				},
+			},
+		},
+	})
+	require.NoError(t, err)
+	assert.Equal(t, []string{"auth.token", "basicAuthPassword"}, schema.SecretFields())
+}
@@ -0,0 +1,196 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"fmt"
+	"sort"
+	"strings"
+
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+const preserveUnknownFields = "x-kubernetes-preserve-unknown-fields"
+
+// UnknownField is the spec field that is not declared in the schema.
+type UnknownField struct {
+	// Path is the path of the field, e.g. "spec.auth.credential".
+	Path string
+	// Suggestion is the closest property name declared
+	// on the same level of the schema, if any.
+	Suggestion string
+}
+
+func (f UnknownField) String() string {
+	if f.Suggestion == "" {
+		return "unknown field " + f.Path
+	}
+	return fmt.Sprintf("unknown field %s, did you mean %q?", f.Path, f.Suggestion)
+}
+
+// UnknownFieldsError lists the spec fields that are not declared in the schema.
+type UnknownFieldsError []UnknownField
+
+func (e UnknownFieldsError) Error() string {
+	fields := make([]string, len(e))
+	for i, field := range e {
+		fields[i] = "\t" + field.String()
+	}
+	return "spec has unknown fields:\n" + strings.Join(fields, "\n")
+}
+
+// UnknownFields returns the spec fields that are not declared in the schema,
+// ignoring the objects that allow additional or unknown properties.
+// The spec is not modified.
+func (s *Schema) UnknownFields(spec map[string]interface{}) ([]UnknownField, error) {
+	_, fields, err := s.walk(spec, false)
+	return fields, err
+}
+
+// Prune returns the processed copy of the spec without the fields
+// that are not declared in the schema, and the removed fields.
+func (s *Schema) Prune(spec map[string]interface{}) (map[string]interface{}, []UnknownField, error) {
+	return s.walk(spec, true)
+}
+
+func (s *Schema) walk(spec map[string]interface{}, prune bool) (map[string]interface{}, []UnknownField, error) {
+	processed, err := s.process(deepCopy(spec).(map[string]interface{}), true)
+	if err != nil {
+		return nil, nil, err
+	}
+	fields := unknownFields("spec", s.schema, processed, prune)
+	sort.Slice(fields, func(i, j int) bool {
+		return fields[i].Path < fields[j].Path
+	})
+	return processed, fields, nil
+}
+
+func unknownFields(path string, schema spec.Schema, value interface{}, prune bool) []UnknownField {
+	var result []UnknownField
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for key, nested := range v {
+			property, known := schema.Properties[key]
+			switch {
+			case known:
+				result = append(result, unknownFields(path+"."+key, property, nested, prune)...)
+			case schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil:
+				result = append(result, unknownFields(path+"."+key, *schema.AdditionalProperties.Schema, nested, prune)...)
+			case closed(schema):
+				result = append(result, UnknownField{
+					Path:       path + "." + key,
+					Suggestion: Closest(key, propertyKeys(schema.Properties)),
+				})
+				if prune {
+					delete(v, key)
+				}
+			}
+		}
+	case []interface{}:
+		if schema.Items == nil || schema.Items.Schema == nil {
+			break
+		}
+		for i, item := range v {
+			result = append(result, unknownFields(fmt.Sprintf("%s[%d]", path, i), *schema.Items.Schema, item, prune)...)
+		}
+	}
+	return result
+}
+
+func deepCopy(value interface{}) interface{} {
+	switch v := value.(type) {
+	case map[string]interface{}:
+		result := make(map[string]interface{}, len(v))
+		for k, nested := range v {
+			result[k] = deepCopy(nested)
+		}
+		return result
+	case []interface{}:
+		result := make([]interface{}, len(v))
+		for i, nested := range v {
+			result[i] = deepCopy(nested)
+		}
+		return result
+	}
+	return value
+}
+
+// closed reports whether the object schema rejects the properties it does not declare.
+func closed(schema spec.Schema) bool {
+	if preserve, ok := schema.Extensions.GetBool(preserveUnknownFields); ok && preserve {
+		return false
+	}
+	return len(schema.Properties) != 0 ||
+		schema.AdditionalProperties != nil && !schema.AdditionalProperties.Allows
+}
+
+func propertyKeys(properties map[string]spec.Schema) []string {
+	keys := make([]string, 0, len(properties))
+	for k := range properties {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
+// Closest returns the candidate most similar to the name
+// or empty string if none of the candidates is similar enough.
+func Closest(name string, candidates []string) string {
+	var closest string
+	best := len(name)/2 + 1
+	for _, c := range candidates {
+		if strings.EqualFold(c, name) {
+			return c
+		}
+		if d := distance(strings.ToLower(name), strings.ToLower(c)); d < best {
+			best, closest = d, c
+		}
+	}
+	return closest
+}
+
+// distance is the Levenshtein distance between two strings.
+func distance(a, b string) int {
+	ra, rb := []rune(a), []rune(b)
+	prev := make([]int, len(rb)+1)
+	for j := range prev {
+		prev[j] = j
+	}
+	for i := 1; i <= len(ra); i++ {
+		cur := make([]int, len(rb)+1)
+		cur[0] = i
+		for j := 1; j <= len(rb); j++ {
+			cost := 1
+			if ra[i-1] == rb[j-1] {
+				cost = 0
+			}
+			cur[j] = min(prev[j]+1, cur[j-1]+1, prev[j-1]+cost)
+		}
+		prev = cur
+	}
+	return prev[len(rb)]
+}
+
+func min(values ...int) int {
+	m := values[0]
+	for _, v := range values[1:] {
+		if v < m {
+			m = v
+		}
+	}
+	return m
+}
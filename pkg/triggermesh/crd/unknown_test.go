@@ -0,0 +1,150 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+func fixtureSchema(t *testing.T, kind string) *Schema {
+	f, err := os.Open("../../../test/fixtures/crd.yaml")
+	require.NoError(t, err)
+	defer f.Close()
+	crds, err := Parse(f)
+	require.NoError(t, err)
+	crd, exists := crds[kind]
+	require.True(t, exists, kind)
+	schema, _, err := crd.ServedSchema()
+	require.NoError(t, err)
+	return schema
+}
+
+func TestUnknownFields(t *testing.T) {
+	testCases := map[string]struct {
+		kind     string
+		spec     map[string]interface{}
+		expected []string
+	}{
+		"source known fields": {
+			kind: "awss3source",
+			spec: map[string]interface{}{
+				"arn":        "arn:aws:s3:::bucket",
+				"eventTypes": "s3:ObjectCreated:*",
+				"auth":       map[string]interface{}{"credentials": map[string]interface{}{"accessKeyID": map[string]interface{}{"value": "foo"}}},
+			},
+		},
+		"source misspelled top-level field": {
+			kind: "awss3source",
+			spec: map[string]interface{}{
+				"arn":       "arn:aws:s3:::bucket",
+				"eventType": "s3:ObjectCreated:*",
+			},
+			expected: []string{`unknown field spec.eventType, did you mean "eventTypes"?`},
+		},
+		"source misspelled nested field": {
+			kind: "awss3source",
+			spec: map[string]interface{}{
+				"arn":  "arn:aws:s3:::bucket",
+				"auth": map[string]interface{}{"iamrole": "arn:aws:iam::123:role/foo"},
+			},
+			expected: []string{`unknown field spec.auth.iamrole, did you mean "iamRole"?`},
+		},
+		"source array item field": {
+			kind: "awss3source",
+			spec: map[string]interface{}{
+				"arn": "arn:aws:s3:::bucket",
+				"adapterOverrides": map[string]interface{}{
+					"tolerations": []interface{}{map[string]interface{}{"key": "foo", "efect": "NoSchedule"}},
+				},
+			},
+			expected: []string{`unknown field spec.adapterOverrides.tolerations[0].efect, did you mean "effect"?`},
+		},
+		"target additional properties": {
+			kind: "httptarget",
+			spec: map[string]interface{}{
+				"endpoint": "https://example.com",
+				"headers":  "X-Foo:bar",
+			},
+		},
+		"target unrelated field": {
+			kind: "httptarget",
+			spec: map[string]interface{}{
+				"endpoint":   "https://example.com",
+				"retryCount": "3",
+				"metod":      "GET",
+			},
+			expected: []string{
+				`unknown field spec.metod, did you mean "method"?`,
+				"unknown field spec.retryCount",
+			},
+		},
+		"target object from string": {
+			kind: "httptarget",
+			spec: map[string]interface{}{
+				"endpoint": "https://example.com",
+				"response": "eventType: foo\neventSorce: bar",
+			},
+			expected: []string{`unknown field spec.response.eventSorce, did you mean "eventSource"?`},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			schema := fixtureSchema(t, tc.kind)
+			unknown, err := schema.UnknownFields(tc.spec)
+			require.NoError(t, err)
+			var fields []string
+			for _, field := range unknown {
+				fields = append(fields, field.String())
+			}
+			assert.Equal(t, tc.expected, fields)
+
+			pruned, removed, err := schema.Prune(tc.spec)
+			require.NoError(t, err)
+			assert.Equal(t, unknown, removed)
+			_, err = schema.Process(pruned)
+			assert.NoError(t, err)
+			remaining, err := schema.UnknownFields(pruned)
+			require.NoError(t, err)
+			assert.Empty(t, remaining)
+		})
+	}
+}
+
+func TestUnknownFieldsKeepSpec(t *testing.T) {
+	schema := fixtureSchema(t, "httptarget")
+	spec := map[string]interface{}{
+		"endpoint": "https://example.com",
+		"response": "eventType: foo\nfoo: bar",
+	}
+	_, err := schema.UnknownFields(spec)
+	require.NoError(t, err)
+	_, _, err = schema.Prune(spec)
+	require.NoError(t, err)
+	assert.Equal(t, "eventType: foo\nfoo: bar", spec["response"])
+}
+
+func TestClosest(t *testing.T) {
+	candidates := []string{"endpoint", "method", "headers"}
+	assert.Equal(t, "method", Closest("Method", candidates))
+	assert.Equal(t, "endpoint", Closest("endpont", candidates))
+	assert.Equal(t, "", Closest("retryCount", candidates))
+	assert.Equal(t, "", Closest("foo", nil))
+}
@@ -0,0 +1,167 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"bytes"
+	"fmt"
+	"io"
+	"net/http"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+
+	"gopkg.in/yaml.v3"
+	"k8s.io/client-go/rest"
+	"k8s.io/client-go/tools/clientcmd"
+	"k8s.io/kube-openapi/pkg/validation/spec"
+)
+
+const (
+	crdKind    = "CustomResourceDefinition"
+	crdListAPI = "/apis/apiextensions.k8s.io/v1/customresourcedefinitions"
+)
+
+// ServedSchema returns the spec schema and the name of the served CRD version.
+func (c CRD) ServedSchema() (*Schema, string, error) {
+	for _, v := range c.Spec.Versions {
+		if v.Served {
+			schema, err := GetSchema(v.Schema.OpenAPIV3Schema.Properties.Spec)
+			if err != nil {
+				return nil, "", fmt.Errorf("CRD schema: %w", err)
+			}
+			return schema, v.Name, nil
+		}
+	}
+	return nil, "", fmt.Errorf("CRD schema not found")
+}
+
+// Problems returns the spec fields unknown to the schema
+// and the required fields missing in the spec.
+func (s *Schema) Problems(spec map[string]interface{}) []string {
+	problems := problems("spec", s.schema, spec)
+	sort.Strings(problems)
+	return problems
+}
+
+func problems(path string, schema spec.Schema, value interface{}) []string {
+	var result []string
+	switch v := value.(type) {
+	case map[string]interface{}:
+		for _, required := range schema.Required {
+			if _, set := v[required]; !set {
+				result = append(result, fmt.Sprintf("missing required field %s.%s", path, required))
+			}
+		}
+		for key, nested := range v {
+			property, known := schema.Properties[key]
+			switch {
+			case known:
+				result = append(result, problems(path+"."+key, property, nested)...)
+			case schema.AdditionalProperties != nil && schema.AdditionalProperties.Schema != nil:
+				result = append(result, problems(path+"."+key, *schema.AdditionalProperties.Schema, nested)...)
+			case closed(schema):
+				result = append(result, fmt.Sprintf("unknown field %s.%s", path, key))
+			}
+		}
+	case []interface{}:
+		if schema.Items == nil || schema.Items.Schema == nil {
+			break
+		}
+		for i, item := range v {
+			result = append(result, problems(fmt.Sprintf("%s[%d]", path, i), *schema.Items.Schema, item)...)
+		}
+	}
+	return result
+}
+
+// Load reads the CRDs installed in the cluster of the kubeconfig file
+// or, if the source is a directory, the CRDs from its YAML and JSON files.
+func Load(source string) (map[string]CRD, error) {
+	info, err := os.Stat(source)
+	if err != nil {
+		return nil, err
+	}
+	if info.IsDir() {
+		return loadDir(source)
+	}
+	return loadCluster(source)
+}
+
+func loadDir(dir string) (map[string]CRD, error) {
+	result := make(map[string]CRD)
+	err := filepath.WalkDir(dir, func(path string, d os.DirEntry, err error) error {
+		if err != nil || d.IsDir() {
+			return err
+		}
+		switch strings.ToLower(filepath.Ext(path)) {
+		case ".yaml", ".yml", ".json":
+		default:
+			return nil
+		}
+		f, err := os.Open(path)
+		if err != nil {
+			return err
+		}
+		crds, err := Parse(f)
+		if err != nil {
+			return fmt.Errorf("%s: %w", path, err)
+		}
+		for kind, crd := range crds {
+			if crd.Kind == crdKind {
+				result[kind] = crd
+			}
+		}
+		return nil
+	})
+	return result, err
+}
+
+func loadCluster(kubeconfig string) (map[string]CRD, error) {
+	config, err := clientcmd.BuildConfigFromFlags("", kubeconfig)
+	if err != nil {
+		return nil, fmt.Errorf("kubeconfig: %w", err)
+	}
+	client, err := rest.HTTPClientFor(config)
+	if err != nil {
+		return nil, fmt.Errorf("cluster client: %w", err)
+	}
+	resp, err := client.Get(strings.TrimSuffix(config.Host, "/") + crdListAPI)
+	if err != nil {
+		return nil, fmt.Errorf("listing cluster CRDs: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return nil, fmt.Errorf("listing cluster CRDs: %w", err)
+	}
+	if resp.StatusCode != http.StatusOK {
+		return nil, fmt.Errorf("listing cluster CRDs: %s", resp.Status)
+	}
+	var list struct {
+		Items []CRD `yaml:"items"`
+	}
+	if err := yaml.NewDecoder(bytes.NewReader(body)).Decode(&list); err != nil {
+		return nil, fmt.Errorf("decoding cluster CRDs: %w", err)
+	}
+	result := make(map[string]CRD, len(list.Items))
+	for _, crd := range list.Items {
+		result[strings.ToLower(crd.Spec.Names.Kind)] = crd
+	}
+	return result, nil
+}
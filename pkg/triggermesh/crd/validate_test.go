@@ -0,0 +1,91 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package crd
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+const testCRD = `apiVersion: apiextensions.k8s.io/v1
+kind: CustomResourceDefinition
+metadata:
+  name: httptargets.targets.triggermesh.io
+spec:
+  group: targets.triggermesh.io
+  names:
+    kind: HTTPTarget
+  versions:
+  - name: v1alpha1
+    served: true
+    schema:
+      openAPIV3Schema:
+        properties:
+          spec:
+            type: object
+            required:
+            - endpoint
+            properties:
+              endpoint:
+                type: string
+              headers:
+                type: object
+                additionalProperties:
+                  type: string
+              basicAuth:
+                type: object
+                properties:
+                  username:
+                    type: string
+---
+apiVersion: v1
+kind: ConfigMap
+metadata:
+  name: unrelated
+`
+
+func TestLoadDirAndProblems(t *testing.T) {
+	dir := t.TempDir()
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "crds.yaml"), []byte(testCRD), 0o644))
+	assert.NoError(t, os.WriteFile(filepath.Join(dir, "README.md"), []byte("# CRDs"), 0o644))
+
+	crds, err := Load(dir)
+	assert.NoError(t, err)
+	assert.Len(t, crds, 1)
+	crd, exists := crds["httptarget"]
+	assert.True(t, exists)
+
+	schema, version, err := crd.ServedSchema()
+	assert.NoError(t, err)
+	assert.Equal(t, "v1alpha1", version)
+
+	assert.Empty(t, schema.Problems(map[string]interface{}{
+		"endpoint": "https://example.com",
+		"headers":  map[string]interface{}{"X-Foo": "bar"},
+	}))
+	assert.Equal(t, []string{
+		"missing required field spec.endpoint",
+		"unknown field spec.basicAuth.password",
+		"unknown field spec.method",
+	}, schema.Problems(map[string]interface{}{
+		"method":    "GET",
+		"basicAuth": map[string]interface{}{"username": "foo", "password": "bar"},
+	}))
+}
@@ -0,0 +1,194 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package ingest fronts the broker with the proxy enforcing the ingest
+// limits: the oversized events are rejected with 413 and the events
+// exceeding the rate limit with 429 response.
+package ingest
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"text/template"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+const (
+	proxyImage = "nginx:alpine"
+	proxyPort  = "8080/tcp"
+)
+
+var proxyConfig = template.Must(template.New("nginx").Parse(`error_log /dev/stderr warn;
+events {}
+http {
+{{- if .Rate }}
+  limit_req_zone $server_name zone=ingest:1m rate={{ .Rate }};
+  limit_req_status 429;
+  limit_req_log_level warn;
+{{- end }}
+  client_max_body_size {{ .MaxBodySize }};
+  server {
+    listen 8080;
+    error_page 413 @too_large;
+    error_page 429 @too_many;
+    location / {
+{{- if .Rate }}
+      limit_req zone=ingest burst={{ .Burst }} nodelay;
+{{- end }}
+      proxy_pass http://` + urls.DockerHost + `:{{ .Port }};
+    }
+    location @too_large {
+      default_type text/plain;
+      return 413 "event payload exceeds the broker limit of {{ .Limits.MaxPayloadSize }}\n";
+    }
+    location @too_many {
+      default_type text/plain;
+      add_header Retry-After 1 always;
+      return 429 "event rate exceeds the broker limit of {{ .Limits.RateLimit }}\n";
+    }
+  }
+}
+`))
+
+type proxyData struct {
+	Port   string
+	Limits config.IngestLimits
+	// MaxBodySize is zero if the payload size is not limited.
+	MaxBodySize int64
+	Rate        string
+	Burst       int
+}
+
+// ProxyName returns the container name of the broker ingest proxy.
+func ProxyName(broker string) string {
+	return broker + "-ingest"
+}
+
+// Config renders the proxy configuration enforcing the limits
+// in front of the broker published at the port.
+func Config(port string, limits config.IngestLimits) ([]byte, error) {
+	if err := limits.Validate(); err != nil {
+		return nil, err
+	}
+	data := proxyData{Port: port, Limits: limits}
+	if limits.MaxPayloadSize != "" {
+		data.MaxBodySize, _ = config.ParsePayloadSize(limits.MaxPayloadSize)
+	}
+	if limits.RateLimit != "" {
+		n, unit, _ := config.ParseRateLimit(limits.RateLimit)
+		// bursts up to the limit are accepted without the delay
+		data.Rate = fmt.Sprintf("%dr/%s", n, unit)
+		data.Burst = n
+	}
+	var out bytes.Buffer
+	if err := proxyConfig.Execute(&out, data); err != nil {
+		return nil, fmt.Errorf("ingest proxy config: %w", err)
+	}
+	return out.Bytes(), nil
+}
+
+// Start runs the ingest proxy in front of the broker container.
+// The proxy is restarted only if its configuration changes, so that
+// the components keep sending the events to the same port.
+func Start(ctx context.Context, configBase, broker string, brokerContainer *docker.Container, limits config.IngestLimits) (*docker.Container, error) {
+	proxyConfig, err := Config(brokerContainer.HostPort(), limits)
+	if err != nil {
+		return nil, err
+	}
+	client, err := docker.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("docker client: %w", err)
+	}
+	configFile := filepath.Join(configBase, broker, ProxyName(broker)+".conf")
+	current, err := os.ReadFile(configFile)
+	if err != nil && !os.IsNotExist(err) {
+		return nil, fmt.Errorf("reading ingest proxy config: %w", err)
+	}
+	changed := !bytes.Equal(current, proxyConfig)
+	if changed {
+		if err := os.WriteFile(configFile, proxyConfig, os.ModePerm); err != nil {
+			return nil, fmt.Errorf("writing ingest proxy config: %w", err)
+		}
+	}
+	proxy := &docker.Container{
+		Name:  ProxyName(broker),
+		Image: proxyImage,
+		CreateContainerOptions: []docker.ContainerOption{
+			docker.WithImage(proxyImage),
+			docker.WithPort(proxyPort),
+		},
+		CreateHostOptions: []docker.HostOption{
+			docker.WithHostPortBinding(proxyPort),
+			docker.WithExtraHost(),
+			docker.WithVolumeBind(configFile + ":/etc/nginx/nginx.conf:ro"),
+		},
+	}
+	container, err := proxy.Start(ctx, client, changed)
+	if err != nil {
+		return nil, fmt.Errorf("starting ingest proxy: %w", err)
+	}
+	return container, nil
+}
+
+// Port returns the local port of the running ingest proxy of the broker.
+func Port(ctx context.Context, broker string) (string, bool) {
+	client, err := docker.NewClient()
+	if err != nil {
+		return "", false
+	}
+	proxy := &docker.Container{Name: ProxyName(broker)}
+	if _, err := proxy.LookupHostConfig(ctx, client); err != nil || !proxy.Online {
+		return "", false
+	}
+	return proxy.HostPort(), true
+}
+
+// Entrypoint is the destination of the events sent to the broker:
+// the ingest proxy, if it is running, or the broker itself.
+type Entrypoint struct {
+	Broker    string
+	Component urls.Published
+}
+
+// GetPort returns the local port the broker accepts the events at.
+// The broker port is resolved first so that the offline broker
+// is reported even if its proxy is still running.
+func (e Entrypoint) GetPort(ctx context.Context) (string, error) {
+	port, err := e.Component.GetPort(ctx)
+	if err != nil {
+		return "", err
+	}
+	if proxyPort, limited := Port(ctx, e.Broker); limited {
+		return proxyPort, nil
+	}
+	return port, nil
+}
+
+// Remove stops the ingest proxy of the broker and removes its config.
+func Remove(ctx context.Context, configBase, broker string) error {
+	client, err := docker.NewClient()
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	_ = os.Remove(filepath.Join(configBase, broker, ProxyName(broker)+".conf"))
+	return docker.ForceStop(ctx, ProxyName(broker), client)
+}
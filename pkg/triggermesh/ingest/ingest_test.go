@@ -0,0 +1,51 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package ingest
+
+import (
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/tmctl/pkg/config"
+)
+
+func TestConfig(t *testing.T) {
+	proxy, err := Config("49153", config.IngestLimits{MaxPayloadSize: "1MB", RateLimit: "500/s"})
+	require.NoError(t, err)
+	assert.Contains(t, string(proxy), "proxy_pass http://host.docker.internal:49153;")
+	assert.Contains(t, string(proxy), "client_max_body_size 1048576;")
+	assert.Contains(t, string(proxy), "rate=500r/s;")
+	assert.Contains(t, string(proxy), "limit_req zone=ingest burst=500 nodelay;")
+	assert.Contains(t, string(proxy), `return 413 "event payload exceeds the broker limit of 1MB\n";`)
+	assert.Contains(t, string(proxy), `return 429 "event rate exceeds the broker limit of 500/s\n";`)
+
+	// the payload size is not limited by the nginx default
+	proxy, err = Config("49153", config.IngestLimits{RateLimit: "100/m"})
+	require.NoError(t, err)
+	assert.Contains(t, string(proxy), "client_max_body_size 0;")
+	assert.Contains(t, string(proxy), "rate=100r/m;")
+
+	proxy, err = Config("49153", config.IngestLimits{MaxPayloadSize: "64KB"})
+	require.NoError(t, err)
+	assert.NotContains(t, string(proxy), "limit_req")
+
+	_, err = Config("49153", config.IngestLimits{RateLimit: "often"})
+	assert.Error(t, err)
+	assert.Equal(t, "foo-ingest", ProxyName("foo"))
+}
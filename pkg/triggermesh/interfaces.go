@@ -45,6 +45,17 @@ type Runnable interface {
 	Logs(ctx context.Context, since time.Time, follow bool) (io.ReadCloser, error)
 }
 
+// Networked is implemented by the components whose containers join the network
+// of the context, where the other containers resolve them by the aliases.
+type Networked interface {
+	NetworkAliases() []string
+}
+
+// Versioned is implemented by the components running the TriggerMesh adapter images.
+type Versioned interface {
+	GetVersion() string
+}
+
 // Producer is implemeted by all components that produce events.
 type Producer interface {
 	SetEventAttributes(map[string]string) error
@@ -59,6 +70,12 @@ type Consumer interface {
 	GetPort(context.Context) (string, error)
 }
 
+// Replier is implemented by the consumers that may respond
+// to the delivered events with the new events.
+type Replier interface {
+	ProducedEventTypes() ([]string, error)
+}
+
 // Parent is the interface of the components that produce additional components.
 type Parent interface {
 	GetChildren() ([]Component, error)
@@ -0,0 +1,107 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package logging configures the adapters logging level and
+// filters the adapters log lines by their severity.
+package logging
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+const (
+	// ConfigEnv is the adapters zap logger configuration variable.
+	ConfigEnv = "K_LOGGING_CONFIG"
+	// DefaultLevel is the adapters logging level if the annotation is not set.
+	DefaultLevel = "info"
+)
+
+// Levels are the supported logging levels in the order of severity.
+var Levels = []string{"debug", "info", "warn", "error"}
+
+// Validate checks that the logging level is supported.
+func Validate(level string) error {
+	if severity(level) == -1 {
+		return fmt.Errorf("unsupported log level %q, must be one of %s", level, strings.Join(Levels, ", "))
+	}
+	return nil
+}
+
+// Level returns the logging level recorded in the object annotation.
+// Empty string means that the adapter runs with its default level.
+func Level(object kubernetes.Object) string {
+	return object.Metadata.Annotations[triggermesh.LogLevelAnnotation]
+}
+
+// Effective returns the logging level the object adapter runs with.
+func Effective(object kubernetes.Object) string {
+	if level := Level(object); level != "" {
+		return level
+	}
+	return DefaultLevel
+}
+
+// Env adds the logger configuration with the level to the container
+// environment. The environment is not changed if the level is empty.
+func Env(level string, env map[string]string) map[string]string {
+	if level == "" {
+		return env
+	}
+	if env == nil {
+		env = make(map[string]string, 1)
+	}
+	env[ConfigEnv] = Config(level)
+	return env
+}
+
+// Config returns the K_LOGGING_CONFIG value with the logging level.
+func Config(level string) string {
+	zap, _ := json.Marshal(map[string]string{"level": level})
+	config, _ := json.Marshal(map[string]string{"zap-logger-config": string(zap)})
+	return string(config)
+}
+
+// LineLevel returns the level of the JSON formatted log line.
+func LineLevel(line []byte) (string, bool) {
+	var entry struct {
+		Level string `json:"level"`
+	}
+	if err := json.Unmarshal(line, &entry); err != nil || entry.Level == "" {
+		return "", false
+	}
+	return strings.ToLower(entry.Level), true
+}
+
+// Enabled returns true if the log level is not less severe than the minimum one.
+// Unknown levels are always enabled.
+func Enabled(minimum, level string) bool {
+	l := severity(level)
+	return l == -1 || l >= severity(minimum)
+}
+
+func severity(level string) int {
+	for i, l := range Levels {
+		if l == level {
+			return i
+		}
+	}
+	return -1
+}
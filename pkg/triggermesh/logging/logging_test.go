@@ -0,0 +1,71 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package logging
+
+import (
+	"encoding/json"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+)
+
+func TestEnv(t *testing.T) {
+	var object kubernetes.Object
+	assert.Nil(t, Env(Level(object), nil))
+	assert.Equal(t, DefaultLevel, Effective(object))
+
+	object.Metadata.Annotations = map[string]string{triggermesh.LogLevelAnnotation: "debug"}
+	env := Env(Level(object), map[string]string{"FOO": "bar"})
+	assert.Equal(t, "bar", env["FOO"])
+	assert.Equal(t, "debug", Effective(object))
+
+	var config map[string]string
+	assert.NoError(t, json.Unmarshal([]byte(env[ConfigEnv]), &config))
+	assert.JSONEq(t, `{"level":"debug"}`, config["zap-logger-config"])
+}
+
+func TestValidate(t *testing.T) {
+	for _, level := range Levels {
+		assert.NoError(t, Validate(level))
+	}
+	assert.Error(t, Validate("verbose"))
+	assert.Error(t, Validate(""))
+}
+
+func TestFilter(t *testing.T) {
+	testCases := []struct {
+		line    string
+		minimum string
+		enabled bool
+	}{
+		{line: `{"level":"debug","msg":"foo"}`, minimum: "info", enabled: false},
+		{line: `{"level":"info","msg":"foo"}`, minimum: "info", enabled: true},
+		{line: `{"level":"error","msg":"foo"}`, minimum: "warn", enabled: true},
+		{line: `{"level":"DEBUG","msg":"foo"}`, minimum: "debug", enabled: true},
+		{line: `{"level":"dpanic","msg":"foo"}`, minimum: "error", enabled: true},
+	}
+	for _, tc := range testCases {
+		level, ok := LineLevel([]byte(tc.line))
+		assert.True(t, ok, tc.line)
+		assert.Equal(t, tc.enabled, Enabled(tc.minimum, level), tc.line)
+	}
+	_, ok := LineLevel([]byte("plain text line"))
+	assert.False(t, ok)
+}
@@ -0,0 +1,78 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package triggermesh
+
+import (
+	"errors"
+	"fmt"
+	"regexp"
+	"strings"
+
+	"k8s.io/apimachinery/pkg/util/validation"
+)
+
+// MaxNameLength is the maximum length of the component name.
+const MaxNameLength = validation.DNS1123LabelMaxLength
+
+var invalidNameChars = regexp.MustCompile(`[^a-z0-9]+`)
+
+// ValidateName checks that the name can be used as the container, file
+// and Kubernetes object name, i.e. that it is the RFC 1123 label.
+func ValidateName(name string) error {
+	if len(validation.IsDNS1123Label(name)) == 0 {
+		return nil
+	}
+	msg := fmt.Sprintf("invalid name %q: name must consist of lower case alphanumeric characters or '-', "+
+		"start and end with an alphanumeric character and be at most %d characters long", name, MaxNameLength)
+	if suggestion := SanitizeName(name); suggestion != "" {
+		msg = fmt.Sprintf("%s, e.g. %q", msg, suggestion)
+	}
+	return errors.New(msg)
+}
+
+// ValidateComponentName checks the component name and rejects
+// the names reserved by the broker of the context.
+func ValidateComponentName(name, broker string) error {
+	if err := ValidateName(name); err != nil {
+		return err
+	}
+	if name == broker || name == broker+"-broker" {
+		return fmt.Errorf("name %q is reserved by the broker %q", name, broker)
+	}
+	return nil
+}
+
+// SanitizeName converts the string into the valid name by lowercasing it,
+// replacing the disallowed characters with '-' and trimming it to the
+// maximum length. Empty string is returned if nothing is left.
+func SanitizeName(name string) string {
+	name = invalidNameChars.ReplaceAllString(strings.ToLower(name), "-")
+	name = strings.Trim(name, "-")
+	if len(name) > MaxNameLength {
+		name = strings.TrimRight(name[:MaxNameLength], "-")
+	}
+	return name
+}
+
+// ContainerPort is the port the component containers listen on.
+const ContainerPort = "8080"
+
+// NetworkAliases returns the names the component container is resolved by
+// in the network of the context.
+func NetworkAliases(name, context string) []string {
+	return []string{name, name + "." + context + ".local"}
+}
@@ -0,0 +1,70 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package triggermesh
+
+import (
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func TestValidateName(t *testing.T) {
+	testCases := map[string]bool{
+		"foo-httppollersource":  true,
+		"a":                     true,
+		"My Source!":            false,
+		"foo_bar":               false,
+		"-foo":                  false,
+		"foo.bar":               false,
+		"":                      false,
+		strings.Repeat("a", 63): true,
+		strings.Repeat("a", 64): false,
+	}
+	for name, valid := range testCases {
+		t.Run(name, func(t *testing.T) {
+			err := ValidateName(name)
+			assert.Equal(t, valid, err == nil, err)
+		})
+	}
+}
+
+func TestValidateComponentName(t *testing.T) {
+	assert.NoError(t, ValidateComponentName("sockeye", "foo"))
+	assert.Error(t, ValidateComponentName("foo", "foo"))
+	assert.Error(t, ValidateComponentName("foo-broker", "foo"))
+	assert.Error(t, ValidateComponentName("Sockeye", "foo"))
+}
+
+func TestSanitizeName(t *testing.T) {
+	testCases := map[string]string{
+		"My Source!":                   "my-source",
+		"foo_bar.baz":                  "foo-bar-baz",
+		"--foo--":                      "foo",
+		"!!!":                          "",
+		strings.Repeat("a", 70):        strings.Repeat("a", 63),
+		strings.Repeat("a", 62) + "-b": strings.Repeat("a", 62),
+	}
+	for name, expected := range testCases {
+		sanitized := SanitizeName(name)
+		assert.Equal(t, expected, sanitized)
+		if sanitized != "" {
+			assert.NoError(t, ValidateName(sanitized))
+		}
+	}
+	assert.Contains(t, ValidateName("My Source!").Error(), `"my-source"`)
+}
@@ -71,11 +71,12 @@ func EnvsToString(envs []corev1.EnvVar) []string {
 	return result
 }
 
-func OpenPort() int {
+// OpenPort returns the free TCP port number.
+func OpenPort() (int, error) {
 	listener, err := net.Listen("tcp", ":0")
 	if err != nil {
-		panic(err)
+		return 0, fmt.Errorf("looking for open port: %w", err)
 	}
 	listener.Close()
-	return listener.Addr().(*net.TCPAddr).Port
+	return listener.Addr().(*net.TCPAddr).Port, nil
 }
@@ -0,0 +1,216 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package replicas runs additional copies of the target containers
+// behind the round-robin load balancer.
+package replicas
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"text/template"
+
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+const (
+	proxyImage = "nginx:alpine"
+	proxyPort  = "8080/tcp"
+)
+
+var proxyConfig = template.Must(template.New("nginx").Parse(`events {}
+http {
+  upstream replicas {
+{{- range . }}
+    server ` + urls.DockerHost + `:{{ . }};
+{{- end }}
+  }
+  server {
+    listen 8080;
+    location / {
+      proxy_pass http://replicas;
+    }
+  }
+}
+`))
+
+// Replica is the status of the single target copy.
+type Replica struct {
+	Name   string
+	Port   string
+	Online bool
+}
+
+// Count returns the number of target replicas recorded in the object annotation.
+func Count(object kubernetes.Object) int {
+	replicas, err := strconv.Atoi(object.Metadata.Annotations[triggermesh.ReplicasAnnotation])
+	if err != nil || replicas < 1 {
+		return 1
+	}
+	return replicas
+}
+
+// Name returns the container name of the target replica.
+func Name(target string, replica int) string {
+	if replica == 0 {
+		return target
+	}
+	return fmt.Sprintf("%s%d", NamePrefix(target), replica)
+}
+
+// NamePrefix returns the common prefix of the target replicas container names.
+func NamePrefix(target string) string {
+	return target + "-replica-"
+}
+
+// ProxyName returns the container name of the target load balancer.
+func ProxyName(target string) string {
+	return target + "-lb"
+}
+
+// Scale starts copies of the primary target container and the load balancer
+// in front of them. Extra replicas of the previous scale are removed.
+// Returned value is the local port that events must be delivered to.
+func Scale(ctx context.Context, configBase, broker string, primary *docker.Container, previous, replicas int, restart bool) (string, error) {
+	client, err := docker.NewClient()
+	if err != nil {
+		return "", fmt.Errorf("docker client: %w", err)
+	}
+	ports := []string{primary.HostPort()}
+	for i := 1; i < replicas; i++ {
+		replica := &docker.Container{
+			Name:                   Name(primary.Name, i),
+			Image:                  primary.Image,
+			CreateContainerOptions: primary.CreateContainerOptions,
+			CreateHostOptions:      primary.CreateHostOptions,
+		}
+		container, err := replica.Start(ctx, client, restart)
+		if err != nil {
+			return "", fmt.Errorf("starting replica %q: %w", replica.Name, err)
+		}
+		ports = append(ports, container.HostPort())
+	}
+	for i := replicas; i < previous; i++ {
+		if err := docker.ForceStop(ctx, Name(primary.Name, i), client); err != nil {
+			return "", fmt.Errorf("removing replica %q: %w", Name(primary.Name, i), err)
+		}
+	}
+
+	configFile := filepath.Join(configBase, broker, primary.Name+"-lb.conf")
+	if replicas < 2 {
+		_ = docker.ForceStop(ctx, ProxyName(primary.Name), client)
+		_ = os.Remove(configFile)
+		return primary.HostPort(), nil
+	}
+
+	var config bytes.Buffer
+	if err := proxyConfig.Execute(&config, ports); err != nil {
+		return "", fmt.Errorf("load balancer config: %w", err)
+	}
+	if err := os.WriteFile(configFile, config.Bytes(), os.ModePerm); err != nil {
+		return "", fmt.Errorf("writing load balancer config: %w", err)
+	}
+	proxy := &docker.Container{
+		Name:  ProxyName(primary.Name),
+		Image: proxyImage,
+		CreateContainerOptions: []docker.ContainerOption{
+			docker.WithImage(proxyImage),
+			docker.WithPort(proxyPort),
+		},
+		CreateHostOptions: []docker.HostOption{
+			docker.WithHostPortBinding(proxyPort),
+			docker.WithExtraHost(),
+			docker.WithVolumeBind(configFile + ":/etc/nginx/nginx.conf:ro"),
+		},
+	}
+	// load balancer is always restarted to pick up the new upstreams list.
+	container, err := proxy.Start(ctx, client, true)
+	if err != nil {
+		return "", fmt.Errorf("starting load balancer: %w", err)
+	}
+	return container.HostPort(), nil
+}
+
+// Remove stops target replicas and the load balancer.
+// Primary target container is not affected.
+func Remove(ctx context.Context, target string, replicas int) error {
+	client, err := docker.NewClient()
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	for i := 1; i < replicas; i++ {
+		_ = docker.ForceStop(ctx, Name(target, i), client)
+	}
+	_ = docker.ForceStop(ctx, ProxyName(target), client)
+	return nil
+}
+
+// ProxyPort returns the local port of the target load balancer if it is running.
+func ProxyPort(ctx context.Context, target string) (string, bool) {
+	client, err := docker.NewClient()
+	if err != nil {
+		return "", false
+	}
+	proxy := &docker.Container{Name: ProxyName(target)}
+	if _, err := proxy.LookupHostConfig(ctx, client); err != nil || !proxy.Online {
+		return "", false
+	}
+	return proxy.HostPort(), true
+}
+
+// RouteTriggers updates the target triggers to deliver events to the local port.
+func RouteTriggers(target, port, broker, configBase string) error {
+	triggers, err := tmbroker.GetTargetTriggers(target, broker, configBase)
+	if err != nil {
+		return fmt.Errorf("target triggers: %w", err)
+	}
+	for _, trigger := range triggers {
+		if err := trigger.(*tmbroker.Trigger).SetTargetPort(port); err != nil {
+			return fmt.Errorf("trigger %q: %w", trigger.GetName(), err)
+		}
+		if err := trigger.(*tmbroker.Trigger).UpdateLocalTarget(); err != nil {
+			return fmt.Errorf("broker config update: %w", err)
+		}
+	}
+	return nil
+}
+
+// Status returns the list of target replicas with their state.
+func Status(ctx context.Context, target string, replicas int) []Replica {
+	client, err := docker.NewClient()
+	if err != nil {
+		return nil
+	}
+	var result []Replica
+	for i := 1; i < replicas; i++ {
+		replica := Replica{Name: Name(target, i)}
+		container := &docker.Container{Name: replica.Name}
+		if _, err := container.LookupHostConfig(ctx, client); err == nil {
+			replica.Online = container.Online
+			replica.Port = container.HostPort()
+		}
+		result = append(result, replica)
+	}
+	return result
+}
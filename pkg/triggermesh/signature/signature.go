@@ -0,0 +1,262 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package signature fronts the webhook sources with the proxy
+// that rejects the requests without the valid HMAC signature.
+package signature
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/template"
+	"time"
+
+	"github.com/triggermesh/tmctl/pkg/docker"
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+const (
+	// official nginx image ships the njs module the verifier is written with
+	proxyImage = "nginx:alpine"
+	proxyPort  = "8080/tcp"
+
+	// SecretField is the secret store field of the signature key.
+	SecretField = "signature-secret"
+	// DefaultAlgorithm is the signature algorithm used if none is set.
+	DefaultAlgorithm = "hmac-sha256"
+
+	scriptFile      = "verifier.js"
+	rejectedMessage = "signature rejected"
+)
+
+// algorithms maps the supported algorithms to the njs digest names.
+var algorithms = map[string]string{
+	"hmac-sha1":   "sha1",
+	"hmac-sha256": "sha256",
+}
+
+var proxyConfig = template.Must(template.New("nginx").Parse(`load_module modules/ngx_http_js_module.so;
+env SIGNATURE_HEADER;
+env SIGNATURE_ALGORITHM;
+env SIGNATURE_SECRET;
+error_log /dev/stderr warn;
+events {}
+http {
+  js_import verifier from /etc/nginx/verifier.js;
+  client_max_body_size 1m;
+  client_body_buffer_size 1m;
+  subrequest_output_buffer_size 1m;
+  server {
+    listen 8080;
+    location / {
+      js_content verifier.handle;
+    }
+    location /_upstream/ {
+      internal;
+      proxy_pass http://` + urls.DockerHost + `:{{ . }}/;
+    }
+  }
+}
+`))
+
+// verifierScript compares the request signature header with the HMAC
+// of the body and forwards the signed requests to the source. Header
+// value may carry the digest name prefix, e.g. "sha256=<hex>".
+const verifierScript = `const crypto = require('crypto');
+
+function equal(a, b) {
+  if (a.length !== b.length) {
+    return false;
+  }
+  let diff = 0;
+  for (let i = 0; i < a.length; i++) {
+    diff |= a.charCodeAt(i) ^ b.charCodeAt(i);
+  }
+  return diff === 0;
+}
+
+async function handle(r) {
+  const algorithm = process.env.SIGNATURE_ALGORITHM;
+  let signature = r.headersIn[process.env.SIGNATURE_HEADER] || '';
+  if (signature.startsWith(algorithm + '=')) {
+    signature = signature.slice(algorithm.length + 1);
+  }
+  const body = r.requestBuffer || Buffer.from('');
+  const digest = crypto.createHmac(algorithm, process.env.SIGNATURE_SECRET).update(body).digest('hex');
+  if (!equal(signature.toLowerCase(), digest)) {
+    r.warn('` + rejectedMessage + `');
+    r.return(401, 'invalid signature\n');
+    return;
+  }
+  const reply = await r.subrequest('/_upstream' + r.uri, {
+    method: r.method,
+    args: r.variables.args,
+    body: r.requestText,
+  });
+  r.return(reply.status, reply.responseText);
+}
+
+export default { handle };
+`
+
+// Verification is the signature check of the webhook source requests.
+type Verification struct {
+	Header    string
+	Algorithm string
+}
+
+// Algorithms returns the names of the supported signature algorithms.
+func Algorithms() []string {
+	var result []string
+	for name := range algorithms {
+		result = append(result, name)
+	}
+	sort.Strings(result)
+	return result
+}
+
+// Validate checks that the verification is complete and supported.
+func (v Verification) Validate() error {
+	if v.Header == "" {
+		return fmt.Errorf("signature header is required")
+	}
+	if _, supported := algorithms[v.Algorithm]; !supported {
+		return fmt.Errorf("unsupported signature algorithm %q, expected one of: %s", v.Algorithm, strings.Join(Algorithms(), ", "))
+	}
+	return nil
+}
+
+// Annotations returns the object annotations recording the verification.
+func (v Verification) Annotations() map[string]string {
+	return map[string]string{
+		triggermesh.SignatureHeaderAnnotation:    v.Header,
+		triggermesh.SignatureAlgorithmAnnotation: v.Algorithm,
+	}
+}
+
+// FromObject returns the verification recorded in the object annotations,
+// false if the requests of the source are not verified.
+func FromObject(object kubernetes.Object) (Verification, bool) {
+	header, set := object.Metadata.Annotations[triggermesh.SignatureHeaderAnnotation]
+	if !set {
+		return Verification{}, false
+	}
+	algorithm := object.Metadata.Annotations[triggermesh.SignatureAlgorithmAnnotation]
+	if algorithm == "" {
+		algorithm = DefaultAlgorithm
+	}
+	return Verification{Header: header, Algorithm: algorithm}, true
+}
+
+// ProxyName returns the container name of the source verifier.
+func ProxyName(source string) string {
+	return source + "-verifier"
+}
+
+// Start runs the verifier in front of the source container.
+// Returned container publishes the port the webhook requests must be sent to.
+func Start(ctx context.Context, configBase, broker string, source *docker.Container, v Verification, secret []byte) (*docker.Container, error) {
+	if err := v.Validate(); err != nil {
+		return nil, err
+	}
+	client, err := docker.NewClient()
+	if err != nil {
+		return nil, fmt.Errorf("docker client: %w", err)
+	}
+	dir := filepath.Join(configBase, broker)
+	var config bytes.Buffer
+	if err := proxyConfig.Execute(&config, source.HostPort()); err != nil {
+		return nil, fmt.Errorf("verifier config: %w", err)
+	}
+	configFile := filepath.Join(dir, ProxyName(source.Name)+".conf")
+	if err := os.WriteFile(configFile, config.Bytes(), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("writing verifier config: %w", err)
+	}
+	script := filepath.Join(dir, scriptFile)
+	if err := os.WriteFile(script, []byte(verifierScript), os.ModePerm); err != nil {
+		return nil, fmt.Errorf("writing verifier script: %w", err)
+	}
+	proxy := &docker.Container{
+		Name:  ProxyName(source.Name),
+		Image: proxyImage,
+		CreateContainerOptions: []docker.ContainerOption{
+			docker.WithImage(proxyImage),
+			docker.WithPort(proxyPort),
+			docker.WithEnv([]string{
+				"SIGNATURE_HEADER=" + v.Header,
+				"SIGNATURE_ALGORITHM=" + algorithms[v.Algorithm],
+				"SIGNATURE_SECRET=" + string(secret),
+			}),
+		},
+		CreateHostOptions: []docker.HostOption{
+			docker.WithHostPortBinding(proxyPort),
+			docker.WithExtraHost(),
+			docker.WithVolumeBind(configFile + ":/etc/nginx/nginx.conf:ro"),
+			docker.WithVolumeBind(script + ":/etc/nginx/" + scriptFile + ":ro"),
+		},
+	}
+	// verifier is always restarted to pick up the source port and the secret.
+	container, err := proxy.Start(ctx, client, true)
+	if err != nil {
+		return nil, fmt.Errorf("starting verifier: %w", err)
+	}
+	return container, nil
+}
+
+// Remove stops the source verifier and removes its config.
+func Remove(ctx context.Context, configBase, broker, source string) error {
+	client, err := docker.NewClient()
+	if err != nil {
+		return fmt.Errorf("docker client: %w", err)
+	}
+	_ = os.Remove(filepath.Join(configBase, broker, ProxyName(source)+".conf"))
+	return docker.ForceStop(ctx, ProxyName(source), client)
+}
+
+// Status returns the local port of the source verifier and the number
+// of the requests it rejected since it started. Port is empty if the
+// verifier is not running.
+func Status(ctx context.Context, source string) (string, int, error) {
+	client, err := docker.NewClient()
+	if err != nil {
+		return "", 0, fmt.Errorf("docker client: %w", err)
+	}
+	proxy := &docker.Container{Name: ProxyName(source)}
+	if _, err := proxy.LookupHostConfig(ctx, client); err != nil || !proxy.Online {
+		return "", 0, nil
+	}
+	logs, err := proxy.Logs(ctx, client, time.Unix(0, 0), false)
+	if err != nil {
+		return proxy.HostPort(), 0, fmt.Errorf("verifier logs: %w", err)
+	}
+	defer logs.Close()
+	rejected := 0
+	scanner := bufio.NewScanner(logs)
+	for scanner.Scan() {
+		if strings.Contains(scanner.Text(), rejectedMessage) {
+			rejected++
+		}
+	}
+	return proxy.HostPort(), rejected, scanner.Err()
+}
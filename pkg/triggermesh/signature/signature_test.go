@@ -0,0 +1,53 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package signature
+
+import (
+	"bytes"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+)
+
+func TestVerification(t *testing.T) {
+	v := Verification{Header: "X-Hub-Signature-256", Algorithm: DefaultAlgorithm}
+	assert.NoError(t, v.Validate())
+	assert.Error(t, Verification{Algorithm: DefaultAlgorithm}.Validate())
+	assert.EqualError(t, Verification{Header: "X-Signature", Algorithm: "md5"}.Validate(),
+		`unsupported signature algorithm "md5", expected one of: hmac-sha1, hmac-sha256`)
+
+	var object kubernetes.Object
+	_, verified := FromObject(object)
+	assert.False(t, verified)
+
+	object.Metadata.Annotations = v.Annotations()
+	restored, verified := FromObject(object)
+	assert.True(t, verified)
+	assert.Equal(t, v, restored)
+}
+
+func TestProxyConfig(t *testing.T) {
+	var config bytes.Buffer
+	require.NoError(t, proxyConfig.Execute(&config, "49153"))
+	assert.Contains(t, config.String(), "proxy_pass http://host.docker.internal:49153/;")
+	assert.Contains(t, config.String(), "env SIGNATURE_SECRET;")
+	assert.Contains(t, verifierScript, "r.warn('"+rejectedMessage+"')")
+	assert.Equal(t, "foo-verifier", ProxyName("foo"))
+}
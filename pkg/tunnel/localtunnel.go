@@ -0,0 +1,161 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"io"
+	"net"
+	"net/http"
+	"net/url"
+	"strconv"
+	"sync"
+
+	"github.com/triggermesh/tmctl/pkg/httpclient"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+// DefaultLocaltunnelServer is the public localtunnel server.
+const DefaultLocaltunnelServer = "https://localtunnel.me"
+
+// Localtunnel is the client for the localtunnel.me compatible servers.
+type Localtunnel struct {
+	Server string
+}
+
+type localtunnelInfo struct {
+	ID           string `json:"id"`
+	Port         int    `json:"port"`
+	URL          string `json:"url"`
+	MaxConnCount int    `json:"max_conn_count"`
+	Message      string `json:"message"`
+}
+
+var _ Provider = (*Localtunnel)(nil)
+
+// Run requests the new tunnel and keeps the pool of connections
+// between the server and the local port until any of them fails.
+func (l *Localtunnel) Run(ctx context.Context, localPort string, ready func(url string)) error {
+	server := l.Server
+	if server == "" {
+		server = DefaultLocaltunnelServer
+	}
+	serverURL, err := url.Parse(server)
+	if err != nil {
+		return fmt.Errorf("server address: %w", err)
+	}
+	info, err := requestTunnel(ctx, server)
+	if err != nil {
+		return err
+	}
+	if info.MaxConnCount < 1 {
+		info.MaxConnCount = 1
+	}
+	remote := net.JoinHostPort(serverURL.Hostname(), strconv.Itoa(info.Port))
+	local := urls.New("").Address(urls.Host, localPort)
+
+	ctx, cancel := context.WithCancel(ctx)
+	defer cancel()
+
+	var once sync.Once
+	errs := make(chan error, info.MaxConnCount)
+	for i := 0; i < info.MaxConnCount; i++ {
+		go func() {
+			errs <- serve(ctx, remote, local, func() {
+				once.Do(func() { ready(info.URL) })
+			})
+		}()
+	}
+	select {
+	case <-ctx.Done():
+		return nil
+	case err := <-errs:
+		return err
+	}
+}
+
+func requestTunnel(ctx context.Context, server string) (localtunnelInfo, error) {
+	var info localtunnelInfo
+	req, err := http.NewRequestWithContext(ctx, http.MethodGet, server+"/?new", nil)
+	if err != nil {
+		return info, fmt.Errorf("tunnel request: %w", err)
+	}
+	resp, err := httpclient.Do(req)
+	if err != nil {
+		return info, fmt.Errorf("tunnel request: %w", err)
+	}
+	defer resp.Body.Close()
+	body, err := io.ReadAll(resp.Body)
+	if err != nil {
+		return info, fmt.Errorf("tunnel response: %w", err)
+	}
+	if err := json.Unmarshal(body, &info); err != nil {
+		return info, fmt.Errorf("tunnel response: %w", err)
+	}
+	if info.URL == "" || info.Port == 0 {
+		return info, fmt.Errorf("tunnel is not allocated: %s", info.Message)
+	}
+	return info, nil
+}
+
+// serve proxies the server connections to the local port one at a time.
+func serve(ctx context.Context, remote, local string, connected func()) error {
+	var dialer net.Dialer
+	for {
+		remoteConn, err := dialer.DialContext(ctx, "tcp", remote)
+		if err != nil {
+			return fmt.Errorf("tunnel connection: %w", err)
+		}
+		connected()
+		// wait for the first bytes of the request
+		// before opening the local connection
+		buf := make([]byte, 32*1024)
+		n, err := remoteConn.Read(buf)
+		if err != nil {
+			remoteConn.Close()
+			if ctx.Err() != nil {
+				return nil
+			}
+			return fmt.Errorf("tunnel connection: %w", err)
+		}
+		localConn, err := dialer.DialContext(ctx, "tcp", local)
+		if err != nil {
+			remoteConn.Close()
+			continue
+		}
+		if _, err := localConn.Write(buf[:n]); err == nil {
+			pipe(remoteConn, localConn)
+		}
+		remoteConn.Close()
+		localConn.Close()
+	}
+}
+
+func pipe(a, b net.Conn) {
+	done := make(chan struct{}, 2)
+	go func() {
+		_, _ = io.Copy(a, b)
+		done <- struct{}{}
+	}()
+	go func() {
+		_, _ = io.Copy(b, a)
+		done <- struct{}{}
+	}()
+	<-done
+}
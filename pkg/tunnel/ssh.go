@@ -0,0 +1,89 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"bufio"
+	"bytes"
+	"context"
+	"fmt"
+	"io"
+	"os/exec"
+	"regexp"
+	"strings"
+	"text/template"
+)
+
+var urlRegexp = regexp.MustCompile(`https://[a-zA-Z0-9.\-]+[^\s"']*`)
+
+// Command runs the user-provided tunnel command, e.g. "ssh -R 80:localhost:{{.Port}} serveo.net".
+// The public URL is the first HTTPS address printed by the command.
+type Command struct {
+	Template string
+}
+
+var _ Provider = (*Command)(nil)
+
+// Run starts the command and waits for it to exit.
+func (c *Command) Run(ctx context.Context, localPort string, ready func(url string)) error {
+	command, err := c.render(localPort)
+	if err != nil {
+		return err
+	}
+	cmd := exec.CommandContext(ctx, "sh", "-c", command)
+	stdout, err := cmd.StdoutPipe()
+	if err != nil {
+		return fmt.Errorf("command output: %w", err)
+	}
+	cmd.Stderr = cmd.Stdout
+	if err := cmd.Start(); err != nil {
+		return fmt.Errorf("starting %q: %w", command, err)
+	}
+	scanURL(stdout, ready)
+	if err := cmd.Wait(); err != nil && ctx.Err() == nil {
+		return fmt.Errorf("%q: %w", command, err)
+	}
+	return nil
+}
+
+func (c *Command) render(localPort string) (string, error) {
+	tmpl, err := template.New("tunnel").Parse(c.Template)
+	if err != nil {
+		return "", fmt.Errorf("command template: %w", err)
+	}
+	var command bytes.Buffer
+	if err := tmpl.Execute(&command, struct{ Port string }{localPort}); err != nil {
+		return "", fmt.Errorf("command template: %w", err)
+	}
+	return strings.TrimSpace(command.String()), nil
+}
+
+// scanURL reads the command output until it ends,
+// reporting the first URL it finds.
+func scanURL(output io.Reader, ready func(url string)) {
+	found := false
+	scanner := bufio.NewScanner(output)
+	for scanner.Scan() {
+		if found {
+			continue
+		}
+		if url := urlRegexp.FindString(scanner.Text()); url != "" {
+			found = true
+			ready(url)
+		}
+	}
+}
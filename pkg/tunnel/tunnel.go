@@ -0,0 +1,102 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package tunnel exposes local ports to the internet so that
+// the external services can deliver webhooks to the local sources.
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+const (
+	tunnelsDir = "tunnels"
+
+	initialBackoff = time.Second
+	maxBackoff     = 30 * time.Second
+)
+
+// Provider opens the tunnel to the local port. Run blocks until the tunnel
+// is closed, ready is called with the public URL once the tunnel is established.
+type Provider interface {
+	Run(ctx context.Context, localPort string, ready func(url string)) error
+}
+
+// Keep runs the provider until the context is cancelled,
+// reconnecting with exponential backoff every time the tunnel drops.
+func Keep(ctx context.Context, p Provider, localPort string, ready func(url string), dropped func(err error, retry time.Duration)) {
+	backoff := initialBackoff
+	for {
+		err := p.Run(ctx, localPort, func(url string) {
+			backoff = initialBackoff
+			ready(url)
+		})
+		if ctx.Err() != nil {
+			return
+		}
+		if err == nil {
+			err = errors.New("tunnel closed")
+		}
+		dropped(err, backoff)
+		select {
+		case <-ctx.Done():
+			return
+		case <-time.After(backoff):
+		}
+		backoff = nextBackoff(backoff)
+	}
+}
+
+func nextBackoff(current time.Duration) time.Duration {
+	if current *= 2; current > maxBackoff {
+		return maxBackoff
+	}
+	return current
+}
+
+// SetURL stores the component's public URL while the tunnel is active.
+// The file lives next to the manifest and is removed when the tunnel is closed.
+func SetURL(configBase, broker, component, url string) error {
+	dir := filepath.Join(configBase, broker, tunnelsDir)
+	if err := os.MkdirAll(dir, os.ModePerm); err != nil {
+		return fmt.Errorf("tunnels directory: %w", err)
+	}
+	return os.WriteFile(filepath.Join(dir, component), []byte(url), 0644)
+}
+
+// URL returns the component's active tunnel URL, if any.
+func URL(configBase, broker, component string) string {
+	data, err := os.ReadFile(filepath.Join(configBase, broker, tunnelsDir, component))
+	if err != nil {
+		return ""
+	}
+	return strings.TrimSpace(string(data))
+}
+
+// Clear removes the component's tunnel URL.
+func Clear(configBase, broker, component string) error {
+	err := os.Remove(filepath.Join(configBase, broker, tunnelsDir, component))
+	if errors.Is(err, os.ErrNotExist) {
+		return nil
+	}
+	return err
+}
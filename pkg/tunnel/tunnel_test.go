@@ -0,0 +1,123 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package tunnel
+
+import (
+	"context"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+)
+
+type flakyProvider struct {
+	runs   int
+	cancel context.CancelFunc
+}
+
+func (f *flakyProvider) Run(ctx context.Context, localPort string, ready func(url string)) error {
+	f.runs++
+	if f.runs == 3 {
+		ready("https://foo.example.com")
+		f.cancel()
+		return nil
+	}
+	return errors.New("connection refused")
+}
+
+func TestKeep(t *testing.T) {
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	p := &flakyProvider{cancel: cancel}
+
+	var url string
+	var drops int
+	done := make(chan struct{})
+	go func() {
+		Keep(ctx, p, "8080", func(u string) { url = u }, func(error, time.Duration) { drops++ })
+		close(done)
+	}()
+	select {
+	case <-done:
+	case <-time.After(10 * time.Second):
+		t.Fatal("tunnel was not reconnected")
+	}
+	if p.runs != 3 {
+		t.Errorf("expected 3 runs, got %d", p.runs)
+	}
+	if drops != 2 {
+		t.Errorf("expected 2 drops, got %d", drops)
+	}
+	if url != "https://foo.example.com" {
+		t.Errorf("unexpected URL %q", url)
+	}
+}
+
+func TestNextBackoff(t *testing.T) {
+	backoff := initialBackoff
+	for i := 0; i < 10; i++ {
+		backoff = nextBackoff(backoff)
+	}
+	if backoff != maxBackoff {
+		t.Errorf("expected backoff to be capped at %s, got %s", maxBackoff, backoff)
+	}
+}
+
+func TestCommandRender(t *testing.T) {
+	c := &Command{Template: "ssh -R 80:localhost:{{.Port}} serveo.net"}
+	command, err := c.render("53412")
+	if err != nil {
+		t.Fatal(err)
+	}
+	if command != "ssh -R 80:localhost:53412 serveo.net" {
+		t.Errorf("unexpected command %q", command)
+	}
+}
+
+func TestScanURL(t *testing.T) {
+	output := `Warning: Permanently added 'serveo.net' to the list of known hosts.
+Forwarding HTTP traffic from https://abc123.serveo.net
+Forwarding HTTP traffic from https://other.serveo.net
+`
+	var urls []string
+	scanURL(strings.NewReader(output), func(url string) { urls = append(urls, url) })
+	if len(urls) != 1 || urls[0] != "https://abc123.serveo.net" {
+		t.Errorf("unexpected URLs %v", urls)
+	}
+}
+
+func TestURL(t *testing.T) {
+	dir := t.TempDir()
+	if url := URL(dir, "foo", "webhook"); url != "" {
+		t.Errorf("expected empty URL, got %q", url)
+	}
+	if err := SetURL(dir, "foo", "webhook", "https://foo.example.com"); err != nil {
+		t.Fatal(err)
+	}
+	if url := URL(dir, "foo", "webhook"); url != "https://foo.example.com" {
+		t.Errorf("unexpected URL %q", url)
+	}
+	if err := Clear(dir, "foo", "webhook"); err != nil {
+		t.Fatal(err)
+	}
+	if err := Clear(dir, "foo", "webhook"); err != nil {
+		t.Errorf("clearing missing URL: %v", err)
+	}
+	if url := URL(dir, "foo", "webhook"); url != "" {
+		t.Errorf("expected empty URL, got %q", url)
+	}
+}
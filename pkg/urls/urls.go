@@ -0,0 +1,154 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package urls resolves the addresses of the broker and the components
+// from the host running tmctl, from the containers and from outside.
+package urls
+
+import (
+	"context"
+	"net"
+	"net/url"
+	"os"
+	"strings"
+)
+
+const (
+	// DockerHost is the name the containers resolve the Docker host by.
+	// Docker Desktop resolves it natively, Linux engines need HostGateway.
+	DockerHost = "host.docker.internal"
+	// HostGateway is the extra host entry resolving DockerHost to
+	// the gateway of the container network on Linux engines.
+	HostGateway = DockerHost + ":host-gateway"
+
+	localhost = "localhost"
+)
+
+// Scope is the network location the URL is used from.
+type Scope string
+
+const (
+	// Host is the machine running tmctl.
+	Host Scope = "host"
+	// ContainerNetwork are the containers of the context.
+	ContainerNetwork Scope = "container-network"
+	// External are the clients outside of the Docker host, e.g. webhook senders.
+	External Scope = "external"
+)
+
+// Resolver builds the URLs of the ports published on the Docker host.
+type Resolver struct {
+	// DockerEndpoint is the Docker engine endpoint, e.g. "tcp://10.0.0.5:2376".
+	// The ports of the remote engine are published on its host.
+	DockerEndpoint string
+	// AdvertiseAddress overrides the host name of the external URLs.
+	AdvertiseAddress string
+}
+
+// Published is implemented by the components publishing their port on the Docker host.
+type Published interface {
+	GetPort(context.Context) (string, error)
+}
+
+// New returns the resolver of the Docker engine selected by the DOCKER_HOST variable.
+func New(advertiseAddress string) Resolver {
+	return Resolver{
+		DockerEndpoint:   os.Getenv("DOCKER_HOST"),
+		AdvertiseAddress: advertiseAddress,
+	}
+}
+
+// Hostname returns the name of the Docker host in the scope.
+func (r Resolver) Hostname(scope Scope) string {
+	switch scope {
+	case ContainerNetwork:
+		return DockerHost
+	case External:
+		if r.AdvertiseAddress != "" {
+			return r.AdvertiseAddress
+		}
+	}
+	return r.engineHost()
+}
+
+// Address returns the "host:port" address of the published port.
+func (r Resolver) Address(scope Scope, port string) string {
+	return net.JoinHostPort(r.Hostname(scope), port)
+}
+
+// URL returns the HTTP URL of the published port.
+func (r Resolver) URL(scope Scope, port string) string {
+	return "http://" + r.Address(scope, port)
+}
+
+// ComponentURL returns the URL of the component in the scope.
+func (r Resolver) ComponentURL(ctx context.Context, component Published, scope Scope) (string, error) {
+	port, err := component.GetPort(ctx)
+	if err != nil {
+		return "", err
+	}
+	return r.URL(scope, port), nil
+}
+
+// BrokerIngestURL returns the URL the events are sent to the broker at.
+// The broker is expected to be the ingest entrypoint of the context,
+// i.e. the proxy enforcing the ingest limits if there is one.
+func (r Resolver) BrokerIngestURL(ctx context.Context, broker Published, scope Scope) (string, error) {
+	return r.ComponentURL(ctx, broker, scope)
+}
+
+// HostAddress translates the "host:port" address of the Docker host
+// used by the containers into the address reachable from the host.
+func (r Resolver) HostAddress(address string) string {
+	host, port, err := net.SplitHostPort(address)
+	if err != nil || host != DockerHost {
+		return address
+	}
+	return r.Address(Host, port)
+}
+
+// HostURL translates the URL of the Docker host used by the containers
+// into the URL reachable from the host.
+func (r Resolver) HostURL(address string) string {
+	u, err := url.Parse(address)
+	if err != nil {
+		return address
+	}
+	if u.Host == DockerHost {
+		u.Host = r.Hostname(Host)
+	} else {
+		u.Host = r.HostAddress(u.Host)
+	}
+	return u.String()
+}
+
+// engineHost returns the host the Docker engine publishes the ports on.
+func (r Resolver) engineHost() string {
+	if r.DockerEndpoint == "" {
+		return localhost
+	}
+	u, err := url.Parse(r.DockerEndpoint)
+	if err != nil {
+		return localhost
+	}
+	switch strings.ToLower(u.Scheme) {
+	case "tcp", "http", "https", "ssh":
+		if host := u.Hostname(); host != "" {
+			return host
+		}
+	}
+	return localhost
+}
@@ -0,0 +1,128 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package urls
+
+import (
+	"context"
+	"fmt"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+)
+
+type component string
+
+func (c component) GetPort(context.Context) (string, error) {
+	if c == "" {
+		return "", fmt.Errorf("offline")
+	}
+	return string(c), nil
+}
+
+func TestResolver(t *testing.T) {
+	testCases := map[string]struct {
+		resolver Resolver
+		expected map[Scope]string
+	}{
+		"linux gateway": {
+			resolver: Resolver{DockerEndpoint: "unix:///var/run/docker.sock"},
+			expected: map[Scope]string{
+				Host:             "http://localhost:8080",
+				ContainerNetwork: "http://host.docker.internal:8080",
+				External:         "http://localhost:8080",
+			},
+		},
+		"docker desktop": {
+			resolver: Resolver{},
+			expected: map[Scope]string{
+				Host:             "http://localhost:8080",
+				ContainerNetwork: "http://host.docker.internal:8080",
+				External:         "http://localhost:8080",
+			},
+		},
+		"remote docker host": {
+			resolver: Resolver{DockerEndpoint: "tcp://10.0.0.5:2376"},
+			expected: map[Scope]string{
+				Host:             "http://10.0.0.5:8080",
+				ContainerNetwork: "http://host.docker.internal:8080",
+				External:         "http://10.0.0.5:8080",
+			},
+		},
+		"remote docker host over ssh": {
+			resolver: Resolver{DockerEndpoint: "ssh://user@docker.example.com"},
+			expected: map[Scope]string{
+				Host:             "http://docker.example.com:8080",
+				ContainerNetwork: "http://host.docker.internal:8080",
+				External:         "http://docker.example.com:8080",
+			},
+		},
+		"advertise address": {
+			resolver: Resolver{DockerEndpoint: "tcp://10.0.0.5:2376", AdvertiseAddress: "events.example.com"},
+			expected: map[Scope]string{
+				Host:             "http://10.0.0.5:8080",
+				ContainerNetwork: "http://host.docker.internal:8080",
+				External:         "http://events.example.com:8080",
+			},
+		},
+		"ipv6 advertise address": {
+			resolver: Resolver{AdvertiseAddress: "fd00::1"},
+			expected: map[Scope]string{
+				Host:             "http://localhost:8080",
+				ContainerNetwork: "http://host.docker.internal:8080",
+				External:         "http://[fd00::1]:8080",
+			},
+		},
+	}
+	for name, tc := range testCases {
+		t.Run(name, func(t *testing.T) {
+			for scope, expected := range tc.expected {
+				assert.Equal(t, expected, tc.resolver.URL(scope, "8080"), scope)
+				url, err := tc.resolver.ComponentURL(context.Background(), component("8080"), scope)
+				require.NoError(t, err)
+				assert.Equal(t, expected, url, scope)
+				url, err = tc.resolver.BrokerIngestURL(context.Background(), component("8080"), scope)
+				require.NoError(t, err)
+				assert.Equal(t, expected, url, scope)
+			}
+		})
+	}
+}
+
+func TestComponentURLOffline(t *testing.T) {
+	_, err := Resolver{}.ComponentURL(context.Background(), component(""), Host)
+	assert.Error(t, err)
+}
+
+func TestHostURL(t *testing.T) {
+	local := Resolver{}
+	assert.Equal(t, "localhost:6379", local.HostAddress("host.docker.internal:6379"))
+	assert.Equal(t, "redis:6379", local.HostAddress("redis:6379"))
+	assert.Equal(t, "http://localhost:8080/path", local.HostURL("http://host.docker.internal:8080/path"))
+	assert.Equal(t, "http://localhost/path", local.HostURL("http://host.docker.internal/path"))
+	assert.Equal(t, "https://example.com/path", local.HostURL("https://example.com/path"))
+
+	remote := Resolver{DockerEndpoint: "tcp://10.0.0.5:2376"}
+	assert.Equal(t, "10.0.0.5:6379", remote.HostAddress("host.docker.internal:6379"))
+	assert.Equal(t, "http://10.0.0.5:8080", remote.HostURL("http://host.docker.internal:8080"))
+}
+
+func TestNew(t *testing.T) {
+	t.Setenv("DOCKER_HOST", "tcp://10.0.0.5:2376")
+	assert.Equal(t, "10.0.0.5", New("").Hostname(Host))
+	assert.Equal(t, "events.example.com", New("events.example.com").Hostname(External))
+}
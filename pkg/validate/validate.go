@@ -0,0 +1,254 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+// Package validate checks the manifest objects without the running
+// components: the object structure, the specs against the CRD schemas,
+// the references between the objects and the transformation specs.
+package validate
+
+import (
+	"encoding/json"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/triggermesh/tmctl/pkg/kubernetes"
+	"github.com/triggermesh/tmctl/pkg/triggermesh"
+	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/components/transformation"
+	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
+)
+
+// Severity is the level of the finding.
+type Severity string
+
+const (
+	SeverityError   Severity = "error"
+	SeverityWarning Severity = "warning"
+)
+
+// AtLeast returns true if the severity is not lower than the threshold.
+func (s Severity) AtLeast(threshold Severity) bool {
+	return s == SeverityError || threshold == SeverityWarning
+}
+
+// ParseSeverity returns the severity of its name.
+func ParseSeverity(name string) (Severity, error) {
+	switch s := Severity(name); s {
+	case SeverityError, SeverityWarning:
+		return s, nil
+	}
+	return "", fmt.Errorf("unknown severity %q, expected %q or %q", name, SeverityError, SeverityWarning)
+}
+
+// Finding is the problem of the manifest object.
+type Finding struct {
+	// Object is the "<Kind> <name>" reference of the object.
+	Object   string
+	Severity Severity
+	Message  string
+}
+
+func (f Finding) String() string {
+	return fmt.Sprintf("%s: %s", f.Severity, f.Message)
+}
+
+// Objects validates the manifest objects against the CRDs and returns
+// the findings ordered by the object position in the manifest.
+func Objects(objects []kubernetes.Object, crds map[string]crd.CRD) []Finding {
+	var findings []Finding
+	seen := make(map[string]bool, len(objects))
+	for i, object := range objects {
+		ref := reference(object, i)
+		report := func(severity Severity, format string, args ...interface{}) {
+			findings = append(findings, Finding{Object: ref, Severity: severity, Message: fmt.Sprintf(format, args...)})
+		}
+		structure(object, report)
+		key := strings.ToLower(object.Kind) + "/" + object.Metadata.Name
+		if seen[key] {
+			report(SeverityError, "duplicate object")
+		}
+		seen[key] = true
+
+		switch {
+		case object.APIVersion == tmbroker.APIVersion && object.Kind == tmbroker.TriggerKind:
+			trigger(object, objects, report)
+		case strings.Contains(object.APIVersion, ".triggermesh.io/") && object.APIVersion != tmbroker.APIVersion:
+			schema(object, crds, report)
+			sinkRef(object, objects, report)
+		}
+		if object.Kind == "Transformation" {
+			for _, finding := range transformation.Lint(object.Spec) {
+				severity := SeverityWarning
+				if finding.Severity == transformation.SeverityError {
+					severity = SeverityError
+				}
+				report(severity, "%s[%d]: %s", finding.Section, finding.Operation, finding.Message)
+			}
+		}
+	}
+	return findings
+}
+
+// Count returns the number of the findings of at least the severity.
+func Count(findings []Finding, threshold Severity) int {
+	count := 0
+	for _, finding := range findings {
+		if finding.Severity.AtLeast(threshold) {
+			count++
+		}
+	}
+	return count
+}
+
+type reporter func(severity Severity, format string, args ...interface{})
+
+func reference(object kubernetes.Object, index int) string {
+	kind, name := object.Kind, object.Metadata.Name
+	if kind == "" {
+		kind = "Object"
+	}
+	if name == "" {
+		return fmt.Sprintf("%s #%d", kind, index+1)
+	}
+	return fmt.Sprintf("%s %q", kind, name)
+}
+
+func structure(object kubernetes.Object, report reporter) {
+	if object.APIVersion == "" {
+		report(SeverityError, "missing apiVersion")
+	}
+	if object.Kind == "" {
+		report(SeverityError, "missing kind")
+	}
+	if object.Metadata.Name == "" {
+		report(SeverityError, "missing metadata.name")
+	} else if err := triggermesh.ValidateName(object.Metadata.Name); err != nil {
+		report(SeverityError, "%v", err)
+	}
+	if _, set := object.Metadata.Labels[triggermesh.ContextLabel]; !set {
+		report(SeverityWarning, "missing %s label", triggermesh.ContextLabel)
+	}
+}
+
+func schema(object kubernetes.Object, crds map[string]crd.CRD, report reporter) {
+	c, exists := crds[strings.ToLower(object.Kind)]
+	if !exists {
+		report(SeverityError, "kind %s is unknown", object.Kind)
+		return
+	}
+	s, version, err := c.ServedSchema()
+	if err != nil {
+		report(SeverityError, "%v", err)
+		return
+	}
+	if expected := c.Spec.Group + "/" + version; object.APIVersion != expected {
+		report(SeverityWarning, "apiVersion %s differs from the served %s", object.APIVersion, expected)
+	}
+	for _, problem := range s.Problems(object.Spec) {
+		report(SeverityError, "%s", problem)
+	}
+}
+
+type objectRef struct {
+	Kind string `json:"kind"`
+	Name string `json:"name"`
+}
+
+type triggerSpec struct {
+	Broker objectRef `json:"broker"`
+	Target struct {
+		Ref *objectRef `json:"ref"`
+		URI string     `json:"uri"`
+	} `json:"target"`
+	Filters []json.RawMessage `json:"filters"`
+}
+
+func trigger(object kubernetes.Object, objects []kubernetes.Object, report reporter) {
+	var spec triggerSpec
+	if err := decode(object.Spec, &spec); err != nil {
+		report(SeverityError, "spec: %v", err)
+		return
+	}
+	if spec.Broker.Name == "" {
+		report(SeverityError, "missing spec.broker.name")
+	} else if !exists(objects, spec.Broker) {
+		report(SeverityError, "broker %q is not in the manifest", spec.Broker.Name)
+	}
+	switch {
+	case spec.Target.Ref != nil:
+		if !exists(objects, *spec.Target.Ref) {
+			report(SeverityError, "target %s %q is not in the manifest", spec.Target.Ref.Kind, spec.Target.Ref.Name)
+		}
+	case spec.Target.URI == "":
+		report(SeverityError, "missing spec.target")
+	}
+	if len(spec.Filters) == 0 {
+		report(SeverityWarning, "trigger without filters delivers all events")
+	}
+}
+
+// sinkRef checks that the producer sends the events to the manifest object.
+func sinkRef(object kubernetes.Object, objects []kubernetes.Object, report reporter) {
+	var spec struct {
+		Sink struct {
+			Ref *objectRef `json:"ref"`
+		} `json:"sink"`
+	}
+	if err := decode(object.Spec, &spec); err != nil || spec.Sink.Ref == nil {
+		return
+	}
+	if !exists(objects, *spec.Sink.Ref) {
+		report(SeverityError, "sink %s %q is not in the manifest", spec.Sink.Ref.Kind, spec.Sink.Ref.Name)
+	}
+}
+
+// exists returns true if the manifest has the referenced object,
+// kinds are compared case-insensitively as some references use the CRD form.
+func exists(objects []kubernetes.Object, ref objectRef) bool {
+	for _, object := range objects {
+		if object.Metadata.Name == ref.Name && (ref.Kind == "" || strings.EqualFold(object.Kind, ref.Kind)) {
+			return true
+		}
+	}
+	return false
+}
+
+func decode(spec map[string]interface{}, into interface{}) error {
+	data, err := json.Marshal(spec)
+	if err != nil {
+		return err
+	}
+	return json.Unmarshal(data, into)
+}
+
+// ByObject groups the findings by the object keeping their order.
+func ByObject(findings []Finding) ([]string, map[string][]Finding) {
+	var order []string
+	groups := make(map[string][]Finding)
+	for _, finding := range findings {
+		if _, seen := groups[finding.Object]; !seen {
+			order = append(order, finding.Object)
+		}
+		groups[finding.Object] = append(groups[finding.Object], finding)
+	}
+	for _, object := range order {
+		sort.SliceStable(groups[object], func(i, j int) bool {
+			return groups[object][i].Severity == SeverityError && groups[object][j].Severity != SeverityError
+		})
+	}
+	return order, groups
+}
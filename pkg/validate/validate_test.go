@@ -0,0 +1,122 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package validate
+
+import (
+	"os"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	"github.com/stretchr/testify/require"
+
+	"github.com/triggermesh/tmctl/pkg/manifest"
+	"github.com/triggermesh/tmctl/test"
+)
+
+func TestObjects(t *testing.T) {
+	data, err := os.ReadFile(test.Manifest())
+	require.NoError(t, err)
+	objects, err := manifest.Decode(data)
+	require.NoError(t, err)
+	assert.Empty(t, Objects(objects, test.CRD()))
+
+	broken, err := manifest.Decode([]byte(`---
+apiVersion: eventing.triggermesh.io/v1alpha1
+kind: RedisBroker
+metadata:
+  labels:
+    triggermesh.io/context: foo
+  name: foo
+---
+apiVersion: targets.triggermesh.io/v1alpha1
+kind: HTTPTarget
+metadata:
+  labels:
+    triggermesh.io/context: foo
+  name: Bar
+spec:
+  endpoint: https://example.com
+  unknown: true
+---
+apiVersion: eventing.triggermesh.io/v1alpha1
+kind: Trigger
+metadata:
+  name: foo-trigger
+spec:
+  broker:
+    kind: RedisBroker
+    name: foo
+  target:
+    ref:
+      kind: Service
+      name: sockeye
+---
+apiVersion: flow.triggermesh.io/v1alpha1
+kind: Transformation
+metadata:
+  labels:
+    triggermesh.io/context: foo
+  name: foo-transformation
+spec:
+  data:
+  - operation: add
+    paths:
+    - key: foo
+      value: bar
+    - key: foo
+      value: baz
+---
+apiVersion: sources.triggermesh.io/v1alpha1
+kind: UnknownSource
+metadata:
+  labels:
+    triggermesh.io/context: foo
+  name: foo-transformation
+`))
+	require.NoError(t, err)
+	findings := Objects(broken, test.CRD())
+
+	order, groups := ByObject(findings)
+	assert.Equal(t, []string{`HTTPTarget "Bar"`, `Trigger "foo-trigger"`, `Transformation "foo-transformation"`, `UnknownSource "foo-transformation"`}, order)
+	assert.Contains(t, groups[`HTTPTarget "Bar"`], Finding{Object: `HTTPTarget "Bar"`, Severity: SeverityError, Message: "unknown field spec.unknown"})
+	assert.Equal(t, []Finding{
+		{Object: `Trigger "foo-trigger"`, Severity: SeverityError, Message: `target Service "sockeye" is not in the manifest`},
+		{Object: `Trigger "foo-trigger"`, Severity: SeverityWarning, Message: "missing triggermesh.io/context label"},
+		{Object: `Trigger "foo-trigger"`, Severity: SeverityWarning, Message: "trigger without filters delivers all events"},
+	}, groups[`Trigger "foo-trigger"`])
+	assert.NotEmpty(t, groups[`Transformation "foo-transformation"`])
+	assert.Equal(t, []Finding{
+		{Object: `UnknownSource "foo-transformation"`, Severity: SeverityError, Message: "kind UnknownSource is unknown"},
+	}, groups[`UnknownSource "foo-transformation"`])
+
+	errors := Count(findings, SeverityError)
+	assert.NotZero(t, errors)
+	assert.Greater(t, Count(findings, SeverityWarning), errors)
+}
+
+func TestParseSeverity(t *testing.T) {
+	s, err := ParseSeverity("warning")
+	assert.NoError(t, err)
+	assert.Equal(t, SeverityWarning, s)
+	_, err = ParseSeverity("info")
+	assert.Error(t, err)
+
+	assert.True(t, SeverityError.AtLeast(SeverityError))
+	assert.True(t, SeverityError.AtLeast(SeverityWarning))
+	assert.False(t, SeverityWarning.AtLeast(SeverityError))
+	assert.True(t, SeverityWarning.AtLeast(SeverityWarning))
+}
@@ -0,0 +1,137 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiretap
+
+import (
+	"bufio"
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+// RecordedAtExtension is the CloudEvent extension
+// that holds the time when the event was recorded.
+const RecordedAtExtension = "recordedat"
+
+// maxRecordedLine limits the size of the recorded event line being read.
+const maxRecordedLine = 16 * 1024 * 1024
+
+// Recorder writes events to the file as JSON lines,
+// rotating the file when it reaches the size limit.
+type Recorder struct {
+	path    string
+	maxSize int64
+
+	file *os.File
+	size int64
+}
+
+// NewRecorder opens the file for appending the events.
+// Zero maxSize disables the rotation.
+func NewRecorder(path string, maxSize int64) (*Recorder, error) {
+	r := &Recorder{
+		path:    path,
+		maxSize: maxSize,
+	}
+	if err := r.open(); err != nil {
+		return nil, err
+	}
+	return r, nil
+}
+
+// Record writes the event with the receive timestamp.
+func (r *Recorder) Record(event cloudevents.Event) error {
+	event.SetExtension(RecordedAtExtension, time.Now().UTC())
+	data, err := json.Marshal(event)
+	if err != nil {
+		return fmt.Errorf("encode event: %w", err)
+	}
+	data = append(data, '\n')
+	if r.maxSize > 0 && r.size > 0 && r.size+int64(len(data)) > r.maxSize {
+		if err := r.rotate(); err != nil {
+			return fmt.Errorf("rotate %q: %w", r.path, err)
+		}
+	}
+	n, err := r.file.Write(data)
+	r.size += int64(n)
+	return err
+}
+
+// Close closes the current file.
+func (r *Recorder) Close() error {
+	return r.file.Close()
+}
+
+// ReadRecorded decodes the events written by the recorder, empty lines are skipped.
+func ReadRecorded(r io.Reader) ([]cloudevents.Event, error) {
+	var events []cloudevents.Event
+	scanner := bufio.NewScanner(r)
+	scanner.Buffer(make([]byte, 0, 64*1024), maxRecordedLine)
+	for line := 1; scanner.Scan(); line++ {
+		if strings.TrimSpace(scanner.Text()) == "" {
+			continue
+		}
+		var event cloudevents.Event
+		if err := json.Unmarshal(scanner.Bytes(), &event); err != nil {
+			return nil, fmt.Errorf("line %d: %w", line, err)
+		}
+		events = append(events, event)
+	}
+	return events, scanner.Err()
+}
+
+func (r *Recorder) open() error {
+	file, err := os.OpenFile(r.path, os.O_APPEND|os.O_CREATE|os.O_WRONLY, 0644)
+	if err != nil {
+		return fmt.Errorf("record file: %w", err)
+	}
+	info, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return fmt.Errorf("record file: %w", err)
+	}
+	r.file = file
+	r.size = info.Size()
+	return nil
+}
+
+// rotate renames the current file to the first free "<name>.<n><ext>" path
+// and continues recording into the new file.
+func (r *Recorder) rotate() error {
+	if err := r.file.Close(); err != nil {
+		return err
+	}
+	ext := filepath.Ext(r.path)
+	base := strings.TrimSuffix(r.path, ext)
+	for i := 1; ; i++ {
+		rotated := fmt.Sprintf("%s.%d%s", base, i, ext)
+		if _, err := os.Stat(rotated); err == nil {
+			continue
+		}
+		if err := os.Rename(r.path, rotated); err != nil {
+			return err
+		}
+		break
+	}
+	return r.open()
+}
@@ -0,0 +1,114 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiretap
+
+import (
+	"bufio"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+)
+
+func TestRecorder(t *testing.T) {
+	dir := t.TempDir()
+	path := filepath.Join(dir, "events.jsonl")
+
+	event := cloudevents.NewEvent()
+	event.SetID("1")
+	event.SetType("foo.bar")
+	event.SetSource("test")
+	if err := event.SetData(cloudevents.ApplicationJSON, map[string]string{"foo": "bar"}); err != nil {
+		t.Fatal(err)
+	}
+	line, err := json.Marshal(event)
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	// every file fits two events
+	r, err := NewRecorder(path, int64(len(line)*2+100))
+	if err != nil {
+		t.Fatal(err)
+	}
+	for i := 0; i < 5; i++ {
+		if err := r.Record(event); err != nil {
+			t.Fatal(err)
+		}
+	}
+	if err := r.Close(); err != nil {
+		t.Fatal(err)
+	}
+
+	files := map[string]int{
+		"events.1.jsonl": 2,
+		"events.2.jsonl": 2,
+		"events.jsonl":   1,
+	}
+	for name, count := range files {
+		f, err := os.Open(filepath.Join(dir, name))
+		if err != nil {
+			t.Fatal(err)
+		}
+		lines := 0
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			var recorded cloudevents.Event
+			if err := json.Unmarshal(scanner.Bytes(), &recorded); err != nil {
+				t.Fatalf("%s: %v", name, err)
+			}
+			if _, set := recorded.Extensions()[RecordedAtExtension]; !set {
+				t.Errorf("%s: %s extension is not set", name, RecordedAtExtension)
+			}
+			if recorded.Type() != "foo.bar" {
+				t.Errorf("%s: unexpected event type %q", name, recorded.Type())
+			}
+			lines++
+		}
+		f.Close()
+		if lines != count {
+			t.Errorf("%s: expected %d events, got %d", name, count, lines)
+		}
+	}
+}
+
+func TestReadRecorded(t *testing.T) {
+	input := `{"specversion":"1.0","id":"1","source":"test","type":"foo.bar","datacontenttype":"application/json","data":{"region":"eu-west-1"}}
+
+{"specversion":"1.0","id":"2","source":"test","type":"foo.baz"}
+`
+	events, err := ReadRecorded(strings.NewReader(input))
+	if err != nil {
+		t.Fatal(err)
+	}
+	if len(events) != 2 {
+		t.Fatalf("expected 2 events, got %d", len(events))
+	}
+	if events[0].ID() != "1" || string(events[0].Data()) != `{"region":"eu-west-1"}` {
+		t.Errorf("unexpected first event: %s", events[0])
+	}
+	if events[1].Type() != "foo.baz" {
+		t.Errorf("unexpected second event type %q", events[1].Type())
+	}
+
+	if _, err := ReadRecorded(strings.NewReader(input + "not json\n")); err == nil || !strings.HasPrefix(err.Error(), "line 4: ") {
+		t.Errorf("expected line 4 decoding error, got %v", err)
+	}
+}
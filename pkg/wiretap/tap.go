@@ -0,0 +1,59 @@
+/*
+Copyright 2022 TriggerMesh Inc.
+
+Licensed under the Apache License, Version 2.0 (the "License");
+you may not use this file except in compliance with the License.
+You may obtain a copy of the License at
+
+    http://www.apache.org/licenses/LICENSE-2.0
+
+Unless required by applicable law or agreed to in writing, software
+distributed under the License is distributed on an "AS IS" BASIS,
+WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+See the License for the specific language governing permissions and
+limitations under the License.
+*/
+
+package wiretap
+
+import (
+	"context"
+	"fmt"
+	"net"
+	"strconv"
+
+	cloudevents "github.com/cloudevents/sdk-go/v2"
+
+	"github.com/triggermesh/tmctl/pkg/log"
+	"github.com/triggermesh/tmctl/pkg/urls"
+)
+
+// Listen starts the in-process CloudEvents receiver and sets it as the wiretap
+// destination. Received events are sent to the returned channel which is
+// closed when the context is cancelled.
+func (w *Wiretap) Listen(ctx context.Context) (<-chan cloudevents.Event, error) {
+	listener, err := net.Listen("tcp", ":0")
+	if err != nil {
+		return nil, fmt.Errorf("listener: %w", err)
+	}
+	client, err := cloudevents.NewClientHTTP(cloudevents.WithListener(listener))
+	if err != nil {
+		listener.Close()
+		return nil, fmt.Errorf("cloudevents client: %w", err)
+	}
+	w.Destination = urls.New("").URL(urls.ContainerNetwork, strconv.Itoa(listener.Addr().(*net.TCPAddr).Port))
+
+	events := make(chan cloudevents.Event)
+	go func() {
+		defer close(events)
+		if err := client.StartReceiver(ctx, func(event cloudevents.Event) {
+			select {
+			case events <- event:
+			case <-ctx.Done():
+			}
+		}); err != nil {
+			log.Printf("Wiretap receiver: %v", err)
+		}
+	}()
+	return events, nil
+}
@@ -26,19 +26,26 @@ import (
 	v1 "knative.dev/pkg/apis/duck/v1"
 
 	"github.com/docker/docker/client"
+	eventingbroker "github.com/triggermesh/brokers/pkg/config/broker"
 	"github.com/triggermesh/tmctl/pkg/config"
 	"github.com/triggermesh/tmctl/pkg/docker"
 	"github.com/triggermesh/tmctl/pkg/triggermesh"
 	tmbroker "github.com/triggermesh/tmctl/pkg/triggermesh/components/broker"
+	"github.com/triggermesh/tmctl/pkg/urls"
 	"github.com/triggermesh/triggermesh-core/pkg/apis/eventing/v1alpha1"
 )
 
 type Wiretap struct {
+	// Name is the name of the wiretap trigger.
+	Name        string
 	Broker      string
 	ConfigBase  string
 	Destination string
+	// Filters limits the events delivered to the wiretap.
+	Filters []eventingbroker.Filter
 
-	client *client.Client
+	client  *client.Client
+	adapter bool
 }
 
 const (
@@ -52,6 +59,7 @@ func New(broker, configBase string) (*Wiretap, error) {
 		return nil, err
 	}
 	return &Wiretap{
+		Name:       "wiretap",
 		Broker:     broker,
 		ConfigBase: configBase,
 		client:     dockerClient,
@@ -78,7 +86,8 @@ func (w *Wiretap) CreateAdapter(ctx context.Context) (io.ReadCloser, error) {
 	if err != nil {
 		return nil, fmt.Errorf("starting container: %w", err)
 	}
-	w.Destination = fmt.Sprintf("http://host.docker.internal:%s", c.HostPort())
+	w.adapter = true
+	w.Destination = urls.New("").URL(urls.ContainerNetwork, c.HostPort())
 	return c.Logs(ctx, w.client, time.Now().Add(2*time.Second), true)
 }
 
@@ -88,13 +97,14 @@ func (w *Wiretap) CreateTrigger() error {
 		return fmt.Errorf("wiretap URL: %w", err)
 	}
 	trigger := &tmbroker.Trigger{
-		Name:       "wiretap",
+		Name:       w.Name,
 		ConfigBase: w.ConfigBase,
 		LocalURL:   url,
 		TriggerSpec: v1alpha1.TriggerSpec{
+			Filters: w.Filters,
 			Target: v1.Destination{
 				Ref: &v1.KReference{
-					Name: "wiretap",
+					Name: w.Name,
 				},
 			},
 			Broker: v1.KReference{
@@ -122,7 +132,7 @@ func (w *Wiretap) BrokerLogs(ctx context.Context, c config.BrokerConfig) (io.Rea
 
 func (w *Wiretap) Cleanup(ctx context.Context) error {
 	trigger := &tmbroker.Trigger{
-		Name:       "wiretap",
+		Name:       w.Name,
 		ConfigBase: w.ConfigBase,
 		TriggerSpec: v1alpha1.TriggerSpec{
 			Broker: v1.KReference{
@@ -133,5 +143,8 @@ func (w *Wiretap) Cleanup(ctx context.Context) error {
 	if err := trigger.RemoveFromLocalConfig(); err != nil {
 		return fmt.Errorf("removing trigger: %v", err)
 	}
+	if !w.adapter {
+		return nil
+	}
 	return docker.ForceStop(ctx, fmt.Sprintf("%s-wiretap", w.Broker), w.client)
 }
@@ -143,6 +143,7 @@ mkdir -p $TESTDIR
 go build -ldflags="-X 'main.Commit=`git rev-parse HEAD`'" -o $TMCTL main.go
 
 HOME=$TESTDIR
+export TMCTL_HOME=$TESTDIR/home
 
 print_version
 if [ $? -ne 0 ]; then 
@@ -17,8 +17,9 @@ limitations under the License.
 package test
 
 import (
+	"fmt"
 	"os"
-	"path"
+	"path/filepath"
 	"runtime"
 
 	"github.com/triggermesh/tmctl/pkg/triggermesh/crd"
@@ -26,12 +27,26 @@ import (
 
 func Manifest() string {
 	_, filename, _, _ := runtime.Caller(0)
-	return path.Dir(filename) + "/fixtures/manifest.yaml"
+	return filepath.Join(filepath.Dir(filename), "fixtures", "manifest.yaml")
+}
+
+// CanonicalManifest returns the path of the manifest fixture
+// with the objects in the canonical order.
+func CanonicalManifest() string {
+	_, filename, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(filename), "fixtures", "manifest-canonical.yaml")
+}
+
+// LegacyManifest returns the path of the manifest fixture
+// in the format of the older CLI version.
+func LegacyManifest(version int) string {
+	_, filename, _, _ := runtime.Caller(0)
+	return filepath.Join(filepath.Dir(filename), "fixtures", "legacy", fmt.Sprintf("manifest-v%d.yaml", version))
 }
 
 func CRD() map[string]crd.CRD {
 	_, filename, _, _ := runtime.Caller(0)
-	reader, err := os.Open(path.Dir(filename) + "/fixtures/crd.yaml")
+	reader, err := os.Open(filepath.Join(filepath.Dir(filename), "fixtures", "crd.yaml"))
 	if err != nil {
 		panic(err)
 	}
@@ -44,5 +59,5 @@ func CRD() map[string]crd.CRD {
 
 func ConfigBase() string {
 	_, filename, _, _ := runtime.Caller(0)
-	return path.Dir(filename) + "/fixtures"
+	return filepath.Join(filepath.Dir(filename), "fixtures")
 }